@@ -6,18 +6,29 @@ import (
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/accounting"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/events"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/persister"
+	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// outputsUnavailable is set while the writes to all outputs are failing.
+	outputsUnavailable bool
+
+	// flushC requests an immediate flush of the outputs.
+	flushC chan struct{}
 }
 
 // NewAgent returns an Agent struct based off the given Config
@@ -26,6 +37,11 @@ func NewAgent(config *config.Config) (*Agent, error) {
 		Config: config,
 	}
 
+	err := tls.SetPolicy(config.Agent.TLSMinVersion, config.Agent.FIPS)
+	if err != nil {
+		return nil, err
+	}
+
 	if !a.Config.Agent.OmitHostname {
 		if a.Config.Agent.Hostname == "" {
 			hostname, err := os.Hostname()
@@ -74,6 +90,7 @@ func (a *Agent) Connect() error {
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.Config.Outputs {
+		o.Wait()
 		err = o.Output.Close()
 		switch ot := o.Output.(type) {
 		case telegraf.ServiceOutput:
@@ -83,6 +100,29 @@ func (a *Agent) Close() error {
 	return err
 }
 
+// restoreState registers the stateful inputs with a persister and loads
+// their state from the statefile.  Each input is identified by its name, with
+// a "#n" suffix for the n-th instance when an input is configured more than
+// once.
+func (a *Agent) restoreState() (*persister.Persister, error) {
+	p := persister.NewPersister(a.Config.Agent.Statefile)
+	instances := make(map[string]int)
+	for _, input := range a.Config.Inputs {
+		id := input.Name()
+		instances[id]++
+		if n := instances[id]; n > 1 {
+			id = fmt.Sprintf("%s#%d", id, n)
+		}
+
+		if plugin, ok := input.Input.(telegraf.StatefulPlugin); ok {
+			if err := p.Register(id, plugin); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, p.Load()
+}
+
 func panicRecover(input *models.RunningInput) {
 	if err := recover(); err != nil {
 		trace := make([]byte, 2048)
@@ -120,11 +160,13 @@ func (a *Agent) gatherer(
 	for {
 		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
 
-		start := time.Now()
-		gatherWithTimeout(shutdown, input, acc, interval)
-		elapsed := time.Since(start)
+		if !input.Paused() {
+			start := time.Now()
+			gatherWithTimeout(shutdown, input, acc, interval)
+			elapsed := time.Since(start)
 
-		GatherTime.Incr(elapsed.Nanoseconds())
+			GatherTime.Incr(elapsed.Nanoseconds())
+		}
 
 		select {
 		case <-shutdown:
@@ -135,6 +177,59 @@ func (a *Agent) gatherer(
 	}
 }
 
+// scheduledGatherer runs an input at the activation times of its schedule.
+func (a *Agent) scheduledGatherer(
+	shutdown chan struct{},
+	input *models.RunningInput,
+	metricC chan telegraf.Metric,
+) {
+	defer panicRecover(input)
+
+	GatherTime := selfstat.RegisterTiming("gather",
+		"gather_time_ns",
+		map[string]string{"input": input.Config.Name},
+	)
+
+	acc := NewAccumulator(input, metricC)
+	acc.SetPrecision(a.Config.Agent.Precision.Duration,
+		a.Config.Agent.Interval.Duration)
+
+	sched := input.Config.Schedule
+	next := sched.Next(time.Now())
+	for !next.IsZero() {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-shutdown:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Activations missed while gathering are skipped, the next gather
+		// must complete before the following activation.
+		now := time.Now()
+		if now.Before(next) {
+			now = next
+		}
+		next = sched.Next(now)
+
+		if !input.Paused() {
+			start := time.Now()
+			timeout := next.Sub(start)
+			if next.IsZero() || timeout <= 0 {
+				timeout = a.Config.Agent.Interval.Duration
+			}
+			gatherWithTimeout(shutdown, input, acc, timeout)
+			elapsed := time.Since(start)
+
+			GatherTime.Incr(elapsed.Nanoseconds())
+		}
+	}
+
+	log.Printf("W! Schedule of input %s has no further activations\n",
+		input.Name())
+}
+
 // gatherWithTimeout gathers from the given input, with the given timeout.
 //   when the given timeout is reached, gatherWithTimeout logs an error message
 //   but continues waiting for it to return. This is to avoid leaving behind
@@ -150,7 +245,9 @@ func gatherWithTimeout(
 	defer ticker.Stop()
 	done := make(chan error)
 	go func() {
-		done <- input.Input.Gather(acc)
+		accounting.Do(input.Name(), input.Index, func() {
+			done <- input.Gather(acc)
+		})
 	}()
 
 	for {
@@ -203,7 +300,7 @@ func (a *Agent) Test() error {
 		input.SetTrace(true)
 		input.SetDefaultTags(a.Config.Tags)
 
-		if err := input.Input.Gather(acc); err != nil {
+		if err := input.Gather(acc); err != nil {
 			return err
 		}
 
@@ -212,7 +309,7 @@ func (a *Agent) Test() error {
 		switch input.Name() {
 		case "inputs.cpu", "inputs.mongodb", "inputs.procstat":
 			time.Sleep(500 * time.Millisecond)
-			if err := input.Input.Gather(acc); err != nil {
+			if err := input.Gather(acc); err != nil {
 				return err
 			}
 		}
@@ -224,13 +321,22 @@ func (a *Agent) Test() error {
 // flush writes a list of metrics to all configured outputs
 func (a *Agent) flush() {
 	var wg sync.WaitGroup
+	var failed int32
+	var outputs int32
 
 	wg.Add(len(a.Config.Outputs))
 	for _, o := range a.Config.Outputs {
+		if !o.Config.Shadow {
+			outputs++
+		}
 		go func(output *models.RunningOutput) {
 			defer wg.Done()
-			err := output.Write()
+			var err error
+			accounting.Do("outputs."+output.Name, output.Index, func() {
+				err = output.Write()
+			})
 			if err != nil {
+				atomic.AddInt32(&failed, 1)
 				log.Printf("E! Error writing to output [%s]: %s\n",
 					output.Name, err.Error())
 			}
@@ -238,10 +344,21 @@ func (a *Agent) flush() {
 	}
 
 	wg.Wait()
+
+	// Shadow outputs never return errors, so they are not counted.
+	unavailable := outputs > 0 && failed == outputs
+	if unavailable != a.outputsUnavailable {
+		a.outputsUnavailable = unavailable
+		if unavailable {
+			events.Publish(events.OutputsUnavailable, "")
+		} else {
+			events.Publish(events.OutputsAvailable, "")
+		}
+	}
 }
 
 // flusher monitors the metrics input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) error {
+func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric, stopInputs func()) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 300)
@@ -253,30 +370,23 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for {
-			select {
-			case <-shutdown:
-				if len(outMetricC) > 0 {
-					// keep going until outMetricC is flushed
-					continue
+		// outMetricC is closed once the service inputs are stopped, on
+		// shutdown.
+		for m := range outMetricC {
+			// if dropOriginal is set to true, then we will only send this
+			// metric to the aggregators, not the outputs.
+			var dropOriginal bool
+			for _, agg := range a.Config.Aggregators {
+				if ok := agg.Add(m.Copy()); ok {
+					dropOriginal = true
 				}
-				return
-			case m := <-outMetricC:
-				// if dropOriginal is set to true, then we will only send this
-				// metric to the aggregators, not the outputs.
-				var dropOriginal bool
-				for _, agg := range a.Config.Aggregators {
-					if ok := agg.Add(m.Copy()); ok {
-						dropOriginal = true
-					}
-				}
-				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(m.Copy())
-						}
+			}
+			if !dropOriginal {
+				for i, o := range a.Config.Outputs {
+					if i == len(a.Config.Outputs)-1 {
+						o.AddMetric(m)
+					} else {
+						o.AddMetric(m.Copy())
 					}
 				}
 			}
@@ -318,7 +428,26 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 		select {
 		case <-shutdown:
 			log.Println("I! Hang on, flushing any cached metrics before shutdown")
+			// The service inputs are stopped while their last metrics are
+			// still processed.
+			stopped := make(chan struct{})
+			go func() {
+				stopInputs()
+				close(stopped)
+			}()
+			for stopping := true; stopping; {
+				select {
+				case metric := <-metricC:
+					a.process(metric, outMetricC)
+				case <-stopped:
+					stopping = false
+				}
+			}
+			for len(metricC) > 0 {
+				a.process(<-metricC, outMetricC)
+			}
 			// wait for outMetricC to get flushed before flushing outputs
+			close(outMetricC)
 			wg.Wait()
 			a.flush()
 			return nil
@@ -335,20 +464,37 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 						" already a flush ongoing.")
 				}
 			}()
+		case <-a.flushC:
+			go func() {
+				select {
+				case semaphore <- struct{}{}:
+					a.flush()
+					<-semaphore
+				default:
+					log.Println("W! Skipping a requested flush because there is" +
+						" already a flush ongoing.")
+				}
+			}()
 		case metric := <-metricC:
-			// NOTE potential bottleneck here as we put each metric through the
-			// processors serially.
-			mS := []telegraf.Metric{metric}
-			for _, processor := range a.Config.Processors {
-				mS = processor.Apply(mS...)
-			}
-			for _, m := range mS {
-				outMetricC <- m
-			}
+			a.process(metric, outMetricC)
 		}
 	}
 }
 
+// process puts a metric through the processors, on to the outputs and the
+// aggregators.
+func (a *Agent) process(metric telegraf.Metric, outMetricC chan telegraf.Metric) {
+	// NOTE potential bottleneck here as we put each metric through the
+	// processors serially.
+	mS := []telegraf.Metric{metric}
+	for _, processor := range a.Config.Processors {
+		mS = processor.Apply(mS...)
+	}
+	for _, m := range mS {
+		outMetricC <- m
+	}
+}
+
 // Run runs the agent daemon, gathering every Interval
 func (a *Agent) Run(shutdown chan struct{}) error {
 	var wg sync.WaitGroup
@@ -362,7 +508,47 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	metricC := make(chan telegraf.Metric, 100)
 	aggC := make(chan telegraf.Metric, 100)
 
-	// Start all ServicePlugins
+	// Restore the state of stateful inputs before they are started, the
+	// state is saved again after all service inputs have been stopped.
+	if a.Config.Agent.Statefile != "" {
+		p, err := a.restoreState()
+		if err != nil {
+			log.Printf("E! Restoring plugin state failed, exiting\n%s\n",
+				err.Error())
+			return err
+		}
+		defer func() {
+			if err := p.Store(); err != nil {
+				log.Printf("E! Saving plugin state failed: %s\n", err.Error())
+			}
+		}()
+	}
+
+	// The plugins must be labelled from their start for the goroutines of
+	// the service inputs to be accounted.
+	if a.Config.Agent.ResourceAccounting {
+		accounting.Enable()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accounting.Run(a.Config.Agent.Interval.Duration, shutdown)
+		}()
+	}
+
+	// Start all ServicePlugins.  They are stopped by the flusher on
+	// shutdown, so that their last metrics are written, or on the errors
+	// below.  The inputs reporting the failure of their service, only the
+	// telegraf.SupervisedInput ones, are restarted by their supervisor.
+	var supervisors []*supervisor
+	var stopOnce sync.Once
+	stopInputs := func() {
+		stopOnce.Do(func() {
+			for i := len(supervisors) - 1; i >= 0; i-- {
+				supervisors[i].stop()
+			}
+		})
+	}
+	defer stopInputs()
 	for _, input := range a.Config.Inputs {
 		input.SetDefaultTags(a.Config.Tags)
 		switch p := input.Input.(type) {
@@ -371,13 +557,42 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 			// Service input plugins should set their own precision of their
 			// metrics.
 			acc.SetPrecision(time.Nanosecond, 0)
-			if err := p.Start(acc); err != nil {
+			sup := newSupervisor(input, p, acc)
+			if err := sup.start(); err != nil {
 				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
 					input.Name(), err.Error())
 				return err
 			}
-			defer p.Stop()
+			supervisors = append(supervisors, sup)
+		}
+	}
+	wg.Add(len(supervisors))
+	for _, sup := range supervisors {
+		go func(sup *supervisor) {
+			defer wg.Done()
+			sup.run()
+		}(sup)
+	}
+
+	if a.Config.Agent.ProbeAddress != "" {
+		probe := newProbeServer(a)
+		if err := probe.Start(a.Config.Agent.ProbeAddress); err != nil {
+			log.Printf("E! Probe service failed to start, exiting\n%s\n",
+				err.Error())
+			return err
 		}
+		defer probe.Stop()
+	}
+
+	a.flushC = make(chan struct{}, 1)
+	if a.Config.Agent.ControlAddress != "" {
+		control := newControlServer(a)
+		if err := control.Start(a.Config.Agent.ControlAddress); err != nil {
+			log.Printf("E! Control service failed to start, exiting\n%s\n",
+				err.Error())
+			return err
+		}
+		defer control.Stop()
 	}
 
 	// Round collection to nearest interval by sleeping
@@ -389,7 +604,7 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := a.flusher(shutdown, metricC, aggC); err != nil {
+		if err := a.flusher(shutdown, metricC, aggC, stopInputs); err != nil {
 			log.Printf("E! Flusher routine failed, exiting: %s\n", err.Error())
 			close(shutdown)
 		}
@@ -415,6 +630,10 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		}
 		go func(in *models.RunningInput, interv time.Duration) {
 			defer wg.Done()
+			if in.Config.Schedule != nil {
+				a.scheduledGatherer(shutdown, in, metricC)
+				return
+			}
 			a.gatherer(shutdown, in, interv, metricC)
 		}(input, interval)
 	}
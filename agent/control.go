@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf/internal/events"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// controlServer serves the control API used to change the state of the
+// agent at runtime.
+//
+//	GET  /inputs               list the inputs and their state
+//	POST /inputs/<name>/pause  pause all inputs named <name>
+//	POST /inputs/<name>/resume resume all inputs named <name>
+//	POST /flush                flush the outputs now
+type controlServer struct {
+	agent *Agent
+	token string
+
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+type inputState struct {
+	Name   string `json:"name"`
+	Paused bool   `json:"paused"`
+}
+
+func newControlServer(agent *Agent) *controlServer {
+	return &controlServer{
+		agent: agent,
+		token: agent.Config.Agent.ControlToken,
+	}
+}
+
+// Start begins listening on the given address, either a TCP address or a
+// unix socket in the form unix:///path.
+func (c *controlServer) Start(address string) error {
+	network := "tcp"
+	if strings.HasPrefix(address, "unix://") {
+		network = "unix"
+		address = strings.TrimPrefix(address, "unix://")
+		os.Remove(address)
+	}
+
+	if network == "tcp" && c.token == "" {
+		return errors.New("control_token is required when serving the control API on TCP")
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	c.listener = listener
+	c.server = &http.Server{Handler: c}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.server.Serve(c.listener)
+	}()
+
+	log.Printf("I! Started control service on %s\n", address)
+	return nil
+}
+
+// Stop closes the listener and waits for the server to exit.
+func (c *controlServer) Stop() {
+	c.listener.Close()
+	c.wg.Wait()
+}
+
+func (c *controlServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if !authorized(req, c.token) {
+		http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.Trim(req.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "inputs":
+		if req.Method != http.MethodGet {
+			http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		c.listInputs(res)
+	case len(parts) == 3 && parts[0] == "inputs":
+		if req.Method != http.MethodPost {
+			http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		c.setInputState(res, parts[1], parts[2])
+	case path == "flush":
+		if req.Method != http.MethodPost {
+			http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		select {
+		case c.agent.flushC <- struct{}{}:
+		default:
+			// a flush has already been requested
+		}
+		res.WriteHeader(http.StatusAccepted)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+func (c *controlServer) listInputs(res http.ResponseWriter) {
+	states := make([]inputState, 0, len(c.agent.Config.Inputs))
+	for _, input := range c.agent.Config.Inputs {
+		states = append(states, inputState{
+			Name:   input.Config.Name,
+			Paused: input.Paused(),
+		})
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(res).Encode(states)
+}
+
+func (c *controlServer) setInputState(res http.ResponseWriter, name, action string) {
+	if action != "pause" && action != "resume" {
+		http.Error(res, "404 page not found", http.StatusNotFound)
+		return
+	}
+
+	var inputs []*models.RunningInput
+	for _, input := range c.agent.Config.Inputs {
+		if input.Config.Name == name {
+			inputs = append(inputs, input)
+		}
+	}
+	if len(inputs) == 0 {
+		http.Error(res, "No matching input found.", http.StatusNotFound)
+		return
+	}
+
+	if action == "pause" {
+		for _, input := range inputs {
+			input.Pause()
+		}
+		log.Printf("I! Paused input [%s]\n", name)
+		events.Publish(events.InputPaused, name)
+	} else {
+		for _, input := range inputs {
+			input.Resume()
+		}
+		log.Printf("I! Resumed input [%s]\n", name)
+		events.Publish(events.InputResumed, name)
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newControl(token string) (*controlServer, *Agent) {
+	a := newProbeAgent("")
+	a.Config.Agent.ControlToken = token
+	a.flushC = make(chan struct{}, 1)
+	return newControlServer(a), a
+}
+
+func TestControl_PauseResume(t *testing.T) {
+	c, a := newControl("")
+	sub := events.Subscribe(events.InputPaused, events.InputResumed)
+	defer events.Unsubscribe(sub)
+
+	req := httptest.NewRequest("POST", "/inputs/probe_input/pause", nil)
+	res := httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	assert.True(t, a.Config.Inputs[0].Paused())
+	assert.Equal(t, events.InputPaused, (<-sub.C).Type)
+
+	req = httptest.NewRequest("GET", "/inputs", nil)
+	res = httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[{"name":"probe_input","paused":true}]`, res.Body.String())
+
+	req = httptest.NewRequest("POST", "/inputs/probe_input/resume", nil)
+	res = httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	assert.False(t, a.Config.Inputs[0].Paused())
+	assert.Equal(t, events.InputResumed, (<-sub.C).Type)
+}
+
+func TestControl_PausedInputDiscardsMetrics(t *testing.T) {
+	_, a := newControl("")
+	input := a.Config.Inputs[0]
+
+	input.Pause()
+	m := input.MakeMetric("cpu", map[string]interface{}{"value": 1}, nil, 0, time.Unix(0, 0))
+	assert.Nil(t, m)
+
+	input.Resume()
+	m = input.MakeMetric("cpu", map[string]interface{}{"value": 1}, nil, 0, time.Unix(0, 0))
+	assert.NotNil(t, m)
+}
+
+func TestControl_UnknownInput(t *testing.T) {
+	c, _ := newControl("")
+
+	req := httptest.NewRequest("POST", "/inputs/cpu/pause", nil)
+	res := httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	req = httptest.NewRequest("POST", "/inputs/probe_input/restart", nil)
+	res = httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestControl_Method(t *testing.T) {
+	c, _ := newControl("")
+
+	req := httptest.NewRequest("GET", "/inputs/probe_input/pause", nil)
+	res := httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+}
+
+func TestControl_Flush(t *testing.T) {
+	c, a := newControl("secret")
+
+	req := httptest.NewRequest("POST", "/flush", nil)
+	res := httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Len(t, a.flushC, 0)
+
+	req = httptest.NewRequest("POST", "/flush", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res = httptest.NewRecorder()
+	c.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	assert.Len(t, a.flushC, 1)
+}
+
+func TestControl_TCPRequiresToken(t *testing.T) {
+	c, _ := newControl("")
+	err := c.Start("localhost:0")
+	require.Error(t, err)
+}
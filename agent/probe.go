@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// probeServer serves on-demand gathers of the configured inputs over HTTP.
+//
+//	GET /probe?input=cpu&format=json
+//
+// The input parameter is optional, when omitted all inputs are gathered. The
+// format may be either "influx" (the default) or "json". Metrics gathered by
+// a probe are returned to the caller only, they are not sent to the outputs.
+type probeServer struct {
+	agent *Agent
+	token string
+
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+func newProbeServer(agent *Agent) *probeServer {
+	return &probeServer{
+		agent: agent,
+		token: agent.Config.Agent.ProbeToken,
+	}
+}
+
+// Start begins listening on the given address.
+func (p *probeServer) Start(address string) error {
+	if p.token == "" {
+		return errors.New("probe_token is required when serving the probe service")
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	p.listener = listener
+	p.server = &http.Server{Handler: p}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.server.Serve(p.listener)
+	}()
+
+	log.Printf("I! Started probe service on %s\n", address)
+	return nil
+}
+
+// Stop closes the listener and waits for the server to exit.
+func (p *probeServer) Stop() {
+	p.listener.Close()
+	p.wg.Wait()
+}
+
+func (p *probeServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/probe" {
+		http.NotFound(res, req)
+		return
+	}
+
+	if !authorized(req, p.token) {
+		http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "influx"
+	}
+	var serializer serializers.Serializer
+	var contentType string
+	var err error
+	switch format {
+	case "influx":
+		serializer, err = serializers.NewInfluxSerializer()
+		contentType = "text/plain; charset=utf-8"
+	case "json":
+		serializer, err = serializers.NewJsonSerializer(time.Nanosecond)
+		contentType = "application/json"
+	default:
+		http.Error(res, fmt.Sprintf("Invalid format: %s", format),
+			http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	inputs := p.selectInputs(req.URL.Query().Get("input"))
+	if len(inputs) == 0 {
+		http.Error(res, "No matching input found.", http.StatusNotFound)
+		return
+	}
+
+	var metrics []telegraf.Metric
+	for _, input := range inputs {
+		m, err := p.gather(input)
+		if err != nil {
+			http.Error(res, fmt.Sprintf("Error gathering from %s: %s",
+				input.Name(), err), http.StatusInternalServerError)
+			return
+		}
+		metrics = append(metrics, m...)
+	}
+
+	var octets []byte
+	if format == "json" {
+		octets, err = serializer.SerializeBatch(metrics)
+	} else {
+		for _, m := range metrics {
+			var buf []byte
+			buf, err = serializer.Serialize(m)
+			if err != nil {
+				break
+			}
+			octets = append(octets, buf...)
+		}
+	}
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.WriteHeader(http.StatusOK)
+	res.Write(octets)
+}
+
+// authorized checks the bearer token of the request, if a token is set.  The
+// services served on TCP refuse to start without a token.
+func authorized(req *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	bearer := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
+
+// selectInputs returns the non-service inputs matching name, or all of them
+// if name is empty.
+func (p *probeServer) selectInputs(name string) []*models.RunningInput {
+	var selected []*models.RunningInput
+	for _, input := range p.agent.Config.Inputs {
+		if _, ok := input.Input.(telegraf.ServiceInput); ok {
+			continue
+		}
+		if name == "" || input.Config.Name == name {
+			selected = append(selected, input)
+		}
+	}
+	return selected
+}
+
+// gather runs a single gather of the input and returns the resulting
+// metrics. If the input does not complete within its collection interval
+// an error is returned; the gather itself is left running.
+func (p *probeServer) gather(input *models.RunningInput) ([]telegraf.Metric, error) {
+	interval := p.agent.Config.Agent.Interval.Duration
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+
+	metricC := make(chan telegraf.Metric, 100)
+	acc := NewAccumulator(input, metricC)
+	acc.SetPrecision(p.agent.Config.Agent.Precision.Duration, interval)
+
+	var metrics []telegraf.Metric
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for m := range metricC {
+			metrics = append(metrics, m)
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		err := input.Gather(acc)
+		close(metricC)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		<-collected
+		if err != nil {
+			return nil, err
+		}
+		return metrics, nil
+	case <-time.After(interval):
+		return nil, fmt.Errorf("took longer to collect than collection interval (%s)",
+			interval)
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type probeInput struct{}
+
+func (p *probeInput) SampleConfig() string { return "" }
+func (p *probeInput) Description() string  { return "" }
+func (p *probeInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("probe",
+		map[string]interface{}{"value": 42},
+		map[string]string{"tag": "a"},
+		time.Unix(0, 0))
+	return nil
+}
+
+func newProbeAgent(token string) *Agent {
+	c := config.NewConfig()
+	c.Agent.ProbeToken = token
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&probeInput{},
+		&models.InputConfig{Name: "probe_input"}))
+	return &Agent{Config: c}
+}
+
+func TestProbe_Influx(t *testing.T) {
+	p := newProbeServer(newProbeAgent(""))
+
+	req := httptest.NewRequest("GET", "/probe?input=probe_input", nil)
+	res := httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "probe,tag=a value=42i 0\n", res.Body.String())
+}
+
+func TestProbe_JSON(t *testing.T) {
+	p := newProbeServer(newProbeAgent(""))
+
+	req := httptest.NewRequest("GET", "/probe?format=json", nil)
+	res := httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), `"name":"probe"`)
+}
+
+func TestProbe_UnknownInput(t *testing.T) {
+	p := newProbeServer(newProbeAgent(""))
+
+	req := httptest.NewRequest("GET", "/probe?input=cpu", nil)
+	res := httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestProbe_Token(t *testing.T) {
+	p := newProbeServer(newProbeAgent("secret"))
+
+	req := httptest.NewRequest("GET", "/probe", nil)
+	res := httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	req = httptest.NewRequest("GET", "/probe", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	res = httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	req = httptest.NewRequest("GET", "/probe", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res = httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestProbe_RequiresToken(t *testing.T) {
+	p := newProbeServer(newProbeAgent(""))
+	err := p.Start("localhost:0")
+	require.Error(t, err)
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/accounting"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// The delay before restarting a failed service input doubles on each
+// consecutive failure, from minRestartDelay to maxRestartDelay.  It is reset
+// once the service has run for maxRestartDelay.
+var (
+	minRestartDelay = time.Second
+	maxRestartDelay = 5 * time.Minute
+)
+
+// supervisor starts and stops a service input.  The inputs are restarted
+// when their service fails only if they are telegraf.SupervisedInput, since
+// the agent cannot tell otherwise: the others keep running as started.
+type supervisor struct {
+	input   *models.RunningInput
+	service telegraf.ServiceInput
+	acc     telegraf.Accumulator
+
+	mu      sync.Mutex
+	running bool
+	stopped bool
+	done    chan struct{}
+
+	restarts selfstat.Stat
+}
+
+func newSupervisor(input *models.RunningInput, service telegraf.ServiceInput, acc telegraf.Accumulator) *supervisor {
+	return &supervisor{
+		input:   input,
+		service: service,
+		acc:     acc,
+		done:    make(chan struct{}),
+		restarts: selfstat.Register("gather", "service_restarts",
+			map[string]string{"input": input.Config.Name}),
+	}
+}
+
+// start starts the service, unless the supervisor is stopped.
+func (s *supervisor) start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil
+	}
+	var err error
+	accounting.Do(s.input.Name(), s.input.Index, func() {
+		err = s.service.Start(s.acc)
+	})
+	s.running = err == nil
+	return err
+}
+
+// halt stops the service if it is running.
+func (s *supervisor) halt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.service.Stop()
+		s.running = false
+	}
+}
+
+// stop stops the service for good.
+func (s *supervisor) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	close(s.done)
+	if s.running {
+		s.service.Stop()
+		s.running = false
+	}
+}
+
+// run restarts the service when it fails, until the supervisor is stopped.
+func (s *supervisor) run() {
+	p, ok := s.service.(telegraf.SupervisedInput)
+	if !ok {
+		return
+	}
+
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		var err error
+		select {
+		case <-s.done:
+			return
+		case err = <-p.Failed():
+		}
+		if time.Since(started) >= maxRestartDelay {
+			delay = minRestartDelay
+		}
+		log.Printf("E! Service for input %s failed, restarting in %s: %s\n",
+			s.input.Name(), delay, err)
+		s.halt()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxRestartDelay {
+				delay = maxRestartDelay
+			}
+			if err := s.start(); err != nil {
+				log.Printf("E! Service for input %s failed to restart, retrying in %s: %s\n",
+					s.input.Name(), delay, err)
+				continue
+			}
+			break
+		}
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		s.restarts.Incr(1)
+		log.Printf("I! Service for input %s restarted\n", s.input.Name())
+	}
+}
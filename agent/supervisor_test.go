@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// failingInput is a supervised input whose service fails on demand, and
+// fails to start as many times as startErrors.
+type failingInput struct {
+	mu          sync.Mutex
+	starts      int
+	stops       int
+	startErrors int
+	failed      chan error
+}
+
+func (i *failingInput) SampleConfig() string                { return "" }
+func (i *failingInput) Description() string                 { return "" }
+func (i *failingInput) Gather(_ telegraf.Accumulator) error { return nil }
+
+func (i *failingInput) Start(_ telegraf.Accumulator) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.startErrors > 0 {
+		i.startErrors--
+		return errors.New("address already in use")
+	}
+	i.starts++
+	i.failed = make(chan error, 1)
+	return nil
+}
+
+func (i *failingInput) Stop() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stops++
+}
+
+func (i *failingInput) Failed() <-chan error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.failed
+}
+
+func (i *failingInput) fail() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.failed <- errors.New("accept: too many open files")
+}
+
+func (i *failingInput) counts() (int, int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.starts, i.stops
+}
+
+func waitStarts(t *testing.T, input *failingInput, starts int) {
+	for i := 0; i < 100; i++ {
+		if n, _ := input.counts(); n >= starts {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("input not started %d times", starts)
+}
+
+func newTestSupervisor(input telegraf.ServiceInput) *supervisor {
+	ri := models.NewRunningInput(input, &models.InputConfig{Name: "supervisor_test"})
+	return newSupervisor(ri, input, &testutil.Accumulator{})
+}
+
+func setRestartDelays(min, max time.Duration) func() {
+	oldMin, oldMax := minRestartDelay, maxRestartDelay
+	minRestartDelay, maxRestartDelay = min, max
+	return func() {
+		minRestartDelay, maxRestartDelay = oldMin, oldMax
+	}
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	defer setRestartDelays(10*time.Millisecond, time.Second)()
+
+	input := &failingInput{}
+	s := newTestSupervisor(input)
+	require.NoError(t, s.start())
+	restarts := s.restarts.Get()
+	done := make(chan struct{})
+	go func() {
+		s.run()
+		close(done)
+	}()
+
+	input.fail()
+	waitStarts(t, input, 2)
+	_, stops := input.counts()
+	require.Equal(t, 1, stops)
+
+	// The restarts are retried until the service starts.
+	input.mu.Lock()
+	input.startErrors = 2
+	input.mu.Unlock()
+	input.fail()
+	waitStarts(t, input, 3)
+
+	s.stop()
+	<-done
+	starts, stops := input.counts()
+	require.Equal(t, 3, starts)
+	require.Equal(t, 3, stops)
+	require.Equal(t, restarts+2, s.restarts.Get())
+}
+
+func TestSupervisorStop(t *testing.T) {
+	defer setRestartDelays(time.Hour, time.Hour)()
+
+	input := &failingInput{}
+	s := newTestSupervisor(input)
+	require.NoError(t, s.start())
+	done := make(chan struct{})
+	go func() {
+		s.run()
+		close(done)
+	}()
+
+	// The service is not restarted once stopped, while waiting to.
+	input.fail()
+	s.stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("supervisor not stopped")
+	}
+	s.stop()
+	require.NoError(t, s.start())
+	starts, stops := input.counts()
+	require.Equal(t, 1, starts)
+	require.Equal(t, 1, stops)
+}
+
+// serviceInput is a service input without supervision.
+type serviceInput struct {
+	failingInput
+}
+
+func (i *serviceInput) Failed() {}
+
+func TestSupervisorUnsupervised(t *testing.T) {
+	input := &serviceInput{}
+	s := newTestSupervisor(input)
+	require.NoError(t, s.start())
+	// run returns at once, the service is only started and stopped.
+	s.run()
+	s.stop()
+	starts, stops := input.counts()
+	require.Equal(t, 1, starts)
+	require.Equal(t, 1, stops)
+}
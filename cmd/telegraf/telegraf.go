@@ -15,6 +15,7 @@ import (
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/events"
 	"github.com/influxdata/telegraf/logger"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/all"
 	"github.com/influxdata/telegraf/plugins/inputs"
@@ -51,6 +52,8 @@ var fAggregatorFilters = flag.String("aggregator-filter", "",
 	"filter the aggregators to enable, separator is :")
 var fProcessorFilters = flag.String("processor-filter", "",
 	"filter the processors to enable, separator is :")
+var fProfiles = flag.String("profiles", "",
+	"profiles of the plugins to enable, separator is ,")
 var fUsage = flag.String("usage", "",
 	"print usage for a plugin, ie, 'telegraf --usage mysql'")
 var fService = flag.String("service", "",
@@ -85,6 +88,7 @@ func reloadLoop(
 ) {
 	reload := make(chan bool, 1)
 	reload <- true
+	reloaded := false
 	for <-reload {
 		reload <- false
 
@@ -92,6 +96,7 @@ func reloadLoop(
 		c := config.NewConfig()
 		c.OutputFilters = outputFilters
 		c.InputFilters = inputFilters
+		c.Profiles = activeProfiles()
 		err := c.LoadConfig(*fConfig)
 		if err != nil {
 			log.Fatal("E! " + err.Error())
@@ -145,6 +150,11 @@ func reloadLoop(
 			log.Fatal("E! " + err.Error())
 		}
 
+		if reloaded {
+			events.Publish(events.ConfigReloaded, "")
+		}
+		reloaded = true
+
 		shutdown := make(chan struct{})
 		signals := make(chan os.Signal)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
@@ -230,6 +240,23 @@ func displayVersion() string {
 	return "v" + version
 }
 
+// activeProfiles returns the profiles of the --profiles flag, or of the
+// TELEGRAF_PROFILES environment variable if the flag is not set.
+func activeProfiles() []string {
+	value := *fProfiles
+	if value == "" {
+		value = os.Getenv("TELEGRAF_PROFILES")
+	}
+
+	var profiles []string
+	for _, profile := range strings.Split(value, ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
 func main() {
 	flag.Usage = func() { usageExit(0) }
 	flag.Parse()
@@ -274,6 +301,12 @@ func main() {
 			fmt.Printf("Telegraf %s (git: %s %s)\n", displayVersion(), branch, commit)
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				if !config.PrintCheck(*fConfig, *fConfigDirectory) {
+					os.Exit(1)
+				}
+				return
+			}
 			config.PrintSampleConfig(
 				inputFilters,
 				outputFilters,
@@ -281,6 +314,18 @@ func main() {
 				processorFilters,
 			)
 			return
+		case "plugins":
+			err := config.PrintPlugins(
+				displayVersion(),
+				inputFilters,
+				outputFilters,
+				aggregatorFilters,
+				processorFilters,
+			)
+			if err != nil {
+				log.Fatalf("E! %s", err)
+			}
+			return
 		}
 	}
 
@@ -29,3 +29,15 @@ type ServiceInput interface {
 	// Stop stops the services and closes any necessary channels and connections
 	Stop()
 }
+
+// SupervisedInput is implemented by the service inputs which report the
+// failure of their service, such as a listener which cannot accept
+// connections anymore, so that the agent restarts them.  The service inputs
+// which do not implement it are not restarted.
+type SupervisedInput interface {
+	ServiceInput
+
+	// Failed returns a channel receiving the error which ended the service
+	// started last.  Nothing is received once the service is stopped.
+	Failed() <-chan error
+}
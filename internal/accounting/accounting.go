@@ -0,0 +1,252 @@
+// accounting is a package for attributing the resource usage of telegraf to
+// its plugins.  The CPU time is sampled with the CPU profiler of the Go
+// runtime, the gathers of the inputs, including the goroutines started by the
+// service inputs, and the writes of the outputs being labelled with their
+// plugin.  The allocated bytes are sampled with the memory profiler, from the
+// plugin package found on the stack of the allocations.
+//
+// The usage is reported through the selfstat package as the cpu_time_ns and
+// alloc_bytes fields of the internal_gather and internal_write measurements.
+// The CPU time is reported by instance of the plugins, tagged with their
+// index.  The allocated bytes are summed over all instances of one type of
+// plugin, as the memory profiler does not record the labels.
+package accounting
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// The keys of the profiler labels holding the plugin name and the index of
+// its instance.
+const (
+	labelKey    = "plugin"
+	instanceKey = "instance"
+)
+
+// instance is a plugin instance, by the plugin name and its index.
+type instance struct {
+	plugin string
+	index  string
+}
+
+var enabled int32
+
+// Enable enables the labelling of the plugins by Do.  It must be called
+// before the plugins are started for their goroutines to be labelled.
+func Enable() {
+	atomic.StoreInt32(&enabled, 1)
+}
+
+// Enabled returns true if the accounting is enabled.
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// Do calls f, with its CPU time and the CPU time of the goroutines it starts
+// attributed to the instance of the plugin when the accounting is enabled.
+// The plugin is named by its type and name, eg. "inputs.syslog", and the
+// instance by its index among the plugins of the same name.
+func Do(plugin string, index int, f func()) {
+	if !Enabled() {
+		f()
+		return
+	}
+	doWithLabel(plugin, strconv.Itoa(index), f)
+}
+
+// Run samples the resource usage of the plugins until shutdown is closed.
+// The CPU profiler is run continuously, a new profile being started at each
+// interval, so the CPU profiles of the pprof endpoint fail with "cpu
+// profiling already in use" as long as Run runs.
+func Run(interval time.Duration, shutdown chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	a := newAccountant()
+	profiling := a.startCPUProfile()
+	for {
+		select {
+		case <-shutdown:
+			if profiling {
+				pprof.StopCPUProfile()
+			}
+			return
+		case <-ticker.C:
+			if profiling {
+				pprof.StopCPUProfile()
+				a.addCPUProfile()
+			}
+			a.setAllocs()
+			profiling = a.startCPUProfile()
+		}
+	}
+}
+
+type accountant struct {
+	profile bytes.Buffer
+	// busy is set while the CPU profiler is used by someone else.
+	busy bool
+	// plugins caches the plugin of the program counters of the stacks.
+	plugins map[uintptr]string
+	records []runtime.MemProfileRecord
+}
+
+func newAccountant() *accountant {
+	return &accountant{
+		plugins: make(map[uintptr]string),
+	}
+}
+
+// startCPUProfile starts a CPU profile window, returning false if the CPU
+// profiler is unavailable.
+func (a *accountant) startCPUProfile() bool {
+	if !cpuSupported {
+		return false
+	}
+	a.profile.Reset()
+	if err := pprof.StartCPUProfile(&a.profile); err != nil {
+		if !a.busy {
+			log.Printf("W! Resource accounting of the CPU time suspended: %s\n", err)
+			a.busy = true
+		}
+		return false
+	}
+	if a.busy {
+		log.Printf("I! Resource accounting of the CPU time resumed\n")
+		a.busy = false
+	}
+	return true
+}
+
+// addCPUProfile adds the CPU time of the plugins in the profile of the last
+// window to their stats.
+func (a *accountant) addCPUProfile() {
+	times, err := parseCPUProfile(a.profile.Bytes())
+	if err != nil {
+		log.Printf("E! Resource accounting failed to parse the CPU profile: %s\n", err)
+		return
+	}
+	for inst, ns := range times {
+		if stat := pluginStat(inst.plugin, inst.index, "cpu_time_ns"); stat != nil {
+			stat.Incr(ns)
+		}
+	}
+}
+
+// setAllocs sets the allocated bytes stats of the plugins to the bytes
+// allocated since start, as of the last garbage collection.  The stats are
+// not tagged with the instances, which the stacks do not tell apart.
+func (a *accountant) setAllocs() {
+	n, _ := runtime.MemProfile(nil, true)
+	for len(a.records) < n {
+		a.records = make([]runtime.MemProfileRecord, n+50)
+		n, _ = runtime.MemProfile(a.records, true)
+	}
+
+	allocs := make(map[string]int64)
+	rate := float64(runtime.MemProfileRate)
+	for _, r := range a.records[:n] {
+		plugin := a.stackPlugin(r.Stack())
+		if plugin == "" {
+			continue
+		}
+		allocs[plugin] += scaleAllocs(r.AllocObjects, r.AllocBytes, rate)
+	}
+	for plugin, b := range allocs {
+		if stat := pluginStat(plugin, "", "alloc_bytes"); stat != nil {
+			stat.Set(b)
+		}
+	}
+}
+
+// stackPlugin returns the plugin of the innermost frame of the stack in a
+// plugin package, or an empty string.
+func (a *accountant) stackPlugin(stack []uintptr) string {
+	for _, pc := range stack {
+		plugin, ok := a.plugins[pc]
+		if !ok {
+			if f := runtime.FuncForPC(pc - 1); f != nil {
+				plugin = funcPlugin(f.Name())
+			}
+			a.plugins[pc] = plugin
+		}
+		if plugin != "" {
+			return plugin
+		}
+	}
+	return ""
+}
+
+// scaleAllocs estimates the bytes allocated from the sampled allocations,
+// as the heap profiles of the pprof package do.
+func scaleAllocs(objects, size int64, rate float64) int64 {
+	if objects == 0 || size == 0 || rate <= 1 {
+		return size
+	}
+	avg := float64(size) / float64(objects)
+	return int64(float64(size) / (1 - math.Exp(-avg/rate)))
+}
+
+// funcPlugin returns the plugin of the package of a function, eg.
+// "inputs.syslog" for
+// "github.com/influxdata/telegraf/plugins/inputs/syslog.(*Syslog).Gather", or
+// an empty string.
+func funcPlugin(name string) string {
+	const pkg = "/telegraf/plugins/"
+	i := strings.Index(name, pkg)
+	if i < 0 {
+		return ""
+	}
+	name = name[i+len(pkg):]
+
+	i = strings.IndexByte(name, '/')
+	if i < 0 {
+		return ""
+	}
+	kind, name := name[:i], name[i+1:]
+	switch kind {
+	case "inputs", "outputs":
+	default:
+		return ""
+	}
+
+	if i := strings.IndexAny(name, "./"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name == "all" {
+		return ""
+	}
+	return kind + "." + name
+}
+
+// pluginStat returns the stat of the field of the plugin, tagged with the
+// index of the instance unless it is empty, or nil if the plugin is not an
+// input or an output.
+func pluginStat(plugin, index, field string) selfstat.Stat {
+	var measurement string
+	var tags map[string]string
+	switch {
+	case strings.HasPrefix(plugin, "inputs."):
+		measurement = "gather"
+		tags = map[string]string{"input": strings.TrimPrefix(plugin, "inputs.")}
+	case strings.HasPrefix(plugin, "outputs."):
+		measurement = "write"
+		tags = map[string]string{"output": strings.TrimPrefix(plugin, "outputs.")}
+	default:
+		return nil
+	}
+	if index != "" {
+		tags[instanceKey] = index
+	}
+	return selfstat.Register(measurement, field, tags)
+}
@@ -0,0 +1,132 @@
+package accounting
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncPlugin(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin string
+	}{
+		{"github.com/influxdata/telegraf/plugins/inputs/syslog.(*Syslog).Gather", "inputs.syslog"},
+		{"github.com/influxdata/telegraf/plugins/inputs/syslog.(*Syslog).listenStream.func1", "inputs.syslog"},
+		{"github.com/influxdata/telegraf/plugins/outputs/influxdb.(*InfluxDB).Write", "outputs.influxdb"},
+		{"github.com/influxdata/telegraf/plugins/inputs/snmp_legacy.init", "inputs.snmp_legacy"},
+		{"github.com/influxdata/telegraf/plugins/inputs/all.init", ""},
+		{"github.com/influxdata/telegraf/plugins/parsers/influx.(*Parser).Parse", ""},
+		{"github.com/influxdata/telegraf/agent.(*Agent).flush", ""},
+		{"runtime.mallocgc", ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.plugin, funcPlugin(tt.name), tt.name)
+	}
+}
+
+func TestScaleAllocs(t *testing.T) {
+	require.Equal(t, int64(0), scaleAllocs(0, 0, 512*1024))
+	require.Equal(t, int64(4096), scaleAllocs(1, 4096, 1))
+	// small allocations are sampled less often than large ones
+	require.True(t, scaleAllocs(100, 100*64, 512*1024) > 100*64*1000)
+	require.InDelta(t, 4<<20, scaleAllocs(1, 4<<20, 512*1024), 4096)
+}
+
+func TestPluginStat(t *testing.T) {
+	stat := pluginStat("inputs.syslog", "1", "cpu_time_ns")
+	require.Equal(t, "internal_gather", stat.Name())
+	require.Equal(t, "cpu_time_ns", stat.FieldName())
+	require.Equal(t, map[string]string{"input": "syslog", "instance": "1"}, stat.Tags())
+
+	stat = pluginStat("outputs.influxdb", "", "alloc_bytes")
+	require.Equal(t, "internal_write", stat.Name())
+	require.Equal(t, map[string]string{"output": "influxdb"}, stat.Tags())
+
+	require.Nil(t, pluginStat("processors.rename", "0", "alloc_bytes"))
+}
+
+func TestParseCPUProfileErrors(t *testing.T) {
+	times, err := parseCPUProfile(nil)
+	require.NoError(t, err)
+	require.Empty(t, times)
+
+	_, err = parseCPUProfile([]byte("not a profile"))
+	require.Error(t, err)
+}
+
+func TestDecodeMessage(t *testing.T) {
+	// field 1 varint 150, field 2 bytes "ab", field 3 fixed64, field 4 fixed32
+	data := []byte{0x08, 0x96, 0x01, 0x12, 0x02, 'a', 'b',
+		0x19, 1, 2, 3, 4, 5, 6, 7, 8, 0x25, 1, 2, 3, 4}
+	var fields []int
+	err := decodeMessage(data, func(field int, wire int, v uint64, b []byte) error {
+		fields = append(fields, field)
+		switch field {
+		case 1:
+			require.Equal(t, uint64(150), v)
+		case 2:
+			require.Equal(t, []byte("ab"), b)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 4}, fields)
+
+	err = decodeMessage(data[:5], func(int, int, uint64, []byte) error { return nil })
+	require.Equal(t, errTruncated, err)
+}
+
+func TestDoDisabled(t *testing.T) {
+	called := false
+	Do("inputs.test", 0, func() { called = true })
+	require.True(t, called)
+}
+
+func TestCPUTime(t *testing.T) {
+	if !cpuSupported {
+		t.Skip("profiler labels not supported")
+	}
+	if testing.Short() {
+		t.Skip("Skipping CPU profiling test in short mode")
+	}
+	Enable()
+
+	var buf bytes.Buffer
+	require.NoError(t, pprof.StartCPUProfile(&buf))
+	var wg sync.WaitGroup
+	Do("inputs.test", 0, func() {
+		// goroutines started by the plugin are accounted to it
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			spin(300 * time.Millisecond)
+		}()
+	})
+	Do("inputs.test", 1, func() {
+		spin(300 * time.Millisecond)
+	})
+	Do("outputs.test", 0, func() {
+		spin(300 * time.Millisecond)
+	})
+	wg.Wait()
+	pprof.StopCPUProfile()
+
+	times, err := parseCPUProfile(buf.Bytes())
+	require.NoError(t, err)
+	require.True(t, times[instance{"inputs.test", "0"}] > 0)
+	require.True(t, times[instance{"inputs.test", "1"}] > 0)
+	require.True(t, times[instance{"outputs.test", "0"}] > 0)
+	require.Equal(t, int64(0), times[instance{}])
+}
+
+func spin(d time.Duration) {
+	n := 0
+	for start := time.Now(); time.Since(start) < d; {
+		n++
+	}
+}
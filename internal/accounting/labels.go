@@ -0,0 +1,16 @@
+// +build go1.9
+
+package accounting
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// cpuSupported is true if the CPU profiles hold the labels of the samples.
+const cpuSupported = true
+
+func doWithLabel(plugin, index string, f func()) {
+	pprof.Do(context.Background(), pprof.Labels(labelKey, plugin, instanceKey, index),
+		func(context.Context) { f() })
+}
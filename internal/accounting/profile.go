@@ -0,0 +1,209 @@
+package accounting
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+)
+
+// The fields of the messages of the profile.proto format of the CPU profiles
+// read by parseCPUProfile.
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileStringTable = 6
+
+	valueTypeType = 1
+
+	sampleValue = 2
+	sampleLabel = 3
+
+	labelKeyField = 1
+	labelStr      = 2
+)
+
+var errTruncated = errors.New("truncated profile")
+
+type profileLabel struct {
+	key, str int64
+}
+
+type profileSampleRecord struct {
+	values []int64
+	labels []profileLabel
+}
+
+// parseCPUProfile returns the CPU time in nanoseconds of the samples of the
+// gzipped CPU profile by the values of their plugin and instance labels.
+func parseCPUProfile(data []byte) (map[instance]int64, error) {
+	times := make(map[instance]int64)
+	if len(data) == 0 {
+		return times, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []int64
+	var samples []profileSampleRecord
+	var strs []string
+	err = decodeMessage(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == profileSampleType && wire == 2:
+			var typ int64
+			err := decodeMessage(b, func(field int, wire int, v uint64, b []byte) error {
+				if field == valueTypeType && wire == 0 {
+					typ = int64(v)
+				}
+				return nil
+			})
+			types = append(types, typ)
+			return err
+		case field == profileSample && wire == 2:
+			s, err := decodeSample(b)
+			samples = append(samples, s)
+			return err
+		case field == profileStringTable && wire == 2:
+			strs = append(strs, string(b))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value := -1
+	for i, typ := range types {
+		if typ >= 0 && typ < int64(len(strs)) && strs[typ] == "cpu" {
+			value = i
+		}
+	}
+	if value < 0 {
+		return nil, errors.New("no cpu sample type in profile")
+	}
+
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(strs)) {
+			return ""
+		}
+		return strs[i]
+	}
+	for _, s := range samples {
+		if value >= len(s.values) {
+			continue
+		}
+		var inst instance
+		for _, l := range s.labels {
+			switch str(l.key) {
+			case labelKey:
+				inst.plugin = str(l.str)
+			case instanceKey:
+				inst.index = str(l.str)
+			}
+		}
+		if inst.plugin != "" {
+			times[inst] += s.values[value]
+		}
+	}
+	return times, nil
+}
+
+func decodeSample(data []byte) (profileSampleRecord, error) {
+	var s profileSampleRecord
+	err := decodeMessage(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == sampleValue && wire == 0:
+			s.values = append(s.values, int64(v))
+		case field == sampleValue && wire == 2:
+			// packed repeated values
+			for len(b) > 0 {
+				v, n := decodeVarint(b)
+				if n == 0 {
+					return errTruncated
+				}
+				s.values = append(s.values, int64(v))
+				b = b[n:]
+			}
+		case field == sampleLabel && wire == 2:
+			var l profileLabel
+			err := decodeMessage(b, func(field int, wire int, v uint64, b []byte) error {
+				switch {
+				case field == labelKeyField && wire == 0:
+					l.key = int64(v)
+				case field == labelStr && wire == 0:
+					l.str = int64(v)
+				}
+				return nil
+			})
+			s.labels = append(s.labels, l)
+			return err
+		}
+		return nil
+	})
+	return s, err
+}
+
+// decodeMessage calls f with the fields of the protocol buffers message, the
+// value v of the varint fields, or the bytes b of the length delimited ones.
+func decodeMessage(data []byte, f func(field int, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := decodeVarint(data)
+		if n == 0 {
+			return errTruncated
+		}
+		data = data[n:]
+
+		field, wire := int(key>>3), int(key&7)
+		var v uint64
+		var b []byte
+		switch wire {
+		case 0:
+			v, n = decodeVarint(data)
+			if n == 0 {
+				return errTruncated
+			}
+		case 1:
+			n = 8
+		case 2:
+			var l uint64
+			l, n = decodeVarint(data)
+			if n == 0 || uint64(len(data)-n) < l {
+				return errTruncated
+			}
+			b = data[n : n+int(l)]
+			n += int(l)
+		case 5:
+			n = 4
+		default:
+			return errors.New("unsupported wire type in profile")
+		}
+		if len(data) < n {
+			return errTruncated
+		}
+		data = data[n:]
+
+		if err := f(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeVarint returns the varint at the start of data and its length, or a
+// zero length if it is truncated.
+func decodeVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		v |= uint64(data[i]&0x7f) << (7 * uint(i))
+		if data[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
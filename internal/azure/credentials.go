@@ -0,0 +1,114 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/token"
+)
+
+const defaultLoginURL = "https://login.microsoftonline.com"
+
+// CredentialConfig is an Azure Active Directory application authenticating
+// with a client secret.
+type CredentialConfig struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+
+	// LoginURL overrides the Azure Active Directory endpoint.
+	LoginURL string
+}
+
+// TokenSource returns a TokenSource for access tokens to the given resource,
+// such as "https://management.azure.com/".
+func (c *CredentialConfig) TokenSource(resource string, client *http.Client) *TokenSource {
+	loginURL := c.LoginURL
+	if loginURL == "" {
+		loginURL = defaultLoginURL
+	}
+	return &TokenSource{
+		tokenURL: fmt.Sprintf("%s/%s/oauth2/token", loginURL, url.PathEscape(c.TenantID)),
+		form: url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {c.ClientID},
+			"client_secret": {c.ClientSecret},
+			"resource":      {resource},
+		},
+		client: client,
+	}
+}
+
+// TokenSource requests access tokens using the client credentials grant and
+// caches them until shortly before they expire.
+type TokenSource struct {
+	tokenURL string
+	form     url.Values
+	client   *http.Client
+
+	cache token.Cache
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   string `json:"expires_in"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token returns a valid access token, requesting a new one if needed.
+func (t *TokenSource) Token(ctx context.Context) (string, error) {
+	return t.cache.Token(func() (string, time.Time, error) {
+		return t.fetch(ctx)
+	})
+}
+
+// Invalidate discards the cached token, for example after a request was
+// rejected as unauthorized.
+func (t *TokenSource) Invalidate() {
+	t.cache.Invalidate()
+}
+
+func (t *TokenSource) fetch(ctx context.Context) (string, time.Time, error) {
+	req, err := http.NewRequest("POST", t.tokenURL, strings.NewReader(t.form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &errorResponse{}
+		json.NewDecoder(resp.Body).Decode(errResp)
+		if errResp.ErrorDescription != "" {
+			return "", time.Time{}, fmt.Errorf("requesting token failed: %s: %s", resp.Status,
+				errResp.ErrorDescription)
+		}
+		return "", time.Time{}, fmt.Errorf("requesting token failed: %s", resp.Status)
+	}
+
+	tok := &tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(tok); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresIn, err := strconv.Atoi(tok.ExpiresIn)
+	if err != nil {
+		expiresIn = 0
+	}
+	return tok.AccessToken, time.Now().Add(time.Duration(expiresIn) * time.Second), nil
+}
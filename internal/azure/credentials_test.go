@@ -0,0 +1,62 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenSource(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/tenant/oauth2/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		require.Equal(t, "client", r.PostForm.Get("client_id"))
+		require.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		require.Equal(t, "https://management.azure.com/", r.PostForm.Get("resource"))
+		requests++
+		w.Write([]byte(`{"access_token": "token", "expires_in": "3600"}`))
+	}))
+	defer ts.Close()
+
+	config := &CredentialConfig{
+		TenantID:     "tenant",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		LoginURL:     ts.URL,
+	}
+	source := config.TokenSource("https://management.azure.com/", http.DefaultClient)
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token", token)
+
+	// The token is cached until it is invalidated.
+	_, err = source.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, requests)
+
+	source.Invalidate()
+	_, err = source.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+}
+
+func TestTokenSourceError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "invalid_client", "error_description": "Invalid client secret"}`))
+	}))
+	defer ts.Close()
+
+	config := &CredentialConfig{TenantID: "tenant", LoginURL: ts.URL}
+	source := config.TokenSource("https://management.azure.com/", http.DefaultClient)
+
+	_, err := source.Token(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid client secret")
+}
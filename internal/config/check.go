@@ -0,0 +1,411 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// Severities of the diagnostics.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found in a configuration file.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Plugin   string `json:"plugin,omitempty"`
+	Option   string `json:"option,omitempty"`
+	Message  string `json:"message"`
+}
+
+// CheckResult is the result of checking configuration files.
+type CheckResult struct {
+	Valid       bool          `json:"valid"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+// lineRe matches the line number prefixed by the toml package to its errors.
+var lineRe = regexp.MustCompile(`^line (\d+): `)
+
+// checker collects the diagnostics of the files being checked.
+type checker struct {
+	file        string
+	diagnostics []*Diagnostic
+	loading     map[string]bool
+}
+
+// Check checks the configuration file, the files it includes and the files
+// of the directory, if not empty.  Unlike LoadConfig, it does not stop at
+// the first problem: all the options of all the plugins are checked, and
+// the plugins implementing telegraf.Validator check the consistency of
+// their options.  Plugins are checked whatever their profiles and if_env
+// conditions.
+func Check(path, directory string) *CheckResult {
+	ch := &checker{loading: make(map[string]bool)}
+	if path == "" && directory == "" {
+		var err error
+		if path, err = getDefaultConfigPath(); err != nil {
+			ch.add(SeverityError, 0, "", "", err.Error())
+		}
+	}
+	if path != "" {
+		ch.checkFile(path)
+	}
+	if directory != "" {
+		err := walkDirectory(directory, func(path string) error {
+			ch.checkFile(path)
+			return nil
+		})
+		if err != nil {
+			ch.file = directory
+			ch.add(SeverityError, 0, "", "", err.Error())
+		}
+	}
+
+	result := &CheckResult{Valid: true, Diagnostics: []*Diagnostic{}}
+	for _, d := range ch.diagnostics {
+		if d.Severity == SeverityError {
+			result.Valid = false
+		}
+		result.Diagnostics = append(result.Diagnostics, d)
+	}
+	return result
+}
+
+// PrintCheck checks the configuration and prints the result as JSON, it
+// returns false if the configuration is invalid.
+func PrintCheck(path, directory string) bool {
+	result := Check(path, directory)
+	octets, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Printf("{\"valid\": false, \"diagnostics\": [{\"severity\": \"error\", \"message\": %q}]}\n", err)
+		return false
+	}
+	fmt.Println(string(octets))
+	return result.Valid
+}
+
+func (ch *checker) add(severity string, line int, plugin, option, message string) {
+	if m := lineRe.FindStringSubmatch(message); m != nil {
+		fmt.Sscan(m[1], &line)
+		message = message[len(m[0]):]
+	}
+	ch.diagnostics = append(ch.diagnostics, &Diagnostic{
+		Severity: severity,
+		File:     ch.file,
+		Line:     line,
+		Plugin:   plugin,
+		Option:   option,
+		Message:  message,
+	})
+}
+
+func (ch *checker) checkFile(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	ch.loading[abs] = true
+	defer delete(ch.loading, abs)
+
+	ch.file = path
+	tbl, err := parseFile(path)
+	if err != nil {
+		ch.add(SeverityError, 0, "", "", err.Error())
+		return
+	}
+
+	if val, ok := tbl.Fields["include"]; ok {
+		paths, err := includedFiles(abs, val)
+		if err != nil {
+			ch.add(SeverityError, line(val), "", "include", err.Error())
+		}
+		for _, included := range paths {
+			if ch.loading[included] {
+				ch.add(SeverityError, line(val), "", "include", fmt.Sprintf("%s is included recursively", included))
+				continue
+			}
+			ch.checkFile(included)
+			ch.file = path
+		}
+	}
+
+	for _, name := range sortedKeys(tbl.Fields) {
+		val := tbl.Fields[name]
+		if name == "include" {
+			continue
+		}
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			ch.add(SeverityError, line(val), "", name, "invalid configuration, expecting a table")
+			continue
+		}
+
+		switch name {
+		case "agent":
+			ch.checkOptions("agent", subTable, func() interface{} { return &AgentConfig{} })
+		case "global_tags", "tags":
+			if err := toml.UnmarshalTable(subTable, map[string]string{}); err != nil {
+				ch.add(SeverityError, subTable.Line, name, "", err.Error())
+			}
+		case "inputs", "plugins", "outputs", "processors", "aggregators":
+			kind := name
+			if kind == "plugins" {
+				kind = "inputs"
+			}
+			for _, pluginName := range sortedKeys(subTable.Fields) {
+				switch pluginSubTable := subTable.Fields[pluginName].(type) {
+				case *ast.Table:
+					if kind == "processors" || kind == "aggregators" {
+						ch.add(SeverityError, pluginSubTable.Line, kind+"."+pluginName, "",
+							fmt.Sprintf("%s must be declared as [[%s.%s]]", pluginName, kind, pluginName))
+						continue
+					}
+					ch.checkPlugin(kind, pluginName, pluginSubTable)
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						ch.checkPlugin(kind, pluginName, t)
+					}
+				default:
+					ch.add(SeverityError, line(pluginSubTable), kind+"."+pluginName, "", "unsupported config format")
+				}
+			}
+		default:
+			// Legacy top level inputs
+			ch.checkPlugin("inputs", name, subTable)
+		}
+	}
+}
+
+// checkPlugin checks the options common to the plugins of the kind, then
+// each of the options of the plugin, and finally the consistency of the
+// options if the plugin is a telegraf.Validator.
+func (ch *checker) checkPlugin(kind, name string, tbl *ast.Table) {
+	plugin := kind + "." + name
+	if kind == "inputs" && name == "io" {
+		name = "diskio"
+	}
+	creator := pluginCreator(kind, name)
+	if creator == nil {
+		if tag, ok := excludedPlugin(kind, name); ok {
+			ch.add(SeverityWarning, tbl.Line, plugin, "",
+				fmt.Sprintf("excluded from this build by the %s build tag", tag))
+			return
+		}
+		ch.add(SeverityError, tbl.Line, plugin, "", fmt.Sprintf("unknown plugin %s", plugin))
+		return
+	}
+
+	if p, ok := creator().(telegraf.DeprecatedPlugin); ok {
+		since, notice := p.Deprecated()
+		ch.add(SeverityWarning, tbl.Line, plugin, "",
+			fmt.Sprintf("deprecated since version %s, %s", since, notice))
+	}
+
+	// The common options are removed from the table, as when loading the
+	// configuration.
+	if _, err := (&Config{}).inProfiles(tbl); err != nil {
+		ch.add(SeverityError, tbl.Line, plugin, "profiles", err.Error())
+	}
+	if _, err := envCondition(tbl); err != nil {
+		ch.add(SeverityError, tbl.Line, plugin, "if_env", err.Error())
+	}
+	if err := buildCommon(kind, name, creator(), tbl); err != nil {
+		ch.add(SeverityError, tbl.Line, plugin, "", err.Error())
+	}
+
+	if !ch.checkOptions(plugin, tbl, creator) {
+		return
+	}
+
+	instance := creator()
+	if err := toml.UnmarshalTable(tbl, instance); err != nil {
+		ch.add(SeverityError, tbl.Line, plugin, "", err.Error())
+		return
+	}
+	if v, ok := instance.(telegraf.Validator); ok {
+		if err := v.Validate(); err != nil {
+			ch.add(SeverityError, tbl.Line, plugin, "", err.Error())
+		}
+	}
+}
+
+// checkOptions unmarshals the options of the table one by one, so that all
+// the unknown options and invalid values are reported, and returns true if
+// they are all valid.
+func (ch *checker) checkOptions(plugin string, tbl *ast.Table, creator func() interface{}) bool {
+	durations := make(map[string]bool)
+	for _, opt := range options(reflect.ValueOf(creator())) {
+		if opt.Type == "duration" {
+			durations[opt.Name] = true
+		}
+	}
+
+	valid := true
+	for _, key := range sortedKeys(tbl.Fields) {
+		// internal.Duration ignores the values it cannot parse.
+		if durations[key] {
+			if err := checkDuration(tbl.Fields[key]); err != nil {
+				ch.add(SeverityError, line(tbl.Fields[key]), plugin, key, err.Error())
+				valid = false
+				continue
+			}
+		}
+
+		option := &ast.Table{
+			Name:   tbl.Name,
+			Line:   tbl.Line,
+			Fields: map[string]interface{}{key: tbl.Fields[key]},
+		}
+		if err := toml.UnmarshalTable(option, creator()); err != nil {
+			ch.add(SeverityError, line(tbl.Fields[key]), plugin, key, err.Error())
+			valid = false
+		}
+	}
+	return valid
+}
+
+// checkDuration returns an error if the value is a string which is not a
+// duration, integers and floats are durations in seconds.
+func checkDuration(node interface{}) error {
+	kv, ok := node.(*ast.KeyValue)
+	if !ok {
+		return nil
+	}
+	str, ok := kv.Value.(*ast.String)
+	if !ok {
+		return nil
+	}
+	_, err := time.ParseDuration(str.Value)
+	return err
+}
+
+// filterOptions are the options of buildFilter.
+var filterOptions = []string{"namedrop", "namepass", "fielddrop", "fieldpass",
+	"drop", "pass", "tagdrop", "tagpass", "tagexclude", "taginclude"}
+
+// commonOptions are the options common to the plugins of each kind, besides
+// the filters and the options of the parsers and serializers.
+var commonOptions = map[string][]string{
+	"inputs":      {"name_prefix", "name_suffix", "name_override", "interval", "schedule", "schedule_timezone", "tags"},
+	"outputs":     {"shadow", "max_body_size"},
+	"processors":  {"order"},
+	"aggregators": {"period", "delay", "drop_original", "name_prefix", "name_suffix", "name_override", "tags"},
+}
+
+// buildCommon builds the configuration common to the plugins of the kind,
+// and removes the common options from the table even if they are invalid.
+func buildCommon(kind, name string, plugin interface{}, tbl *ast.Table) error {
+	defer func() {
+		for _, key := range append(commonOptions[kind], filterOptions...) {
+			delete(tbl.Fields, key)
+		}
+	}()
+
+	switch kind {
+	case "inputs":
+		if _, ok := plugin.(parsers.ParserInput); ok {
+			if _, err := buildParser(name, tbl); err != nil {
+				return err
+			}
+		}
+		_, err := buildInput(name, tbl)
+		return err
+	case "outputs":
+		if _, ok := plugin.(serializers.SerializerOutput); ok {
+			if _, err := buildSerializer(name, tbl); err != nil {
+				return err
+			}
+		}
+		_, err := buildOutput(name, tbl)
+		return err
+	case "processors":
+		_, err := buildProcessor(name, tbl)
+		return err
+	case "aggregators":
+		_, err := buildAggregator(name, tbl)
+		return err
+	}
+	return nil
+}
+
+// pluginCreator returns a function creating the plugin, or nil if there is
+// no such plugin.
+func pluginCreator(kind, name string) func() interface{} {
+	switch kind {
+	case "inputs":
+		if creator, ok := inputs.Inputs[name]; ok {
+			return func() interface{} { return creator() }
+		}
+	case "outputs":
+		if creator, ok := outputs.Outputs[name]; ok {
+			return func() interface{} { return creator() }
+		}
+	case "processors":
+		if creator, ok := processors.Processors[name]; ok {
+			return func() interface{} { return creator() }
+		}
+	case "aggregators":
+		if creator, ok := aggregators.Aggregators[name]; ok {
+			return func() interface{} { return creator() }
+		}
+	}
+	return nil
+}
+
+// excludedPlugin returns the build tag excluding the plugin from the build,
+// if it is excluded.
+func excludedPlugin(kind, name string) (string, bool) {
+	var tag string
+	var ok bool
+	switch kind {
+	case "inputs":
+		tag, ok = inputs.Excluded[name]
+	case "outputs":
+		tag, ok = outputs.Excluded[name]
+	}
+	return tag, ok
+}
+
+// line returns the line of a node of the syntax tree.
+func line(node interface{}) int {
+	switch n := node.(type) {
+	case *ast.KeyValue:
+		return n.Line
+	case *ast.Table:
+		return n.Line
+	case []*ast.Table:
+		if len(n) > 0 {
+			return n[0].Line
+		}
+	}
+	return 0
+}
+
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
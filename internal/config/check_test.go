@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/stretchr/testify/require"
+)
+
+type checkedInput struct {
+	Mode string `toml:"mode"`
+	Port int    `toml:"port"`
+}
+
+func (*checkedInput) SampleConfig() string                  { return "" }
+func (*checkedInput) Description() string                   { return "" }
+func (*checkedInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (*checkedInput) Deprecated() (string, string)          { return "1.7", "use the other input" }
+
+func (c *checkedInput) Validate() error {
+	if c.Mode == "push" && c.Port == 0 {
+		return fmt.Errorf("port is required in push mode")
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("checked", func() telegraf.Input { return &checkedInput{} })
+}
+
+func TestCheck_Valid(t *testing.T) {
+	result := Check("./testdata/single_plugin.toml", "./testdata/subconfig")
+	require.True(t, result.Valid)
+	require.Empty(t, result.Diagnostics)
+}
+
+func TestCheck_Invalid(t *testing.T) {
+	result := Check("./testdata/check_invalid.toml", "")
+	require.False(t, result.Valid)
+
+	file := "./testdata/check_invalid.toml"
+	expected := []*Diagnostic{
+		{SeverityError, file, 2, "agent", "interval", `time: unknown unit "x" in duration "10x"`},
+		{SeverityError, file, 3, "agent", "unknown_agent_option", "field corresponding to `unknown_agent_option' is not defined in `*config.AgentConfig'"},
+		{SeverityWarning, file, 16, "inputs.checked", "", "deprecated since version 1.7, use the other input"},
+		{SeverityError, file, 16, "inputs.checked", "profiles", "profiles must be a list of profile names"},
+		{SeverityError, file, 16, "inputs.checked", "", "port is required in push mode"},
+		{SeverityError, file, 10, "inputs.exec", "", "Invalid data format: xml"},
+		{SeverityError, file, 5, "inputs.memcached", "", `time: unknown unit "q" in duration "5q"`},
+		{SeverityError, file, 7, "inputs.memcached", "unknown", "field corresponding to `unknown' is not defined in `*memcached.Memcached'"},
+		{SeverityError, file, 14, "inputs.nonexistent", "", "unknown plugin inputs.nonexistent"},
+		{SeverityError, file, 22, "outputs.file", "files", "file.File.Files: `string' type is not assignable to `[]string' type"},
+	}
+	require.Equal(t, expected, result.Diagnostics)
+}
+
+func TestCheck_Missing(t *testing.T) {
+	result := Check("./testdata/missing.toml", "")
+	require.False(t, result.Valid)
+	require.Len(t, result.Diagnostics, 1)
+	require.Equal(t, "./testdata/missing.toml", result.Diagnostics[0].File)
+}
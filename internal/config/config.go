@@ -20,6 +20,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/schedule"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -39,8 +40,9 @@ var (
 	// Default output plugins
 	outputDefaults = []string{"influxdb"}
 
-	// envVarRe is a regex to find environment variables in the config file
-	envVarRe = regexp.MustCompile(`\$\w+`)
+	// envVarRe is a regex to find environment variables in the config file,
+	// as $VAR, ${VAR} or ${VAR:-default}
+	envVarRe = regexp.MustCompile(`\$\{(\w+)(:-[^}]*)?\}|\$(\w+)`)
 
 	envVarEscaper = strings.NewReplacer(
 		`"`, `\"`,
@@ -55,6 +57,9 @@ type Config struct {
 	Tags          map[string]string
 	InputFilters  []string
 	OutputFilters []string
+	// Profiles are the active profiles, plugins with a profiles option are
+	// only loaded if one of their profiles is active
+	Profiles []string
 
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
@@ -62,6 +67,9 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// loading are the files being loaded, to detect include cycles
+	loading map[string]bool
 }
 
 func NewConfig() *Config {
@@ -145,6 +153,39 @@ type AgentConfig struct {
 	Quiet        bool
 	Hostname     string
 	OmitHostname bool
+
+	// ProbeAddress is the address to serve on-demand gathers of the inputs
+	// on. The probe service is disabled when empty.
+	ProbeAddress string
+
+	// ProbeToken is the bearer token required by the probe service. The
+	// probe service does not start without it.
+	ProbeToken string
+
+	// ControlAddress is the address to serve the control API on, either a
+	// TCP address or a unix socket as unix:///path. The control service is
+	// disabled when empty.
+	ControlAddress string
+
+	// ControlToken is the bearer token required by the control service. It
+	// is required when serving on TCP.
+	ControlToken string
+
+	// Statefile is the file used to persist the state of stateful plugins
+	// across restarts.  State is not persisted when empty.
+	Statefile string
+
+	// TLSMinVersion is the minimum TLS version of the TLS connections of all
+	// plugins, one of "TLS10", "TLS11" or "TLS12".
+	TLSMinVersion string `toml:"tls_min_version"`
+
+	// FIPS restricts the TLS connections of all plugins to the TLS versions,
+	// cipher suites and curves approved by FIPS 140-2.
+	FIPS bool `toml:"fips"`
+
+	// ResourceAccounting attributes the CPU time and the allocated bytes of
+	// the agent to the inputs and outputs, reported by the internal input.
+	ResourceAccounting bool `toml:"resource_accounting"`
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -258,6 +299,43 @@ var header = `# Telegraf Configuration
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address to serve on-demand gathers of the inputs on, the probe service
+  ## is disabled when empty.  A GET request to /probe gathers all inputs, or
+  ## only the named input with /probe?input=cpu, and returns the metrics in
+  ## influx or json format (/probe?format=json).
+  # probe_address = "localhost:8194"
+  ## Bearer token required in the Authorization header of probe requests,
+  ## the probe service does not start without it.
+  # probe_token = ""
+
+  ## Address to serve the control API on, used to pause and resume inputs
+  ## and to trigger a flush of the outputs at runtime.  Either a TCP address
+  ## or a unix socket such as "unix:///var/run/telegraf/control.sock".  The
+  ## control service is disabled when empty.
+  # control_address = ""
+  ## Bearer token required in the Authorization header of control requests,
+  ## required when serving on TCP.
+  # control_token = ""
+
+  ## File used to persist the state of plugins, such as the file offsets of
+  ## the tail input, across restarts.  State is not persisted when empty.
+  # statefile = ""
+
+  ## Minimum TLS version of the TLS connections of all plugins, one of
+  ## "TLS10", "TLS11" or "TLS12".  By default the minimum version of Go.
+  # tls_min_version = "TLS12"
+  ## Restrict the TLS connections of all plugins to the TLS versions, cipher
+  ## suites and curves approved by FIPS 140-2.  Build Telegraf with
+  ## "make telegraf-fips" to also use a FIPS validated crypto module.
+  # fips = false
+
+  ## Attribute the CPU time and the allocated bytes of the agent to the
+  ## inputs and outputs, as the cpu_time_ns and alloc_bytes fields of the
+  ## internal_gather and internal_write metrics of the internal input.  The
+  ## CPU profiles of the pprof endpoint of --pprof-addr fail while enabled,
+  ## as the CPU profiler is run continuously.
+  # resource_accounting = false
+
 
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
@@ -514,6 +592,11 @@ func PrintOutputConfig(name string) error {
 }
 
 func (c *Config) LoadDirectory(path string) error {
+	return walkDirectory(path, c.LoadConfig)
+}
+
+// walkDirectory calls fn with each *.conf file of the directory.
+func walkDirectory(path string, fn func(string) error) error {
 	walkfn := func(thispath string, info os.FileInfo, _ error) error {
 		if info == nil {
 			log.Printf("W! Telegraf is not permitted to read %s", thispath)
@@ -532,11 +615,7 @@ func (c *Config) LoadDirectory(path string) error {
 		if len(name) < 6 || name[len(name)-5:] != ".conf" {
 			return nil
 		}
-		err := c.LoadConfig(thispath)
-		if err != nil {
-			return err
-		}
-		return nil
+		return fn(thispath)
 	}
 	return filepath.Walk(path, walkfn)
 }
@@ -578,6 +657,14 @@ func (c *Config) LoadConfig(path string) error {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
 
+	// Load the included files first, so that this file can override their
+	// settings:
+	if val, ok := tbl.Fields["include"]; ok {
+		if err = c.loadIncludes(path, val); err != nil {
+			return fmt.Errorf("Error parsing %s, %s", path, err)
+		}
+	}
+
 	// Parse tags tables first:
 	for _, tableName := range []string{"tags", "global_tags"} {
 		if val, ok := tbl.Fields[tableName]; ok {
@@ -606,6 +693,9 @@ func (c *Config) LoadConfig(path string) error {
 
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
+		if name == "include" {
+			continue
+		}
 		subTable, ok := val.(*ast.Table)
 		if !ok {
 			return fmt.Errorf("%s: invalid configuration", path)
@@ -694,6 +784,146 @@ func (c *Config) LoadConfig(path string) error {
 	return nil
 }
 
+// loadIncludes loads the files of the include list of the config file at
+// path.  The included paths are relative to the directory of the file and
+// may be glob patterns.
+func (c *Config) loadIncludes(path string, val interface{}) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if c.loading == nil {
+		c.loading = make(map[string]bool)
+	}
+	c.loading[abs] = true
+	defer delete(c.loading, abs)
+
+	paths, err := includedFiles(abs, val)
+	if err != nil {
+		return err
+	}
+	for _, match := range paths {
+		if c.loading[match] {
+			return fmt.Errorf("%s is included recursively", match)
+		}
+		if err = c.LoadConfig(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// includedFiles returns the files matching the include option of the file
+// at the absolute path.
+func includedFiles(abs string, val interface{}) ([]string, error) {
+	var patterns []string
+	if kv, ok := val.(*ast.KeyValue); ok {
+		if ary, ok := kv.Value.(*ast.Array); ok {
+			for _, elem := range ary.Value {
+				if str, ok := elem.(*ast.String); ok {
+					patterns = append(patterns, str.Value)
+				}
+			}
+		}
+	}
+	if patterns == nil {
+		return nil, fmt.Errorf("include must be a list of files")
+	}
+
+	var paths []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(abs), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %s: %s", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("included file %s not found", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// enabled checks the profiles and the if_env condition of a plugin, and
+// removes them from the table.
+func (c *Config) enabled(tbl *ast.Table) (bool, error) {
+	inProfiles, err := c.inProfiles(tbl)
+	if err != nil {
+		return false, err
+	}
+	envEnabled, err := envCondition(tbl)
+	if err != nil {
+		return false, err
+	}
+	return inProfiles && envEnabled, nil
+}
+
+// inProfiles returns true if the plugin has no profiles option, or if one
+// of its profiles is active.
+func (c *Config) inProfiles(tbl *ast.Table) (bool, error) {
+	node, ok := tbl.Fields["profiles"]
+	if !ok {
+		return true, nil
+	}
+	delete(tbl.Fields, "profiles")
+
+	var profiles []string
+	if kv, ok := node.(*ast.KeyValue); ok {
+		if ary, ok := kv.Value.(*ast.Array); ok {
+			for _, elem := range ary.Value {
+				if str, ok := elem.(*ast.String); ok {
+					profiles = append(profiles, str.Value)
+				}
+			}
+		}
+	}
+	if len(profiles) == 0 {
+		return false, fmt.Errorf("profiles must be a list of profile names")
+	}
+
+	for _, profile := range profiles {
+		if sliceContains(profile, c.Profiles) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// envCondition checks the if_env condition of a plugin, and removes it from
+// the table.  The plugin is enabled if the environment variable is set and
+// not empty, or for "VAR=value" if the variable has the value.  A leading
+// "!" negates the condition.
+func envCondition(tbl *ast.Table) (bool, error) {
+	node, ok := tbl.Fields["if_env"]
+	if !ok {
+		return true, nil
+	}
+	delete(tbl.Fields, "if_env")
+
+	var cond string
+	if kv, ok := node.(*ast.KeyValue); ok {
+		if str, ok := kv.Value.(*ast.String); ok {
+			cond = str.Value
+		}
+	}
+	negate := strings.HasPrefix(cond, "!")
+	cond = strings.TrimPrefix(cond, "!")
+	if cond == "" {
+		return false, fmt.Errorf("if_env must be the name of an environment variable")
+	}
+
+	parts := strings.SplitN(cond, "=", 2)
+	value := os.Getenv(parts[0])
+	result := value != ""
+	if len(parts) == 2 {
+		result = value == parts[1]
+	}
+	return result != negate, nil
+}
+
 // trimBOM trims the Byte-Order-Marks from the beginning of the file.
 // this is for Windows compatibility only.
 // see https://github.com/influxdata/telegraf/issues/1378
@@ -717,19 +947,36 @@ func parseFile(fpath string) (*ast.Table, error) {
 	// ugh windows why
 	contents = trimBOM(contents)
 
-	env_vars := envVarRe.FindAll(contents, -1)
-	for _, env_var := range env_vars {
-		env_val, ok := os.LookupEnv(strings.TrimPrefix(string(env_var), "$"))
-		if ok {
-			env_val = escapeEnv(env_val)
-			contents = bytes.Replace(contents, env_var, []byte(env_val), 1)
+	return toml.Parse(substituteEnv(contents))
+}
+
+// substituteEnv replaces the environment variables in the contents.  Unset
+// variables are left as is, unless they have a default value which is also
+// used for variables set to an empty value.
+func substituteEnv(contents []byte) []byte {
+	return envVarRe.ReplaceAllFunc(contents, func(match []byte) []byte {
+		sub := envVarRe.FindSubmatch(match)
+		name := string(sub[1])
+		if name == "" {
+			name = string(sub[3])
 		}
-	}
 
-	return toml.Parse(contents)
+		value, ok := os.LookupEnv(name)
+		if len(sub[2]) > 0 && value == "" {
+			// The default is written as it should appear in the config.
+			return sub[2][len(":-"):]
+		}
+		if !ok {
+			return match
+		}
+		return []byte(escapeEnv(value))
+	})
 }
 
 func (c *Config) addAggregator(name string, table *ast.Table) error {
+	if ok, err := c.enabled(table); !ok {
+		return err
+	}
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
 		return fmt.Errorf("Undefined but requested aggregator: %s", name)
@@ -750,6 +997,9 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 }
 
 func (c *Config) addProcessor(name string, table *ast.Table) error {
+	if ok, err := c.enabled(table); !ok {
+		return err
+	}
 	creator, ok := processors.Processors[name]
 	if !ok {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
@@ -765,6 +1015,14 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return err
 	}
 
+	// Processors have no other way to report an invalid configuration than
+	// failing the load.
+	if v, ok := processor.(telegraf.Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("processors.%s: %s", name, err)
+		}
+	}
+
 	rf := &models.RunningProcessor{
 		Name:      name,
 		Processor: processor,
@@ -779,12 +1037,24 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if len(c.OutputFilters) > 0 && !sliceContains(name, c.OutputFilters) {
 		return nil
 	}
+	if ok, err := c.enabled(table); !ok {
+		return err
+	}
 	creator, ok := outputs.Outputs[name]
 	if !ok {
+		if tag, ok := outputs.Excluded[name]; ok {
+			log.Printf("W! Output %s is excluded from this build by the %s build tag, skipping it", name, tag)
+			return nil
+		}
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
 
+	outputConfig, err := buildOutput(name, table)
+	if err != nil {
+		return err
+	}
+
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
 	switch t := output.(type) {
@@ -794,11 +1064,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 			return err
 		}
 		t.SetSerializer(serializer)
-	}
-
-	outputConfig, err := buildOutput(name, table)
-	if err != nil {
-		return err
+		outputConfig.Serializer = serializer
 	}
 
 	if err := toml.UnmarshalTable(table, output); err != nil {
@@ -807,6 +1073,11 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	for _, o := range c.Outputs {
+		if o.Name == ro.Name {
+			ro.Index++
+		}
+	}
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -815,6 +1086,9 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if len(c.InputFilters) > 0 && !sliceContains(name, c.InputFilters) {
 		return nil
 	}
+	if ok, err := c.enabled(table); !ok {
+		return err
+	}
 	// Legacy support renaming io input to diskio
 	if name == "io" {
 		name = "diskio"
@@ -822,6 +1096,10 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 
 	creator, ok := inputs.Inputs[name]
 	if !ok {
+		if tag, ok := inputs.Excluded[name]; ok {
+			log.Printf("W! Input %s is excluded from this build by the %s build tag, skipping it", name, tag)
+			return nil
+		}
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
@@ -847,6 +1125,11 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	for _, i := range c.Inputs {
+		if i.Config.Name == rp.Config.Name {
+			rp.Index++
+		}
+	}
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -1144,6 +1427,35 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	location := time.Local
+	if node, ok := tbl.Fields["schedule_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				loc, err := time.LoadLocation(str.Value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid schedule_timezone: %s", err)
+				}
+				location = loc
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				sched, err := schedule.Parse(str.Value, location)
+				if err != nil {
+					return nil, fmt.Errorf("invalid schedule: %s", err)
+				}
+				cp.Schedule = sched
+			}
+		}
+	}
+
+	if cp.Schedule != nil && cp.Interval != 0 {
+		return nil, fmt.Errorf("only one of interval and schedule may be set")
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1181,6 +1493,8 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "schedule")
+	delete(tbl.Fields, "schedule_timezone")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -1343,108 +1657,53 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 // a serializers.Serializer object, and creates it, which can then be added onto
 // an Output object.
 func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error) {
-	c := &serializers.Config{TimestampUnits: time.Duration(1 * time.Second)}
+	c := &serializers.Config{}
 
-	if node, ok := tbl.Fields["data_format"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if str, ok := kv.Value.(*ast.String); ok {
-				c.DataFormat = str.Value
-			}
-		}
+	// The serializer options are moved out of the table of the output, so
+	// that each output has its own data format and options.
+	options := &ast.Table{
+		Name:   tbl.Name,
+		Fields: make(map[string]interface{}),
 	}
-
-	if c.DataFormat == "" {
-		c.DataFormat = "influx"
-	}
-
-	if node, ok := tbl.Fields["prefix"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if str, ok := kv.Value.(*ast.String); ok {
-				c.Prefix = str.Value
-			}
-		}
-	}
-
-	if node, ok := tbl.Fields["template"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if str, ok := kv.Value.(*ast.String); ok {
-				c.Template = str.Value
-			}
-		}
-	}
-
-	if node, ok := tbl.Fields["influx_max_line_bytes"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if integer, ok := kv.Value.(*ast.Integer); ok {
-				v, err := integer.Int()
-				if err != nil {
-					return nil, err
-				}
-				c.InfluxMaxLineBytes = int(v)
-			}
-		}
-	}
-
-	if node, ok := tbl.Fields["influx_sort_fields"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if b, ok := kv.Value.(*ast.Boolean); ok {
-				var err error
-				c.InfluxSortFields, err = b.Boolean()
-				if err != nil {
-					return nil, err
-				}
-			}
+	for _, key := range serializers.ConfigKeys() {
+		if node, ok := tbl.Fields[key]; ok {
+			options.Fields[key] = node
+			delete(tbl.Fields, key)
 		}
 	}
-
-	if node, ok := tbl.Fields["influx_uint_support"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if b, ok := kv.Value.(*ast.Boolean); ok {
-				var err error
-				c.InfluxUintSupport, err = b.Boolean()
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	if err := toml.UnmarshalTable(options, c); err != nil {
+		return nil, err
 	}
 
-	if node, ok := tbl.Fields["graphite_tag_support"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if b, ok := kv.Value.(*ast.Boolean); ok {
-				var err error
-				c.GraphiteTagSupport, err = b.Boolean()
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	if c.DataFormat == "" {
+		c.DataFormat = "influx"
 	}
 
-	if node, ok := tbl.Fields["json_timestamp_units"]; ok {
-		if kv, ok := node.(*ast.KeyValue); ok {
-			if str, ok := kv.Value.(*ast.String); ok {
-				timestampVal, err := time.ParseDuration(str.Value)
-				if err != nil {
-					return nil, fmt.Errorf("Unable to parse json_timestamp_units as a duration, %s", err)
+	// json_timestamp_units is the legacy name of timestamp_units, which is
+	// used if both are set.
+	for _, field := range []string{"json_timestamp_units", "timestamp_units"} {
+		if node, ok := tbl.Fields[field]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					timestampVal, err := time.ParseDuration(str.Value)
+					if err != nil {
+						return nil, fmt.Errorf("Unable to parse %s as a duration, %s", field, err)
+					}
+					if timestampVal <= 0 {
+						return nil, fmt.Errorf("%s must be positive, found %s", field, str.Value)
+					}
+					// now that we have a duration, truncate it to the nearest
+					// power of ten (just in case)
+					nearest_exponent := int64(math.Log10(float64(timestampVal.Nanoseconds())))
+					new_nanoseconds := int64(math.Pow(10.0, float64(nearest_exponent)))
+					c.TimestampUnits = time.Duration(new_nanoseconds)
 				}
-				// now that we have a duration, truncate it to the nearest
-				// power of ten (just in case)
-				nearest_exponent := int64(math.Log10(float64(timestampVal.Nanoseconds())))
-				new_nanoseconds := int64(math.Pow(10.0, float64(nearest_exponent)))
-				c.TimestampUnits = time.Duration(new_nanoseconds)
 			}
 		}
 	}
 
-	delete(tbl.Fields, "influx_max_line_bytes")
-	delete(tbl.Fields, "influx_sort_fields")
-	delete(tbl.Fields, "influx_uint_support")
-	delete(tbl.Fields, "graphite_tag_support")
-	delete(tbl.Fields, "data_format")
-	delete(tbl.Fields, "prefix")
-	delete(tbl.Fields, "template")
 	delete(tbl.Fields, "json_timestamp_units")
+	delete(tbl.Fields, "timestamp_units")
 	return serializers.NewSerializer(c)
 }
 
@@ -1468,5 +1727,34 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	if len(oc.Filter.FieldPass) > 0 {
 		oc.Filter.NamePass = oc.Filter.FieldPass
 	}
+
+	if node, ok := tbl.Fields["shadow"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				oc.Shadow, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_body_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				if v < 0 {
+					return nil, fmt.Errorf("max_body_size must not be negative: %d", v)
+				}
+				oc.MaxBodySize = int(v)
+			}
+		}
+	}
+
+	delete(tbl.Fields, "shadow")
+	delete(tbl.Fields, "max_body_size")
 	return oc, nil
 }
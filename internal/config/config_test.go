@@ -2,17 +2,23 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	_ "github.com/influxdata/telegraf/plugins/outputs/file"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	_ "github.com/influxdata/telegraf/plugins/processors/strings"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
@@ -174,3 +180,208 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_LoadSchedule(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/schedule.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	sched := c.Inputs[0].Config.Schedule
+	require.NotNil(t, sched)
+	assert.Equal(t, time.UTC, sched.Location())
+
+	// Friday evening, the next activation is on Monday.
+	from := time.Date(2018, 6, 1, 20, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2018, 6, 4, 2, 30, 0, 0, time.UTC), sched.Next(from))
+}
+
+func TestConfig_LoadScheduleWithInterval(t *testing.T) {
+	c := NewConfig()
+	require.Error(t, c.LoadConfig("./testdata/schedule_with_interval.toml"))
+}
+
+func TestConfig_LoadSerializers(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/serializers.toml"))
+	require.Len(t, c.Outputs, 3)
+
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"b": int64(1), "a": uint64(2)},
+		time.Unix(1, 500000000))
+	require.NoError(t, err)
+
+	expected := []string{
+		"cpu,host=localhost a=2u,b=1i 1500000000\n",
+		`{"fields":{"a":2,"b":1},"name":"cpu","tags":{"host":"localhost"},"timestamp":1}` + "\n",
+		"telegraf.cpu.localhost.a 2 1\ntelegraf.cpu.localhost.b 1 1\n",
+	}
+	for i, output := range c.Outputs {
+		require.NotNil(t, output.Config.Serializer)
+		buf, err := output.Config.Serializer.Serialize(m)
+		require.NoError(t, err)
+		if i == 2 {
+			// The graphite fields are not sorted.
+			require.ElementsMatch(t, strings.SplitAfter(expected[i], "\n"),
+				strings.SplitAfter(string(buf), "\n"))
+			continue
+		}
+		assert.Equal(t, expected[i], string(buf))
+	}
+}
+
+func TestConfig_LoadSerializersInvalid(t *testing.T) {
+	c := NewConfig()
+	require.Error(t, c.LoadConfig("./testdata/serializers_invalid.toml"))
+}
+
+func TestConfig_LoadIncludes(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/include/main.toml"))
+
+	// The including file overrides the settings of the included files.
+	require.Equal(t, 20*time.Second, c.Agent.Interval.Duration)
+	require.Equal(t, 15*time.Second, c.Agent.FlushInterval.Duration)
+	require.Equal(t, map[string]string{"dc": "us-east-1"}, c.Tags)
+
+	require.Len(t, c.Inputs, 3)
+	require.Equal(t, "/usr/bin/mycollector --foo=bar", c.Inputs[0].Input.(*exec.Exec).Command)
+	require.Equal(t, []string{"localhost"}, c.Inputs[1].Input.(*memcached.Memcached).Servers)
+	require.Equal(t, "/var/run/grafana-server.pid", c.Inputs[2].Input.(*procstat.Procstat).PidFile)
+}
+
+func TestConfig_LoadIncludesInvalid(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/include/cycle.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cycle.toml is included recursively")
+
+	c = NewConfig()
+	err = c.LoadConfig("./testdata/include/missing.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "nonexistent.toml not found")
+}
+
+func TestConfig_LoadEnvConditionals(t *testing.T) {
+	for _, name := range []string{"TEST_MEMCACHED_HOST", "TEST_MEMCACHED_PORT",
+		"TEST_ENABLE_EXEC", "TEST_ENABLE_FILE", "TEST_ROLE"} {
+		require.NoError(t, os.Unsetenv(name))
+	}
+	os.Setenv("TEST_MEMCACHED_PORT", "")
+	os.Setenv("TEST_ENABLE_EXEC", "1")
+	os.Setenv("TEST_ROLE", "db")
+	defer func() {
+		os.Unsetenv("TEST_MEMCACHED_PORT")
+		os.Unsetenv("TEST_ENABLE_EXEC")
+		os.Unsetenv("TEST_ROLE")
+	}()
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/env_conditionals.toml"))
+
+	require.Len(t, c.Inputs, 3)
+	require.Equal(t, []string{"localhost:11211"}, c.Inputs[0].Input.(*memcached.Memcached).Servers)
+	require.Equal(t, "/usr/bin/mycollector", c.Inputs[1].Input.(*exec.Exec).Command)
+	require.Equal(t, "/var/run/mysqld.pid", c.Inputs[2].Input.(*procstat.Procstat).PidFile)
+	require.Empty(t, c.Outputs)
+}
+
+func TestSubstituteEnv(t *testing.T) {
+	os.Setenv("TEST_SUBST", `a"b`)
+	os.Setenv("TEST_SUBST_EMPTY", "")
+	os.Unsetenv("TEST_SUBST_UNSET")
+	defer os.Unsetenv("TEST_SUBST")
+	defer os.Unsetenv("TEST_SUBST_EMPTY")
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`v = "$TEST_SUBST"`, `v = "a\"b"`},
+		{`v = "${TEST_SUBST}"`, `v = "a\"b"`},
+		{`v = "${TEST_SUBST:-x}"`, `v = "a\"b"`},
+		{`v = "${TEST_SUBST_EMPTY:-x}"`, `v = "x"`},
+		{`v = "${TEST_SUBST_UNSET:-x y}"`, `v = "x y"`},
+		{`v = "${TEST_SUBST_UNSET:-}"`, `v = ""`},
+		{`v = "$TEST_SUBST_UNSET ${TEST_SUBST_UNSET}"`, `v = "$TEST_SUBST_UNSET ${TEST_SUBST_UNSET}"`},
+		{`v = "$TEST_SUBST_EMPTY"`, `v = ""`},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, string(substituteEnv([]byte(tt.in))), tt.in)
+	}
+}
+
+func TestConfig_LoadProfiles(t *testing.T) {
+	pidFiles := func(c *Config) []string {
+		var files []string
+		for _, input := range c.Inputs {
+			if p, ok := input.Input.(*procstat.Procstat); ok {
+				files = append(files, p.PidFile)
+			}
+		}
+		return files
+	}
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/profiles.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Empty(t, c.Outputs)
+
+	c = NewConfig()
+	c.Profiles = []string{"role:web"}
+	require.NoError(t, c.LoadConfig("./testdata/profiles.toml"))
+	require.Len(t, c.Inputs, 2)
+	require.Equal(t, []string{"/var/run/nginx.pid"}, pidFiles(c))
+	require.Empty(t, c.Outputs)
+
+	c = NewConfig()
+	c.Profiles = []string{"role:all", "env:dev"}
+	require.NoError(t, c.LoadConfig("./testdata/profiles.toml"))
+	require.Equal(t, []string{"/var/run/mysqld.pid"}, pidFiles(c))
+	require.Len(t, c.Outputs, 1)
+}
+
+func TestConfig_LoadExcluded(t *testing.T) {
+	inputs.AddExcluded("excluded", "noexcluded")
+	outputs.AddExcluded("excluded", "noexcluded")
+	defer delete(inputs.Excluded, "excluded")
+	defer delete(outputs.Excluded, "excluded")
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/excluded.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Empty(t, c.Outputs)
+
+	result := Check("./testdata/excluded.toml", "")
+	require.True(t, result.Valid)
+	require.Len(t, result.Diagnostics, 2)
+	require.Equal(t, SeverityWarning, result.Diagnostics[0].Severity)
+	require.Equal(t, "excluded from this build by the noexcluded build tag", result.Diagnostics[0].Message)
+}
+
+func TestConfig_LoadInstances(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/instances.toml"))
+
+	require.Len(t, c.Inputs, 3)
+	servers := make(map[string]int)
+	for _, input := range c.Inputs {
+		switch p := input.Input.(type) {
+		case *memcached.Memcached:
+			servers[p.Servers[0]] = input.Index
+		case *exec.Exec:
+			require.Equal(t, 0, input.Index)
+		}
+	}
+	require.Equal(t, map[string]int{"localhost:11211": 0, "localhost:11212": 1}, servers)
+	require.Len(t, c.Outputs, 2)
+	require.Equal(t, 0, c.Outputs[0].Index)
+	require.Equal(t, 1, c.Outputs[1].Index)
+}
+
+func TestConfig_LoadInvalidProcessor(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/processor_invalid.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "processors.strings: hmac requires a key")
+}
@@ -0,0 +1,372 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/processors"
+
+	"github.com/influxdata/toml/ast"
+	"github.com/naoina/go-stringutil"
+)
+
+// dataFormatOptions are the options of each data format of the parsers, as
+// read by buildParser.
+var dataFormatOptions = map[string][]string{
+	"collectd":   {"collectd_auth_file", "collectd_security_level", "collectd_typesdb"},
+	"dropwizard": {"dropwizard_metric_registry_path", "dropwizard_time_path", "dropwizard_time_format", "dropwizard_tags_path", "dropwizard_tag_paths", "separator", "templates"},
+	"graphite":   {"separator", "templates"},
+	"influx":     {},
+	"json":       {"tag_keys"},
+	"nagios":     {},
+	"value":      {"data_type"},
+}
+
+// PluginList describes the plugins compiled into Telegraf.
+type PluginList struct {
+	Version     string        `json:"version"`
+	Inputs      []*PluginInfo `json:"inputs"`
+	Outputs     []*PluginInfo `json:"outputs"`
+	Processors  []*PluginInfo `json:"processors"`
+	Aggregators []*PluginInfo `json:"aggregators"`
+	Parsers     []*PluginInfo `json:"parsers"`
+}
+
+// PluginInfo describes a plugin and its options.
+type PluginInfo struct {
+	Name         string        `json:"name"`
+	Description  string        `json:"description,omitempty"`
+	Service      bool          `json:"service,omitempty"`
+	Options      []*OptionInfo `json:"options"`
+	Deprecated   *Deprecation  `json:"deprecated,omitempty"`
+	Dependencies []string      `json:"dependencies,omitempty"`
+}
+
+// OptionInfo describes an option of a plugin.  The type is one of "string",
+// "integer", "float", "boolean", "duration", "array" or "table".
+type OptionInfo struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type,omitempty"`
+	Default interface{} `json:"default,omitempty"`
+}
+
+// Deprecation describes why a plugin is deprecated.
+type Deprecation struct {
+	Since  string `json:"since"`
+	Notice string `json:"notice"`
+}
+
+// Plugins returns the description of the plugins matching the filters, all
+// the plugins of a type are listed when its filter is empty.
+func Plugins(
+	version string,
+	inputFilters []string,
+	outputFilters []string,
+	aggregatorFilters []string,
+	processorFilters []string,
+) *PluginList {
+	list := &PluginList{
+		Version:     version,
+		Inputs:      []*PluginInfo{},
+		Outputs:     []*PluginInfo{},
+		Processors:  []*PluginInfo{},
+		Aggregators: []*PluginInfo{},
+		Parsers:     []*PluginInfo{},
+	}
+
+	for _, name := range filteredNames(inputs.Inputs, inputFilters) {
+		input := inputs.Inputs[name]()
+		info := pluginInfo(name, input)
+		info.Description = input.Description()
+		_, info.Service = input.(telegraf.ServiceInput)
+		list.Inputs = append(list.Inputs, info)
+	}
+	for _, name := range filteredNames(outputs.Outputs, outputFilters) {
+		output := outputs.Outputs[name]()
+		info := pluginInfo(name, output)
+		info.Description = output.Description()
+		list.Outputs = append(list.Outputs, info)
+	}
+	for _, name := range filteredNames(processors.Processors, processorFilters) {
+		processor := processors.Processors[name]()
+		info := pluginInfo(name, processor)
+		info.Description = processor.Description()
+		list.Processors = append(list.Processors, info)
+	}
+	for _, name := range filteredNames(aggregators.Aggregators, aggregatorFilters) {
+		aggregator := aggregators.Aggregators[name]()
+		info := pluginInfo(name, aggregator)
+		info.Description = aggregator.Description()
+		list.Aggregators = append(list.Aggregators, info)
+	}
+
+	for _, name := range filteredNames(dataFormatOptions, nil) {
+		info := &PluginInfo{Name: name, Options: []*OptionInfo{}}
+		for _, option := range dataFormatOptions[name] {
+			info.Options = append(info.Options, &OptionInfo{Name: option})
+		}
+		list.Parsers = append(list.Parsers, info)
+	}
+	return list
+}
+
+// PrintPlugins prints the description of the plugins as JSON.
+func PrintPlugins(
+	version string,
+	inputFilters []string,
+	outputFilters []string,
+	aggregatorFilters []string,
+	processorFilters []string,
+) error {
+	list := Plugins(version, inputFilters, outputFilters, aggregatorFilters, processorFilters)
+	octets, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(octets))
+	return nil
+}
+
+// filteredNames returns the sorted keys of a plugin registry which are in
+// the filters, or all of them if there are no filters.
+func filteredNames(registry interface{}, filters []string) []string {
+	var names []string
+	for _, key := range reflect.ValueOf(registry).MapKeys() {
+		name := key.String()
+		if len(filters) == 0 || sliceContains(name, filters) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pluginInfo(name string, plugin interface{}) *PluginInfo {
+	info := &PluginInfo{Name: name}
+	info.Options = options(reflect.ValueOf(plugin))
+	if p, ok := plugin.(telegraf.DeprecatedPlugin); ok {
+		since, notice := p.Deprecated()
+		info.Deprecated = &Deprecation{Since: since, Notice: notice}
+	}
+	if p, ok := plugin.(telegraf.DependentPlugin); ok {
+		info.Dependencies = p.Dependencies()
+	}
+	return info
+}
+
+var durationType = reflect.TypeOf(internal.Duration{})
+
+// options returns the options of a plugin, named the way the toml package
+// reads them: from the toml tag of the field or else in snake case.  The
+// fields of embedded structs, such as the TLS configuration, are options of
+// the plugin.
+func options(v reflect.Value) []*OptionInfo {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return []*OptionInfo{}
+		}
+		v = v.Elem()
+	}
+	opts := []*OptionInfo{}
+	if v.Kind() != reflect.Struct {
+		return opts
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := field.Tag.Get("toml")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			opts = append(opts, options(v.Field(i))...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = stringutil.ToSnakeCase(field.Name)
+		}
+
+		typ, def := optionType(v.Field(i))
+		if typ == "" {
+			continue
+		}
+		opts = append(opts, &OptionInfo{Name: name, Type: typ, Default: def})
+	}
+	return opts
+}
+
+// optionType returns the type of an option and its default value, if it is
+// set and representable in JSON.  An empty type is returned for fields that
+// cannot be set from the configuration.
+func optionType(v reflect.Value) (string, interface{}) {
+	if v.Type() == durationType {
+		if !v.CanInterface() || v.Interface().(internal.Duration).Duration == 0 {
+			return "duration", nil
+		}
+		return "duration", v.Interface().(internal.Duration).Duration.String()
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return "duration", nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			typ, _ := optionType(reflect.New(v.Type().Elem()).Elem())
+			return typ, nil
+		}
+		return optionType(v.Elem())
+	case reflect.String:
+		return "string", nonZero(v)
+	case reflect.Bool:
+		return "boolean", nonZero(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nonZero(v)
+	case reflect.Float32, reflect.Float64:
+		return "float", nonZero(v)
+	case reflect.Slice, reflect.Array:
+		if v.CanInterface() && v.Len() > 0 && isScalar(v.Type().Elem()) {
+			return "array", v.Interface()
+		}
+		return "array", nil
+	case reflect.Struct, reflect.Map:
+		return "table", nil
+	}
+	return "", nil
+}
+
+// nonZero returns the value, or nil if it is the zero value of its type or
+// it is not accessible, such as the fields of unexported embedded structs.
+func nonZero(v reflect.Value) interface{} {
+	if !v.CanInterface() || v.Interface() == reflect.Zero(v.Type()).Interface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// ReferencedPlugins returns the sorted names of the plugins of each type,
+// "inputs", "outputs", "processors" and "aggregators", declared in the
+// configuration files, the files they include and the files of the
+// directory, if not empty.  The plugins are not loaded, so they do not need
+// to be compiled in, and are returned whatever their profiles and if_env
+// conditions.
+func ReferencedPlugins(paths []string, directory string) (map[string][]string, error) {
+	r := &referencedPlugins{
+		names:   make(map[string]map[string]bool),
+		loading: make(map[string]bool),
+	}
+	for _, path := range paths {
+		if err := r.add(path); err != nil {
+			return nil, err
+		}
+	}
+	if directory != "" {
+		if err := walkDirectory(directory, r.add); err != nil {
+			return nil, err
+		}
+	}
+
+	referenced := make(map[string][]string)
+	for _, kind := range []string{"inputs", "outputs", "processors", "aggregators"} {
+		names := []string{}
+		for name := range r.names[kind] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		referenced[kind] = names
+	}
+	return referenced, nil
+}
+
+type referencedPlugins struct {
+	names   map[string]map[string]bool
+	loading map[string]bool
+}
+
+func (r *referencedPlugins) add(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	r.loading[abs] = true
+	defer delete(r.loading, abs)
+
+	tbl, err := parseFile(path)
+	if err != nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
+	if val, ok := tbl.Fields["include"]; ok {
+		included, err := includedFiles(abs, val)
+		if err != nil {
+			return fmt.Errorf("Error parsing %s, %s", path, err)
+		}
+		for _, match := range included {
+			if r.loading[match] {
+				return fmt.Errorf("%s is included recursively", match)
+			}
+			if err := r.add(match); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, val := range tbl.Fields {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "agent", "global_tags", "tags":
+		case "inputs", "plugins", "outputs", "processors", "aggregators":
+			kind := name
+			if kind == "plugins" {
+				kind = "inputs"
+			}
+			for pluginName := range subTable.Fields {
+				r.reference(kind, pluginName)
+			}
+		default:
+			// Legacy top level inputs
+			r.reference("inputs", name)
+		}
+	}
+	return nil
+}
+
+func (r *referencedPlugins) reference(kind, name string) {
+	if kind == "inputs" && name == "io" {
+		name = "diskio"
+	}
+	if r.names[kind] == nil {
+		r.names[kind] = make(map[string]bool)
+	}
+	r.names[kind][name] = true
+}
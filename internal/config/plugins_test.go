@@ -0,0 +1,108 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/stretchr/testify/require"
+)
+
+type testTLS struct {
+	TLSCA string `toml:"tls_ca"`
+}
+
+type testPlugin struct {
+	Servers []string
+	Timeout internal.Duration
+	Port    int   `toml:"port"`
+	Enabled *bool `toml:"enabled"`
+	Tags    map[string]string
+	Ignored string `toml:"-"`
+	Label   string `toml:"name"`
+	testTLS
+
+	count int
+}
+
+func (*testPlugin) Deprecated() (string, string) {
+	return "1.7", "use the other plugin"
+}
+
+func (*testPlugin) Dependencies() []string {
+	return []string{"testctl"}
+}
+
+func TestPluginInfo(t *testing.T) {
+	p := &testPlugin{
+		Servers: []string{"localhost"},
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	info := pluginInfo("test", p)
+
+	require.Equal(t, []*OptionInfo{
+		{Name: "servers", Type: "array", Default: []string{"localhost"}},
+		{Name: "timeout", Type: "duration", Default: "5s"},
+		{Name: "port", Type: "integer"},
+		{Name: "enabled", Type: "boolean"},
+		{Name: "tags", Type: "table"},
+		{Name: "name", Type: "string"},
+		{Name: "tls_ca", Type: "string"},
+	}, info.Options)
+	require.Equal(t, &Deprecation{Since: "1.7", Notice: "use the other plugin"}, info.Deprecated)
+	require.Equal(t, []string{"testctl"}, info.Dependencies)
+}
+
+func TestPlugins(t *testing.T) {
+	list := Plugins("1.7.0", []string{"memcached", "exec"}, []string{"file"}, []string{"none"}, []string{"none"})
+
+	require.Equal(t, "1.7.0", list.Version)
+	require.Len(t, list.Inputs, 2)
+	require.Equal(t, "exec", list.Inputs[0].Name)
+	require.Equal(t, "memcached", list.Inputs[1].Name)
+	require.Contains(t, list.Inputs[1].Options, &OptionInfo{Name: "gather_slabs", Type: "boolean"})
+	require.Len(t, list.Outputs, 1)
+	require.Empty(t, list.Processors)
+	require.Empty(t, list.Aggregators)
+
+	var formats []string
+	for _, parser := range list.Parsers {
+		formats = append(formats, parser.Name)
+	}
+	require.Equal(t, []string{"collectd", "dropwizard", "graphite", "influx", "json", "nagios", "value"}, formats)
+}
+
+func TestOptionTypes(t *testing.T) {
+	var s struct {
+		F float64
+		U uint64
+		D time.Duration
+		C chan int
+	}
+	opts := options(reflect.ValueOf(&s))
+	require.Equal(t, []*OptionInfo{
+		{Name: "f", Type: "float"},
+		{Name: "u", Type: "integer"},
+		{Name: "d", Type: "duration"},
+	}, opts)
+}
+
+func TestReferencedPlugins(t *testing.T) {
+	referenced, err := ReferencedPlugins([]string{"./testdata/include/main.toml"}, "./testdata/subconfig")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"inputs":      {"exec", "memcached", "procstat"},
+		"outputs":     {},
+		"processors":  {},
+		"aggregators": {},
+	}, referenced)
+
+	referenced, err = ReferencedPlugins([]string{"./testdata/telegraf-agent.toml"}, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"influxdb", "kafka"}, referenced["outputs"])
+	require.Contains(t, referenced["inputs"], "diskio")
+
+	_, err = ReferencedPlugins([]string{"./testdata/include/cycle.toml"}, "")
+	require.Error(t, err)
+}
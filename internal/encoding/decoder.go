@@ -0,0 +1,114 @@
+// Package encoding converts the text received by the inputs in the
+// character encoding of their character_encoding option to UTF-8, before it
+// is parsed.
+package encoding
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// charset is a character encoding of the character_encoding option.
+type charset struct {
+	newTransformer func() transform.Transformer
+	// utf16 is set for the encodings of 2 bytes code units.
+	utf16 bool
+}
+
+func decoderOf(e encoding.Encoding) func() transform.Transformer {
+	return func() transform.Transformer {
+		return e.NewDecoder()
+	}
+}
+
+// charsets are the character encodings supported, by name.  A byte order
+// mark at the start of the text overrides the byte order of UTF-16, and is
+// removed; "utf-16" requires one.
+var charsets = map[string]charset{
+	"utf-8": {
+		newTransformer: func() transform.Transformer {
+			return unicode.BOMOverride(transform.Nop)
+		},
+	},
+	"utf-16le": {
+		newTransformer: decoderOf(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)),
+		utf16:          true,
+	},
+	"utf-16be": {
+		newTransformer: decoderOf(unicode.UTF16(unicode.BigEndian, unicode.UseBOM)),
+		utf16:          true,
+	},
+	"utf-16": {
+		newTransformer: decoderOf(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)),
+		utf16:          true,
+	},
+	"iso-8859-1":   {newTransformer: decoderOf(charmap.ISO8859_1)},
+	"latin1":       {newTransformer: decoderOf(charmap.ISO8859_1)},
+	"iso-8859-15":  {newTransformer: decoderOf(charmap.ISO8859_15)},
+	"windows-1252": {newTransformer: decoderOf(charmap.Windows1252)},
+	"shift_jis":    {newTransformer: decoderOf(japanese.ShiftJIS)},
+	"shift-jis":    {newTransformer: decoderOf(japanese.ShiftJIS)},
+}
+
+// Decoder converts the text of a character encoding to UTF-8.  A nil Decoder
+// returns the text as is.
+type Decoder struct {
+	charset
+}
+
+// NewDecoder returns the decoder of the character encoding of the
+// character_encoding option, or nil when it is empty.
+func NewDecoder(name string) (*Decoder, error) {
+	if name == "" {
+		return nil, nil
+	}
+	c, ok := charsets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported character_encoding %q", name)
+	}
+	return &Decoder{charset: c}, nil
+}
+
+// NewLineDecoder returns the decoder of the character encoding of the
+// character_encoding option for the lines split at the line feed byte before
+// being decoded, like those of the tail input.  UTF-16 is not supported, as
+// its code units may contain the line feed byte, such as U+010A.
+func NewLineDecoder(name string) (*Decoder, error) {
+	d, err := NewDecoder(name)
+	if err == nil && d != nil && d.utf16 {
+		return nil, fmt.Errorf("character_encoding %q is not supported, the lines are split before being decoded", name)
+	}
+	return d, err
+}
+
+// Bytes decodes a complete text, such as a datagram or a file.
+func (d *Decoder) Bytes(b []byte) ([]byte, error) {
+	if d == nil {
+		return b, nil
+	}
+	b, _, err := transform.Bytes(d.newTransformer(), b)
+	return b, err
+}
+
+// Reader decodes the text read from r, such as a connection, before it is
+// split into messages.
+func (d *Decoder) Reader(r io.Reader) io.Reader {
+	if d == nil {
+		return r
+	}
+	return transform.NewReader(r, d.newTransformer())
+}
+
+// Line decodes a line which was split at the line feed byte before being
+// decoded, with a decoder of NewLineDecoder.  Only the first line may start
+// with a byte order mark, which is removed.
+func (d *Decoder) Line(b []byte) ([]byte, error) {
+	return d.Bytes(b)
+}
@@ -0,0 +1,102 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		text string
+	}{
+		{"", []byte("cpu value=1"), "cpu value=1"},
+		{"utf-8", []byte("\xef\xbb\xbfcpu value=1"), "cpu value=1"},
+		{"UTF-16LE", []byte("c\x00\xe9\x00"), "cé"},
+		{"utf-16be", []byte("\x00c\x00\xe9"), "cé"},
+		{"utf-16", []byte("\xff\xfec\x00\xe9\x00"), "cé"},
+		{"utf-16", []byte("\xfe\xff\x00c\x00\xe9"), "cé"},
+		{"latin1", []byte("caf\xe9"), "café"},
+		{"iso-8859-15", []byte("\xa4"), "€"},
+		{"windows-1252", []byte("\x80"), "€"},
+		{"shift_jis", []byte("\x93\xfa\x96\x7b"), "日本"},
+	}
+	for _, tt := range tests {
+		d, err := NewDecoder(tt.name)
+		require.NoError(t, err)
+		text, err := d.Bytes(tt.data)
+		require.NoError(t, err)
+		require.Equal(t, tt.text, string(text), tt.name)
+	}
+
+	// The byte order of "utf-16" is detected from the byte order mark.
+	d, err := NewDecoder("utf-16")
+	require.NoError(t, err)
+	_, err = d.Bytes([]byte("\x00c\x00\xe9"))
+	require.Error(t, err)
+
+	_, err = NewDecoder("ebcdic")
+	require.EqualError(t, err, `unsupported character_encoding "ebcdic"`)
+}
+
+func TestDecoderReader(t *testing.T) {
+	d, err := NewDecoder("utf-16le")
+	require.NoError(t, err)
+	r := d.Reader(bytes.NewReader([]byte("\xff\xfea\x00\n\x00b\x00\r\x00\n\x00")))
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []string{"a", "b"}, lines)
+
+	var nilDecoder *Decoder
+	text, err := ioutil.ReadAll(nilDecoder.Reader(bytes.NewReader([]byte("caf\xe9"))))
+	require.NoError(t, err)
+	require.Equal(t, "caf\xe9", string(text))
+}
+
+func TestDecoderLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		text  []string
+	}{
+		{"utf-8", []string{"\xef\xbb\xbfcaf\xc3\xa9", "caf\xc3\xa9"}, []string{"café", "café"}},
+		{"latin1", []string{"caf\xe9"}, []string{"café"}},
+		{"shift_jis", []string{"\x93\xfa\x96\x7b"}, []string{"日本"}},
+		{"", []string{"café"}, []string{"café"}},
+	}
+	for _, tt := range tests {
+		d, err := NewLineDecoder(tt.name)
+		require.NoError(t, err)
+		for i, line := range tt.lines {
+			text, err := d.Line([]byte(line))
+			require.NoError(t, err)
+			require.Equal(t, tt.text[i], string(text), tt.name)
+		}
+	}
+}
+
+func TestNewLineDecoder(t *testing.T) {
+	// The code units of UTF-16 may contain the line feed byte, like "Ċ"
+	// (U+010A), so its lines cannot be split before being decoded.
+	for _, name := range []string{"utf-16le", "UTF-16BE", "utf-16"} {
+		_, err := NewLineDecoder(name)
+		require.EqualError(t, err, fmt.Sprintf("character_encoding %q is not supported, the lines are split before being decoded", name))
+	}
+
+	_, err := NewLineDecoder("ebcdic")
+	require.EqualError(t, err, `unsupported character_encoding "ebcdic"`)
+	d, err := NewLineDecoder("")
+	require.NoError(t, err)
+	require.Nil(t, d)
+}
@@ -0,0 +1,129 @@
+// events is a package for signaling operational events between telegraf
+// components.  Any component may publish an event, and plugins may subscribe
+// to the events they want to react to, for example an input could stop
+// accepting data while all outputs are failing.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by telegraf.
+const (
+	// ConfigReloaded is published after the configuration has been
+	// reloaded, before the new plugins are started.
+	ConfigReloaded = "config_reloaded"
+
+	// OutputDegraded is published when a write to an output fails after
+	// previously succeeding, OutputRecovered when it succeeds again.  The
+	// source is the output name.
+	OutputDegraded  = "output_degraded"
+	OutputRecovered = "output_recovered"
+
+	// OutputsUnavailable is published when the writes to all outputs are
+	// failing, OutputsAvailable when at least one succeeds again.
+	OutputsUnavailable = "outputs_unavailable"
+	OutputsAvailable   = "outputs_available"
+
+	// InputPaused and InputResumed are published when an input is paused
+	// or resumed.  The source is the input name.
+	InputPaused  = "input_paused"
+	InputResumed = "input_resumed"
+)
+
+// subscriptionBuffer is the number of events a subscription holds before
+// further events to it are dropped.
+const subscriptionBuffer = 16
+
+var bus = newBus()
+
+// Event is an operational event.
+type Event struct {
+	Type   string
+	Source string
+	Time   time.Time
+}
+
+// Subscription receives the events it is subscribed to on C.
+type Subscription struct {
+	C <-chan Event
+
+	c     chan Event
+	types map[string]bool
+}
+
+type eventBus struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func newBus() *eventBus {
+	return &eventBus{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish sends an event of the given type to all subscribers.  Publish
+// never blocks; if a subscriber is not keeping up the event is dropped for
+// that subscriber.
+func Publish(eventType, source string) {
+	bus.publish(Event{
+		Type:   eventType,
+		Source: source,
+		Time:   time.Now(),
+	})
+}
+
+// Subscribe returns a subscription to the given event types, or to all events
+// if no types are given.  The subscription must be closed with Unsubscribe
+// when it is no longer used.
+func Subscribe(types ...string) *Subscription {
+	return bus.subscribe(types...)
+}
+
+// Unsubscribe removes the subscription and closes its channel.
+func Unsubscribe(sub *Subscription) {
+	bus.unsubscribe(sub)
+}
+
+func (b *eventBus) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if len(sub.types) > 0 && !sub.types[e.Type] {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+		}
+	}
+}
+
+func (b *eventBus) subscribe(types ...string) *Subscription {
+	c := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{
+		C:     c,
+		c:     c,
+		types: make(map[string]bool, len(types)),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+func (b *eventBus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.c)
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	sub := Subscribe(OutputDegraded)
+	defer Unsubscribe(sub)
+
+	Publish(OutputDegraded, "influxdb")
+
+	e := <-sub.C
+	require.Equal(t, OutputDegraded, e.Type)
+	require.Equal(t, "influxdb", e.Source)
+	require.False(t, e.Time.IsZero())
+}
+
+func TestSubscribeFiltersTypes(t *testing.T) {
+	sub := Subscribe(OutputsUnavailable)
+	defer Unsubscribe(sub)
+	all := Subscribe()
+	defer Unsubscribe(all)
+
+	Publish(ConfigReloaded, "")
+	Publish(OutputsUnavailable, "")
+
+	e := <-sub.C
+	require.Equal(t, OutputsUnavailable, e.Type)
+	require.Len(t, sub.C, 0)
+
+	require.Equal(t, ConfigReloaded, (<-all.C).Type)
+	require.Equal(t, OutputsUnavailable, (<-all.C).Type)
+}
+
+func TestPublishDoesNotBlock(t *testing.T) {
+	sub := Subscribe()
+	defer Unsubscribe(sub)
+
+	for i := 0; i < subscriptionBuffer*2; i++ {
+		Publish(InputPaused, "cpu")
+	}
+	require.Len(t, sub.C, subscriptionBuffer)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	sub := Subscribe()
+	Unsubscribe(sub)
+	Unsubscribe(sub)
+
+	Publish(InputPaused, "cpu")
+	_, ok := <-sub.C
+	require.False(t, ok)
+}
@@ -0,0 +1,149 @@
+// Package httpconfig holds the settings of the HTTP clients shared by the
+// plugins, which embed HTTPClientConfig for uniform options and create their
+// client with CreateClient.
+package httpconfig
+
+import (
+	ctls "crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	dialTimeout      = 30 * time.Second
+	dialKeepAlive    = 30 * time.Second
+	handshakeTimeout = 10 * time.Second
+)
+
+// HTTPClientConfig represents the standard HTTP client config.
+type HTTPClientConfig struct {
+	Timeout             internal.Duration `toml:"timeout"`
+	IdleConnTimeout     internal.Duration `toml:"idle_conn_timeout"`
+	MaxIdleConns        int               `toml:"max_idle_conn"`
+	MaxIdleConnsPerHost int               `toml:"max_idle_conn_per_host"`
+	DisableKeepAlives   bool              `toml:"disable_keep_alives"`
+	Renegotiation       string            `toml:"tls_renegotiation_method"`
+	Headers             map[string]string `toml:"headers"`
+	Cookies             map[string]string `toml:"cookies"`
+	tls.ClientConfig
+}
+
+// CreateClient returns an HTTP client with the settings of the config.  Its
+// requests and connections are counted in the internal_http_client
+// measurement with the plugin tag, such as "inputs.http".
+func (c *HTTPClientConfig) CreateClient(plugin string) (*http.Client, error) {
+	if c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 {
+		return nil, fmt.Errorf("max_idle_conn and max_idle_conn_per_host cannot be negative")
+	}
+
+	renegotiation, err := renegotiationSupport(c.Renegotiation)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg == nil {
+		// https URLs are still requested without TLS options.
+		tlsCfg = tls.PolicyConfig()
+	}
+	if renegotiation != ctls.RenegotiateNever {
+		if tlsCfg == nil {
+			tlsCfg = &ctls.Config{}
+		}
+		tlsCfg.Renegotiation = renegotiation
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: dialKeepAlive,
+		}).DialContext,
+		TLSClientConfig:     tlsCfg,
+		TLSHandshakeTimeout: handshakeTimeout,
+		DisableKeepAlives:   c.DisableKeepAlives,
+		MaxIdleConns:        c.MaxIdleConns,
+		MaxIdleConnsPerHost: c.MaxIdleConnsPerHost,
+		IdleConnTimeout:     c.IdleConnTimeout.Duration,
+	}
+
+	return &http.Client{
+		Transport: newStatsTransport(transport, plugin),
+		Timeout:   c.Timeout.Duration,
+	}, nil
+}
+
+// PrepareRequest sets the headers and the cookies of the config on the
+// request.  The Host header sets the host of the request.
+func (c *HTTPClientConfig) PrepareRequest(req *http.Request) {
+	for k, v := range c.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+	for name, value := range c.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
+func renegotiationSupport(method string) (ctls.RenegotiationSupport, error) {
+	switch strings.ToLower(method) {
+	case "", "never":
+		return ctls.RenegotiateNever, nil
+	case "once":
+		return ctls.RenegotiateOnceAsClient, nil
+	case "freely":
+		return ctls.RenegotiateFreelyAsClient, nil
+	default:
+		return ctls.RenegotiateNever, fmt.Errorf("unknown tls_renegotiation_method %q", method)
+	}
+}
+
+// statsTransport counts the requests of a transport and whether their
+// connections are new or reused.
+type statsTransport struct {
+	transport http.RoundTripper
+	trace     *httptrace.ClientTrace
+
+	requests          selfstat.Stat
+	connectionsNew    selfstat.Stat
+	connectionsReused selfstat.Stat
+}
+
+func newStatsTransport(transport http.RoundTripper, plugin string) *statsTransport {
+	tags := map[string]string{"plugin": plugin}
+	t := &statsTransport{
+		transport:         transport,
+		requests:          selfstat.Register("http_client", "requests", tags),
+		connectionsNew:    selfstat.Register("http_client", "connections_new", tags),
+		connectionsReused: selfstat.Register("http_client", "connections_reused", tags),
+	}
+	t.trace = &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.connectionsReused.Incr(1)
+			} else {
+				t.connectionsNew.Incr(1)
+			}
+		},
+	}
+	return t
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Incr(1)
+	ctx := httptrace.WithClientTrace(req.Context(), t.trace)
+	return t.transport.RoundTrip(req.WithContext(ctx))
+}
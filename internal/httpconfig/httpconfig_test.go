@@ -0,0 +1,100 @@
+package httpconfig
+
+import (
+	ctls "crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateClient(t *testing.T) {
+	c := &HTTPClientConfig{
+		Timeout:             internal.Duration{Duration: 5 * time.Second},
+		IdleConnTimeout:     internal.Duration{Duration: time.Minute},
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 2,
+		DisableKeepAlives:   true,
+		Renegotiation:       "once",
+	}
+	client, err := c.CreateClient("inputs.test")
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, client.Timeout)
+
+	transport := client.Transport.(*statsTransport).transport.(*http.Transport)
+	require.Equal(t, time.Minute, transport.IdleConnTimeout)
+	require.Equal(t, 10, transport.MaxIdleConns)
+	require.Equal(t, 2, transport.MaxIdleConnsPerHost)
+	require.True(t, transport.DisableKeepAlives)
+	require.Equal(t, ctls.RenegotiateOnceAsClient, transport.TLSClientConfig.Renegotiation)
+}
+
+func TestCreateClientPolicy(t *testing.T) {
+	c := &HTTPClientConfig{}
+	client, err := c.CreateClient("inputs.test")
+	require.NoError(t, err)
+	transport := client.Transport.(*statsTransport).transport.(*http.Transport)
+	require.Nil(t, transport.TLSClientConfig)
+
+	// The policy applies to plugins without TLS options.
+	defer tls.SetPolicy("", false)
+	require.NoError(t, tls.SetPolicy("TLS12", true))
+	client, err = c.CreateClient("inputs.test")
+	require.NoError(t, err)
+	transport = client.Transport.(*statsTransport).transport.(*http.Transport)
+	require.Equal(t, uint16(ctls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+	require.NotContains(t, transport.TLSClientConfig.CipherSuites, ctls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305)
+}
+
+func TestCreateClientErrors(t *testing.T) {
+	c := &HTTPClientConfig{Renegotiation: "always"}
+	_, err := c.CreateClient("inputs.test")
+	require.EqualError(t, err, `unknown tls_renegotiation_method "always"`)
+
+	c = &HTTPClientConfig{MaxIdleConns: -1}
+	_, err = c.CreateClient("inputs.test")
+	require.EqualError(t, err, "max_idle_conn and max_idle_conn_per_host cannot be negative")
+}
+
+func TestPrepareRequest(t *testing.T) {
+	c := &HTTPClientConfig{
+		Headers: map[string]string{"X-Special-Header": "Special-Value", "Host": "example.org"},
+		Cookies: map[string]string{"session": "abc"},
+	}
+	req, err := http.NewRequest("GET", "http://127.0.0.1/", nil)
+	require.NoError(t, err)
+	c.PrepareRequest(req)
+
+	require.Equal(t, "Special-Value", req.Header.Get("X-Special-Header"))
+	require.Equal(t, "example.org", req.Host)
+	cookie, err := req.Cookie("session")
+	require.NoError(t, err)
+	require.Equal(t, "abc", cookie.Value)
+}
+
+func TestConnectionStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c := &HTTPClientConfig{}
+	client, err := c.CreateClient("inputs.stats_test")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	tags := map[string]string{"plugin": "inputs.stats_test"}
+	require.Equal(t, int64(3), selfstat.Register("http_client", "requests", tags).Get())
+	require.Equal(t, int64(1), selfstat.Register("http_client", "connections_new", tags).Get())
+	require.Equal(t, int64(2), selfstat.Register("http_client", "connections_reused", tags).Get())
+}
@@ -0,0 +1,209 @@
+// listen is a package for opening the sockets of the service inputs, with
+// a common control of the IPv6 dual-stack sockets.
+package listen
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Config is the socket options of the listeners of a service input.
+type Config struct {
+	// IPv6Only sets the IPV6_V6ONLY option of the IPv6 sockets.  If nil, Go
+	// listens on both IPv4 and IPv6 on the wildcard addresses of the tcp and
+	// udp networks when the system supports it, and on IPv6 only with the
+	// tcp6 and udp6 networks.
+	IPv6Only *bool `toml:"ipv6_v6only"`
+}
+
+// Listen listens on the address of a stream network, like net.Listen.
+//
+// If IPv6Only is set, the listener of an empty or IPv6 host of the tcp and
+// tcp6 networks is opened with IPV6_V6ONLY set accordingly, and the listener
+// of an IPv4 host, including 0.0.0.0, on IPv4 only.
+func (c *Config) Listen(network, address string) (net.Listener, error) {
+	if c.IPv6Only == nil || (network != "tcp" && network != "tcp6") {
+		return net.Listen(network, address)
+	}
+	addr, err := net.ResolveTCPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if addr.IP.To4() != nil {
+		return net.Listen("tcp4", address)
+	}
+
+	f, err := socketIPv6(network, addr.IP, addr.Port, addr.Zone, *c.IPv6Only)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// ListenPacket listens on the address of a packet network, like
+// net.ListenPacket, with the IPV6_V6ONLY option of the udp and udp6 networks
+// set as by Listen.
+func (c *Config) ListenPacket(network, address string) (net.PacketConn, error) {
+	if c.IPv6Only == nil || (network != "udp" && network != "udp6") {
+		return net.ListenPacket(network, address)
+	}
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if addr.IP.To4() != nil {
+		return net.ListenPacket("udp4", address)
+	}
+
+	f, err := socketIPv6(network, addr.IP, addr.Port, addr.Zone, *c.IPv6Only)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
+
+// ListenAll listens on each of the addresses, which may be prefixed by their
+// network as in "tcp6://[::1]:8186", and returns a listener accepting the
+// connections of all of them.  Only the tcp, tcp4 and tcp6 networks are
+// accepted.
+func (c *Config) ListenAll(addresses []string) (net.Listener, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no address to listen on")
+	}
+
+	var listeners []net.Listener
+	for _, address := range addresses {
+		network, addr := SplitAddress(address, "tcp")
+		switch network {
+		case "tcp", "tcp4", "tcp6":
+		default:
+			closeAll(listeners)
+			return nil, fmt.Errorf("unknown protocol '%s' in '%s'", network, address)
+		}
+		l, err := c.Listen(network, addr)
+		if err != nil {
+			closeAll(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if len(listeners) == 1 {
+		return listeners[0], nil
+	}
+	return newMultiListener(listeners), nil
+}
+
+// SplitAddress splits the network prefixing an address, as in "udp6://:8125",
+// or returns the default network if it has none.
+func SplitAddress(address, network string) (string, string) {
+	parts := strings.SplitN(address, "://", 2)
+	if len(parts) != 2 {
+		return network, address
+	}
+	return parts[0], parts[1]
+}
+
+func closeAll(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// zoneIndex returns the index of the interface of an IPv6 zone.
+func zoneIndex(zone string) (uint32, error) {
+	if zone == "" {
+		return 0, nil
+	}
+	if ifi, err := net.InterfaceByName(zone); err == nil {
+		return uint32(ifi.Index), nil
+	}
+	n, err := strconv.ParseUint(zone, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown IPv6 zone %q", zone)
+	}
+	return uint32(n), nil
+}
+
+// errClosed is the error of Accept on a closed listener, reported as by the
+// listeners of the net package.
+var errClosed = errors.New("use of closed network connection")
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// multiListener accepts the connections of several listeners.
+type multiListener struct {
+	listeners []net.Listener
+	conns     chan acceptResult
+	done      chan struct{}
+	once      sync.Once
+}
+
+func newMultiListener(listeners []net.Listener) *multiListener {
+	m := &multiListener{
+		listeners: listeners,
+		conns:     make(chan acceptResult),
+		done:      make(chan struct{}),
+	}
+	for _, l := range listeners {
+		go m.accept(l)
+	}
+	return m
+}
+
+func (m *multiListener) accept(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		select {
+		case m.conns <- acceptResult{conn: conn, err: err}:
+		case <-m.done:
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if err != nil {
+			if ne, ok := err.(net.Error); !ok || !ne.Temporary() {
+				return
+			}
+		}
+	}
+}
+
+// Accept returns the next connection accepted by any of the listeners.
+func (m *multiListener) Accept() (net.Conn, error) {
+	select {
+	case r := <-m.conns:
+		return r.conn, r.err
+	case <-m.done:
+		addr := m.Addr()
+		return nil, &net.OpError{Op: "accept", Net: addr.Network(), Addr: addr, Err: errClosed}
+	}
+}
+
+// Close closes all listeners.
+func (m *multiListener) Close() error {
+	var err error
+	m.once.Do(func() {
+		close(m.done)
+		for _, l := range m.listeners {
+			if e := l.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	})
+	return err
+}
+
+// Addr returns the address of the first listener.
+func (m *multiListener) Addr() net.Addr {
+	return m.listeners[0].Addr()
+}
@@ -0,0 +1,155 @@
+package listen
+
+import (
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAddress(t *testing.T) {
+	network, address := SplitAddress("udp6://[::1]:8125", "udp")
+	require.Equal(t, "udp6", network)
+	require.Equal(t, "[::1]:8125", address)
+
+	network, address = SplitAddress(":8125", "udp")
+	require.Equal(t, "udp", network)
+	require.Equal(t, ":8125", address)
+}
+
+func TestListenAll(t *testing.T) {
+	c := &Config{}
+	l, err := c.ListenAll([]string{"127.0.0.1:0", "tcp4://127.0.0.1:0"})
+	require.NoError(t, err)
+	m, ok := l.(*multiListener)
+	require.True(t, ok)
+	require.Equal(t, m.listeners[0].Addr(), l.Addr())
+
+	for _, ml := range m.listeners {
+		conn, err := net.Dial("tcp", ml.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		accepted, err := l.Accept()
+		require.NoError(t, err)
+		require.Equal(t, conn.LocalAddr().String(), accepted.RemoteAddr().String())
+		accepted.Close()
+	}
+
+	require.NoError(t, l.Close())
+	_, err = l.Accept()
+	require.Error(t, err)
+	require.True(t, strings.HasSuffix(err.Error(), ": use of closed network connection"))
+	for _, ml := range m.listeners {
+		_, err := net.Dial("tcp", ml.Addr().String())
+		require.Error(t, err)
+	}
+}
+
+func TestListenAllSingle(t *testing.T) {
+	c := &Config{}
+	l, err := c.ListenAll([]string{"tcp://127.0.0.1:0"})
+	require.NoError(t, err)
+	defer l.Close()
+	_, ok := l.(*net.TCPListener)
+	require.True(t, ok)
+}
+
+func TestListenAllErrors(t *testing.T) {
+	c := &Config{}
+	_, err := c.ListenAll(nil)
+	require.Error(t, err)
+
+	_, err = c.ListenAll([]string{"127.0.0.1:0", "udp://127.0.0.1:0"})
+	require.EqualError(t, err, "unknown protocol 'udp' in 'udp://127.0.0.1:0'")
+}
+
+func TestIPv4Host(t *testing.T) {
+	v6only := true
+	c := &Config{IPv6Only: &v6only}
+
+	l, err := c.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	require.NotNil(t, l.Addr().(*net.TCPAddr).IP.To4())
+
+	pc, err := c.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+	require.NotNil(t, pc.LocalAddr().(*net.UDPAddr).IP.To4())
+}
+
+func skipWithoutIPv6(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ipv6_v6only is not supported on windows")
+	}
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available")
+	}
+	l.Close()
+}
+
+func TestIPv6Only(t *testing.T) {
+	skipWithoutIPv6(t)
+
+	v6only := true
+	c := &Config{IPv6Only: &v6only}
+	l, err := c.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer l.Close()
+	_, ok := l.(*net.TCPListener)
+	require.True(t, ok)
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("::1", port), time.Second)
+	require.NoError(t, err)
+	conn.Close()
+
+	_, err = net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", port), time.Second)
+	require.Error(t, err)
+}
+
+func TestDualStack(t *testing.T) {
+	skipWithoutIPv6(t)
+	if runtime.GOOS == "openbsd" {
+		t.Skip("dual-stack sockets are not supported on openbsd")
+	}
+
+	v6only := false
+	c := &Config{IPv6Only: &v6only}
+	pc, err := c.ListenPacket("udp6", ":0")
+	require.NoError(t, err)
+	defer pc.Close()
+	_, ok := pc.(*net.UDPConn)
+	require.True(t, ok)
+	port := strconv.Itoa(pc.LocalAddr().(*net.UDPAddr).Port)
+
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		conn, err := net.Dial("udp", net.JoinHostPort(host, port))
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(host))
+		require.NoError(t, err)
+		conn.Close()
+
+		b := make([]byte, 64)
+		pc.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := pc.ReadFrom(b)
+		require.NoError(t, err)
+		require.Equal(t, host, string(b[:n]))
+	}
+}
@@ -0,0 +1,74 @@
+// +build !windows
+
+package listen
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// socketIPv6 opens an IPv6 socket of the tcp or udp network bound to the
+// address, with the IPV6_V6ONLY option set, listening for the connections of
+// a stream socket.
+func socketIPv6(network string, ip net.IP, port int, zone string, v6only bool) (*os.File, error) {
+	sotype := syscall.SOCK_DGRAM
+	if network == "tcp" || network == "tcp6" {
+		sotype = syscall.SOCK_STREAM
+	}
+
+	sa := &syscall.SockaddrInet6{Port: port}
+	if ip != nil {
+		copy(sa.Addr[:], ip.To16())
+	}
+	index, err := zoneIndex(zone)
+	if err != nil {
+		return nil, err
+	}
+	sa.ZoneId = index
+
+	syscall.ForkLock.RLock()
+	fd, err := syscall.Socket(syscall.AF_INET6, sotype, 0)
+	if err == nil {
+		syscall.CloseOnExec(fd)
+	}
+	syscall.ForkLock.RUnlock()
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	if err := setup(fd, sotype, sa, v6only); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	name := network + ":" + net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+func setup(fd int, sotype int, sa *syscall.SockaddrInet6, v6only bool) error {
+	if sotype == syscall.SOCK_STREAM {
+		// as the listeners of the net package
+		err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+		if err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	value := 0
+	if v6only {
+		value = 1
+	}
+	err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, value)
+	if err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		return os.NewSyscallError("bind", err)
+	}
+	if sotype == syscall.SOCK_STREAM {
+		if err := syscall.Listen(fd, syscall.SOMAXCONN); err != nil {
+			return os.NewSyscallError("listen", err)
+		}
+	}
+	return nil
+}
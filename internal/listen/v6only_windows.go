@@ -0,0 +1,13 @@
+package listen
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// socketIPv6 is unsupported on Windows, where the sockets can not be
+// turned into listeners of the net package.
+func socketIPv6(network string, ip net.IP, port int, zone string, v6only bool) (*os.File, error) {
+	return nil, errors.New("ipv6_v6only is not supported on windows")
+}
@@ -2,9 +2,12 @@ package models
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/schedule"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/selfstat"
 )
@@ -15,9 +18,20 @@ type RunningInput struct {
 	Input  telegraf.Input
 	Config *InputConfig
 
+	// Index is the position of the input among the inputs of the same
+	// plugin in the configuration, from 0.
+	Index int
+
 	trace       bool
 	defaultTags map[string]string
 
+	// Serializes calls to Gather, which may come from the scheduled
+	// collection as well as from on-demand probes.
+	gatherLock sync.Mutex
+
+	// paused is set while the input is paused through the control API.
+	paused int32
+
 	MetricsGathered selfstat.Stat
 }
 
@@ -45,12 +59,32 @@ type InputConfig struct {
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+
+	// Schedule gathers the input at the times of a cron expression instead
+	// of on an interval when set.
+	Schedule *schedule.Schedule
 }
 
 func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// Pause stops the input from being gathered, any metrics added by the input
+// while paused are discarded.
+func (r *RunningInput) Pause() {
+	atomic.StoreInt32(&r.paused, 1)
+}
+
+// Resume undoes Pause.
+func (r *RunningInput) Resume() {
+	atomic.StoreInt32(&r.paused, 0)
+}
+
+// Paused returns true if the input is paused.
+func (r *RunningInput) Paused() bool {
+	return atomic.LoadInt32(&r.paused) == 1
+}
+
 // MakeMetric either returns a metric, or returns nil if the metric doesn't
 // need to be created (because of filtering, an error, etc.)
 func (r *RunningInput) MakeMetric(
@@ -60,6 +94,10 @@ func (r *RunningInput) MakeMetric(
 	mType telegraf.ValueType,
 	t time.Time,
 ) telegraf.Metric {
+	if r.Paused() {
+		return nil
+	}
+
 	m := makemetric(
 		measurement,
 		fields,
@@ -89,6 +127,14 @@ func (r *RunningInput) MakeMetric(
 	return m
 }
 
+// Gather calls Gather on the wrapped Input. Concurrent calls are serialized
+// since plugins are not required to support them.
+func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
+	r.gatherLock.Lock()
+	defer r.gatherLock.Unlock()
+	return r.Input.Gather(acc)
+}
+
 func (r *RunningInput) Trace() bool {
 	return r.trace
 }
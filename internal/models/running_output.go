@@ -3,11 +3,16 @@ package models
 import (
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/internal/events"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -27,8 +32,13 @@ type RunningOutput struct {
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	// Index is the position of the output among the outputs of the same
+	// plugin in the configuration, from 0.
+	Index int
+
 	MetricsFiltered selfstat.Stat
 	MetricsWritten  selfstat.Stat
+	MetricsDropped  selfstat.Stat
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
@@ -36,6 +46,14 @@ type RunningOutput struct {
 	metrics     *buffer.Buffer
 	failMetrics *buffer.Buffer
 
+	// Shadow outputs write in the background, shadowBusy is set while a
+	// write is in progress.
+	shadowBusy int32
+	shadowWg   sync.WaitGroup
+
+	// failing is set while writes to the output are failing.
+	failing int32
+
 	// Guards against concurrent calls to the Output as described in #3009
 	sync.Mutex
 }
@@ -53,6 +71,10 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DEFAULT_METRIC_BATCH_SIZE
 	}
+	tags := map[string]string{"output": name}
+	if conf.Shadow {
+		tags["shadow"] = "true"
+	}
 	ro := &RunningOutput{
 		Name:              name,
 		metrics:           buffer.NewBuffer(batchSize),
@@ -64,29 +86,39 @@ func NewRunningOutput(
 		MetricsWritten: selfstat.Register(
 			"write",
 			"metrics_written",
-			map[string]string{"output": name},
+			tags,
 		),
 		MetricsFiltered: selfstat.Register(
 			"write",
 			"metrics_filtered",
-			map[string]string{"output": name},
+			tags,
+		),
+		MetricsDropped: selfstat.Register(
+			"write",
+			"metrics_dropped",
+			tags,
 		),
 		BufferSize: selfstat.Register(
 			"write",
 			"buffer_size",
-			map[string]string{"output": name},
+			tags,
 		),
 		BufferLimit: selfstat.Register(
 			"write",
 			"buffer_limit",
-			map[string]string{"output": name},
+			tags,
 		),
 		WriteTime: selfstat.RegisterTiming(
 			"write",
 			"write_time_ns",
-			map[string]string{"output": name},
+			tags,
 		),
 	}
+	if conf.MaxBodySize > 0 && conf.Serializer == nil {
+		if _, ok := output.(serializers.SizedOutput); !ok {
+			conf.Serializer = influx.NewSerializer()
+		}
+	}
 	ro.BufferLimit.Set(int64(ro.MetricBufferLimit))
 	return ro
 }
@@ -116,15 +148,23 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	ro.metrics.Add(m)
 	if ro.metrics.Len() == ro.MetricBatchSize {
 		batch := ro.metrics.Batch(ro.MetricBatchSize)
-		err := ro.write(batch)
-		if err != nil {
-			ro.failMetrics.Add(batch...)
+		if ro.Config.Shadow {
+			ro.writeShadow(batch)
+			return
 		}
+		failed, err := ro.writeBatch(batch)
+		ro.setFailing(err != nil)
+		ro.failMetrics.Add(failed...)
 	}
 }
 
 // Write writes all cached points to this output.
 func (ro *RunningOutput) Write() error {
+	if ro.Config.Shadow {
+		ro.writeShadow(ro.metrics.Batch(ro.MetricBatchSize))
+		return nil
+	}
+
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
 	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
@@ -146,11 +186,9 @@ func (ro *RunningOutput) Write() error {
 			// write to this output again. We are not exiting the loop just so
 			// that we can rotate the metrics to preserve order.
 			if err == nil {
-				err = ro.write(batch)
-			}
-			if err != nil {
-				ro.failMetrics.Add(batch...)
+				batch, err = ro.writeBatch(batch)
 			}
+			ro.failMetrics.Add(batch...)
 		}
 	}
 
@@ -158,8 +196,9 @@ func (ro *RunningOutput) Write() error {
 	// see comment above about not trying to write to an already failed output.
 	// if ro.failMetrics is empty then err will always be nil at this point.
 	if err == nil {
-		err = ro.write(batch)
+		batch, err = ro.writeBatch(batch)
 	}
+	ro.setFailing(err != nil)
 
 	if err != nil {
 		ro.failMetrics.Add(batch...)
@@ -168,6 +207,95 @@ func (ro *RunningOutput) Write() error {
 	return nil
 }
 
+// setFailing records whether writes to the output are failing, publishing
+// an event when this changes.
+func (ro *RunningOutput) setFailing(failing bool) {
+	var v int32
+	if failing {
+		v = 1
+	}
+	if atomic.SwapInt32(&ro.failing, v) == v {
+		return
+	}
+	if failing {
+		events.Publish(events.OutputDegraded, ro.Name)
+	} else {
+		events.Publish(events.OutputRecovered, ro.Name)
+	}
+}
+
+// writeShadow writes the batch in the background. The batch is dropped if
+// the write fails or if the previous write has not yet completed, so that a
+// shadow output never delays the other outputs or accumulates metrics.
+func (ro *RunningOutput) writeShadow(batch []telegraf.Metric) {
+	if len(batch) == 0 {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&ro.shadowBusy, 0, 1) {
+		log.Printf("W! Shadow output [%s] is still writing, dropping batch of %d metrics",
+			ro.Name, len(batch))
+		ro.MetricsDropped.Incr(int64(len(batch)))
+		return
+	}
+
+	ro.shadowWg.Add(1)
+	go func() {
+		defer ro.shadowWg.Done()
+		defer atomic.StoreInt32(&ro.shadowBusy, 0)
+		if failed, err := ro.writeBatch(batch); err != nil {
+			log.Printf("W! Shadow output [%s] failed to write batch of %d metrics: %s",
+				ro.Name, len(failed), err)
+			ro.MetricsDropped.Incr(int64(len(failed)))
+		}
+	}()
+}
+
+// Wait waits for any background writes of a shadow output to complete.
+func (ro *RunningOutput) Wait() {
+	ro.shadowWg.Wait()
+}
+
+// writeBatch writes the metrics to the output, first splitting them into
+// batches that serialize to at most MaxBodySize bytes if it is set.  If a
+// write fails the metrics that were not written are returned with the error,
+// only the failed metrics of a batch if the output returns an
+// outputs.PartialWriteError.
+func (ro *RunningOutput) writeBatch(metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	if ro.Config.MaxBodySize <= 0 {
+		if err := ro.write(metrics); err != nil {
+			return failedMetrics(metrics, err), err
+		}
+		return nil, nil
+	}
+
+	var batches [][]telegraf.Metric
+	if ro.Config.Serializer != nil {
+		batches = serializers.SplitBatch(ro.Config.Serializer, metrics, ro.Config.MaxBodySize)
+	} else {
+		sized := ro.Output.(serializers.SizedOutput)
+		batches = serializers.SplitBatchBySize(sized.MetricSize, metrics, ro.Config.MaxBodySize)
+	}
+	for i, batch := range batches {
+		if err := ro.write(batch); err != nil {
+			failed := failedMetrics(batch, err)
+			for _, batch := range batches[i+1:] {
+				failed = append(failed, batch...)
+			}
+			return failed, err
+		}
+	}
+	return nil, nil
+}
+
+// failedMetrics returns the metrics of the batch that were not written by
+// the failed write.
+func failedMetrics(batch []telegraf.Metric, err error) []telegraf.Metric {
+	if perr, ok := err.(*outputs.PartialWriteError); ok {
+		return perr.Failed
+	}
+	return batch
+}
+
 func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	nMetrics := len(metrics)
 	if nMetrics == 0 {
@@ -178,6 +306,12 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	start := time.Now()
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
+	if perr, ok := err.(*outputs.PartialWriteError); ok {
+		nMetrics -= len(perr.Failed)
+		log.Printf("D! Output [%s] wrote %d metrics of a batch of %d in %s\n",
+			ro.Name, nMetrics, len(metrics), elapsed)
+		ro.MetricsWritten.Incr(int64(nMetrics))
+	}
 	if err == nil {
 		log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
 			ro.Name, nMetrics, elapsed)
@@ -191,4 +325,16 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 type OutputConfig struct {
 	Name   string
 	Filter Filter
+
+	// Shadow outputs receive the same metrics as the other outputs, but
+	// their writes are best effort: they never block the agent and failed
+	// writes are dropped instead of buffered.
+	Shadow bool
+
+	// MaxBodySize is the maximum size in bytes of the serialized metrics
+	// sent in a single write, zero for no limit.  The size is measured with
+	// Serializer, or by the output if it is a serializers.SizedOutput without
+	// a serializer, or else with the influx serializer.
+	MaxBodySize int
+	Serializer  serializers.Serializer
 }
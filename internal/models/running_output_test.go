@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -330,6 +331,163 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutputShadow(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Shadow: true,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	ro.Wait()
+	// flushed in the background when the batch filled
+	assert.Len(t, m.Metrics(), 4)
+
+	err := ro.Write()
+	require.NoError(t, err)
+	ro.Wait()
+	assert.Len(t, m.Metrics(), 5)
+}
+
+// Verify that a shadow output drops batches that fail to write.
+func TestRunningOutputShadowWriteFail(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Shadow: true,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("shadow_fail", m, conf, 4, 12)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	ro.Wait()
+
+	// failures are not returned to the agent
+	err := ro.Write()
+	require.NoError(t, err)
+	ro.Wait()
+	assert.Len(t, m.Metrics(), 0)
+
+	m.Lock()
+	m.failWrite = false
+	m.Unlock()
+	for _, metric := range next5[:2] {
+		ro.AddMetric(metric)
+	}
+	err = ro.Write()
+	require.NoError(t, err)
+	ro.Wait()
+
+	// only the metrics added after the failure are written
+	assert.Len(t, m.Metrics(), 2)
+	assert.Equal(t, int64(5), ro.MetricsDropped.Get())
+}
+
+func TestRunningOutputMaxBodySize(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:      Filter{},
+		MaxBodySize: 1,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	// every metric is larger than the limit, so each is written alone
+	err := ro.Write()
+	require.NoError(t, err)
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, 5, m.writes)
+}
+
+// Verify that the size of the metrics is measured by the outputs measuring
+// it themselves.
+func TestRunningOutputMaxBodySizeSizedOutput(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:      Filter{},
+		MaxBodySize: 20,
+	}
+
+	m := &sizedOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	assert.Nil(t, conf.Serializer)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	err := ro.Write()
+	require.NoError(t, err)
+	assert.Equal(t, first5, m.Metrics())
+	assert.Equal(t, 3, m.writes)
+}
+
+// Verify that only the metrics not yet written are retried when a split
+// batch fails part way.
+func TestRunningOutputMaxBodySizeWriteFail(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:      Filter{},
+		MaxBodySize: 1,
+	}
+
+	m := &mockOutput{}
+	m.failAfter = 2
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	err := ro.Write()
+	require.Error(t, err)
+	assert.Len(t, m.Metrics(), 2)
+
+	m.Lock()
+	m.failAfter = 0
+	m.Unlock()
+	err = ro.Write()
+	require.NoError(t, err)
+	assert.Equal(t, first5, m.Metrics())
+}
+
+// Verify that only the failed metrics of a partial write are retried.
+func TestRunningOutputPartialWrite(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.partial = 2
+	ro := NewRunningOutput("partial", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	err := ro.Write()
+	require.Error(t, err)
+	assert.Equal(t, first5[:2], m.Metrics())
+	assert.Equal(t, int64(2), ro.MetricsWritten.Get())
+
+	m.Lock()
+	m.partial = 0
+	m.Unlock()
+	err = ro.Write()
+	require.NoError(t, err)
+	assert.Equal(t, first5, m.Metrics())
+	assert.Equal(t, int64(5), ro.MetricsWritten.Get())
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -486,6 +644,13 @@ type mockOutput struct {
 
 	// if true, mock a write failure
 	failWrite bool
+
+	// if non-zero, mock a write failure once this many writes succeeded
+	failAfter int
+	writes    int
+
+	// if non-zero, mock a partial write of this many metrics of the batch
+	partial int
 }
 
 func (m *mockOutput) Connect() error {
@@ -507,9 +672,18 @@ func (m *mockOutput) SampleConfig() string {
 func (m *mockOutput) Write(metrics []telegraf.Metric) error {
 	m.Lock()
 	defer m.Unlock()
-	if m.failWrite {
+	if m.failWrite || (m.failAfter > 0 && m.writes >= m.failAfter) {
 		return fmt.Errorf("Failed Write!")
 	}
+	m.writes++
+
+	if m.partial > 0 && m.partial < len(metrics) {
+		m.metrics = append(m.metrics, metrics[:m.partial]...)
+		return &outputs.PartialWriteError{
+			Err:    fmt.Errorf("Partial Write!"),
+			Failed: metrics[m.partial:],
+		}
+	}
 
 	if m.metrics == nil {
 		m.metrics = []telegraf.Metric{}
@@ -554,3 +728,12 @@ func (m *perfOutput) Write(metrics []telegraf.Metric) error {
 	}
 	return nil
 }
+
+// sizedOutput measures every metric as 10 bytes.
+type sizedOutput struct {
+	mockOutput
+}
+
+func (s *sizedOutput) MetricSize(metric telegraf.Metric) int {
+	return 10
+}
@@ -0,0 +1,103 @@
+// persister saves the state of stateful plugins to a file and restores it
+// on startup, so that plugins can resume where they left off after a
+// restart without each of them implementing its own file format.
+package persister
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Persister stores the state of the registered plugins in a JSON file, keyed
+// by plugin id.
+type Persister struct {
+	Filename string
+
+	plugins map[string]telegraf.StatefulPlugin
+}
+
+// NewPersister returns a Persister using the given file.
+func NewPersister(filename string) *Persister {
+	return &Persister{
+		Filename: filename,
+		plugins:  make(map[string]telegraf.StatefulPlugin),
+	}
+}
+
+// Register adds a plugin under the given id.  The id must be stable across
+// restarts for the state to be restored.
+func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
+	if _, ok := p.plugins[id]; ok {
+		return fmt.Errorf("plugin with id %q already registered", id)
+	}
+	p.plugins[id] = plugin
+	return nil
+}
+
+// Load reads the statefile and restores the state of the registered plugins.
+// A missing statefile is not an error.  Plugins whose state can not be
+// restored are logged and left in their initial state.
+func (p *Persister) Load() error {
+	buf, err := ioutil.ReadFile(p.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading statefile failed: %v", err)
+	}
+
+	states := make(map[string]interface{})
+	if err := json.Unmarshal(buf, &states); err != nil {
+		return fmt.Errorf("decoding statefile failed: %v", err)
+	}
+
+	for id, plugin := range p.plugins {
+		state, ok := states[id]
+		if !ok {
+			continue
+		}
+		if err := plugin.SetState(state); err != nil {
+			log.Printf("E! Restoring state of %s failed: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// Store writes the state of the registered plugins to the statefile.  The
+// file is written atomically by renaming a temporary file.
+func (p *Persister) Store() error {
+	states := make(map[string]interface{}, len(p.plugins))
+	for id, plugin := range p.plugins {
+		states[id] = plugin.GetState()
+	}
+
+	buf, err := json.Marshal(states)
+	if err != nil {
+		return fmt.Errorf("encoding state failed: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p.Filename), filepath.Base(p.Filename))
+	if err != nil {
+		return fmt.Errorf("creating statefile failed: %v", err)
+	}
+	_, err = tmp.Write(buf)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing statefile failed: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), p.Filename); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing statefile failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package persister
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type statefulPlugin struct {
+	state interface{}
+	err   error
+}
+
+func (s *statefulPlugin) GetState() interface{} {
+	return s.state
+}
+
+func (s *statefulPlugin) SetState(state interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.state = state
+	return nil
+}
+
+func tempStatefile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "persister")
+	require.NoError(t, err)
+	return filepath.Join(dir, "state.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestStoreAndLoad(t *testing.T) {
+	filename, cleanup := tempStatefile(t)
+	defer cleanup()
+
+	p := NewPersister(filename)
+	require.NoError(t, p.Register("inputs.tail", &statefulPlugin{
+		state: map[string]interface{}{"/var/log/syslog": 42.0},
+	}))
+	require.NoError(t, p.Register("inputs.other", &statefulPlugin{state: "bookmark"}))
+	require.NoError(t, p.Store())
+
+	tail := &statefulPlugin{}
+	other := &statefulPlugin{}
+	unknown := &statefulPlugin{state: "initial"}
+	p = NewPersister(filename)
+	require.NoError(t, p.Register("inputs.tail", tail))
+	require.NoError(t, p.Register("inputs.other", other))
+	require.NoError(t, p.Register("inputs.unknown", unknown))
+	require.NoError(t, p.Load())
+
+	require.Equal(t, map[string]interface{}{"/var/log/syslog": 42.0}, tail.state)
+	require.Equal(t, "bookmark", other.state)
+	require.Equal(t, "initial", unknown.state)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	filename, cleanup := tempStatefile(t)
+	defer cleanup()
+
+	p := NewPersister(filename)
+	require.NoError(t, p.Register("inputs.tail", &statefulPlugin{}))
+	require.NoError(t, p.Load())
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	filename, cleanup := tempStatefile(t)
+	defer cleanup()
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("not json"), 0644))
+	p := NewPersister(filename)
+	require.Error(t, p.Load())
+}
+
+func TestLoadSetStateError(t *testing.T) {
+	filename, cleanup := tempStatefile(t)
+	defer cleanup()
+
+	p := NewPersister(filename)
+	require.NoError(t, p.Register("inputs.tail", &statefulPlugin{state: 1.0}))
+	require.NoError(t, p.Store())
+
+	// Errors restoring a single plugin are logged and do not fail the load.
+	p = NewPersister(filename)
+	require.NoError(t, p.Register("inputs.tail", &statefulPlugin{err: errors.New("bad state")}))
+	require.NoError(t, p.Load())
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	p := NewPersister("state.json")
+	require.NoError(t, p.Register("inputs.tail", &statefulPlugin{}))
+	require.Error(t, p.Register("inputs.tail", &statefulPlugin{}))
+}
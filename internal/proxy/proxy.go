@@ -0,0 +1,201 @@
+// Package proxy dials the TCP connections of the plugins through a SOCKS5
+// or an HTTP CONNECT proxy, such as a bastion host of an isolated network.
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyConfig represents the standard proxy config of the plugins dialing
+// TCP connections.
+type ProxyConfig struct {
+	ProxyURL string `toml:"proxy_url"`
+}
+
+// Dialer dials connections, through the proxy if one is configured.
+type Dialer struct {
+	timeout time.Duration
+	proxy   *url.URL
+	forward *net.Dialer
+}
+
+// Dialer returns the dialer of the config.  The timeout, if not 0, limits
+// the time to connect, including the handshakes with the proxy and of TLS.
+func (c *ProxyConfig) Dialer(timeout time.Duration) (*Dialer, error) {
+	d := &Dialer{
+		timeout: timeout,
+		forward: &net.Dialer{Timeout: timeout},
+	}
+	if c.ProxyURL == "" {
+		return d, nil
+	}
+
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy_url %q: %s", c.ProxyURL, err)
+	}
+	var port string
+	switch u.Scheme {
+	case "socks5":
+		port = "1080"
+	case "http":
+		port = "80"
+	default:
+		return nil, fmt.Errorf("unknown protocol '%s' in proxy_url, socks5 or http", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in proxy_url %q", c.ProxyURL)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	d.proxy = u
+	return d, nil
+}
+
+// Dial connects to the address on the network.  Only the TCP networks can
+// be dialed through the proxy.
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	if d.proxy == nil {
+		return d.forward.Dial(network, address)
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("proxy_url only applies to tcp, not %s", network)
+	}
+
+	if d.proxy.Scheme == "http" {
+		return d.dialConnect(address)
+	}
+	var auth *proxy.Auth
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		auth = &proxy.Auth{User: d.proxy.User.Username(), Password: password}
+	}
+	// The host of the address is resolved by the proxy.
+	forward := &deadlineDialer{Dialer: d.forward, timeout: d.timeout}
+	socks, err := proxy.SOCKS5("tcp", d.proxy.Host, auth, forward)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := socks.Dial(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %s", d.proxy.Host, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// deadlineDialer sets the deadline of the handshakes with the proxy on the
+// connections it dials.
+type deadlineDialer struct {
+	*net.Dialer
+	timeout time.Duration
+}
+
+func (d *deadlineDialer) Dial(network, address string) (net.Conn, error) {
+	conn, err := d.Dialer.Dial(network, address)
+	if err == nil && d.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.timeout))
+	}
+	return conn, err
+}
+
+// DialTLS connects to the address on the network with TLS.  The server name
+// verified defaults to the host of the address.
+func (d *Dialer) DialTLS(network, address string, config *tls.Config) (net.Conn, error) {
+	if d.proxy == nil {
+		return tls.DialWithDialer(d.forward, network, address, config)
+	}
+
+	conn, err := d.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			config.ServerName = host
+		} else {
+			config.ServerName = address
+		}
+	}
+	tlsConn := tls.Client(conn, config)
+	if d.timeout > 0 {
+		tlsConn.SetDeadline(time.Now().Add(d.timeout))
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// dialConnect opens a tunnel to the address with the CONNECT method of the
+// HTTP proxy.
+func (d *Dialer) dialConnect(address string) (net.Conn, error) {
+	conn, err := d.forward.Dial("tcp", d.proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+	if d.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.timeout))
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		credentials := d.proxy.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %s", d.proxy.Host, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %s", d.proxy.Host, err)
+	}
+	// The body of the response, if any, is the tunnel.
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %s", d.proxy.Host, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	if br.Buffered() > 0 {
+		// The server spoke first, its data was read with the response.
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads the data buffered from the connection first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// echoServer returns the address of a server echoing the lines it reads.
+func echoServer(t *testing.T) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// connectProxy returns the address of an HTTP proxy accepting the CONNECT
+// requests with the authorization, if not empty.
+func connectProxy(t *testing.T, authorization string) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				if req.Method != "CONNECT" {
+					io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+					return
+				}
+				if authorization != "" && req.Header.Get("Proxy-Authorization") != authorization {
+					io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer target.Close()
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// socks5Proxy returns the address of a SOCKS5 proxy accepting the user and
+// the password, or no authentication if the user is empty.
+func socks5Proxy(t *testing.T, user, password string) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				target, err := socks5Handshake(conn, user, password)
+				if err != nil {
+					return
+				}
+				defer target.Close()
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func socks5Handshake(conn net.Conn, user, password string) (net.Conn, error) {
+	b := make([]byte, 512)
+	if _, err := io.ReadFull(conn, b[:2]); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(conn, b[:b[1]]); err != nil {
+		return nil, err
+	}
+	if user == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		if _, err := io.ReadFull(conn, b[:2]); err != nil {
+			return nil, err
+		}
+		u := make([]byte, b[1])
+		io.ReadFull(conn, u)
+		io.ReadFull(conn, b[:1])
+		p := make([]byte, b[0])
+		io.ReadFull(conn, p)
+		if string(u) != user || string(p) != password {
+			conn.Write([]byte{1, 1})
+			return nil, io.EOF
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	// The request, with a domain name or an IPv4 address.
+	if _, err := io.ReadFull(conn, b[:4]); err != nil {
+		return nil, err
+	}
+	var host string
+	switch b[3] {
+	case 1:
+		io.ReadFull(conn, b[:4])
+		host = net.IP(b[:4]).String()
+	case 3:
+		io.ReadFull(conn, b[:1])
+		name := make([]byte, b[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+	io.ReadFull(conn, b[:2])
+	port := binary.BigEndian.Uint16(b[:2])
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return nil, err
+	}
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+	return target, nil
+}
+
+func echo(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	_, err := conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", line)
+}
+
+func TestDialWithoutProxy(t *testing.T) {
+	target, closeTarget := echoServer(t)
+	defer closeTarget()
+
+	d, err := (&ProxyConfig{}).Dialer(time.Second)
+	require.NoError(t, err)
+	conn, err := d.Dial("tcp", target)
+	require.NoError(t, err)
+	echo(t, conn)
+}
+
+func TestDialHTTPConnect(t *testing.T) {
+	target, closeTarget := echoServer(t)
+	defer closeTarget()
+	// "user:secret" in base64.
+	proxyAddr, closeProxy := connectProxy(t, "Basic dXNlcjpzZWNyZXQ=")
+	defer closeProxy()
+
+	c := &ProxyConfig{ProxyURL: "http://user:secret@" + proxyAddr}
+	d, err := c.Dialer(time.Second)
+	require.NoError(t, err)
+	conn, err := d.Dial("tcp", target)
+	require.NoError(t, err)
+	echo(t, conn)
+
+	c = &ProxyConfig{ProxyURL: "http://user:wrong@" + proxyAddr}
+	d, err = c.Dialer(time.Second)
+	require.NoError(t, err)
+	_, err = d.Dial("tcp", target)
+	require.EqualError(t, err, "proxy "+proxyAddr+": 407 Proxy Authentication Required")
+}
+
+func TestDialSOCKS5(t *testing.T) {
+	target, closeTarget := echoServer(t)
+	defer closeTarget()
+	proxyAddr, closeProxy := socks5Proxy(t, "", "")
+	defer closeProxy()
+
+	d, err := (&ProxyConfig{ProxyURL: "socks5://" + proxyAddr}).Dialer(time.Second)
+	require.NoError(t, err)
+	conn, err := d.Dial("tcp", target)
+	require.NoError(t, err)
+	echo(t, conn)
+}
+
+func TestDialSOCKS5Auth(t *testing.T) {
+	target, closeTarget := echoServer(t)
+	defer closeTarget()
+	proxyAddr, closeProxy := socks5Proxy(t, "user", "secret")
+	defer closeProxy()
+
+	d, err := (&ProxyConfig{ProxyURL: "socks5://user:secret@" + proxyAddr}).Dialer(time.Second)
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(target)
+	// The host name is resolved by the proxy.
+	conn, err := d.Dial("tcp", net.JoinHostPort("localhost", port))
+	require.NoError(t, err)
+	echo(t, conn)
+
+	d, err = (&ProxyConfig{ProxyURL: "socks5://user:wrong@" + proxyAddr}).Dialer(time.Second)
+	require.NoError(t, err)
+	_, err = d.Dial("tcp", target)
+	require.Error(t, err)
+}
+
+func TestDialerErrors(t *testing.T) {
+	tests := []struct {
+		url string
+		err string
+	}{
+		{"https://proxy:3128", "unknown protocol 'https' in proxy_url, socks5 or http"},
+		{"proxy:3128", "unknown protocol 'proxy' in proxy_url, socks5 or http"},
+		{"socks5://", `missing host in proxy_url "socks5://"`},
+		// The message of the parse error depends on the version of Go.
+		{"http://proxy%zz:3128", ""},
+	}
+	for _, tt := range tests {
+		_, err := (&ProxyConfig{ProxyURL: tt.url}).Dialer(0)
+		if tt.err == "" {
+			require.Error(t, err, tt.url)
+		} else {
+			require.EqualError(t, err, tt.err)
+		}
+	}
+
+	d, err := (&ProxyConfig{ProxyURL: "socks5://proxy"}).Dialer(0)
+	require.NoError(t, err)
+	require.Equal(t, "proxy:1080", d.proxy.Host)
+	_, err = d.Dial("udp", "127.0.0.1:514")
+	require.EqualError(t, err, "proxy_url only applies to tcp, not udp")
+}
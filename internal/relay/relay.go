@@ -0,0 +1,203 @@
+// Package relay implements the protocol between the relay output and the
+// relay_listener input, forwarding metrics from one Telegraf to another.
+//
+// The protocol runs over a TCP or TLS connection, as a sequence of frames:
+//
+//	length (4 bytes) | type (1 byte) | sequence (8 bytes) | payload
+//
+// with the length of the payload and the integers in big endian.  The
+// client opens with a hello frame, with the version of the protocol, the
+// compression of its batches and its token, to which the server replies
+// with a welcome frame or an error frame before closing the connection.
+// Then each batch frame, with line protocol compressed as announced, is
+// acknowledged by an ack frame with its sequence number once its metrics
+// are added by the server, or rejected by an error frame with its sequence
+// number.  An error frame with the sequence number 0 ends the connection.
+package relay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+)
+
+// Version is the version of the protocol.
+const Version = 1
+
+// DefaultPort is the port of the relay_listener input by default.
+const DefaultPort = "8095"
+
+// Types of the frames.
+const (
+	FrameHello   byte = 1
+	FrameWelcome byte = 2
+	FrameBatch   byte = 3
+	FrameAck     byte = 4
+	FrameError   byte = 5
+)
+
+// Compressions of the batches.
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+)
+
+var compressions = []string{CompressionNone, CompressionGzip, CompressionSnappy}
+
+const headerSize = 13
+
+// ErrFrameTooLarge is returned by ReadFrame for the frames over the maximum
+// size, which are skipped.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// ErrTooLarge is returned by Decompress for the batches over the maximum
+// size once decompressed.
+var ErrTooLarge = errors.New("batch too large")
+
+// Frame is a frame of the protocol.
+type Frame struct {
+	Type    byte
+	Seq     uint64
+	Payload []byte
+}
+
+// WriteFrame writes a frame in a single write.
+func WriteFrame(w io.Writer, f Frame) error {
+	b := make([]byte, headerSize+len(f.Payload))
+	binary.BigEndian.PutUint32(b, uint32(len(f.Payload)))
+	b[4] = f.Type
+	binary.BigEndian.PutUint64(b[5:], f.Seq)
+	copy(b[headerSize:], f.Payload)
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadFrame reads a frame.  The payload of the frames over maxSize bytes is
+// discarded, and the frame returned without it along with
+// ErrFrameTooLarge, so that the sender can be told which frame was skipped.
+func ReadFrame(r io.Reader, maxSize int) (Frame, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Frame{}, err
+	}
+	size := int64(binary.BigEndian.Uint32(header[:]))
+	f := Frame{
+		Type: header[4],
+		Seq:  binary.BigEndian.Uint64(header[5:]),
+	}
+	if size > int64(maxSize) {
+		if _, err := io.CopyN(ioutil.Discard, r, size); err != nil {
+			return f, err
+		}
+		return f, ErrFrameTooLarge
+	}
+	f.Payload = make([]byte, size)
+	if _, err := io.ReadFull(r, f.Payload); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// Hello is the payload of the hello frame.
+type Hello struct {
+	Version     byte
+	Compression string
+	Token       string
+}
+
+// Marshal returns the payload of the hello frame.
+func (h *Hello) Marshal() []byte {
+	code := 0
+	for i, c := range compressions {
+		if c == h.Compression {
+			code = i
+		}
+	}
+	return append([]byte{h.Version, byte(code)}, h.Token...)
+}
+
+// ParseHello parses the payload of a hello frame.
+func ParseHello(payload []byte) (*Hello, error) {
+	if len(payload) < 2 {
+		return nil, fmt.Errorf("invalid hello")
+	}
+	h := &Hello{Version: payload[0], Token: string(payload[2:])}
+	if int(payload[1]) >= len(compressions) {
+		return nil, fmt.Errorf("unknown compression %d", payload[1])
+	}
+	h.Compression = compressions[payload[1]]
+	return h, nil
+}
+
+// CheckCompression returns an error if the compression is unknown.
+func CheckCompression(compression string) error {
+	for _, c := range compressions {
+		if c == compression {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown compression %q", compression)
+}
+
+// Compress compresses a batch.
+func Compress(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// Decompress decompresses a batch, of at most maxSize bytes once
+// decompressed, without decompressing more than that.
+func Decompress(compression string, data []byte, maxSize int) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		if len(data) > maxSize {
+			return nil, ErrTooLarge
+		}
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxSize {
+			return nil, ErrTooLarge
+		}
+		return data, nil
+	case CompressionSnappy:
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxSize {
+			return nil, ErrTooLarge
+		}
+		return snappy.Decode(nil, data)
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
@@ -0,0 +1,68 @@
+package relay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteFrame(&buf, Frame{Type: FrameBatch, Seq: 7, Payload: []byte("cpu value=1 0\n")}))
+	require.NoError(t, WriteFrame(&buf, Frame{Type: FrameBatch, Seq: 8, Payload: []byte(strings.Repeat("a", 100))}))
+	require.NoError(t, WriteFrame(&buf, Frame{Type: FrameAck, Seq: 9}))
+	require.Equal(t, []byte{0, 0, 0, 14, FrameBatch, 0, 0, 0, 0, 0, 0, 0, 7}, buf.Bytes()[:headerSize])
+
+	f, err := ReadFrame(&buf, 64)
+	require.NoError(t, err)
+	require.Equal(t, Frame{Type: FrameBatch, Seq: 7, Payload: []byte("cpu value=1 0\n")}, f)
+
+	// The frames too large are skipped, with their type and sequence.
+	f, err = ReadFrame(&buf, 64)
+	require.Equal(t, ErrFrameTooLarge, err)
+	require.Equal(t, Frame{Type: FrameBatch, Seq: 8}, f)
+
+	f, err = ReadFrame(&buf, 64)
+	require.NoError(t, err)
+	require.Equal(t, Frame{Type: FrameAck, Seq: 9, Payload: []byte{}}, f)
+
+	_, err = ReadFrame(&buf, 64)
+	require.Error(t, err)
+}
+
+func TestHello(t *testing.T) {
+	h := &Hello{Version: Version, Compression: CompressionSnappy, Token: "secret"}
+	parsed, err := ParseHello(h.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, h, parsed)
+
+	_, err = ParseHello([]byte{Version})
+	require.EqualError(t, err, "invalid hello")
+	_, err = ParseHello([]byte{Version, 9})
+	require.EqualError(t, err, "unknown compression 9")
+}
+
+func TestCompression(t *testing.T) {
+	data := []byte(strings.Repeat("cpu,host=a usage=1 1500000000000000000\n", 100))
+	for _, c := range compressions {
+		t.Run(c, func(t *testing.T) {
+			require.NoError(t, CheckCompression(c))
+			compressed, err := Compress(c, data)
+			require.NoError(t, err)
+			if c != CompressionNone {
+				require.True(t, len(compressed) < len(data))
+			}
+
+			decompressed, err := Decompress(c, compressed, len(data))
+			require.NoError(t, err)
+			require.Equal(t, data, decompressed)
+
+			// The batches are not decompressed past the maximum size.
+			_, err = Decompress(c, compressed, len(data)-1)
+			require.Equal(t, ErrTooLarge, err)
+		})
+	}
+	require.EqualError(t, CheckCompression("zstd"), `unknown compression "zstd"`)
+}
@@ -0,0 +1,89 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@never",
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt, time.UTC)
+		require.Error(t, err, tt)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		next string
+	}{
+		{"* * * * *", "2018-06-01T10:15:30Z", "2018-06-01T10:16:00Z"},
+		{"*/15 * * * *", "2018-06-01T10:15:00Z", "2018-06-01T10:30:00Z"},
+		{"30 2 * * *", "2018-06-01T10:15:00Z", "2018-06-02T02:30:00Z"},
+		{"0 9-17/4 * * *", "2018-06-01T13:00:00Z", "2018-06-01T17:00:00Z"},
+		{"0 0 1,15 * *", "2018-06-02T00:00:00Z", "2018-06-15T00:00:00Z"},
+		{"0 0 * * mon-fri", "2018-06-01T12:00:00Z", "2018-06-04T00:00:00Z"},
+		{"0 0 * * 7", "2018-06-01T12:00:00Z", "2018-06-03T00:00:00Z"},
+		{"0 0 1 feb *", "2018-06-01T12:00:00Z", "2019-02-01T00:00:00Z"},
+		{"0 0 29 2 *", "2018-06-01T12:00:00Z", "2020-02-29T00:00:00Z"},
+		// Either the day of month or the day of week matches.
+		{"0 0 13 * fri", "2018-06-01T12:00:00Z", "2018-06-08T00:00:00Z"},
+		{"@monthly", "2018-06-01T12:00:00Z", "2018-07-01T00:00:00Z"},
+		{"@hourly", "2018-06-01T12:00:00Z", "2018-06-01T13:00:00Z"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec, time.UTC)
+		require.NoError(t, err, tt.spec)
+
+		from, err := time.Parse(time.RFC3339, tt.from)
+		require.NoError(t, err)
+		next, err := time.Parse(time.RFC3339, tt.next)
+		require.NoError(t, err)
+
+		require.True(t, next.Equal(s.Next(from)),
+			"%s: expected %s, got %s", tt.spec, next, s.Next(from))
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *", time.UTC)
+	require.NoError(t, err)
+	require.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestNextLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	s, err := Parse("0 2 * * *", loc)
+	require.NoError(t, err)
+
+	from := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.True(t, time.Date(2018, 6, 2, 6, 0, 0, 0, time.UTC).Equal(s.Next(from)))
+
+	// 02:00 does not exist on the day daylight saving time starts, so that
+	// day is skipped.
+	from = time.Date(2018, 3, 10, 12, 0, 0, 0, loc)
+	next := s.Next(from)
+	require.True(t, next.After(from))
+	require.Equal(t, 12, next.Day())
+}
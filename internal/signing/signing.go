@@ -0,0 +1,332 @@
+// Package signing signs the serialized batches written by outputs, so that
+// consumers can detect modified, dropped or reordered batches.
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// Signing methods.
+const (
+	HMACSHA256 = "hmac-sha256"
+	Ed25519    = "ed25519"
+)
+
+// Config is the signing configuration of an output.
+type Config struct {
+	// SignatureMethod is one of "hmac-sha256" or "ed25519", an empty
+	// method disables signing.
+	SignatureMethod string `toml:"signature_method"`
+	// SignatureKey is the shared secret for "hmac-sha256", or the hex or
+	// base64 encoded private key or seed for "ed25519".
+	SignatureKey     string `toml:"signature_key"`
+	SignatureKeyFile string `toml:"signature_key_file"`
+	// SignatureKeyID identifies the key to the consumers.
+	SignatureKeyID string `toml:"signature_key_id"`
+	// SignatureChain links each signature to the previous one.
+	SignatureChain bool `toml:"signature_chain"`
+}
+
+// Signer returns a Signer for the configuration, or nil if signing is not
+// configured.
+func (c *Config) Signer() (*Signer, error) {
+	if c.SignatureMethod == "" {
+		return nil, nil
+	}
+
+	key, err := readKey(c.SignatureKey, c.SignatureKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Signer{
+		keyID: c.SignatureKeyID,
+		chain: c.SignatureChain,
+		seq:   1,
+	}
+	switch c.SignatureMethod {
+	case HMACSHA256:
+		s.method = HMACSHA256
+		s.hmacKey = []byte(key)
+	case Ed25519:
+		b, err := decodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		switch len(b) {
+		case 32:
+			s.privateKey = newKeyFromSeed(b)
+		case ed25519.PrivateKeySize:
+			s.privateKey = ed25519.PrivateKey(b)
+		default:
+			return nil, fmt.Errorf("ed25519 key must be a 32 byte seed or a %d byte private key",
+				ed25519.PrivateKeySize)
+		}
+		s.method = Ed25519
+	default:
+		return nil, fmt.Errorf("unknown signature_method %q", c.SignatureMethod)
+	}
+	return s, nil
+}
+
+// Signature is the signature of a batch.
+type Signature struct {
+	KeyID  string
+	Method string
+	// Sequence and Previous are only set for chained signatures.  The first
+	// signature after a restart has the sequence 1 and no previous
+	// signature.
+	Sequence uint64
+	Previous []byte
+	Value    []byte
+}
+
+// String encodes the signature as a list of comma separated key=value
+// pairs, with the binary values in base64.
+func (s *Signature) String() string {
+	parts := make([]string, 0, 5)
+	if s.KeyID != "" {
+		parts = append(parts, "keyid="+s.KeyID)
+	}
+	parts = append(parts, "method="+s.Method)
+	if s.Sequence > 0 {
+		parts = append(parts, "seq="+strconv.FormatUint(s.Sequence, 10))
+		if len(s.Previous) > 0 {
+			parts = append(parts, "prev="+base64.StdEncoding.EncodeToString(s.Previous))
+		}
+	}
+	parts = append(parts, "sig="+base64.StdEncoding.EncodeToString(s.Value))
+	return strings.Join(parts, ",")
+}
+
+// ParseSignature decodes a signature encoded by Signature.String.
+func ParseSignature(v string) (*Signature, error) {
+	s := &Signature{}
+	for _, part := range strings.Split(v, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid signature part %q", part)
+		}
+		var err error
+		switch kv[0] {
+		case "keyid":
+			s.KeyID = kv[1]
+		case "method":
+			s.Method = kv[1]
+		case "seq":
+			s.Sequence, err = strconv.ParseUint(kv[1], 10, 64)
+		case "prev":
+			s.Previous, err = base64.StdEncoding.DecodeString(kv[1])
+		case "sig":
+			s.Value, err = base64.StdEncoding.DecodeString(kv[1])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature %s: %s", kv[0], err)
+		}
+	}
+	if s.Method == "" || len(s.Value) == 0 {
+		return nil, fmt.Errorf("signature has no method or value")
+	}
+	return s, nil
+}
+
+// Signer signs batches.  When chaining, each signature covers the sequence
+// number and the previous acknowledged signature in addition to the batch.
+type Signer struct {
+	method     string
+	keyID      string
+	hmacKey    []byte
+	privateKey ed25519.PrivateKey
+	chain      bool
+
+	mu   sync.Mutex
+	seq  uint64
+	prev []byte
+}
+
+// Sign signs the serialized batch.  Chained signatures only advance when
+// the batch is acknowledged, so that a batch which failed to be written is
+// signed with the same sequence number when retried.
+func (s *Signer) Sign(body []byte) *Signature {
+	sig := &Signature{
+		KeyID:  s.keyID,
+		Method: s.method,
+	}
+	if s.chain {
+		s.mu.Lock()
+		sig.Sequence = s.seq
+		sig.Previous = s.prev
+		s.mu.Unlock()
+	}
+
+	msg := message(sig, body)
+	switch s.method {
+	case HMACSHA256:
+		sig.Value = hmacSHA256(s.hmacKey, msg)
+	case Ed25519:
+		sig.Value = ed25519.Sign(s.privateKey, msg)
+	}
+	return sig
+}
+
+// Ack records that the batch of the signature was written, so that the
+// next signature is chained to it.
+func (s *Signer) Ack(sig *Signature) {
+	if !s.chain {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sig.Sequence == s.seq {
+		s.seq++
+		s.prev = sig.Value
+	}
+}
+
+// Verifier verifies the signatures of batches.  For chained signatures it
+// also checks that no batch is missing or out of order; a sequence of 1
+// starts a new chain, such as after a restart of the sender.
+type Verifier struct {
+	method    string
+	hmacKey   []byte
+	publicKey ed25519.PublicKey
+
+	mu   sync.Mutex
+	seq  uint64
+	prev []byte
+}
+
+// NewVerifier returns a verifier of the method, using the shared secret
+// for "hmac-sha256" or the hex or base64 encoded public key for "ed25519".
+func NewVerifier(method, key string) (*Verifier, error) {
+	v := &Verifier{method: method}
+	switch method {
+	case HMACSHA256:
+		v.hmacKey = []byte(key)
+	case Ed25519:
+		b, err := decodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("ed25519 public key must be %d bytes", ed25519.PublicKeySize)
+		}
+		v.publicKey = ed25519.PublicKey(b)
+	default:
+		return nil, fmt.Errorf("unknown signature method %q", method)
+	}
+	return v, nil
+}
+
+// Verify checks the encoded signature of the serialized batch.
+func (v *Verifier) Verify(body []byte, signature string) error {
+	sig, err := ParseSignature(signature)
+	if err != nil {
+		return err
+	}
+	if sig.Method != v.method {
+		return fmt.Errorf("signature method %q does not match %q", sig.Method, v.method)
+	}
+
+	msg := message(sig, body)
+	switch v.method {
+	case HMACSHA256:
+		if !hmac.Equal(sig.Value, hmacSHA256(v.hmacKey, msg)) {
+			return fmt.Errorf("invalid signature")
+		}
+	case Ed25519:
+		if !ed25519.Verify(v.publicKey, msg, sig.Value) {
+			return fmt.Errorf("invalid signature")
+		}
+	}
+
+	if sig.Sequence == 0 {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if sig.Sequence != 1 {
+		if sig.Sequence != v.seq+1 || !hmac.Equal(sig.Previous, v.prev) {
+			return fmt.Errorf("broken signature chain: expected sequence %d, got %d",
+				v.seq+1, sig.Sequence)
+		}
+	}
+	v.seq = sig.Sequence
+	v.prev = sig.Value
+	return nil
+}
+
+// message returns the signed message of the batch: for chained signatures
+// the big endian sequence number and the previous signature followed by
+// the batch, otherwise the batch alone.
+func message(sig *Signature, body []byte) []byte {
+	if sig.Sequence == 0 {
+		return body
+	}
+	msg := make([]byte, 8, 8+len(sig.Previous)+len(body))
+	binary.BigEndian.PutUint64(msg, sig.Sequence)
+	msg = append(msg, sig.Previous...)
+	return append(msg, body...)
+}
+
+func hmacSHA256(key, msg []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+func readKey(key, keyFile string) (string, error) {
+	if keyFile != "" {
+		b, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("reading signature_key_file: %s", err)
+		}
+		key = strings.TrimSpace(string(b))
+	}
+	if key == "" {
+		return "", fmt.Errorf("signing requires a signature_key or signature_key_file")
+	}
+	return key, nil
+}
+
+// decodeKey decodes a hex or base64 encoded key.
+func decodeKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if b, err := hex.DecodeString(key); err == nil {
+		return b, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("key is neither hex nor base64 encoded")
+	}
+	return b, nil
+}
+
+// newKeyFromSeed derives the private key of a seed, as in RFC 8032.
+func newKeyFromSeed(seed []byte) ed25519.PrivateKey {
+	_, priv, _ := ed25519.GenerateKey(&seedReader{seed: seed})
+	return priv
+}
+
+// seedReader returns the seed to ed25519.GenerateKey, which reads the
+// private key seed from its random source.
+type seedReader struct {
+	seed []byte
+}
+
+func (r *seedReader) Read(p []byte) (int, error) {
+	n := copy(p, r.seed)
+	r.seed = r.seed[n:]
+	return n, nil
+}
@@ -0,0 +1,170 @@
+package signing
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	seed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+	// Public key of the seed, from the test vectors of RFC 8032.
+	publicKey = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+)
+
+func TestNotConfigured(t *testing.T) {
+	c := &Config{}
+	s, err := c.Signer()
+	require.NoError(t, err)
+	require.Nil(t, s)
+}
+
+func TestHMAC(t *testing.T) {
+	c := &Config{
+		SignatureMethod: "hmac-sha256",
+		SignatureKey:    "secret",
+		SignatureKeyID:  "k1",
+	}
+	s, err := c.Signer()
+	require.NoError(t, err)
+
+	body := []byte("cpu value=1 0\n")
+	sig := s.Sign(body)
+	require.Equal(t, "keyid=k1,method=hmac-sha256,sig=Rd+/d61EoqEYDLgJpB2tQpZd/vbT10xnXZFoVQjD0Wc=",
+		sig.String())
+
+	v, err := NewVerifier("hmac-sha256", "secret")
+	require.NoError(t, err)
+	require.NoError(t, v.Verify(body, sig.String()))
+	require.Error(t, v.Verify([]byte("cpu value=2 0\n"), sig.String()))
+
+	v, err = NewVerifier("hmac-sha256", "other")
+	require.NoError(t, err)
+	require.Error(t, v.Verify(body, sig.String()))
+}
+
+func TestEd25519(t *testing.T) {
+	c := &Config{
+		SignatureMethod: "ed25519",
+		SignatureKey:    seed,
+	}
+	s, err := c.Signer()
+	require.NoError(t, err)
+	require.Equal(t, publicKey, hex.EncodeToString(s.privateKey.Public().(ed25519.PublicKey)))
+
+	body := []byte("cpu value=1 0\n")
+	sig := s.Sign(body)
+
+	v, err := NewVerifier("ed25519", publicKey)
+	require.NoError(t, err)
+	require.NoError(t, v.Verify(body, sig.String()))
+	require.Error(t, v.Verify([]byte("cpu value=2 0\n"), sig.String()))
+
+	v, err = NewVerifier("hmac-sha256", "secret")
+	require.NoError(t, err)
+	require.Error(t, v.Verify(body, sig.String()))
+}
+
+func TestKeyFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "signing")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(seed + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	c := &Config{
+		SignatureMethod:  "ed25519",
+		SignatureKeyFile: f.Name(),
+	}
+	s, err := c.Signer()
+	require.NoError(t, err)
+	require.Equal(t, publicKey, hex.EncodeToString(s.privateKey.Public().(ed25519.PublicKey)))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []*Config{
+		{SignatureMethod: "md5", SignatureKey: "secret"},
+		{SignatureMethod: "hmac-sha256"},
+		{SignatureMethod: "ed25519", SignatureKey: "00112233"},
+		{SignatureMethod: "ed25519", SignatureKey: "not a key!"},
+		{SignatureMethod: "hmac-sha256", SignatureKeyFile: "/nonexistent"},
+	}
+	for _, c := range tests {
+		_, err := c.Signer()
+		require.Error(t, err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	c := &Config{
+		SignatureMethod: "hmac-sha256",
+		SignatureKey:    "secret",
+		SignatureChain:  true,
+	}
+	s, err := c.Signer()
+	require.NoError(t, err)
+	v, err := NewVerifier("hmac-sha256", "secret")
+	require.NoError(t, err)
+
+	batch1 := []byte("cpu value=1 0\n")
+	sig1 := s.Sign(batch1)
+	require.Equal(t, uint64(1), sig1.Sequence)
+	require.Empty(t, sig1.Previous)
+
+	// A batch that failed to be written is signed again with the same
+	// sequence number.
+	require.Equal(t, sig1.String(), s.Sign(batch1).String())
+	s.Ack(sig1)
+	require.NoError(t, v.Verify(batch1, sig1.String()))
+
+	batch2 := []byte("cpu value=2 0\n")
+	sig2 := s.Sign(batch2)
+	require.Equal(t, uint64(2), sig2.Sequence)
+	require.Equal(t, sig1.Value, sig2.Previous)
+	s.Ack(sig2)
+
+	batch3 := []byte("cpu value=3 0\n")
+	sig3 := s.Sign(batch3)
+	s.Ack(sig3)
+
+	// Batch 2 is dropped.
+	err = v.Verify(batch3, sig3.String())
+	require.EqualError(t, err, "broken signature chain: expected sequence 2, got 3")
+
+	// The previous signature is part of the signed message.
+	sig3.Previous = sig1.Value
+	require.Error(t, v.Verify(batch3, sig3.String()))
+
+	// A restarted sender starts a new chain.
+	s, err = c.Signer()
+	require.NoError(t, err)
+	sig := s.Sign(batch1)
+	require.NoError(t, v.Verify(batch1, sig.String()))
+}
+
+func TestParseSignature(t *testing.T) {
+	sig := &Signature{
+		KeyID:    "k1",
+		Method:   "ed25519",
+		Sequence: 7,
+		Previous: []byte{1, 2, 3},
+		Value:    []byte{4, 5, 6},
+	}
+	require.Equal(t, "keyid=k1,method=ed25519,seq=7,prev=AQID,sig=BAUG", sig.String())
+
+	parsed, err := ParseSignature(sig.String())
+	require.NoError(t, err)
+	require.Equal(t, sig, parsed)
+
+	_, err = ParseSignature("method=ed25519")
+	require.Error(t, err)
+	_, err = ParseSignature("method=ed25519,sig=!!")
+	require.Error(t, err)
+	_, err = ParseSignature("garbage")
+	require.Error(t, err)
+}
@@ -0,0 +1,9 @@
+// +build boringcrypto
+
+package tls
+
+// The fipsonly package restricts crypto/tls to the FIPS 140-2 approved
+// settings, it is only available in the BoringCrypto branch of Go.
+import _ "crypto/tls/fipsonly"
+
+const boringCrypto = true
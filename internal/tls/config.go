@@ -28,7 +28,9 @@ type ServerConfig struct {
 }
 
 // TLSConfig returns a tls.Config, may be nil without error if TLS is not
-// configured.
+// configured.  As plugins connect in plain text when it is nil, the policy is
+// not applied to it; plugins making TLS connections without TLS options must
+// use PolicyConfig instead.
 func (c *ClientConfig) TLSConfig() (*tls.Config, error) {
 	// Support deprecated variable names
 	if c.TLSCA == "" && c.SSLCA != "" {
@@ -69,11 +71,13 @@ func (c *ClientConfig) TLSConfig() (*tls.Config, error) {
 		}
 	}
 
+	ApplyPolicy(tlsConfig)
 	return tlsConfig, nil
 }
 
 // TLSConfig returns a tls.Config, may be nil without error if TLS is not
-// configured.
+// configured, in which case the plugin does not serve TLS and the policy does
+// not apply.
 func (c *ServerConfig) TLSConfig() (*tls.Config, error) {
 	if c.TLSCert == "" && c.TLSKey == "" && len(c.TLSAllowedCACerts) == 0 {
 		return nil, nil
@@ -97,6 +101,7 @@ func (c *ServerConfig) TLSConfig() (*tls.Config, error) {
 		}
 	}
 
+	ApplyPolicy(tlsConfig)
 	return tlsConfig, nil
 }
 
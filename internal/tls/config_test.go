@@ -1,6 +1,7 @@
 package tls_test
 
 import (
+	ctls "crypto/tls"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -224,3 +225,140 @@ func TestConnect(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 200, resp.StatusCode)
 }
+
+func TestSetPolicy(t *testing.T) {
+	defer tls.SetPolicy("", false)
+
+	clientConfig := tls.ClientConfig{
+		TLSCA: pki.CACertPath(),
+	}
+	serverConfig := tls.ServerConfig{
+		TLSCert: pki.ServerCertPath(),
+		TLSKey:  pki.ServerKeyPath(),
+	}
+
+	require.EqualError(t, tls.SetPolicy("SSL3", false), `unsupported tls_min_version "SSL3"`)
+
+	require.NoError(t, tls.SetPolicy("TLS11", false))
+	client, err := clientConfig.TLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, uint16(ctls.VersionTLS11), client.MinVersion)
+	require.Nil(t, client.CipherSuites)
+
+	require.NoError(t, tls.SetPolicy("", true))
+	server, err := serverConfig.TLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, uint16(ctls.VersionTLS12), server.MinVersion)
+	require.Contains(t, server.CipherSuites, ctls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+	require.NotContains(t, server.CipherSuites, ctls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305)
+	require.NotContains(t, server.CurvePreferences, ctls.X25519)
+
+	// The policy only raises the minimum version of plugins.
+	config := &ctls.Config{MinVersion: ctls.VersionTLS12}
+	require.NoError(t, tls.SetPolicy("TLS10", false))
+	tls.ApplyPolicy(config)
+	require.Equal(t, uint16(ctls.VersionTLS12), config.MinVersion)
+
+	require.NoError(t, tls.SetPolicy("", false))
+	client, err = clientConfig.TLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, uint16(0), client.MinVersion)
+}
+
+func TestPolicyConfig(t *testing.T) {
+	defer tls.SetPolicy("", false)
+
+	// Plugins without TLS options connect in plain text.
+	empty := tls.ClientConfig{}
+	client, err := empty.TLSConfig()
+	require.NoError(t, err)
+	require.Nil(t, client)
+	require.Nil(t, tls.PolicyConfig())
+
+	require.NoError(t, tls.SetPolicy("TLS12", false))
+	client, err = empty.TLSConfig()
+	require.NoError(t, err)
+	require.Nil(t, client)
+	config := tls.PolicyConfig()
+	require.Equal(t, uint16(ctls.VersionTLS12), config.MinVersion)
+	require.Equal(t, ctls.RenegotiateNever, config.Renegotiation)
+
+	require.NoError(t, tls.SetPolicy("", false))
+	require.Nil(t, tls.PolicyConfig())
+}
+
+func TestParseVersionAndCipherSuites(t *testing.T) {
+	v, err := tls.ParseVersion("TLS11")
+	require.NoError(t, err)
+	require.Equal(t, uint16(ctls.VersionTLS11), v)
+	_, err = tls.ParseVersion("TLS1.2")
+	require.EqualError(t, err, `unsupported TLS version "TLS1.2"`)
+
+	suites, err := tls.ParseCipherSuites([]string{
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{
+		ctls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		ctls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	}, suites)
+	_, err = tls.ParseCipherSuites([]string{"TLS_RSA_WITH_NULL_SHA"})
+	require.EqualError(t, err, `unsupported cipher suite "TLS_RSA_WITH_NULL_SHA"`)
+
+	// The FIPS policy only keeps the approved cipher suites of plugins.
+	defer tls.SetPolicy("", false)
+	require.NoError(t, tls.SetPolicy("", true))
+	config := &ctls.Config{CipherSuites: suites}
+	tls.ApplyPolicy(config)
+	require.Equal(t, []uint16{ctls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, config.CipherSuites)
+
+	config = &ctls.Config{CipherSuites: []uint16{ctls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305}}
+	tls.ApplyPolicy(config)
+	require.Equal(t, []uint16{}, config.CipherSuites)
+}
+
+func TestConnectPolicy(t *testing.T) {
+	defer tls.SetPolicy("", false)
+	require.NoError(t, tls.SetPolicy("TLS12", true))
+
+	serverConfig := tls.ServerConfig{
+		TLSCert: pki.ServerCertPath(),
+		TLSKey:  pki.ServerKeyPath(),
+	}
+	serverTLSConfig, err := serverConfig.TLSConfig()
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = serverTLSConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	clientConfig := tls.ClientConfig{
+		TLSCA: pki.CACertPath(),
+	}
+	clientTLSConfig, err := clientConfig.TLSConfig()
+	require.NoError(t, err)
+
+	client := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: clientTLSConfig,
+		},
+		Timeout: 10 * time.Second,
+	}
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+
+	// Clients not allowed by the policy are refused.
+	client.Transport = &http.Transport{
+		TLSClientConfig: &ctls.Config{
+			InsecureSkipVerify: true,
+			MaxVersion:         ctls.VersionTLS11,
+		},
+	}
+	_, err = client.Get(ts.URL)
+	require.Error(t, err)
+}
@@ -0,0 +1,5 @@
+// +build !boringcrypto
+
+package tls
+
+const boringCrypto = false
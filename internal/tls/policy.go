@@ -0,0 +1,175 @@
+package tls
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Policy restricts the TLS configurations of all plugins.  It is set by the
+// agent from the tls_min_version and fips options.
+type Policy struct {
+	MinVersion       uint16
+	CipherSuites     []uint16
+	CurvePreferences []tls.CurveID
+}
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+}
+
+// cipherSuites are the cipher suites supported by crypto/tls, by IANA name.
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+// fipsCipherSuites are the cipher suites approved by FIPS 140-2.
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// fipsCurves are the elliptic curves approved by FIPS 140-2.
+var fipsCurves = []tls.CurveID{
+	tls.CurveP256,
+	tls.CurveP384,
+	tls.CurveP521,
+}
+
+var (
+	mu     sync.RWMutex
+	policy Policy
+)
+
+// SetPolicy sets the policy applied to the TLS configurations, from the
+// minimum TLS version, one of "TLS10", "TLS11" or "TLS12", and whether only
+// FIPS 140-2 approved TLS versions, cipher suites and curves are allowed.
+func SetPolicy(minVersion string, fips bool) error {
+	var p Policy
+	if minVersion != "" {
+		v, ok := tlsVersions[minVersion]
+		if !ok {
+			return fmt.Errorf("unsupported tls_min_version %q", minVersion)
+		}
+		p.MinVersion = v
+	}
+
+	if fips {
+		if p.MinVersion < tls.VersionTLS12 {
+			p.MinVersion = tls.VersionTLS12
+		}
+		p.CipherSuites = fipsCipherSuites
+		p.CurvePreferences = fipsCurves
+		if !boringCrypto {
+			log.Printf("W! FIPS mode restricts the TLS settings of the plugins, " +
+				"but Telegraf is not built with the BoringCrypto module (make telegraf-fips)")
+		}
+	}
+
+	mu.Lock()
+	policy = p
+	mu.Unlock()
+	return nil
+}
+
+// ParseVersion returns the TLS version of its name, one of "TLS10", "TLS11"
+// or "TLS12".
+func ParseVersion(name string) (uint16, error) {
+	v, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q", name)
+	}
+	return v, nil
+}
+
+// ParseCipherSuites returns the cipher suites of their IANA names, such as
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		suite, ok := cipherSuites[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+// PolicyConfig returns a tls.Config with only the policy applied, nil if no
+// policy is set.  It is the configuration of the TLS connections of plugins
+// without TLS options, such as HTTP clients of https URLs.
+func PolicyConfig() *tls.Config {
+	mu.RLock()
+	p := policy
+	mu.RUnlock()
+
+	if p.MinVersion == 0 && p.CipherSuites == nil && p.CurvePreferences == nil {
+		return nil
+	}
+	c := &tls.Config{Renegotiation: tls.RenegotiateNever}
+	ApplyPolicy(c)
+	return c
+}
+
+// ApplyPolicy restricts a TLS configuration to the policy.  Configurations
+// returned by ClientConfig and ServerConfig already have the policy
+// applied, plugins creating their own configurations must apply it.
+func ApplyPolicy(c *tls.Config) {
+	mu.RLock()
+	p := policy
+	mu.RUnlock()
+
+	if c.MinVersion < p.MinVersion {
+		c.MinVersion = p.MinVersion
+	}
+	if p.CipherSuites != nil {
+		if c.CipherSuites == nil {
+			c.CipherSuites = p.CipherSuites
+		} else {
+			// Only keep the cipher suites of the plugin allowed by the
+			// policy, none if none is.
+			suites := []uint16{}
+			for _, suite := range c.CipherSuites {
+				for _, allowed := range p.CipherSuites {
+					if suite == allowed {
+						suites = append(suites, suite)
+					}
+				}
+			}
+			c.CipherSuites = suites
+		}
+		c.PreferServerCipherSuites = true
+	}
+	if p.CurvePreferences != nil {
+		c.CurvePreferences = p.CurvePreferences
+	}
+}
@@ -0,0 +1,64 @@
+// token is a package for caching the access tokens of the plugins
+// authenticating with a token service.
+package token
+
+import (
+	"sync"
+	"time"
+)
+
+// RenewBefore is how long before they expire the tokens are renewed.
+const RenewBefore = time.Minute
+
+// FetchFunc requests a new token, returning it with the time it expires, or
+// a zero time if it does not expire.
+type FetchFunc func() (token string, expiry time.Time, err error)
+
+// Cache caches a token until shortly before it expires, or until it is
+// invalidated.  The zero value is an empty cache.
+type Cache struct {
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns the cached token if it is still valid, otherwise the token
+// requested with fetch.  The concurrent calls wait for the token being
+// requested.
+func (c *Cache) Token(fetch FetchFunc) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.expiry.IsZero() || c.now().Before(c.expiry)) {
+		return c.token, nil
+	}
+
+	token, expiry, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expiry = time.Time{}
+	if !expiry.IsZero() {
+		c.expiry = expiry.Add(-RenewBefore)
+	}
+	return c.token, nil
+}
+
+// Invalidate discards the cached token, for example after a request was
+// rejected as unauthorized.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+func (c *Cache) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
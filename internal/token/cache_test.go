@@ -0,0 +1,72 @@
+package token
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &Cache{Now: func() time.Time { return now }}
+
+	var fetches int
+	fetch := func() (string, time.Time, error) {
+		fetches++
+		return "token", now.Add(time.Hour), nil
+	}
+
+	token, err := c.Token(fetch)
+	require.NoError(t, err)
+	require.Equal(t, "token", token)
+
+	// The token is cached until shortly before it expires.
+	now = now.Add(58 * time.Minute)
+	_, err = c.Token(fetch)
+	require.NoError(t, err)
+	require.Equal(t, 1, fetches)
+
+	now = now.Add(time.Minute)
+	_, err = c.Token(fetch)
+	require.NoError(t, err)
+	require.Equal(t, 2, fetches)
+
+	// or until it is invalidated.
+	c.Invalidate()
+	_, err = c.Token(fetch)
+	require.NoError(t, err)
+	require.Equal(t, 3, fetches)
+}
+
+func TestCacheNoExpiry(t *testing.T) {
+	c := &Cache{}
+
+	var fetches int
+	fetch := func() (string, time.Time, error) {
+		fetches++
+		return "token", time.Time{}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Token(fetch)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, fetches)
+}
+
+func TestCacheError(t *testing.T) {
+	c := &Cache{}
+
+	_, err := c.Token(func() (string, time.Time, error) {
+		return "", time.Time{}, errors.New("denied")
+	})
+	require.EqualError(t, err, "denied")
+
+	token, err := c.Token(func() (string, time.Time, error) {
+		return "token", time.Time{}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "token", token)
+}
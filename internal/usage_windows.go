@@ -11,13 +11,16 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config check        check the configuration, print the problems as JSON
   version             print the version to stdout
+  plugins             print the available plugins and their options as JSON
 
   --config <file>     configuration file to load
   --test              gather metrics once, print them to stdout, and exit
   --config-directory  directory containing additional *.conf files
   --input-filter      filter the input plugins to enable, separator is :
   --output-filter     filter the output plugins to enable, separator is :
+  --profiles          profiles of the plugins to enable, separator is ,
   --usage             print usage for a plugin, ie, 'telegraf --usage mysql'
   --debug             print metrics as they're generated to stdout
   --pprof-addr        pprof address to listen on, format: localhost:6060 or :6060
@@ -34,6 +37,12 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
+  # list the options of the cpu input & influxdb output plugins as JSON
+  telegraf --input-filter cpu --output-filter influxdb plugins
+
+  # check a configuration before deploying it
+  telegraf --config telegraf.conf config check
+
   # run a single telegraf collection, outputing metrics to stdout
   telegraf --config telegraf.conf --test
 
@@ -43,6 +52,9 @@ Examples:
   # run telegraf, enabling the cpu & memory input, and influxdb output plugins
   telegraf --config telegraf.conf --input-filter cpu:mem --output-filter influxdb
 
+  # run telegraf with the plugins of the web role and those without profiles
+  telegraf --config telegraf.conf --profiles role:web
+
   # run telegraf with pprof
   telegraf --config telegraf.conf --pprof-addr localhost:6060
 
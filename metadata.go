@@ -0,0 +1,17 @@
+package telegraf
+
+// DeprecatedPlugin is implemented by plugins that are deprecated and will be
+// removed in a future version.
+type DeprecatedPlugin interface {
+	// Deprecated returns the version in which the plugin was deprecated and
+	// a notice on what to use instead.
+	Deprecated() (since string, notice string)
+}
+
+// DependentPlugin is implemented by plugins that require external programs
+// or libraries, which are not part of Telegraf, to be installed on the host.
+type DependentPlugin interface {
+	// Dependencies returns the names of the required programs or libraries,
+	// such as "ipmitool".
+	Dependencies() []string
+}
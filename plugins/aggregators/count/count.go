@@ -0,0 +1,103 @@
+package count
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "60s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = true
+
+  ## Tags to group the metrics by, the other tags are removed from the
+  ## counts.  If empty, the metrics are grouped by all of their tags.
+  group_by = ["severity", "appname", "hostname"]
+`
+
+type Count struct {
+	GroupBy []string `toml:"group_by"`
+
+	cache map[string]*group
+}
+
+type group struct {
+	name  string
+	tags  map[string]string
+	count int64
+}
+
+func NewCount() *Count {
+	c := &Count{}
+	c.Reset()
+	return c
+}
+
+func (c *Count) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Count) Description() string {
+	return "Count the metrics of each group of tags, such as log events by severity."
+}
+
+func (c *Count) Add(in telegraf.Metric) {
+	tags := in.Tags()
+	if len(c.GroupBy) > 0 {
+		tags = make(map[string]string, len(c.GroupBy))
+		for _, key := range c.GroupBy {
+			if value, ok := in.GetTag(key); ok {
+				tags[key] = value
+			}
+		}
+	}
+
+	id := groupID(in.Name(), tags)
+	g, ok := c.cache[id]
+	if !ok {
+		g = &group{
+			name: in.Name(),
+			tags: tags,
+		}
+		c.cache[id] = g
+	}
+	g.count++
+}
+
+func (c *Count) Push(acc telegraf.Accumulator) {
+	for _, g := range c.cache {
+		acc.AddFields(g.name, map[string]interface{}{"count": g.count}, g.tags)
+	}
+}
+
+func (c *Count) Reset() {
+	c.cache = make(map[string]*group)
+}
+
+// groupID returns a key identifying the measurement and tags of a group.
+func groupID(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, 2*len(keys)+1)
+	parts = append(parts, name)
+	for _, k := range keys {
+		parts = append(parts, k, tags[k])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func init() {
+	aggregators.Add("count", func() telegraf.Aggregator {
+		return NewCount()
+	})
+}
@@ -0,0 +1,118 @@
+package count
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func syslog(severity, appname string) telegraf.Metric {
+	m, _ := metric.New("syslog",
+		map[string]string{
+			"severity": severity,
+			"appname":  appname,
+			"hostname": "web01",
+			"facility": "daemon",
+		},
+		map[string]interface{}{
+			"message":       "connection closed",
+			"severity_code": 3,
+		},
+		time.Now(),
+	)
+	return m
+}
+
+func TestCountGroupBy(t *testing.T) {
+	c := NewCount()
+	c.GroupBy = []string{"severity", "appname"}
+
+	c.Add(syslog("err", "sshd"))
+	c.Add(syslog("err", "sshd"))
+	c.Add(syslog("info", "sshd"))
+	c.Add(syslog("err", "nginx"))
+
+	var acc testutil.Accumulator
+	c.Push(&acc)
+
+	require.Len(t, acc.Metrics, 3)
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{"count": int64(2)},
+		map[string]string{"severity": "err", "appname": "sshd"})
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"severity": "info", "appname": "sshd"})
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"severity": "err", "appname": "nginx"})
+}
+
+func TestCountMissingTag(t *testing.T) {
+	c := NewCount()
+	c.GroupBy = []string{"severity", "procid"}
+
+	c.Add(syslog("err", "sshd"))
+	c.Add(syslog("err", "nginx"))
+
+	var acc testutil.Accumulator
+	c.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{"count": int64(2)},
+		map[string]string{"severity": "err"})
+}
+
+func TestCountAllTags(t *testing.T) {
+	c := NewCount()
+
+	c.Add(syslog("err", "sshd"))
+	c.Add(syslog("err", "sshd"))
+	m, _ := metric.New("other",
+		map[string]string{"severity": "err"},
+		map[string]interface{}{"value": 1},
+		time.Now())
+	c.Add(m)
+
+	var acc testutil.Accumulator
+	c.Push(&acc)
+
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{"count": int64(2)},
+		map[string]string{
+			"severity": "err",
+			"appname":  "sshd",
+			"hostname": "web01",
+			"facility": "daemon",
+		})
+	acc.AssertContainsTaggedFields(t, "other",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"severity": "err"})
+}
+
+func TestCountReset(t *testing.T) {
+	c := NewCount()
+	c.GroupBy = []string{"severity"}
+
+	c.Add(syslog("err", "sshd"))
+
+	var acc testutil.Accumulator
+	c.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+
+	c.Reset()
+	acc.ClearMetrics()
+	c.Push(&acc)
+	require.Len(t, acc.Metrics, 0)
+
+	c.Add(syslog("err", "sshd"))
+	c.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"severity": "err"})
+}
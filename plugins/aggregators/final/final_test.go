@@ -0,0 +1,74 @@
+package final
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimple(t *testing.T) {
+	acc := testutil.Accumulator{}
+	final := NewFinal()
+
+	tags := map[string]string{"foo": "bar"}
+	start := time.Now().Add(-10 * time.Minute)
+	m1, _ := metric.New("m1", tags, map[string]interface{}{"a": int64(1)}, start)
+	m2, _ := metric.New("m1", tags, map[string]interface{}{"a": int64(2)}, start.Add(time.Second))
+	m3, _ := metric.New("m1", tags, map[string]interface{}{"a": int64(3)}, start.Add(2*time.Second))
+	final.Add(m1)
+	final.Add(m2)
+	final.Add(m3)
+	final.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{"a_final": int64(3)}, tags)
+	require.Equal(t, uint64(1), acc.NMetrics())
+
+	// The series is emitted only once.
+	acc.ClearMetrics()
+	final.Reset()
+	final.Push(&acc)
+	require.Equal(t, uint64(0), acc.NMetrics())
+}
+
+func TestTwoSeries(t *testing.T) {
+	acc := testutil.Accumulator{}
+	final := NewFinal()
+
+	old := time.Now().Add(-10 * time.Minute)
+	m1, _ := metric.New("m1", map[string]string{"host": "a"},
+		map[string]interface{}{"a": 1.0}, old)
+	m2, _ := metric.New("m1", map[string]string{"host": "b"},
+		map[string]interface{}{"a": 2.0}, time.Now())
+	final.Add(m1)
+	final.Add(m2)
+	final.Push(&acc)
+
+	// Only the series that stopped updating is final.
+	require.Equal(t, uint64(1), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{"a_final": 1.0}, map[string]string{"host": "a"})
+}
+
+func TestLongDifference(t *testing.T) {
+	acc := testutil.Accumulator{}
+	final := NewFinal()
+	final.SeriesTimeout.Duration = 30 * time.Second
+
+	tags := map[string]string{"foo": "bar"}
+	now := time.Now()
+	m1, _ := metric.New("m", tags, map[string]interface{}{"a": int64(1)}, now.Add(-2*time.Minute))
+	final.Add(m1)
+	final.Push(&acc)
+	require.Equal(t, uint64(1), acc.NMetrics())
+
+	// A series that reappears is tracked again.
+	acc.ClearMetrics()
+	m2, _ := metric.New("m", tags, map[string]interface{}{"a": int64(2)}, now)
+	final.Add(m2)
+	final.Push(&acc)
+	require.Equal(t, uint64(0), acc.NMetrics())
+}
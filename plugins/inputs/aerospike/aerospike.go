@@ -27,6 +27,9 @@ type Aerospike struct {
 	EnableSSL bool `toml:"enable_ssl"` // deprecated in 1.7; use enable_tls
 	tlsint.ClientConfig
 
+	GatherSets    bool `toml:"gather_sets"`
+	GatherLatency bool `toml:"gather_latency"`
+
 	initialized bool
 	tlsConfig   *tls.Config
 }
@@ -47,6 +50,13 @@ var sampleConfig = `
   # tls_key = "/etc/telegraf/key.pem"
   ## If false, skip chain & host verification
   # insecure_skip_verify = true
+
+  ## Gather statistics of the sets of each namespace.
+  # gather_sets = false
+
+  ## Gather the latency histograms of the node, such as the reads and
+  ## writes of each namespace.
+  # gather_latency = false
  `
 
 func (a *Aerospike) SampleConfig() string {
@@ -65,6 +75,7 @@ func (a *Aerospike) Gather(acc telegraf.Accumulator) error {
 		}
 		if tlsConfig == nil && (a.EnableTLS || a.EnableSSL) {
 			tlsConfig = &tls.Config{}
+			tlsint.ApplyPolicy(tlsConfig)
 		}
 		a.tlsConfig = tlsConfig
 		a.initialized = true
@@ -160,11 +171,166 @@ func (a *Aerospike) gatherServer(hostport string, acc telegraf.Accumulator) erro
 				}
 			}
 			acc.AddFields("aerospike_namespace", nFields, nTags, time.Now())
+
+			if a.GatherSets {
+				a.gatherSets(n, namespace, hostport, acc)
+			}
+		}
+
+		if a.GatherLatency {
+			a.gatherLatency(n, hostport, acc)
 		}
 	}
 	return nil
 }
 
+func (a *Aerospike) gatherSets(n *as.Node, namespace, hostport string, acc telegraf.Accumulator) {
+	command := "sets/" + namespace
+	info, err := as.RequestNodeInfo(n, command)
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+
+	now := time.Now()
+	for _, set := range parseSets(info[command]) {
+		tags := map[string]string{
+			"aerospike_host": hostport,
+			"node_name":      n.GetName(),
+			"namespace":      namespace,
+			"set":            set.name,
+		}
+		acc.AddFields("aerospike_set", set.fields, tags, now)
+	}
+}
+
+func (a *Aerospike) gatherLatency(n *as.Node, hostport string, acc telegraf.Accumulator) {
+	info, err := as.RequestNodeInfo(n, "latency:")
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+
+	now := time.Now()
+	for _, h := range parseLatency(info["latency:"]) {
+		tags := map[string]string{
+			"aerospike_host": hostport,
+			"node_name":      n.GetName(),
+			"histogram":      h.name,
+		}
+		if h.namespace != "" {
+			tags["namespace"] = h.namespace
+		}
+		acc.AddFields("aerospike_latency", h.fields, tags, now)
+	}
+}
+
+type setStats struct {
+	name   string
+	fields map[string]interface{}
+}
+
+// parseSets parses the response of the sets/<namespace> command, such as
+// "ns=test:set=demo:objects=2:tombstones=0;ns=test:set=other:objects=5;".
+// Older servers name the keys "ns_name" and "set_name".
+func parseSets(info string) []setStats {
+	var sets []setStats
+	for _, record := range strings.Split(info, ";") {
+		if record == "" {
+			continue
+		}
+
+		set := setStats{fields: make(map[string]interface{})}
+		for _, stat := range strings.Split(record, ":") {
+			parts := strings.SplitN(stat, "=", 2)
+			if len(parts) < 2 {
+				continue
+			}
+			switch parts[0] {
+			case "ns", "ns_name":
+				continue
+			case "set", "set_name":
+				set.name = parts[1]
+				continue
+			}
+			val, err := parseValue(parts[1])
+			if err == nil {
+				set.fields[strings.Replace(parts[0], "-", "_", -1)] = val
+			} else {
+				log.Printf("I! skipping aerospike field %v with int64 overflow: %q", parts[0], parts[1])
+			}
+		}
+		if set.name != "" && len(set.fields) > 0 {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+type latencyHistogram struct {
+	namespace string
+	name      string
+	fields    map[string]interface{}
+}
+
+// parseLatency parses the response of the latency: command.  Each histogram
+// is a header followed by the last slice of data, for example
+// "{test}-read:10:45:40-GMT,ops/sec,>1ms,>8ms,>64ms;10:45:50,3.2,0.00,0.00,0.00;"
+// where the thresholds are the percentage of operations exceeding them.
+// Histograms without data are reported as "{test}-write:error-no-data-yet-or-back-too-small;".
+func parseLatency(info string) []latencyHistogram {
+	var histograms []latencyHistogram
+	records := strings.Split(info, ";")
+	for i := 0; i < len(records); i++ {
+		header := strings.SplitN(records[i], ":", 2)
+		if len(header) < 2 || strings.HasPrefix(header[1], "error") {
+			continue
+		}
+		if i+1 >= len(records) {
+			break
+		}
+		i++
+		columns := strings.Split(header[1], ",")
+		values := strings.Split(records[i], ",")
+		if len(columns) != len(values) {
+			continue
+		}
+
+		h := latencyHistogram{
+			name:   header[0],
+			fields: make(map[string]interface{}),
+		}
+		if strings.HasPrefix(h.name, "{") {
+			if end := strings.Index(h.name, "}-"); end > 0 {
+				h.namespace = h.name[1:end]
+				h.name = h.name[end+2:]
+			}
+		}
+
+		// The first column is the time of the slice.
+		for j := 1; j < len(columns); j++ {
+			val, err := strconv.ParseFloat(values[j], 64)
+			if err != nil {
+				continue
+			}
+			h.fields[latencyFieldName(columns[j])] = val
+		}
+		if len(h.fields) > 0 {
+			histograms = append(histograms, h)
+		}
+	}
+	return histograms
+}
+
+// latencyFieldName converts a column of a latency histogram to a field name,
+// "ops/sec" becomes "ops_per_sec" and ">1ms" becomes "over_1ms".
+func latencyFieldName(column string) string {
+	if column == "ops/sec" {
+		return "ops_per_sec"
+	}
+	return strings.Replace(column, ">", "over_", 1)
+}
+
 func parseValue(v string) (interface{}, error) {
 	if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
 		return parsed, nil
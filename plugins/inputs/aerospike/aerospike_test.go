@@ -66,3 +66,53 @@ func TestAerospikeParseValue(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, val, `BB977942A2CA502`, "must be left as string")
 }
+
+func TestAerospikeParseSets(t *testing.T) {
+	info := "ns=test:set=demo:objects=2:tombstones=0:memory_data_bytes=128:stop-writes-count=0:disable-eviction=false;" +
+		"ns_name=test:set_name=legacy:n_objects=5;" +
+		"ns=test:set=empty;"
+
+	sets := parseSets(info)
+	require.Len(t, sets, 2)
+
+	assert.Equal(t, "demo", sets[0].name)
+	assert.Equal(t, map[string]interface{}{
+		"objects":           int64(2),
+		"tombstones":        int64(0),
+		"memory_data_bytes": int64(128),
+		"stop_writes_count": int64(0),
+		"disable_eviction":  false,
+	}, sets[0].fields)
+
+	assert.Equal(t, "legacy", sets[1].name)
+	assert.Equal(t, map[string]interface{}{
+		"n_objects": int64(5),
+	}, sets[1].fields)
+}
+
+func TestAerospikeParseLatency(t *testing.T) {
+	info := "{test}-read:10:45:40-GMT,ops/sec,>1ms,>8ms,>64ms;10:45:50,3.2,1.50,0.00,0.00;" +
+		"{test}-write:error-no-data-yet-or-back-too-small;" +
+		"batch-index:10:45:40-GMT,ops/sec,>1ms,>8ms,>64ms;10:45:50,12.0,0.25,0.10,0.00;"
+
+	histograms := parseLatency(info)
+	require.Len(t, histograms, 2)
+
+	assert.Equal(t, "test", histograms[0].namespace)
+	assert.Equal(t, "read", histograms[0].name)
+	assert.Equal(t, map[string]interface{}{
+		"ops_per_sec": 3.2,
+		"over_1ms":    1.5,
+		"over_8ms":    0.0,
+		"over_64ms":   0.0,
+	}, histograms[0].fields)
+
+	assert.Equal(t, "", histograms[1].namespace)
+	assert.Equal(t, "batch-index", histograms[1].name)
+	assert.Equal(t, map[string]interface{}{
+		"ops_per_sec": 12.0,
+		"over_1ms":    0.25,
+		"over_8ms":    0.1,
+		"over_64ms":   0.0,
+	}, histograms[1].fields)
+}
@@ -0,0 +1,9 @@
+// +build !custom,!noaws
+
+package all
+
+// The Amazon Web Services plugins, excluded with the noaws build tag.
+import (
+	_ "github.com/influxdata/telegraf/plugins/inputs/aws_cost"
+	_ "github.com/influxdata/telegraf/plugins/inputs/cloudwatch"
+)
@@ -0,0 +1,10 @@
+// +build !custom,noaws
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/inputs"
+
+func init() {
+	inputs.AddExcluded("aws_cost", "noaws")
+	inputs.AddExcluded("cloudwatch", "noaws")
+}
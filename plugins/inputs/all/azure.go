@@ -0,0 +1,9 @@
+// +build !custom,!noazure
+
+package all
+
+// The Microsoft Azure plugins, excluded with the noazure build tag.
+import (
+	_ "github.com/influxdata/telegraf/plugins/inputs/azure_consumption"
+	_ "github.com/influxdata/telegraf/plugins/inputs/azure_query"
+)
@@ -0,0 +1,10 @@
+// +build !custom,noazure
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/inputs"
+
+func init() {
+	inputs.AddExcluded("azure_consumption", "noazure")
+	inputs.AddExcluded("azure_query", "noazure")
+}
@@ -0,0 +1,8 @@
+// +build !custom,!nogcp
+
+package all
+
+// The Google Cloud Platform plugins, excluded with the nogcp build tag.
+import (
+	_ "github.com/influxdata/telegraf/plugins/inputs/gcp_billing"
+)
@@ -0,0 +1,9 @@
+// +build !custom,nogcp
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/inputs"
+
+func init() {
+	inputs.AddExcluded("gcp_billing", "nogcp")
+}
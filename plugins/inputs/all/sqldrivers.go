@@ -0,0 +1,11 @@
+// +build !custom,!nosqldrivers
+
+package all
+
+// The SQL database drivers plugins, excluded with the nosqldrivers build tag.
+import (
+	_ "github.com/influxdata/telegraf/plugins/inputs/mysql"
+	_ "github.com/influxdata/telegraf/plugins/inputs/postgresql"
+	_ "github.com/influxdata/telegraf/plugins/inputs/postgresql_extensible"
+	_ "github.com/influxdata/telegraf/plugins/inputs/sqlserver"
+)
@@ -0,0 +1,12 @@
+// +build !custom,nosqldrivers
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/inputs"
+
+func init() {
+	inputs.AddExcluded("mysql", "nosqldrivers")
+	inputs.AddExcluded("postgresql", "nosqldrivers")
+	inputs.AddExcluded("postgresql_extensible", "nosqldrivers")
+	inputs.AddExcluded("sqlserver", "nosqldrivers")
+}
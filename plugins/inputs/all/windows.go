@@ -0,0 +1,9 @@
+// +build !custom,!nowindows
+
+package all
+
+// The Windows only plugins, excluded with the nowindows build tag.
+import (
+	_ "github.com/influxdata/telegraf/plugins/inputs/win_perf_counters"
+	_ "github.com/influxdata/telegraf/plugins/inputs/win_services"
+)
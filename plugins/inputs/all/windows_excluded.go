@@ -0,0 +1,10 @@
+// +build !custom,nowindows
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/inputs"
+
+func init() {
+	inputs.AddExcluded("win_perf_counters", "nowindows")
+	inputs.AddExcluded("win_services", "nowindows")
+}
@@ -0,0 +1,200 @@
+package apcupsd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultAddress = "tcp://127.0.0.1:3551"
+
+// ApcUpsd reads the status of UPSes from apcupsd Network Information
+// Servers.
+type ApcUpsd struct {
+	Servers []string          `toml:"servers"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+var sampleConfig = `
+  ## A list of running apcupsd servers to connect to, the Network
+  ## Information Server must be enabled with "NETSERVER on" in apcupsd.conf.
+  ## If not provided will default to tcp://127.0.0.1:3551
+  servers = ["tcp://127.0.0.1:3551"]
+
+  ## Timeout for dialing and reading from each server.
+  timeout = "5s"
+`
+
+func (a *ApcUpsd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *ApcUpsd) Description() string {
+	return "Monitor APC UPSes connected to apcupsd"
+}
+
+// tagKeys maps the keys of the status to tag names.
+var tagKeys = map[string]string{
+	"UPSNAME":  "ups_name",
+	"MODEL":    "model",
+	"SERIALNO": "serial",
+	"STATUS":   "status",
+}
+
+// fields maps the keys of the status to field names, the values of the
+// fields are the leading number of the status values such as "230.0 Volts".
+// Counters and durations in seconds are integers, other values are floats.
+var fields = map[string]struct {
+	name    string
+	integer bool
+}{
+	"LINEV":     {"input_voltage", false},
+	"LINEFREQ":  {"input_frequency", false},
+	"OUTPUTV":   {"output_voltage", false},
+	"LOADPCT":   {"load_percent", false},
+	"BCHARGE":   {"battery_charge_percent", false},
+	"TIMELEFT":  {"time_left_minutes", false},
+	"BATTV":     {"battery_voltage", false},
+	"ITEMP":     {"internal_temp", false},
+	"NOMPOWER":  {"nominal_power", true},
+	"NUMXFERS":  {"transfers", true},
+	"TONBATT":   {"time_on_battery_seconds", true},
+	"CUMONBATT": {"cumulative_time_on_battery_seconds", true},
+}
+
+func (a *ApcUpsd) Gather(acc telegraf.Accumulator) error {
+	servers := a.Servers
+	if len(servers) == 0 {
+		servers = []string{defaultAddress}
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			if err := a.gatherServer(acc, server); err != nil {
+				acc.AddError(fmt.Errorf("[server=%s]: %s", server, err))
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (a *ApcUpsd) gatherServer(acc telegraf.Accumulator, server string) error {
+	u, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "tcp" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	status, err := fetchStatus(u.Host, a.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]string{
+		"server": u.Host,
+	}
+	for key, tag := range tagKeys {
+		if value := status[key]; value != "" {
+			tags[tag] = value
+		}
+	}
+	values := make(map[string]interface{})
+	for key, field := range fields {
+		value, ok := status[key]
+		if !ok {
+			continue
+		}
+		if v, err := parseValue(value, field.integer); err == nil {
+			values[field.name] = v
+		}
+	}
+	if flags, ok := status["STATFLAG"]; ok {
+		if v, err := strconv.ParseInt(flags, 0, 64); err == nil {
+			values["status_flags"] = v
+		}
+	}
+	acc.AddFields("apcupsd", values, tags)
+	return nil
+}
+
+// fetchStatus sends the "status" command to the Network Information Server
+// and returns the status of the UPS.  Messages of the protocol are
+// prefixed by their length as a 16 bit big-endian integer, the response is
+// a message for each line and ends with an empty message.
+func fetchStatus(address string, timeout time.Duration) (map[string]string, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	cmd := []byte("status")
+	req := make([]byte, 2, 2+len(cmd))
+	binary.BigEndian.PutUint16(req, uint16(len(cmd)))
+	if _, err := conn.Write(append(req, cmd...)); err != nil {
+		return nil, err
+	}
+
+	status := make(map[string]string)
+	r := bufio.NewReader(conn)
+	for {
+		var size uint16
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return status, nil
+		}
+		line := make([]byte, size)
+		if _, err := io.ReadFull(r, line); err != nil {
+			return nil, err
+		}
+
+		// APC      : 001,036,0875
+		parts := strings.SplitN(string(line), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		status[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+}
+
+// parseValue parses the leading number of values such as "230.0 Volts" or
+// "0 Seconds".
+func parseValue(value string, integer bool) (interface{}, error) {
+	parts := strings.Fields(value)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	if integer {
+		return strconv.ParseInt(parts[0], 10, 64)
+	}
+	return strconv.ParseFloat(parts[0], 64)
+}
+
+func init() {
+	inputs.Add("apcupsd", func() telegraf.Input {
+		return &ApcUpsd{
+			Servers: []string{defaultAddress},
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
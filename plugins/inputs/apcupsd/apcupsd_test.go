@@ -0,0 +1,153 @@
+package apcupsd
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var status = []string{
+	"APC      : 001,036,0875",
+	"DATE     : 2018-06-01 12:00:00 +0000  ",
+	"HOSTNAME : branch-1",
+	"VERSION  : 3.14.14 (31 May 2016) debian",
+	"UPSNAME  : rack-a",
+	"CABLE    : USB Cable",
+	"DRIVER   : USB UPS Driver",
+	"UPSMODE  : Stand Alone",
+	"MODEL    : Smart-UPS 1500",
+	"STATUS   : ONLINE ",
+	"LINEV    : 231.8 Volts",
+	"LOADPCT  : 17.0 Percent",
+	"BCHARGE  : 100.0 Percent",
+	"TIMELEFT : 58.0 Minutes",
+	"MBATTCHG : 5 Percent",
+	"OUTPUTV  : 231.8 Volts",
+	"ITEMP    : 29.2 C",
+	"BATTV    : 27.1 Volts",
+	"LINEFREQ : 50.0 Hz",
+	"LASTXFER : Line voltage notch or spike",
+	"NUMXFERS : 3",
+	"TONBATT  : 0 Seconds",
+	"CUMONBATT: 42 Seconds",
+	"XOFFBATT : N/A",
+	"STATFLAG : 0x05000008",
+	"SERIALNO : AS1234567890",
+	"NOMPOWER : 1000 Watts",
+	"END APC  : 2018-06-01 12:00:10 +0000  ",
+}
+
+func listen(t *testing.T, lines []string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var size uint16
+		if binary.Read(conn, binary.BigEndian, &size) != nil {
+			return
+		}
+		cmd := make([]byte, size)
+		if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "status" {
+			return
+		}
+		for _, line := range append(lines, "") {
+			binary.Write(conn, binary.BigEndian, uint16(len(line)))
+			conn.Write([]byte(line))
+		}
+	}()
+	return l
+}
+
+func TestGather(t *testing.T) {
+	l := listen(t, status)
+	defer l.Close()
+
+	a := &ApcUpsd{
+		Servers: []string{"tcp://" + l.Addr().String()},
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(a.Gather))
+
+	acc.AssertContainsTaggedFields(t, "apcupsd",
+		map[string]interface{}{
+			"input_voltage":                      231.8,
+			"input_frequency":                    50.0,
+			"output_voltage":                     231.8,
+			"load_percent":                       17.0,
+			"battery_charge_percent":             100.0,
+			"time_left_minutes":                  58.0,
+			"battery_voltage":                    27.1,
+			"internal_temp":                      29.2,
+			"nominal_power":                      int64(1000),
+			"transfers":                          int64(3),
+			"time_on_battery_seconds":            int64(0),
+			"cumulative_time_on_battery_seconds": int64(42),
+			"status_flags":                       int64(0x05000008),
+		},
+		map[string]string{
+			"server":   l.Addr().String(),
+			"ups_name": "rack-a",
+			"model":    "Smart-UPS 1500",
+			"serial":   "AS1234567890",
+			"status":   "ONLINE",
+		})
+}
+
+func TestGatherUnreachable(t *testing.T) {
+	l := listen(t, status)
+	addr := l.Addr().String()
+	l.Close()
+
+	a := &ApcUpsd{
+		Servers: []string{"tcp://" + addr},
+		Timeout: internal.Duration{Duration: time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "[server=tcp://"+addr+"]")
+	require.Empty(t, acc.Metrics)
+}
+
+func TestGatherUnsupportedScheme(t *testing.T) {
+	a := &ApcUpsd{
+		Servers: []string{"udp://127.0.0.1:3551"},
+		Timeout: internal.Duration{Duration: time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), `unsupported scheme "udp"`)
+}
+
+func TestParseValue(t *testing.T) {
+	v, err := parseValue("231.8 Volts", false)
+	require.NoError(t, err)
+	require.Equal(t, 231.8, v)
+
+	v, err = parseValue("0 Seconds", true)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), v)
+
+	_, err = parseValue("N/A", false)
+	require.Error(t, err)
+
+	_, err = parseValue("", true)
+	require.Error(t, err)
+}
@@ -0,0 +1,299 @@
+// +build linux
+
+package auditd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	// auditNlgrpReadlog is the multicast group of the kernel audit netlink
+	// socket for read-only subscribers, AUDIT_NLGRP_READLOG.
+	auditNlgrpReadlog = 1
+
+	// readTimeout is the timeout of the reads, to stop the plugin and emit
+	// the incomplete events in time.
+	readTimeout = time.Second
+
+	// eventTimeout is the time after which an event without its EOE record
+	// is emitted.
+	eventTimeout = 2 * time.Second
+)
+
+var allCategories = []string{"execve", "login", "denied"}
+
+type Auditd struct {
+	Source       string   `toml:"source"`
+	AudispSocket string   `toml:"audisp_socket"`
+	Categories   []string `toml:"categories"`
+
+	acc        telegraf.Accumulator
+	categories map[string]bool
+	assembler  *assembler
+	fd         int
+	conn       net.Conn
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+var sampleConfig = `
+  ## Source of the audit records:
+  ##   "netlink" - subscribe to the audit records of the kernel, which
+  ##               requires Linux 3.16 or later and the CAP_AUDIT_READ
+  ##               capability, alongside auditd
+  ##   "audisp"  - read the records of the af_unix plugin of audispd
+  # source = "netlink"
+
+  ## Socket of the af_unix plugin of audispd, in "string" format.
+  # audisp_socket = "/var/run/audispd_events"
+
+  ## Categories of events to report:
+  ##   "execve" - programs executed
+  ##   "login"  - logins and authentications, successful or not
+  ##   "denied" - system calls and SELinux accesses denied
+  # categories = ["execve", "login", "denied"]
+`
+
+func (a *Auditd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Auditd) Description() string {
+	return "Report Linux audit events, such as programs executed, logins and permission denials"
+}
+
+func (a *Auditd) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Validate checks the source and the categories.
+func (a *Auditd) Validate() error {
+	switch a.Source {
+	case "", "netlink", "audisp":
+	default:
+		return fmt.Errorf("unknown source %q", a.Source)
+	}
+	for _, category := range a.Categories {
+		if !contains(allCategories, category) {
+			return fmt.Errorf("unknown category %q", category)
+		}
+	}
+	return nil
+}
+
+func (a *Auditd) Start(acc telegraf.Accumulator) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	categories := a.Categories
+	if len(categories) == 0 {
+		categories = allCategories
+	}
+	a.categories = make(map[string]bool)
+	for _, category := range categories {
+		a.categories[category] = true
+	}
+
+	a.acc = acc
+	a.assembler = newAssembler(eventTimeout, a.emit)
+	a.done = make(chan struct{})
+
+	if a.Source == "audisp" {
+		conn, err := net.Dial("unix", a.AudispSocket)
+		if err != nil {
+			return err
+		}
+		a.conn = conn
+		a.wg.Add(1)
+		go a.readAudisp()
+		return nil
+	}
+
+	fd, err := openNetlink()
+	if err != nil {
+		return fmt.Errorf("subscribing to the audit netlink socket: %s", err)
+	}
+	a.fd = fd
+	a.wg.Add(1)
+	go a.readNetlink()
+	return nil
+}
+
+func (a *Auditd) Stop() {
+	close(a.done)
+	a.wg.Wait()
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn = nil
+	} else {
+		syscall.Close(a.fd)
+	}
+}
+
+func (a *Auditd) stopping() bool {
+	select {
+	case <-a.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// openNetlink returns a netlink socket receiving the audit records of the
+// kernel, with the read timeout.
+func openNetlink() (int, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_AUDIT)
+	if err != nil {
+		return 0, err
+	}
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: auditNlgrpReadlog,
+	}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return 0, err
+	}
+	tv := syscall.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return 0, err
+	}
+	return fd, nil
+}
+
+func (a *Auditd) readNetlink() {
+	defer a.wg.Done()
+
+	// Records are at most 8970 bytes long.
+	buf := make([]byte, 64*1024)
+	for !a.stopping() {
+		n, _, err := syscall.Recvfrom(a.fd, buf, 0)
+		switch err {
+		case nil:
+		case syscall.EAGAIN, syscall.EINTR:
+			a.assembler.flush(time.Now())
+			continue
+		case syscall.ENOBUFS:
+			a.acc.AddError(fmt.Errorf("audit records lost, the receive buffer is full"))
+			continue
+		default:
+			a.acc.AddError(fmt.Errorf("reading the audit netlink socket: %s", err))
+			return
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			a.acc.AddError(err)
+			continue
+		}
+		now := time.Now()
+		for _, m := range msgs {
+			typ, ok := recordTypes[m.Header.Type]
+			if !ok {
+				continue
+			}
+			r, err := parseRecord(typ, string(bytes.TrimRight(m.Data, "\x00")))
+			if err != nil {
+				a.acc.AddError(err)
+				continue
+			}
+			a.assembler.add(r, now)
+		}
+		a.assembler.flush(now)
+	}
+}
+
+func (a *Auditd) readAudisp() {
+	defer a.wg.Done()
+
+	reader := bufio.NewReader(a.conn)
+	var line string
+	for !a.stopping() {
+		a.conn.SetReadDeadline(time.Now().Add(readTimeout))
+		s, err := reader.ReadString('\n')
+		line += s
+		if err != nil {
+			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+				a.assembler.flush(time.Now())
+				continue
+			}
+			log.Printf("E! Error reading the audisp socket %s: %s", a.AudispSocket, err)
+			if !a.reconnect() {
+				return
+			}
+			reader = bufio.NewReader(a.conn)
+			line = ""
+			continue
+		}
+
+		now := time.Now()
+		typ := strings.TrimPrefix(strings.SplitN(line, " ", 2)[0], "type=")
+		if knownRecordType(typ) {
+			r, err := parseLine(line)
+			if err != nil {
+				a.acc.AddError(err)
+			} else {
+				a.assembler.add(r, now)
+			}
+		}
+		line = ""
+		a.assembler.flush(now)
+	}
+}
+
+// reconnect connects to the audisp socket again, such as after audispd was
+// restarted, until it succeeds or the plugin is stopped.
+func (a *Auditd) reconnect() bool {
+	a.conn.Close()
+	for {
+		select {
+		case <-a.done:
+			return false
+		case <-time.After(readTimeout):
+		}
+		conn, err := net.Dial("unix", a.AudispSocket)
+		if err == nil {
+			a.conn = conn
+			return true
+		}
+	}
+}
+
+// emit adds the metric of an event, if it is one of the categories.
+func (a *Auditd) emit(e *event) {
+	tags, fields, ok := convert(e, a.categories)
+	if ok {
+		a.acc.AddFields("auditd", fields, tags, e.time)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	inputs.Add("auditd", func() telegraf.Input {
+		return &Auditd{
+			Source:       "netlink",
+			AudispSocket: "/var/run/audispd_events",
+		}
+	})
+}
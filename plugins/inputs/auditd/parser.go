@@ -0,0 +1,389 @@
+package auditd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordTypes are the names of the audit record types used, by their
+// number in linux/audit.h.
+var recordTypes = map[uint16]string{
+	1100: "USER_AUTH",
+	1112: "USER_LOGIN",
+	1300: "SYSCALL",
+	1302: "PATH",
+	1307: "CWD",
+	1309: "EXECVE",
+	1320: "EOE",
+	1400: "AVC",
+}
+
+func knownRecordType(name string) bool {
+	for _, typ := range recordTypes {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// record is an audit record, one of the records of an event.
+type record struct {
+	typ    string
+	time   time.Time
+	serial uint64
+	fields map[string]string
+}
+
+// parseLine parses a record as written by auditd and audispd:
+//
+//	type=SYSCALL msg=audit(1528300000.123:456): arch=c000003e syscall=59 ...
+func parseLine(line string) (*record, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "type=") {
+		return nil, fmt.Errorf("missing record type: %q", line)
+	}
+	i := strings.Index(line, " msg=")
+	if i < 0 {
+		return nil, fmt.Errorf("missing record message: %q", line)
+	}
+	return parseRecord(line[len("type="):i], line[i+len(" msg="):])
+}
+
+// parseRecord parses the message of a record, as received from the kernel:
+//
+//	audit(1528300000.123:456): arch=c000003e syscall=59 ...
+func parseRecord(typ, msg string) (*record, error) {
+	if !strings.HasPrefix(msg, "audit(") {
+		return nil, fmt.Errorf("missing audit header: %q", msg)
+	}
+	end := strings.Index(msg, "):")
+	if end < 0 {
+		return nil, fmt.Errorf("missing audit header: %q", msg)
+	}
+	header := msg[len("audit("):end]
+
+	colon := strings.IndexByte(header, ':')
+	if colon < 0 {
+		return nil, fmt.Errorf("invalid audit header: %q", header)
+	}
+	serial, err := strconv.ParseUint(header[colon+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit serial: %q", header)
+	}
+	secs, err := strconv.ParseFloat(header[:colon], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit timestamp: %q", header)
+	}
+	// The timestamp has a millisecond resolution.
+	ts := time.Unix(0, int64(secs*1000+0.5)*int64(time.Millisecond))
+
+	r := &record{
+		typ:    typ,
+		time:   ts,
+		serial: serial,
+		fields: make(map[string]string),
+	}
+	body := msg[end+len("):"):]
+	if typ == "AVC" {
+		parseAVC(body, r.fields)
+	}
+	parseFields(body, r.fields)
+	return r, nil
+}
+
+// parseFields parses the key=value pairs of a record into fields.  The
+// values are either quoted, hex encoded for the strings that contain
+// special characters, or plain.  The msg='...' value of the records of user
+// space programs contains key=value pairs itself.  Words without a value
+// and the fields translated by auditd in the enriched format, after the
+// 0x1d separator, are ignored.
+func parseFields(s string, fields map[string]string) {
+	if i := strings.IndexByte(s, '\x1d'); i >= 0 {
+		s = s[:i]
+	}
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return
+		}
+		eq := strings.IndexByte(s, '=')
+		space := strings.IndexByte(s, ' ')
+		if eq < 0 || (space >= 0 && space < eq) {
+			// A word without value.
+			if space < 0 {
+				return
+			}
+			s = s[space:]
+			continue
+		}
+
+		key := s[:eq]
+		s = s[eq+1:]
+		var value string
+		switch {
+		case strings.HasPrefix(s, `"`):
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				end = len(s) - 1
+			}
+			value, s = s[1:end+1], s[min(end+2, len(s)):]
+			fields[key] = value
+		case strings.HasPrefix(s, "'"):
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				end = len(s) - 1
+			}
+			value, s = s[1:end+1], s[min(end+2, len(s)):]
+			parseFields(value, fields)
+		default:
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+			fields[key] = decode(key, value)
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// decode decodes the unquoted values of the string fields, which are hex
+// encoded.
+func decode(key, value string) string {
+	switch {
+	case key == "exe", key == "comm", key == "cwd", key == "name",
+		key == "proctitle", key == "acct", key == "cmd",
+		len(key) > 1 && key[0] == 'a' && isDigits(key[1:]):
+	default:
+		return value
+	}
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return value
+	}
+	return strings.Replace(string(b), "\x00", " ", -1)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// parseAVC parses the result and the permissions of an AVC record, such as:
+//
+//	avc:  denied  { read write } for  pid=1234 comm="httpd" ...
+func parseAVC(s string, fields map[string]string) {
+	f := strings.Fields(s)
+	if len(f) > 1 && f[0] == "avc:" {
+		fields["avc"] = f[1]
+	}
+	open := strings.IndexByte(s, '{')
+	end := strings.IndexByte(s, '}')
+	if open >= 0 && end > open {
+		fields["permission"] = strings.Join(strings.Fields(s[open+1:end]), " ")
+	}
+}
+
+// event is the records of an audit event, sharing the same serial.
+type event struct {
+	serial   uint64
+	time     time.Time
+	records  []*record
+	received time.Time
+}
+
+// find returns the first record of the type, or nil.
+func (e *event) find(typ string) *record {
+	for _, r := range e.records {
+		if r.typ == typ {
+			return r
+		}
+	}
+	return nil
+}
+
+// assembler groups the records into events.  The events of system calls end
+// with an EOE record, while the records of user space programs are events
+// by themselves.  The events whose end was lost are emitted after the
+// timeout.
+type assembler struct {
+	timeout time.Duration
+	events  map[uint64]*event
+	emit    func(*event)
+}
+
+func newAssembler(timeout time.Duration, emit func(*event)) *assembler {
+	return &assembler{
+		timeout: timeout,
+		events:  make(map[uint64]*event),
+		emit:    emit,
+	}
+}
+
+func (a *assembler) add(r *record, now time.Time) {
+	e, ok := a.events[r.serial]
+	if r.typ == "EOE" {
+		if ok {
+			delete(a.events, r.serial)
+			a.emit(e)
+		}
+		return
+	}
+	if !ok {
+		e = &event{serial: r.serial, time: r.time, received: now}
+		a.events[r.serial] = e
+	}
+	e.records = append(e.records, r)
+
+	if strings.HasPrefix(r.typ, "USER_") {
+		delete(a.events, r.serial)
+		a.emit(e)
+	}
+}
+
+// flush emits the events received before the timeout, in order.
+func (a *assembler) flush(now time.Time) {
+	var expired []*event
+	for serial, e := range a.events {
+		if now.Sub(e.received) >= a.timeout {
+			expired = append(expired, e)
+			delete(a.events, serial)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].serial < expired[j].serial })
+	for _, e := range expired {
+		a.emit(e)
+	}
+}
+
+// Errno values of failed system calls which are permission denials.
+const (
+	errEPERM  = "-1"
+	errEACCES = "-13"
+)
+
+// convert returns the metric of an event of the categories, or false if the
+// event is not one of them.
+func convert(e *event, categories map[string]bool) (map[string]string, map[string]interface{}, bool) {
+	syscall := e.find("SYSCALL")
+	execve := e.find("EXECVE")
+	avc := e.find("AVC")
+	user := e.find("USER_LOGIN")
+	if user == nil {
+		user = e.find("USER_AUTH")
+	}
+
+	tags := make(map[string]string)
+	fields := map[string]interface{}{
+		"serial": int64(e.serial),
+	}
+	switch {
+	case execve != nil && syscall != nil && categories["execve"]:
+		tags["category"] = "execve"
+		syscallTags(syscall, tags)
+
+		var args []string
+		argc, _ := strconv.Atoi(execve.fields["argc"])
+		for i := 0; i < argc; i++ {
+			args = append(args, execve.fields["a"+strconv.Itoa(i)])
+		}
+		fields["command"] = strings.Join(args, " ")
+		if cwd := e.find("CWD"); cwd != nil {
+			addString(fields, "cwd", cwd.fields["cwd"])
+		}
+		addInts(fields, syscall.fields, "pid", "ppid", "auid", "ses")
+		addString(fields, "key", syscall.fields["key"])
+
+	case user != nil && categories["login"]:
+		tags["category"] = "login"
+		addTag(tags, "uid", user.fields["uid"])
+		addTag(tags, "exe", user.fields["exe"])
+		addTag(tags, "result", user.fields["res"])
+		fields["record_type"] = user.typ
+		for _, key := range []string{"acct", "addr", "hostname", "terminal"} {
+			addString(fields, key, user.fields[key])
+		}
+		addInts(fields, user.fields, "pid", "auid", "ses")
+
+	case avc != nil && avc.fields["avc"] == "denied" && categories["denied"]:
+		tags["category"] = "denied"
+		if syscall != nil {
+			syscallTags(syscall, tags)
+			addInts(fields, syscall.fields, "auid")
+		}
+		tags["result"] = "denied"
+		for _, key := range []string{"permission", "comm", "name", "path", "scontext", "tcontext", "tclass"} {
+			addString(fields, key, avc.fields[key])
+		}
+		addInts(fields, avc.fields, "pid")
+
+	case syscall != nil && syscall.fields["success"] == "no" &&
+		(syscall.fields["exit"] == errEPERM || syscall.fields["exit"] == errEACCES) &&
+		categories["denied"]:
+		tags["category"] = "denied"
+		syscallTags(syscall, tags)
+		tags["result"] = "denied"
+		addString(fields, "syscall", syscall.fields["syscall"])
+		addString(fields, "comm", syscall.fields["comm"])
+		addString(fields, "key", syscall.fields["key"])
+		if path := e.find("PATH"); path != nil {
+			addString(fields, "name", path.fields["name"])
+		}
+		addInts(fields, syscall.fields, "exit", "pid", "auid", "ses")
+
+	default:
+		return nil, nil, false
+	}
+	return tags, fields, true
+}
+
+// syscallTags sets the uid, exe and result tags of a SYSCALL record.
+func syscallTags(syscall *record, tags map[string]string) {
+	addTag(tags, "uid", syscall.fields["uid"])
+	addTag(tags, "exe", syscall.fields["exe"])
+	switch syscall.fields["success"] {
+	case "yes":
+		tags["result"] = "success"
+	case "no":
+		tags["result"] = "failed"
+	}
+}
+
+// unset is the value of the fields which are not set, such as the key of
+// the records matching no keyed rule.
+const unset = "(null)"
+
+func addTag(tags map[string]string, key, value string) {
+	if value != "" && value != unset && value != "?" {
+		tags[key] = value
+	}
+}
+
+func addString(fields map[string]interface{}, key, value string) {
+	if value != "" && value != unset && value != "?" {
+		fields[key] = value
+	}
+}
+
+func addInts(fields map[string]interface{}, values map[string]string, keys ...string) {
+	for _, key := range keys {
+		if v, err := strconv.ParseInt(values[key], 10, 64); err == nil {
+			fields[key] = v
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package auditd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var allEvents = map[string]bool{"execve": true, "login": true, "denied": true}
+
+const execveRecords = `type=SYSCALL msg=audit(1528300000.123:4567): arch=c000003e syscall=59 success=yes exit=0 a0=55d0c2a4b0d8 a1=55d0c2a4a2c8 a2=55d0c2a4a2e0 a3=8 items=2 ppid=1432 pid=1501 auid=1000 uid=0 gid=0 euid=0 suid=0 fsuid=0 egid=0 sgid=0 fsgid=0 tty=pts0 ses=3 comm="cat" exe="/usr/bin/cat" key="exec"
+type=EXECVE msg=audit(1528300000.123:4567): argc=2 a0="cat" a1=2F6574632F6D7920736861646F77
+type=CWD msg=audit(1528300000.123:4567): cwd="/root"
+type=PATH msg=audit(1528300000.123:4567): item=0 name="/usr/bin/cat" inode=1234 dev=08:01 mode=0100755 ouid=0 ogid=0 rdev=00:00 nametype=NORMAL
+type=PROCTITLE msg=audit(1528300000.123:4567): proctitle=636174002F6574632F6D7920736861646F77
+type=EOE msg=audit(1528300000.123:4567): 
+`
+
+const loginRecord = `type=USER_LOGIN msg=audit(1528300001.456:4568): pid=1612 uid=0 auid=1000 ses=4 msg='op=login id=1000 exe="/usr/sbin/sshd" hostname=? addr=192.0.2.10 terminal=/dev/pts/1 res=success'`
+
+const authRecord = `type=USER_AUTH msg=audit(1528300002.000:4569): pid=1620 uid=0 auid=4294967295 ses=4294967295 msg='op=PAM:authentication grantors=? acct="admin" exe="/usr/sbin/sshd" hostname=198.51.100.7 addr=198.51.100.7 terminal=ssh res=failed'`
+
+const deniedRecords = `type=SYSCALL msg=audit(1528300003.000:4570): arch=c000003e syscall=257 success=no exit=-13 a0=ffffff9c a1=7ffd1a2c a2=0 a3=0 items=1 ppid=1432 pid=1650 auid=1000 uid=1000 gid=1000 euid=1000 ses=3 comm="cat" exe="/usr/bin/cat" key=(null)
+type=PATH msg=audit(1528300003.000:4570): item=0 name="/etc/shadow" inode=5678 dev=08:01 mode=0100640 nametype=NORMAL
+type=EOE msg=audit(1528300003.000:4570): 
+`
+
+const avcRecords = `type=AVC msg=audit(1528300004.000:4571): avc:  denied  { read write } for  pid=2301 comm="httpd" name="index.html" dev="sda1" ino=1234 scontext=system_u:system_r:httpd_t:s0 tcontext=unconfined_u:object_r:user_home_t:s0 tclass=file permissive=0
+type=SYSCALL msg=audit(1528300004.000:4571): arch=c000003e syscall=2 success=no exit=-13 items=0 ppid=2300 pid=2301 auid=4294967295 uid=48 gid=48 ses=4294967295 comm="httpd" exe="/usr/sbin/httpd" subj=system_u:system_r:httpd_t:s0 key=(null)
+type=EOE msg=audit(1528300004.000:4571): 
+`
+
+func assemble(t *testing.T, records string) []*event {
+	var events []*event
+	a := newAssembler(time.Second, func(e *event) { events = append(events, e) })
+	now := time.Unix(1528300000, 0)
+	for _, line := range strings.Split(strings.TrimSpace(records), "\n") {
+		r, err := parseLine(line)
+		require.NoError(t, err)
+		a.add(r, now)
+	}
+	a.flush(now.Add(time.Second))
+	return events
+}
+
+func TestParseRecord(t *testing.T) {
+	r, err := parseLine(`type=EXECVE msg=audit(1528300000.123:4567): argc=3 a0="ls" a1=2D6C a2="/tmp"`)
+	require.NoError(t, err)
+	require.Equal(t, "EXECVE", r.typ)
+	require.Equal(t, uint64(4567), r.serial)
+	require.Equal(t, time.Unix(1528300000, 123000000), r.time)
+	require.Equal(t, map[string]string{"argc": "3", "a0": "ls", "a1": "-l", "a2": "/tmp"}, r.fields)
+
+	// Enriched format.
+	r, err = parseLine("type=SYSCALL msg=audit(1528300000.123:4567): uid=0 exe=\"/usr/bin/ls\"\x1dUID=\"root\"")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"uid": "0", "exe": "/usr/bin/ls"}, r.fields)
+
+	_, err = parseLine("type=SYSCALL audit(1528300000.123:4567): uid=0")
+	require.Error(t, err)
+	_, err = parseRecord("SYSCALL", "audit(1528300000.123): uid=0")
+	require.Error(t, err)
+}
+
+func TestExecve(t *testing.T) {
+	events := assemble(t, execveRecords)
+	require.Len(t, events, 1)
+
+	tags, fields, ok := convert(events[0], allEvents)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{
+		"category": "execve",
+		"uid":      "0",
+		"exe":      "/usr/bin/cat",
+		"result":   "success",
+	}, tags)
+	require.Equal(t, map[string]interface{}{
+		"serial":  int64(4567),
+		"command": "cat /etc/my shadow",
+		"cwd":     "/root",
+		"pid":     int64(1501),
+		"ppid":    int64(1432),
+		"auid":    int64(1000),
+		"ses":     int64(3),
+		"key":     "exec",
+	}, fields)
+
+	_, _, ok = convert(events[0], map[string]bool{"login": true})
+	require.False(t, ok)
+}
+
+func TestLogin(t *testing.T) {
+	events := assemble(t, loginRecord+"\n"+authRecord)
+	require.Len(t, events, 2)
+
+	tags, fields, ok := convert(events[0], allEvents)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{
+		"category": "login",
+		"uid":      "0",
+		"exe":      "/usr/sbin/sshd",
+		"result":   "success",
+	}, tags)
+	require.Equal(t, map[string]interface{}{
+		"serial":      int64(4568),
+		"record_type": "USER_LOGIN",
+		"addr":        "192.0.2.10",
+		"terminal":    "/dev/pts/1",
+		"pid":         int64(1612),
+		"auid":        int64(1000),
+		"ses":         int64(4),
+	}, fields)
+
+	tags, fields, ok = convert(events[1], allEvents)
+	require.True(t, ok)
+	require.Equal(t, "failed", tags["result"])
+	require.Equal(t, "admin", fields["acct"])
+	require.Equal(t, "198.51.100.7", fields["hostname"])
+}
+
+func TestDenied(t *testing.T) {
+	events := assemble(t, deniedRecords+avcRecords)
+	require.Len(t, events, 2)
+
+	tags, fields, ok := convert(events[0], allEvents)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{
+		"category": "denied",
+		"uid":      "1000",
+		"exe":      "/usr/bin/cat",
+		"result":   "denied",
+	}, tags)
+	require.Equal(t, map[string]interface{}{
+		"serial":  int64(4570),
+		"syscall": "257",
+		"comm":    "cat",
+		"name":    "/etc/shadow",
+		"exit":    int64(-13),
+		"pid":     int64(1650),
+		"auid":    int64(1000),
+		"ses":     int64(3),
+	}, fields)
+
+	tags, fields, ok = convert(events[1], allEvents)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{
+		"category": "denied",
+		"uid":      "48",
+		"exe":      "/usr/sbin/httpd",
+		"result":   "denied",
+	}, tags)
+	require.Equal(t, "read write", fields["permission"])
+	require.Equal(t, "index.html", fields["name"])
+	require.Equal(t, "httpd_t", strings.Split(fields["scontext"].(string), ":")[2])
+	require.Equal(t, "file", fields["tclass"])
+}
+
+func TestAssemblerTimeout(t *testing.T) {
+	var events []*event
+	a := newAssembler(time.Second, func(e *event) { events = append(events, e) })
+
+	now := time.Unix(1528300000, 0)
+	for _, line := range strings.Split(strings.TrimSpace(deniedRecords), "\n")[:2] {
+		r, err := parseLine(line)
+		require.NoError(t, err)
+		a.add(r, now)
+	}
+	a.flush(now.Add(500 * time.Millisecond))
+	require.Empty(t, events)
+
+	// The event is emitted without its EOE record after the timeout.
+	a.flush(now.Add(time.Second))
+	require.Len(t, events, 1)
+	require.Len(t, events[0].records, 2)
+}
@@ -11,8 +11,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
-	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -43,12 +42,11 @@ var (
 type Vars map[string]interface{}
 
 type Aurora struct {
-	Schedulers []string          `toml:"schedulers"`
-	Roles      []string          `toml:"roles"`
-	Timeout    internal.Duration `toml:"timeout"`
-	Username   string            `toml:"username"`
-	Password   string            `toml:"password"`
-	tls.ClientConfig
+	Schedulers []string `toml:"schedulers"`
+	Roles      []string `toml:"roles"`
+	Username   string   `toml:"username"`
+	Password   string   `toml:"password"`
+	httpconfig.HTTPClientConfig
 
 	client *http.Client
 	urls   []*url.URL
@@ -77,6 +75,15 @@ var sampleConfig = `
   # tls_key = "/etc/telegraf/key.pem"
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+
+  ## Optional HTTP headers and cookies
+  # headers = {"X-Special-Header" = "Special-Value"}
+  # cookies = {"session" = "value"}
+
+  ## Idle connections kept open for the next requests, per host, and time
+  ## before closing them (default = 2 and 0, unlimited).
+  # max_idle_conn_per_host = 2
+  # idle_conn_timeout = "0s"
 `
 
 func (a *Aurora) SampleConfig() string {
@@ -125,16 +132,13 @@ func (a *Aurora) Gather(acc telegraf.Accumulator) error {
 }
 
 func (a *Aurora) initialize() error {
-	tlsCfg, err := a.ClientConfig.TLSConfig()
-	if err != nil {
-		return err
+	if a.Timeout.Duration < time.Second {
+		a.Timeout.Duration = defaultTimeout
 	}
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			Proxy:           http.ProxyFromEnvironment,
-			TLSClientConfig: tlsCfg,
-		},
+	client, err := a.CreateClient("inputs.aurora")
+	if err != nil {
+		return err
 	}
 
 	urls := make([]*url.URL, 0, len(a.Schedulers))
@@ -147,10 +151,6 @@ func (a *Aurora) initialize() error {
 		urls = append(urls, loc)
 	}
 
-	if a.Timeout.Duration < time.Second {
-		a.Timeout.Duration = defaultTimeout
-	}
-
 	if len(a.Roles) == 0 {
 		a.Roles = defaultRoles
 	}
@@ -185,6 +185,7 @@ func (a *Aurora) gatherRole(ctx context.Context, origin *url.URL) (RoleType, err
 		req.SetBasicAuth(a.Username, a.Password)
 	}
 	req.Header.Add("Accept", "text/plain")
+	a.PrepareRequest(req)
 
 	resp, err := a.client.Do(req.WithContext(ctx))
 	if err != nil {
@@ -218,6 +219,7 @@ func (a *Aurora) gatherScheduler(
 		req.SetBasicAuth(a.Username, a.Password)
 	}
 	req.Header.Add("Accept", "application/json")
+	a.PrepareRequest(req)
 
 	resp, err := a.client.Do(req.WithContext(ctx))
 	if err != nil {
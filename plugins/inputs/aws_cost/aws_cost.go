@@ -0,0 +1,264 @@
+package aws_cost
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = "aws_cost"
+
+type AWSCost struct {
+	AccessKey string `toml:"access_key"`
+	SecretKey string `toml:"secret_key"`
+	RoleARN   string `toml:"role_arn"`
+	Profile   string `toml:"profile"`
+	Filename  string `toml:"shared_credential_file"`
+	Token     string `toml:"token"`
+
+	Metrics []string          `toml:"metrics"`
+	GroupBy []string          `toml:"group_by"`
+	Days    int               `toml:"days"`
+	Timeout internal.Duration `toml:"timeout"`
+
+	Region        string   `toml:"region"`
+	QuotaServices []string `toml:"quota_services"`
+
+	client costClient
+	quotas quotaClient
+	usage  usageClient
+	now    func() time.Time
+}
+
+var sampleConfig = `
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Cost metrics to collect, one of "UnblendedCost", "BlendedCost",
+  ## "AmortizedCost", "NetUnblendedCost", "NetAmortizedCost", "UsageQuantity"
+  ## or "NormalizedUsageAmount".  Costs are not collected when empty.
+  # metrics = ["UnblendedCost"]
+
+  ## Up to two dimensions to group the costs by, such as "SERVICE",
+  ## "LINKED_ACCOUNT", "REGION" or "USAGE_TYPE".
+  # group_by = ["SERVICE"]
+
+  ## Number of days to collect, including the current day.  Costs of the
+  ## recent days are revised by AWS, so these are collected again.
+  # days = 3
+
+  ## Timeout for Cost Explorer and Service Quotas requests.
+  # timeout = "30s"
+
+  ## Service codes of the services whose quotas are collected from the
+  ## Service Quotas API, with their usage and utilization, such as "ec2" or
+  ## "lambda".  Quotas are not collected when empty.
+  # quota_services = []
+
+  ## Region of the quotas.
+  # region = "us-east-1"
+
+  ## Every request to the Cost Explorer API is charged, so a daily schedule
+  ## is recommended over an interval.
+  schedule = "0 6 * * *"
+`
+
+func (a *AWSCost) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AWSCost) Description() string {
+	return "Gather daily spend from the AWS Cost Explorer API and service quotas utilization"
+}
+
+func (a *AWSCost) Gather(acc telegraf.Accumulator) error {
+	if a.client == nil {
+		if err := a.init(); err != nil {
+			return err
+		}
+	}
+
+	if len(a.GroupBy) > 2 {
+		return fmt.Errorf("at most two group_by dimensions are supported")
+	}
+
+	if len(a.QuotaServices) > 0 {
+		a.gatherQuotas(acc)
+	}
+	if len(a.Metrics) == 0 {
+		return nil
+	}
+	return a.gatherCosts(acc)
+}
+
+func (a *AWSCost) gatherCosts(acc telegraf.Accumulator) error {
+
+	end := truncateDay(a.now().UTC()).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -a.Days)
+	request := &CostRequest{
+		TimePeriod: DateInterval{
+			Start: start.Format("2006-01-02"),
+			End:   end.Format("2006-01-02"),
+		},
+		Granularity: "DAILY",
+		Metrics:     a.Metrics,
+	}
+	for _, key := range a.GroupBy {
+		request.GroupBy = append(request.GroupBy, GroupDefinition{
+			Type: "DIMENSION",
+			Key:  key,
+		})
+	}
+
+	ctx := context.Background()
+	for {
+		resp, err := a.client.GetCostAndUsage(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range resp.ResultsByTime {
+			a.addResult(acc, result)
+		}
+
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		request.NextPageToken = resp.NextPageToken
+	}
+}
+
+func (a *AWSCost) addResult(acc telegraf.Accumulator, result ResultByTime) {
+	timestamp, err := time.Parse("2006-01-02", result.TimePeriod.Start)
+	if err != nil {
+		acc.AddError(fmt.Errorf("invalid time period start %q", result.TimePeriod.Start))
+		return
+	}
+
+	// Without grouping the costs are only present in the total.
+	if len(a.GroupBy) == 0 {
+		a.addMetrics(acc, map[string]string{}, result.Total, result.Estimated, timestamp)
+		return
+	}
+
+	for _, group := range result.Groups {
+		tags := make(map[string]string, len(group.Keys)+1)
+		for i, key := range group.Keys {
+			if i < len(a.GroupBy) {
+				tags[strings.ToLower(a.GroupBy[i])] = key
+			}
+		}
+		a.addMetrics(acc, tags, group.Metrics, result.Estimated, timestamp)
+	}
+}
+
+func (a *AWSCost) addMetrics(
+	acc telegraf.Accumulator,
+	tags map[string]string,
+	metrics map[string]MetricValue,
+	estimated bool,
+	timestamp time.Time,
+) {
+	fields := make(map[string]interface{}, len(metrics)+1)
+	for name, value := range metrics {
+		amount, err := strconv.ParseFloat(value.Amount, 64)
+		if err != nil {
+			acc.AddError(fmt.Errorf("invalid amount %q for %s", value.Amount, name))
+			continue
+		}
+		fields[snakeCase(name)] = amount
+
+		if strings.HasSuffix(name, "Cost") && value.Unit != "" {
+			tags["currency"] = value.Unit
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	fields["estimated"] = estimated
+
+	acc.AddFields(measurement, fields, tags, timestamp)
+}
+
+func (a *AWSCost) init() error {
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:    signingRegion,
+		AccessKey: a.AccessKey,
+		SecretKey: a.SecretKey,
+		RoleARN:   a.RoleARN,
+		Profile:   a.Profile,
+		Filename:  a.Filename,
+		Token:     a.Token,
+	}
+	config := credentialConfig.Credentials().ClientConfig(signingName)
+	if config.Config == nil || config.Config.Credentials == nil {
+		return fmt.Errorf("no AWS credentials available")
+	}
+
+	a.client = newCEClient(defaultEndpoint, config.Config.Credentials, a.Timeout.Duration)
+
+	if len(a.QuotaServices) > 0 {
+		credentialConfig.Region = a.Region
+		provider := credentialConfig.Credentials()
+		config := provider.ClientConfig(quotasSigningName)
+		url := fmt.Sprintf("https://servicequotas.%s.amazonaws.com", a.Region)
+		a.quotas = newSQClient(url, a.Region, config.Config.Credentials, a.Timeout.Duration)
+		a.usage = cloudwatch.New(provider)
+	}
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// snakeCase converts metric names such as "UnblendedCost" to field names
+// such as "unblended_cost".
+func snakeCase(name string) string {
+	var b bytes.Buffer
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func init() {
+	inputs.Add("aws_cost", func() telegraf.Input {
+		return &AWSCost{
+			Metrics: []string{"UnblendedCost"},
+			GroupBy: []string{"SERVICE"},
+			Days:    3,
+			Timeout: internal.Duration{Duration: 30 * time.Second},
+			Region:  "us-east-1",
+			now:     time.Now,
+		}
+	})
+}
@@ -0,0 +1,352 @@
+package aws_cost
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	requests  []CostRequest
+	responses []*CostResponse
+}
+
+func (m *mockClient) GetCostAndUsage(ctx context.Context, req *CostRequest) (*CostResponse, error) {
+	m.requests = append(m.requests, *req)
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return resp, nil
+}
+
+func newAWSCost(client costClient) *AWSCost {
+	return &AWSCost{
+		Metrics: []string{"UnblendedCost"},
+		GroupBy: []string{"SERVICE"},
+		Days:    2,
+		client:  client,
+		now: func() time.Time {
+			return time.Date(2018, 6, 2, 12, 0, 0, 0, time.UTC)
+		},
+	}
+}
+
+func TestGatherGrouped(t *testing.T) {
+	client := &mockClient{
+		responses: []*CostResponse{
+			{
+				ResultsByTime: []ResultByTime{
+					{
+						TimePeriod: DateInterval{Start: "2018-06-01", End: "2018-06-02"},
+						Groups: []Group{
+							{
+								Keys: []string{"Amazon Elastic Compute Cloud - Compute"},
+								Metrics: map[string]MetricValue{
+									"UnblendedCost": {Amount: "12.5", Unit: "USD"},
+								},
+							},
+						},
+					},
+				},
+				NextPageToken: "page2",
+			},
+			{
+				ResultsByTime: []ResultByTime{
+					{
+						TimePeriod: DateInterval{Start: "2018-06-02", End: "2018-06-03"},
+						Groups: []Group{
+							{
+								Keys: []string{"Amazon Simple Storage Service"},
+								Metrics: map[string]MetricValue{
+									"UnblendedCost": {Amount: "0.25", Unit: "USD"},
+								},
+							},
+						},
+						Estimated: true,
+					},
+				},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	plugin := newAWSCost(client)
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	require.Len(t, client.requests, 2)
+	require.Equal(t, DateInterval{Start: "2018-06-01", End: "2018-06-03"},
+		client.requests[0].TimePeriod)
+	require.Equal(t, "DAILY", client.requests[0].Granularity)
+	require.Equal(t, []GroupDefinition{{Type: "DIMENSION", Key: "SERVICE"}},
+		client.requests[0].GroupBy)
+	require.Equal(t, "page2", client.requests[1].NextPageToken)
+
+	acc.AssertContainsTaggedFields(t, "aws_cost",
+		map[string]interface{}{
+			"unblended_cost": 12.5,
+			"estimated":      false,
+		},
+		map[string]string{
+			"service":  "Amazon Elastic Compute Cloud - Compute",
+			"currency": "USD",
+		})
+	acc.AssertContainsTaggedFields(t, "aws_cost",
+		map[string]interface{}{
+			"unblended_cost": 0.25,
+			"estimated":      true,
+		},
+		map[string]string{
+			"service":  "Amazon Simple Storage Service",
+			"currency": "USD",
+		})
+
+	m, ok := acc.Get("aws_cost")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), m.Time)
+}
+
+func TestGatherTotal(t *testing.T) {
+	client := &mockClient{
+		responses: []*CostResponse{
+			{
+				ResultsByTime: []ResultByTime{
+					{
+						TimePeriod: DateInterval{Start: "2018-06-02", End: "2018-06-03"},
+						Total: map[string]MetricValue{
+							"UnblendedCost": {Amount: "3", Unit: "USD"},
+							"UsageQuantity": {Amount: "42", Unit: "N/A"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	plugin := newAWSCost(client)
+	plugin.Metrics = []string{"UnblendedCost", "UsageQuantity"}
+	plugin.GroupBy = nil
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	acc.AssertContainsTaggedFields(t, "aws_cost",
+		map[string]interface{}{
+			"unblended_cost": 3.0,
+			"usage_quantity": 42.0,
+			"estimated":      false,
+		},
+		map[string]string{
+			"currency": "USD",
+		})
+}
+
+func TestGatherTooManyGroups(t *testing.T) {
+	var acc testutil.Accumulator
+	plugin := newAWSCost(&mockClient{})
+	plugin.GroupBy = []string{"SERVICE", "REGION", "USAGE_TYPE"}
+	require.Error(t, acc.GatherError(plugin.Gather))
+}
+
+func TestSnakeCase(t *testing.T) {
+	require.Equal(t, "unblended_cost", snakeCase("UnblendedCost"))
+	require.Equal(t, "normalized_usage_amount", snakeCase("NormalizedUsageAmount"))
+}
+
+func TestCEClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, getCostAndUsageTarget, r.Header.Get("X-Amz-Target"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKID/"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		req := &CostRequest{}
+		require.NoError(t, json.Unmarshal(body, req))
+		require.Equal(t, []string{"UnblendedCost"}, req.Metrics)
+
+		w.Write([]byte(`{"ResultsByTime":[{"TimePeriod":{"Start":"2018-06-01","End":"2018-06-02"},"Total":{"UnblendedCost":{"Amount":"1.5","Unit":"USD"}},"Groups":[],"Estimated":false}]}`))
+	}))
+	defer ts.Close()
+
+	client := newCEClient(ts.URL,
+		credentials.NewStaticCredentials("AKID", "SECRET", ""), time.Second)
+	resp, err := client.GetCostAndUsage(context.Background(), &CostRequest{
+		Metrics: []string{"UnblendedCost"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.ResultsByTime, 1)
+	require.Equal(t, "1.5", resp.ResultsByTime[0].Total["UnblendedCost"].Amount)
+}
+
+func TestCEClientError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"LimitExceededException","message":"Rate exceeded"}`))
+	}))
+	defer ts.Close()
+
+	client := newCEClient(ts.URL,
+		credentials.NewStaticCredentials("AKID", "SECRET", ""), time.Second)
+	_, err := client.GetCostAndUsage(context.Background(), &CostRequest{})
+	require.EqualError(t, err, "LimitExceededException: Rate exceeded")
+}
+
+type mockQuotaClient struct {
+	requests  []QuotasRequest
+	responses []*QuotasResponse
+}
+
+func (m *mockQuotaClient) ListServiceQuotas(ctx context.Context, req *QuotasRequest) (*QuotasResponse, error) {
+	m.requests = append(m.requests, *req)
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return resp, nil
+}
+
+type mockUsageClient struct {
+	inputs []*cloudwatch.GetMetricStatisticsInput
+	output *cloudwatch.GetMetricStatisticsOutput
+}
+
+func (m *mockUsageClient) GetMetricStatistics(input *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return m.output, nil
+}
+
+func TestGatherQuotas(t *testing.T) {
+	quotas := &mockQuotaClient{
+		responses: []*QuotasResponse{
+			{
+				Quotas: []ServiceQuota{
+					{
+						ServiceCode: "ec2",
+						ServiceName: "Amazon Elastic Compute Cloud (Amazon EC2)",
+						QuotaCode:   "L-1216C47A",
+						QuotaName:   "Running On-Demand Standard instances",
+						Value:       64,
+						Unit:        "None",
+						UsageMetric: &MetricInfo{
+							MetricNamespace: "AWS/Usage",
+							MetricName:      "ResourceCount",
+							MetricDimensions: map[string]string{
+								"Type":     "Resource",
+								"Service":  "EC2",
+								"Resource": "vCPU",
+							},
+							MetricStatisticRecommendation: "Maximum",
+						},
+					},
+				},
+				NextToken: "page2",
+			},
+			{
+				Quotas: []ServiceQuota{
+					{
+						ServiceCode: "ec2",
+						ServiceName: "Amazon Elastic Compute Cloud (Amazon EC2)",
+						QuotaCode:   "L-0263D0A3",
+						QuotaName:   "EC2-VPC Elastic IPs",
+						Value:       5,
+						Unit:        "None",
+					},
+				},
+			},
+		},
+	}
+	usage := &mockUsageClient{
+		output: &cloudwatch.GetMetricStatisticsOutput{
+			Datapoints: []*cloudwatch.Datapoint{
+				{Timestamp: aws.Time(time.Date(2018, 6, 2, 11, 50, 0, 0, time.UTC)), Maximum: aws.Float64(12)},
+				{Timestamp: aws.Time(time.Date(2018, 6, 2, 11, 55, 0, 0, time.UTC)), Maximum: aws.Float64(16)},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	plugin := newAWSCost(&mockClient{})
+	plugin.Metrics = nil
+	plugin.Region = "eu-west-1"
+	plugin.QuotaServices = []string{"ec2"}
+	plugin.quotas = quotas
+	plugin.usage = usage
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	require.Equal(t, []QuotasRequest{
+		{ServiceCode: "ec2"},
+		{ServiceCode: "ec2", NextToken: "page2"},
+	}, quotas.requests)
+
+	require.Len(t, usage.inputs, 1)
+	input := usage.inputs[0]
+	require.Equal(t, "AWS/Usage", *input.Namespace)
+	require.Equal(t, "ResourceCount", *input.MetricName)
+	require.Equal(t, "Resource", *input.Dimensions[0].Name)
+	require.Equal(t, "Service", *input.Dimensions[1].Name)
+	require.Equal(t, "Type", *input.Dimensions[2].Name)
+	require.Equal(t, []*string{aws.String("Maximum")}, input.Statistics)
+
+	now := time.Date(2018, 6, 2, 12, 0, 0, 0, time.UTC)
+	acc.AssertContainsTaggedFields(t, "aws_service_quota",
+		map[string]interface{}{
+			"value":       float64(64),
+			"usage":       float64(16),
+			"utilization": float64(25),
+		},
+		map[string]string{
+			"region":       "eu-west-1",
+			"service_code": "ec2",
+			"service_name": "Amazon Elastic Compute Cloud (Amazon EC2)",
+			"quota_code":   "L-1216C47A",
+			"quota_name":   "Running On-Demand Standard instances",
+		})
+	acc.AssertContainsTaggedFields(t, "aws_service_quota",
+		map[string]interface{}{
+			"value": float64(5),
+		},
+		map[string]string{
+			"region":       "eu-west-1",
+			"service_code": "ec2",
+			"service_name": "Amazon Elastic Compute Cloud (Amazon EC2)",
+			"quota_code":   "L-0263D0A3",
+			"quota_name":   "EC2-VPC Elastic IPs",
+		})
+	for _, m := range acc.Metrics {
+		require.Equal(t, now, m.Time)
+	}
+	// The costs are not collected without metrics.
+	require.False(t, acc.HasMeasurement("aws_cost"))
+}
+
+func TestSQClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, listServiceQuotasTarget, r.Header.Get("X-Amz-Target"))
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/servicequotas/")
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		req := &QuotasRequest{}
+		require.NoError(t, json.Unmarshal(body, req))
+		require.Equal(t, "lambda", req.ServiceCode)
+
+		w.Write([]byte(`{"Quotas":[{"ServiceCode":"lambda","QuotaCode":"L-B99A9384","QuotaName":"Concurrent executions","Value":1000.0,"Unit":"None"}]}`))
+	}))
+	defer ts.Close()
+
+	client := newSQClient(ts.URL, "eu-west-1",
+		credentials.NewStaticCredentials("AKID", "SECRET", ""), time.Second)
+	resp, err := client.ListServiceQuotas(context.Background(), &QuotasRequest{ServiceCode: "lambda"})
+	require.NoError(t, err)
+	require.Len(t, resp.Quotas, 1)
+	require.Equal(t, float64(1000), resp.Quotas[0].Value)
+	require.Nil(t, resp.Quotas[0].UsageMetric)
+}
@@ -0,0 +1,163 @@
+package aws_cost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	// Cost Explorer is only served from the us-east-1 region.
+	defaultEndpoint = "https://ce.us-east-1.amazonaws.com"
+	signingName     = "ce"
+	signingRegion   = "us-east-1"
+
+	getCostAndUsageTarget = "AWSInsightsIndexService.GetCostAndUsage"
+)
+
+type costClient interface {
+	GetCostAndUsage(ctx context.Context, req *CostRequest) (*CostResponse, error)
+}
+
+type DateInterval struct {
+	Start string `json:"Start"`
+	End   string `json:"End"`
+}
+
+type GroupDefinition struct {
+	Type string `json:"Type"`
+	Key  string `json:"Key"`
+}
+
+// CostRequest is the request of the GetCostAndUsage API.
+type CostRequest struct {
+	TimePeriod    DateInterval      `json:"TimePeriod"`
+	Granularity   string            `json:"Granularity"`
+	Metrics       []string          `json:"Metrics"`
+	GroupBy       []GroupDefinition `json:"GroupBy,omitempty"`
+	NextPageToken string            `json:"NextPageToken,omitempty"`
+}
+
+type MetricValue struct {
+	Amount string `json:"Amount"`
+	Unit   string `json:"Unit"`
+}
+
+type Group struct {
+	Keys    []string               `json:"Keys"`
+	Metrics map[string]MetricValue `json:"Metrics"`
+}
+
+type ResultByTime struct {
+	TimePeriod DateInterval           `json:"TimePeriod"`
+	Total      map[string]MetricValue `json:"Total"`
+	Groups     []Group                `json:"Groups"`
+	Estimated  bool                   `json:"Estimated"`
+}
+
+// CostResponse is the response of the GetCostAndUsage API.
+type CostResponse struct {
+	ResultsByTime []ResultByTime `json:"ResultsByTime"`
+	NextPageToken string         `json:"NextPageToken"`
+}
+
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Type, e.Message)
+	}
+	return fmt.Sprintf("%s (status %d)", e.Type, e.StatusCode)
+}
+
+// jsonClient calls an AWS JSON API with requests signed using Signature
+// Version 4.
+type jsonClient struct {
+	url           string
+	signingName   string
+	signingRegion string
+	signer        *v4.Signer
+	httpClient    *http.Client
+}
+
+func newJSONClient(url, signingName, signingRegion string, creds *credentials.Credentials, timeout time.Duration) *jsonClient {
+	return &jsonClient{
+		url:           url,
+		signingName:   signingName,
+		signingRegion: signingRegion,
+		signer:        v4.NewSigner(creds),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// call sends the request to the operation of the target, and decodes its
+// response.
+func (c *jsonClient) call(ctx context.Context, target string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	// Sign sets the request body.
+	_, err = c.signer.Sign(req, bytes.NewReader(body), c.signingName, c.signingRegion, time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}{}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Type:       apiErr.Type,
+			Message:    apiErr.Message,
+		}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// ceClient calls the Cost Explorer API.
+type ceClient struct {
+	*jsonClient
+}
+
+func newCEClient(url string, creds *credentials.Credentials, timeout time.Duration) *ceClient {
+	return &ceClient{newJSONClient(url, signingName, signingRegion, creds, timeout)}
+}
+
+func (c *ceClient) GetCostAndUsage(ctx context.Context, request *CostRequest) (*CostResponse, error) {
+	response := &CostResponse{}
+	if err := c.call(ctx, getCostAndUsageTarget, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
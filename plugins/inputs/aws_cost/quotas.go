@@ -0,0 +1,196 @@
+package aws_cost
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	quotaMeasurement = "aws_service_quota"
+
+	quotasSigningName       = "servicequotas"
+	listServiceQuotasTarget = "ServiceQuotasV20190624.ListServiceQuotas"
+
+	// usagePeriod is the period of the statistics of the usage metrics,
+	// which are published every minute.
+	usagePeriod = 5 * time.Minute
+	// usageWindow is how far back the latest usage is looked for.
+	usageWindow = time.Hour
+)
+
+type quotaClient interface {
+	ListServiceQuotas(ctx context.Context, req *QuotasRequest) (*QuotasResponse, error)
+}
+
+// usageClient gets the usage of the quotas from their CloudWatch metrics.
+type usageClient interface {
+	GetMetricStatistics(*cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error)
+}
+
+// QuotasRequest is the request of the ListServiceQuotas API.
+type QuotasRequest struct {
+	ServiceCode string `json:"ServiceCode"`
+	NextToken   string `json:"NextToken,omitempty"`
+}
+
+// MetricInfo is the CloudWatch metric of the usage of a quota.
+type MetricInfo struct {
+	MetricNamespace               string            `json:"MetricNamespace"`
+	MetricName                    string            `json:"MetricName"`
+	MetricDimensions              map[string]string `json:"MetricDimensions"`
+	MetricStatisticRecommendation string            `json:"MetricStatisticRecommendation"`
+}
+
+type ServiceQuota struct {
+	ServiceCode string      `json:"ServiceCode"`
+	ServiceName string      `json:"ServiceName"`
+	QuotaCode   string      `json:"QuotaCode"`
+	QuotaName   string      `json:"QuotaName"`
+	Value       float64     `json:"Value"`
+	Unit        string      `json:"Unit"`
+	UsageMetric *MetricInfo `json:"UsageMetric"`
+}
+
+// QuotasResponse is the response of the ListServiceQuotas API.
+type QuotasResponse struct {
+	Quotas    []ServiceQuota `json:"Quotas"`
+	NextToken string         `json:"NextToken"`
+}
+
+// sqClient calls the Service Quotas API.
+type sqClient struct {
+	*jsonClient
+}
+
+func newSQClient(url, region string, creds *credentials.Credentials, timeout time.Duration) *sqClient {
+	return &sqClient{newJSONClient(url, quotasSigningName, region, creds, timeout)}
+}
+
+func (c *sqClient) ListServiceQuotas(ctx context.Context, request *QuotasRequest) (*QuotasResponse, error) {
+	response := &QuotasResponse{}
+	if err := c.call(ctx, listServiceQuotasTarget, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// gatherQuotas adds the quotas of the services of quota_services, with
+// their usage when AWS publishes it.
+func (a *AWSCost) gatherQuotas(acc telegraf.Accumulator) {
+	ctx := context.Background()
+	for _, service := range a.QuotaServices {
+		request := &QuotasRequest{ServiceCode: service}
+		for {
+			resp, err := a.quotas.ListServiceQuotas(ctx, request)
+			if err != nil {
+				acc.AddError(fmt.Errorf("listing the quotas of %s: %s", service, err))
+				break
+			}
+			for _, quota := range resp.Quotas {
+				a.addQuota(acc, quota)
+			}
+			if resp.NextToken == "" {
+				break
+			}
+			request.NextToken = resp.NextToken
+		}
+	}
+}
+
+func (a *AWSCost) addQuota(acc telegraf.Accumulator, quota ServiceQuota) {
+	tags := map[string]string{
+		"region":       a.Region,
+		"service_code": quota.ServiceCode,
+		"service_name": quota.ServiceName,
+		"quota_code":   quota.QuotaCode,
+		"quota_name":   quota.QuotaName,
+	}
+	if quota.Unit != "" && quota.Unit != "None" {
+		tags["unit"] = quota.Unit
+	}
+	fields := map[string]interface{}{
+		"value": quota.Value,
+	}
+
+	if quota.UsageMetric != nil && quota.UsageMetric.MetricName != "" {
+		usage, ok, err := a.quotaUsage(quota.UsageMetric)
+		if err != nil {
+			acc.AddError(fmt.Errorf("getting the usage of the quota %s of %s: %s",
+				quota.QuotaCode, quota.ServiceCode, err))
+		} else if ok {
+			fields["usage"] = usage
+			if quota.Value > 0 {
+				fields["utilization"] = usage / quota.Value * 100
+			}
+		}
+	}
+
+	acc.AddFields(quotaMeasurement, fields, tags, a.now())
+}
+
+// quotaUsage returns the latest usage of the metric of a quota, with the
+// statistic recommended by AWS.  ok is false if no usage was published
+// recently.
+func (a *AWSCost) quotaUsage(metric *MetricInfo) (usage float64, ok bool, err error) {
+	statistic := metric.MetricStatisticRecommendation
+	if statistic == "" {
+		statistic = cloudwatch.StatisticMaximum
+	}
+
+	names := make([]string, 0, len(metric.MetricDimensions))
+	for name := range metric.MetricDimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	dimensions := make([]*cloudwatch.Dimension, 0, len(names))
+	for _, name := range names {
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(name),
+			Value: aws.String(metric.MetricDimensions[name]),
+		})
+	}
+
+	end := a.now()
+	resp, err := a.usage.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String(metric.MetricNamespace),
+		MetricName: aws.String(metric.MetricName),
+		Dimensions: dimensions,
+		StartTime:  aws.Time(end.Add(-usageWindow)),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int64(int64(usagePeriod / time.Second)),
+		Statistics: []*string{aws.String(statistic)},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	var latest *cloudwatch.Datapoint
+	for _, point := range resp.Datapoints {
+		if point.Timestamp != nil && (latest == nil || point.Timestamp.After(*latest.Timestamp)) {
+			latest = point
+		}
+	}
+	if latest == nil {
+		return 0, false, nil
+	}
+	switch statistic {
+	case cloudwatch.StatisticSum:
+		usage = aws.Float64Value(latest.Sum)
+	case cloudwatch.StatisticAverage:
+		usage = aws.Float64Value(latest.Average)
+	case cloudwatch.StatisticMinimum:
+		usage = aws.Float64Value(latest.Minimum)
+	case cloudwatch.StatisticSampleCount:
+		usage = aws.Float64Value(latest.SampleCount)
+	default:
+		usage = aws.Float64Value(latest.Maximum)
+	}
+	return usage, true, nil
+}
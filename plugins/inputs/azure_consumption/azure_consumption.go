@@ -0,0 +1,224 @@
+package azure_consumption
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/azure"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement = "azure_consumption"
+
+	defaultManagementURL = "https://management.azure.com"
+	apiVersion           = "2018-03-31"
+)
+
+type AzureConsumption struct {
+	TenantID       string            `toml:"tenant_id"`
+	ClientID       string            `toml:"client_id"`
+	ClientSecret   string            `toml:"client_secret"`
+	SubscriptionID string            `toml:"subscription_id"`
+	Days           int               `toml:"days"`
+	Timeout        internal.Duration `toml:"timeout"`
+
+	loginURL      string
+	managementURL string
+	client        *http.Client
+	tokenSource   *azure.TokenSource
+	now           func() time.Time
+}
+
+var sampleConfig = `
+  ## Azure Active Directory application used to read the usage, it requires
+  ## the "Billing Reader" role on the subscription.
+  tenant_id = ""
+  client_id = ""
+  client_secret = ""
+
+  ## Subscription to collect the consumption of.
+  subscription_id = ""
+
+  ## Number of days to collect, including the current day.  Usage of the
+  ## recent days is still being reported by Azure, so these are collected
+  ## again.
+  # days = 3
+
+  ## Timeout for API requests.
+  # timeout = "30s"
+
+  ## Usage details are updated a few times per day, a daily schedule is
+  ## recommended over an interval.
+  schedule = "0 6 * * *"
+`
+
+func (a *AzureConsumption) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AzureConsumption) Description() string {
+	return "Gather daily spend from the Azure Consumption API"
+}
+
+// usageDetail is a single entry of the usage details list.
+type usageDetail struct {
+	Properties struct {
+		UsageStart      string  `json:"usageStart"`
+		ConsumedService string  `json:"consumedService"`
+		PretaxCost      float64 `json:"pretaxCost"`
+		Currency        string  `json:"currency"`
+	} `json:"properties"`
+}
+
+type usageDetailsResponse struct {
+	Value    []usageDetail `json:"value"`
+	NextLink string        `json:"nextLink"`
+}
+
+type apiErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type costKey struct {
+	day      time.Time
+	service  string
+	currency string
+}
+
+func (a *AzureConsumption) Gather(acc telegraf.Accumulator) error {
+	if a.SubscriptionID == "" {
+		return fmt.Errorf("subscription_id is required")
+	}
+
+	if a.client == nil {
+		a.client = &http.Client{
+			Timeout: a.Timeout.Duration,
+		}
+		credentialConfig := &azure.CredentialConfig{
+			TenantID:     a.TenantID,
+			ClientID:     a.ClientID,
+			ClientSecret: a.ClientSecret,
+			LoginURL:     a.loginURL,
+		}
+		a.tokenSource = credentialConfig.TokenSource(a.managementURL+"/", a.client)
+	}
+
+	ctx := context.Background()
+
+	end := truncateDay(a.now().UTC()).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -a.Days)
+
+	params := url.Values{}
+	params.Set("api-version", apiVersion)
+	params.Set("$filter", fmt.Sprintf(
+		"properties/usageStart ge '%s' and properties/usageEnd lt '%s'",
+		start.Format("2006-01-02"), end.Format("2006-01-02")))
+	loc := fmt.Sprintf("%s/subscriptions/%s/providers/Microsoft.Consumption/usageDetails?%s",
+		a.managementURL, url.PathEscape(a.SubscriptionID), params.Encode())
+
+	// Usage details are reported per resource and meter, these are summed
+	// up per day and consumed service.
+	costs := make(map[costKey]float64)
+	for loc != "" {
+		resp := &usageDetailsResponse{}
+		if err := a.get(ctx, loc, resp); err != nil {
+			return err
+		}
+
+		for _, detail := range resp.Value {
+			usageStart, err := time.Parse(time.RFC3339, detail.Properties.UsageStart)
+			if err != nil {
+				acc.AddError(fmt.Errorf("invalid usage start %q",
+					detail.Properties.UsageStart))
+				continue
+			}
+
+			key := costKey{
+				day:      truncateDay(usageStart.UTC()),
+				service:  detail.Properties.ConsumedService,
+				currency: detail.Properties.Currency,
+			}
+			costs[key] += detail.Properties.PretaxCost
+		}
+
+		loc = resp.NextLink
+	}
+
+	for key, cost := range costs {
+		tags := map[string]string{
+			"subscription_id":  a.SubscriptionID,
+			"consumed_service": key.service,
+		}
+		if key.currency != "" {
+			tags["currency"] = key.currency
+		}
+		fields := map[string]interface{}{
+			"pretax_cost": cost,
+		}
+		acc.AddFields(measurement, fields, tags, key.day)
+	}
+	return nil
+}
+
+func (a *AzureConsumption) get(ctx context.Context, loc string, v interface{}) error {
+	token, err := a.tokenSource.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", loc, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		a.tokenSource.Invalidate()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func apiError(resp *http.Response) error {
+	errResp := &apiErrorResponse{}
+	json.NewDecoder(resp.Body).Decode(errResp)
+	if errResp.Error.Message != "" {
+		return fmt.Errorf("%s: %s: %s", resp.Status, errResp.Error.Code,
+			errResp.Error.Message)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func init() {
+	inputs.Add("azure_consumption", func() telegraf.Input {
+		return &AzureConsumption{
+			Days:          3,
+			Timeout:       internal.Duration{Duration: 30 * time.Second},
+			managementURL: defaultManagementURL,
+			now:           time.Now,
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package azure_consumption
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const usagePage1 = `{
+  "value": [
+    {"properties": {"usageStart": "2018-06-01T00:00:00Z", "consumedService": "Microsoft.Compute", "pretaxCost": 1.5, "currency": "EUR"}},
+    {"properties": {"usageStart": "2018-06-01T00:00:00Z", "consumedService": "Microsoft.Compute", "pretaxCost": 2.25, "currency": "EUR"}}
+  ],
+  "nextLink": "%s/page2"
+}`
+
+const usagePage2 = `{
+  "value": [
+    {"properties": {"usageStart": "2018-06-02T00:00:00Z", "consumedService": "Microsoft.Storage", "pretaxCost": 0.5, "currency": "EUR"}}
+  ]
+}`
+
+func newTestServer(t *testing.T, logins *int) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tenant/oauth2/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			require.Equal(t, "client", r.PostForm.Get("client_id"))
+			require.Equal(t, "secret", r.PostForm.Get("client_secret"))
+			*logins++
+			w.Write([]byte(`{"access_token": "token", "expires_in": "3600"}`))
+		case "/subscriptions/sub/providers/Microsoft.Consumption/usageDetails":
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			require.Equal(t, apiVersion, r.URL.Query().Get("api-version"))
+			require.Equal(t,
+				"properties/usageStart ge '2018-06-01' and properties/usageEnd lt '2018-06-03'",
+				r.URL.Query().Get("$filter"))
+			fmt.Fprintf(w, usagePage1, ts.URL)
+		case "/page2":
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			w.Write([]byte(usagePage2))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ts
+}
+
+func newAzureConsumption(url string) *AzureConsumption {
+	return &AzureConsumption{
+		TenantID:       "tenant",
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		SubscriptionID: "sub",
+		Days:           2,
+		Timeout:        internal.Duration{Duration: time.Second},
+		loginURL:       url,
+		managementURL:  url,
+		now: func() time.Time {
+			return time.Date(2018, 6, 2, 12, 0, 0, 0, time.UTC)
+		},
+	}
+}
+
+func TestGather(t *testing.T) {
+	var logins int
+	ts := newTestServer(t, &logins)
+	defer ts.Close()
+
+	plugin := newAzureConsumption(ts.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	acc.AssertContainsTaggedFields(t, "azure_consumption",
+		map[string]interface{}{
+			"pretax_cost": 3.75,
+		},
+		map[string]string{
+			"subscription_id":  "sub",
+			"consumed_service": "Microsoft.Compute",
+			"currency":         "EUR",
+		})
+	acc.AssertContainsTaggedFields(t, "azure_consumption",
+		map[string]interface{}{
+			"pretax_cost": 0.5,
+		},
+		map[string]string{
+			"subscription_id":  "sub",
+			"consumed_service": "Microsoft.Storage",
+			"currency":         "EUR",
+		})
+
+	// The token is reused until it expires.
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, 1, logins)
+}
+
+func TestGatherLoginError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "invalid_client", "error_description": "Invalid client secret"}`))
+	}))
+	defer ts.Close()
+
+	plugin := newAzureConsumption(ts.URL)
+
+	var acc testutil.Accumulator
+	err := acc.GatherError(plugin.Gather)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid client secret")
+}
+
+func TestGatherMissingSubscription(t *testing.T) {
+	plugin := newAzureConsumption("http://localhost")
+	plugin.SubscriptionID = ""
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(plugin.Gather))
+}
@@ -0,0 +1,406 @@
+package azure_query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/azure"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	defaultMeasurement = "azure_query"
+
+	sourceLogAnalytics  = "log_analytics"
+	sourceResourceGraph = "resource_graph"
+
+	defaultLogAnalyticsURL = "https://api.loganalytics.io"
+	defaultManagementURL   = "https://management.azure.com"
+	resourceGraphVersion   = "2019-04-01"
+)
+
+type AzureQuery struct {
+	TenantID     string            `toml:"tenant_id"`
+	ClientID     string            `toml:"client_id"`
+	ClientSecret string            `toml:"client_secret"`
+	Timeout      internal.Duration `toml:"timeout"`
+	Queries      []*Query          `toml:"query"`
+
+	loginURL        string
+	logAnalyticsURL string
+	managementURL   string
+	client          *http.Client
+	tokenSources    map[string]*azure.TokenSource
+}
+
+// Query is a KQL query whose result table is converted into metrics.
+type Query struct {
+	Name          string            `toml:"name"`
+	Source        string            `toml:"source"`
+	WorkspaceID   string            `toml:"workspace_id"`
+	Subscriptions []string          `toml:"subscriptions"`
+	Query         string            `toml:"query"`
+	Timespan      internal.Duration `toml:"timespan"`
+	TagColumns    []string          `toml:"tag_columns"`
+	TimeColumn    string            `toml:"time_column"`
+}
+
+var sampleConfig = `
+  ## Azure Active Directory application used to run the queries.  It requires
+  ## the "Log Analytics Reader" role on the workspaces and the "Reader" role
+  ## on the subscriptions queried.
+  tenant_id = ""
+  client_id = ""
+  client_secret = ""
+
+  ## Timeout for API requests.
+  # timeout = "30s"
+
+  ## Queries to run, each query produces a metric per row of its result.
+  [[inputs.azure_query.query]]
+    ## Measurement name of the metrics, defaults to "azure_query".
+    name = "heartbeat"
+
+    ## Service to query, either "log_analytics" for Azure Monitor Logs or
+    ## "resource_graph" for Azure Resource Graph.
+    source = "log_analytics"
+
+    ## Log Analytics workspace to query.
+    workspace_id = ""
+
+    ## Subscriptions to query with Resource Graph.
+    # subscriptions = []
+
+    ## The KQL query to run.
+    query = "Heartbeat | summarize last_heartbeat = max(TimeGenerated), count = count() by Computer"
+
+    ## Time range of the Log Analytics data to query, ending now.  When not
+    ## set the time range must be specified in the query.
+    timespan = "1h"
+
+    ## Columns to use as tags instead of fields.
+    tag_columns = ["Computer"]
+
+    ## Datetime column to use as metric timestamp, defaults to the gather
+    ## time.
+    # time_column = ""
+`
+
+func (a *AzureQuery) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AzureQuery) Description() string {
+	return "Run KQL queries against Azure Monitor Logs or Azure Resource Graph"
+}
+
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type table struct {
+	Columns []column        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+type logAnalyticsRequest struct {
+	Query    string `json:"query"`
+	Timespan string `json:"timespan,omitempty"`
+}
+
+type logAnalyticsResponse struct {
+	Tables []table `json:"tables"`
+}
+
+type resourceGraphOptions struct {
+	ResultFormat string `json:"resultFormat"`
+	SkipToken    string `json:"$skipToken,omitempty"`
+}
+
+type resourceGraphRequest struct {
+	Subscriptions []string             `json:"subscriptions"`
+	Query         string               `json:"query"`
+	Options       resourceGraphOptions `json:"options"`
+}
+
+type resourceGraphResponse struct {
+	Data      table  `json:"data"`
+	SkipToken string `json:"$skipToken"`
+}
+
+type apiErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (a *AzureQuery) Gather(acc telegraf.Accumulator) error {
+	if a.client == nil {
+		a.client = &http.Client{
+			Timeout: a.Timeout.Duration,
+		}
+		a.tokenSources = make(map[string]*azure.TokenSource)
+	}
+
+	ctx := context.Background()
+	for _, q := range a.Queries {
+		now := time.Now()
+		var err error
+		switch q.Source {
+		case "", sourceLogAnalytics:
+			err = a.queryLogAnalytics(ctx, acc, q, now)
+		case sourceResourceGraph:
+			err = a.queryResourceGraph(ctx, acc, q, now)
+		default:
+			err = fmt.Errorf("unknown source %q", q.Source)
+		}
+		if err != nil {
+			acc.AddError(fmt.Errorf("query %q failed: %v", q.Name, err))
+		}
+	}
+	return nil
+}
+
+func (a *AzureQuery) queryLogAnalytics(ctx context.Context, acc telegraf.Accumulator, q *Query, now time.Time) error {
+	if q.WorkspaceID == "" {
+		return fmt.Errorf("workspace_id is required")
+	}
+
+	request := &logAnalyticsRequest{Query: q.Query}
+	if q.Timespan.Duration > 0 {
+		request.Timespan = isoDuration(q.Timespan.Duration)
+	}
+
+	loc := fmt.Sprintf("%s/v1/workspaces/%s/query", a.logAnalyticsURL,
+		url.PathEscape(q.WorkspaceID))
+	response := &logAnalyticsResponse{}
+	if err := a.post(ctx, a.logAnalyticsURL, loc, request, response); err != nil {
+		return err
+	}
+
+	// The first table is the primary result of the query.
+	if len(response.Tables) == 0 {
+		return nil
+	}
+	return addTable(acc, q, &response.Tables[0], now)
+}
+
+func (a *AzureQuery) queryResourceGraph(ctx context.Context, acc telegraf.Accumulator, q *Query, now time.Time) error {
+	if len(q.Subscriptions) == 0 {
+		return fmt.Errorf("subscriptions are required")
+	}
+
+	request := &resourceGraphRequest{
+		Subscriptions: q.Subscriptions,
+		Query:         q.Query,
+		Options: resourceGraphOptions{
+			ResultFormat: "table",
+		},
+	}
+
+	loc := fmt.Sprintf("%s/providers/Microsoft.ResourceGraph/resources?api-version=%s",
+		a.managementURL, resourceGraphVersion)
+	for {
+		response := &resourceGraphResponse{}
+		if err := a.post(ctx, a.managementURL, loc, request, response); err != nil {
+			return err
+		}
+		if err := addTable(acc, q, &response.Data, now); err != nil {
+			return err
+		}
+
+		if response.SkipToken == "" {
+			return nil
+		}
+		request.Options.SkipToken = response.SkipToken
+	}
+}
+
+func (a *AzureQuery) post(ctx context.Context, resource, loc string, request, response interface{}) error {
+	tokenSource, ok := a.tokenSources[resource]
+	if !ok {
+		credentialConfig := &azure.CredentialConfig{
+			TenantID:     a.TenantID,
+			ClientID:     a.ClientID,
+			ClientSecret: a.ClientSecret,
+			LoginURL:     a.loginURL,
+		}
+		tokenSource = credentialConfig.TokenSource(resource+"/", a.client)
+		a.tokenSources[resource] = tokenSource
+	}
+
+	token, err := tokenSource.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", loc, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		tokenSource.Invalidate()
+	}
+	if resp.StatusCode != http.StatusOK {
+		errResp := &apiErrorResponse{}
+		json.NewDecoder(resp.Body).Decode(errResp)
+		if errResp.Error.Message != "" {
+			return fmt.Errorf("%s: %s: %s", resp.Status, errResp.Error.Code,
+				errResp.Error.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	return decoder.Decode(response)
+}
+
+// addTable adds a metric for each row of the table, converting the values by
+// their column type.
+func addTable(acc telegraf.Accumulator, q *Query, t *table, now time.Time) error {
+	measurement := q.Name
+	if measurement == "" {
+		measurement = defaultMeasurement
+	}
+
+	isTag := make(map[string]bool, len(q.TagColumns))
+	for _, name := range q.TagColumns {
+		isTag[name] = true
+	}
+
+	for _, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return fmt.Errorf("row has %d values for %d columns", len(row), len(t.Columns))
+		}
+
+		tags := make(map[string]string)
+		fields := make(map[string]interface{})
+		timestamp := now
+		for i, col := range t.Columns {
+			value := row[i]
+			if value == nil {
+				continue
+			}
+
+			switch {
+			case col.Name == q.TimeColumn:
+				s, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("time column %q is not a datetime", col.Name)
+				}
+				ts, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return fmt.Errorf("parsing time column %q failed: %v", col.Name, err)
+				}
+				timestamp = ts
+			case isTag[col.Name]:
+				tags[col.Name] = toString(value)
+			default:
+				if v, ok := convert(col.Type, value); ok {
+					fields[col.Name] = v
+				}
+			}
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields(measurement, fields, tags, timestamp)
+	}
+	return nil
+}
+
+// convert converts a decoded JSON value to the field type of the column.
+func convert(columnType string, value interface{}) (interface{}, bool) {
+	switch strings.ToLower(columnType) {
+	case "int", "long", "integer":
+		if n, ok := value.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				return v, true
+			}
+			if v, err := n.Float64(); err == nil {
+				return v, true
+			}
+		}
+		return nil, false
+	case "real", "double", "decimal":
+		if n, ok := value.(json.Number); ok {
+			if v, err := n.Float64(); err == nil {
+				return v, true
+			}
+		}
+		return nil, false
+	case "bool", "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, true
+		case json.Number:
+			return v.String() != "0", true
+		}
+		return nil, false
+	default:
+		return toString(value), true
+	}
+}
+
+// toString formats values as strings, dynamic values such as objects and
+// arrays are encoded as JSON.
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		octets, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(octets)
+	}
+}
+
+// isoDuration formats a duration as an ISO 8601 duration such as "PT3600S".
+func isoDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%dS", int64(d/time.Second))
+}
+
+func init() {
+	inputs.Add("azure_query", func() telegraf.Input {
+		return &AzureQuery{
+			Timeout:         internal.Duration{Duration: 30 * time.Second},
+			logAnalyticsURL: defaultLogAnalyticsURL,
+			managementURL:   defaultManagementURL,
+		}
+	})
+}
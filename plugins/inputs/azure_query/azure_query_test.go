@@ -0,0 +1,180 @@
+package azure_query
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const logAnalyticsResult = `{
+  "tables": [
+    {
+      "name": "PrimaryResult",
+      "columns": [
+        {"name": "Computer", "type": "string"},
+        {"name": "TimeGenerated", "type": "datetime"},
+        {"name": "count_", "type": "long"},
+        {"name": "avg_cpu", "type": "real"},
+        {"name": "healthy", "type": "bool"},
+        {"name": "properties", "type": "dynamic"}
+      ],
+      "rows": [
+        ["web01", "2018-06-01T10:00:00Z", 42, 12.5, true, {"zone": "1"}],
+        ["web02", "2018-06-01T10:05:00Z", 7, null, false, null]
+      ]
+    }
+  ]
+}`
+
+const resourceGraphPage1 = `{
+  "data": {
+    "columns": [
+      {"name": "type", "type": "string"},
+      {"name": "count_", "type": "integer"}
+    ],
+    "rows": [
+      ["microsoft.compute/virtualmachines", 12]
+    ]
+  },
+  "$skipToken": "next"
+}`
+
+const resourceGraphPage2 = `{
+  "data": {
+    "columns": [
+      {"name": "type", "type": "string"},
+      {"name": "count_", "type": "integer"}
+    ],
+    "rows": [
+      ["microsoft.storage/storageaccounts", 3]
+    ]
+  }
+}`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tenant/oauth2/token":
+			w.Write([]byte(`{"access_token": "token", "expires_in": "3600"}`))
+		case "/v1/workspaces/workspace/query":
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			request := &logAnalyticsRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+			require.Equal(t, "Heartbeat | take 2", request.Query)
+			require.Equal(t, "PT3600S", request.Timespan)
+			w.Write([]byte(logAnalyticsResult))
+		case "/providers/Microsoft.ResourceGraph/resources":
+			require.Equal(t, resourceGraphVersion, r.URL.Query().Get("api-version"))
+			request := &resourceGraphRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+			require.Equal(t, []string{"sub"}, request.Subscriptions)
+			require.Equal(t, "table", request.Options.ResultFormat)
+			if request.Options.SkipToken == "" {
+				w.Write([]byte(resourceGraphPage1))
+			} else {
+				require.Equal(t, "next", request.Options.SkipToken)
+				w.Write([]byte(resourceGraphPage2))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": "PathNotFound", "message": "not found"}}`))
+		}
+	}))
+}
+
+func newAzureQuery(url string, queries ...*Query) *AzureQuery {
+	return &AzureQuery{
+		TenantID:        "tenant",
+		ClientID:        "client",
+		ClientSecret:    "secret",
+		Timeout:         internal.Duration{Duration: time.Second},
+		Queries:         queries,
+		loginURL:        url,
+		logAnalyticsURL: url,
+		managementURL:   url,
+	}
+}
+
+func TestGatherLogAnalytics(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	plugin := newAzureQuery(ts.URL, &Query{
+		Name:        "heartbeat",
+		WorkspaceID: "workspace",
+		Query:       "Heartbeat | take 2",
+		Timespan:    internal.Duration{Duration: time.Hour},
+		TagColumns:  []string{"Computer"},
+		TimeColumn:  "TimeGenerated",
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	acc.AssertContainsTaggedFields(t, "heartbeat",
+		map[string]interface{}{
+			"count_":     int64(42),
+			"avg_cpu":    12.5,
+			"healthy":    true,
+			"properties": `{"zone":"1"}`,
+		},
+		map[string]string{"Computer": "web01"})
+	acc.AssertContainsTaggedFields(t, "heartbeat",
+		map[string]interface{}{
+			"count_":  int64(7),
+			"healthy": false,
+		},
+		map[string]string{"Computer": "web02"})
+
+	m, ok := acc.Get("heartbeat")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC), m.Time)
+}
+
+func TestGatherResourceGraph(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	plugin := newAzureQuery(ts.URL, &Query{
+		Source:        "resource_graph",
+		Subscriptions: []string{"sub"},
+		Query:         "Resources | summarize count() by type",
+		TagColumns:    []string{"type"},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	acc.AssertContainsTaggedFields(t, "azure_query",
+		map[string]interface{}{"count_": int64(12)},
+		map[string]string{"type": "microsoft.compute/virtualmachines"})
+	acc.AssertContainsTaggedFields(t, "azure_query",
+		map[string]interface{}{"count_": int64(3)},
+		map[string]string{"type": "microsoft.storage/storageaccounts"})
+}
+
+func TestGatherQueryError(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	plugin := newAzureQuery(ts.URL,
+		&Query{Name: "missing", WorkspaceID: "missing", Query: "Heartbeat"},
+		&Query{Name: "invalid", Source: "unknown"},
+	)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 2)
+	require.Contains(t, acc.Errors[0].Error(), "not found")
+	require.Contains(t, acc.Errors[1].Error(), "unknown source")
+}
+
+func TestIsoDuration(t *testing.T) {
+	require.Equal(t, "PT90S", isoDuration(90*time.Second))
+}
@@ -0,0 +1,312 @@
+package bacnet
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultPort = "47808"
+
+type BACnet struct {
+	Listen           string            `toml:"listen"`
+	BroadcastAddress string            `toml:"broadcast_address"`
+	Discover         bool              `toml:"discover"`
+	Timeout          internal.Duration `toml:"timeout"`
+	Devices          []*Device         `toml:"device"`
+
+	// addresses are the addresses of the devices discovered with Who-Is.
+	addresses map[uint32]address
+	invokeID  byte
+}
+
+type Device struct {
+	Instance uint32    `toml:"instance"`
+	Address  string    `toml:"address"`
+	Name     string    `toml:"name"`
+	Objects  []*Object `toml:"object"`
+}
+
+type Object struct {
+	Type       string   `toml:"type"`
+	Instance   uint32   `toml:"instance"`
+	Name       string   `toml:"name"`
+	Properties []string `toml:"properties"`
+}
+
+var sampleConfig = `
+  ## Local address of the BACnet/IP client.  Devices usually send their I-Am
+  ## responses to the BACnet/IP port, in which case the client must listen
+  ## on port 47808 to discover them.
+  # listen = ":47808"
+
+  ## Address where the Who-Is requests are broadcast, to discover the
+  ## addresses of the devices.
+  # broadcast_address = "255.255.255.255:47808"
+
+  ## Discover all the devices of the network on each interval, reported in
+  ## the bacnet_device measurement.
+  # discover = false
+
+  ## Timeout for the responses of the devices, and duration of the
+  ## discovery.
+  # timeout = "3s"
+
+  ## Devices to read, by device instance.  The address of the device is
+  ## discovered with Who-Is when not set.
+  # [[inputs.bacnet.device]]
+  #   instance = 1234
+  #   # address = "192.168.1.20:47808"
+  #   name = "ahu-1"
+  #
+  #   ## Objects of the device, with their type such as "analog-input",
+  #   ## "analog-value", "binary-input" or "multi-state-value".  The
+  #   ## properties read default to "present-value".
+  #   [[inputs.bacnet.device.object]]
+  #     type = "analog-input"
+  #     instance = 1
+  #     name = "supply_air_temperature"
+  #     # properties = ["present-value", "status-flags"]
+`
+
+func (b *BACnet) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BACnet) Description() string {
+	return "Read properties of BACnet/IP devices"
+}
+
+func (b *BACnet) Gather(acc telegraf.Accumulator) error {
+	if b.addresses == nil {
+		b.addresses = make(map[uint32]address)
+	}
+
+	conn, err := net.ListenPacket("udp4", b.Listen)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if b.Discover || b.undiscovered() {
+		devices, err := b.whoIs(conn)
+		if err != nil {
+			acc.AddError(fmt.Errorf("discovering devices: %s", err))
+		}
+		for _, device := range devices {
+			b.addresses[device.instance] = device.address
+			if !b.Discover {
+				continue
+			}
+			tags := map[string]string{
+				"device_instance": strconv.FormatUint(uint64(device.instance), 10),
+				"address":         device.address.String(),
+			}
+			fields := map[string]interface{}{
+				"vendor_id":    int64(device.vendorID),
+				"max_apdu":     int64(device.maxAPDU),
+				"segmentation": int64(device.segmentation),
+			}
+			acc.AddFields("bacnet_device", fields, tags)
+		}
+	}
+
+	for _, device := range b.Devices {
+		if err := b.gatherDevice(acc, conn, device); err != nil {
+			acc.AddError(fmt.Errorf("[device=%d]: %s", device.Instance, err))
+		}
+	}
+	return nil
+}
+
+// undiscovered returns whether a device has neither a configured nor a
+// discovered address.
+func (b *BACnet) undiscovered() bool {
+	for _, device := range b.Devices {
+		if _, ok := b.addresses[device.Instance]; !ok && device.Address == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *BACnet) gatherDevice(acc telegraf.Accumulator, conn net.PacketConn, device *Device) error {
+	var dst address
+	if device.Address != "" {
+		addr, err := resolve(device.Address)
+		if err != nil {
+			return err
+		}
+		dst = address{ip: addr.String()}
+	} else {
+		var ok bool
+		if dst, ok = b.addresses[device.Instance]; !ok {
+			return fmt.Errorf("device not found")
+		}
+	}
+
+	for _, object := range device.Objects {
+		objectType, ok := objectTypes[object.Type]
+		if !ok {
+			acc.AddError(fmt.Errorf("[device=%d]: unknown object type %q", device.Instance, object.Type))
+			continue
+		}
+		props := object.Properties
+		if len(props) == 0 {
+			props = []string{"present-value"}
+		}
+
+		fields := make(map[string]interface{})
+		for _, name := range props {
+			property, ok := properties[name]
+			if !ok {
+				acc.AddError(fmt.Errorf("[device=%d]: unknown property %q", device.Instance, name))
+				continue
+			}
+
+			values, err := b.readProperty(conn, dst, objectType, object.Instance, property)
+			if err, ok := err.(net.Error); ok && err.Timeout() {
+				// The remaining reads would time out too, the device is
+				// discovered again on the next interval.
+				delete(b.addresses, device.Instance)
+				return fmt.Errorf("no response from %s", dst)
+			}
+			if err != nil {
+				acc.AddError(fmt.Errorf("[device=%d]: reading %s %d %s: %s",
+					device.Instance, object.Type, object.Instance, name, err))
+				continue
+			}
+			if len(values) == 0 {
+				continue
+			}
+			if v, ok := fieldValue(values[0]); ok {
+				fields[strings.Replace(name, "-", "_", -1)] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{
+			"device_instance": strconv.FormatUint(uint64(device.Instance), 10),
+			"address":         dst.String(),
+			"object_type":     object.Type,
+			"object_instance": strconv.FormatUint(uint64(object.Instance), 10),
+		}
+		if device.Name != "" {
+			tags["device_name"] = device.Name
+		}
+		if object.Name != "" {
+			tags["object_name"] = object.Name
+		}
+		acc.AddFields("bacnet", fields, tags)
+	}
+	return nil
+}
+
+// whoIs broadcasts a Who-Is request and returns the devices responding
+// before the timeout, by device instance.
+func (b *BACnet) whoIs(conn net.PacketConn) (map[uint32]*iAm, error) {
+	addr, err := resolve(b.BroadcastAddress)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(encodeWhoIs(0, 0), addr); err != nil {
+		return nil, err
+	}
+
+	devices := make(map[uint32]*iAm)
+	conn.SetReadDeadline(time.Now().Add(b.Timeout.Duration))
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			return devices, nil
+		}
+		if err != nil {
+			return devices, err
+		}
+		src, apdu, err := decodePacket(from.String(), buf[:n])
+		if err != nil {
+			continue
+		}
+		if device, err := decodeIAm(apdu); err == nil {
+			device.address = src
+			devices[device.instance] = device
+		}
+	}
+}
+
+// readProperty reads a property of an object, responses to other requests
+// and unrelated requests received meanwhile are ignored.
+func (b *BACnet) readProperty(
+	conn net.PacketConn,
+	dst address,
+	objectType, instance, property uint32,
+) ([]interface{}, error) {
+	addr, err := resolve(dst.ip)
+	if err != nil {
+		return nil, err
+	}
+	b.invokeID++
+	invokeID := b.invokeID
+	if _, err := conn.WriteTo(encodeReadProperty(dst, invokeID, objectType, instance, property), addr); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(b.Timeout.Duration))
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, err
+		}
+		src, apdu, err := decodePacket(from.String(), buf[:n])
+		if err != nil || src != dst {
+			continue
+		}
+		if len(apdu) < 2 || apdu[0]&0xF0 == pduConfirmedRequest ||
+			apdu[0]&0xF0 == pduUnconfirmedRequest || apdu[1] != invokeID {
+			continue
+		}
+		return decodeReadPropertyAck(apdu)
+	}
+}
+
+// fieldValue converts a decoded value to a field value.
+func fieldValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case float64, int64, bool, string:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case enumerated:
+		return int64(v), true
+	case bitString:
+		return int64(v), true
+	}
+	return nil, false
+}
+
+func resolve(addr string) (*net.UDPAddr, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultPort)
+	}
+	return net.ResolveUDPAddr("udp4", addr)
+}
+
+func init() {
+	inputs.Add("bacnet", func() telegraf.Input {
+		return &BACnet{
+			Listen:           ":" + defaultPort,
+			BroadcastAddress: "255.255.255.255:" + defaultPort,
+			Timeout:          internal.Duration{Duration: 3 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,300 @@
+package bacnet
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDevice is a BACnet/IP device answering Who-Is and ReadProperty
+// requests with the responses of the values.
+type fakeDevice struct {
+	conn     net.PacketConn
+	instance uint32
+	values   map[[3]uint32][]byte
+}
+
+func newFakeDevice(t *testing.T) *fakeDevice {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	d := &fakeDevice{
+		conn:     conn,
+		instance: 1234,
+		values: map[[3]uint32][]byte{
+			// analog-input 1: present-value 21.5, status-flags fault
+			{0, 1, 85}:  {0x44, 0x41, 0xAC, 0x00, 0x00},
+			{0, 1, 111}: {0x82, 0x04, 0x40},
+			{0, 1, 77}:  {0x74, 0x00, 'S', 'A', 'T'},
+			// binary-input 2: present-value active
+			{3, 2, 85}: {0x91, 0x01},
+			// multi-state-value 4: present-value 3, out-of-service false
+			{19, 4, 85}: {0x21, 0x03},
+			{19, 4, 81}: {0x10},
+		},
+	}
+	go d.serve()
+	return d
+}
+
+func (d *fakeDevice) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		_, apdu, err := decodePacket(from.String(), buf[:n])
+		if err != nil || len(apdu) < 2 {
+			continue
+		}
+
+		var resp []byte
+		switch {
+		case apdu[0] == pduUnconfirmedRequest && apdu[1] == serviceWhoIs:
+			resp = []byte{pduUnconfirmedRequest, serviceIAm, 0xC4, 0, 0, 0, 0,
+				0x22, 0x05, 0xC4, 0x91, 0x03, 0x22, 0x01, 0x04}
+			binary.BigEndian.PutUint32(resp[3:], objectTypeDevice<<22|d.instance)
+		case apdu[0] == pduConfirmedRequest && apdu[3] == serviceReadProperty:
+			invokeID := apdu[2]
+			id := binary.BigEndian.Uint32(apdu[5:])
+			property := uint32(apdu[10])
+			if apdu[9] == 0x1A {
+				property = uint32(binary.BigEndian.Uint16(apdu[10:]))
+			}
+
+			value, ok := d.values[[3]uint32{id >> 22, id & 0x3FFFFF, property}]
+			if !ok {
+				// error class object, code unknown-object
+				resp = []byte{pduError, invokeID, serviceReadProperty, 0x91, 0x01, 0x91, 0x1F}
+				break
+			}
+			resp = []byte{pduComplexAck, invokeID, serviceReadProperty}
+			resp = append(resp, apdu[4:]...)
+			resp = append(resp, 0x3E)
+			resp = append(resp, value...)
+			resp = append(resp, 0x3F)
+
+			// An unrelated broadcast received before the response.
+			d.conn.WriteTo(encodeWhoIs(1, 2), from)
+		default:
+			continue
+		}
+		d.conn.WriteTo(encodeBVLC(bvlcOriginalUnicast, append([]byte{npduVersion, 0x00}, resp...)), from)
+	}
+}
+
+func newBACnet(d *fakeDevice) *BACnet {
+	return &BACnet{
+		Listen:           "127.0.0.1:0",
+		BroadcastAddress: d.conn.LocalAddr().String(),
+		Timeout:          internal.Duration{Duration: 200 * time.Millisecond},
+	}
+}
+
+func TestGather(t *testing.T) {
+	d := newFakeDevice(t)
+	defer d.conn.Close()
+	addr := d.conn.LocalAddr().String()
+
+	b := newBACnet(d)
+	b.Discover = true
+	b.Devices = []*Device{
+		{
+			Instance: 1234,
+			Name:     "ahu-1",
+			Objects: []*Object{
+				{
+					Type:       "analog-input",
+					Instance:   1,
+					Name:       "supply_air_temperature",
+					Properties: []string{"present-value", "status-flags", "object-name"},
+				},
+				{Type: "binary-input", Instance: 2},
+				{
+					Type:       "multi-state-value",
+					Instance:   4,
+					Properties: []string{"present-value", "out-of-service"},
+				},
+				{Type: "analog-value", Instance: 3},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "bacnet_device",
+		map[string]interface{}{
+			"vendor_id":    int64(260),
+			"max_apdu":     int64(1476),
+			"segmentation": int64(3),
+		},
+		map[string]string{
+			"device_instance": "1234",
+			"address":         addr,
+		})
+	acc.AssertContainsTaggedFields(t, "bacnet",
+		map[string]interface{}{
+			"present_value": 21.5,
+			"status_flags":  int64(2),
+			"object_name":   "SAT",
+		},
+		map[string]string{
+			"device_instance": "1234",
+			"device_name":     "ahu-1",
+			"address":         addr,
+			"object_type":     "analog-input",
+			"object_instance": "1",
+			"object_name":     "supply_air_temperature",
+		})
+	acc.AssertContainsTaggedFields(t, "bacnet",
+		map[string]interface{}{
+			"present_value": int64(1),
+		},
+		map[string]string{
+			"device_instance": "1234",
+			"device_name":     "ahu-1",
+			"address":         addr,
+			"object_type":     "binary-input",
+			"object_instance": "2",
+		})
+	acc.AssertContainsTaggedFields(t, "bacnet",
+		map[string]interface{}{
+			"present_value":  int64(3),
+			"out_of_service": false,
+		},
+		map[string]string{
+			"device_instance": "1234",
+			"device_name":     "ahu-1",
+			"address":         addr,
+			"object_type":     "multi-state-value",
+			"object_instance": "4",
+		})
+	require.Len(t, acc.Metrics, 4)
+
+	require.Len(t, acc.Errors, 1)
+	require.EqualError(t, acc.Errors[0],
+		"[device=1234]: reading analog-value 3 present-value: error class 1 code 31")
+}
+
+func TestGatherConfiguredAddress(t *testing.T) {
+	d := newFakeDevice(t)
+	defer d.conn.Close()
+
+	b := newBACnet(d)
+	b.BroadcastAddress = "127.0.0.1:1"
+	b.Devices = []*Device{
+		{
+			Instance: 1234,
+			Address:  d.conn.LocalAddr().String(),
+			Objects:  []*Object{{Type: "binary-input", Instance: 2}},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(b.Gather))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, int64(1), acc.Metrics[0].Fields["present_value"])
+}
+
+func TestGatherDeviceNotFound(t *testing.T) {
+	d := newFakeDevice(t)
+	defer d.conn.Close()
+
+	b := newBACnet(d)
+	b.Devices = []*Device{
+		{
+			Instance: 99,
+			Objects:  []*Object{{Type: "analog-input", Instance: 1}},
+		},
+		{
+			Instance: 1234,
+			Objects: []*Object{
+				{Type: "analog-input", Instance: 1, Properties: []string{"priority-array"}},
+				{Type: "schedule", Instance: 1},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+	require.Len(t, acc.Errors, 3)
+	require.EqualError(t, acc.Errors[0], "[device=99]: device not found")
+	require.EqualError(t, acc.Errors[1], `[device=1234]: unknown property "priority-array"`)
+	require.EqualError(t, acc.Errors[2], `[device=1234]: unknown object type "schedule"`)
+}
+
+func TestGatherNoResponse(t *testing.T) {
+	d := newFakeDevice(t)
+	addr := d.conn.LocalAddr().String()
+	d.conn.Close()
+
+	b := newBACnet(d)
+	b.Devices = []*Device{
+		{
+			Instance: 1234,
+			Address:  addr,
+			Objects: []*Object{
+				{Type: "analog-input", Instance: 1},
+				{Type: "analog-input", Instance: 2},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+	require.Len(t, acc.Errors, 1)
+	require.EqualError(t, acc.Errors[0], "[device=1234]: no response from "+addr)
+}
+
+func TestDecodeValues(t *testing.T) {
+	values, err := decodeValues([]byte{
+		0x00,             // null
+		0x32, 0xFF, 0x38, // signed -200
+		0x55, 0x08, 0x40, 0x09, 0x21, 0xFB, 0x54, 0x44, 0x2D, 0x18, // double
+		0x75, 0x04, 0x00, 'a', 'b', 'c', // character string
+		0xC4, 0x00, 0x00, 0x00, 0x05, // analog-input 5
+	})
+	require.NoError(t, err)
+	require.Len(t, values, 5)
+	require.Nil(t, values[0])
+	require.Equal(t, int64(-200), values[1])
+	require.InDelta(t, 3.14159265, values[2], 1e-8)
+	require.Equal(t, "abc", values[3])
+	require.Equal(t, objectID{typ: 0, instance: 5}, values[4])
+
+	_, err = decodeValues([]byte{0x44, 0x41})
+	require.EqualError(t, err, "truncated value")
+}
+
+func TestDecodePacketRouted(t *testing.T) {
+	// I-Am of device 5 on network 2, address 0x0A, forwarded by a BBMD.
+	packet := []byte{bvlcTypeIP, bvlcForwardedNPDU, 0, 0,
+		192, 168, 1, 20, 0xBA, 0xC0,
+		npduVersion, npduSourcePresent, 0x00, 0x02, 0x01, 0x0A,
+		pduUnconfirmedRequest, serviceIAm, 0xC4, 0x02, 0x00, 0x00, 0x05,
+		0x22, 0x01, 0xE0, 0x91, 0x00, 0x21, 0x07}
+	binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)))
+
+	src, apdu, err := decodePacket("192.168.1.1:47808", packet)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.1.20:47808/2:0a", src.String())
+
+	device, err := decodeIAm(apdu)
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), device.instance)
+	require.Equal(t, uint64(480), device.maxAPDU)
+	require.Equal(t, uint64(7), device.vendorID)
+
+	// Requests to routed devices carry the destination network.
+	request := encodeReadProperty(src, 1, 0, 1, 85)
+	require.Equal(t, []byte{npduVersion, npduExpectingReply | npduDestinationPresent,
+		0x00, 0x02, 0x01, 0x0A, 0xFF}, request[4:11])
+}
@@ -0,0 +1,411 @@
+package bacnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// This file implements the subset of BACnet/IP (ASHRAE 135, annex J) used
+// by the plugin: the Who-Is and I-Am services to discover devices, and the
+// ReadProperty service to read the properties of objects.
+
+const (
+	bvlcTypeIP              = 0x81
+	bvlcOriginalUnicast     = 0x0A
+	bvlcOriginalBroadcast   = 0x0B
+	bvlcForwardedNPDU       = 0x04
+	npduVersion             = 0x01
+	npduExpectingReply      = 0x04
+	npduSourcePresent       = 0x08
+	npduDestinationPresent  = 0x20
+	npduNetworkLayerMessage = 0x80
+
+	pduConfirmedRequest   = 0x00
+	pduUnconfirmedRequest = 0x10
+	pduSimpleAck          = 0x20
+	pduComplexAck         = 0x30
+	pduError              = 0x50
+	pduReject             = 0x60
+	pduAbort              = 0x70
+
+	serviceIAm          = 0x00
+	serviceWhoIs        = 0x08
+	serviceReadProperty = 0x0C
+
+	// maxAPDU is the maximum APDU accepted by the client, 1476 octets
+	// without segmentation.
+	maxAPDU = 0x05
+
+	objectTypeDevice = 8
+)
+
+// Application tags of the primitive values.
+const (
+	tagNull = iota
+	tagBoolean
+	tagUnsigned
+	tagSigned
+	tagReal
+	tagDouble
+	tagOctetString
+	tagCharacterString
+	tagBitString
+	tagEnumerated
+	tagDate
+	tagTime
+	tagObjectIdentifier
+)
+
+var objectTypes = map[string]uint32{
+	"analog-input":       0,
+	"analog-output":      1,
+	"analog-value":       2,
+	"binary-input":       3,
+	"binary-output":      4,
+	"binary-value":       5,
+	"device":             8,
+	"multi-state-input":  13,
+	"multi-state-output": 14,
+	"multi-state-value":  19,
+	"accumulator":        23,
+	"pulse-converter":    24,
+}
+
+var properties = map[string]uint32{
+	"description":    28,
+	"event-state":    36,
+	"object-name":    77,
+	"out-of-service": 81,
+	"present-value":  85,
+	"reliability":    103,
+	"status-flags":   111,
+	"units":          117,
+}
+
+// address is the address of a device, with the network and address of the
+// device behind a BACnet router.
+type address struct {
+	ip     string
+	net    uint16
+	adr    string
+	routed bool
+}
+
+func (a address) String() string {
+	if !a.routed {
+		return a.ip
+	}
+	return fmt.Sprintf("%s/%d:%x", a.ip, a.net, a.adr)
+}
+
+// iAm is the announcement of a device, in response to Who-Is.
+type iAm struct {
+	address      address
+	instance     uint32
+	maxAPDU      uint64
+	segmentation uint64
+	vendorID     uint64
+}
+
+// encodeWhoIs encodes a broadcast Who-Is request, limited to the device
+// instances between low and high when high is not zero.
+func encodeWhoIs(low, high uint32) []byte {
+	apdu := []byte{pduUnconfirmedRequest, serviceWhoIs}
+	if high != 0 {
+		apdu = append(apdu, encodeContextUnsigned(0, low)...)
+		apdu = append(apdu, encodeContextUnsigned(1, high)...)
+	}
+	npdu := []byte{npduVersion, 0x00}
+	return encodeBVLC(bvlcOriginalBroadcast, append(npdu, apdu...))
+}
+
+// encodeReadProperty encodes a confirmed ReadProperty request.
+func encodeReadProperty(dst address, invokeID byte, objectType, instance, property uint32) []byte {
+	npdu := []byte{npduVersion, npduExpectingReply}
+	if dst.routed {
+		npdu[1] |= npduDestinationPresent
+		npdu = append(npdu, byte(dst.net>>8), byte(dst.net), byte(len(dst.adr)))
+		npdu = append(npdu, []byte(dst.adr)...)
+		npdu = append(npdu, 0xFF) // hop count
+	}
+
+	apdu := []byte{pduConfirmedRequest, maxAPDU, invokeID, serviceReadProperty}
+	apdu = append(apdu, encodeContextObjectID(0, objectType, instance)...)
+	apdu = append(apdu, encodeContextUnsigned(1, property)...)
+	return encodeBVLC(bvlcOriginalUnicast, append(npdu, apdu...))
+}
+
+func encodeBVLC(function byte, npdu []byte) []byte {
+	b := make([]byte, 4, 4+len(npdu))
+	b[0] = bvlcTypeIP
+	b[1] = function
+	binary.BigEndian.PutUint16(b[2:], uint16(4+len(npdu)))
+	return append(b, npdu...)
+}
+
+func encodeContextUnsigned(tag byte, v uint32) []byte {
+	var b []byte
+	switch {
+	case v < 1<<8:
+		b = []byte{byte(v)}
+	case v < 1<<16:
+		b = []byte{byte(v >> 8), byte(v)}
+	case v < 1<<24:
+		b = []byte{byte(v >> 16), byte(v >> 8), byte(v)}
+	default:
+		b = []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	}
+	return append([]byte{tag<<4 | 0x08 | byte(len(b))}, b...)
+}
+
+func encodeContextObjectID(tag byte, objectType, instance uint32) []byte {
+	b := []byte{tag<<4 | 0x08 | 4, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], objectType<<22|instance&0x3FFFFF)
+	return b
+}
+
+// decodePacket decodes the BVLC and NPDU of a packet received from ip, and
+// returns the address of the source and the APDU.
+func decodePacket(ip string, b []byte) (address, []byte, error) {
+	src := address{ip: ip}
+	if len(b) < 4 || b[0] != bvlcTypeIP {
+		return src, nil, fmt.Errorf("not a BACnet/IP packet")
+	}
+	if int(binary.BigEndian.Uint16(b[2:])) != len(b) {
+		return src, nil, fmt.Errorf("invalid BVLC length")
+	}
+	switch b[1] {
+	case bvlcOriginalUnicast, bvlcOriginalBroadcast:
+		b = b[4:]
+	case bvlcForwardedNPDU:
+		// The address of the originating device precedes the NPDU.
+		if len(b) < 10 {
+			return src, nil, fmt.Errorf("truncated BVLC")
+		}
+		src.ip = fmt.Sprintf("%d.%d.%d.%d:%d", b[4], b[5], b[6], b[7], binary.BigEndian.Uint16(b[8:]))
+		b = b[10:]
+	default:
+		return src, nil, fmt.Errorf("unsupported BVLC function 0x%02x", b[1])
+	}
+
+	if len(b) < 2 || b[0] != npduVersion {
+		return src, nil, fmt.Errorf("invalid NPDU")
+	}
+	control := b[1]
+	b = b[2:]
+	if control&npduNetworkLayerMessage != 0 {
+		return src, nil, fmt.Errorf("network layer message")
+	}
+	if control&npduDestinationPresent != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return src, nil, fmt.Errorf("truncated NPDU")
+		}
+		b = b[3+int(b[2]):]
+	}
+	if control&npduSourcePresent != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return src, nil, fmt.Errorf("truncated NPDU")
+		}
+		src.routed = true
+		src.net = binary.BigEndian.Uint16(b)
+		src.adr = string(b[3 : 3+int(b[2])])
+		b = b[3+int(b[2]):]
+	}
+	if control&npduDestinationPresent != 0 {
+		if len(b) < 1 {
+			return src, nil, fmt.Errorf("truncated NPDU")
+		}
+		b = b[1:] // hop count
+	}
+	return src, b, nil
+}
+
+// decodeIAm decodes the APDU of an I-Am request.
+func decodeIAm(apdu []byte) (*iAm, error) {
+	if len(apdu) < 2 || apdu[0] != pduUnconfirmedRequest || apdu[1] != serviceIAm {
+		return nil, fmt.Errorf("not an I-Am")
+	}
+	values, err := decodeValues(apdu[2:])
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("invalid I-Am")
+	}
+	id, ok1 := values[0].(objectID)
+	max, ok2 := values[1].(uint64)
+	seg, ok3 := values[2].(enumerated)
+	vendor, ok4 := values[3].(uint64)
+	if !ok1 || !ok2 || !ok3 || !ok4 || id.typ != objectTypeDevice {
+		return nil, fmt.Errorf("invalid I-Am")
+	}
+	return &iAm{
+		instance:     id.instance,
+		maxAPDU:      max,
+		segmentation: uint64(seg),
+		vendorID:     vendor,
+	}, nil
+}
+
+// decodeReadPropertyAck decodes the response to a ReadProperty request and
+// returns the values of the property.
+func decodeReadPropertyAck(apdu []byte) ([]interface{}, error) {
+	if len(apdu) < 3 {
+		return nil, fmt.Errorf("truncated APDU")
+	}
+	switch apdu[0] & 0xF0 {
+	case pduComplexAck:
+		if apdu[0]&0x08 != 0 {
+			return nil, fmt.Errorf("segmented responses are not supported")
+		}
+		if apdu[2] != serviceReadProperty {
+			return nil, fmt.Errorf("unexpected service 0x%02x", apdu[2])
+		}
+	case pduError:
+		values, err := decodeValues(apdu[3:])
+		if err == nil && len(values) == 2 {
+			return nil, fmt.Errorf("error class %v code %v", values[0], values[1])
+		}
+		return nil, fmt.Errorf("error response")
+	case pduReject:
+		return nil, fmt.Errorf("rejected with reason %d", apdu[2])
+	case pduAbort:
+		return nil, fmt.Errorf("aborted with reason %d", apdu[2])
+	default:
+		return nil, fmt.Errorf("unexpected PDU type 0x%02x", apdu[0])
+	}
+
+	// The object identifier and property identifier, and the optional
+	// array index, precede the value between opening and closing tag 3.
+	b := apdu[3:]
+	for len(b) > 0 && b[0] != 0x3E {
+		length := int(b[0] & 0x07)
+		if b[0]&0x08 == 0 || length > 4 || len(b) < 1+length {
+			return nil, fmt.Errorf("invalid ReadProperty response")
+		}
+		b = b[1+length:]
+	}
+	if len(b) < 2 || b[len(b)-1] != 0x3F {
+		return nil, fmt.Errorf("invalid ReadProperty response")
+	}
+	return decodeValues(b[1 : len(b)-1])
+}
+
+// objectID is a decoded object identifier.
+type objectID struct {
+	typ      uint32
+	instance uint32
+}
+
+// enumerated is a decoded enumerated value, distinct from unsigned values
+// to validate I-Am requests.
+type enumerated uint64
+
+// bitString is a decoded bit string, with the first bit of the string as
+// the least significant bit.
+type bitString uint64
+
+// decodeValues decodes a sequence of application tagged values.
+func decodeValues(b []byte) ([]interface{}, error) {
+	var values []interface{}
+	for len(b) > 0 {
+		tag := b[0] >> 4
+		context := b[0]&0x08 != 0
+		lvt := uint32(b[0] & 0x07)
+		b = b[1:]
+		if context {
+			return nil, fmt.Errorf("unexpected context tag %d", tag)
+		}
+		if tag == tagBoolean {
+			values = append(values, lvt != 0)
+			continue
+		}
+
+		length := lvt
+		if lvt == 5 {
+			if len(b) < 1 {
+				return nil, fmt.Errorf("truncated value")
+			}
+			length, b = uint32(b[0]), b[1:]
+			switch length {
+			case 254:
+				if len(b) < 2 {
+					return nil, fmt.Errorf("truncated value")
+				}
+				length, b = uint32(binary.BigEndian.Uint16(b)), b[2:]
+			case 255:
+				if len(b) < 4 {
+					return nil, fmt.Errorf("truncated value")
+				}
+				length, b = binary.BigEndian.Uint32(b), b[4:]
+			}
+		}
+		if uint32(len(b)) < length {
+			return nil, fmt.Errorf("truncated value")
+		}
+		data := b[:length]
+		b = b[length:]
+
+		switch tag {
+		case tagNull:
+			values = append(values, nil)
+		case tagUnsigned:
+			values = append(values, decodeUnsigned(data))
+		case tagSigned:
+			v := int64(decodeUnsigned(data))
+			if length > 0 && length < 8 && data[0]&0x80 != 0 {
+				v -= 1 << (8 * length)
+			}
+			values = append(values, v)
+		case tagReal:
+			if length != 4 {
+				return nil, fmt.Errorf("invalid real")
+			}
+			values = append(values, float64(math.Float32frombits(binary.BigEndian.Uint32(data))))
+		case tagDouble:
+			if length != 8 {
+				return nil, fmt.Errorf("invalid double")
+			}
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(data)))
+		case tagCharacterString:
+			// Only the UTF-8 character set is decoded.
+			if length < 1 || data[0] != 0 {
+				values = append(values, nil)
+				continue
+			}
+			values = append(values, string(data[1:]))
+		case tagBitString:
+			var v bitString
+			if length > 0 {
+				bits := (length-1)*8 - uint32(data[0])
+				for i := uint32(0); i < bits && i < 64; i++ {
+					if data[1+i/8]&(0x80>>(i%8)) != 0 {
+						v |= 1 << i
+					}
+				}
+			}
+			values = append(values, v)
+		case tagEnumerated:
+			values = append(values, enumerated(decodeUnsigned(data)))
+		case tagObjectIdentifier:
+			if length != 4 {
+				return nil, fmt.Errorf("invalid object identifier")
+			}
+			v := binary.BigEndian.Uint32(data)
+			values = append(values, objectID{typ: v >> 22, instance: v & 0x3FFFFF})
+		default:
+			// Octet strings, dates and times are not decoded.
+			values = append(values, nil)
+		}
+	}
+	return values, nil
+}
+
+func decodeUnsigned(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
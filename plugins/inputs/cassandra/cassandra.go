@@ -200,6 +200,10 @@ func (j *Cassandra) Description() string {
 	return "Read Cassandra metrics through Jolokia"
 }
 
+func (j *Cassandra) Deprecated() (string, string) {
+	return "1.7", "use the jolokia2 input with the cassandra.conf example configuration"
+}
+
 func (j *Cassandra) getAttr(requestUrl *url.URL) (map[string]interface{}, error) {
 	// Create + send request
 	req, err := http.NewRequest("GET", requestUrl.String(), nil)
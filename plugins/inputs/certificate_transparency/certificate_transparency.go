@@ -0,0 +1,237 @@
+package certificate_transparency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement = "certificate_transparency"
+
+	defaultURL = "https://api.certspotter.com"
+)
+
+type CertificateTransparency struct {
+	Domains           []string          `toml:"domains"`
+	IncludeSubdomains bool              `toml:"include_subdomains"`
+	URL               string            `toml:"url"`
+	APIToken          string            `toml:"api_token"`
+	MaxPages          int               `toml:"max_pages"`
+	Timeout           internal.Duration `toml:"timeout"`
+
+	client *http.Client
+
+	mu sync.Mutex
+	// Id of the last issuance seen for each domain.
+	cursors map[string]string
+	// Domains for which all existing issuances have been seen, only
+	// issuances after that are reported as new.
+	initialized map[string]bool
+}
+
+var sampleConfig = `
+  ## Domains to watch for newly issued certificates.
+  domains = ["example.com"]
+
+  ## Include certificates for subdomains of the domains.
+  # include_subdomains = true
+
+  ## URL and API token of the Cert Spotter API.  Unauthenticated requests
+  ## are subject to a low rate limit.
+  # url = "https://api.certspotter.com"
+  # api_token = ""
+
+  ## Maximum number of pages requested for each domain per gather.
+  # max_pages = 10
+
+  ## Timeout for HTTP requests.
+  # timeout = "10s"
+
+  ## Certificate transparency logs are updated slowly, an interval of an
+  ## hour is usually sufficient.
+  interval = "1h"
+`
+
+func (c *CertificateTransparency) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CertificateTransparency) Description() string {
+	return "Watch certificate transparency logs for newly issued certificates"
+}
+
+type issuance struct {
+	ID       string   `json:"id"`
+	DNSNames []string `json:"dns_names"`
+}
+
+type apiErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (c *CertificateTransparency) Gather(acc telegraf.Accumulator) error {
+	if c.client == nil {
+		c.client = &http.Client{
+			Timeout: c.Timeout.Duration,
+		}
+	}
+
+	for _, domain := range c.Domains {
+		if err := c.gatherDomain(acc, domain); err != nil {
+			acc.AddError(fmt.Errorf("[%s]: %s", domain, err))
+		}
+	}
+	return nil
+}
+
+// gatherDomain counts the issuances after the last one seen.  On the first
+// gather of a domain the existing issuances are skipped, possibly across
+// several gathers, and no metric is added until they have all been seen.
+func (c *CertificateTransparency) gatherDomain(acc telegraf.Accumulator, domain string) error {
+	c.mu.Lock()
+	cursor := c.cursors[domain]
+	initialized := c.initialized[domain]
+	c.mu.Unlock()
+
+	var count int
+	var wildcards int
+	complete := false
+	for page := 0; page < c.MaxPages; page++ {
+		issuances, err := c.issuances(domain, cursor)
+		if err != nil {
+			return err
+		}
+		if len(issuances) == 0 {
+			complete = true
+			break
+		}
+
+		for _, iss := range issuances {
+			count++
+			for _, name := range iss.DNSNames {
+				if strings.HasPrefix(name, "*.") {
+					wildcards++
+					break
+				}
+			}
+		}
+		cursor = issuances[len(issuances)-1].ID
+
+		c.mu.Lock()
+		c.cursors[domain] = cursor
+		c.mu.Unlock()
+	}
+
+	if !initialized {
+		if complete {
+			c.mu.Lock()
+			c.initialized[domain] = true
+			c.mu.Unlock()
+		}
+		return nil
+	}
+
+	tags := map[string]string{
+		"domain": domain,
+	}
+	fields := map[string]interface{}{
+		"new_certificates":          count,
+		"new_wildcard_certificates": wildcards,
+	}
+	acc.AddFields(measurement, fields, tags)
+	return nil
+}
+
+func (c *CertificateTransparency) issuances(domain, after string) ([]issuance, error) {
+	params := url.Values{}
+	params.Set("domain", domain)
+	params.Set("include_subdomains", fmt.Sprintf("%t", c.IncludeSubdomains))
+	params.Add("expand", "dns_names")
+	if after != "" {
+		params.Set("after", after)
+	}
+
+	loc := strings.TrimSuffix(c.URL, "/") + "/v1/issuances?" + params.Encode()
+	req, err := http.NewRequest("GET", loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &apiErrorResponse{}
+		json.NewDecoder(resp.Body).Decode(errResp)
+		if errResp.Message != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, errResp.Message)
+		}
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	var issuances []issuance
+	err = json.NewDecoder(resp.Body).Decode(&issuances)
+	return issuances, err
+}
+
+// GetState returns the id of the last issuance seen for each domain.
+func (c *CertificateTransparency) GetState() interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := make(map[string]string)
+	for domain, cursor := range c.cursors {
+		if c.initialized[domain] {
+			state[domain] = cursor
+		}
+	}
+	return state
+}
+
+func (c *CertificateTransparency) SetState(state interface{}) error {
+	domains, ok := state.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid state type %T", state)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for domain, v := range domains {
+		cursor, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("invalid cursor for %s: %v", domain, v)
+		}
+		c.cursors[domain] = cursor
+		c.initialized[domain] = true
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("certificate_transparency", func() telegraf.Input {
+		return &CertificateTransparency{
+			IncludeSubdomains: true,
+			URL:               defaultURL,
+			MaxPages:          10,
+			Timeout:           internal.Duration{Duration: 10 * time.Second},
+			cursors:           make(map[string]string),
+			initialized:       make(map[string]bool),
+		}
+	})
+}
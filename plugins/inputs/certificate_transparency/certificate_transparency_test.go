@@ -0,0 +1,159 @@
+package certificate_transparency
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer serves the issuances two per page.
+func newTestServer(t *testing.T, issuances *[]issuance, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/issuances", r.URL.Path)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "example.com", r.URL.Query().Get("domain"))
+		require.Equal(t, "true", r.URL.Query().Get("include_subdomains"))
+		*requests++
+
+		var start int
+		if after := r.URL.Query().Get("after"); after != "" {
+			id, err := strconv.Atoi(after)
+			require.NoError(t, err)
+			start = id
+		}
+
+		page := []issuance{}
+		for _, iss := range *issuances {
+			id, _ := strconv.Atoi(iss.ID)
+			if id > start && len(page) < 2 {
+				page = append(page, iss)
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func newCertificateTransparency(url string) *CertificateTransparency {
+	return &CertificateTransparency{
+		Domains:           []string{"example.com"},
+		IncludeSubdomains: true,
+		URL:               url,
+		APIToken:          "token",
+		MaxPages:          10,
+		Timeout:           internal.Duration{Duration: time.Second},
+		cursors:           make(map[string]string),
+		initialized:       make(map[string]bool),
+	}
+}
+
+func TestGather(t *testing.T) {
+	issuances := []issuance{
+		{ID: "1", DNSNames: []string{"example.com"}},
+		{ID: "2", DNSNames: []string{"www.example.com"}},
+		{ID: "3", DNSNames: []string{"example.com", "*.example.com"}},
+	}
+	var requests int
+	ts := newTestServer(t, &issuances, &requests)
+	defer ts.Close()
+
+	c := newCertificateTransparency(ts.URL)
+
+	// The existing issuances are skipped on the first gather.
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(c.Gather))
+	require.Empty(t, acc.Metrics)
+	require.Equal(t, 3, requests)
+
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(c.Gather))
+	acc.AssertContainsTaggedFields(t, "certificate_transparency",
+		map[string]interface{}{
+			"new_certificates":          0,
+			"new_wildcard_certificates": 0,
+		},
+		map[string]string{
+			"domain": "example.com",
+		})
+
+	issuances = append(issuances,
+		issuance{ID: "4", DNSNames: []string{"*.example.com"}},
+		issuance{ID: "5", DNSNames: []string{"mail.example.com"}},
+		issuance{ID: "6", DNSNames: []string{"api.example.com"}})
+
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(c.Gather))
+	acc.AssertContainsTaggedFields(t, "certificate_transparency",
+		map[string]interface{}{
+			"new_certificates":          3,
+			"new_wildcard_certificates": 1,
+		},
+		map[string]string{
+			"domain": "example.com",
+		})
+}
+
+func TestGatherMaxPages(t *testing.T) {
+	issuances := []issuance{
+		{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "5"},
+	}
+	var requests int
+	ts := newTestServer(t, &issuances, &requests)
+	defer ts.Close()
+
+	c := newCertificateTransparency(ts.URL)
+	c.MaxPages = 2
+
+	// The domain is initialized once all existing issuances have been seen.
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(c.Gather))
+	require.False(t, c.initialized["example.com"])
+	require.Equal(t, "4", c.cursors["example.com"])
+
+	require.NoError(t, acc.GatherError(c.Gather))
+	require.True(t, c.initialized["example.com"])
+	require.Empty(t, acc.Metrics)
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"code": "rate_limited", "message": "You have exceeded the rate limit"}`))
+	}))
+	defer ts.Close()
+
+	c := newCertificateTransparency(ts.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "exceeded the rate limit")
+}
+
+func TestState(t *testing.T) {
+	c := newCertificateTransparency("http://localhost")
+	c.cursors["example.com"] = "42"
+	c.initialized["example.com"] = true
+	c.cursors["example.org"] = "7"
+
+	// Only domains that have been initialized are saved.
+	state := c.GetState()
+	require.Equal(t, map[string]string{"example.com": "42"}, state)
+
+	restored := newCertificateTransparency("http://localhost")
+	require.NoError(t, restored.SetState(map[string]interface{}{
+		"example.com": "42",
+	}))
+	require.Equal(t, "42", restored.cursors["example.com"])
+	require.True(t, restored.initialized["example.com"])
+
+	require.Error(t, restored.SetState(map[string]interface{}{
+		"example.com": 42.0,
+	}))
+}
@@ -26,6 +26,10 @@ func (*Chrony) Description() string {
 	return "Get standard chrony metrics, requires chronyc executable."
 }
 
+func (*Chrony) Dependencies() []string {
+	return []string{"chronyc"}
+}
+
 func (*Chrony) SampleConfig() string {
 	return `
   ## If true, chronyc tries to perform a DNS lookup for the time server.
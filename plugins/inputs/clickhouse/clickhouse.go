@@ -0,0 +1,414 @@
+package clickhouse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	metricsQuery = "SELECT metric, toUInt64(value) AS value FROM system.metrics"
+	eventsQuery  = "SELECT event AS metric, toUInt64(value) AS value FROM system.events"
+	asyncQuery   = "SELECT metric, toFloat64(value) AS value FROM system.asynchronous_metrics"
+	partsQuery   = "SELECT database, table, sum(bytes) AS bytes, count() AS parts, sum(rows) AS rows " +
+		"FROM system.parts WHERE active = 1 GROUP BY database, table"
+	replicasQuery = "SELECT database, table, is_leader, is_readonly, is_session_expired, " +
+		"queue_size, inserts_in_queue, merges_in_queue, absolute_delay, total_replicas, active_replicas " +
+		"FROM system.replicas"
+	clustersQuery = "SELECT cluster, shard_num, host_name FROM system.clusters"
+)
+
+type ClickHouse struct {
+	Servers        []string `toml:"servers"`
+	Username       string   `toml:"username"`
+	Password       string   `toml:"password"`
+	AutoDiscovery  bool     `toml:"auto_discovery"`
+	ClusterInclude []string `toml:"cluster_include"`
+	ClusterExclude []string `toml:"cluster_exclude"`
+	httpconfig.HTTPClientConfig
+
+	client        *http.Client
+	clusterFilter filter.Filter
+}
+
+var sampleConfig = `
+  ## URLs of the HTTP interface of the ClickHouse servers.
+  servers = ["http://127.0.0.1:8123"]
+
+  ## Credentials of the ClickHouse user.
+  # username = "default"
+  # password = ""
+
+  ## Amount of time allowed to complete each query.
+  # timeout = "5s"
+
+  ## Discover the replicas of the clusters the servers belong to, from the
+  ## system.clusters table, and gather the metrics of each of them.  The
+  ## replicas are requested with the scheme, port and credentials of the
+  ## server they were discovered from.
+  # auto_discovery = true
+
+  ## Filter the clusters used for auto discovery by name.  Values can be
+  ## specified as glob patterns, the test clusters of the default server
+  ## configuration can be excluded with "test_*".
+  # cluster_include = []
+  # cluster_exclude = ["test_*"]
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Optional HTTP headers and cookies
+  # headers = {"X-Special-Header" = "Special-Value"}
+  # cookies = {"session" = "value"}
+
+  ## Idle connections kept open for the next requests, per host, and time
+  ## before closing them (default = 2 and 0, unlimited).
+  # max_idle_conn_per_host = 2
+  # idle_conn_timeout = "0s"
+`
+
+func (ch *ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (ch *ClickHouse) Description() string {
+	return "Read metrics from the system tables of ClickHouse servers"
+}
+
+// connection is a server to gather, along with the cluster and shard it was
+// discovered in.
+type connection struct {
+	url     *url.URL
+	cluster string
+	shard   string
+}
+
+func (ch *ClickHouse) Gather(acc telegraf.Accumulator) error {
+	if ch.client == nil {
+		if err := ch.init(); err != nil {
+			return err
+		}
+	}
+
+	var conns []connection
+	seen := make(map[string]bool)
+	for _, server := range ch.Servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			acc.AddError(fmt.Errorf("[%s]: %s", server, err))
+			continue
+		}
+
+		discovered := false
+		if ch.AutoDiscovery {
+			replicas, err := ch.discover(u)
+			if err != nil {
+				acc.AddError(fmt.Errorf("[%s]: discovering replicas: %s", u.Host, err))
+			}
+			for _, conn := range replicas {
+				discovered = true
+				if !seen[conn.url.Host] {
+					seen[conn.url.Host] = true
+					conns = append(conns, conn)
+				}
+			}
+		}
+
+		if !discovered && !seen[u.Host] {
+			seen[u.Host] = true
+			conns = append(conns, connection{url: u})
+		}
+	}
+
+	for _, conn := range conns {
+		ch.gatherConnection(acc, conn)
+	}
+	return nil
+}
+
+func (ch *ClickHouse) init() error {
+	var err error
+	ch.clusterFilter, err = filter.NewIncludeExcludeFilter(ch.ClusterInclude, ch.ClusterExclude)
+	if err != nil {
+		return fmt.Errorf("error compiling cluster filters: %s", err)
+	}
+
+	ch.client, err = ch.CreateClient("inputs.clickhouse")
+	return err
+}
+
+// discover returns the replicas of the clusters of the server.
+func (ch *ClickHouse) discover(u *url.URL) ([]connection, error) {
+	var rows []struct {
+		Cluster  string  `json:"cluster"`
+		ShardNum uint64N `json:"shard_num"`
+		HostName string  `json:"host_name"`
+	}
+	if err := ch.query(u, clustersQuery, &rows); err != nil {
+		return nil, err
+	}
+
+	port := u.Port()
+	var conns []connection
+	for _, row := range rows {
+		if !ch.clusterFilter.Match(row.Cluster) {
+			continue
+		}
+
+		replica := *u
+		if port != "" {
+			replica.Host = net.JoinHostPort(row.HostName, port)
+		} else {
+			replica.Host = row.HostName
+		}
+		conns = append(conns, connection{
+			url:     &replica,
+			cluster: row.Cluster,
+			shard:   strconv.FormatUint(uint64(row.ShardNum), 10),
+		})
+	}
+	return conns, nil
+}
+
+func (ch *ClickHouse) gatherConnection(acc telegraf.Accumulator, conn connection) {
+	tags := map[string]string{
+		"source": conn.url.Hostname(),
+	}
+	if conn.cluster != "" {
+		tags["cluster"] = conn.cluster
+		tags["shard_num"] = conn.shard
+	}
+
+	gathers := []func(telegraf.Accumulator, connection, map[string]string) error{
+		ch.gatherMetrics,
+		ch.gatherParts,
+		ch.gatherReplicas,
+	}
+	for _, gather := range gathers {
+		if err := gather(acc, conn, tags); err != nil {
+			acc.AddError(fmt.Errorf("[%s]: %s", conn.url.Host, err))
+		}
+	}
+}
+
+// gatherMetrics adds the system.metrics, system.events and
+// system.asynchronous_metrics tables as one metric each.
+func (ch *ClickHouse) gatherMetrics(acc telegraf.Accumulator, conn connection, tags map[string]string) error {
+	tables := []struct {
+		measurement string
+		query       string
+	}{
+		{"clickhouse_metrics", metricsQuery},
+		{"clickhouse_events", eventsQuery},
+	}
+	for _, table := range tables {
+		var rows []struct {
+			Metric string  `json:"metric"`
+			Value  uint64N `json:"value"`
+		}
+		if err := ch.query(conn.url, table.query, &rows); err != nil {
+			return err
+		}
+
+		fields := make(map[string]interface{})
+		for _, row := range rows {
+			fields[snakeCase(row.Metric)] = uint64(row.Value)
+		}
+		acc.AddFields(table.measurement, fields, copyTags(tags))
+	}
+
+	// Values which are not finite are returned as null.
+	var rows []struct {
+		Metric string   `json:"metric"`
+		Value  *float64 `json:"value"`
+	}
+	if err := ch.query(conn.url, asyncQuery, &rows); err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	for _, row := range rows {
+		if row.Value != nil {
+			fields[snakeCase(row.Metric)] = *row.Value
+		}
+	}
+	acc.AddFields("clickhouse_asynchronous_metrics", fields, copyTags(tags))
+	return nil
+}
+
+// gatherParts adds the size of the active parts of each table.
+func (ch *ClickHouse) gatherParts(acc telegraf.Accumulator, conn connection, tags map[string]string) error {
+	var rows []struct {
+		Database string  `json:"database"`
+		Table    string  `json:"table"`
+		Bytes    uint64N `json:"bytes"`
+		Parts    uint64N `json:"parts"`
+		Rows     uint64N `json:"rows"`
+	}
+	if err := ch.query(conn.url, partsQuery, &rows); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		tableTags := copyTags(tags)
+		tableTags["database"] = row.Database
+		tableTags["table"] = row.Table
+		fields := map[string]interface{}{
+			"bytes": uint64(row.Bytes),
+			"parts": uint64(row.Parts),
+			"rows":  uint64(row.Rows),
+		}
+		acc.AddFields("clickhouse_tables", fields, tableTags)
+	}
+	return nil
+}
+
+// gatherReplicas adds the replication status of each replicated table.
+func (ch *ClickHouse) gatherReplicas(acc telegraf.Accumulator, conn connection, tags map[string]string) error {
+	var rows []struct {
+		Database         string  `json:"database"`
+		Table            string  `json:"table"`
+		IsLeader         uint64N `json:"is_leader"`
+		IsReadonly       uint64N `json:"is_readonly"`
+		IsSessionExpired uint64N `json:"is_session_expired"`
+		QueueSize        uint64N `json:"queue_size"`
+		InsertsInQueue   uint64N `json:"inserts_in_queue"`
+		MergesInQueue    uint64N `json:"merges_in_queue"`
+		AbsoluteDelay    uint64N `json:"absolute_delay"`
+		TotalReplicas    uint64N `json:"total_replicas"`
+		ActiveReplicas   uint64N `json:"active_replicas"`
+	}
+	if err := ch.query(conn.url, replicasQuery, &rows); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		replicaTags := copyTags(tags)
+		replicaTags["database"] = row.Database
+		replicaTags["table"] = row.Table
+		fields := map[string]interface{}{
+			"is_leader":          row.IsLeader != 0,
+			"is_readonly":        row.IsReadonly != 0,
+			"is_session_expired": row.IsSessionExpired != 0,
+			"queue_size":         uint64(row.QueueSize),
+			"inserts_in_queue":   uint64(row.InsertsInQueue),
+			"merges_in_queue":    uint64(row.MergesInQueue),
+			"absolute_delay":     uint64(row.AbsoluteDelay),
+			"total_replicas":     uint64(row.TotalReplicas),
+			"active_replicas":    uint64(row.ActiveReplicas),
+		}
+		acc.AddFields("clickhouse_replicas", fields, replicaTags)
+	}
+	return nil
+}
+
+type queryResponse struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// query runs the query on the server and decodes the rows of the result
+// into v.
+func (ch *ClickHouse) query(u *url.URL, query string, v interface{}) error {
+	req, err := http.NewRequest("POST", u.String(), bytes.NewBufferString(query+" FORMAT JSON"))
+	if err != nil {
+		return err
+	}
+	if ch.Username != "" {
+		req.Header.Set("X-ClickHouse-User", ch.Username)
+	}
+	if ch.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", ch.Password)
+	}
+	ch.PrepareRequest(req)
+
+	resp, err := ch.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Errors are returned as plain text, such as "Code: 516, e.displayText() = ..."
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("query failed: %s: %s", resp.Status,
+			strings.TrimSpace(string(body)))
+	}
+
+	result := &queryResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return err
+	}
+	return json.Unmarshal(result.Data, v)
+}
+
+// uint64N is an unsigned integer which may be quoted, as 64-bit integers
+// are quoted in the JSON format by default.
+type uint64N uint64
+
+func (n *uint64N) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = uint64N(v)
+	return nil
+}
+
+// snakeCase converts the CamelCase names of the system tables to snake case,
+// for example "HTTPConnection" becomes "http_connection".  Names which are
+// already lower case, such as "jemalloc.active", are unchanged.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var buf bytes.Buffer
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				buf.WriteByte('_')
+			}
+			buf.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	out := make(map[string]string, len(tags))
+	for k, v := range tags {
+		out[k] = v
+	}
+	return out
+}
+
+func init() {
+	inputs.Add("clickhouse", func() telegraf.Input {
+		return &ClickHouse{
+			Username:      "default",
+			AutoDiscovery: true,
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package clickhouse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	metricsResponse = `{"meta":[{"name":"metric","type":"String"},{"name":"value","type":"UInt64"}],
+"data":[{"metric":"Query","value":"1"},{"metric":"HTTPConnection","value":"2"}],"rows":2}`
+	eventsResponse = `{"meta":[{"name":"metric","type":"String"},{"name":"value","type":"UInt64"}],
+"data":[{"metric":"SelectQuery","value":"137"},{"metric":"ZooKeeperTransactions","value":"9"}],"rows":2}`
+	asyncResponse = `{"meta":[{"name":"metric","type":"String"},{"name":"value","type":"Float64"}],
+"data":[{"metric":"jemalloc.active","value":4288512},{"metric":"Uptime","value":3600},{"metric":"ReplicasMaxAbsoluteDelay","value":null}],"rows":3}`
+	partsResponse = `{"meta":[],
+"data":[{"database":"default","table":"hits","bytes":"1048576","parts":"12","rows":"100000"}],"rows":1}`
+	replicasResponse = `{"meta":[],
+"data":[{"database":"default","table":"hits","is_leader":1,"is_readonly":0,"is_session_expired":0,"queue_size":3,"inserts_in_queue":1,"merges_in_queue":2,"absolute_delay":"5","total_replicas":2,"active_replicas":2}],"rows":1}`
+)
+
+func newServer(t *testing.T, clusters string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "default" ||
+			r.Header.Get("X-ClickHouse-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintln(w, "Code: 516, e.displayText() = DB::Exception: default: Authentication failed")
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		query := string(body)
+		require.True(t, strings.HasSuffix(query, " FORMAT JSON"))
+
+		switch {
+		case strings.Contains(query, "system.clusters"):
+			fmt.Fprintln(w, clusters)
+		case strings.Contains(query, "system.asynchronous_metrics"):
+			fmt.Fprintln(w, asyncResponse)
+		case strings.Contains(query, "system.metrics"):
+			fmt.Fprintln(w, metricsResponse)
+		case strings.Contains(query, "system.events"):
+			fmt.Fprintln(w, eventsResponse)
+		case strings.Contains(query, "system.parts"):
+			fmt.Fprintln(w, partsResponse)
+		case strings.Contains(query, "system.replicas"):
+			fmt.Fprintln(w, replicasResponse)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestGather(t *testing.T) {
+	ts := newServer(t, `{"data":[]}`)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	ch := &ClickHouse{
+		Servers:       []string{ts.URL},
+		Username:      "default",
+		Password:      "secret",
+		AutoDiscovery: true,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(ch.Gather))
+
+	tags := map[string]string{
+		"source": u.Hostname(),
+	}
+	acc.AssertContainsTaggedFields(t, "clickhouse_metrics",
+		map[string]interface{}{
+			"query":           uint64(1),
+			"http_connection": uint64(2),
+		}, tags)
+	acc.AssertContainsTaggedFields(t, "clickhouse_events",
+		map[string]interface{}{
+			"select_query":            uint64(137),
+			"zoo_keeper_transactions": uint64(9),
+		}, tags)
+	acc.AssertContainsTaggedFields(t, "clickhouse_asynchronous_metrics",
+		map[string]interface{}{
+			"jemalloc.active": float64(4288512),
+			"uptime":          float64(3600),
+		}, tags)
+
+	tableTags := map[string]string{
+		"source":   u.Hostname(),
+		"database": "default",
+		"table":    "hits",
+	}
+	acc.AssertContainsTaggedFields(t, "clickhouse_tables",
+		map[string]interface{}{
+			"bytes": uint64(1048576),
+			"parts": uint64(12),
+			"rows":  uint64(100000),
+		}, tableTags)
+	acc.AssertContainsTaggedFields(t, "clickhouse_replicas",
+		map[string]interface{}{
+			"is_leader":          true,
+			"is_readonly":        false,
+			"is_session_expired": false,
+			"queue_size":         uint64(3),
+			"inserts_in_queue":   uint64(1),
+			"merges_in_queue":    uint64(2),
+			"absolute_delay":     uint64(5),
+			"total_replicas":     uint64(2),
+			"active_replicas":    uint64(2),
+		}, tableTags)
+}
+
+func TestGatherAutoDiscovery(t *testing.T) {
+	// The server is discovered as the replica of its own cluster, the replica
+	// of the excluded cluster is not gathered.
+	ts := newServer(t, `{"data":[
+{"cluster":"production","shard_num":1,"host_name":"127.0.0.1"},
+{"cluster":"test_shard_localhost","shard_num":1,"host_name":"localhost"}]}`)
+	defer ts.Close()
+
+	ch := &ClickHouse{
+		Servers:        []string{ts.URL},
+		Username:       "default",
+		Password:       "secret",
+		AutoDiscovery:  true,
+		ClusterExclude: []string{"test_*"},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(ch.Gather))
+
+	require.Len(t, acc.Metrics, 5)
+	for _, m := range acc.Metrics {
+		require.Equal(t, "127.0.0.1", m.Tags["source"])
+		require.Equal(t, "production", m.Tags["cluster"])
+		require.Equal(t, "1", m.Tags["shard_num"])
+	}
+}
+
+func TestGatherQueryError(t *testing.T) {
+	ts := newServer(t, `{"data":[]}`)
+	defer ts.Close()
+
+	ch := &ClickHouse{
+		Servers:  []string{ts.URL},
+		Username: "default",
+		Password: "wrong",
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, ch.Gather(&acc))
+	require.Len(t, acc.Errors, 3)
+	require.Contains(t, acc.Errors[0].Error(), "Authentication failed")
+	require.Empty(t, acc.Metrics)
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Query", "query"},
+		{"HTTPConnection", "http_connection"},
+		{"ReplicatedPartFetches", "replicated_part_fetches"},
+		{"OSCPUVirtualTimeMicroseconds", "oscpu_virtual_time_microseconds"},
+		{"jemalloc.background_thread.num_runs", "jemalloc.background_thread.num_runs"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, snakeCase(tt.name))
+	}
+}
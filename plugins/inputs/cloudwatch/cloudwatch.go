@@ -40,6 +40,7 @@ type (
 	Metric struct {
 		MetricNames []string     `toml:"names"`
 		Dimensions  []*Dimension `toml:"dimensions"`
+		Unit        string       `toml:"unit"`
 	}
 
 	Dimension struct {
@@ -55,10 +56,25 @@ type (
 
 	cloudwatchClient interface {
 		ListMetrics(*cloudwatch.ListMetricsInput) (*cloudwatch.ListMetricsOutput, error)
-		GetMetricStatistics(*cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error)
+		GetMetricData(*GetMetricDataInput) (*GetMetricDataOutput, error)
+	}
+
+	// dataQuery identifies the metric and statistic of a metric data query.
+	dataQuery struct {
+		metric    int
+		statistic string
 	}
 )
 
+// statistics are requested for every metric.
+var statistics = []string{
+	cloudwatch.StatisticAverage,
+	cloudwatch.StatisticMaximum,
+	cloudwatch.StatisticMinimum,
+	cloudwatch.StatisticSum,
+	cloudwatch.StatisticSampleCount,
+}
+
 func (c *CloudWatch) SampleConfig() string {
 	return `
   ## Amazon Region
@@ -103,11 +119,12 @@ func (c *CloudWatch) SampleConfig() string {
   ## Metric Statistic Namespace (required)
   namespace = "AWS/ELB"
 
-  ## Maximum requests per second. Note that the global default AWS rate limit is
-  ## 400 reqs/sec, so if you define multiple namespaces, these should add up to a
-  ## maximum of 400. Optional - default value is 200.
+  ## Maximum requests per second. Metrics are requested in batches of up to
+  ## 100 metrics per request.  Note that the global default AWS rate limit is
+  ## 50 reqs/sec for GetMetricData, so if you define multiple namespaces, these
+  ## should add up to a maximum of 50. Optional - default value is 25.
   ## See http://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/cloudwatch_limits.html
-  ratelimit = 200
+  ratelimit = 25
 
   ## Metrics to Pull (optional)
   ## Defaults to all Metrics in Namespace if nothing is provided
@@ -115,6 +132,12 @@ func (c *CloudWatch) SampleConfig() string {
   #[[inputs.cloudwatch.metrics]]
   #  names = ["Latency", "RequestCount"]
   #
+  #  ## Unit of the metrics (optional), such as "Seconds" or "Count".  Only the
+  #  ## datapoints published with this unit are returned, and the metrics are
+  #  ## tagged with it.  GetMetricData does not return the units, so the
+  #  ## metrics have no unit tag without it.
+  #  # unit = "Seconds"
+  #
   #  ## Dimension filters for Metric (optional)
   #  [[inputs.cloudwatch.metrics.dimensions]]
   #    name = "LoadBalancerName"
@@ -188,19 +211,27 @@ func (c *CloudWatch) Gather(acc telegraf.Accumulator) error {
 
 	now := time.Now()
 
+	// Each request queries all statistics of up to batchSize metrics.
+	batchSize := maxMetricDataQueries / len(statistics)
+
 	// limit concurrency or we can easily exhaust user connection limit
 	// see cloudwatch API request limits:
 	// http://docs.aws.amazon.com/AmazonCloudWatch/latest/DeveloperGuide/cloudwatch_limits.html
 	lmtr := limiter.NewRateLimiter(c.RateLimit, time.Second)
 	defer lmtr.Stop()
 	var wg sync.WaitGroup
-	wg.Add(len(metrics))
-	for _, m := range metrics {
+	for start := 0; start < len(metrics); start += batchSize {
+		end := start + batchSize
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+
 		<-lmtr.C
-		go func(inm *cloudwatch.Metric) {
+		wg.Add(1)
+		go func(batch []*cloudwatch.Metric) {
 			defer wg.Done()
-			acc.AddError(c.gatherMetric(acc, inm, now))
-		}(m)
+			acc.AddError(c.gatherMetrics(acc, batch, now))
+		}(metrics[start:end])
 	}
 	wg.Wait()
 
@@ -212,7 +243,7 @@ func init() {
 		ttl, _ := time.ParseDuration("1hr")
 		return &CloudWatch{
 			CacheTTL:  internal.Duration{Duration: ttl},
-			RateLimit: 200,
+			RateLimit: 25,
 		}
 	})
 }
@@ -232,7 +263,7 @@ func (c *CloudWatch) initializeCloudWatch() error {
 	}
 	configProvider := credentialConfig.Credentials()
 
-	c.client = cloudwatch.New(configProvider)
+	c.client = &cloudwatchService{cloudwatch.New(configProvider)}
 	return nil
 }
 
@@ -276,49 +307,75 @@ func (c *CloudWatch) fetchNamespaceMetrics() ([]*cloudwatch.Metric, error) {
 }
 
 /*
- * Gather given Metric and emit any error
+ * Gather a batch of Metrics with a single GetMetricData query and emit any error
  */
-func (c *CloudWatch) gatherMetric(
+func (c *CloudWatch) gatherMetrics(
 	acc telegraf.Accumulator,
-	metric *cloudwatch.Metric,
+	metrics []*cloudwatch.Metric,
 	now time.Time,
 ) error {
-	params := c.getStatisticsInput(metric, now)
-	resp, err := c.client.GetMetricStatistics(params)
-	if err != nil {
-		return err
-	}
+	input, queries := c.getDataInput(metrics, now)
 
-	for _, point := range resp.Datapoints {
-		tags := map[string]string{
-			"region": c.Region,
-			"unit":   snakeCase(*point.Unit),
-		}
-
-		for _, d := range metric.Dimensions {
-			tags[snakeCase(*d.Name)] = *d.Value
+	// fields of each metric by timestamp
+	fields := make([]map[int64]map[string]interface{}, len(metrics))
+	timestamps := make(map[int64]time.Time)
+	for {
+		resp, err := c.client.GetMetricData(input)
+		if err != nil {
+			return err
 		}
 
-		// record field for each statistic
-		fields := map[string]interface{}{}
+		for _, result := range resp.MetricDataResults {
+			if result.Id == nil {
+				continue
+			}
+			query, ok := queries[*result.Id]
+			if !ok {
+				continue
+			}
+			metric := metrics[query.metric]
 
-		if point.Average != nil {
-			fields[formatField(*metric.MetricName, cloudwatch.StatisticAverage)] = *point.Average
+			for i, value := range result.Timestamps {
+				if value == nil || i >= len(result.Values) || result.Values[i] == nil {
+					continue
+				}
+				ts, err := parseTimestamp(*value)
+				if err != nil {
+					return err
+				}
+				if fields[query.metric] == nil {
+					fields[query.metric] = make(map[int64]map[string]interface{})
+				}
+				points := fields[query.metric]
+				key := ts.UnixNano()
+				if points[key] == nil {
+					points[key] = make(map[string]interface{})
+					timestamps[key] = ts
+				}
+				points[key][formatField(*metric.MetricName, query.statistic)] = *result.Values[i]
+			}
 		}
-		if point.Maximum != nil {
-			fields[formatField(*metric.MetricName, cloudwatch.StatisticMaximum)] = *point.Maximum
+
+		if resp.NextToken == nil {
+			break
 		}
-		if point.Minimum != nil {
-			fields[formatField(*metric.MetricName, cloudwatch.StatisticMinimum)] = *point.Minimum
+		input.NextToken = resp.NextToken
+	}
+
+	for i, points := range fields {
+		tags := map[string]string{
+			"region": c.Region,
 		}
-		if point.SampleCount != nil {
-			fields[formatField(*metric.MetricName, cloudwatch.StatisticSampleCount)] = *point.SampleCount
+		if unit := c.metricUnit(metrics[i]); unit != "" {
+			tags["unit"] = snakeCase(unit)
 		}
-		if point.Sum != nil {
-			fields[formatField(*metric.MetricName, cloudwatch.StatisticSum)] = *point.Sum
+		for _, d := range metrics[i].Dimensions {
+			tags[snakeCase(*d.Name)] = *d.Value
 		}
 
-		acc.AddFields(formatMeasurement(c.Namespace), fields, tags, *point.Timestamp)
+		for key, pointFields := range points {
+			acc.AddFields(formatMeasurement(c.Namespace), pointFields, tags, timestamps[key])
+		}
 	}
 
 	return nil
@@ -344,26 +401,56 @@ func snakeCase(s string) string {
 }
 
 /*
- * Map Metric to *cloudwatch.GetMetricStatisticsInput for given timeframe
+ * Map Metrics to a *GetMetricDataInput for given timeframe, along with the
+ * metric and statistic of each query by id
  */
-func (c *CloudWatch) getStatisticsInput(metric *cloudwatch.Metric, now time.Time) *cloudwatch.GetMetricStatisticsInput {
+func (c *CloudWatch) getDataInput(metrics []*cloudwatch.Metric, now time.Time) (*GetMetricDataInput, map[string]dataQuery) {
 	end := now.Add(-c.Delay.Duration)
+	period := aws.Int64(int64(c.Period.Duration.Seconds()))
 
-	input := &cloudwatch.GetMetricStatisticsInput{
-		StartTime:  aws.Time(end.Add(-c.Period.Duration)),
-		EndTime:    aws.Time(end),
-		MetricName: metric.MetricName,
-		Namespace:  metric.Namespace,
-		Period:     aws.Int64(int64(c.Period.Duration.Seconds())),
-		Dimensions: metric.Dimensions,
-		Statistics: []*string{
-			aws.String(cloudwatch.StatisticAverage),
-			aws.String(cloudwatch.StatisticMaximum),
-			aws.String(cloudwatch.StatisticMinimum),
-			aws.String(cloudwatch.StatisticSum),
-			aws.String(cloudwatch.StatisticSampleCount)},
+	input := &GetMetricDataInput{
+		StartTime: aws.Time(end.Add(-c.Period.Duration)),
+		EndTime:   aws.Time(end),
+	}
+	queries := make(map[string]dataQuery, len(metrics)*len(statistics))
+	for i, metric := range metrics {
+		var unit *string
+		if u := c.metricUnit(metric); u != "" {
+			unit = aws.String(u)
+		}
+		for _, statistic := range statistics {
+			id := fmt.Sprintf("m%d_%s", i, snakeCase(statistic))
+			input.MetricDataQueries = append(input.MetricDataQueries, &MetricDataQuery{
+				Id: aws.String(id),
+				MetricStat: &MetricStat{
+					Metric: metric,
+					Period: period,
+					Stat:   aws.String(statistic),
+					Unit:   unit,
+				},
+				ReturnData: aws.Bool(true),
+			})
+			queries[id] = dataQuery{metric: i, statistic: statistic}
+		}
+	}
+	return input, queries
+}
+
+/*
+ * Unit configured for a metric, or an empty string
+ */
+func (c *CloudWatch) metricUnit(metric *cloudwatch.Metric) string {
+	for _, m := range c.Metrics {
+		if m.Unit == "" {
+			continue
+		}
+		for _, name := range m.MetricNames {
+			if isSelected(name, metric, m.Dimensions) {
+				return m.Unit
+			}
+		}
 	}
-	return input
+	return ""
 }
 
 /*
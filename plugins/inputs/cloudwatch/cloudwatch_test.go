@@ -1,10 +1,15 @@
 package cloudwatch
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
@@ -31,19 +36,24 @@ func (m *mockGatherCloudWatchClient) ListMetrics(params *cloudwatch.ListMetricsI
 	return result, nil
 }
 
-func (m *mockGatherCloudWatchClient) GetMetricStatistics(params *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
-	dataPoint := &cloudwatch.Datapoint{
-		Timestamp:   params.EndTime,
-		Minimum:     aws.Float64(0.1),
-		Maximum:     aws.Float64(0.3),
-		Average:     aws.Float64(0.2),
-		Sum:         aws.Float64(123),
-		SampleCount: aws.Float64(100),
-		Unit:        aws.String("Seconds"),
+func (m *mockGatherCloudWatchClient) GetMetricData(params *GetMetricDataInput) (*GetMetricDataOutput, error) {
+	values := map[string]float64{
+		cloudwatch.StatisticMinimum:     0.1,
+		cloudwatch.StatisticMaximum:     0.3,
+		cloudwatch.StatisticAverage:     0.2,
+		cloudwatch.StatisticSum:         123,
+		cloudwatch.StatisticSampleCount: 100,
 	}
-	result := &cloudwatch.GetMetricStatisticsOutput{
-		Label:      aws.String("Latency"),
-		Datapoints: []*cloudwatch.Datapoint{dataPoint},
+
+	result := &GetMetricDataOutput{}
+	for _, query := range params.MetricDataQueries {
+		result.MetricDataResults = append(result.MetricDataResults, &MetricDataResult{
+			Id:         query.Id,
+			Label:      query.MetricStat.Metric.MetricName,
+			StatusCode: aws.String("Complete"),
+			Timestamps: []*string{aws.String(params.EndTime.UTC().Format(time.RFC3339))},
+			Values:     []*float64{aws.Float64(values[*query.MetricStat.Stat])},
+		})
 	}
 	return result, nil
 }
@@ -59,6 +69,18 @@ func TestGather(t *testing.T) {
 		Delay:     internalDuration,
 		Period:    internalDuration,
 		RateLimit: 200,
+		Metrics: []*Metric{
+			&Metric{
+				MetricNames: []string{"Latency"},
+				Dimensions: []*Dimension{
+					&Dimension{
+						Name:  "LoadBalancerName",
+						Value: "p-example",
+					},
+				},
+				Unit: "Seconds",
+			},
+		},
 	}
 
 	var acc testutil.Accumulator
@@ -74,8 +96,8 @@ func TestGather(t *testing.T) {
 	fields["latency_sample_count"] = 100.0
 
 	tags := map[string]string{}
-	tags["unit"] = "seconds"
 	tags["region"] = "us-east-1"
+	tags["unit"] = "seconds"
 	tags["load_balancer_name"] = "p-example"
 
 	assert.True(t, acc.HasMeasurement("cloudwatch_aws_elb"))
@@ -132,7 +154,7 @@ func (m *mockSelectMetricsCloudWatchClient) ListMetrics(params *cloudwatch.ListM
 	return result, nil
 }
 
-func (m *mockSelectMetricsCloudWatchClient) GetMetricStatistics(params *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+func (m *mockSelectMetricsCloudWatchClient) GetMetricData(params *GetMetricDataInput) (*GetMetricDataOutput, error) {
 	return nil, nil
 }
 
@@ -171,7 +193,7 @@ func TestSelectMetrics(t *testing.T) {
 	assert.Nil(t, err)
 }
 
-func TestGenerateStatisticsInputParams(t *testing.T) {
+func TestGenerateDataInputParams(t *testing.T) {
 	d := &cloudwatch.Dimension{
 		Name:  aws.String("LoadBalancerName"),
 		Value: aws.String("p-example"),
@@ -197,13 +219,148 @@ func TestGenerateStatisticsInputParams(t *testing.T) {
 
 	now := time.Now()
 
-	params := c.getStatisticsInput(m, now)
+	params, queries := c.getDataInput([]*cloudwatch.Metric{m}, now)
 
 	assert.EqualValues(t, *params.EndTime, now.Add(-c.Delay.Duration))
 	assert.EqualValues(t, *params.StartTime, now.Add(-c.Period.Duration).Add(-c.Delay.Duration))
-	assert.Len(t, params.Dimensions, 1)
-	assert.Len(t, params.Statistics, 5)
-	assert.EqualValues(t, *params.Period, 60)
+	assert.Len(t, params.MetricDataQueries, 5)
+	assert.Len(t, queries, 5)
+	for _, query := range params.MetricDataQueries {
+		assert.Equal(t, m, query.MetricStat.Metric)
+		assert.EqualValues(t, 60, *query.MetricStat.Period)
+		assert.Nil(t, query.MetricStat.Unit)
+		assert.Equal(t, dataQuery{metric: 0, statistic: *query.MetricStat.Stat},
+			queries[*query.Id])
+	}
+
+	c.Metrics = []*Metric{
+		&Metric{
+			MetricNames: []string{"Latency"},
+			Dimensions: []*Dimension{
+				&Dimension{
+					Name:  "LoadBalancerName",
+					Value: "*",
+				},
+			},
+			Unit: "Seconds",
+		},
+	}
+	params, _ = c.getDataInput([]*cloudwatch.Metric{m}, now)
+	for _, query := range params.MetricDataQueries {
+		assert.Equal(t, "Seconds", *query.MetricStat.Unit)
+	}
+}
+
+type mockBatchCloudWatchClient struct {
+	mockSelectMetricsCloudWatchClient
+	requests []*GetMetricDataInput
+	sync.Mutex
+}
+
+func (m *mockBatchCloudWatchClient) GetMetricData(params *GetMetricDataInput) (*GetMetricDataOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.requests = append(m.requests, params)
+	return &GetMetricDataOutput{}, nil
+}
+
+func TestGatherBatches(t *testing.T) {
+	duration, _ := time.ParseDuration("1m")
+	internalDuration := internal.Duration{
+		Duration: duration,
+	}
+	c := &CloudWatch{
+		Region:    "us-east-1",
+		Namespace: "AWS/ELB",
+		Delay:     internalDuration,
+		Period:    internalDuration,
+		RateLimit: 200,
+	}
+
+	// The namespace contains 36 metrics, with 5 statistics each.
+	client := &mockBatchCloudWatchClient{}
+	c.client = client
+
+	var acc testutil.Accumulator
+	assert.NoError(t, acc.GatherError(c.Gather))
+	assert.Len(t, client.requests, 1)
+	assert.Len(t, client.requests[0].MetricDataQueries, 180)
+}
+
+func TestGetMetricDataRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "GetMetricData", r.PostForm.Get("Action"))
+		assert.Equal(t, "m0_average", r.PostForm.Get("MetricDataQueries.member.1.Id"))
+		assert.Equal(t, "Latency",
+			r.PostForm.Get("MetricDataQueries.member.1.MetricStat.Metric.MetricName"))
+		assert.Equal(t, "p-example",
+			r.PostForm.Get("MetricDataQueries.member.1.MetricStat.Metric.Dimensions.member.1.Value"))
+		assert.Equal(t, "Average", r.PostForm.Get("MetricDataQueries.member.1.MetricStat.Stat"))
+		assert.Equal(t, "60", r.PostForm.Get("MetricDataQueries.member.1.MetricStat.Period"))
+		assert.Equal(t, "2018-06-01T10:00:00Z", r.PostForm.Get("StartTime"))
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<GetMetricDataResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricDataResult>
+    <MetricDataResults>
+      <member>
+        <Id>m0_average</Id>
+        <Label>Latency</Label>
+        <StatusCode>Complete</StatusCode>
+        <Timestamps>
+          <member>2018-06-01T10:00:00Z</member>
+        </Timestamps>
+        <Values>
+          <member>0.2</member>
+        </Values>
+      </member>
+    </MetricDataResults>
+  </GetMetricDataResult>
+  <ResponseMetadata>
+    <RequestId>00000000-0000-0000-0000-000000000000</RequestId>
+  </ResponseMetadata>
+</GetMetricDataResponse>`))
+	}))
+	defer ts.Close()
+
+	sess := session.New(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(ts.URL),
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	})
+	client := &cloudwatchService{cloudwatch.New(sess)}
+
+	start := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	resp, err := client.GetMetricData(&GetMetricDataInput{
+		StartTime: aws.Time(start),
+		EndTime:   aws.Time(start.Add(time.Minute)),
+		MetricDataQueries: []*MetricDataQuery{
+			{
+				Id: aws.String("m0_average"),
+				MetricStat: &MetricStat{
+					Metric: &cloudwatch.Metric{
+						Namespace:  aws.String("AWS/ELB"),
+						MetricName: aws.String("Latency"),
+						Dimensions: []*cloudwatch.Dimension{
+							{
+								Name:  aws.String("LoadBalancerName"),
+								Value: aws.String("p-example"),
+							},
+						},
+					},
+					Period: aws.Int64(60),
+					Stat:   aws.String("Average"),
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp.MetricDataResults, 1)
+	result := resp.MetricDataResults[0]
+	assert.Equal(t, "m0_average", *result.Id)
+	assert.Equal(t, "2018-06-01T10:00:00Z", *result.Timestamps[0])
+	assert.Equal(t, 0.2, *result.Values[0])
 }
 
 func TestMetricsCacheTimeout(t *testing.T) {
@@ -0,0 +1,91 @@
+package cloudwatch
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// The vendored aws-sdk-go predates the GetMetricData API, these are the API
+// shapes of the operation as defined by the CloudWatch service model.  They
+// are serialized by the query protocol handlers of the SDK client.
+
+const opGetMetricData = "GetMetricData"
+
+// maxMetricDataQueries is the maximum number of queries in a single
+// GetMetricData request.
+const maxMetricDataQueries = 500
+
+type GetMetricDataInput struct {
+	_ struct{} `type:"structure"`
+
+	EndTime           *time.Time         `type:"timestamp" timestampFormat:"iso8601" required:"true"`
+	MaxDatapoints     *int64             `type:"integer"`
+	MetricDataQueries []*MetricDataQuery `type:"list" required:"true"`
+	NextToken         *string            `type:"string"`
+	ScanBy            *string            `type:"string" enum:"ScanBy"`
+	StartTime         *time.Time         `type:"timestamp" timestampFormat:"iso8601" required:"true"`
+}
+
+type MetricDataQuery struct {
+	_ struct{} `type:"structure"`
+
+	Expression *string     `min:"1" type:"string"`
+	Id         *string     `min:"1" type:"string" required:"true"`
+	Label      *string     `type:"string"`
+	MetricStat *MetricStat `type:"structure"`
+	ReturnData *bool       `type:"boolean"`
+}
+
+type MetricStat struct {
+	_ struct{} `type:"structure"`
+
+	Metric *cloudwatch.Metric `type:"structure" required:"true"`
+	Period *int64             `min:"1" type:"integer" required:"true"`
+	Stat   *string            `type:"string" required:"true"`
+	Unit   *string            `type:"string" enum:"StandardUnit"`
+}
+
+type GetMetricDataOutput struct {
+	_ struct{} `type:"structure"`
+
+	MetricDataResults []*MetricDataResult `type:"list"`
+	NextToken         *string             `type:"string"`
+}
+
+type MetricDataResult struct {
+	_ struct{} `type:"structure"`
+
+	Id         *string    `min:"1" type:"string"`
+	Label      *string    `type:"string"`
+	StatusCode *string    `type:"string" enum:"StatusCode"`
+	Values     []*float64 `type:"list"`
+
+	// The XML unmarshaler of the vendored SDK does not support lists of
+	// timestamps, these are parsed with parseTimestamp.
+	Timestamps []*string `type:"list"`
+}
+
+// parseTimestamp parses a timestamp of a MetricDataResult.
+func parseTimestamp(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339, s)
+}
+
+// cloudwatchService extends the SDK client with the GetMetricData
+// operation.
+type cloudwatchService struct {
+	*cloudwatch.CloudWatch
+}
+
+func (s *cloudwatchService) GetMetricData(input *GetMetricDataInput) (*GetMetricDataOutput, error) {
+	op := &request.Operation{
+		Name:       opGetMetricData,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+
+	output := &GetMetricDataOutput{}
+	req := s.NewRequest(op, input, output)
+	return output, req.Send()
+}
@@ -1,16 +1,31 @@
 package couchbase
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
 	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	couchbase "github.com/couchbase/go-couchbase"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 type Couchbase struct {
 	Servers []string
+
+	BucketStatsIncluded []string          `toml:"bucket_stats_included"`
+	GatherXDCR          bool              `toml:"gather_xdcr"`
+	Timeout             internal.Duration `toml:"timeout"`
+
+	client           *http.Client
+	bucketStatFilter filter.Filter
 }
 
 var sampleConfig = `
@@ -24,6 +39,18 @@ var sampleConfig = `
   ## If no protocol is specified, HTTP is used.
   ## If no port is specified, 8091 is used.
   servers = ["http://localhost:8091"]
+
+  ## Detailed bucket statistics to add to the couchbase_bucket measurement,
+  ## such as "ep_queue_size" or "couch_docs_fragmentation".  Values can be
+  ## specified as glob patterns, "*" adds all statistics.
+  # bucket_stats_included = []
+
+  ## Gather the progress of the XDCR replications of the cluster.
+  # gather_xdcr = false
+
+  ## Timeout for requests to the REST API, used for detailed bucket
+  ## statistics and XDCR.
+  # timeout = "5s"
 `
 
 var regexpURI = regexp.MustCompile(`(\S+://)?(\S+\:\S+@)`)
@@ -39,6 +66,12 @@ func (r *Couchbase) Description() string {
 // Reads stats from all configured clusters. Accumulates stats.
 // Returns one of the errors encountered while gathering stats (if any).
 func (r *Couchbase) Gather(acc telegraf.Accumulator) error {
+	if r.client == nil {
+		if err := r.init(); err != nil {
+			return err
+		}
+	}
+
 	if len(r.Servers) == 0 {
 		r.gatherServer("http://localhost:8091/", acc, nil)
 		return nil
@@ -96,13 +129,149 @@ func (r *Couchbase) gatherServer(addr string, acc telegraf.Accumulator, pool *co
 		fields["disk_used"] = bs["diskUsed"]
 		fields["data_used"] = bs["dataUsed"]
 		fields["mem_used"] = bs["memUsed"]
+		if r.bucketStatFilter != nil {
+			if err := r.gatherBucketStats(addr, bucketName, fields); err != nil {
+				acc.AddError(fmt.Errorf("[%s]: %s", bucketName, err))
+			}
+		}
 		acc.AddFields("couchbase_bucket", fields, tags)
 	}
+
+	if r.GatherXDCR {
+		if err := r.gatherXDCR(addr, acc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Couchbase) init() error {
+	if len(r.BucketStatsIncluded) > 0 {
+		var err error
+		r.bucketStatFilter, err = filter.Compile(r.BucketStatsIncluded)
+		if err != nil {
+			return fmt.Errorf("error compiling bucket stats filter: %s", err)
+		}
+	}
+	r.client = &http.Client{
+		Timeout: r.Timeout.Duration,
+	}
+	return nil
+}
+
+type bucketStatsResponse struct {
+	Op struct {
+		Samples map[string][]float64 `json:"samples"`
+	} `json:"op"`
+}
+
+// gatherBucketStats adds the latest sample of the included detailed
+// statistics of the bucket to fields.
+func (r *Couchbase) gatherBucketStats(addr, bucket string, fields map[string]interface{}) error {
+	resp := &bucketStatsResponse{}
+	path := "/pools/default/buckets/" + url.PathEscape(bucket) + "/stats"
+	if err := r.get(addr, path, resp); err != nil {
+		return err
+	}
+
+	for name, samples := range resp.Op.Samples {
+		// Statistics of XDCR replications are named by their path, such as
+		// "replications/<id>/<source>/<target>/changes_left".
+		if name == "timestamp" || strings.Contains(name, "/") || len(samples) == 0 {
+			continue
+		}
+		if !r.bucketStatFilter.Match(name) {
+			continue
+		}
+		fields[name] = samples[len(samples)-1]
+	}
+	return nil
+}
+
+type task struct {
+	Type        string        `json:"type"`
+	ID          string        `json:"id"`
+	Source      string        `json:"source"`
+	Target      string        `json:"target"`
+	Status      string        `json:"status"`
+	ChangesLeft int64         `json:"changesLeft"`
+	DocsChecked int64         `json:"docsChecked"`
+	Errors      []interface{} `json:"errors"`
+}
+
+// gatherXDCR adds the progress of the XDCR replications, which are listed
+// as tasks of the cluster.
+func (r *Couchbase) gatherXDCR(addr string, acc telegraf.Accumulator) error {
+	var tasks []task
+	if err := r.get(addr, "/pools/default/tasks", &tasks); err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		if t.Type != "xdcr" {
+			continue
+		}
+
+		// The target is formatted as "/remoteClusters/<uuid>/buckets/<name>".
+		var remote, targetBucket string
+		parts := strings.Split(strings.TrimPrefix(t.Target, "/"), "/")
+		if len(parts) == 4 {
+			remote, targetBucket = parts[1], parts[3]
+		}
+
+		tags := map[string]string{
+			"cluster":        regexpURI.ReplaceAllString(addr, "${1}"),
+			"source_bucket":  t.Source,
+			"target_bucket":  targetBucket,
+			"remote_cluster": remote,
+			"status":         t.Status,
+		}
+		fields := map[string]interface{}{
+			"changes_left": t.ChangesLeft,
+			"docs_checked": t.DocsChecked,
+			"errors":       len(t.Errors),
+		}
+		acc.AddFields("couchbase_xdcr", fields, tags)
+	}
 	return nil
 }
 
+// get requests path from the REST API of the server, using the credentials
+// of the server URL.
+func (r *Couchbase) get(addr, path string, v interface{}) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	user := u.User
+	u.User = nil
+	u.Path = path
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u.String(), resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
 func init() {
 	inputs.Add("couchbase", func() telegraf.Input {
-		return &Couchbase{}
+		return &Couchbase{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
 	})
 }
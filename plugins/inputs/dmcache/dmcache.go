@@ -23,6 +23,10 @@ func (c *DMCache) Description() string {
 	return "Provide a native collection for dmsetup based statistics for dm-cache"
 }
 
+func (c *DMCache) Dependencies() []string {
+	return []string{"dmsetup"}
+}
+
 func init() {
 	inputs.Add("dmcache", func() telegraf.Input {
 		return &DMCache{
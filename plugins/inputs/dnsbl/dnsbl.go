@@ -0,0 +1,182 @@
+package dnsbl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = "dnsbl"
+
+type DNSBL struct {
+	Targets          []string          `toml:"targets"`
+	Blacklists       []string          `toml:"blacklists"`
+	DomainBlacklists []string          `toml:"domain_blacklists"`
+	Timeout          internal.Duration `toml:"timeout"`
+
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+var sampleConfig = `
+  ## IP addresses or host names to check.  Host names are resolved and each
+  ## of their addresses is checked against the blacklists.
+  targets = ["mail.example.com"]
+
+  ## IP based blacklists (DNSBL/RBL).
+  blacklists = ["zen.spamhaus.org", "bl.spamcop.net", "b.barracudacentral.org"]
+
+  ## Domain based blacklists (RHSBL), the host names of the targets are
+  ## checked against these.
+  # domain_blacklists = ["dbl.spamhaus.org"]
+
+  ## Timeout for each DNS query.
+  # timeout = "5s"
+`
+
+func (d *DNSBL) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DNSBL) Description() string {
+	return "Check IP addresses and domains against DNS blacklists"
+}
+
+// query is a single lookup of a target in a blacklist.
+type query struct {
+	target    string
+	address   string
+	blacklist string
+	name      string
+}
+
+func (d *DNSBL) Gather(acc telegraf.Accumulator) error {
+	var queries []query
+	for _, target := range d.Targets {
+		addresses := []string{target}
+		if net.ParseIP(target) == nil {
+			for _, bl := range d.DomainBlacklists {
+				queries = append(queries, query{
+					target:    target,
+					blacklist: bl,
+					name:      strings.TrimSuffix(target, ".") + "." + bl,
+				})
+			}
+
+			if len(d.Blacklists) == 0 {
+				continue
+			}
+
+			var err error
+			addresses, err = d.resolve(target)
+			if err != nil {
+				acc.AddError(fmt.Errorf("resolving %s: %s", target, err))
+				continue
+			}
+		}
+
+		for _, address := range addresses {
+			name, err := reverse(address)
+			if err != nil {
+				acc.AddError(err)
+				continue
+			}
+			for _, bl := range d.Blacklists {
+				queries = append(queries, query{
+					target:    target,
+					address:   address,
+					blacklist: bl,
+					name:      name + "." + bl,
+				})
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, q := range queries {
+		wg.Add(1)
+		go func(q query) {
+			defer wg.Done()
+			d.check(acc, q)
+		}(q)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (d *DNSBL) resolve(host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout.Duration)
+	defer cancel()
+	return d.lookupHost(ctx, host)
+}
+
+// check looks up the query name, the target is listed if the name resolves.
+// The addresses returned indicate the reason of the listing, by convention
+// they are within 127.0.0.0/8.
+func (d *DNSBL) check(acc telegraf.Accumulator, q query) {
+	codes, err := d.resolve(q.name)
+	if err != nil && !isNotFound(err) {
+		acc.AddError(fmt.Errorf("checking %s in %s: %s", q.target, q.blacklist, err))
+		return
+	}
+
+	tags := map[string]string{
+		"target":    q.target,
+		"blacklist": q.blacklist,
+	}
+	if q.address != "" {
+		tags["address"] = q.address
+	}
+
+	sort.Strings(codes)
+	fields := map[string]interface{}{
+		"listed":       len(codes) > 0,
+		"return_codes": strings.Join(codes, ","),
+	}
+	acc.AddFields(measurement, fields, tags)
+}
+
+// isNotFound returns true if the error is a NXDOMAIN response.
+func isNotFound(err error) bool {
+	dnsErr, ok := err.(*net.DNSError)
+	return ok && dnsErr.Err == "no such host"
+}
+
+// reverse returns the reversed representation of the address used for
+// lookups in IP based blacklists, such as "4.3.2.1" for 1.2.3.4.  IPv6
+// addresses are reversed by nibble.
+func reverse(address string) (string, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address %q", address)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+
+	const hexDigits = "0123456789abcdef"
+	nibbles := make([]string, 0, 32)
+	for i := len(ip) - 1; i >= 0; i-- {
+		nibbles = append(nibbles,
+			string(hexDigits[ip[i]&0x0f]), string(hexDigits[ip[i]>>4]))
+	}
+	return strings.Join(nibbles, "."), nil
+}
+
+func init() {
+	inputs.Add("dnsbl", func() telegraf.Input {
+		return &DNSBL{
+			Timeout:    internal.Duration{Duration: 5 * time.Second},
+			lookupHost: net.DefaultResolver.LookupHost,
+		}
+	})
+}
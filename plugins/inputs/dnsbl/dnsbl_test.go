@@ -0,0 +1,110 @@
+package dnsbl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var records = map[string][]string{
+	"mail.example.com":             {"192.0.2.1"},
+	"1.2.0.192.zen.spamhaus.org":   {"127.0.0.4", "127.0.0.2"},
+	"example.com.dbl.spamhaus.org": {"127.0.1.2"},
+	"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.zen.spamhaus.org": {"127.0.0.3"},
+}
+
+func lookupHost(ctx context.Context, host string) ([]string, error) {
+	if host == "1.2.0.192.bl.broken.org" {
+		return nil, errors.New("i/o timeout")
+	}
+	if addrs, ok := records[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host}
+}
+
+func TestGather(t *testing.T) {
+	d := &DNSBL{
+		Targets:          []string{"mail.example.com", "2001:db8::1", "example.com"},
+		Blacklists:       []string{"zen.spamhaus.org"},
+		DomainBlacklists: []string{"dbl.spamhaus.org"},
+		lookupHost:       lookupHost,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "dnsbl",
+		map[string]interface{}{
+			"listed":       true,
+			"return_codes": "127.0.0.2,127.0.0.4",
+		},
+		map[string]string{
+			"target":    "mail.example.com",
+			"address":   "192.0.2.1",
+			"blacklist": "zen.spamhaus.org",
+		})
+	acc.AssertContainsTaggedFields(t, "dnsbl",
+		map[string]interface{}{
+			"listed":       false,
+			"return_codes": "",
+		},
+		map[string]string{
+			"target":    "mail.example.com",
+			"blacklist": "dbl.spamhaus.org",
+		})
+	acc.AssertContainsTaggedFields(t, "dnsbl",
+		map[string]interface{}{
+			"listed":       true,
+			"return_codes": "127.0.0.3",
+		},
+		map[string]string{
+			"target":    "2001:db8::1",
+			"address":   "2001:db8::1",
+			"blacklist": "zen.spamhaus.org",
+		})
+	acc.AssertContainsTaggedFields(t, "dnsbl",
+		map[string]interface{}{
+			"listed":       true,
+			"return_codes": "127.0.1.2",
+		},
+		map[string]string{
+			"target":    "example.com",
+			"blacklist": "dbl.spamhaus.org",
+		})
+
+	// example.com does not resolve, only the domain blacklist is checked.
+	require.Len(t, acc.Errors, 1)
+	require.Len(t, acc.Metrics, 4)
+}
+
+func TestGatherQueryError(t *testing.T) {
+	d := &DNSBL{
+		Targets:    []string{"192.0.2.1"},
+		Blacklists: []string{"bl.broken.org", "zen.spamhaus.org"},
+		lookupHost: lookupHost,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Len(t, acc.Metrics, 1)
+}
+
+func TestReverse(t *testing.T) {
+	name, err := reverse("192.0.2.1")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.0.192", name)
+
+	name, err = reverse("2001:db8::1")
+	require.NoError(t, err)
+	require.Equal(t,
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2", name)
+
+	_, err = reverse("example.com")
+	require.Error(t, err)
+}
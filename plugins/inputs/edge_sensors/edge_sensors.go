@@ -0,0 +1,229 @@
+package edge_sensors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultW1Path = "/sys/bus/w1/devices"
+
+type EdgeSensors struct {
+	W1Path string       `toml:"w1_path"`
+	W1     []*W1Sensor  `toml:"w1"`
+	I2C    []*I2CSensor `toml:"i2c"`
+	IIO    []*IIOSensor `toml:"iio"`
+}
+
+// W1Sensor configures the name and offsets of a 1-Wire sensor, 1-Wire
+// sensors are gathered without configuration.
+type W1Sensor struct {
+	ID      string             `toml:"id"`
+	Name    string             `toml:"name"`
+	Offsets map[string]float64 `toml:"offsets"`
+}
+
+// IIOSensor is a humidity sensor of the Industrial I/O subsystem, such as
+// the DHT11 and DHT22 sensors of the dht11 kernel module.
+type IIOSensor struct {
+	Path    string             `toml:"path"`
+	Name    string             `toml:"name"`
+	Offsets map[string]float64 `toml:"offsets"`
+}
+
+var sampleConfig = `
+  ## Path of the 1-Wire devices of the w1-therm kernel module, the
+  ## temperature of all DS18B20 and compatible sensors found is gathered.
+  ## Set to "" to disable.
+  # w1_path = "/sys/bus/w1/devices"
+
+  ## Calibration offsets, added to the values of the sensors, are set for
+  ## each sensor in an "offsets" table with the names of the fields.
+  # [[inputs.edge_sensors.w1]]
+  #   id = "28-000005e2fdc3"
+  #   name = "freezer"
+  #   [inputs.edge_sensors.w1.offsets]
+  #     temperature = -0.5
+
+  ## BME280 (or BMP280) and SHT3x sensors on the I2C buses, the bus is the
+  ## number of the /dev/i2c-<bus> device.
+  # [[inputs.edge_sensors.i2c]]
+  #   type = "bme280"
+  #   bus = 1
+  #   address = "0x76"
+  #   name = "outdoor"
+  #   [inputs.edge_sensors.i2c.offsets]
+  #     humidity = 2.0
+  # [[inputs.edge_sensors.i2c]]
+  #   type = "sht3x"
+  #   bus = 1
+  #   address = "0x44"
+
+  ## DHT11 and DHT22 sensors of the dht11 kernel module, enabled on the
+  ## Raspberry Pi with "dtoverlay=dht11,gpiopin=<pin>".
+  # [[inputs.edge_sensors.iio]]
+  #   path = "/sys/bus/iio/devices/iio:device0"
+  #   name = "greenhouse"
+`
+
+func (e *EdgeSensors) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *EdgeSensors) Description() string {
+	return "Read 1-Wire, I2C and DHT sensors of single board computers"
+}
+
+func (e *EdgeSensors) Gather(acc telegraf.Accumulator) error {
+	if e.W1Path != "" {
+		if err := e.gatherW1(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+
+	for _, s := range e.I2C {
+		fields, err := s.read()
+		if err != nil {
+			acc.AddError(fmt.Errorf("[sensor=%s]: %s", s.id(), err))
+			continue
+		}
+		addFields(acc, s.Type, s.id(), s.Name, s.Offsets, fields)
+	}
+
+	for _, s := range e.IIO {
+		fields, err := readIIO(s.Path)
+		if err != nil {
+			acc.AddError(fmt.Errorf("[sensor=%s]: %s", s.Path, err))
+			continue
+		}
+		addFields(acc, "dht", filepath.Base(s.Path), s.Name, s.Offsets, fields)
+	}
+	return nil
+}
+
+// gatherW1 gathers the 1-Wire sensors having a w1_slave file, which reads:
+//
+//	72 01 4b 46 7f ff 0e 10 57 : crc=57 YES
+//	72 01 4b 46 7f ff 0e 10 57 t=23125
+func (e *EdgeSensors) gatherW1(acc telegraf.Accumulator) error {
+	files, err := filepath.Glob(filepath.Join(e.W1Path, "*", "w1_slave"))
+	if err != nil {
+		return err
+	}
+
+	configs := make(map[string]*W1Sensor)
+	for _, s := range e.W1 {
+		configs[s.ID] = s
+	}
+
+	for _, file := range files {
+		id := filepath.Base(filepath.Dir(file))
+		temperature, err := readW1(file)
+		if err != nil {
+			acc.AddError(fmt.Errorf("[sensor=%s]: %s", id, err))
+			continue
+		}
+
+		config := configs[id]
+		if config == nil {
+			config = &W1Sensor{}
+		}
+		fields := map[string]float64{"temperature": temperature}
+		addFields(acc, "w1_therm", id, config.Name, config.Offsets, fields)
+	}
+	return nil
+}
+
+func readW1(file string) (float64, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		return 0, fmt.Errorf("unexpected content %q", string(b))
+	}
+	if !strings.HasSuffix(lines[0], "YES") {
+		return 0, fmt.Errorf("crc check failed")
+	}
+	i := strings.Index(lines[1], "t=")
+	if i < 0 {
+		return 0, fmt.Errorf("unexpected content %q", string(b))
+	}
+	v, err := strconv.ParseInt(lines[1][i+2:], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) / 1000, nil
+}
+
+// readIIO reads the temperature and humidity of an Industrial I/O device,
+// in thousandths of degrees Celsius and percents.  The dht11 kernel module
+// fails reads when the response of the sensor is corrupted, which happens
+// regularly, so the read is attempted a few times.
+func readIIO(path string) (map[string]float64, error) {
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		var temperature, humidity float64
+		temperature, err = readMilli(filepath.Join(path, "in_temp_input"))
+		if err != nil {
+			continue
+		}
+		humidity, err = readMilli(filepath.Join(path, "in_humidityrelative_input"))
+		if err != nil {
+			continue
+		}
+		return map[string]float64{
+			"temperature": temperature,
+			"humidity":    humidity,
+		}, nil
+	}
+	return nil, err
+}
+
+func readMilli(file string) (float64, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) / 1000, nil
+}
+
+// addFields adds the values of a sensor with its calibration offsets.
+func addFields(
+	acc telegraf.Accumulator,
+	typ, id, name string,
+	offsets map[string]float64,
+	values map[string]float64,
+) {
+	tags := map[string]string{
+		"type":   typ,
+		"sensor": id,
+	}
+	if name != "" {
+		tags["name"] = name
+	}
+	fields := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		fields[k] = v + offsets[k]
+	}
+	acc.AddFields("edge_sensors", fields, tags)
+}
+
+func init() {
+	inputs.Add("edge_sensors", func() telegraf.Input {
+		return &EdgeSensors{
+			W1Path: defaultW1Path,
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package edge_sensors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBME280 is a BME280 with the calibration of the example of the
+// datasheet of the BMP280.
+type fakeBME280 struct {
+	registers [256]byte
+	pointer   byte
+	writes    [][]byte
+}
+
+func newFakeBME280(chipID byte) *fakeBME280 {
+	d := &fakeBME280{}
+	d.registers[0xD0] = chipID
+
+	le := func(register byte, values ...int16) {
+		for i, v := range values {
+			binary.LittleEndian.PutUint16(d.registers[int(register)+2*i:], uint16(v))
+		}
+	}
+	binary.LittleEndian.PutUint16(d.registers[0x88:], 27504)
+	le(0x8A, 26435, -1000)
+	binary.LittleEndian.PutUint16(d.registers[0x8E:], 36477)
+	le(0x90, -10685, 3024, 2855, 140, -7, 15500, -14600, 6000)
+
+	// H1=75, H2=362, H3=0, H4=313, H5=50, H6=30
+	d.registers[0xA1] = 75
+	le(0xE1, 362)
+	d.registers[0xE3] = 0
+	d.registers[0xE4] = 313 >> 4
+	d.registers[0xE5] = 313&0x0F | (50&0x0F)<<4
+	d.registers[0xE6] = 50 >> 4
+	d.registers[0xE7] = 30
+
+	adc := func(register byte, v uint32) {
+		d.registers[register] = byte(v >> 12)
+		d.registers[register+1] = byte(v >> 4)
+		d.registers[register+2] = byte(v << 4)
+	}
+	adc(0xF7, 415148)
+	adc(0xFA, 519888)
+	binary.BigEndian.PutUint16(d.registers[0xFD:], 30000)
+	return d
+}
+
+func (d *fakeBME280) Write(b []byte) (int, error) {
+	if len(b) == 1 {
+		d.pointer = b[0]
+	} else {
+		d.writes = append(d.writes, b)
+	}
+	return len(b), nil
+}
+
+func (d *fakeBME280) Read(b []byte) (int, error) {
+	n := copy(b, d.registers[d.pointer:])
+	d.pointer += byte(n)
+	return n, nil
+}
+
+func (d *fakeBME280) Close() error {
+	return nil
+}
+
+type fakeSHT3x struct {
+	bytes.Buffer
+	command []byte
+}
+
+func (d *fakeSHT3x) Write(b []byte) (int, error) {
+	d.command = b
+	return len(b), nil
+}
+
+func (d *fakeSHT3x) Close() error {
+	return nil
+}
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestReadBME280(t *testing.T) {
+	d := newFakeBME280(bme280ChipID)
+	values, err := readBME280(d)
+	require.NoError(t, err)
+	require.InDelta(t, 25.08, values["temperature"], 0.01)
+	require.InDelta(t, 1006.53, values["pressure"], 0.01)
+	require.InDelta(t, 55.0, values["humidity"], 0.01)
+	require.Equal(t, [][]byte{{0xF2, 0x01}, {0xF4, 0x25}}, d.writes)
+}
+
+func TestReadBMP280(t *testing.T) {
+	d := newFakeBME280(bmp280ChipID)
+	values, err := readBME280(d)
+	require.NoError(t, err)
+	require.InDelta(t, 25.08, values["temperature"], 0.01)
+	require.InDelta(t, 1006.53, values["pressure"], 0.01)
+	require.NotContains(t, values, "humidity")
+	require.Equal(t, [][]byte{{0xF4, 0x25}}, d.writes)
+}
+
+func TestReadBME280UnexpectedChip(t *testing.T) {
+	_, err := readBME280(newFakeBME280(0x55))
+	require.EqualError(t, err, "unexpected chip id 0x55")
+}
+
+func TestReadSHT3x(t *testing.T) {
+	d := &fakeSHT3x{}
+	d.Buffer.Write([]byte{0x66, 0x66, 0x93, 0x80, 0x00, 0xA2})
+	values, err := readSHT3x(d)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x24, 0x00}, d.command)
+	require.InDelta(t, 25.0, values["temperature"], 0.001)
+	require.InDelta(t, 50.0, values["humidity"], 0.001)
+
+	d.Buffer.Write([]byte{0x66, 0x66, 0x00, 0x80, 0x00, 0xA2})
+	_, err = readSHT3x(d)
+	require.EqualError(t, err, "crc check failed")
+}
+
+func TestCRC8(t *testing.T) {
+	// Example of the datasheet.
+	require.Equal(t, byte(0x92), crc8([]byte{0xBE, 0xEF}))
+}
+
+func TestGather(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edge_sensors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(path, content string) {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	write("w1/28-000005e2fdc3/w1_slave",
+		"72 01 4b 46 7f ff 0e 10 57 : crc=57 YES\n72 01 4b 46 7f ff 0e 10 57 t=23125\n")
+	write("w1/28-000005e2aaaa/w1_slave",
+		"50 05 4b 46 7f ff 0c 10 1c : crc=1c YES\n50 05 4b 46 7f ff 0c 10 1c t=-1500\n")
+	write("w1/28-000005e2bbbb/w1_slave",
+		"ff ff ff ff ff ff ff ff ff : crc=c9 NO\nff ff ff ff ff ff ff ff ff t=-62\n")
+	write("w1/w1_bus_master1/w1_master_slave_count", "3\n")
+	write("iio:device0/in_temp_input", "21400\n")
+	write("iio:device0/in_humidityrelative_input", "48300\n")
+
+	openI2C = func(bus int, address uint16) (io.ReadWriteCloser, error) {
+		if bus != 1 {
+			return nil, fmt.Errorf("open /dev/i2c-%d: no such file or directory", bus)
+		}
+		d := &fakeSHT3x{}
+		d.Buffer.Write([]byte{0x66, 0x66, 0x93, 0x80, 0x00, 0xA2})
+		return d, nil
+	}
+
+	e := &EdgeSensors{
+		W1Path: filepath.Join(dir, "w1"),
+		W1: []*W1Sensor{
+			{
+				ID:      "28-000005e2fdc3",
+				Name:    "freezer",
+				Offsets: map[string]float64{"temperature": 0.5},
+			},
+		},
+		I2C: []*I2CSensor{
+			{
+				Type:    "sht3x",
+				Bus:     1,
+				Address: "0x44",
+				Offsets: map[string]float64{"humidity": -5},
+			},
+			{Type: "sht3x", Bus: 2, Address: "0x44"},
+			{Type: "sht3x", Bus: 1, Address: "0x100"},
+		},
+		IIO: []*IIOSensor{
+			{Path: filepath.Join(dir, "iio:device0"), Name: "greenhouse"},
+			{Path: filepath.Join(dir, "iio:device1")},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "edge_sensors",
+		map[string]interface{}{"temperature": 23.625},
+		map[string]string{"type": "w1_therm", "sensor": "28-000005e2fdc3", "name": "freezer"})
+	acc.AssertContainsTaggedFields(t, "edge_sensors",
+		map[string]interface{}{"temperature": -1.5},
+		map[string]string{"type": "w1_therm", "sensor": "28-000005e2aaaa"})
+	acc.AssertContainsTaggedFields(t, "edge_sensors",
+		map[string]interface{}{"temperature": 21.4, "humidity": 48.3},
+		map[string]string{"type": "dht", "sensor": "iio:device0", "name": "greenhouse"})
+
+	m, ok := acc.Get("edge_sensors")
+	require.True(t, ok)
+	for _, metric := range acc.Metrics {
+		if metric.Tags["type"] == "sht3x" {
+			m = metric
+		}
+	}
+	require.Equal(t, map[string]string{"type": "sht3x", "sensor": "i2c-1-0x44"}, m.Tags)
+	require.InDelta(t, 25.0, m.Fields["temperature"], 0.001)
+	require.InDelta(t, 45.0, m.Fields["humidity"], 0.001)
+
+	require.Len(t, acc.Metrics, 4)
+	require.Len(t, acc.Errors, 4)
+	errors := make([]string, 0, len(acc.Errors))
+	for _, err := range acc.Errors {
+		errors = append(errors, err.Error())
+	}
+	require.Contains(t, errors, "[sensor=28-000005e2bbbb]: crc check failed")
+	require.Contains(t, errors, "[sensor=i2c-2-0x44]: open /dev/i2c-2: no such file or directory")
+	require.Contains(t, errors, `[sensor=i2c-1-0x100]: invalid address "0x100"`)
+}
@@ -0,0 +1,230 @@
+package edge_sensors
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// sleep is used to mock the wait for measurements in tests.
+var sleep = time.Sleep
+
+// I2CSensor is a sensor on an I2C bus.
+type I2CSensor struct {
+	Type    string             `toml:"type"`
+	Bus     int                `toml:"bus"`
+	Address string             `toml:"address"`
+	Name    string             `toml:"name"`
+	Offsets map[string]float64 `toml:"offsets"`
+}
+
+func (s *I2CSensor) id() string {
+	return fmt.Sprintf("i2c-%d-%s", s.Bus, s.Address)
+}
+
+func (s *I2CSensor) read() (map[string]float64, error) {
+	address, err := strconv.ParseUint(s.Address, 0, 7)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q", s.Address)
+	}
+
+	var read func(io.ReadWriter) (map[string]float64, error)
+	switch s.Type {
+	case "bme280":
+		read = readBME280
+	case "sht3x":
+		read = readSHT3x
+	default:
+		return nil, fmt.Errorf("unknown type %q", s.Type)
+	}
+
+	dev, err := openI2C(s.Bus, uint16(address))
+	if err != nil {
+		return nil, err
+	}
+	defer dev.Close()
+	return read(dev)
+}
+
+// readRegisters reads consecutive registers of a device.
+func readRegisters(dev io.ReadWriter, register byte, n int) ([]byte, error) {
+	if _, err := dev.Write([]byte{register}); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(dev, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+const (
+	bme280ChipID = 0x60
+	bmp280ChipID = 0x58
+)
+
+// bme280Calibration are the compensation parameters of a BME280, stored in
+// the non-volatile memory of each sensor.
+type bme280Calibration struct {
+	T1                             uint16
+	T2, T3                         int16
+	P1                             uint16
+	P2, P3, P4, P5, P6, P7, P8, P9 int16
+	H1, H3                         uint8
+	H2, H4, H5                     int16
+	H6                             int8
+}
+
+// readBME280 triggers a measurement of a BME280 in forced mode, without
+// oversampling or filter, and returns the compensated values, as described
+// in the datasheet.  The BMP280 has the same registers, without humidity.
+func readBME280(dev io.ReadWriter) (map[string]float64, error) {
+	id, err := readRegisters(dev, 0xD0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if id[0] != bme280ChipID && id[0] != bmp280ChipID {
+		return nil, fmt.Errorf("unexpected chip id 0x%02x", id[0])
+	}
+	humidity := id[0] == bme280ChipID
+
+	b, err := readRegisters(dev, 0x88, 26)
+	if err != nil {
+		return nil, err
+	}
+	c := bme280Calibration{
+		T1: binary.LittleEndian.Uint16(b[0:]),
+		T2: int16(binary.LittleEndian.Uint16(b[2:])),
+		T3: int16(binary.LittleEndian.Uint16(b[4:])),
+		P1: binary.LittleEndian.Uint16(b[6:]),
+		P2: int16(binary.LittleEndian.Uint16(b[8:])),
+		P3: int16(binary.LittleEndian.Uint16(b[10:])),
+		P4: int16(binary.LittleEndian.Uint16(b[12:])),
+		P5: int16(binary.LittleEndian.Uint16(b[14:])),
+		P6: int16(binary.LittleEndian.Uint16(b[16:])),
+		P7: int16(binary.LittleEndian.Uint16(b[18:])),
+		P8: int16(binary.LittleEndian.Uint16(b[20:])),
+		P9: int16(binary.LittleEndian.Uint16(b[22:])),
+		H1: b[25],
+	}
+
+	if humidity {
+		b, err = readRegisters(dev, 0xE1, 7)
+		if err != nil {
+			return nil, err
+		}
+		c.H2 = int16(binary.LittleEndian.Uint16(b[0:]))
+		c.H3 = b[2]
+		c.H4 = int16(int8(b[3]))<<4 | int16(b[4]&0x0F)
+		c.H5 = int16(int8(b[5]))<<4 | int16(b[4]>>4)
+		c.H6 = int8(b[6])
+
+		// Humidity oversampling x1, applied by the write to ctrl_meas.
+		if _, err := dev.Write([]byte{0xF2, 0x01}); err != nil {
+			return nil, err
+		}
+	}
+	// Temperature and pressure oversampling x1, forced mode.
+	if _, err := dev.Write([]byte{0xF4, 0x25}); err != nil {
+		return nil, err
+	}
+	sleep(10 * time.Millisecond)
+
+	b, err = readRegisters(dev, 0xF7, 8)
+	if err != nil {
+		return nil, err
+	}
+	adcP := float64(uint32(b[0])<<12 | uint32(b[1])<<4 | uint32(b[2])>>4)
+	adcT := float64(uint32(b[3])<<12 | uint32(b[4])<<4 | uint32(b[5])>>4)
+	adcH := float64(uint32(b[6])<<8 | uint32(b[7]))
+
+	t, tFine := c.temperature(adcT)
+	values := map[string]float64{
+		"temperature": t,
+		"pressure":    c.pressure(adcP, tFine) / 100,
+	}
+	if humidity {
+		values["humidity"] = c.humidity(adcH, tFine)
+	}
+	return values, nil
+}
+
+// temperature returns the temperature in degrees Celsius and the fine
+// temperature used by the compensation of the pressure and humidity.
+func (c *bme280Calibration) temperature(adc float64) (float64, float64) {
+	v1 := (adc/16384 - float64(c.T1)/1024) * float64(c.T2)
+	v2 := (adc/131072 - float64(c.T1)/8192) * (adc/131072 - float64(c.T1)/8192) * float64(c.T3)
+	tFine := v1 + v2
+	return tFine / 5120, tFine
+}
+
+// pressure returns the pressure in Pascals.
+func (c *bme280Calibration) pressure(adc, tFine float64) float64 {
+	v1 := tFine/2 - 64000
+	v2 := v1 * v1 * float64(c.P6) / 32768
+	v2 = v2 + v1*float64(c.P5)*2
+	v2 = v2/4 + float64(c.P4)*65536
+	v1 = (float64(c.P3)*v1*v1/524288 + float64(c.P2)*v1) / 524288
+	v1 = (1 + v1/32768) * float64(c.P1)
+	if v1 == 0 {
+		return 0
+	}
+	p := 1048576 - adc
+	p = (p - v2/4096) * 6250 / v1
+	v1 = float64(c.P9) * p * p / 2147483648
+	v2 = p * float64(c.P8) / 32768
+	return p + (v1+v2+float64(c.P7))/16
+}
+
+// humidity returns the relative humidity in percents.
+func (c *bme280Calibration) humidity(adc, tFine float64) float64 {
+	h := tFine - 76800
+	h = (adc - (float64(c.H4)*64 + float64(c.H5)/16384*h)) *
+		(float64(c.H2) / 65536 * (1 + float64(c.H6)/67108864*h*(1+float64(c.H3)/67108864*h)))
+	h = h * (1 - float64(c.H1)*h/524288)
+	return math.Max(0, math.Min(100, h))
+}
+
+// readSHT3x triggers a single shot measurement of a SHT3x with high
+// repeatability, and returns the temperature and humidity.
+func readSHT3x(dev io.ReadWriter) (map[string]float64, error) {
+	if _, err := dev.Write([]byte{0x24, 0x00}); err != nil {
+		return nil, err
+	}
+	sleep(16 * time.Millisecond)
+
+	b := make([]byte, 6)
+	if _, err := io.ReadFull(dev, b); err != nil {
+		return nil, err
+	}
+	if crc8(b[0:2]) != b[2] || crc8(b[3:5]) != b[5] {
+		return nil, fmt.Errorf("crc check failed")
+	}
+
+	t := float64(binary.BigEndian.Uint16(b[0:]))
+	h := float64(binary.BigEndian.Uint16(b[3:]))
+	return map[string]float64{
+		"temperature": -45 + 175*t/65535,
+		"humidity":    100 * h / 65535,
+	}, nil
+}
+
+// crc8 is the checksum of the SHT3x, with polynomial 0x31 and
+// initialization 0xFF.
+func crc8(b []byte) byte {
+	crc := byte(0xFF)
+	for _, v := range b {
+		crc ^= v
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,28 @@
+// +build linux
+
+package edge_sensors
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// i2cSlave is the ioctl of the i2c-dev driver setting the address of the
+// device to communicate with.
+const i2cSlave = 0x0703
+
+// openI2C is used to mock the devices in tests.
+var openI2C = func(bus int, address uint16) (io.ReadWriteCloser, error) {
+	f, err := os.OpenFile(fmt.Sprintf("/dev/i2c-%d", bus), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), i2cSlave, uintptr(address))
+	if errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("setting address 0x%02x: %s", address, errno)
+	}
+	return f, nil
+}
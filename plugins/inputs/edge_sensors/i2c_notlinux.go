@@ -0,0 +1,13 @@
+// +build !linux
+
+package edge_sensors
+
+import (
+	"fmt"
+	"io"
+)
+
+// openI2C is used to mock the devices in tests.
+var openI2C = func(bus int, address uint16) (io.ReadWriteCloser, error) {
+	return nil, fmt.Errorf("i2c sensors are only supported on linux")
+}
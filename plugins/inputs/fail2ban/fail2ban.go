@@ -44,6 +44,10 @@ func (f *Fail2ban) Description() string {
 	return "Read metrics from fail2ban."
 }
 
+func (f *Fail2ban) Dependencies() []string {
+	return []string{"fail2ban-client"}
+}
+
 func (f *Fail2ban) SampleConfig() string {
 	return sampleConfig
 }
@@ -0,0 +1,153 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+type File struct {
+	Files             []string `toml:"files"`
+	Watch             bool     `toml:"watch"`
+	CharacterEncoding string   `toml:"character_encoding"`
+
+	parser parsers.Parser
+
+	// modTimes are the modification times of the files read, in watch mode.
+	modTimes map[string]time.Time
+}
+
+const sampleConfig = `
+  ## Files to parse each interval.  The globs are expanded each interval, so
+  ## that new files are read too.  They accept standard unix glob matching
+  ## rules, but with the addition of ** as a "super asterisk". ie:
+  ##   /var/log/**.log     -> recursively find all .log files in /var/log
+  ##   /var/log/*/*.log    -> find all .log files with a parent dir in /var/log
+  ##   /var/log/apache.log -> only read the apache log file
+  files = ["/var/log/apache/access.log"]
+
+  ## Only read the files created or modified since the previous interval,
+  ## instead of all the files each interval.
+  # watch = false
+
+  ## Character encoding of the files, converted to UTF-8 before parsing:
+  ## "utf-8", "utf-16le", "utf-16be", "utf-16" (with a byte order mark),
+  ## "iso-8859-1" (or "latin1"), "iso-8859-15", "windows-1252" or "shift_jis".
+  ## Defaults to reading the files as is.  Gzip compressed files are
+  ## decompressed first, whatever their name.
+  # character_encoding = ""
+
+  ## The dataformat to be read from files
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Parse the complete contents of files, with a data format"
+}
+
+func (f *File) SetParser(p parsers.Parser) {
+	f.parser = p
+}
+
+// Validate checks the files and the character encoding.
+func (f *File) Validate() error {
+	for _, file := range f.Files {
+		if _, err := globpath.Compile(file); err != nil {
+			return fmt.Errorf("invalid file %q: %s", file, err)
+		}
+	}
+	_, err := encoding.NewDecoder(f.CharacterEncoding)
+	return err
+}
+
+func (f *File) Gather(acc telegraf.Accumulator) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	decoder, _ := encoding.NewDecoder(f.CharacterEncoding)
+
+	modTimes := make(map[string]time.Time)
+	for _, file := range f.Files {
+		g, _ := globpath.Compile(file)
+		for path, info := range g.Match() {
+			if info.IsDir() {
+				continue
+			}
+			modTimes[path] = info.ModTime()
+			if last, ok := f.modTimes[path]; ok && f.Watch && !info.ModTime().After(last) {
+				continue
+			}
+			if err := f.readMetrics(path, decoder, acc); err != nil {
+				acc.AddError(fmt.Errorf("%s: %s", path, err))
+				// Read the file again at the next interval.
+				delete(modTimes, path)
+			}
+		}
+	}
+	f.modTimes = modTimes
+	return nil
+}
+
+func (f *File) readMetrics(path string, decoder *encoding.Decoder, acc telegraf.Accumulator) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data, err = decompress(data)
+	if err != nil {
+		return err
+	}
+	data, err = decoder.Bytes(data)
+	if err != nil {
+		return err
+	}
+
+	metrics, err := f.parser.Parse(data)
+	if err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	return nil
+}
+
+// decompress decompresses the gzip compressed files, detected by their
+// magic number.
+func decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+	return data, nil
+}
+
+func init() {
+	inputs.Add("file", func() telegraf.Input {
+		return &File{}
+	})
+}
@@ -0,0 +1,152 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newFile(t *testing.T, files ...string) *File {
+	p, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	f := &File{Files: files}
+	f.SetParser(p)
+	return f
+}
+
+func TestGatherGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+	write("a.out", "cpu,host=a usage=1\n")
+
+	f := newFile(t, filepath.Join(dir, "*.out"))
+	acc := &testutil.Accumulator{}
+	require.NoError(t, f.Gather(acc))
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"usage": float64(1)}, map[string]string{"host": "a"})
+	require.Len(t, acc.Metrics, 1)
+
+	// New files are read at the next interval.
+	write("b.out", "cpu,host=b usage=2\n")
+	acc.ClearMetrics()
+	require.NoError(t, f.Gather(acc))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"usage": float64(2)}, map[string]string{"host": "b"})
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.out")
+	require.NoError(t, ioutil.WriteFile(path, []byte("cpu usage=1\n"), 0644))
+
+	f := newFile(t, path)
+	f.Watch = true
+	acc := &testutil.Accumulator{}
+	require.NoError(t, f.Gather(acc))
+	require.Len(t, acc.Metrics, 1)
+
+	// Unchanged files are not read again.
+	acc.ClearMetrics()
+	require.NoError(t, f.Gather(acc))
+	require.Len(t, acc.Metrics, 0)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("cpu usage=2\n"), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	require.NoError(t, f.Gather(acc))
+	acc.AssertContainsFields(t, "cpu", map[string]interface{}{"usage": float64(2)})
+
+	// Files which could not be parsed are read again.
+	require.NoError(t, ioutil.WriteFile(path, []byte("not metrics\n"), 0644))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	acc.ClearMetrics()
+	require.NoError(t, f.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+	require.NoError(t, f.Gather(acc))
+	require.Len(t, acc.Errors, 2)
+}
+
+func TestDecompress(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte("cpu usage=1\n"))
+	w.Close()
+
+	data, err := decompress(buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, "cpu usage=1\n", string(data))
+
+	data, err = decompress([]byte("cpu usage=1\n"))
+	require.NoError(t, err)
+	require.Equal(t, "cpu usage=1\n", string(data))
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		encoding string
+		data     []byte
+	}{
+		{"", []byte("température=1")},
+		{"UTF-8", []byte("\xef\xbb\xbftempérature=1")},
+		{"latin1", []byte("temp\xe9rature=1")},
+		{"windows-1252", []byte("temp\xe9rature=1")},
+		{"utf-16le", []byte("\xff\xfet\x00e\x00m\x00p\x00\xe9\x00r\x00a\x00t\x00u\x00r\x00e\x00=\x001\x00")},
+		{"utf-16le", []byte("t\x00e\x00m\x00p\x00\xe9\x00r\x00a\x00t\x00u\x00r\x00e\x00=\x001\x00")},
+		{"utf-16be", []byte("\x00t\x00e\x00m\x00p\x00\xe9\x00r\x00a\x00t\x00u\x00r\x00e\x00=\x001")},
+		{"utf-16", []byte("\xff\xfet\x00e\x00m\x00p\x00\xe9\x00r\x00a\x00t\x00u\x00r\x00e\x00=\x001\x00")},
+	}
+	for _, tt := range tests {
+		decoder, err := encoding.NewDecoder(tt.encoding)
+		require.NoError(t, err, tt.encoding)
+		data, err := decoder.Bytes(tt.data)
+		require.NoError(t, err, tt.encoding)
+		require.Equal(t, "température=1", string(data), tt.encoding)
+	}
+
+	decoder, err := encoding.NewDecoder("utf-16")
+	require.NoError(t, err)
+	_, err = decoder.Bytes([]byte("t\x00"))
+	require.Error(t, err)
+}
+
+func TestGatherEncodedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.gz")
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte("room,name=caf\xe9 temperature=21.5\n"))
+	w.Close()
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+
+	f := newFile(t, path)
+	f.CharacterEncoding = "iso-8859-1"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, f.Gather(acc))
+	acc.AssertContainsTaggedFields(t, "room", map[string]interface{}{"temperature": 21.5}, map[string]string{"name": "café"})
+}
+
+func TestValidate(t *testing.T) {
+	f := &File{Files: []string{"/tmp/*.out"}, CharacterEncoding: "ebcdic"}
+	require.EqualError(t, f.Validate(), `unsupported character_encoding "ebcdic"`)
+	f.CharacterEncoding = "Latin1"
+	require.NoError(t, f.Validate())
+}
@@ -0,0 +1,402 @@
+package gcp_billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement = "gcp_billing"
+
+	defaultBigQueryURL = "https://www.googleapis.com/bigquery/v2"
+	bigQueryScope      = "https://www.googleapis.com/auth/bigquery.readonly"
+	jwtBearerGrant     = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+// tableRe matches fully qualified BigQuery table names, the table can not be
+// passed as a query parameter.
+var tableRe = regexp.MustCompile(`^[A-Za-z0-9_:.-]+\.[A-Za-z0-9_]+\.[A-Za-z0-9_]+$`)
+
+const query = `
+SELECT
+  CAST(DATE(usage_start_time) AS STRING) AS day,
+  service.description AS service,
+  IFNULL(project.id, '') AS project_id,
+  currency,
+  SUM(cost) AS cost,
+  SUM(IFNULL((SELECT SUM(c.amount) FROM UNNEST(credits) c), 0)) AS credits
+FROM ` + "`%s`" + `
+WHERE usage_start_time >= TIMESTAMP(@start) AND usage_start_time < TIMESTAMP(@end)
+GROUP BY day, service, project_id, currency`
+
+type GCPBilling struct {
+	CredentialsFile string            `toml:"credentials_file"`
+	Project         string            `toml:"project"`
+	Table           string            `toml:"table"`
+	Days            int               `toml:"days"`
+	Timeout         internal.Duration `toml:"timeout"`
+
+	bigQueryURL string
+	account     *serviceAccount
+	client      *http.Client
+	token       string
+	tokenExpiry time.Time
+	now         func() time.Time
+}
+
+var sampleConfig = `
+  ## Service account key file, the account requires the "BigQuery Job User"
+  ## role in the project and read access to the billing export dataset.
+  credentials_file = "/etc/telegraf/gcp-billing.json"
+
+  ## Project to run the query jobs in, defaults to the project of the
+  ## service account.
+  # project = ""
+
+  ## BigQuery table the billing data is exported to.
+  table = "my-project.billing.gcp_billing_export_v1_XXXXXX_XXXXXX_XXXXXX"
+
+  ## Number of days to collect, including the current day.  Costs of the
+  ## recent days are still being exported, so these are collected again.
+  # days = 3
+
+  ## Timeout for API requests and query jobs.
+  # timeout = "60s"
+
+  ## Billing data is exported a few times per day and every query is charged
+  ## by the bytes processed, a daily schedule is recommended over an interval.
+  schedule = "0 6 * * *"
+`
+
+func (g *GCPBilling) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GCPBilling) Description() string {
+	return "Gather daily spend from the Google Cloud billing export in BigQuery"
+}
+
+// serviceAccount is the relevant part of a service account key file.
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type queryParameter struct {
+	Name          string `json:"name"`
+	ParameterType struct {
+		Type string `json:"type"`
+	} `json:"parameterType"`
+	ParameterValue struct {
+		Value string `json:"value"`
+	} `json:"parameterValue"`
+}
+
+type queryRequest struct {
+	Query           string           `json:"query"`
+	UseLegacySQL    bool             `json:"useLegacySql"`
+	ParameterMode   string           `json:"parameterMode"`
+	QueryParameters []queryParameter `json:"queryParameters"`
+	TimeoutMs       int64            `json:"timeoutMs"`
+}
+
+type queryResponse struct {
+	JobComplete  bool `json:"jobComplete"`
+	JobReference struct {
+		ProjectID string `json:"projectId"`
+		JobID     string `json:"jobId"`
+		Location  string `json:"location"`
+	} `json:"jobReference"`
+	Rows []struct {
+		F []struct {
+			V *string `json:"v"`
+		} `json:"f"`
+	} `json:"rows"`
+	PageToken string `json:"pageToken"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type apiErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (g *GCPBilling) Gather(acc telegraf.Accumulator) error {
+	if err := g.init(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := g.refreshToken(ctx); err != nil {
+		return err
+	}
+
+	end := truncateDay(g.now().UTC()).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -g.Days)
+
+	request := &queryRequest{
+		Query:         fmt.Sprintf(query, g.Table),
+		UseLegacySQL:  false,
+		ParameterMode: "NAMED",
+		QueryParameters: []queryParameter{
+			dateParameter("start", start),
+			dateParameter("end", end),
+		},
+		TimeoutMs: int64(g.Timeout.Duration / time.Millisecond),
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp := &queryResponse{}
+	loc := fmt.Sprintf("%s/projects/%s/queries", g.bigQueryURL, url.PathEscape(g.Project))
+	if err := g.do(ctx, "POST", loc, body, resp); err != nil {
+		return err
+	}
+
+	// Wait for the job to complete and fetch the remaining pages.
+	deadline := g.now().Add(g.Timeout.Duration)
+	for {
+		if resp.JobComplete {
+			g.addRows(acc, resp)
+		}
+		if resp.JobComplete && resp.PageToken == "" {
+			return nil
+		}
+		if !resp.JobComplete && g.now().After(deadline) {
+			return fmt.Errorf("query job %s did not complete within %s",
+				resp.JobReference.JobID, g.Timeout.Duration)
+		}
+
+		params := url.Values{}
+		params.Set("timeoutMs", strconv.FormatInt(request.TimeoutMs, 10))
+		if resp.JobReference.Location != "" {
+			params.Set("location", resp.JobReference.Location)
+		}
+		if resp.PageToken != "" {
+			params.Set("pageToken", resp.PageToken)
+		}
+		loc := fmt.Sprintf("%s/projects/%s/queries/%s?%s", g.bigQueryURL,
+			url.PathEscape(resp.JobReference.ProjectID),
+			url.PathEscape(resp.JobReference.JobID), params.Encode())
+
+		resp = &queryResponse{}
+		if err := g.do(ctx, "GET", loc, nil, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (g *GCPBilling) addRows(acc telegraf.Accumulator, resp *queryResponse) {
+	for _, row := range resp.Rows {
+		if len(row.F) != 6 {
+			acc.AddError(fmt.Errorf("unexpected number of columns %d", len(row.F)))
+			continue
+		}
+		values := make([]string, len(row.F))
+		for i, f := range row.F {
+			if f.V != nil {
+				values[i] = *f.V
+			}
+		}
+
+		day, err := time.Parse("2006-01-02", values[0])
+		if err != nil {
+			acc.AddError(fmt.Errorf("invalid day %q", values[0]))
+			continue
+		}
+
+		tags := map[string]string{
+			"service": values[1],
+		}
+		if values[2] != "" {
+			tags["project_id"] = values[2]
+		}
+		if values[3] != "" {
+			tags["currency"] = values[3]
+		}
+
+		fields := make(map[string]interface{}, 2)
+		if cost, err := strconv.ParseFloat(values[4], 64); err == nil {
+			fields["cost"] = cost
+		}
+		if credits, err := strconv.ParseFloat(values[5], 64); err == nil {
+			fields["credits"] = credits
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		acc.AddFields(measurement, fields, tags, day)
+	}
+}
+
+func (g *GCPBilling) init() error {
+	if g.account != nil {
+		return nil
+	}
+
+	if !tableRe.MatchString(g.Table) {
+		return fmt.Errorf("invalid table %q, expected project.dataset.table", g.Table)
+	}
+
+	octets, err := ioutil.ReadFile(g.CredentialsFile)
+	if err != nil {
+		return fmt.Errorf("reading credentials_file failed: %v", err)
+	}
+	account := &serviceAccount{}
+	if err := json.Unmarshal(octets, account); err != nil {
+		return fmt.Errorf("decoding credentials_file failed: %v", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return fmt.Errorf("credentials_file is not a service account key")
+	}
+
+	if g.Project == "" {
+		g.Project = account.ProjectID
+	}
+	g.client = &http.Client{
+		Timeout: g.Timeout.Duration + 10*time.Second,
+	}
+	g.account = account
+	return nil
+}
+
+// refreshToken exchanges a signed JWT for an access token when the current
+// one is about to expire.
+func (g *GCPBilling) refreshToken(ctx context.Context) error {
+	if g.token != "" && g.now().Before(g.tokenExpiry) {
+		return nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(g.account.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("parsing private key failed: %v", err)
+	}
+
+	now := g.now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   g.account.ClientEmail,
+		"scope": bigQueryScope,
+		"aud":   g.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", jwtBearerGrant)
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequest("POST", g.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+
+	token := &tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(token); err != nil {
+		return err
+	}
+	g.token = token.AccessToken
+	// Renew the token a minute before it expires.
+	g.tokenExpiry = now.Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return nil
+}
+
+func (g *GCPBilling) do(ctx context.Context, method, loc string, body []byte, v interface{}) error {
+	req, err := http.NewRequest(method, loc, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		g.token = ""
+	}
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func apiError(resp *http.Response) error {
+	errResp := &apiErrorResponse{}
+	json.NewDecoder(resp.Body).Decode(errResp)
+	switch {
+	case errResp.Error.Message != "":
+		return fmt.Errorf("%s: %s", resp.Status, errResp.Error.Message)
+	case errResp.ErrorDescription != "":
+		return fmt.Errorf("%s: %s", resp.Status, errResp.ErrorDescription)
+	default:
+		return fmt.Errorf("%s", resp.Status)
+	}
+}
+
+func dateParameter(name string, t time.Time) queryParameter {
+	p := queryParameter{Name: name}
+	p.ParameterType.Type = "DATE"
+	p.ParameterValue.Value = t.Format("2006-01-02")
+	return p
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func init() {
+	inputs.Add("gcp_billing", func() telegraf.Input {
+		return &GCPBilling{
+			Days:        3,
+			Timeout:     internal.Duration{Duration: 60 * time.Second},
+			bigQueryURL: defaultBigQueryURL,
+			now:         time.Now,
+		}
+	})
+}
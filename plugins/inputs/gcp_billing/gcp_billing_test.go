@@ -0,0 +1,143 @@
+package gcp_billing
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const queryResult = `{
+  "jobComplete": true,
+  "jobReference": {"projectId": "my-project", "jobId": "job1", "location": "US"},
+  "rows": [
+    {"f": [{"v": "2018-06-01"}, {"v": "Compute Engine"}, {"v": "web"}, {"v": "USD"}, {"v": "12.5"}, {"v": "-2.5"}]}
+  ],
+  "pageToken": "page2"
+}`
+
+const queryResultPage2 = `{
+  "jobComplete": true,
+  "jobReference": {"projectId": "my-project", "jobId": "job1", "location": "US"},
+  "rows": [
+    {"f": [{"v": "2018-06-02"}, {"v": "Cloud Storage"}, {"v": null}, {"v": "USD"}, {"v": "0.5"}, {"v": "0"}]}
+  ]
+}`
+
+func writeCredentials(t *testing.T, key *rsa.PrivateKey, tokenURI string) string {
+	pemKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	octets, err := json.Marshal(&serviceAccount{
+		ProjectID:   "my-project",
+		ClientEmail: "telegraf@my-project.iam.gserviceaccount.com",
+		PrivateKey:  string(pemKey),
+		TokenURI:    tokenURI,
+	})
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "gcp_billing")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write(octets)
+	require.NoError(t, err)
+	return f.Name()
+}
+
+func TestGather(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, jwtBearerGrant, r.PostForm.Get("grant_type"))
+			// The claims are issued at the fixed test time and are expired.
+			parser := &jwt.Parser{SkipClaimsValidation: true}
+			token, err := parser.Parse(r.PostForm.Get("assertion"),
+				func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil })
+			require.NoError(t, err)
+			claims := token.Claims.(jwt.MapClaims)
+			require.Equal(t, "telegraf@my-project.iam.gserviceaccount.com", claims["iss"])
+			require.Equal(t, ts.URL+"/token", claims["aud"])
+			w.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+		case "/projects/my-project/queries":
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			request := &queryRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+			require.Contains(t, request.Query, "`my-project.billing.export`")
+			require.Equal(t, "2018-06-01", request.QueryParameters[0].ParameterValue.Value)
+			require.Equal(t, "2018-06-03", request.QueryParameters[1].ParameterValue.Value)
+			w.Write([]byte(queryResult))
+		case "/projects/my-project/queries/job1":
+			require.Equal(t, "page2", r.URL.Query().Get("pageToken"))
+			require.Equal(t, "US", r.URL.Query().Get("location"))
+			w.Write([]byte(queryResultPage2))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	credentials := writeCredentials(t, key, ts.URL+"/token")
+	defer os.Remove(credentials)
+
+	plugin := &GCPBilling{
+		CredentialsFile: credentials,
+		Table:           "my-project.billing.export",
+		Days:            2,
+		Timeout:         internal.Duration{Duration: time.Second},
+		bigQueryURL:     ts.URL,
+		now: func() time.Time {
+			return time.Date(2018, 6, 2, 12, 0, 0, 0, time.UTC)
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	acc.AssertContainsTaggedFields(t, "gcp_billing",
+		map[string]interface{}{
+			"cost":    12.5,
+			"credits": -2.5,
+		},
+		map[string]string{
+			"service":    "Compute Engine",
+			"project_id": "web",
+			"currency":   "USD",
+		})
+	acc.AssertContainsTaggedFields(t, "gcp_billing",
+		map[string]interface{}{
+			"cost":    0.5,
+			"credits": 0.0,
+		},
+		map[string]string{
+			"service":  "Cloud Storage",
+			"currency": "USD",
+		})
+}
+
+func TestGatherInvalidTable(t *testing.T) {
+	plugin := &GCPBilling{
+		Table: "billing; DROP TABLE export",
+		now:   time.Now,
+	}
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(plugin.Gather))
+}
@@ -0,0 +1,385 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultBaseURL = "https://api.github.com"
+
+type GitHub struct {
+	Repositories       []string          `toml:"repositories"`
+	AccessToken        string            `toml:"access_token"`
+	EnterpriseBaseURL  string            `toml:"enterprise_base_url"`
+	GatherWorkflowRuns bool              `toml:"gather_workflow_runs"`
+	HTTPTimeout        internal.Duration `toml:"http_timeout"`
+
+	client *http.Client
+	now    func() time.Time
+
+	// The rate limit as reported by the last response.  No requests are sent
+	// while the limit is exhausted and the reset time has not passed.
+	rateLimit     int
+	rateRemaining int
+	rateReset     time.Time
+
+	// Update time of the most recent completed workflow run reported for
+	// each repository.
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+}
+
+var sampleConfig = `
+  ## List of repositories to monitor, as "owner/repository".
+  repositories = ["influxdata/telegraf"]
+
+  ## Personal access token for the GitHub API.  Unauthenticated requests are
+  ## limited to 60 per hour.
+  # access_token = ""
+
+  ## Base URL of the API of a GitHub Enterprise server, such as
+  ## "https://github.example.com/api/v3".
+  # enterprise_base_url = ""
+
+  ## Gather the GitHub Actions workflow runs that completed since the last
+  ## gather, as well as the number of queued and in progress runs.
+  # gather_workflow_runs = true
+
+  ## Timeout for HTTP requests.
+  # http_timeout = "5s"
+`
+
+func (g *GitHub) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GitHub) Description() string {
+	return "Gather repository and workflow run statistics from GitHub"
+}
+
+type repository struct {
+	StargazersCount  int    `json:"stargazers_count"`
+	ForksCount       int    `json:"forks_count"`
+	SubscribersCount int    `json:"subscribers_count"`
+	OpenIssuesCount  int    `json:"open_issues_count"`
+	Size             int    `json:"size"`
+	Language         string `json:"language"`
+	License          *struct {
+		SpdxID string `json:"spdx_id"`
+	} `json:"license"`
+}
+
+type workflowRun struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	RunNumber    int       `json:"run_number"`
+	Event        string    `json:"event"`
+	Status       string    `json:"status"`
+	Conclusion   string    `json:"conclusion"`
+	HeadBranch   string    `json:"head_branch"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	RunStartedAt time.Time `json:"run_started_at"`
+}
+
+type workflowRunsResponse struct {
+	TotalCount   int           `json:"total_count"`
+	WorkflowRuns []workflowRun `json:"workflow_runs"`
+}
+
+type apiErrorResponse struct {
+	Message string `json:"message"`
+}
+
+func (g *GitHub) Gather(acc telegraf.Accumulator) error {
+	if g.client == nil {
+		g.client = &http.Client{
+			Timeout: g.HTTPTimeout.Duration,
+		}
+	}
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for _, fullName := range g.Repositories {
+		parts := strings.SplitN(fullName, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			acc.AddError(fmt.Errorf("invalid repository %q, expected \"owner/repository\"", fullName))
+			continue
+		}
+
+		wg.Add(1)
+		go func(owner, name string) {
+			defer wg.Done()
+			if err := g.gatherRepository(ctx, acc, owner, name); err != nil {
+				acc.AddError(fmt.Errorf("[%s/%s]: %s", owner, name, err))
+			}
+		}(parts[0], parts[1])
+	}
+	wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.rateLimit != 0 {
+		fields := map[string]interface{}{
+			"limit":     g.rateLimit,
+			"remaining": g.rateRemaining,
+		}
+		acc.AddFields("github_rate_limit", fields, nil)
+	}
+	return nil
+}
+
+func (g *GitHub) gatherRepository(ctx context.Context, acc telegraf.Accumulator, owner, name string) error {
+	path := fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(name))
+
+	repo := &repository{}
+	if _, err := g.get(ctx, path, repo); err != nil {
+		return err
+	}
+
+	var pulls []json.RawMessage
+	resp, err := g.get(ctx, path+"/pulls?state=open&per_page=1", &pulls)
+	if err != nil {
+		return err
+	}
+	openPulls := lastPage(resp.Header.Get("Link"))
+	if openPulls == 0 {
+		openPulls = len(pulls)
+	}
+
+	tags := map[string]string{
+		"owner": owner,
+		"name":  name,
+	}
+	if repo.Language != "" {
+		tags["language"] = repo.Language
+	}
+	if repo.License != nil && repo.License.SpdxID != "" {
+		tags["license"] = repo.License.SpdxID
+	}
+
+	// The open issues count of GitHub includes the pull requests.
+	fields := map[string]interface{}{
+		"stars":              repo.StargazersCount,
+		"forks":              repo.ForksCount,
+		"watchers":           repo.SubscribersCount,
+		"open_issues":        repo.OpenIssuesCount - openPulls,
+		"open_pull_requests": openPulls,
+		"size":               repo.Size,
+	}
+
+	if g.GatherWorkflowRuns {
+		for _, status := range []string{"queued", "in_progress"} {
+			runs := &workflowRunsResponse{}
+			_, err := g.get(ctx, path+"/actions/runs?per_page=1&status="+status, runs)
+			if err != nil {
+				return err
+			}
+			fields["workflow_runs_"+status] = runs.TotalCount
+		}
+
+		if err := g.gatherWorkflowRuns(ctx, acc, path, owner, name); err != nil {
+			return err
+		}
+	}
+
+	acc.AddFields("github_repository", fields, tags)
+	return nil
+}
+
+// gatherWorkflowRuns adds the workflow runs that completed since the last
+// gather.  Only the most recent page of runs is examined.
+func (g *GitHub) gatherWorkflowRuns(ctx context.Context, acc telegraf.Accumulator, path, owner, name string) error {
+	runs := &workflowRunsResponse{}
+	_, err := g.get(ctx, path+"/actions/runs?per_page=100&status=completed", runs)
+	if err != nil {
+		return err
+	}
+
+	key := owner + "/" + name
+	g.mu.Lock()
+	since := g.lastRun[key]
+	g.mu.Unlock()
+
+	latest := since
+	for _, run := range runs.WorkflowRuns {
+		if !run.UpdatedAt.After(since) {
+			continue
+		}
+		if run.UpdatedAt.After(latest) {
+			latest = run.UpdatedAt
+		}
+
+		started := run.RunStartedAt
+		if started.IsZero() {
+			started = run.CreatedAt
+		}
+
+		tags := map[string]string{
+			"owner":    owner,
+			"name":     name,
+			"workflow": run.Name,
+			"branch":   run.HeadBranch,
+			"event":    run.Event,
+		}
+		if run.Conclusion != "" {
+			tags["conclusion"] = run.Conclusion
+		}
+		fields := map[string]interface{}{
+			"run_id":           run.ID,
+			"run_number":       run.RunNumber,
+			"queued_seconds":   started.Sub(run.CreatedAt).Seconds(),
+			"duration_seconds": run.UpdatedAt.Sub(started).Seconds(),
+		}
+		acc.AddFields("github_workflow_run", fields, tags, run.CreatedAt)
+	}
+
+	g.mu.Lock()
+	g.lastRun[key] = latest
+	g.mu.Unlock()
+	return nil
+}
+
+// get requests the path from the API and decodes the JSON response into v.
+func (g *GitHub) get(ctx context.Context, path string, v interface{}) (*http.Response, error) {
+	g.mu.Lock()
+	if g.rateRemaining == 0 && g.now().Before(g.rateReset) {
+		reset := g.rateReset
+		g.mu.Unlock()
+		return nil, fmt.Errorf("API rate limit exceeded, resets at %s",
+			reset.Format(time.RFC3339))
+	}
+	g.mu.Unlock()
+
+	baseURL := defaultBaseURL
+	if g.EnterpriseBaseURL != "" {
+		baseURL = strings.TrimSuffix(g.EnterpriseBaseURL, "/")
+	}
+
+	req, err := http.NewRequest("GET", baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", "telegraf")
+	if g.AccessToken != "" {
+		req.Header.Set("Authorization", "token "+g.AccessToken)
+	}
+
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	g.updateRateLimit(resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &apiErrorResponse{}
+		json.NewDecoder(resp.Body).Decode(errResp)
+		if errResp.Message != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, errResp.Message)
+		}
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (g *GitHub) updateRateLimit(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rateLimit = limit
+	g.rateRemaining = remaining
+	g.rateReset = time.Unix(reset, 0)
+}
+
+var lastPageRe = regexp.MustCompile(`[?&]page=(\d+)[^>]*>;\s*rel="last"`)
+
+// lastPage returns the number of the last page from the Link header, or 0 if
+// there is no last page.
+func lastPage(link string) int {
+	for _, part := range strings.Split(link, ",") {
+		m := lastPageRe.FindStringSubmatch(part)
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// GetState returns the update time of the last reported workflow run of each
+// repository.
+func (g *GitHub) GetState() interface{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	state := make(map[string]string, len(g.lastRun))
+	for repo, t := range g.lastRun {
+		state[repo] = t.Format(time.RFC3339)
+	}
+	return state
+}
+
+func (g *GitHub) SetState(state interface{}) error {
+	repos, ok := state.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid state type %T", state)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for repo, v := range repos {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("invalid time for %s: %v", repo, v)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		g.lastRun[repo] = t
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("github", func() telegraf.Input {
+		return &GitHub{
+			GatherWorkflowRuns: true,
+			HTTPTimeout:        internal.Duration{Duration: 5 * time.Second},
+			now:                time.Now,
+			lastRun:            make(map[string]time.Time),
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const repositoryResponse = `{
+  "stargazers_count": 100,
+  "forks_count": 20,
+  "subscribers_count": 10,
+  "open_issues_count": 15,
+  "size": 2048,
+  "language": "Go",
+  "license": {"spdx_id": "MIT"}
+}`
+
+const completedRunsResponse = `{
+  "total_count": 2,
+  "workflow_runs": [
+    {
+      "id": 2,
+      "name": "CI",
+      "run_number": 8,
+      "event": "push",
+      "status": "completed",
+      "conclusion": "failure",
+      "head_branch": "master",
+      "created_at": "2018-06-01T12:10:00Z",
+      "updated_at": "2018-06-01T12:20:00Z",
+      "run_started_at": "2018-06-01T12:11:00Z"
+    },
+    {
+      "id": 1,
+      "name": "CI",
+      "run_number": 7,
+      "event": "pull_request",
+      "status": "completed",
+      "conclusion": "success",
+      "head_branch": "feature",
+      "created_at": "2018-06-01T12:00:00Z",
+      "updated_at": "2018-06-01T12:05:00Z",
+      "run_started_at": "2018-06-01T12:00:30Z"
+    }
+  ]
+}`
+
+func newTestServer(t *testing.T, requests *int) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "token secret", r.Header.Get("Authorization"))
+		*requests++
+
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Reset", "1527854400")
+
+		switch r.URL.Path {
+		case "/repos/influxdata/telegraf":
+			w.Write([]byte(repositoryResponse))
+		case "/repos/influxdata/telegraf/pulls":
+			require.Equal(t, "open", r.URL.Query().Get("state"))
+			w.Header().Set("Link", fmt.Sprintf(
+				`<%s/repos/influxdata/telegraf/pulls?state=open&per_page=1&page=2>; rel="next", `+
+					`<%s/repos/influxdata/telegraf/pulls?state=open&per_page=1&page=5>; rel="last"`,
+				ts.URL, ts.URL))
+			w.Write([]byte(`[{"number": 1}]`))
+		case "/repos/influxdata/telegraf/actions/runs":
+			switch r.URL.Query().Get("status") {
+			case "queued":
+				w.Write([]byte(`{"total_count": 3, "workflow_runs": []}`))
+			case "in_progress":
+				w.Write([]byte(`{"total_count": 1, "workflow_runs": []}`))
+			case "completed":
+				w.Write([]byte(completedRunsResponse))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+		}
+	}))
+	return ts
+}
+
+func newGitHub(url string) *GitHub {
+	return &GitHub{
+		Repositories:       []string{"influxdata/telegraf"},
+		AccessToken:        "secret",
+		EnterpriseBaseURL:  url,
+		GatherWorkflowRuns: true,
+		now: func() time.Time {
+			return time.Date(2018, 6, 1, 12, 30, 0, 0, time.UTC)
+		},
+		lastRun: make(map[string]time.Time),
+	}
+}
+
+func TestGather(t *testing.T) {
+	var requests int
+	ts := newTestServer(t, &requests)
+	defer ts.Close()
+
+	g := newGitHub(ts.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(g.Gather))
+
+	acc.AssertContainsTaggedFields(t, "github_repository",
+		map[string]interface{}{
+			"stars":                     100,
+			"forks":                     20,
+			"watchers":                  10,
+			"open_issues":               10,
+			"open_pull_requests":        5,
+			"size":                      2048,
+			"workflow_runs_queued":      3,
+			"workflow_runs_in_progress": 1,
+		},
+		map[string]string{
+			"owner":    "influxdata",
+			"name":     "telegraf",
+			"language": "Go",
+			"license":  "MIT",
+		})
+
+	acc.AssertContainsTaggedFields(t, "github_workflow_run",
+		map[string]interface{}{
+			"run_id":           int64(2),
+			"run_number":       8,
+			"queued_seconds":   60.0,
+			"duration_seconds": 540.0,
+		},
+		map[string]string{
+			"owner":      "influxdata",
+			"name":       "telegraf",
+			"workflow":   "CI",
+			"branch":     "master",
+			"event":      "push",
+			"conclusion": "failure",
+		})
+	require.Len(t, acc.Metrics, 4)
+
+	acc.AssertContainsFields(t, "github_rate_limit",
+		map[string]interface{}{
+			"limit":     5000,
+			"remaining": 4990,
+		})
+
+	// Runs that have already been reported are skipped.
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(g.Gather))
+	require.False(t, acc.HasMeasurement("github_workflow_run"))
+	require.True(t, acc.HasMeasurement("github_repository"))
+}
+
+func TestGatherRateLimitExceeded(t *testing.T) {
+	var requests int
+	ts := newTestServer(t, &requests)
+	defer ts.Close()
+
+	g := newGitHub(ts.URL)
+	g.rateLimit = 60
+	g.rateRemaining = 0
+	g.rateReset = g.now().Add(time.Minute)
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Equal(t, 0, requests)
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "rate limit exceeded")
+
+	// Requests resume after the reset time.
+	g.rateReset = g.now().Add(-time.Minute)
+	acc = testutil.Accumulator{}
+	require.NoError(t, acc.GatherError(g.Gather))
+	require.NotEqual(t, 0, requests)
+}
+
+func TestGatherInvalidRepository(t *testing.T) {
+	g := newGitHub("http://localhost")
+	g.Repositories = []string{"telegraf"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+}
+
+func TestLastPage(t *testing.T) {
+	require.Equal(t, 0, lastPage(""))
+	require.Equal(t, 34, lastPage(
+		`<https://api.github.com/repositories/1/pulls?page=2>; rel="next", `+
+			`<https://api.github.com/repositories/1/pulls?page=34>; rel="last"`))
+	require.Equal(t, 0, lastPage(
+		`<https://api.github.com/repositories/1/pulls?page=1>; rel="prev"`))
+}
+
+func TestState(t *testing.T) {
+	g := newGitHub("http://localhost")
+	g.lastRun["influxdata/telegraf"] = time.Date(2018, 6, 1, 12, 20, 0, 0, time.UTC)
+
+	state := g.GetState()
+	require.Equal(t, map[string]string{
+		"influxdata/telegraf": "2018-06-01T12:20:00Z",
+	}, state)
+
+	restored := newGitHub("http://localhost")
+	require.NoError(t, restored.SetState(map[string]interface{}{
+		"influxdata/telegraf": "2018-06-01T12:20:00Z",
+	}))
+	require.Equal(t, g.lastRun, restored.lastRun)
+
+	require.Error(t, restored.SetState([]interface{}{}))
+	require.Error(t, restored.SetState(map[string]interface{}{
+		"influxdata/telegraf": 42.0,
+	}))
+}
@@ -0,0 +1,397 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultURL = "https://gitlab.com"
+
+type GitLab struct {
+	Projects        []string          `toml:"projects"`
+	URL             string            `toml:"url"`
+	PrivateToken    string            `toml:"private_token"`
+	GatherPipelines bool              `toml:"gather_pipelines"`
+	HTTPTimeout     internal.Duration `toml:"http_timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+	now    func() time.Time
+
+	// The rate limit as reported by the last response.  No requests are sent
+	// while the limit is exhausted and the reset time has not passed.
+	rateLimit     int
+	rateRemaining int
+	rateReset     time.Time
+
+	// Update time of the most recent finished pipeline reported for each
+	// project.
+	mu           sync.Mutex
+	lastPipeline map[string]time.Time
+}
+
+var sampleConfig = `
+  ## List of projects to monitor, as "namespace/project".
+  projects = ["gitlab-org/gitlab-ce"]
+
+  ## URL of the GitLab server.
+  # url = "https://gitlab.com"
+
+  ## Personal access token with the "read_api" or "api" scope.
+  # private_token = ""
+
+  ## Gather the pipelines that finished since the last gather, as well as the
+  ## number of pending and running pipelines and pending jobs.
+  # gather_pipelines = true
+
+  ## Timeout for HTTP requests.
+  # http_timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (g *GitLab) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GitLab) Description() string {
+	return "Gather project and pipeline statistics from GitLab"
+}
+
+type project struct {
+	StarCount       int `json:"star_count"`
+	ForksCount      int `json:"forks_count"`
+	OpenIssuesCount int `json:"open_issues_count"`
+}
+
+type pipeline struct {
+	ID             int64      `json:"id"`
+	Status         string     `json:"status"`
+	Ref            string     `json:"ref"`
+	Source         string     `json:"source"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	StartedAt      *time.Time `json:"started_at"`
+	Duration       *float64   `json:"duration"`
+	QueuedDuration *float64   `json:"queued_duration"`
+}
+
+type apiErrorResponse struct {
+	Message interface{} `json:"message"`
+	Error   string      `json:"error"`
+}
+
+func (g *GitLab) Gather(acc telegraf.Accumulator) error {
+	if g.client == nil {
+		tlsCfg, err := g.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		g.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: g.HTTPTimeout.Duration,
+		}
+	}
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for _, name := range g.Projects {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := g.gatherProject(ctx, acc, name); err != nil {
+				acc.AddError(fmt.Errorf("[%s]: %s", name, err))
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.rateLimit != 0 {
+		fields := map[string]interface{}{
+			"limit":     g.rateLimit,
+			"remaining": g.rateRemaining,
+		}
+		acc.AddFields("gitlab_rate_limit", fields, nil)
+	}
+	return nil
+}
+
+func (g *GitLab) gatherProject(ctx context.Context, acc telegraf.Accumulator, name string) error {
+	// The path of the project is passed as a single, encoded, path segment.
+	path := "/projects/" + url.PathEscape(name)
+
+	proj := &project{}
+	if _, err := g.get(ctx, path, proj); err != nil {
+		return err
+	}
+
+	openMergeRequests, err := g.count(ctx, path+"/merge_requests?state=opened")
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]string{
+		"project": name,
+	}
+	fields := map[string]interface{}{
+		"stars":               proj.StarCount,
+		"forks":               proj.ForksCount,
+		"open_issues":         proj.OpenIssuesCount,
+		"open_merge_requests": openMergeRequests,
+	}
+
+	if g.GatherPipelines {
+		counts := []struct {
+			field string
+			query string
+		}{
+			{"pipelines_pending", "/pipelines?status=pending"},
+			{"pipelines_running", "/pipelines?status=running"},
+			{"jobs_pending", "/jobs?scope=pending"},
+		}
+		for _, c := range counts {
+			n, err := g.count(ctx, path+c.query)
+			if err != nil {
+				return err
+			}
+			fields[c.field] = n
+		}
+
+		if err := g.gatherPipelines(ctx, acc, path, name); err != nil {
+			return err
+		}
+	}
+
+	acc.AddFields("gitlab_project", fields, tags)
+	return nil
+}
+
+// gatherPipelines adds the pipelines that finished since the last gather.
+// Only the most recent page of pipelines is examined.
+func (g *GitLab) gatherPipelines(ctx context.Context, acc telegraf.Accumulator, path, name string) error {
+	g.mu.Lock()
+	since := g.lastPipeline[name]
+	g.mu.Unlock()
+
+	params := url.Values{}
+	params.Set("scope", "finished")
+	params.Set("order_by", "updated_at")
+	params.Set("per_page", "100")
+	if !since.IsZero() {
+		params.Set("updated_after", since.Format(time.RFC3339))
+	}
+
+	var pipelines []pipeline
+	if _, err := g.get(ctx, path+"/pipelines?"+params.Encode(), &pipelines); err != nil {
+		return err
+	}
+
+	latest := since
+	for _, p := range pipelines {
+		if !p.UpdatedAt.After(since) {
+			continue
+		}
+
+		// The pipeline list does not include the durations.
+		detail := &pipeline{}
+		_, err := g.get(ctx, fmt.Sprintf("%s/pipelines/%d", path, p.ID), detail)
+		if err != nil {
+			return err
+		}
+		if p.UpdatedAt.After(latest) {
+			latest = p.UpdatedAt
+		}
+
+		tags := map[string]string{
+			"project": name,
+			"ref":     detail.Ref,
+			"status":  detail.Status,
+		}
+		if detail.Source != "" {
+			tags["source"] = detail.Source
+		}
+		fields := map[string]interface{}{
+			"pipeline_id": detail.ID,
+		}
+		if detail.Duration != nil {
+			fields["duration_seconds"] = *detail.Duration
+		}
+		if detail.QueuedDuration != nil {
+			fields["queued_seconds"] = *detail.QueuedDuration
+		} else if detail.StartedAt != nil {
+			fields["queued_seconds"] = detail.StartedAt.Sub(detail.CreatedAt).Seconds()
+		}
+		acc.AddFields("gitlab_pipeline", fields, tags, detail.CreatedAt)
+	}
+
+	g.mu.Lock()
+	g.lastPipeline[name] = latest
+	g.mu.Unlock()
+	return nil
+}
+
+// count returns the total number of items of a list, as reported by the
+// X-Total header.
+func (g *GitLab) count(ctx context.Context, path string) (int, error) {
+	var items []json.RawMessage
+	resp, err := g.get(ctx, path+"&per_page=1", &items)
+	if err != nil {
+		return 0, err
+	}
+
+	total := resp.Header.Get("X-Total")
+	if total == "" {
+		// The total is omitted for lists of more than 10000 items.
+		return 0, fmt.Errorf("total count not available for %s", path)
+	}
+	return strconv.Atoi(total)
+}
+
+// get requests the path from the API and decodes the JSON response into v.
+func (g *GitLab) get(ctx context.Context, path string, v interface{}) (*http.Response, error) {
+	g.mu.Lock()
+	if g.rateRemaining == 0 && g.now().Before(g.rateReset) {
+		reset := g.rateReset
+		g.mu.Unlock()
+		return nil, fmt.Errorf("API rate limit exceeded, resets at %s",
+			reset.Format(time.RFC3339))
+	}
+	g.mu.Unlock()
+
+	baseURL := defaultURL
+	if g.URL != "" {
+		baseURL = strings.TrimSuffix(g.URL, "/")
+	}
+
+	req, err := http.NewRequest("GET", baseURL+"/api/v4"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "telegraf")
+	if g.PrivateToken != "" {
+		req.Header.Set("Private-Token", g.PrivateToken)
+	}
+
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	g.updateRateLimit(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &apiErrorResponse{}
+		json.NewDecoder(resp.Body).Decode(errResp)
+		if errResp.Message != nil {
+			return nil, fmt.Errorf("%s: %v", resp.Status, errResp.Message)
+		}
+		if errResp.Error != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, errResp.Error)
+		}
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (g *GitLab) updateRateLimit(resp *http.Response) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err == nil {
+			g.rateRemaining = 0
+			g.rateReset = g.now().Add(time.Duration(retryAfter) * time.Second)
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(resp.Header.Get("RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	g.rateLimit = limit
+	g.rateRemaining = remaining
+	g.rateReset = time.Unix(reset, 0)
+}
+
+// GetState returns the update time of the last reported pipeline of each
+// project.
+func (g *GitLab) GetState() interface{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	state := make(map[string]string, len(g.lastPipeline))
+	for name, t := range g.lastPipeline {
+		state[name] = t.Format(time.RFC3339Nano)
+	}
+	return state
+}
+
+func (g *GitLab) SetState(state interface{}) error {
+	projects, ok := state.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid state type %T", state)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for name, v := range projects {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("invalid time for %s: %v", name, v)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		g.lastPipeline[name] = t
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("gitlab", func() telegraf.Input {
+		return &GitLab{
+			URL:             defaultURL,
+			GatherPipelines: true,
+			HTTPTimeout:     internal.Duration{Duration: 5 * time.Second},
+			now:             time.Now,
+			lastPipeline:    make(map[string]time.Time),
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package gitlab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const projectResponse = `{
+  "id": 13083,
+  "path_with_namespace": "gitlab-org/gitlab-ce",
+  "star_count": 100,
+  "forks_count": 20,
+  "open_issues_count": 15
+}`
+
+const pipelinesResponse = `[
+  {"id": 2, "status": "failed", "ref": "master", "created_at": "2018-06-01T12:10:00.000Z", "updated_at": "2018-06-01T12:20:00.000Z"},
+  {"id": 1, "status": "success", "ref": "feature", "created_at": "2018-06-01T12:00:00.000Z", "updated_at": "2018-06-01T12:05:00.000Z"}
+]`
+
+const pipeline2Response = `{
+  "id": 2,
+  "status": "failed",
+  "ref": "master",
+  "source": "push",
+  "created_at": "2018-06-01T12:10:00.000Z",
+  "updated_at": "2018-06-01T12:20:00.000Z",
+  "started_at": "2018-06-01T12:11:00.000Z",
+  "finished_at": "2018-06-01T12:20:00.000Z",
+  "duration": 540
+}`
+
+const pipeline1Response = `{
+  "id": 1,
+  "status": "success",
+  "ref": "feature",
+  "source": "merge_request_event",
+  "created_at": "2018-06-01T12:00:00.000Z",
+  "updated_at": "2018-06-01T12:05:00.000Z",
+  "started_at": "2018-06-01T12:00:30.000Z",
+  "finished_at": "2018-06-01T12:05:00.000Z",
+  "duration": 270,
+  "queued_duration": 25.5
+}`
+
+func newTestServer(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.Header.Get("Private-Token"))
+		*requests++
+
+		w.Header().Set("RateLimit-Limit", "600")
+		w.Header().Set("RateLimit-Remaining", "590")
+		w.Header().Set("RateLimit-Reset", "1527854400")
+
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/gitlab-org%2Fgitlab-ce":
+			w.Write([]byte(projectResponse))
+		case "/api/v4/projects/gitlab-org%2Fgitlab-ce/merge_requests":
+			require.Equal(t, "opened", r.URL.Query().Get("state"))
+			w.Header().Set("X-Total", "5")
+			w.Write([]byte(`[{"iid": 1}]`))
+		case "/api/v4/projects/gitlab-org%2Fgitlab-ce/pipelines":
+			switch r.URL.Query().Get("status") {
+			case "pending":
+				w.Header().Set("X-Total", "3")
+				w.Write([]byte(`[]`))
+			case "running":
+				w.Header().Set("X-Total", "1")
+				w.Write([]byte(`[]`))
+			default:
+				require.Equal(t, "finished", r.URL.Query().Get("scope"))
+				w.Write([]byte(pipelinesResponse))
+			}
+		case "/api/v4/projects/gitlab-org%2Fgitlab-ce/pipelines/1":
+			w.Write([]byte(pipeline1Response))
+		case "/api/v4/projects/gitlab-org%2Fgitlab-ce/pipelines/2":
+			w.Write([]byte(pipeline2Response))
+		case "/api/v4/projects/gitlab-org%2Fgitlab-ce/jobs":
+			require.Equal(t, "pending", r.URL.Query().Get("scope"))
+			w.Header().Set("X-Total", "7")
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "404 Project Not Found"}`))
+		}
+	}))
+}
+
+func newGitLab(url string) *GitLab {
+	return &GitLab{
+		Projects:        []string{"gitlab-org/gitlab-ce"},
+		URL:             url,
+		PrivateToken:    "secret",
+		GatherPipelines: true,
+		now: func() time.Time {
+			return time.Date(2018, 6, 1, 12, 30, 0, 0, time.UTC)
+		},
+		lastPipeline: make(map[string]time.Time),
+	}
+}
+
+func TestGather(t *testing.T) {
+	var requests int
+	ts := newTestServer(t, &requests)
+	defer ts.Close()
+
+	g := newGitLab(ts.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(g.Gather))
+
+	acc.AssertContainsTaggedFields(t, "gitlab_project",
+		map[string]interface{}{
+			"stars":               100,
+			"forks":               20,
+			"open_issues":         15,
+			"open_merge_requests": 5,
+			"pipelines_pending":   3,
+			"pipelines_running":   1,
+			"jobs_pending":        7,
+		},
+		map[string]string{
+			"project": "gitlab-org/gitlab-ce",
+		})
+
+	acc.AssertContainsTaggedFields(t, "gitlab_pipeline",
+		map[string]interface{}{
+			"pipeline_id":      int64(2),
+			"duration_seconds": 540.0,
+			"queued_seconds":   60.0,
+		},
+		map[string]string{
+			"project": "gitlab-org/gitlab-ce",
+			"ref":     "master",
+			"status":  "failed",
+			"source":  "push",
+		})
+	acc.AssertContainsTaggedFields(t, "gitlab_pipeline",
+		map[string]interface{}{
+			"pipeline_id":      int64(1),
+			"duration_seconds": 270.0,
+			"queued_seconds":   25.5,
+		},
+		map[string]string{
+			"project": "gitlab-org/gitlab-ce",
+			"ref":     "feature",
+			"status":  "success",
+			"source":  "merge_request_event",
+		})
+
+	acc.AssertContainsFields(t, "gitlab_rate_limit",
+		map[string]interface{}{
+			"limit":     600,
+			"remaining": 590,
+		})
+	require.Len(t, acc.Metrics, 4)
+
+	// Pipelines that have already been reported are skipped.
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(g.Gather))
+	require.False(t, acc.HasMeasurement("gitlab_pipeline"))
+	require.True(t, acc.HasMeasurement("gitlab_project"))
+}
+
+func TestGatherNotFound(t *testing.T) {
+	var requests int
+	ts := newTestServer(t, &requests)
+	defer ts.Close()
+
+	g := newGitLab(ts.URL)
+	g.Projects = []string{"gitlab-org/missing"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "404 Project Not Found")
+}
+
+func TestGatherTooManyRequests(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	g := newGitLab(ts.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Equal(t, 1, requests)
+	require.Equal(t, g.now().Add(time.Minute), g.rateReset)
+
+	// No requests are sent until the reset time.
+	acc = testutil.Accumulator{}
+	require.NoError(t, g.Gather(&acc))
+	require.Equal(t, 1, requests)
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "rate limit exceeded")
+}
+
+func TestState(t *testing.T) {
+	g := newGitLab("http://localhost")
+	g.lastPipeline["gitlab-org/gitlab-ce"] = time.Date(2018, 6, 1, 12, 20, 0, 500000000, time.UTC)
+
+	state := g.GetState()
+	require.Equal(t, map[string]string{
+		"gitlab-org/gitlab-ce": "2018-06-01T12:20:00.5Z",
+	}, state)
+
+	restored := newGitLab("http://localhost")
+	require.NoError(t, restored.SetState(map[string]interface{}{
+		"gitlab-org/gitlab-ce": "2018-06-01T12:20:00.5Z",
+	}))
+	require.Equal(t, g.lastPipeline, restored.lastPipeline)
+
+	require.Error(t, restored.SetState("invalid"))
+}
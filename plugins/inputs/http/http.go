@@ -5,13 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
@@ -20,14 +19,10 @@ type HTTP struct {
 	URLs   []string `toml:"urls"`
 	Method string
 
-	Headers map[string]string
-
 	// HTTP Basic Auth Credentials
 	Username string
 	Password string
-	tls.ClientConfig
-
-	Timeout internal.Duration
+	httpconfig.HTTPClientConfig
 
 	client *http.Client
 
@@ -55,16 +50,30 @@ var sampleConfig = `
   ## Tag all metrics with the url
   # tag_url = true
 
+  ## Optional HTTP cookies
+  # cookies = {"session" = "value"}
+
   ## Optional TLS Config
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
   # tls_key = "/etc/telegraf/key.pem"
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+  ## TLS renegotiation requested by the servers, "never", "once" or "freely"
+  # tls_renegotiation_method = "never"
 
   ## Amount of time allowed to complete the HTTP request
   # timeout = "5s"
 
+  ## Idle connections kept open for the next requests, in total and per
+  ## host, and time before closing them (default = 0, 2 per host and 0).
+  ## 0 means unlimited.
+  # max_idle_conn = 0
+  # max_idle_conn_per_host = 2
+  # idle_conn_timeout = "0s"
+  ## Close the connections after each request.
+  # disable_keep_alives = false
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -90,17 +99,11 @@ func (h *HTTP) Gather(acc telegraf.Accumulator) error {
 	}
 
 	if h.client == nil {
-		tlsCfg, err := h.ClientConfig.TLSConfig()
+		client, err := h.CreateClient("inputs.http")
 		if err != nil {
 			return err
 		}
-		h.client = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: tlsCfg,
-				Proxy:           http.ProxyFromEnvironment,
-			},
-			Timeout: h.Timeout.Duration,
-		}
+		h.client = client
 	}
 
 	var wg sync.WaitGroup
@@ -140,13 +143,7 @@ func (h *HTTP) gatherURL(
 		return err
 	}
 
-	for k, v := range h.Headers {
-		if strings.ToLower(k) == "host" {
-			request.Host = v
-		} else {
-			request.Header.Add(k, v)
-		}
-	}
+	h.PrepareRequest(request)
 
 	if h.Username != "" || h.Password != "" {
 		request.SetBasicAuth(h.Username, h.Password)
@@ -189,8 +186,10 @@ func (h *HTTP) gatherURL(
 func init() {
 	inputs.Add("http", func() telegraf.Input {
 		return &HTTP{
-			Timeout: internal.Duration{Duration: time.Second * 5},
-			Method:  "GET",
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: time.Second * 5},
+			},
+			Method: "GET",
 		}
 	})
 }
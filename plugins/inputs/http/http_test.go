@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	plugin "github.com/influxdata/telegraf/plugins/inputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
@@ -42,6 +43,30 @@ func TestHTTPwithJSONFormat(t *testing.T) {
 	require.Equal(t, acc.Metrics[0].Tags["url"], url)
 }
 
+func TestHTTPCookies(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err == nil && cookie.Value == "secret" {
+			_, _ = w.Write([]byte(simpleJSON))
+		} else {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer fakeServer.Close()
+
+	plugin := &plugin.HTTP{
+		URLs: []string{fakeServer.URL},
+		HTTPClientConfig: httpconfig.HTTPClientConfig{
+			Cookies: map[string]string{"session": "secret"},
+		},
+	}
+	p, _ := parsers.NewJSONParser("metricName", nil, nil)
+	plugin.SetParser(p)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Len(t, acc.Metrics, 1)
+}
+
 func TestHTTPHeaders(t *testing.T) {
 	header := "X-Special-Header"
 	headerValue := "Special-Value"
@@ -60,8 +85,10 @@ func TestHTTPHeaders(t *testing.T) {
 
 	url := fakeServer.URL + "/endpoint"
 	plugin := &plugin.HTTP{
-		URLs:    []string{url},
-		Headers: map[string]string{header: headerValue},
+		URLs: []string{url},
+		HTTPClientConfig: httpconfig.HTTPClientConfig{
+			Headers: map[string]string{header: headerValue},
+		},
 	}
 	metricName := "metricName"
 	p, _ := parsers.NewJSONParser(metricName, nil, nil)
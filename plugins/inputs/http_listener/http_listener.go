@@ -14,6 +14,8 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/internal/listen"
 	tlsint "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
@@ -35,14 +37,18 @@ const (
 type TimeFunc func() time.Time
 
 type HTTPListener struct {
-	ServiceAddress string
-	ReadTimeout    internal.Duration
-	WriteTimeout   internal.Duration
-	MaxBodySize    int64
-	MaxLineSize    int
-	Port           int
+	ServiceAddress   string
+	ServiceAddresses []string `toml:"service_addresses"`
+	ReadTimeout      internal.Duration
+	WriteTimeout     internal.Duration
+	MaxBodySize      int64
+	MaxLineSize      int
+	Port             int
+
+	CharacterEncoding string
 
 	tlsint.ServerConfig
+	listen.Config
 
 	BasicUsername string
 	BasicPassword string
@@ -58,6 +64,7 @@ type HTTPListener struct {
 	parser  *influx.Parser
 	acc     telegraf.Accumulator
 	pool    *pool
+	decoder *encoding.Decoder
 
 	BytesRecv       selfstat.Stat
 	RequestsServed  selfstat.Stat
@@ -77,6 +84,15 @@ const sampleConfig = `
   ## Address and port to host HTTP listener on
   service_address = ":8186"
 
+  ## Additional addresses to listen on.  The addresses may be prefixed by
+  ## tcp4:// or tcp6:// to listen on IPv4 or IPv6 only, eg. "tcp6://[::1]:8186".
+  # service_addresses = []
+
+  ## Set to listen on IPv6 only, or on both IPv4 and IPv6, on the empty and
+  ## IPv6 hosts, instead of the defaults of the system.  The IPv4 hosts,
+  ## including 0.0.0.0, then listen on IPv4 only.  Not supported on Windows.
+  # ipv6_v6only = false
+
   ## maximum duration before timing out read of the request
   read_timeout = "10s"
   ## maximum duration before timing out write of the response
@@ -90,6 +106,12 @@ const sampleConfig = `
   ## 0 means to use the default of 65536 bytes (64 kibibytes)
   max_line_size = 0
 
+  ## Character encoding of the request bodies, converted to UTF-8 before
+  ## parsing: "utf-8", "utf-16le", "utf-16be", "utf-16" (with a byte order
+  ## mark), "iso-8859-1" (or "latin1"), "iso-8859-15", "windows-1252" or
+  ## "shift_jis".  Defaults to reading the bodies as is.
+  # character_encoding = ""
+
   ## Set one or more allowed client CA certificate file names to 
   ## enable mutually authenticated TLS connections
   tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
@@ -152,6 +174,12 @@ func (h *HTTPListener) Start(acc telegraf.Accumulator) error {
 		h.WriteTimeout.Duration = time.Second * 10
 	}
 
+	decoder, err := encoding.NewDecoder(h.CharacterEncoding)
+	if err != nil {
+		return err
+	}
+	h.decoder = decoder
+
 	h.acc = acc
 	h.pool = NewPool(200, h.MaxLineSize)
 
@@ -168,15 +196,13 @@ func (h *HTTPListener) Start(acc telegraf.Accumulator) error {
 		TLSConfig:    tlsConf,
 	}
 
-	var listener net.Listener
-	if tlsConf != nil {
-		listener, err = tls.Listen("tcp", h.ServiceAddress, tlsConf)
-	} else {
-		listener, err = net.Listen("tcp", h.ServiceAddress)
-	}
+	listener, err := h.ListenAll(append([]string{h.ServiceAddress}, h.ServiceAddresses...))
 	if err != nil {
 		return err
 	}
+	if tlsConf != nil {
+		listener = tls.NewListener(listener, tlsConf)
+	}
 	h.listener = listener
 	h.Port = listener.Addr().(*net.TCPAddr).Port
 
@@ -261,6 +287,7 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 		}
 	}
 	body = http.MaxBytesReader(res, body, h.MaxBodySize)
+	reader := h.decoder.Reader(body)
 
 	var return400 bool
 	var hangingBytes bool
@@ -268,7 +295,7 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 	defer h.pool.put(buf)
 	bufStart := 0
 	for {
-		n, err := io.ReadFull(body, buf[bufStart:])
+		n, err := io.ReadFull(reader, buf[bufStart:])
 		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
 			log.Println("E! " + err.Error())
 			// problem reading the request body
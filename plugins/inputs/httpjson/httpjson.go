@@ -121,6 +121,10 @@ func (h *HttpJson) Description() string {
 	return "Read flattened metrics from one or more JSON HTTP endpoints"
 }
 
+func (h *HttpJson) Deprecated() (string, string) {
+	return "1.6", `use the http input with data_format = "json"`
+}
+
 // Gathers data for all servers.
 func (h *HttpJson) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
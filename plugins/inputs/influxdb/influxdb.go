@@ -10,14 +10,13 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 type InfluxDB struct {
-	URLs    []string `toml:"urls"`
-	Timeout internal.Duration
-	tls.ClientConfig
+	URLs []string `toml:"urls"`
+	httpconfig.HTTPClientConfig
 
 	client *http.Client
 }
@@ -47,6 +46,15 @@ func (*InfluxDB) SampleConfig() string {
 
   ## http request & header timeout
   timeout = "5s"
+
+  ## Optional HTTP headers and cookies
+  # headers = {"X-Special-Header" = "Special-Value"}
+  # cookies = {"session" = "value"}
+
+  ## Idle connections kept open for the next requests, per host, and time
+  ## before closing them (default = 2 and 0, unlimited).
+  # max_idle_conn_per_host = 2
+  # idle_conn_timeout = "0s"
 `
 }
 
@@ -56,17 +64,11 @@ func (i *InfluxDB) Gather(acc telegraf.Accumulator) error {
 	}
 
 	if i.client == nil {
-		tlsCfg, err := i.ClientConfig.TLSConfig()
+		client, err := i.CreateClient("inputs.influxdb")
 		if err != nil {
 			return err
 		}
-		i.client = &http.Client{
-			Transport: &http.Transport{
-				ResponseHeaderTimeout: i.Timeout.Duration,
-				TLSClientConfig:       tlsCfg,
-			},
-			Timeout: i.Timeout.Duration,
-		}
+		i.client = client
 	}
 
 	var wg sync.WaitGroup
@@ -135,7 +137,13 @@ func (i *InfluxDB) gatherURL(
 	shardCounter := 0
 	now := time.Now()
 
-	resp, err := i.client.Get(url)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	i.PrepareRequest(req)
+
+	resp, err := i.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -258,7 +266,9 @@ func (i *InfluxDB) gatherURL(
 func init() {
 	inputs.Add("influxdb", func() telegraf.Input {
 		return &InfluxDB{
-			Timeout: internal.Duration{Duration: time.Second * 5},
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: time.Second * 5},
+			},
 		}
 	})
 }
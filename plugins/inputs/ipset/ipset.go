@@ -33,6 +33,10 @@ func (ipset *Ipset) Description() string {
 	return "Gather packets and bytes counters from Linux ipsets"
 }
 
+func (ipset *Ipset) Dependencies() []string {
+	return []string{"ipset"}
+}
+
 // SampleConfig returns sample configuration options.
 func (ipset *Ipset) SampleConfig() string {
 	return `
@@ -27,6 +27,10 @@ func (ipt *Iptables) Description() string {
 	return "Gather packets and bytes throughput from iptables"
 }
 
+func (ipt *Iptables) Dependencies() []string {
+	return []string{"iptables"}
+}
+
 // SampleConfig returns sample configuration options.
 func (ipt *Iptables) SampleConfig() string {
 	return `
@@ -0,0 +1,359 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	computerTree = "busyExecutors,totalExecutors," +
+		"computer[displayName,offline,numExecutors,executors[idle]]"
+	queueTree = "items[blocked,buildable,stuck,inQueueSince]"
+	jobTree   = "jobs[name,jobs[name]," +
+		"lastCompletedBuild[number,result,duration,timestamp]]"
+)
+
+// resultCodes maps the result of a build to a numeric code.
+var resultCodes = map[string]int{
+	"SUCCESS":   0,
+	"UNSTABLE":  1,
+	"FAILURE":   2,
+	"NOT_BUILT": 3,
+	"ABORTED":   4,
+}
+
+type Jenkins struct {
+	URL             string            `toml:"url"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	MaxSubJobDepth int      `toml:"max_subjob_depth"`
+	JobInclude     []string `toml:"job_include"`
+	JobExclude     []string `toml:"job_exclude"`
+	NodeExclude    []string `toml:"node_exclude"`
+
+	client     *http.Client
+	jobFilter  filter.Filter
+	nodeFilter filter.Filter
+	now        func() time.Time
+}
+
+var sampleConfig = `
+  ## The Jenkins URL
+  url = "http://localhost:8080"
+
+  ## Credentials for basic HTTP authentication, the password may be an API
+  ## token of the user.
+  # username = "admin"
+  # password = "admin"
+
+  ## Maximum time to receive a response.
+  # response_timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Maximum depth of jobs nested in folders, 0 for no limit.
+  # max_subjob_depth = 0
+
+  ## Filter jobs by their full name, such as "folder/job".  Values can be
+  ## specified as glob patterns.
+  # job_include = []
+  # job_exclude = []
+
+  ## Nodes to exclude, by their display name.  Values can be specified as
+  ## glob patterns.
+  # node_exclude = []
+`
+
+func (j *Jenkins) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Jenkins) Description() string {
+	return "Read executor, queue and job build metrics from Jenkins"
+}
+
+type computerResponse struct {
+	BusyExecutors  int `json:"busyExecutors"`
+	TotalExecutors int `json:"totalExecutors"`
+	Computers      []struct {
+		DisplayName  string `json:"displayName"`
+		Offline      bool   `json:"offline"`
+		NumExecutors int    `json:"numExecutors"`
+		Executors    []struct {
+			Idle bool `json:"idle"`
+		} `json:"executors"`
+	} `json:"computer"`
+}
+
+type queueResponse struct {
+	Items []struct {
+		Blocked      bool  `json:"blocked"`
+		Buildable    bool  `json:"buildable"`
+		Stuck        bool  `json:"stuck"`
+		InQueueSince int64 `json:"inQueueSince"`
+	} `json:"items"`
+}
+
+type jobResponse struct {
+	Jobs []job `json:"jobs"`
+}
+
+type job struct {
+	Name string `json:"name"`
+	// Jobs is only set for folders.
+	Jobs               []json.RawMessage `json:"jobs"`
+	LastCompletedBuild *build            `json:"lastCompletedBuild"`
+}
+
+type build struct {
+	Number    int64  `json:"number"`
+	Result    string `json:"result"`
+	Duration  int64  `json:"duration"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (j *Jenkins) Gather(acc telegraf.Accumulator) error {
+	if j.client == nil {
+		if err := j.init(); err != nil {
+			return err
+		}
+	}
+
+	u, err := url.Parse(j.URL)
+	if err != nil {
+		return err
+	}
+	baseURL := strings.TrimSuffix(j.URL, "/") + "/"
+	tags := map[string]string{
+		"source": u.Hostname(),
+	}
+
+	fields := make(map[string]interface{})
+	if err := j.gatherNodes(acc, baseURL, tags, fields); err != nil {
+		acc.AddError(err)
+	}
+	if err := j.gatherQueue(baseURL, fields); err != nil {
+		acc.AddError(err)
+	}
+	if len(fields) > 0 {
+		acc.AddFields("jenkins", fields, tags)
+	}
+
+	if err := j.gatherJobs(acc, baseURL, tags, "", 1); err != nil {
+		acc.AddError(err)
+	}
+	return nil
+}
+
+func (j *Jenkins) init() error {
+	var err error
+	j.jobFilter, err = filter.NewIncludeExcludeFilter(j.JobInclude, j.JobExclude)
+	if err != nil {
+		return fmt.Errorf("error compiling job filters: %s", err)
+	}
+	j.nodeFilter, err = filter.NewIncludeExcludeFilter(nil, j.NodeExclude)
+	if err != nil {
+		return fmt.Errorf("error compiling node filters: %s", err)
+	}
+
+	tlsCfg, err := j.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	j.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: j.ResponseTimeout.Duration,
+	}
+	return nil
+}
+
+func (j *Jenkins) gatherNodes(
+	acc telegraf.Accumulator,
+	baseURL string,
+	tags map[string]string,
+	fields map[string]interface{},
+) error {
+	resp := &computerResponse{}
+	if err := j.get(baseURL+"computer/api/json", computerTree, resp); err != nil {
+		return err
+	}
+
+	fields["busy_executors"] = resp.BusyExecutors
+	fields["total_executors"] = resp.TotalExecutors
+
+	for _, computer := range resp.Computers {
+		if !j.nodeFilter.Match(computer.DisplayName) {
+			continue
+		}
+
+		var busy int
+		for _, executor := range computer.Executors {
+			if !executor.Idle {
+				busy++
+			}
+		}
+
+		status := "online"
+		if computer.Offline {
+			status = "offline"
+		}
+		nodeTags := map[string]string{
+			"node_name": computer.DisplayName,
+			"status":    status,
+		}
+		for k, v := range tags {
+			nodeTags[k] = v
+		}
+		nodeFields := map[string]interface{}{
+			"num_executors":  computer.NumExecutors,
+			"busy_executors": busy,
+		}
+		acc.AddFields("jenkins_node", nodeFields, nodeTags)
+	}
+	return nil
+}
+
+func (j *Jenkins) gatherQueue(baseURL string, fields map[string]interface{}) error {
+	resp := &queueResponse{}
+	if err := j.get(baseURL+"queue/api/json", queueTree, resp); err != nil {
+		return err
+	}
+
+	var blocked, buildable, stuck int
+	var maxWait time.Duration
+	now := j.now()
+	for _, item := range resp.Items {
+		if item.Blocked {
+			blocked++
+		}
+		if item.Buildable {
+			buildable++
+		}
+		if item.Stuck {
+			stuck++
+		}
+		since := time.Unix(0, item.InQueueSince*int64(time.Millisecond))
+		if wait := now.Sub(since); wait > maxWait {
+			maxWait = wait
+		}
+	}
+
+	fields["queue_length"] = len(resp.Items)
+	fields["queue_blocked"] = blocked
+	fields["queue_buildable"] = buildable
+	fields["queue_stuck"] = stuck
+	fields["queue_max_wait_seconds"] = maxWait.Seconds()
+	return nil
+}
+
+// gatherJobs adds the last completed build of the jobs at jobURL, descending
+// into folders up to the maximum depth.
+func (j *Jenkins) gatherJobs(
+	acc telegraf.Accumulator,
+	jobURL string,
+	tags map[string]string,
+	parent string,
+	depth int,
+) error {
+	resp := &jobResponse{}
+	if err := j.get(jobURL+"api/json", jobTree, resp); err != nil {
+		return err
+	}
+
+	for _, job := range resp.Jobs {
+		name := job.Name
+		if parent != "" {
+			name = parent + "/" + job.Name
+		}
+
+		if job.Jobs != nil {
+			if j.MaxSubJobDepth > 0 && depth >= j.MaxSubJobDepth {
+				continue
+			}
+			// The URL is built from the configured URL rather than taken from
+			// the response, which uses the root URL configured in Jenkins.
+			folderURL := jobURL + "job/" + url.PathEscape(job.Name) + "/"
+			err := j.gatherJobs(acc, folderURL, tags, name, depth+1)
+			if err != nil {
+				acc.AddError(err)
+			}
+			continue
+		}
+
+		if job.LastCompletedBuild == nil || !j.jobFilter.Match(name) {
+			continue
+		}
+		b := job.LastCompletedBuild
+
+		jobTags := map[string]string{
+			"name":   name,
+			"result": b.Result,
+		}
+		for k, v := range tags {
+			jobTags[k] = v
+		}
+		jobFields := map[string]interface{}{
+			"duration": b.Duration,
+			"number":   b.Number,
+		}
+		if code, ok := resultCodes[b.Result]; ok {
+			jobFields["result_code"] = code
+		}
+		acc.AddFields("jenkins_job", jobFields, jobTags,
+			time.Unix(0, b.Timestamp*int64(time.Millisecond)))
+	}
+	return nil
+}
+
+func (j *Jenkins) get(loc, tree string, v interface{}) error {
+	req, err := http.NewRequest("GET", loc+"?tree="+url.QueryEscape(tree), nil)
+	if err != nil {
+		return err
+	}
+	if j.Username != "" || j.Password != "" {
+		req.SetBasicAuth(j.Username, j.Password)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", loc, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func init() {
+	inputs.Add("jenkins", func() telegraf.Input {
+		return &Jenkins{
+			URL:             "http://localhost:8080",
+			ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+			now:             time.Now,
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package jenkins
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const computerResponseJSON = `{
+  "busyExecutors": 1,
+  "totalExecutors": 4,
+  "computer": [
+    {"displayName": "master", "offline": false, "numExecutors": 2, "executors": [{"idle": false}, {"idle": true}]},
+    {"displayName": "agent-1", "offline": true, "numExecutors": 2, "executors": [{"idle": true}, {"idle": true}]}
+  ]
+}`
+
+const queueResponseJSON = `{
+  "items": [
+    {"blocked": true, "buildable": false, "stuck": false, "inQueueSince": 1527854340000},
+    {"blocked": false, "buildable": true, "stuck": true, "inQueueSince": 1527854100000}
+  ]
+}`
+
+const rootJobsJSON = `{
+  "jobs": [
+    {"name": "telegraf", "lastCompletedBuild": {"number": 42, "result": "SUCCESS", "duration": 120000, "timestamp": 1527850800000}},
+    {"name": "new-job", "lastCompletedBuild": null},
+    {"name": "team a", "jobs": [{"name": "deploy"}]}
+  ]
+}`
+
+const folderJobsJSON = `{
+  "jobs": [
+    {"name": "deploy", "lastCompletedBuild": {"number": 7, "result": "FAILURE", "duration": 30000, "timestamp": 1527847200000}},
+    {"name": "nested", "jobs": []}
+  ]
+}`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "admin", user)
+		require.Equal(t, "token", pass)
+		require.NotEmpty(t, r.URL.Query().Get("tree"))
+
+		switch r.URL.Path {
+		case "/computer/api/json":
+			w.Write([]byte(computerResponseJSON))
+		case "/queue/api/json":
+			w.Write([]byte(queueResponseJSON))
+		case "/api/json":
+			w.Write([]byte(rootJobsJSON))
+		case "/job/team a/api/json":
+			w.Write([]byte(folderJobsJSON))
+		case "/job/team a/job/nested/api/json":
+			w.Write([]byte(`{"jobs": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newJenkins(url string) *Jenkins {
+	return &Jenkins{
+		URL:      url,
+		Username: "admin",
+		Password: "token",
+		now: func() time.Time {
+			return time.Unix(1527854400, 0)
+		},
+	}
+}
+
+func TestGather(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	j := newJenkins(ts.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(j.Gather))
+
+	acc.AssertContainsTaggedFields(t, "jenkins",
+		map[string]interface{}{
+			"busy_executors":         1,
+			"total_executors":        4,
+			"queue_length":           2,
+			"queue_blocked":          1,
+			"queue_buildable":        1,
+			"queue_stuck":            1,
+			"queue_max_wait_seconds": 300.0,
+		},
+		map[string]string{
+			"source": "127.0.0.1",
+		})
+
+	acc.AssertContainsTaggedFields(t, "jenkins_node",
+		map[string]interface{}{
+			"num_executors":  2,
+			"busy_executors": 1,
+		},
+		map[string]string{
+			"source":    "127.0.0.1",
+			"node_name": "master",
+			"status":    "online",
+		})
+	acc.AssertContainsTaggedFields(t, "jenkins_node",
+		map[string]interface{}{
+			"num_executors":  2,
+			"busy_executors": 0,
+		},
+		map[string]string{
+			"source":    "127.0.0.1",
+			"node_name": "agent-1",
+			"status":    "offline",
+		})
+
+	acc.AssertContainsTaggedFields(t, "jenkins_job",
+		map[string]interface{}{
+			"duration":    int64(120000),
+			"number":      int64(42),
+			"result_code": 0,
+		},
+		map[string]string{
+			"source": "127.0.0.1",
+			"name":   "telegraf",
+			"result": "SUCCESS",
+		})
+	require.True(t, acc.HasTimestamp("jenkins_job", time.Unix(1527850800, 0)))
+	acc.AssertContainsTaggedFields(t, "jenkins_job",
+		map[string]interface{}{
+			"duration":    int64(30000),
+			"number":      int64(7),
+			"result_code": 2,
+		},
+		map[string]string{
+			"source": "127.0.0.1",
+			"name":   "team a/deploy",
+			"result": "FAILURE",
+		})
+	require.Len(t, acc.Metrics, 5)
+}
+
+func TestGatherFilters(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	j := newJenkins(ts.URL)
+	j.JobExclude = []string{"team a/*"}
+	j.NodeExclude = []string{"agent-*"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(j.Gather))
+
+	require.Equal(t, "telegraf", acc.TagValue("jenkins_job", "name"))
+	require.Equal(t, "master", acc.TagValue("jenkins_node", "node_name"))
+	require.Len(t, acc.Metrics, 3)
+}
+
+func TestGatherMaxSubJobDepth(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	j := newJenkins(ts.URL)
+	j.MaxSubJobDepth = 1
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(j.Gather))
+
+	for _, m := range acc.Metrics {
+		if m.Measurement == "jenkins_job" {
+			require.Equal(t, "telegraf", m.Tags["name"])
+		}
+	}
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	j := newJenkins(ts.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, j.Gather(&acc))
+	require.Len(t, acc.Errors, 3)
+	require.Empty(t, acc.Metrics)
+}
@@ -134,6 +134,10 @@ func (j *Jolokia) Description() string {
 	return "Read JMX metrics through Jolokia"
 }
 
+func (j *Jolokia) Deprecated() (string, string) {
+	return "1.5", "use the jolokia2 input"
+}
+
 func (j *Jolokia) doRequest(req *http.Request) ([]map[string]interface{}, error) {
 	resp, err := j.jClient.MakeRequest(req)
 	if err != nil {
@@ -9,7 +9,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -18,9 +18,8 @@ const (
 )
 
 type Kapacitor struct {
-	URLs    []string `toml:"urls"`
-	Timeout internal.Duration
-	tls.ClientConfig
+	URLs []string `toml:"urls"`
+	httpconfig.HTTPClientConfig
 
 	client *http.Client
 }
@@ -46,12 +45,21 @@ func (*Kapacitor) SampleConfig() string {
   # tls_key = "/etc/telegraf/key.pem"
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+
+  ## Optional HTTP headers and cookies
+  # headers = {"X-Special-Header" = "Special-Value"}
+  # cookies = {"session" = "value"}
+
+  ## Idle connections kept open for the next requests, per host, and time
+  ## before closing them (default = 2 and 0, unlimited).
+  # max_idle_conn_per_host = 2
+  # idle_conn_timeout = "0s"
 `
 }
 
 func (k *Kapacitor) Gather(acc telegraf.Accumulator) error {
 	if k.client == nil {
-		client, err := k.createHttpClient()
+		client, err := k.CreateClient("inputs.kapacitor")
 		if err != nil {
 			return err
 		}
@@ -73,22 +81,6 @@ func (k *Kapacitor) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
-func (k *Kapacitor) createHttpClient() (*http.Client, error) {
-	tlsCfg, err := k.ClientConfig.TLSConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: tlsCfg,
-		},
-		Timeout: k.Timeout.Duration,
-	}
-
-	return client, nil
-}
-
 type object struct {
 	Name   string                 `json:"name"`
 	Values map[string]interface{} `json:"values"`
@@ -151,7 +143,13 @@ func (k *Kapacitor) gatherURL(
 ) error {
 	now := time.Now()
 
-	resp, err := k.client.Get(url)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	k.PrepareRequest(req)
+
+	resp, err := k.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -249,8 +247,10 @@ func (k *Kapacitor) gatherURL(
 func init() {
 	inputs.Add("kapacitor", func() telegraf.Input {
 		return &Kapacitor{
-			URLs:    []string{defaultURL},
-			Timeout: internal.Duration{Duration: time.Second * 5},
+			URLs: []string{defaultURL},
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: time.Second * 5},
+			},
 		}
 	})
 }
@@ -0,0 +1,145 @@
+package knx_listener
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// decoders decode the values of the datapoint types, by main number.
+var decoders = map[string]func(sub string, data []byte) (interface{}, error){
+	"1":  decodeBool,
+	"5":  decodeUnsigned8,
+	"6":  decodeSigned8,
+	"7":  decodeUnsigned16,
+	"8":  decodeSigned16,
+	"9":  decodeFloat16,
+	"12": decodeUnsigned32,
+	"13": decodeSigned32,
+	"14": decodeFloat32,
+	"16": decodeString,
+	"17": decodeScene,
+}
+
+// decode returns the value of a datapoint type, such as "9.001", from the
+// data of a telegram.
+func decode(dpt string, data []byte) (interface{}, error) {
+	main, sub := splitDPT(dpt)
+	decoder, ok := decoders[main]
+	if !ok {
+		return nil, fmt.Errorf("unsupported datapoint type %q", dpt)
+	}
+	return decoder(sub, data)
+}
+
+func splitDPT(dpt string) (string, string) {
+	parts := strings.SplitN(dpt, ".", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func checkLength(data []byte, length int) error {
+	if len(data) != length {
+		return fmt.Errorf("invalid data length %d, expected %d", len(data), length)
+	}
+	return nil
+}
+
+func decodeBool(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 1); err != nil {
+		return nil, err
+	}
+	return data[0]&0x01 == 1, nil
+}
+
+func decodeUnsigned8(sub string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 1); err != nil {
+		return nil, err
+	}
+	switch sub {
+	case "001": // percentage, 0-100%
+		return float64(data[0]) * 100 / 255, nil
+	case "003": // angle, 0-360°
+		return float64(data[0]) * 360 / 255, nil
+	}
+	return int64(data[0]), nil
+}
+
+func decodeSigned8(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 1); err != nil {
+		return nil, err
+	}
+	return int64(int8(data[0])), nil
+}
+
+func decodeUnsigned16(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 2); err != nil {
+		return nil, err
+	}
+	return int64(binary.BigEndian.Uint16(data)), nil
+}
+
+func decodeSigned16(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 2); err != nil {
+		return nil, err
+	}
+	return int64(int16(binary.BigEndian.Uint16(data))), nil
+}
+
+// decodeFloat16 decodes the 2-octet floats of KNX, with the sign, a 4 bits
+// exponent and an 11 bits mantissa: 0.01 * mantissa * 2^exponent.
+func decodeFloat16(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 2); err != nil {
+		return nil, err
+	}
+	v := binary.BigEndian.Uint16(data)
+	if v == 0x7fff {
+		return nil, fmt.Errorf("invalid data")
+	}
+	mantissa := int(v & 0x07ff)
+	if v&0x8000 != 0 {
+		mantissa -= 2048
+	}
+	exponent := uint(v>>11) & 0x0f
+	return float64(mantissa<<exponent) / 100, nil
+}
+
+func decodeUnsigned32(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 4); err != nil {
+		return nil, err
+	}
+	return int64(binary.BigEndian.Uint32(data)), nil
+}
+
+func decodeSigned32(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 4); err != nil {
+		return nil, err
+	}
+	return int64(int32(binary.BigEndian.Uint32(data))), nil
+}
+
+func decodeFloat32(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 4); err != nil {
+		return nil, err
+	}
+	return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+}
+
+// decodeString decodes the strings of 14 characters, padded with NUL.
+func decodeString(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 14); err != nil {
+		return nil, err
+	}
+	return strings.TrimRight(string(data), "\x00"), nil
+}
+
+// decodeScene decodes the scene numbers, from 0 for scene 1 to 63.
+func decodeScene(_ string, data []byte) (interface{}, error) {
+	if err := checkLength(data, 1); err != nil {
+		return nil, err
+	}
+	return int64(data[0] & 0x3f), nil
+}
@@ -0,0 +1,48 @@
+package knx_listener
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		dpt   string
+		data  []byte
+		value interface{}
+	}{
+		{"1.001", []byte{0x01}, true},
+		{"1.009", []byte{0x00}, false},
+		{"5.001", []byte{0xff}, float64(100)},
+		{"5.003", []byte{0x80}, float64(128) * 360 / 255},
+		{"5.010", []byte{0xc8}, int64(200)},
+		{"6.010", []byte{0xff}, int64(-1)},
+		{"7.001", []byte{0x12, 0x34}, int64(0x1234)},
+		{"8.001", []byte{0xff, 0xfe}, int64(-2)},
+		{"9.001", []byte{0x0c, 0x33}, 21.5},
+		{"9.001", []byte{0x8a, 0x24}, -30.0},
+		{"9.004", []byte{0x7f, 0xfe}, 670433.28},
+		{"12.001", []byte{0xff, 0xff, 0xff, 0xff}, int64(4294967295)},
+		{"13.010", []byte{0xff, 0xff, 0xff, 0x9c}, int64(-100)},
+		{"14.056", []byte{0x42, 0x28, 0x00, 0x00}, float64(42)},
+		{"16.000", []byte("KNX is OK\x00\x00\x00\x00\x00"), "KNX is OK"},
+		{"17.001", []byte{0x05}, int64(5)},
+	}
+	for _, tt := range tests {
+		value, err := decode(tt.dpt, tt.data)
+		require.NoError(t, err, tt.dpt)
+		require.Equal(t, tt.value, value, tt.dpt)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	_, err := decode("232.600", []byte{0, 0, 0})
+	require.EqualError(t, err, `unsupported datapoint type "232.600"`)
+
+	_, err = decode("9.001", []byte{0x0c})
+	require.EqualError(t, err, "invalid data length 1, expected 2")
+
+	_, err = decode("9.001", []byte{0x7f, 0xff})
+	require.EqualError(t, err, "invalid data")
+}
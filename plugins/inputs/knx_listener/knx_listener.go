@@ -0,0 +1,402 @@
+package knx_listener
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	defaultRoutingAddress = "224.0.23.12:3671"
+	defaultTunnelAddress  = "localhost:3671"
+
+	// readTimeout is the timeout of the reads, to check the connection and
+	// stop the plugin in time.
+	readTimeout = time.Second
+
+	// Timeouts of the tunnelling connection, as per the KNXnet/IP
+	// specification.
+	connectTimeout    = 10 * time.Second
+	heartbeatInterval = 60 * time.Second
+	heartbeatTimeout  = 10 * time.Second
+	reconnectInterval = 5 * time.Second
+)
+
+// Measurement maps group addresses to a measurement and a datapoint type.
+type Measurement struct {
+	Name      string   `toml:"name"`
+	Dpt       string   `toml:"dpt"`
+	Addresses []string `toml:"addresses"`
+}
+
+type KNXListener struct {
+	ServiceType    string        `toml:"service_type"`
+	ServiceAddress string        `toml:"service_address"`
+	Measurements   []Measurement `toml:"measurement"`
+
+	acc      telegraf.Accumulator
+	mappings map[uint16]Measurement
+	conn     *net.UDPConn
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	// Tunnelling connection state.
+	control  *net.UDPAddr
+	data     *net.UDPAddr
+	channel  byte
+	seq      byte
+	received bool
+}
+
+var sampleConfig = `
+  ## Type of KNXnet/IP connection to the bus:
+  ##   "tunnel"  - tunnelling connection to a KNX/IP interface or router
+  ##   "routing" - multicast routing, with a KNX/IP router
+  # service_type = "tunnel"
+
+  ## Address of the KNX/IP interface or router for tunnelling, or multicast
+  ## group for routing.  Defaults to "localhost:3671" for tunnelling and to
+  ## "224.0.23.12:3671" for routing.
+  # service_address = "localhost:3671"
+
+  ## Measurements of the values of the group addresses, with their
+  ## datapoint type.  The telegrams of the other group addresses are ignored.
+  [[inputs.knx_listener.measurement]]
+    ## Name of the measurement.
+    name = "temperature"
+    ## Datapoint type of the values, such as "1.001" for switches or
+    ## "9.001" for temperatures.
+    dpt = "9.001"
+    ## Group addresses, in the 3-level or 2-level notation.
+    addresses = ["5/5/1", "5/5/2"]
+
+  # [[inputs.knx_listener.measurement]]
+  #   name = "switch"
+  #   dpt = "1.001"
+  #   addresses = ["1/1/1"]
+`
+
+func (k *KNXListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *KNXListener) Description() string {
+	return "Listen to the group telegrams of a KNX bus through KNXnet/IP"
+}
+
+func (k *KNXListener) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Validate checks the service type and the measurements.
+func (k *KNXListener) Validate() error {
+	_, err := k.parseMappings()
+	if err != nil {
+		return err
+	}
+	switch k.ServiceType {
+	case "", "tunnel", "routing":
+		return nil
+	default:
+		return fmt.Errorf("unknown service_type %q", k.ServiceType)
+	}
+}
+
+// parseMappings returns the measurements of the group addresses.
+func (k *KNXListener) parseMappings() (map[uint16]Measurement, error) {
+	mappings := make(map[uint16]Measurement)
+	for _, m := range k.Measurements {
+		if m.Name == "" {
+			return nil, fmt.Errorf("measurement without name")
+		}
+		main, _ := splitDPT(m.Dpt)
+		if _, ok := decoders[main]; !ok {
+			return nil, fmt.Errorf("unsupported datapoint type %q of measurement %q", m.Dpt, m.Name)
+		}
+		for _, s := range m.Addresses {
+			addr, err := parseGroupAddress(s)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := mappings[addr]; ok {
+				return nil, fmt.Errorf("group address %q of several measurements", s)
+			}
+			mappings[addr] = m
+		}
+	}
+	return mappings, nil
+}
+
+func (k *KNXListener) Start(acc telegraf.Accumulator) error {
+	if err := k.Validate(); err != nil {
+		return err
+	}
+	k.mappings, _ = k.parseMappings()
+	k.acc = acc
+	k.done = make(chan struct{})
+
+	if k.ServiceType == "routing" {
+		address := k.ServiceAddress
+		if address == "" {
+			address = defaultRoutingAddress
+		}
+		group, err := net.ResolveUDPAddr("udp4", address)
+		if err != nil {
+			return err
+		}
+		k.conn, err = net.ListenMulticastUDP("udp4", nil, group)
+		if err != nil {
+			return err
+		}
+		k.wg.Add(1)
+		go k.listenRouting()
+		return nil
+	}
+
+	address := k.ServiceAddress
+	if address == "" {
+		address = defaultTunnelAddress
+	}
+	var err error
+	k.control, err = net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return err
+	}
+	k.conn, err = net.ListenUDP("udp4", nil)
+	if err != nil {
+		return err
+	}
+	if err := k.connect(); err != nil {
+		k.conn.Close()
+		return err
+	}
+	k.wg.Add(1)
+	go k.listenTunnel()
+	return nil
+}
+
+func (k *KNXListener) Stop() {
+	close(k.done)
+	k.wg.Wait()
+	if k.control != nil {
+		k.send(k.control, serviceDisconnectRequest, []byte{k.channel, 0}, hpaiNAT)
+	}
+	k.conn.Close()
+}
+
+func (k *KNXListener) stopping() bool {
+	select {
+	case <-k.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (k *KNXListener) send(addr *net.UDPAddr, service uint16, body ...[]byte) error {
+	_, err := k.conn.WriteToUDP(packFrame(service, body...), addr)
+	return err
+}
+
+// read returns the next KNXnet/IP frame, or a nil body after the read
+// timeout.
+func (k *KNXListener) read(buf []byte) (uint16, []byte, error) {
+	k.conn.SetReadDeadline(time.Now().Add(readTimeout))
+	n, _, err := k.conn.ReadFromUDP(buf)
+	if err != nil {
+		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+			return 0, nil, nil
+		}
+		return 0, nil, err
+	}
+	return unpackFrame(buf[:n])
+}
+
+func (k *KNXListener) listenRouting() {
+	defer k.wg.Done()
+
+	buf := make([]byte, 1024)
+	for !k.stopping() {
+		service, body, err := k.read(buf)
+		if err != nil {
+			k.acc.AddError(err)
+			continue
+		}
+		if service == serviceRoutingIndication {
+			k.handleCEMI(body)
+		}
+	}
+}
+
+// connect opens the tunnelling connection.
+func (k *KNXListener) connect() error {
+	// Tunnel connection on the link layer.
+	cri := []byte{0x04, 0x04, 0x02, 0x00}
+	if err := k.send(k.control, serviceConnectRequest, hpaiNAT, hpaiNAT, cri); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1024)
+	deadline := time.Now().Add(connectTimeout)
+	for time.Now().Before(deadline) {
+		service, body, err := k.read(buf)
+		if err != nil {
+			return err
+		}
+		if service != serviceConnectResponse || len(body) < 2 {
+			continue
+		}
+		if body[1] != 0 {
+			return fmt.Errorf("connection to %s refused, status 0x%02x", k.control, body[1])
+		}
+		k.channel = body[0]
+		k.data = k.control
+		if len(body) >= 10 {
+			if addr := parseHPAI(body[2:10]); addr != nil {
+				k.data = addr
+			}
+		}
+		k.received = false
+		log.Printf("I! Connected to the KNX/IP interface %s, channel %d", k.control, k.channel)
+		return nil
+	}
+	return fmt.Errorf("no response of %s to the connection request", k.control)
+}
+
+// reconnect opens the tunnelling connection again, until it succeeds or the
+// plugin is stopped.
+func (k *KNXListener) reconnect() bool {
+	for !k.stopping() {
+		err := k.connect()
+		if err == nil {
+			return true
+		}
+		log.Printf("E! Error connecting to the KNX/IP interface %s: %s", k.control, err)
+		select {
+		case <-k.done:
+		case <-time.After(reconnectInterval):
+		}
+	}
+	return false
+}
+
+func (k *KNXListener) listenTunnel() {
+	defer k.wg.Done()
+
+	buf := make([]byte, 1024)
+	lastHeartbeat := time.Now()
+	var pending time.Time
+	for !k.stopping() {
+		now := time.Now()
+		if !pending.IsZero() && now.Sub(pending) > heartbeatTimeout {
+			log.Printf("E! No response of the KNX/IP interface %s, reconnecting", k.control)
+			pending = time.Time{}
+			if !k.reconnect() {
+				return
+			}
+			lastHeartbeat = time.Now()
+		}
+		if pending.IsZero() && now.Sub(lastHeartbeat) >= heartbeatInterval {
+			if err := k.send(k.control, serviceConnectionStateRequest, []byte{k.channel, 0}, hpaiNAT); err != nil {
+				k.acc.AddError(err)
+			}
+			lastHeartbeat = now
+			pending = now
+		}
+
+		service, body, err := k.read(buf)
+		if err != nil {
+			k.acc.AddError(err)
+			continue
+		}
+		if len(body) < 2 {
+			continue
+		}
+
+		switch service {
+		case serviceTunnellingRequest:
+			if len(body) < 4 || body[0] != 4 || body[1] != k.channel {
+				continue
+			}
+			seq := body[2]
+			if err := k.send(k.data, serviceTunnellingAck, []byte{4, k.channel, seq, 0}); err != nil {
+				k.acc.AddError(err)
+			}
+			// Repeated frames, whose acknowledgment was lost, are only
+			// acknowledged.
+			if k.received && seq == k.seq {
+				continue
+			}
+			k.seq = seq
+			k.received = true
+			k.handleCEMI(body[4:])
+		case serviceConnectionStateResponse:
+			if body[0] != k.channel {
+				continue
+			}
+			pending = time.Time{}
+			if body[1] != 0 {
+				log.Printf("E! Connection to the KNX/IP interface %s lost, status 0x%02x", k.control, body[1])
+				if !k.reconnect() {
+					return
+				}
+			}
+		case serviceDisconnectRequest:
+			if body[0] != k.channel {
+				continue
+			}
+			k.send(k.control, serviceDisconnectResponse, []byte{k.channel, 0})
+			log.Printf("W! Disconnected by the KNX/IP interface %s, reconnecting", k.control)
+			pending = time.Time{}
+			if !k.reconnect() {
+				return
+			}
+			lastHeartbeat = time.Now()
+		}
+	}
+}
+
+// handleCEMI adds the value of a group telegram, if its group address is
+// one of the measurements.
+func (k *KNXListener) handleCEMI(b []byte) {
+	t, err := parseCEMI(b)
+	if err != nil {
+		k.acc.AddError(err)
+		return
+	}
+	if t == nil || (t.apci != apciGroupValueWrite && t.apci != apciGroupValueResponse) {
+		return
+	}
+	m, ok := k.mappings[t.destination]
+	if !ok {
+		return
+	}
+
+	value, err := decode(m.Dpt, t.data)
+	if err != nil {
+		k.acc.AddError(fmt.Errorf("decoding the value of %s as %s: %s",
+			formatGroupAddress(t.destination), m.Dpt, err))
+		return
+	}
+	tags := map[string]string{
+		"groupaddress": formatGroupAddress(t.destination),
+		"source":       formatIndividualAddress(t.source),
+	}
+	fields := map[string]interface{}{
+		"value": value,
+	}
+	k.acc.AddFields(m.Name, fields, tags)
+}
+
+func init() {
+	inputs.Add("knx_listener", func() telegraf.Input {
+		return &KNXListener{
+			ServiceType: "tunnel",
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package knx_listener
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// Group telegrams from 1.1.5, a write of 21.5 to 5/5/1, a read of 5/5/1 and
+// a write of true to 1/1/1.
+var (
+	writeTemperature = []byte{0x29, 0x00, 0xbc, 0xe0, 0x11, 0x05, 0x2d, 0x01, 0x03, 0x00, 0x80, 0x0c, 0x33}
+	readTemperature  = []byte{0x29, 0x00, 0xbc, 0xe0, 0x11, 0x05, 0x2d, 0x01, 0x01, 0x00, 0x00}
+	writeSwitch      = []byte{0x29, 0x00, 0xbc, 0xe0, 0x11, 0x05, 0x09, 0x01, 0x01, 0x00, 0x81}
+)
+
+func TestParseGroupAddress(t *testing.T) {
+	addr, err := parseGroupAddress("5/5/1")
+	require.NoError(t, err)
+	require.Equal(t, uint16(0x2d01), addr)
+	require.Equal(t, "5/5/1", formatGroupAddress(addr))
+
+	addr, err = parseGroupAddress("5/1281")
+	require.NoError(t, err)
+	require.Equal(t, uint16(0x2d01), addr)
+
+	for _, s := range []string{"32/0/0", "1/8/0", "1/2048", "1", "a/b/c"} {
+		_, err := parseGroupAddress(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestParseCEMI(t *testing.T) {
+	tg, err := parseCEMI(writeTemperature)
+	require.NoError(t, err)
+	require.Equal(t, &telegram{
+		source:      0x1105,
+		destination: 0x2d01,
+		apci:        apciGroupValueWrite,
+		data:        []byte{0x0c, 0x33},
+	}, tg)
+	require.Equal(t, "1.1.5", formatIndividualAddress(tg.source))
+
+	tg, err = parseCEMI(writeSwitch)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01}, tg.data)
+
+	// L_Data.con
+	tg, err = parseCEMI(append([]byte{0x2e}, writeSwitch[1:]...))
+	require.NoError(t, err)
+	require.Nil(t, tg)
+
+	_, err = parseCEMI(writeTemperature[:11])
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	k := &KNXListener{
+		ServiceType: "tunnel",
+		Measurements: []Measurement{
+			{Name: "temperature", Dpt: "9.001", Addresses: []string{"5/5/1"}},
+			{Name: "switch", Dpt: "1.001", Addresses: []string{"1/1/1"}},
+		},
+	}
+	require.NoError(t, k.Validate())
+
+	k.ServiceType = "usb"
+	require.EqualError(t, k.Validate(), `unknown service_type "usb"`)
+
+	k.ServiceType = "routing"
+	k.Measurements[1].Addresses = []string{"5/5/1"}
+	require.EqualError(t, k.Validate(), `group address "5/5/1" of several measurements`)
+
+	k.Measurements[1].Dpt = "300.1"
+	require.EqualError(t, k.Validate(), `unsupported datapoint type "300.1" of measurement "switch"`)
+}
+
+// gateway is a fake KNX/IP interface.
+type gateway struct {
+	t    *testing.T
+	conn *net.UDPConn
+	peer *net.UDPAddr
+}
+
+func newGateway(t *testing.T) *gateway {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	return &gateway{t: t, conn: conn}
+}
+
+func (g *gateway) expect(service uint16) []byte {
+	buf := make([]byte, 1024)
+	g.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, addr, err := g.conn.ReadFromUDP(buf)
+	require.NoError(g.t, err)
+	g.peer = addr
+	s, body, err := unpackFrame(buf[:n])
+	require.NoError(g.t, err)
+	require.Equal(g.t, service, s)
+	return body
+}
+
+func (g *gateway) send(service uint16, body ...[]byte) {
+	_, err := g.conn.WriteToUDP(packFrame(service, body...), g.peer)
+	require.NoError(g.t, err)
+}
+
+func TestTunnel(t *testing.T) {
+	g := newGateway(t)
+	defer g.conn.Close()
+
+	k := &KNXListener{
+		ServiceType:    "tunnel",
+		ServiceAddress: g.conn.LocalAddr().String(),
+		Measurements: []Measurement{
+			{Name: "temperature", Dpt: "9.001", Addresses: []string{"5/5/1"}},
+			{Name: "switch", Dpt: "1.001", Addresses: []string{"1/1/1"}},
+		},
+	}
+	acc := &testutil.Accumulator{}
+
+	started := make(chan error)
+	go func() {
+		started <- k.Start(acc)
+	}()
+	body := g.expect(serviceConnectRequest)
+	require.Equal(t, []byte{0x04, 0x04, 0x02, 0x00}, body[16:])
+	g.send(serviceConnectResponse, []byte{7, 0}, hpaiNAT, []byte{0x04, 0x04, 0x11, 0x01})
+	require.NoError(t, <-started)
+	defer k.Stop()
+
+	for seq, cemi := range [][]byte{writeTemperature, readTemperature, writeSwitch} {
+		g.send(serviceTunnellingRequest, []byte{4, 7, byte(seq), 0}, cemi)
+		require.Equal(t, []byte{4, 7, byte(seq), 0}, g.expect(serviceTunnellingAck))
+	}
+	// A repeated frame is acknowledged again but ignored.
+	g.send(serviceTunnellingRequest, []byte{4, 7, 2, 0}, writeSwitch)
+	require.Equal(t, []byte{4, 7, 2, 0}, g.expect(serviceTunnellingAck))
+
+	acc.Wait(2)
+	acc.AssertContainsTaggedFields(t, "temperature",
+		map[string]interface{}{"value": 21.5},
+		map[string]string{"groupaddress": "5/5/1", "source": "1.1.5"})
+	acc.AssertContainsTaggedFields(t, "switch",
+		map[string]interface{}{"value": true},
+		map[string]string{"groupaddress": "1/1/1", "source": "1.1.5"})
+	require.Equal(t, 2, len(acc.Metrics))
+}
+
+func TestTunnelRefused(t *testing.T) {
+	g := newGateway(t)
+	defer g.conn.Close()
+
+	k := &KNXListener{
+		ServiceType:    "tunnel",
+		ServiceAddress: g.conn.LocalAddr().String(),
+	}
+	started := make(chan error)
+	go func() {
+		started <- k.Start(&testutil.Accumulator{})
+	}()
+	g.expect(serviceConnectRequest)
+	// No more connections.
+	g.send(serviceConnectResponse, []byte{0, 0x24})
+	require.EqualError(t, <-started, "connection to "+g.conn.LocalAddr().String()+" refused, status 0x24")
+}
@@ -0,0 +1,165 @@
+package knx_listener
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Service types of the KNXnet/IP frames.
+const (
+	serviceConnectRequest          = 0x0205
+	serviceConnectResponse         = 0x0206
+	serviceConnectionStateRequest  = 0x0207
+	serviceConnectionStateResponse = 0x0208
+	serviceDisconnectRequest       = 0x0209
+	serviceDisconnectResponse      = 0x020a
+	serviceTunnellingRequest       = 0x0420
+	serviceTunnellingAck           = 0x0421
+	serviceRoutingIndication       = 0x0530
+)
+
+const (
+	headerLength    = 6
+	protocolVersion = 0x10
+
+	// cemiLDataInd is the message code of the cEMI frames of the telegrams
+	// received from the bus, L_Data.ind.
+	cemiLDataInd = 0x29
+)
+
+// hpaiNAT is the host protocol address information telling the gateway to
+// reply to the address and port the frames come from, which works through
+// NAT.
+var hpaiNAT = []byte{0x08, 0x01, 0, 0, 0, 0, 0, 0}
+
+// packFrame returns a KNXnet/IP frame.
+func packFrame(service uint16, body ...[]byte) []byte {
+	length := headerLength
+	for _, b := range body {
+		length += len(b)
+	}
+	frame := make([]byte, headerLength, length)
+	frame[0] = headerLength
+	frame[1] = protocolVersion
+	binary.BigEndian.PutUint16(frame[2:], service)
+	binary.BigEndian.PutUint16(frame[4:], uint16(length))
+	for _, b := range body {
+		frame = append(frame, b...)
+	}
+	return frame
+}
+
+// unpackFrame returns the service type and the body of a KNXnet/IP frame.
+func unpackFrame(frame []byte) (uint16, []byte, error) {
+	if len(frame) < headerLength || frame[0] != headerLength || frame[1] != protocolVersion {
+		return 0, nil, fmt.Errorf("invalid KNXnet/IP header")
+	}
+	length := int(binary.BigEndian.Uint16(frame[4:]))
+	if length < headerLength || length > len(frame) {
+		return 0, nil, fmt.Errorf("invalid KNXnet/IP frame length %d", length)
+	}
+	return binary.BigEndian.Uint16(frame[2:]), frame[headerLength:length], nil
+}
+
+// parseHPAI returns the UDP address of a host protocol address information,
+// or nil if the address or the port is not set.
+func parseHPAI(b []byte) *net.UDPAddr {
+	if len(b) < 8 || b[0] != 8 {
+		return nil
+	}
+	ip := net.IPv4(b[2], b[3], b[4], b[5])
+	port := int(binary.BigEndian.Uint16(b[6:]))
+	if ip.IsUnspecified() || port == 0 {
+		return nil
+	}
+	return &net.UDPAddr{IP: ip, Port: port}
+}
+
+// Application layer services of the group telegrams.
+const (
+	apciGroupValueRead     = 0
+	apciGroupValueResponse = 1
+	apciGroupValueWrite    = 2
+)
+
+// telegram is a group telegram of the bus.
+type telegram struct {
+	source      uint16
+	destination uint16
+	apci        int
+	data        []byte
+}
+
+// parseCEMI returns the group telegram of an L_Data.ind cEMI frame, or nil
+// for the other frames.
+func parseCEMI(b []byte) (*telegram, error) {
+	if len(b) < 2 || b[0] != cemiLDataInd {
+		return nil, nil
+	}
+	// Message code, additional information, control fields, source and
+	// destination addresses, NPDU length and TPCI/APCI.
+	b = b[2+int(b[1]):]
+	if len(b) < 9 {
+		return nil, fmt.Errorf("cEMI frame too short")
+	}
+	if b[1]&0x80 == 0 {
+		// Not a group address.
+		return nil, nil
+	}
+	t := &telegram{
+		source:      binary.BigEndian.Uint16(b[2:]),
+		destination: binary.BigEndian.Uint16(b[4:]),
+		apci:        int(b[7]&0x03)<<2 | int(b[8]>>6),
+	}
+	length := int(b[6])
+	if len(b) < 8+length {
+		return nil, fmt.Errorf("cEMI frame too short")
+	}
+	if length == 1 {
+		// Values of 6 bits or less are within the APCI.
+		t.data = []byte{b[8] & 0x3f}
+	} else {
+		t.data = b[9 : 8+length]
+	}
+	return t, nil
+}
+
+// parseGroupAddress parses a group address in the 3-level, "main/middle/sub",
+// or 2-level, "main/sub", notation.
+func parseGroupAddress(s string) (uint16, error) {
+	parts := strings.Split(s, "/")
+	limits := map[int][]uint64{
+		2: {31, 2047},
+		3: {31, 7, 255},
+	}[len(parts)]
+	if limits == nil {
+		return 0, fmt.Errorf("invalid group address %q", s)
+	}
+
+	var addr uint16
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 16)
+		if err != nil || v > limits[i] {
+			return 0, fmt.Errorf("invalid group address %q", s)
+		}
+		if i == 0 {
+			addr = uint16(v) << 11
+		} else if len(parts) == 3 && i == 1 {
+			addr |= uint16(v) << 8
+		} else {
+			addr |= uint16(v)
+		}
+	}
+	return addr, nil
+}
+
+func formatGroupAddress(addr uint16) string {
+	return fmt.Sprintf("%d/%d/%d", addr>>11, (addr>>8)&0x07, addr&0xff)
+}
+
+func formatIndividualAddress(addr uint16) string {
+	return fmt.Sprintf("%d.%d.%d", addr>>12, (addr>>8)&0x0f, addr&0xff)
+}
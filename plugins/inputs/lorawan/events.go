@@ -0,0 +1,192 @@
+package lorawan
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	jsonparser "github.com/influxdata/telegraf/plugins/parsers/json"
+)
+
+// device is the device of an event, at the top level of the events of
+// ChirpStack v3 and in deviceInfo with ChirpStack v4.  The JSON names match
+// both versions, whatever the case.
+type device struct {
+	TenantName      string            `json:"tenantName"`
+	ApplicationName string            `json:"applicationName"`
+	DeviceName      string            `json:"deviceName"`
+	DevEUI          string            `json:"devEui"`
+	Tags            map[string]string `json:"tags"`
+}
+
+type uplinkEvent struct {
+	device
+	DeviceInfo *device `json:"deviceInfo"`
+	Time       string  `json:"time"`
+
+	FCnt   *int64                 `json:"fCnt"`
+	FPort  *int64                 `json:"fPort"`
+	DR     *int64                 `json:"dr"`
+	Object map[string]interface{} `json:"object"`
+	RxInfo []struct {
+		GatewayID string   `json:"gatewayId"`
+		RSSI      *int64   `json:"rssi"`
+		SNR       *float64 `json:"snr"`
+		LoRaSNR   *float64 `json:"loRaSNR"`
+	} `json:"rxInfo"`
+	TxInfo struct {
+		Frequency  int64  `json:"frequency"`
+		DR         *int64 `json:"dr"`
+		Modulation struct {
+			LoRa *struct {
+				Bandwidth       int64 `json:"bandwidth"`
+				SpreadingFactor int64 `json:"spreadingFactor"`
+			} `json:"lora"`
+		} `json:"modulation"`
+	} `json:"txInfo"`
+}
+
+type statusEvent struct {
+	device
+	DeviceInfo *device `json:"deviceInfo"`
+	Time       string  `json:"time"`
+
+	Margin                  *int64   `json:"margin"`
+	ExternalPowerSource     bool     `json:"externalPowerSource"`
+	BatteryLevelUnavailable bool     `json:"batteryLevelUnavailable"`
+	BatteryLevel            *float64 `json:"batteryLevel"`
+}
+
+// eventType returns the type of the event of a topic, such as "up" for
+// "application/1/device/0101010101010101/event/up".
+func eventType(topic string) string {
+	return topic[strings.LastIndex(topic, "/")+1:]
+}
+
+// tags returns the tags of the device, with the device tags listed.
+func (d *device) tags(deviceTags []string) map[string]string {
+	tags := map[string]string{
+		"application": d.ApplicationName,
+		"device":      d.DeviceName,
+		"dev_eui":     normalizeEUI(d.DevEUI),
+	}
+	if d.TenantName != "" {
+		tags["tenant"] = d.TenantName
+	}
+	for _, key := range deviceTags {
+		if value, ok := d.Tags[key]; ok && value != "" {
+			tags[key] = value
+		}
+	}
+	return tags
+}
+
+// normalizeEUI returns an EUI in lower case hexadecimal, such as the base64
+// EUIs of the protobuf JSON encoding of ChirpStack v3.
+func normalizeEUI(eui string) string {
+	if len(eui) != 16 {
+		if b, err := base64.StdEncoding.DecodeString(eui); err == nil && len(b) == 8 {
+			return hex.EncodeToString(b)
+		}
+	}
+	return strings.ToLower(eui)
+}
+
+// eventTime returns the time of an event, or now if it has none.
+func eventTime(s string, now time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	return now
+}
+
+// parseUplink adds the metrics of an uplink: the lorawan_uplink metric of the
+// radio reception and the lorawan_data metric of the decoded payload.
+func parseUplink(acc telegraf.Accumulator, payload []byte, deviceTags []string, now time.Time) error {
+	var e uplinkEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return err
+	}
+	dev := &e.device
+	if e.DeviceInfo != nil {
+		dev = e.DeviceInfo
+	}
+	tags := dev.tags(deviceTags)
+	t := eventTime(e.Time, now)
+
+	fields := map[string]interface{}{
+		"gateways": int64(len(e.RxInfo)),
+	}
+	if e.FCnt != nil {
+		fields["f_cnt"] = *e.FCnt
+	}
+	if e.FPort != nil {
+		fields["f_port"] = *e.FPort
+	}
+	if e.DR != nil {
+		fields["dr"] = *e.DR
+	} else if e.TxInfo.DR != nil {
+		fields["dr"] = *e.TxInfo.DR
+	}
+	if e.TxInfo.Frequency > 0 {
+		fields["frequency"] = e.TxInfo.Frequency
+	}
+	if lora := e.TxInfo.Modulation.LoRa; lora != nil {
+		fields["bandwidth"] = lora.Bandwidth
+		fields["spreading_factor"] = lora.SpreadingFactor
+	}
+	// The signal of the best gateway.
+	for _, rx := range e.RxInfo {
+		if rx.RSSI != nil {
+			if rssi, ok := fields["rssi"].(int64); !ok || *rx.RSSI > rssi {
+				fields["rssi"] = *rx.RSSI
+			}
+		}
+		snr := rx.SNR
+		if snr == nil {
+			snr = rx.LoRaSNR
+		}
+		if snr != nil {
+			if best, ok := fields["snr"].(float64); !ok || *snr > best {
+				fields["snr"] = *snr
+			}
+		}
+	}
+	acc.AddFields("lorawan_uplink", fields, tags, t)
+
+	if len(e.Object) > 0 {
+		f := jsonparser.JSONFlattener{}
+		if err := f.FullFlattenJSON("", e.Object, true, true); err != nil {
+			return err
+		}
+		acc.AddFields("lorawan_data", f.Fields, tags, t)
+	}
+	return nil
+}
+
+// parseStatus adds the lorawan_status metric of a device status event.
+func parseStatus(acc telegraf.Accumulator, payload []byte, deviceTags []string, now time.Time) error {
+	var e statusEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return err
+	}
+	dev := &e.device
+	if e.DeviceInfo != nil {
+		dev = e.DeviceInfo
+	}
+
+	fields := map[string]interface{}{
+		"external_power_source": e.ExternalPowerSource,
+	}
+	if e.Margin != nil {
+		fields["margin"] = *e.Margin
+	}
+	if e.BatteryLevel != nil && !e.BatteryLevelUnavailable && !e.ExternalPowerSource {
+		fields["battery_level"] = *e.BatteryLevel
+	}
+	acc.AddFields("lorawan_status", fields, dev.tags(deviceTags), eventTime(e.Time, now))
+	return nil
+}
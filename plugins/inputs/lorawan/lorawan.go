@@ -0,0 +1,235 @@
+package lorawan
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+
+	"github.com/eclipse/paho.mqtt.golang"
+)
+
+var defaultTopics = []string{
+	"application/+/device/+/event/up",
+	"application/+/device/+/event/status",
+}
+
+type LoRaWAN struct {
+	Servers           []string
+	Topics            []string
+	Username          string
+	Password          string
+	QoS               int               `toml:"qos"`
+	ConnectionTimeout internal.Duration `toml:"connection_timeout"`
+	ClientID          string            `toml:"client_id"`
+	DeviceTags        []string          `toml:"device_tags"`
+	tls.ClientConfig
+
+	sync.Mutex
+	client mqtt.Client
+	in     chan mqtt.Message
+	done   chan struct{}
+	acc    telegraf.Accumulator
+
+	connected bool
+}
+
+var sampleConfig = `
+  ## MQTT broker URLs of the ChirpStack MQTT integration, in the
+  ## scheme://host:port format, the scheme can be tcp, ssl, or ws.
+  servers = ["tcp://localhost:1883"]
+
+  ## Topics of the events of the devices, uplinks ("up") and device status
+  ## ("status").  The other events are ignored.
+  # topics = [
+  #   "application/+/device/+/event/up",
+  #   "application/+/device/+/event/status",
+  # ]
+
+  ## MQTT QoS, must be 0, 1, or 2
+  # qos = 0
+  ## Connection timeout for initial connection
+  # connection_timeout = "30s"
+
+  ## If empty, a random client ID will be generated.
+  # client_id = ""
+
+  ## username and password to connect MQTT server.
+  # username = "telegraf"
+  # password = "metricsmetricsmetricsmetrics"
+
+  ## Device tags of ChirpStack added as tags of the metrics.
+  # device_tags = []
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (l *LoRaWAN) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *LoRaWAN) Description() string {
+	return "Read the uplinks and status of LoRaWAN devices from the ChirpStack MQTT integration"
+}
+
+// Gather connects to the broker if the first connection failed, after which
+// the client reconnects by itself.
+func (l *LoRaWAN) Gather(_ telegraf.Accumulator) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if !l.connected {
+		return l.connect()
+	}
+	return nil
+}
+
+func (l *LoRaWAN) Start(acc telegraf.Accumulator) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.QoS > 2 || l.QoS < 0 {
+		return fmt.Errorf("invalid QoS value: %d", l.QoS)
+	}
+	if len(l.Servers) == 0 {
+		return fmt.Errorf("no MQTT server")
+	}
+	if len(l.Topics) == 0 {
+		l.Topics = defaultTopics
+	}
+
+	opts, err := l.createOpts()
+	if err != nil {
+		return err
+	}
+
+	l.acc = acc
+	l.client = mqtt.NewClient(opts)
+	l.in = make(chan mqtt.Message, 1000)
+	l.done = make(chan struct{})
+
+	if err := l.connect(); err != nil {
+		log.Printf("E! Error connecting to the MQTT broker, retrying at the next interval: %s", err)
+	}
+	go l.receiver()
+	return nil
+}
+
+func (l *LoRaWAN) connect() error {
+	if token := l.client.Connect(); token.Wait() && token.Error() != nil {
+		return token.Error()
+	}
+	l.connected = true
+	return nil
+}
+
+func (l *LoRaWAN) Stop() {
+	l.Lock()
+	defer l.Unlock()
+
+	close(l.done)
+	l.client.Disconnect(200)
+}
+
+func (l *LoRaWAN) onConnect(c mqtt.Client) {
+	log.Printf("I! LoRaWAN MQTT client connected")
+	topics := make(map[string]byte)
+	for _, topic := range l.Topics {
+		topics[topic] = byte(l.QoS)
+	}
+	token := c.SubscribeMultiple(topics, l.recvMessage)
+	if token.Wait() && token.Error() != nil {
+		l.acc.AddError(fmt.Errorf("subscribing to %s: %s",
+			strings.Join(l.Topics, ","), token.Error()))
+	}
+}
+
+func (l *LoRaWAN) onConnectionLost(_ mqtt.Client, err error) {
+	l.acc.AddError(fmt.Errorf("MQTT connection lost, reconnecting: %s", err))
+}
+
+func (l *LoRaWAN) recvMessage(_ mqtt.Client, msg mqtt.Message) {
+	select {
+	case l.in <- msg:
+	case <-l.done:
+	}
+}
+
+// receiver adds the metrics of the events received.
+func (l *LoRaWAN) receiver() {
+	for {
+		select {
+		case <-l.done:
+			return
+		case msg := <-l.in:
+			if err := l.parseEvent(msg.Topic(), msg.Payload(), time.Now()); err != nil {
+				l.acc.AddError(fmt.Errorf("parsing the event of %s: %s", msg.Topic(), err))
+			}
+		}
+	}
+}
+
+func (l *LoRaWAN) parseEvent(topic string, payload []byte, now time.Time) error {
+	switch eventType(topic) {
+	case "up":
+		return parseUplink(l.acc, payload, l.DeviceTags, now)
+	case "status":
+		return parseStatus(l.acc, payload, l.DeviceTags, now)
+	}
+	return nil
+}
+
+func (l *LoRaWAN) createOpts() (*mqtt.ClientOptions, error) {
+	opts := mqtt.NewClientOptions()
+	opts.ConnectTimeout = l.ConnectionTimeout.Duration
+
+	if l.ClientID == "" {
+		opts.SetClientID("Telegraf-LoRaWAN-" + internal.RandomString(5))
+	} else {
+		opts.SetClientID(l.ClientID)
+	}
+
+	tlsCfg, err := l.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		opts.SetTLSConfig(tlsCfg)
+	}
+
+	if l.Username != "" {
+		opts.SetUsername(l.Username)
+	}
+	if l.Password != "" {
+		opts.SetPassword(l.Password)
+	}
+
+	for _, server := range l.Servers {
+		opts.AddBroker(server)
+	}
+	opts.SetAutoReconnect(true)
+	opts.SetKeepAlive(time.Second * 60)
+	opts.SetCleanSession(true)
+	opts.SetOnConnectHandler(l.onConnect)
+	opts.SetConnectionLostHandler(l.onConnectionLost)
+	return opts, nil
+}
+
+func init() {
+	inputs.Add("lorawan", func() telegraf.Input {
+		return &LoRaWAN{
+			ConnectionTimeout: internal.Duration{Duration: 30 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package lorawan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1528300000, 0)
+
+const uplinkV4 = `{
+  "deduplicationId": "3ac2a3f5-d7f5-4a39-a7b4-2ab1c8e0e7b6",
+  "time": "2023-01-10T10:30:00.123Z",
+  "deviceInfo": {
+    "tenantId": "52f14cd4-c6f1-4fbd-8f87-4025e1d49242",
+    "tenantName": "ChirpStack",
+    "applicationId": "17c82e96-be03-4f38-aef3-f83d48582d97",
+    "applicationName": "sensors",
+    "deviceProfileName": "Dragino LHT65",
+    "deviceName": "garden",
+    "devEui": "A84041000181C061",
+    "tags": {"site": "home", "floor": ""}
+  },
+  "devAddr": "00189440",
+  "adr": true,
+  "dr": 5,
+  "fCnt": 10,
+  "fPort": 2,
+  "confirmed": false,
+  "data": "y2cJhgHrAX//f/8=",
+  "object": {"TempC_SHT": 24.38, "Hum_SHT": 49.1, "Ext_sensor": "Temperature Sensor", "battery": {"volts": 3.019, "low": false}},
+  "rxInfo": [
+    {"gatewayId": "0016c001f153a14c", "rssi": -86, "snr": 8.5, "channel": 2},
+    {"gatewayId": "0016c001f153a14d", "rssi": -104, "snr": 11}
+  ],
+  "txInfo": {
+    "frequency": 868500000,
+    "modulation": {"lora": {"bandwidth": 125000, "spreadingFactor": 7, "codeRate": "CR_4_5"}}
+  }
+}`
+
+const uplinkV3 = `{
+  "applicationID": "1",
+  "applicationName": "sensors",
+  "deviceName": "garden",
+  "devEUI": "qEBBAAGBwGE=",
+  "rxInfo": [{"gatewayID": "ABbAAfFToUw=", "rssi": -86, "loRaSNR": 8.5}],
+  "txInfo": {"frequency": 868500000, "dr": 5},
+  "adr": true,
+  "fCnt": 10,
+  "fPort": 2,
+  "data": "y2cJhgHrAX//f/8=",
+  "tags": {"site": "home"}
+}`
+
+func TestUplinkV4(t *testing.T) {
+	acc := &testutil.Accumulator{}
+	l := &LoRaWAN{acc: acc, DeviceTags: []string{"site", "floor"}}
+	require.NoError(t, l.parseEvent("application/17c82e96/device/a84041000181c061/event/up", []byte(uplinkV4), now))
+
+	tags := map[string]string{
+		"tenant":      "ChirpStack",
+		"application": "sensors",
+		"device":      "garden",
+		"dev_eui":     "a84041000181c061",
+		"site":        "home",
+	}
+	ts := time.Date(2023, 1, 10, 10, 30, 0, 123000000, time.UTC)
+	require.Len(t, acc.Metrics, 2)
+
+	m := acc.Metrics[0]
+	require.Equal(t, "lorawan_uplink", m.Measurement)
+	require.Equal(t, tags, m.Tags)
+	require.True(t, ts.Equal(m.Time))
+	require.Equal(t, map[string]interface{}{
+		"gateways":         int64(2),
+		"f_cnt":            int64(10),
+		"f_port":           int64(2),
+		"dr":               int64(5),
+		"frequency":        int64(868500000),
+		"bandwidth":        int64(125000),
+		"spreading_factor": int64(7),
+		"rssi":             int64(-86),
+		"snr":              float64(11),
+	}, m.Fields)
+
+	m = acc.Metrics[1]
+	require.Equal(t, "lorawan_data", m.Measurement)
+	require.Equal(t, tags, m.Tags)
+	require.Equal(t, map[string]interface{}{
+		"TempC_SHT":     24.38,
+		"Hum_SHT":       49.1,
+		"Ext_sensor":    "Temperature Sensor",
+		"battery_volts": 3.019,
+		"battery_low":   false,
+	}, m.Fields)
+}
+
+func TestUplinkV3(t *testing.T) {
+	acc := &testutil.Accumulator{}
+	l := &LoRaWAN{acc: acc, DeviceTags: []string{"site"}}
+	require.NoError(t, l.parseEvent("application/1/device/a84041000181c061/event/up", []byte(uplinkV3), now))
+
+	// Without decoded payload, there is no lorawan_data metric.
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "lorawan_uplink",
+		map[string]interface{}{
+			"gateways":  int64(1),
+			"f_cnt":     int64(10),
+			"f_port":    int64(2),
+			"dr":        int64(5),
+			"frequency": int64(868500000),
+			"rssi":      int64(-86),
+			"snr":       8.5,
+		},
+		map[string]string{
+			"application": "sensors",
+			"device":      "garden",
+			"dev_eui":     "a84041000181c061",
+			"site":        "home",
+		})
+	require.Equal(t, now, acc.Metrics[0].Time)
+}
+
+func TestStatus(t *testing.T) {
+	acc := &testutil.Accumulator{}
+	l := &LoRaWAN{acc: acc}
+	status := `{"deviceInfo": {"applicationName": "sensors", "deviceName": "garden", "devEui": "a84041000181c061"},
+		"margin": 7, "externalPowerSource": false, "batteryLevelUnavailable": false, "batteryLevel": 75.5}`
+	require.NoError(t, l.parseEvent("application/1/device/a84041000181c061/event/status", []byte(status), now))
+
+	// The battery level is unknown with external power.
+	status = `{"applicationName": "sensors", "deviceName": "meter", "devEUI": "0101010101010101",
+		"margin": -3, "externalPowerSource": true, "batteryLevelUnavailable": false, "batteryLevel": 0}`
+	require.NoError(t, l.parseEvent("application/1/device/0101010101010101/event/status", []byte(status), now))
+
+	acc.AssertContainsTaggedFields(t, "lorawan_status",
+		map[string]interface{}{"margin": int64(7), "external_power_source": false, "battery_level": 75.5},
+		map[string]string{"application": "sensors", "device": "garden", "dev_eui": "a84041000181c061"})
+	acc.AssertContainsTaggedFields(t, "lorawan_status",
+		map[string]interface{}{"margin": int64(-3), "external_power_source": true},
+		map[string]string{"application": "sensors", "device": "meter", "dev_eui": "0101010101010101"})
+}
+
+func TestOtherEvents(t *testing.T) {
+	acc := &testutil.Accumulator{}
+	l := &LoRaWAN{acc: acc}
+	require.NoError(t, l.parseEvent("application/1/device/0101010101010101/event/join", []byte(`{}`), now))
+	require.Empty(t, acc.Metrics)
+
+	require.Error(t, l.parseEvent("application/1/device/0101010101010101/event/up", []byte(`not json`), now))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -16,6 +17,8 @@ import (
 type Memcached struct {
 	Servers     []string
 	UnixSockets []string
+	GatherSlabs bool `toml:"gather_slabs"`
+	GatherItems bool `toml:"gather_items"`
 }
 
 var sampleConfig = `
@@ -23,6 +26,13 @@ var sampleConfig = `
   ## with optional port. ie localhost, 10.0.0.1:11211, etc.
   servers = ["localhost:11211"]
   # unix_sockets = ["/var/run/memcached.sock"]
+
+  ## Gather per slab class statistics ("stats slabs").
+  # gather_slabs = false
+
+  ## Gather per slab class item statistics ("stats items"), such as the
+  ## evictions and the age of the oldest item.
+  # gather_items = false
 `
 
 var defaultTimeout = 5 * time.Second
@@ -119,15 +129,7 @@ func (m *Memcached) gatherServer(
 	// Read and write buffer
 	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
 
-	// Send command
-	if _, err := fmt.Fprint(rw, "stats\r\n"); err != nil {
-		return err
-	}
-	if err := rw.Flush(); err != nil {
-		return err
-	}
-
-	values, err := parseResponse(rw.Reader)
+	values, err := stats(rw, "stats")
 	if err != nil {
 		return err
 	}
@@ -147,10 +149,85 @@ func (m *Memcached) gatherServer(
 			}
 		}
 	}
+
+	if m.GatherSlabs {
+		values, err := stats(rw, "stats slabs")
+		if err != nil {
+			return err
+		}
+		// The totals over all slab classes are added to the server stats.
+		for key, slabFields := range slabStats(values, "") {
+			if key == "" {
+				for k, v := range slabFields {
+					fields[k] = v
+				}
+				continue
+			}
+			acc.AddFields("memcached_slabs", slabFields, slabTags(tags, key))
+		}
+	}
+
+	if m.GatherItems {
+		values, err := stats(rw, "stats items")
+		if err != nil {
+			return err
+		}
+		for key, itemFields := range slabStats(values, "items:") {
+			if key == "" {
+				continue
+			}
+			acc.AddFields("memcached_items", itemFields, slabTags(tags, key))
+		}
+	}
+
 	acc.AddFields("memcached", fields, tags)
 	return nil
 }
 
+// stats sends a stats command and reads the response.
+func stats(rw *bufio.ReadWriter, command string) (map[string]string, error) {
+	if _, err := fmt.Fprint(rw, command+"\r\n"); err != nil {
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+	return parseResponse(rw.Reader)
+}
+
+// slabStats groups the values of a "stats slabs" or "stats items" response,
+// with keys such as "<prefix><slab class>:<name>", by slab class.  Values
+// that do not belong to a slab class are returned with an empty class.
+func slabStats(values map[string]string, prefix string) map[string]map[string]interface{} {
+	slabs := make(map[string]map[string]interface{})
+	for key, value := range values {
+		key = strings.TrimPrefix(key, prefix)
+
+		var class string
+		if parts := strings.SplitN(key, ":", 2); len(parts) == 2 {
+			class, key = parts[0], parts[1]
+		}
+
+		iValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, ok := slabs[class]; !ok {
+			slabs[class] = make(map[string]interface{})
+		}
+		slabs[class][key] = iValue
+	}
+	return slabs
+}
+
+func slabTags(tags map[string]string, class string) map[string]string {
+	t := map[string]string{"slab_id": class}
+	for k, v := range tags {
+		t[k] = v
+	}
+	return t
+}
+
 func parseResponse(r *bufio.Reader) (map[string]string, error) {
 	values := make(map[string]string)
 
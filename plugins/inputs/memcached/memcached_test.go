@@ -2,6 +2,7 @@ package memcached
 
 import (
 	"bufio"
+	"net"
 	"strings"
 	"testing"
 
@@ -108,6 +109,97 @@ func TestMemcachedParseMetrics(t *testing.T) {
 	}
 }
 
+func TestMemcachedGatherSlabsAndItems(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		responses := map[string]string{
+			"stats":       memcachedStats,
+			"stats slabs": memcachedSlabs,
+			"stats items": memcachedItems,
+		}
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(responses[strings.TrimSpace(line)]))
+		}
+	}()
+
+	m := &Memcached{
+		Servers:     []string{listener.Addr().String()},
+		GatherSlabs: true,
+		GatherItems: true,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(m.Gather))
+
+	server := listener.Addr().String()
+	acc.AssertContainsTaggedFields(t, "memcached_slabs",
+		map[string]interface{}{
+			"chunk_size":      int64(96),
+			"chunks_per_page": int64(10922),
+			"total_pages":     int64(1),
+			"used_chunks":     int64(3),
+			"free_chunks":     int64(10919),
+			"get_hits":        int64(12),
+		},
+		map[string]string{"server": server, "slab_id": "1"})
+	acc.AssertContainsTaggedFields(t, "memcached_items",
+		map[string]interface{}{
+			"number":      int64(3),
+			"age":         int64(1402),
+			"evicted":     int64(7),
+			"outofmemory": int64(0),
+		},
+		map[string]string{"server": server, "slab_id": "1"})
+	acc.AssertContainsTaggedFields(t, "memcached_items",
+		map[string]interface{}{
+			"number":      int64(1),
+			"age":         int64(20),
+			"evicted":     int64(0),
+			"outofmemory": int64(0),
+		},
+		map[string]string{"server": server, "slab_id": "5"})
+
+	require.True(t, acc.HasInt64Field("memcached", "active_slabs"))
+	require.True(t, acc.HasInt64Field("memcached", "total_malloced"))
+	require.True(t, acc.HasInt64Field("memcached", "get_hits"))
+}
+
+var memcachedSlabs = `STAT 1:chunk_size 96
+STAT 1:chunks_per_page 10922
+STAT 1:total_pages 1
+STAT 1:used_chunks 3
+STAT 1:free_chunks 10919
+STAT 1:get_hits 12
+STAT active_slabs 1
+STAT total_malloced 1048576
+END
+`
+
+var memcachedItems = `STAT items:1:number 3
+STAT items:1:age 1402
+STAT items:1:evicted 7
+STAT items:1:outofmemory 0
+STAT items:5:number 1
+STAT items:5:age 20
+STAT items:5:evicted 0
+STAT items:5:outofmemory 0
+END
+`
+
 var memcachedStats = `STAT pid 23235
 STAT uptime 194
 STAT time 1449174679
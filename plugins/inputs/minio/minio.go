@@ -0,0 +1,198 @@
+package minio
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/prometheus"
+)
+
+const (
+	acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
+
+	// tokenIssuer is the issuer MinIO expects in the tokens for its
+	// Prometheus endpoints.
+	tokenIssuer = "prometheus"
+	// tokenLifetime is the validity of the generated tokens, a token is
+	// generated for each gather.
+	tokenLifetime = time.Hour
+)
+
+type Minio struct {
+	URLs            []string          `toml:"urls"`
+	MetricsPath     string            `toml:"metrics_path"`
+	AccessKey       string            `toml:"access_key"`
+	SecretKey       string            `toml:"secret_key"`
+	MetricInclude   []string          `toml:"metric_include"`
+	MetricExclude   []string          `toml:"metric_exclude"`
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	client       *http.Client
+	metricFilter filter.Filter
+	now          func() time.Time
+}
+
+var sampleConfig = `
+  ## URLs of the MinIO servers.
+  urls = ["http://localhost:9000"]
+
+  ## Path of the Prometheus endpoint.  The cluster endpoint reports the
+  ## metrics of all the nodes of the cluster, use "/minio/v2/metrics/node"
+  ## for the metrics of each node or "/minio/prometheus/metrics" for servers
+  ## older than RELEASE.2020-12-03.
+  # metrics_path = "/minio/v2/metrics/cluster"
+
+  ## Access key and secret key of a MinIO user, used to sign the tokens of
+  ## the requests.  Leave empty if MINIO_PROMETHEUS_AUTH_TYPE is "public".
+  # access_key = ""
+  # secret_key = ""
+
+  ## Metrics to include or exclude by name, such as "minio_heal_*".  Values
+  ## can be specified as glob patterns, by default all metrics are included.
+  # metric_include = []
+  # metric_exclude = []
+
+  ## Maximum time to receive a response.
+  # response_timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (m *Minio) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Minio) Description() string {
+	return "Read disk, healing, request and bucket metrics from MinIO servers"
+}
+
+func (m *Minio) Gather(acc telegraf.Accumulator) error {
+	if m.client == nil {
+		if err := m.init(); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range m.URLs {
+		if err := m.gatherURL(acc, u); err != nil {
+			acc.AddError(fmt.Errorf("[%s]: %s", u, err))
+		}
+	}
+	return nil
+}
+
+func (m *Minio) init() error {
+	var err error
+	m.metricFilter, err = filter.NewIncludeExcludeFilter(m.MetricInclude, m.MetricExclude)
+	if err != nil {
+		return fmt.Errorf("error compiling metric filters: %s", err)
+	}
+
+	tlsCfg, err := m.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	m.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: m.ResponseTimeout.Duration,
+	}
+	return nil
+}
+
+func (m *Minio) gatherURL(acc telegraf.Accumulator, u string) error {
+	loc := strings.TrimSuffix(u, "/") + m.MetricsPath
+	req, err := http.NewRequest("GET", loc, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	if m.AccessKey != "" {
+		token, err := m.token()
+		if err != nil {
+			return fmt.Errorf("signing token: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", loc, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	metrics, err := prometheus.Parse(body, resp.Header)
+	if err != nil {
+		return err
+	}
+
+	for _, metric := range metrics {
+		if !m.metricFilter.Match(metric.Name()) {
+			continue
+		}
+
+		tags := metric.Tags()
+		tags["url"] = u
+		switch metric.Type() {
+		case telegraf.Counter:
+			acc.AddCounter(metric.Name(), metric.Fields(), tags, metric.Time())
+		case telegraf.Gauge:
+			acc.AddGauge(metric.Name(), metric.Fields(), tags, metric.Time())
+		case telegraf.Summary:
+			acc.AddSummary(metric.Name(), metric.Fields(), tags, metric.Time())
+		case telegraf.Histogram:
+			acc.AddHistogram(metric.Name(), metric.Fields(), tags, metric.Time())
+		default:
+			acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+		}
+	}
+	return nil
+}
+
+// token returns a token for the Prometheus endpoint, the same as the one
+// generated by "mc admin prometheus generate" but with a short lifetime.
+func (m *Minio) token() (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.StandardClaims{
+		ExpiresAt: m.now().Add(tokenLifetime).Unix(),
+		Subject:   m.AccessKey,
+		Issuer:    tokenIssuer,
+	})
+	return token.SignedString([]byte(m.SecretKey))
+}
+
+func init() {
+	inputs.Add("minio", func() telegraf.Input {
+		return &Minio{
+			MetricsPath:     "/minio/v2/metrics/cluster",
+			ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+			now:             time.Now,
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package minio
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const clusterMetrics = `# HELP minio_bucket_usage_object_total Total number of objects
+# TYPE minio_bucket_usage_object_total gauge
+minio_bucket_usage_object_total{bucket="backups",server="127.0.0.1:9000"} 42
+# HELP minio_cluster_capacity_usable_free_bytes Total free usable capacity online in the cluster
+# TYPE minio_cluster_capacity_usable_free_bytes gauge
+minio_cluster_capacity_usable_free_bytes{server="127.0.0.1:9000"} 1.073741824e+10
+# HELP minio_cluster_disk_offline_total Total drives offline
+# TYPE minio_cluster_disk_offline_total gauge
+minio_cluster_disk_offline_total{server="127.0.0.1:9000"} 1
+# HELP minio_heal_objects_heal_total Objects healed in current self healing run
+# TYPE minio_heal_objects_heal_total gauge
+minio_heal_objects_heal_total{server="127.0.0.1:9000",type="object"} 3
+# HELP minio_s3_requests_total Total number S3 requests
+# TYPE minio_s3_requests_total counter
+minio_s3_requests_total{api="getobject",server="127.0.0.1:9000"} 1024
+# HELP minio_s3_time_ttfb_seconds_distribution Distribution of the time to first byte across API calls
+# TYPE minio_s3_time_ttfb_seconds_distribution histogram
+minio_s3_time_ttfb_seconds_distribution_bucket{api="getobject",server="127.0.0.1:9000",le="0.05"} 1000
+minio_s3_time_ttfb_seconds_distribution_bucket{api="getobject",server="127.0.0.1:9000",le="+Inf"} 1024
+minio_s3_time_ttfb_seconds_distribution_sum{api="getobject",server="127.0.0.1:9000"} 12.5
+minio_s3_time_ttfb_seconds_distribution_count{api="getobject",server="127.0.0.1:9000"} 1024
+`
+
+func newServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/minio/v2/metrics/cluster" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims := &jwt.StandardClaims{}
+		_, err := jwt.ParseWithClaims(auth, claims, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
+			return []byte("minio123"), nil
+		})
+		if err != nil || claims.Subject != "minio" || claims.Issuer != "prometheus" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, clusterMetrics)
+	}))
+}
+
+func TestGather(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	m := &Minio{
+		URLs:          []string{ts.URL},
+		MetricsPath:   "/minio/v2/metrics/cluster",
+		AccessKey:     "minio",
+		SecretKey:     "minio123",
+		MetricExclude: []string{"minio_bucket_*"},
+		now:           time.Now,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(m.Gather))
+
+	tags := map[string]string{
+		"server": "127.0.0.1:9000",
+		"url":    ts.URL,
+	}
+	acc.AssertContainsTaggedFields(t, "minio_cluster_capacity_usable_free_bytes",
+		map[string]interface{}{"gauge": float64(10737418240)}, tags)
+	acc.AssertContainsTaggedFields(t, "minio_cluster_disk_offline_total",
+		map[string]interface{}{"gauge": float64(1)}, tags)
+
+	healTags := map[string]string{
+		"server": "127.0.0.1:9000",
+		"type":   "object",
+		"url":    ts.URL,
+	}
+	acc.AssertContainsTaggedFields(t, "minio_heal_objects_heal_total",
+		map[string]interface{}{"gauge": float64(3)}, healTags)
+
+	apiTags := map[string]string{
+		"api":    "getobject",
+		"server": "127.0.0.1:9000",
+		"url":    ts.URL,
+	}
+	acc.AssertContainsTaggedFields(t, "minio_s3_requests_total",
+		map[string]interface{}{"counter": float64(1024)}, apiTags)
+	acc.AssertContainsTaggedFields(t, "minio_s3_time_ttfb_seconds_distribution",
+		map[string]interface{}{
+			"0.05":  float64(1000),
+			"+Inf":  float64(1024),
+			"sum":   12.5,
+			"count": float64(1024),
+		}, apiTags)
+
+	require.False(t, acc.HasMeasurement("minio_bucket_usage_object_total"))
+}
+
+func TestGatherUnauthorized(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	m := &Minio{
+		URLs:        []string{ts.URL},
+		MetricsPath: "/minio/v2/metrics/cluster",
+		AccessKey:   "minio",
+		SecretKey:   "wrong",
+		now:         time.Now,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "403 Forbidden")
+	require.Empty(t, acc.Metrics)
+}
+
+func TestToken(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	m := &Minio{
+		AccessKey: "minio",
+		SecretKey: "minio123",
+		now:       func() time.Time { return now },
+	}
+
+	token, err := m.token()
+	require.NoError(t, err)
+
+	claims := &jwt.StandardClaims{}
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	_, err = parser.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		require.Equal(t, jwt.SigningMethodHS512, token.Method)
+		return []byte("minio123"), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "minio", claims.Subject)
+	require.Equal(t, "prometheus", claims.Issuer)
+	require.Equal(t, now.Add(time.Hour).Unix(), claims.ExpiresAt)
+}
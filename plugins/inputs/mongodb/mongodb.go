@@ -140,6 +140,7 @@ func (m *MongoDB) gatherServer(server *Server, acc telegraf.Accumulator) error {
 			} else {
 				tlsConfig.InsecureSkipVerify = true
 			}
+			tlsint.ApplyPolicy(tlsConfig)
 		} else {
 			tlsConfig, err = m.ClientConfig.TLSConfig()
 			if err != nil {
@@ -53,6 +53,10 @@ func (n *NTPQ) Description() string {
 	return "Get standard NTP query metrics, requires ntpq executable."
 }
 
+func (n *NTPQ) Dependencies() []string {
+	return []string{"ntpq"}
+}
+
 func (n *NTPQ) SampleConfig() string {
 	return `
   ## If false, set the -n ntpq flag. Can reduce metric gather time.
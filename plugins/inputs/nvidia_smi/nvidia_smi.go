@@ -46,6 +46,10 @@ func (smi *NvidiaSMI) Description() string {
 	return "Pulls statistics from nvidia GPUs attached to the host"
 }
 
+func (smi *NvidiaSMI) Dependencies() []string {
+	return []string{"nvidia-smi"}
+}
+
 // SampleConfig returns the sample configuration for the NvidiaSMI plugin
 func (smi *NvidiaSMI) SampleConfig() string {
 	return `
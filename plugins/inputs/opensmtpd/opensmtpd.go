@@ -45,6 +45,10 @@ func (s *Opensmtpd) Description() string {
 	return "A plugin to collect stats from Opensmtpd - a validating, recursive, and caching DNS resolver "
 }
 
+func (s *Opensmtpd) Dependencies() []string {
+	return []string{"smtpctl"}
+}
+
 // SampleConfig displays configuration instructions
 func (s *Opensmtpd) SampleConfig() string {
 	return sampleConfig
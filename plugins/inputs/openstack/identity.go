@@ -0,0 +1,141 @@
+package openstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/token"
+)
+
+// identity authenticates with Keystone and caches the token and service
+// catalog until shortly before the token expires.
+type identity struct {
+	authURL           string
+	username          string
+	password          string
+	userDomainName    string
+	projectName       string
+	projectDomainName string
+	client            *http.Client
+
+	cache token.Cache
+
+	// mu guards the catalog, which is replaced with the token.
+	mu      sync.Mutex
+	catalog []catalogEntry
+}
+
+type catalogEntry struct {
+	Type      string     `json:"type"`
+	Endpoints []endpoint `json:"endpoints"`
+}
+
+type endpoint struct {
+	Interface string `json:"interface"`
+	Region    string `json:"region"`
+	URL       string `json:"url"`
+}
+
+type domainRef struct {
+	Name string `json:"name"`
+}
+
+type authRequest struct {
+	Auth struct {
+		Identity struct {
+			Methods  []string `json:"methods"`
+			Password struct {
+				User struct {
+					Name     string    `json:"name"`
+					Password string    `json:"password"`
+					Domain   domainRef `json:"domain"`
+				} `json:"user"`
+			} `json:"password"`
+		} `json:"identity"`
+		Scope struct {
+			Project struct {
+				Name   string    `json:"name"`
+				Domain domainRef `json:"domain"`
+			} `json:"project"`
+		} `json:"scope"`
+	} `json:"auth"`
+}
+
+type authResponse struct {
+	Token struct {
+		ExpiresAt time.Time      `json:"expires_at"`
+		Catalog   []catalogEntry `json:"catalog"`
+	} `json:"token"`
+}
+
+// Token returns a valid token and the service catalog, authenticating again
+// if needed.
+func (i *identity) Token() (string, []catalogEntry, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tok, err := i.cache.Token(i.authenticate)
+	if err != nil {
+		return "", nil, err
+	}
+	return tok, i.catalog, nil
+}
+
+// authenticate requests a new token, and replaces the catalog with the one
+// returned with it.
+func (i *identity) authenticate() (string, time.Time, error) {
+	body := &authRequest{}
+	body.Auth.Identity.Methods = []string{"password"}
+	user := &body.Auth.Identity.Password.User
+	user.Name = i.username
+	user.Password = i.password
+	user.Domain.Name = i.userDomainName
+	body.Auth.Scope.Project.Name = i.projectName
+	body.Auth.Scope.Project.Domain.Name = i.projectDomainName
+
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	loc := strings.TrimSuffix(i.authURL, "/") + "/auth/tokens"
+	req, err := http.NewRequest("POST", loc, bytes.NewBuffer(buf))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, fmt.Errorf("authentication failed: %s returned HTTP status %s",
+			loc, resp.Status)
+	}
+
+	auth := &authResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(auth); err != nil {
+		return "", time.Time{}, err
+	}
+	subjectToken := resp.Header.Get("X-Subject-Token")
+	if subjectToken == "" {
+		return "", time.Time{}, fmt.Errorf("authentication failed: no token in response")
+	}
+
+	i.catalog = auth.Token.Catalog
+	return subjectToken, auth.Token.ExpiresAt, nil
+}
+
+// Invalidate discards the cached token, for example after a request was
+// rejected as unauthorized.
+func (i *identity) Invalidate() {
+	i.cache.Invalidate()
+}
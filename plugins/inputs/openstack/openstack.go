@@ -0,0 +1,518 @@
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/token"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// maxPages limits the number of pages requested when listing volumes or
+// images.
+const maxPages = 100
+
+// serviceTypes are the types of the services in the catalog, in order of
+// preference.
+var serviceTypes = map[string][]string{
+	"compute": {"compute"},
+	"volume":  {"volumev3", "block-storage", "volumev2"},
+	"network": {"network"},
+	"image":   {"image"},
+}
+
+type OpenStack struct {
+	AuthURL           string            `toml:"auth_url"`
+	Username          string            `toml:"username"`
+	Password          string            `toml:"password"`
+	UserDomainName    string            `toml:"user_domain_name"`
+	ProjectName       string            `toml:"project_name"`
+	ProjectDomainName string            `toml:"project_domain_name"`
+	Interface         string            `toml:"interface"`
+	Regions           []string          `toml:"regions"`
+	Services          []string          `toml:"services"`
+	Timeout           internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	client   *http.Client
+	identity *identity
+	now      func() time.Time
+}
+
+var sampleConfig = `
+  ## URL of the Keystone v3 identity service.
+  auth_url = "http://controller:5000/v3"
+
+  ## Credentials of the user and the project to authenticate with.  The
+  ## hypervisor, storage pool and network agent metrics require the admin
+  ## role.
+  username = "admin"
+  password = ""
+  # user_domain_name = "Default"
+  project_name = "admin"
+  # project_domain_name = "Default"
+
+  ## Interface of the endpoints to use from the service catalog, one of
+  ## "public", "internal" or "admin".
+  # interface = "public"
+
+  ## Regions to gather, by default all the regions of the service catalog.
+  # regions = []
+
+  ## Services to gather, "compute" for the Nova hypervisors, "volume" for the
+  ## Cinder volumes and pools, "network" for the Neutron agents and "image"
+  ## for the Glance images.
+  # services = ["compute", "volume", "network", "image"]
+
+  ## Timeout for HTTP requests.
+  # timeout = "10s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (o *OpenStack) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenStack) Description() string {
+	return "Read hypervisor, volume, network agent and image metrics from OpenStack"
+}
+
+func (o *OpenStack) Gather(acc telegraf.Accumulator) error {
+	if o.client == nil {
+		if err := o.init(); err != nil {
+			return err
+		}
+	}
+
+	token, catalog, err := o.identity.Token()
+	if err != nil {
+		return err
+	}
+
+	for _, service := range o.Services {
+		types, ok := serviceTypes[service]
+		if !ok {
+			acc.AddError(fmt.Errorf("unknown service %q", service))
+			continue
+		}
+
+		endpoints := o.endpoints(catalog, types)
+		regions := make([]string, 0, len(endpoints))
+		for region := range endpoints {
+			regions = append(regions, region)
+		}
+		sort.Strings(regions)
+
+		for _, region := range regions {
+			if err := o.gatherService(acc, token, service, region, endpoints[region]); err != nil {
+				acc.AddError(fmt.Errorf("[%s %s]: %s", service, region, err))
+			}
+		}
+	}
+	return nil
+}
+
+func (o *OpenStack) init() error {
+	tlsCfg, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	o.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: o.Timeout.Duration,
+	}
+	o.identity = &identity{
+		authURL:           o.AuthURL,
+		username:          o.Username,
+		password:          o.Password,
+		userDomainName:    o.UserDomainName,
+		projectName:       o.ProjectName,
+		projectDomainName: o.ProjectDomainName,
+		client:            o.client,
+		cache:             token.Cache{Now: o.now},
+	}
+	return nil
+}
+
+// endpoints returns the URL of the service for each of the configured
+// regions, using the first of the types found in the catalog for each
+// region.
+func (o *OpenStack) endpoints(catalog []catalogEntry, types []string) map[string]string {
+	endpoints := make(map[string]string)
+	for _, typ := range types {
+		for _, entry := range catalog {
+			if entry.Type != typ {
+				continue
+			}
+			for _, ep := range entry.Endpoints {
+				if ep.Interface != o.Interface || !o.regionIncluded(ep.Region) {
+					continue
+				}
+				if _, ok := endpoints[ep.Region]; !ok {
+					endpoints[ep.Region] = strings.TrimSuffix(ep.URL, "/")
+				}
+			}
+		}
+	}
+	return endpoints
+}
+
+func (o *OpenStack) regionIncluded(region string) bool {
+	if len(o.Regions) == 0 {
+		return true
+	}
+	for _, r := range o.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *OpenStack) gatherService(
+	acc telegraf.Accumulator,
+	token, service, region, endpointURL string,
+) error {
+	switch service {
+	case "compute":
+		return o.gatherHypervisors(acc, token, region, endpointURL)
+	case "volume":
+		if err := o.gatherStoragePools(acc, token, region, endpointURL); err != nil {
+			return err
+		}
+		return o.gatherVolumes(acc, token, region, endpointURL)
+	case "network":
+		return o.gatherNetworkAgents(acc, token, region, endpointURL)
+	case "image":
+		return o.gatherImages(acc, token, region, endpointURL)
+	}
+	return nil
+}
+
+type hypervisorsResponse struct {
+	Hypervisors []struct {
+		HypervisorHostname string `json:"hypervisor_hostname"`
+		HypervisorType     string `json:"hypervisor_type"`
+		State              string `json:"state"`
+		Status             string `json:"status"`
+		VCPUs              int64  `json:"vcpus"`
+		VCPUsUsed          int64  `json:"vcpus_used"`
+		MemoryMB           int64  `json:"memory_mb"`
+		MemoryMBUsed       int64  `json:"memory_mb_used"`
+		LocalGB            int64  `json:"local_gb"`
+		LocalGBUsed        int64  `json:"local_gb_used"`
+		RunningVMs         int64  `json:"running_vms"`
+		CurrentWorkload    int64  `json:"current_workload"`
+	} `json:"hypervisors"`
+}
+
+func (o *OpenStack) gatherHypervisors(acc telegraf.Accumulator, token, region, endpointURL string) error {
+	resp := &hypervisorsResponse{}
+	if err := o.get(token, endpointURL+"/os-hypervisors/detail", resp); err != nil {
+		return err
+	}
+
+	for _, h := range resp.Hypervisors {
+		tags := map[string]string{
+			"region":              region,
+			"hypervisor_hostname": h.HypervisorHostname,
+			"hypervisor_type":     h.HypervisorType,
+			"state":               h.State,
+			"status":              h.Status,
+		}
+		fields := map[string]interface{}{
+			"vcpus":            h.VCPUs,
+			"vcpus_used":       h.VCPUsUsed,
+			"memory_mb":        h.MemoryMB,
+			"memory_mb_used":   h.MemoryMBUsed,
+			"local_gb":         h.LocalGB,
+			"local_gb_used":    h.LocalGBUsed,
+			"running_vms":      h.RunningVMs,
+			"current_workload": h.CurrentWorkload,
+		}
+		acc.AddFields("openstack_hypervisor", fields, tags)
+	}
+	return nil
+}
+
+type poolsResponse struct {
+	Pools []struct {
+		Name         string                     `json:"name"`
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+	} `json:"pools"`
+}
+
+// poolCapacities are the capabilities of the storage pools reported as
+// fields, drivers may report them as "infinite" or "unknown" in which case
+// they are omitted.
+var poolCapacities = []string{
+	"total_capacity_gb",
+	"free_capacity_gb",
+	"allocated_capacity_gb",
+	"provisioned_capacity_gb",
+}
+
+func (o *OpenStack) gatherStoragePools(acc telegraf.Accumulator, token, region, endpointURL string) error {
+	resp := &poolsResponse{}
+	loc := endpointURL + "/scheduler-stats/get_pools?detail=true"
+	if err := o.get(token, loc, resp); err != nil {
+		return err
+	}
+
+	for _, pool := range resp.Pools {
+		tags := map[string]string{
+			"region": region,
+			"name":   pool.Name,
+		}
+		var backend string
+		if json.Unmarshal(pool.Capabilities["volume_backend_name"], &backend) == nil {
+			tags["volume_backend_name"] = backend
+		}
+
+		fields := make(map[string]interface{})
+		for _, capacity := range poolCapacities {
+			if v, ok := parseNumber(pool.Capabilities[capacity]); ok {
+				fields[capacity] = v
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields("openstack_storage_pool", fields, tags)
+		}
+	}
+	return nil
+}
+
+// parseNumber parses a JSON number, which may be quoted.
+func parseNumber(raw json.RawMessage) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.Trim(string(raw), `"`), 64)
+	return v, err == nil
+}
+
+type volumesResponse struct {
+	Volumes []struct {
+		Status    string `json:"status"`
+		Size      int64  `json:"size"`
+		ProjectID string `json:"os-vol-tenant-attr:tenant_id"`
+	} `json:"volumes"`
+	Links []struct {
+		Rel  string `json:"rel"`
+		Href string `json:"href"`
+	} `json:"volumes_links"`
+}
+
+type volumeKey struct {
+	status    string
+	projectID string
+}
+
+type volumeStats struct {
+	count  int64
+	sizeGB int64
+}
+
+func (o *OpenStack) gatherVolumes(acc telegraf.Accumulator, token, region, endpointURL string) error {
+	stats := make(map[volumeKey]*volumeStats)
+	loc := endpointURL + "/volumes/detail?all_tenants=1"
+	for page := 0; loc != "" && page < maxPages; page++ {
+		resp := &volumesResponse{}
+		if err := o.get(token, loc, resp); err != nil {
+			return err
+		}
+
+		for _, v := range resp.Volumes {
+			key := volumeKey{status: v.Status, projectID: v.ProjectID}
+			s, ok := stats[key]
+			if !ok {
+				s = &volumeStats{}
+				stats[key] = s
+			}
+			s.count++
+			s.sizeGB += v.Size
+		}
+
+		loc = ""
+		for _, link := range resp.Links {
+			if link.Rel == "next" {
+				loc = link.Href
+			}
+		}
+	}
+
+	for key, s := range stats {
+		tags := map[string]string{
+			"region": region,
+			"status": key.status,
+		}
+		if key.projectID != "" {
+			tags["project_id"] = key.projectID
+		}
+		fields := map[string]interface{}{
+			"count":   s.count,
+			"size_gb": s.sizeGB,
+		}
+		acc.AddFields("openstack_volumes", fields, tags)
+	}
+	return nil
+}
+
+type agentsResponse struct {
+	Agents []struct {
+		AgentType        string `json:"agent_type"`
+		Binary           string `json:"binary"`
+		Host             string `json:"host"`
+		AvailabilityZone string `json:"availability_zone"`
+		Alive            bool   `json:"alive"`
+		AdminStateUp     bool   `json:"admin_state_up"`
+	} `json:"agents"`
+}
+
+func (o *OpenStack) gatherNetworkAgents(acc telegraf.Accumulator, token, region, endpointURL string) error {
+	resp := &agentsResponse{}
+	if err := o.get(token, endpointURL+"/v2.0/agents", resp); err != nil {
+		return err
+	}
+
+	for _, agent := range resp.Agents {
+		tags := map[string]string{
+			"region":     region,
+			"agent_type": agent.AgentType,
+			"binary":     agent.Binary,
+			"host":       agent.Host,
+		}
+		if agent.AvailabilityZone != "" {
+			tags["availability_zone"] = agent.AvailabilityZone
+		}
+		fields := map[string]interface{}{
+			"alive":          agent.Alive,
+			"admin_state_up": agent.AdminStateUp,
+		}
+		acc.AddFields("openstack_network_agent", fields, tags)
+	}
+	return nil
+}
+
+type imagesResponse struct {
+	Images []struct {
+		Status     string `json:"status"`
+		Visibility string `json:"visibility"`
+		Size       int64  `json:"size"`
+	} `json:"images"`
+	Next string `json:"next"`
+}
+
+type imageKey struct {
+	status     string
+	visibility string
+}
+
+type imageStats struct {
+	count     int64
+	sizeBytes int64
+}
+
+func (o *OpenStack) gatherImages(acc telegraf.Accumulator, token, region, endpointURL string) error {
+	base, err := url.Parse(endpointURL)
+	if err != nil {
+		return err
+	}
+
+	stats := make(map[imageKey]*imageStats)
+	loc := endpointURL + "/v2/images"
+	for page := 0; loc != "" && page < maxPages; page++ {
+		resp := &imagesResponse{}
+		if err := o.get(token, loc, resp); err != nil {
+			return err
+		}
+
+		for _, image := range resp.Images {
+			key := imageKey{status: image.Status, visibility: image.Visibility}
+			s, ok := stats[key]
+			if !ok {
+				s = &imageStats{}
+				stats[key] = s
+			}
+			s.count++
+			s.sizeBytes += image.Size
+		}
+
+		loc = ""
+		if resp.Next != "" {
+			// The next page is a path such as "/v2/images?marker=<id>".
+			next, err := url.Parse(resp.Next)
+			if err != nil {
+				return err
+			}
+			loc = base.ResolveReference(next).String()
+		}
+	}
+
+	for key, s := range stats {
+		tags := map[string]string{
+			"region":     region,
+			"status":     key.status,
+			"visibility": key.visibility,
+		}
+		fields := map[string]interface{}{
+			"count":      s.count,
+			"size_bytes": s.sizeBytes,
+		}
+		acc.AddFields("openstack_images", fields, tags)
+	}
+	return nil
+}
+
+func (o *OpenStack) get(token, loc string, v interface{}) error {
+	req, err := http.NewRequest("GET", loc, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The token was revoked, authenticate again on the next gather.
+		o.identity.Invalidate()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", loc, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func init() {
+	inputs.Add("openstack", func() telegraf.Input {
+		return &OpenStack{
+			UserDomainName:    "Default",
+			ProjectDomainName: "Default",
+			Interface:         "public",
+			Services:          []string{"compute", "volume", "network", "image"},
+			Timeout:           internal.Duration{Duration: 10 * time.Second},
+			now:               time.Now,
+		}
+	})
+}
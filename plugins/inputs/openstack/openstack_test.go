@@ -0,0 +1,246 @@
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	hypervisorsJSON = `{"hypervisors":[{"hypervisor_hostname":"compute-1","hypervisor_type":"QEMU","state":"up","status":"enabled","vcpus":32,"vcpus_used":10,"memory_mb":128000,"memory_mb_used":40960,"local_gb":1000,"local_gb_used":200,"running_vms":5,"current_workload":0}]}`
+	poolsJSON       = `{"pools":[{"name":"block-1@lvm#LVM","capabilities":{"volume_backend_name":"LVM","total_capacity_gb":500.0,"free_capacity_gb":"120.5","allocated_capacity_gb":380,"provisioned_capacity_gb":"unknown"}}]}`
+	volumesPage1    = `{"volumes":[{"status":"available","size":10,"os-vol-tenant-attr:tenant_id":"p1"},{"status":"in-use","size":20,"os-vol-tenant-attr:tenant_id":"p1"}],"volumes_links":[{"rel":"next","href":"%s/volume/volumes/detail?all_tenants=1&marker=v2"}]}`
+	volumesPage2    = `{"volumes":[{"status":"in-use","size":30,"os-vol-tenant-attr:tenant_id":"p1"}]}`
+	agentsJSON      = `{"agents":[{"agent_type":"L3 agent","binary":"neutron-l3-agent","host":"network-1","availability_zone":"nova","alive":false,"admin_state_up":true}]}`
+	imagesPage1     = `{"images":[{"status":"active","visibility":"public","size":1000}],"next":"/image/v2/images?marker=i1"}`
+	imagesPage2     = `{"images":[{"status":"active","visibility":"public","size":500},{"status":"queued","visibility":"private","size":0}]}`
+)
+
+type fakeOpenStack struct {
+	*httptest.Server
+	auths  int
+	revoke bool
+}
+
+func newFakeOpenStack(t *testing.T) *fakeOpenStack {
+	f := &fakeOpenStack{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		req := &authRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		if req.Auth.Identity.Password.User.Name != "admin" ||
+			req.Auth.Identity.Password.User.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "admin", req.Auth.Scope.Project.Name)
+		require.Equal(t, "Default", req.Auth.Scope.Project.Domain.Name)
+
+		f.auths++
+		w.Header().Set("X-Subject-Token", fmt.Sprintf("token-%d", f.auths))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":{"expires_at":"2018-06-01T13:00:00.000000Z","catalog":[
+{"type":"compute","endpoints":[
+  {"interface":"public","region":"RegionOne","url":"%[1]s/compute/v2.1"},
+  {"interface":"internal","region":"RegionOne","url":"http://internal:8774/v2.1"},
+  {"interface":"public","region":"RegionTwo","url":"http://region-two:8774/v2.1"}]},
+{"type":"volumev2","endpoints":[{"interface":"public","region":"RegionOne","url":"http://volumev2:8776/v2/p1"}]},
+{"type":"volumev3","endpoints":[{"interface":"public","region":"RegionOne","url":"%[1]s/volume"}]},
+{"type":"network","endpoints":[{"interface":"public","region":"RegionOne","url":"%[1]s/network/"}]},
+{"type":"image","endpoints":[{"interface":"public","region":"RegionOne","url":"%[1]s/image"}]}]}}`,
+			f.URL)
+	})
+
+	handle := func(path string, body func(r *http.Request) string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if f.revoke || r.Header.Get("X-Auth-Token") != fmt.Sprintf("token-%d", f.auths) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, body(r))
+		})
+	}
+	handle("/compute/v2.1/os-hypervisors/detail", func(*http.Request) string { return hypervisorsJSON })
+	handle("/volume/scheduler-stats/get_pools", func(*http.Request) string { return poolsJSON })
+	handle("/volume/volumes/detail", func(r *http.Request) string {
+		if r.URL.Query().Get("marker") == "v2" {
+			return volumesPage2
+		}
+		return fmt.Sprintf(volumesPage1, f.URL)
+	})
+	handle("/network/v2.0/agents", func(*http.Request) string { return agentsJSON })
+	handle("/image/v2/images", func(r *http.Request) string {
+		if r.URL.Query().Get("marker") == "i1" {
+			return imagesPage2
+		}
+		return imagesPage1
+	})
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+func TestGather(t *testing.T) {
+	f := newFakeOpenStack(t)
+	defer f.Close()
+
+	o := newOpenStack(f.URL)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(o.Gather))
+
+	acc.AssertContainsTaggedFields(t, "openstack_hypervisor",
+		map[string]interface{}{
+			"vcpus":            int64(32),
+			"vcpus_used":       int64(10),
+			"memory_mb":        int64(128000),
+			"memory_mb_used":   int64(40960),
+			"local_gb":         int64(1000),
+			"local_gb_used":    int64(200),
+			"running_vms":      int64(5),
+			"current_workload": int64(0),
+		},
+		map[string]string{
+			"region":              "RegionOne",
+			"hypervisor_hostname": "compute-1",
+			"hypervisor_type":     "QEMU",
+			"state":               "up",
+			"status":              "enabled",
+		})
+
+	acc.AssertContainsTaggedFields(t, "openstack_storage_pool",
+		map[string]interface{}{
+			"total_capacity_gb":     500.0,
+			"free_capacity_gb":      120.5,
+			"allocated_capacity_gb": 380.0,
+		},
+		map[string]string{
+			"region":              "RegionOne",
+			"name":                "block-1@lvm#LVM",
+			"volume_backend_name": "LVM",
+		})
+
+	acc.AssertContainsTaggedFields(t, "openstack_volumes",
+		map[string]interface{}{
+			"count":   int64(1),
+			"size_gb": int64(10),
+		},
+		map[string]string{
+			"region":     "RegionOne",
+			"status":     "available",
+			"project_id": "p1",
+		})
+	acc.AssertContainsTaggedFields(t, "openstack_volumes",
+		map[string]interface{}{
+			"count":   int64(2),
+			"size_gb": int64(50),
+		},
+		map[string]string{
+			"region":     "RegionOne",
+			"status":     "in-use",
+			"project_id": "p1",
+		})
+
+	acc.AssertContainsTaggedFields(t, "openstack_network_agent",
+		map[string]interface{}{
+			"alive":          false,
+			"admin_state_up": true,
+		},
+		map[string]string{
+			"region":            "RegionOne",
+			"agent_type":        "L3 agent",
+			"binary":            "neutron-l3-agent",
+			"host":              "network-1",
+			"availability_zone": "nova",
+		})
+
+	acc.AssertContainsTaggedFields(t, "openstack_images",
+		map[string]interface{}{
+			"count":      int64(2),
+			"size_bytes": int64(1500),
+		},
+		map[string]string{
+			"region":     "RegionOne",
+			"status":     "active",
+			"visibility": "public",
+		})
+	acc.AssertContainsTaggedFields(t, "openstack_images",
+		map[string]interface{}{
+			"count":      int64(1),
+			"size_bytes": int64(0),
+		},
+		map[string]string{
+			"region":     "RegionOne",
+			"status":     "queued",
+			"visibility": "private",
+		})
+
+	require.Len(t, acc.Metrics, 7)
+}
+
+func TestGatherReusesToken(t *testing.T) {
+	f := newFakeOpenStack(t)
+	defer f.Close()
+
+	o := newOpenStack(f.URL)
+	o.Services = []string{"network"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(o.Gather))
+	require.NoError(t, acc.GatherError(o.Gather))
+	require.Equal(t, 1, f.auths)
+
+	// A revoked token is replaced on the next gather.
+	f.revoke = true
+	acc.ClearMetrics()
+	require.NoError(t, o.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+
+	f.revoke = false
+	acc.Errors = nil
+	require.NoError(t, acc.GatherError(o.Gather))
+	require.Equal(t, 2, f.auths)
+
+	// The token is renewed before it expires.
+	o.now = func() time.Time {
+		return time.Date(2018, 6, 1, 12, 59, 30, 0, time.UTC)
+	}
+	o.identity.cache.Now = o.now
+	require.NoError(t, acc.GatherError(o.Gather))
+	require.Equal(t, 3, f.auths)
+}
+
+func TestGatherAuthenticationFailed(t *testing.T) {
+	f := newFakeOpenStack(t)
+	defer f.Close()
+
+	o := newOpenStack(f.URL)
+	o.Password = "wrong"
+
+	var acc testutil.Accumulator
+	err := o.Gather(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "401 Unauthorized")
+}
+
+func newOpenStack(url string) *OpenStack {
+	return &OpenStack{
+		AuthURL:           url + "/v3",
+		Username:          "admin",
+		Password:          "secret",
+		UserDomainName:    "Default",
+		ProjectName:       "admin",
+		ProjectDomainName: "Default",
+		Interface:         "public",
+		Regions:           []string{"RegionOne"},
+		Services:          []string{"compute", "volume", "network", "image"},
+		now: func() time.Time {
+			return time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+		},
+	}
+}
@@ -145,6 +145,10 @@ func (r *passenger) Description() string {
 	return "Read metrics of passenger using passenger-status"
 }
 
+func (r *passenger) Dependencies() []string {
+	return []string{"passenger-status"}
+}
+
 func (g *passenger) Gather(acc telegraf.Accumulator) error {
 	if g.Command == "" {
 		g.Command = "passenger-status -v --show=xml"
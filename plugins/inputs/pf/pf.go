@@ -27,6 +27,10 @@ func (pf *PF) Description() string {
 	return "Gather counters from PF"
 }
 
+func (pf *PF) Dependencies() []string {
+	return []string{"pfctl"}
+}
+
 func (pf *PF) SampleConfig() string {
 	return `
   ## PF require root access on most systems.
@@ -125,6 +125,10 @@ func (p *Postfix) Description() string {
 	return description
 }
 
+func (p *Postfix) Dependencies() []string {
+	return []string{"postconf"}
+}
+
 func init() {
 	inputs.Add("postfix", func() telegraf.Input {
 		return &Postfix{
@@ -0,0 +1,64 @@
+// +build linux
+
+package powerstat
+
+import (
+	"path/filepath"
+
+	"github.com/influxdata/telegraf"
+)
+
+// batteryFields are the fields of the power supply attributes of batteries,
+// which are in micro units.  Batteries report either their energy and power,
+// or their charge and current.
+var batteryFields = []struct {
+	attribute string
+	field     string
+}{
+	{"energy_now", "energy_wh"},
+	{"energy_full", "energy_full_wh"},
+	{"energy_full_design", "energy_full_design_wh"},
+	{"power_now", "power_watts"},
+	{"charge_now", "charge_ah"},
+	{"charge_full", "charge_full_ah"},
+	{"charge_full_design", "charge_full_design_ah"},
+	{"current_now", "current_amperes"},
+	{"voltage_now", "voltage_volts"},
+}
+
+// gatherBatteries adds the charge and power of the batteries of the power
+// supply class.
+func (p *Powerstat) gatherBatteries(acc telegraf.Accumulator) error {
+	supplies, err := filepath.Glob(p.path("class", "power_supply", "*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range supplies {
+		if kind, err := readString(filepath.Join(dir, "type")); err != nil || kind != "Battery" {
+			continue
+		}
+		if present, err := readInt(filepath.Join(dir, "present")); err == nil && present == 0 {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		if capacity, err := readInt(filepath.Join(dir, "capacity")); err == nil {
+			fields["capacity_percent"] = capacity
+		}
+		for _, f := range batteryFields {
+			if v, err := readInt(filepath.Join(dir, f.attribute)); err == nil {
+				fields[f.field] = float64(v) / 1e6
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{"battery": filepath.Base(dir)}
+		if status, err := readString(filepath.Join(dir, "status")); err == nil {
+			tags["status"] = status
+		}
+		acc.AddFields("powerstat_battery", fields, tags)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// +build linux
+
+package powerstat
+
+import (
+	"path/filepath"
+
+	"github.com/influxdata/telegraf"
+)
+
+// cpus returns the sysfs directories of the CPUs.
+func (p *Powerstat) cpus() ([]string, error) {
+	return filepath.Glob(p.path("devices", "system", "cpu", "cpu[0-9]*"))
+}
+
+// gatherCPUFrequency adds the current frequency of each CPU, as set by the
+// cpufreq scaling driver.
+func (p *Powerstat) gatherCPUFrequency(acc telegraf.Accumulator) error {
+	cpus, err := p.cpus()
+	if err != nil {
+		return err
+	}
+	for _, dir := range cpus {
+		khz, err := readInt(filepath.Join(dir, "cpufreq", "scaling_cur_freq"))
+		if err != nil {
+			// Offline CPUs and systems without cpufreq.
+			continue
+		}
+		fields := map[string]interface{}{
+			"frequency_mhz": float64(khz) / 1000,
+		}
+		if khz, err := readInt(filepath.Join(dir, "cpufreq", "scaling_min_freq")); err == nil {
+			fields["min_frequency_mhz"] = float64(khz) / 1000
+		}
+		if khz, err := readInt(filepath.Join(dir, "cpufreq", "scaling_max_freq")); err == nil {
+			fields["max_frequency_mhz"] = float64(khz) / 1000
+		}
+		acc.AddFields("powerstat_cpu", fields, map[string]string{"cpu": filepath.Base(dir)})
+	}
+	return nil
+}
+
+// gatherCStates adds the time spent and the number of entries of each CPU
+// in each of its idle states, as reported by cpuidle.
+func (p *Powerstat) gatherCStates(acc telegraf.Accumulator) error {
+	cpus, err := p.cpus()
+	if err != nil {
+		return err
+	}
+	for _, dir := range cpus {
+		states, err := filepath.Glob(filepath.Join(dir, "cpuidle", "state[0-9]*"))
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			name, err := readString(filepath.Join(state, "name"))
+			if err != nil {
+				continue
+			}
+			usec, err := readInt(filepath.Join(state, "time"))
+			if err != nil {
+				continue
+			}
+			usage, err := readInt(filepath.Join(state, "usage"))
+			if err != nil {
+				continue
+			}
+			fields := map[string]interface{}{
+				"time_us": usec,
+				"usage":   usage,
+			}
+			tags := map[string]string{
+				"cpu":   filepath.Base(dir),
+				"state": name,
+			}
+			acc.AddFields("powerstat_cstate", fields, tags)
+		}
+	}
+	return nil
+}
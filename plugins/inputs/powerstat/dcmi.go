@@ -0,0 +1,77 @@
+// +build linux
+
+package powerstat
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// dcmiFields are the fields of the lines of the ipmitool dcmi power reading
+// output.
+var dcmiFields = map[string]string{
+	"Instantaneous power reading":              "power_watts",
+	"Minimum during sampling period":           "minimum_power_watts",
+	"Maximum during sampling period":           "maximum_power_watts",
+	"Average power reading over sample period": "average_power_watts",
+}
+
+// gatherDCMI adds the platform power reported by the BMC, such as:
+//
+//	Instantaneous power reading:                   220 Watts
+//	Minimum during sampling period:                 12 Watts
+//	Maximum during sampling period:                384 Watts
+//	Average power reading over sample period:      218 Watts
+//	IPMI timestamp:                           Thu Jun 13 09:41:22 2018
+//	Sampling period:                          00000001 Seconds.
+//	Power reading state is:                   activated
+func (p *Powerstat) gatherDCMI(acc telegraf.Accumulator) error {
+	if p.IpmitoolPath == "" {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH")
+	}
+
+	var cmd *exec.Cmd
+	args := []string{"dcmi", "power", "reading"}
+	if p.UseSudo {
+		cmd = execCommand("sudo", append([]string{"-n", p.IpmitoolPath}, args...)...)
+	} else {
+		cmd = execCommand(p.IpmitoolPath, args...)
+	}
+	out, err := internal.CombinedOutputTimeout(cmd, p.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %s - %s",
+			strings.Join(cmd.Args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	fields := make(map[string]interface{})
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if key == "Power reading state is" && value != "activated" {
+			return fmt.Errorf("DCMI power reading is %s", value)
+		}
+		name, ok := dcmiFields[key]
+		if !ok {
+			continue
+		}
+		watts, err := strconv.ParseFloat(strings.TrimSuffix(value, " Watts"), 64)
+		if err != nil {
+			return fmt.Errorf("parsing %q: %s", line, err)
+		}
+		fields[name] = watts
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("no DCMI power reading in the ipmitool output")
+	}
+	acc.AddFields("powerstat_dcmi", fields, nil)
+	return nil
+}
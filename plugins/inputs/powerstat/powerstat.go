@@ -0,0 +1,144 @@
+// +build linux
+
+package powerstat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+var defaultMetrics = []string{"rapl", "cpu_frequency", "battery"}
+
+// gatherers are the functions gathering each of the metrics.
+var gatherers = map[string]func(*Powerstat, telegraf.Accumulator) error{
+	"rapl":          (*Powerstat).gatherRAPL,
+	"cpu_frequency": (*Powerstat).gatherCPUFrequency,
+	"cpu_cstates":   (*Powerstat).gatherCStates,
+	"dcmi":          (*Powerstat).gatherDCMI,
+	"battery":       (*Powerstat).gatherBatteries,
+}
+
+type Powerstat struct {
+	Metrics      []string          `toml:"metrics"`
+	SysfsPath    string            `toml:"sysfs_path"`
+	IpmitoolPath string            `toml:"ipmitool_path"`
+	UseSudo      bool              `toml:"use_sudo"`
+	Timeout      internal.Duration `toml:"timeout"`
+
+	now func() time.Time
+	// energy is the last reading of each RAPL zone, to compute the power
+	// drawn since.
+	energy map[string]energyReading
+}
+
+var sampleConfig = `
+  ## Metrics to gather, among:
+  ##   "rapl"          - energy and power of the Intel RAPL domains
+  ##   "cpu_frequency" - current frequency of each CPU
+  ##   "cpu_cstates"   - time spent in each idle state by each CPU
+  ##   "dcmi"          - platform power reported by the BMC through IPMI DCMI
+  ##   "battery"       - charge and power of the batteries
+  # metrics = ["rapl", "cpu_frequency", "battery"]
+
+  ## Mount point of the sysfs filesystem.
+  # sysfs_path = "/sys"
+
+  ## Path of ipmitool for the "dcmi" metrics, by default the one found in
+  ## the PATH.
+  # ipmitool_path = "/usr/bin/ipmitool"
+
+  ## ipmitool requires root access.  Setting 'use_sudo' to true will make
+  ## use of sudo to run it.  Sudo must be configured to allow the telegraf
+  ## user to run it without password.
+  # use_sudo = false
+
+  ## Timeout for the ipmitool command to complete.
+  # timeout = "5s"
+`
+
+func (p *Powerstat) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Powerstat) Description() string {
+	return "Read power and energy consumption from RAPL, CPU frequency, IPMI DCMI and batteries"
+}
+
+func (p *Powerstat) Gather(acc telegraf.Accumulator) error {
+	metrics := p.Metrics
+	if len(metrics) == 0 {
+		metrics = defaultMetrics
+	}
+
+	for _, metric := range metrics {
+		gather, ok := gatherers[metric]
+		if !ok {
+			acc.AddError(fmt.Errorf("unknown metric %q", metric))
+			continue
+		}
+		if err := gather(p, acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the names of the metrics.
+func (p *Powerstat) Validate() error {
+	for _, metric := range p.Metrics {
+		if _, ok := gatherers[metric]; !ok {
+			return fmt.Errorf("unknown metric %q", metric)
+		}
+	}
+	return nil
+}
+
+// path returns the path of a file of the sysfs filesystem.
+func (p *Powerstat) path(elem ...string) string {
+	root := p.SysfsPath
+	if root == "" {
+		root = "/sys"
+	}
+	return strings.Join(append([]string{root}, elem...), "/")
+}
+
+// readString returns the content of a sysfs file, without the trailing
+// newline.
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// readInt returns the integer value of a sysfs file.
+func readInt(path string) (int64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("powerstat", func() telegraf.Input {
+		p := &Powerstat{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			now:     time.Now,
+		}
+		p.IpmitoolPath, _ = exec.LookPath("ipmitool")
+		return p
+	})
+}
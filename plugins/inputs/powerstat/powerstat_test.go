@@ -0,0 +1,270 @@
+// +build linux
+
+package powerstat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const mockDCMI = `
+    Instantaneous power reading:                   220 Watts
+    Minimum during sampling period:                 12 Watts
+    Maximum during sampling period:                384 Watts
+    Average power reading over sample period:      218 Watts
+    IPMI timestamp:                           Thu Jun 13 09:41:22 2018
+    Sampling period:                          00000001 Seconds.
+    Power reading state is:                   activated
+
+`
+
+const mockDCMIDeactivated = `
+    Instantaneous power reading:                     0 Watts
+    Minimum during sampling period:                  0 Watts
+    Maximum during sampling period:                  0 Watts
+    Average power reading over sample period:        0 Watts
+    IPMI timestamp:                           Thu Jan  1 00:00:00 1970
+    Sampling period:                          00000000 Seconds.
+    Power reading state is:                   deactivated
+
+`
+
+func TestGatherRAPL(t *testing.T) {
+	sysfs := newSysfs(t, map[string]string{
+		"class/powercap/intel-rapl:0/name":                "package-0",
+		"class/powercap/intel-rapl:0/energy_uj":           "262143000000",
+		"class/powercap/intel-rapl:0/max_energy_range_uj": "262143328850",
+		"class/powercap/intel-rapl:0:0/name":              "core",
+		"class/powercap/intel-rapl:0:0/energy_uj":         "1000000",
+	})
+	defer os.RemoveAll(sysfs)
+
+	now := time.Unix(1528300000, 0)
+	p := &Powerstat{
+		Metrics:   []string{"rapl"},
+		SysfsPath: sysfs,
+		now:       func() time.Time { return now },
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "powerstat_rapl",
+		map[string]interface{}{"energy_joules": float64(262143)},
+		map[string]string{"zone": "intel-rapl:0", "domain": "package-0"})
+	acc.AssertContainsTaggedFields(t, "powerstat_rapl",
+		map[string]interface{}{"energy_joules": float64(1)},
+		map[string]string{"zone": "intel-rapl:0:0", "domain": "core"})
+
+	// The power is the energy consumed since the previous gather, with the
+	// package counter wrapping around.
+	writeFile(t, sysfs, "class/powercap/intel-rapl:0/energy_uj", "99671150")
+	writeFile(t, sysfs, "class/powercap/intel-rapl:0:0/energy_uj", "61000000")
+	now = now.Add(10 * time.Second)
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "powerstat_rapl",
+		map[string]interface{}{"energy_joules": 99.67115, "power_watts": float64(10)},
+		map[string]string{"zone": "intel-rapl:0", "domain": "package-0"})
+	acc.AssertContainsTaggedFields(t, "powerstat_rapl",
+		map[string]interface{}{"energy_joules": float64(61), "power_watts": float64(6)},
+		map[string]string{"zone": "intel-rapl:0:0", "domain": "core"})
+}
+
+func TestGatherCPU(t *testing.T) {
+	sysfs := newSysfs(t, map[string]string{
+		"devices/system/cpu/cpu0/cpufreq/scaling_cur_freq": "2394000",
+		"devices/system/cpu/cpu0/cpufreq/scaling_min_freq": "800000",
+		"devices/system/cpu/cpu0/cpufreq/scaling_max_freq": "3400000",
+		"devices/system/cpu/cpu0/cpuidle/state0/name":      "POLL",
+		"devices/system/cpu/cpu0/cpuidle/state0/time":      "1519",
+		"devices/system/cpu/cpu0/cpuidle/state0/usage":     "21",
+		"devices/system/cpu/cpu0/cpuidle/state1/name":      "C1E",
+		"devices/system/cpu/cpu0/cpuidle/state1/time":      "77129354",
+		"devices/system/cpu/cpu0/cpuidle/state1/usage":     "104330",
+		"devices/system/cpu/cpufreq/boost":                 "1",
+	})
+	defer os.RemoveAll(sysfs)
+
+	p := &Powerstat{
+		Metrics:   []string{"cpu_frequency", "cpu_cstates"},
+		SysfsPath: sysfs,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "powerstat_cpu",
+		map[string]interface{}{
+			"frequency_mhz":     float64(2394),
+			"min_frequency_mhz": float64(800),
+			"max_frequency_mhz": float64(3400),
+		},
+		map[string]string{"cpu": "cpu0"})
+	acc.AssertContainsTaggedFields(t, "powerstat_cstate",
+		map[string]interface{}{"time_us": int64(1519), "usage": int64(21)},
+		map[string]string{"cpu": "cpu0", "state": "POLL"})
+	acc.AssertContainsTaggedFields(t, "powerstat_cstate",
+		map[string]interface{}{"time_us": int64(77129354), "usage": int64(104330)},
+		map[string]string{"cpu": "cpu0", "state": "C1E"})
+	require.Len(t, acc.Metrics, 3)
+}
+
+func TestGatherBatteries(t *testing.T) {
+	sysfs := newSysfs(t, map[string]string{
+		"class/power_supply/AC/type":                     "Mains",
+		"class/power_supply/AC/online":                   "0",
+		"class/power_supply/BAT0/type":                   "Battery",
+		"class/power_supply/BAT0/present":                "1",
+		"class/power_supply/BAT0/status":                 "Discharging",
+		"class/power_supply/BAT0/capacity":               "87",
+		"class/power_supply/BAT0/energy_now":             "43510000",
+		"class/power_supply/BAT0/energy_full":            "50010000",
+		"class/power_supply/BAT0/energy_full_design":     "57020000",
+		"class/power_supply/BAT0/power_now":              "9352000",
+		"class/power_supply/BAT0/voltage_now":            "12412000",
+		"class/power_supply/BAT1/type":                   "Battery",
+		"class/power_supply/BAT1/present":                "1",
+		"class/power_supply/BAT1/status":                 "Full",
+		"class/power_supply/BAT1/capacity":               "100",
+		"class/power_supply/BAT1/charge_now":             "3950000",
+		"class/power_supply/BAT1/charge_full":            "3950000",
+		"class/power_supply/BAT1/current_now":            "0",
+		"class/power_supply/hidpp_battery_0/type":        "Battery",
+		"class/power_supply/hidpp_battery_0/present":     "0",
+		"class/power_supply/hidpp_battery_0/capacity":    "50",
+		"class/power_supply/hidpp_battery_0/status":      "Unknown",
+		"class/power_supply/hidpp_battery_0/voltage_now": "3900000",
+	})
+	defer os.RemoveAll(sysfs)
+
+	p := &Powerstat{
+		Metrics:   []string{"battery"},
+		SysfsPath: sysfs,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "powerstat_battery",
+		map[string]interface{}{
+			"capacity_percent":      int64(87),
+			"energy_wh":             43.51,
+			"energy_full_wh":        50.01,
+			"energy_full_design_wh": 57.02,
+			"power_watts":           9.352,
+			"voltage_volts":         12.412,
+		},
+		map[string]string{"battery": "BAT0", "status": "Discharging"})
+	acc.AssertContainsTaggedFields(t, "powerstat_battery",
+		map[string]interface{}{
+			"capacity_percent": int64(100),
+			"charge_ah":        3.95,
+			"charge_full_ah":   3.95,
+			"current_amperes":  float64(0),
+		},
+		map[string]string{"battery": "BAT1", "status": "Full"})
+	require.Len(t, acc.Metrics, 2)
+}
+
+func TestGatherDCMI(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	p := newPowerstat("/usr/bin/ipmitool")
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsFields(t, "powerstat_dcmi",
+		map[string]interface{}{
+			"power_watts":         float64(220),
+			"minimum_power_watts": float64(12),
+			"maximum_power_watts": float64(384),
+			"average_power_watts": float64(218),
+		})
+
+	p = newPowerstat("/deactivated/ipmitool")
+	p.UseSudo = true
+	acc = testutil.Accumulator{}
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.EqualError(t, acc.Errors[0], "DCMI power reading is deactivated")
+
+	p = newPowerstat("")
+	acc = testutil.Accumulator{}
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, (&Powerstat{}).Validate())
+	require.NoError(t, (&Powerstat{Metrics: []string{"rapl", "dcmi"}}).Validate())
+	require.EqualError(t, (&Powerstat{Metrics: []string{"gpu"}}).Validate(), `unknown metric "gpu"`)
+}
+
+func newPowerstat(ipmitool string) *Powerstat {
+	return &Powerstat{
+		Metrics:      []string{"dcmi"},
+		IpmitoolPath: ipmitool,
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+	}
+}
+
+// newSysfs returns a temporary directory with the files of a sysfs tree.
+func newSysfs(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "powerstat")
+	require.NoError(t, err)
+	for name, content := range files {
+		writeFile(t, dir, name, content)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content+"\n"), 0644))
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command
+// For example, if you run:
+// GO_WANT_HELPER_PROCESS=1 go test -test.run=TestHelperProcess -- /usr/bin/ipmitool dcmi power reading
+// it returns below mockDCMI.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args[3:]
+	if args[0] == "sudo" {
+		args = args[2:]
+	}
+
+	switch args[0] {
+	case "/usr/bin/ipmitool":
+		fmt.Fprint(os.Stdout, mockDCMI)
+	case "/deactivated/ipmitool":
+		fmt.Fprint(os.Stdout, mockDCMIDeactivated)
+	default:
+		fmt.Fprint(os.Stdout, "command not found")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
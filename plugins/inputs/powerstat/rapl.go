@@ -0,0 +1,66 @@
+// +build linux
+
+package powerstat
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+type energyReading struct {
+	microjoules int64
+	time        time.Time
+}
+
+// gatherRAPL adds the energy consumed by each RAPL zone of the powercap
+// framework, such as the packages and their core, uncore and dram domains,
+// and the average power drawn since the previous gather.
+func (p *Powerstat) gatherRAPL(acc telegraf.Accumulator) error {
+	zones, err := filepath.Glob(p.path("class", "powercap", "intel-rapl:*"))
+	if err != nil {
+		return err
+	}
+
+	now := p.now()
+	readings := make(map[string]energyReading, len(zones))
+	for _, dir := range zones {
+		zone := filepath.Base(dir)
+		name, err := readString(filepath.Join(dir, "name"))
+		if err != nil {
+			return err
+		}
+		energy, err := readInt(filepath.Join(dir, "energy_uj"))
+		if err != nil {
+			return fmt.Errorf("reading energy of RAPL zone %s: %s", zone, err)
+		}
+		reading := energyReading{microjoules: energy, time: now}
+		readings[zone] = reading
+
+		fields := map[string]interface{}{
+			"energy_joules": float64(energy) / 1e6,
+		}
+		if last, ok := p.energy[zone]; ok && now.After(last.time) {
+			consumed := energy - last.microjoules
+			if consumed < 0 {
+				// The counter wrapped around.
+				max, err := readInt(filepath.Join(dir, "max_energy_range_uj"))
+				if err == nil {
+					consumed += max
+				}
+			}
+			if consumed >= 0 {
+				fields["power_watts"] = float64(consumed) / 1e6 / now.Sub(last.time).Seconds()
+			}
+		}
+		tags := map[string]string{
+			"zone":   zone,
+			"domain": name,
+		}
+		acc.AddFields("powerstat_rapl", fields, tags, now)
+	}
+	p.energy = readings
+	return nil
+}
@@ -0,0 +1,254 @@
+package raid
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	// Controllers found: 1
+	arcconfControllers = regexp.MustCompile(`^Controllers found:\s*(\d+)`)
+	// Logical Device number 0
+	arcconfLogicalDevice = regexp.MustCompile(`(?i)^\s*Logical Device number (\d+)`)
+	// Device #0
+	arcconfDevice = regexp.MustCompile(`^\s*Device #(\d+)`)
+	//    Status of Logical Device                 : Optimal
+	//    Reported Channel,Device(T:L)             : 0,0(0:0)
+	arcconfKeyValue = regexp.MustCompile(`^\s*(.+?)\s+:\s*(.*)$`)
+)
+
+var arcconfLogicalDeviceStates = map[string]int{
+	"optimal":                   stateOK,
+	"degraded":                  stateDegraded,
+	"rebuilding":                stateDegraded,
+	"suboptimal_fault_tolerant": stateDegraded,
+	"impacted":                  stateDegraded,
+	"failed":                    stateFailed,
+	"offline":                   stateFailed,
+}
+
+var arcconfDiskStates = map[string]int{
+	"online":              stateOK,
+	"ready":               stateOK,
+	"hot_spare":           stateOK,
+	"global_hot_spare":    stateOK,
+	"dedicated_hot_spare": stateOK,
+	"raw":                 stateOK,
+	"rebuilding":          stateDegraded,
+	"failed":              stateFailed,
+	"offline":             stateFailed,
+	"missing":             stateFailed,
+}
+
+type arcconfSection int
+
+const (
+	sectionNone arcconfSection = iota
+	sectionBattery
+	sectionLogicalDevice
+	sectionDevice
+)
+
+// arcconfConfig is the configuration of a controller, as reported by
+// "arcconf GETCONFIG <controller> AL".
+type arcconfConfig struct {
+	controllers    int
+	battery        map[string]string
+	logicalDevices []map[string]string
+	devices        []map[string]string
+}
+
+func (r *Raid) gatherArcconf(acc telegraf.Accumulator, path string) error {
+	for controller := 1; ; controller++ {
+		id := strconv.Itoa(controller)
+		out, err := r.run(path, "GETCONFIG", id, "AL")
+		if err != nil {
+			return err
+		}
+		config := parseArcconfConfig(out)
+
+		out, err = r.run(path, "GETSTATUS", id)
+		if err != nil {
+			acc.AddError(err)
+		}
+		rebuilds := parseArcconfStatus(out)
+
+		gatherArcconfController(acc, id, config, rebuilds)
+
+		if controller >= config.controllers {
+			return nil
+		}
+	}
+}
+
+func parseArcconfConfig(out []byte) *arcconfConfig {
+	config := &arcconfConfig{}
+	section := sectionNone
+	var current map[string]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if m := arcconfControllers.FindStringSubmatch(line); m != nil {
+			config.controllers, _ = strconv.Atoi(m[1])
+			continue
+		}
+		switch {
+		case trimmed == "Controller Battery Information":
+			section = sectionBattery
+			config.battery = make(map[string]string)
+			current = config.battery
+			continue
+		case arcconfLogicalDevice.MatchString(line):
+			section = sectionLogicalDevice
+			current = map[string]string{
+				"number": arcconfLogicalDevice.FindStringSubmatch(line)[1],
+			}
+			config.logicalDevices = append(config.logicalDevices, current)
+			continue
+		case arcconfDevice.MatchString(line):
+			section = sectionDevice
+			current = map[string]string{}
+			config.devices = append(config.devices, current)
+			continue
+		case strings.HasSuffix(trimmed, "information") && !strings.Contains(trimmed, ":"):
+			// Headers of other sections, such as "Physical Device information".
+			section = sectionNone
+			current = nil
+			continue
+		case section == sectionDevice && strings.HasPrefix(trimmed, "Device is a"):
+			current["type"] = strings.TrimPrefix(strings.TrimPrefix(trimmed, "Device is an "), "Device is a ")
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if m := arcconfKeyValue.FindStringSubmatch(line); m != nil {
+			// Only the first value of a key is kept, sub sections repeat
+			// keys such as "Status".
+			if _, ok := current[m[1]]; !ok {
+				current[m[1]] = strings.TrimSpace(m[2])
+			}
+		}
+	}
+	return config
+}
+
+// parseArcconfStatus returns the progress of the rebuild tasks of the
+// logical devices, as reported by "arcconf GETSTATUS <controller>".
+func parseArcconfStatus(out []byte) map[string]float64 {
+	rebuilds := make(map[string]float64)
+	var device, operation string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := arcconfKeyValue.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		switch strings.ToLower(m[1]) {
+		case "logical device", "logical device number":
+			device, operation = m[2], ""
+		case "current operation":
+			operation = m[2]
+		case "percentage complete":
+			if device == "" || !strings.EqualFold(operation, "Rebuild") {
+				continue
+			}
+			if v, err := strconv.ParseFloat(m[2], 64); err == nil {
+				rebuilds[device] = v
+			}
+		}
+	}
+	return rebuilds
+}
+
+func gatherArcconfController(
+	acc telegraf.Accumulator,
+	controller string,
+	config *arcconfConfig,
+	rebuilds map[string]float64,
+) {
+	for _, ld := range config.logicalDevices {
+		state := normalizeState(ld["Status of Logical Device"])
+		code, ok := arcconfLogicalDeviceStates[state]
+		if !ok {
+			code = stateUnknown
+		}
+		tags := map[string]string{
+			"controller":    controller,
+			"virtual_drive": ld["number"],
+			"raid_level":    ld["RAID level"],
+			"state":         state,
+		}
+		if name := ld["Logical Device name"]; name != "" {
+			tags["name"] = name
+		}
+		fields := map[string]interface{}{
+			"state_code": code,
+		}
+		if progress, ok := rebuilds[ld["number"]]; ok {
+			fields["rebuild_progress"] = progress
+		}
+		acc.AddFields("raid_virtual_drive", fields, tags)
+	}
+
+	for _, device := range config.devices {
+		if strings.Contains(device["type"], "Enclosure") {
+			continue
+		}
+		state := normalizeState(device["State"])
+		code, ok := arcconfDiskStates[state]
+		if !ok {
+			code = stateUnknown
+		}
+		// Reported Channel,Device(T:L) : 0,0(0:0)
+		slot := device["Reported Channel,Device(T:L)"]
+		if i := strings.Index(slot, "("); i > 0 {
+			slot = slot[:i]
+		}
+		tags := map[string]string{
+			"controller": controller,
+			"slot":       slot,
+			"state":      state,
+			"model":      device["Model"],
+		}
+		fields := map[string]interface{}{
+			"state_code": code,
+		}
+		if v, err := strconv.ParseInt(device["S.M.A.R.T. warnings"], 10, 64); err == nil {
+			fields["smart_warnings"] = v
+		}
+		acc.AddFields("raid_physical_disk", fields, tags)
+	}
+
+	if config.battery != nil && config.battery["Status"] != "" {
+		state := config.battery["Status"]
+		code := stateFailed
+		if state == "Optimal" {
+			code = stateOK
+		}
+		tags := map[string]string{
+			"controller": controller,
+			"type":       "bbu",
+			"state":      normalizeState(state),
+		}
+		fields := map[string]interface{}{
+			"state_code": code,
+		}
+		// Capacity remaining : 100 percent
+		capacity := strings.TrimSuffix(config.battery["Capacity remaining"], " percent")
+		if v, err := strconv.ParseInt(capacity, 10, 64); err == nil {
+			fields["capacity_percent"] = v
+		}
+		acc.AddFields("raid_battery", fields, tags)
+	}
+}
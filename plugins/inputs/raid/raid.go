@@ -0,0 +1,128 @@
+package raid
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// State codes of virtual drives, physical disks and batteries, so that
+// degradation can be alerted on without knowing the states of each tool.
+const (
+	stateOK       = 0
+	stateDegraded = 1
+	stateFailed   = 2
+	stateUnknown  = 3
+)
+
+var defaultPaths = map[string]string{
+	"storcli": "/opt/MegaRAID/storcli/storcli64",
+	"perccli": "/opt/MegaRAID/perccli/perccli64",
+	"arcconf": "/usr/sbin/arcconf",
+}
+
+type Raid struct {
+	Tool    string            `toml:"tool"`
+	Path    string            `toml:"path"`
+	UseSudo bool              `toml:"use_sudo"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+var sampleConfig = `
+  ## Command line utility of the RAID controllers, one of "storcli" and
+  ## "perccli" for LSI/Broadcom MegaRAID and Dell PERC controllers, or
+  ## "arcconf" for Adaptec/Microsemi controllers.
+  tool = "storcli"
+
+  ## Path of the utility, by default the install location of the vendor
+  ## packages: "/opt/MegaRAID/storcli/storcli64",
+  ## "/opt/MegaRAID/perccli/perccli64" or "/usr/sbin/arcconf".
+  # path = ""
+
+  ## The utilities require root access.  Setting 'use_sudo' to true will
+  ## make use of sudo to run the utility.  Sudo must be configured to allow
+  ## the telegraf user to run it without password.
+  # use_sudo = false
+
+  ## Timeout for each run of the utility, querying the controllers may take
+  ## several seconds.
+  # timeout = "20s"
+`
+
+func (r *Raid) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Raid) Description() string {
+	return "Read virtual drive, physical disk and battery states from RAID controllers"
+}
+
+func (r *Raid) Gather(acc telegraf.Accumulator) error {
+	path := r.Path
+	if path == "" {
+		path = defaultPaths[r.Tool]
+	}
+
+	switch r.Tool {
+	case "storcli", "perccli":
+		return r.gatherStorcli(acc, path)
+	case "arcconf":
+		return r.gatherArcconf(acc, path)
+	default:
+		return fmt.Errorf("unknown tool %q", r.Tool)
+	}
+}
+
+// run runs the utility with the arguments and returns its output.
+func (r *Raid) run(path string, args ...string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if r.UseSudo {
+		cmd = execCommand("sudo", append([]string{"-n", path}, args...)...)
+	} else {
+		cmd = execCommand(path, args...)
+	}
+
+	out, err := internal.CombinedOutputTimeout(cmd, r.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s",
+			strings.Join(cmd.Args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// parseTemperature parses temperatures such as "28C" or " 30C (86.00 F)".
+func parseTemperature(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if end <= 0 || s[end] != 'C' {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(s[:end], 10, 64)
+	return v, err == nil
+}
+
+var stateReplacer = strings.NewReplacer(", ", "_", " ", "_", "-", "_")
+
+// normalizeState converts states such as "Suboptimal, Fault Tolerant" to
+// "suboptimal_fault_tolerant".
+func normalizeState(state string) string {
+	return stateReplacer.Replace(strings.ToLower(strings.TrimSpace(state)))
+}
+
+func init() {
+	inputs.Add("raid", func() telegraf.Input {
+		return &Raid{
+			Tool:    "storcli",
+			Timeout: internal.Duration{Duration: 20 * time.Second},
+		}
+	})
+}
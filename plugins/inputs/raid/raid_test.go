@@ -0,0 +1,521 @@
+package raid
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherStorcli(t *testing.T) {
+	r := newRaid("storcli")
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	acc.AssertContainsTaggedFields(t, "raid_virtual_drive",
+		map[string]interface{}{
+			"state_code": stateDegraded,
+		},
+		map[string]string{
+			"controller":    "0",
+			"virtual_drive": "0/0",
+			"raid_level":    "RAID1",
+			"state":         "degraded",
+			"name":          "system",
+		})
+
+	acc.AssertContainsTaggedFields(t, "raid_battery",
+		map[string]interface{}{
+			"state_code":    stateOK,
+			"temperature_c": int64(28),
+		},
+		map[string]string{
+			"controller": "0",
+			"type":       "cachevault",
+			"model":      "CVPM02",
+			"state":      "optimal",
+		})
+
+	acc.AssertContainsTaggedFields(t, "raid_physical_disk",
+		map[string]interface{}{
+			"state_code":          stateOK,
+			"media_errors":        int64(0),
+			"other_errors":        int64(2),
+			"predictive_failures": int64(0),
+			"temperature_c":       int64(30),
+			"smart_alert":         false,
+		},
+		map[string]string{
+			"controller": "0",
+			"slot":       "252:0",
+			"state":      "online",
+			"interface":  "SATA",
+			"media":      "SSD",
+			"model":      "INTEL SSDSC2BB240G4",
+		})
+
+	acc.AssertContainsTaggedFields(t, "raid_physical_disk",
+		map[string]interface{}{
+			"state_code":          stateDegraded,
+			"media_errors":        int64(12),
+			"other_errors":        int64(0),
+			"predictive_failures": int64(1),
+			"temperature_c":       int64(33),
+			"smart_alert":         true,
+			"rebuild_progress":    float64(42),
+		},
+		map[string]string{
+			"controller": "0",
+			"slot":       "252:1",
+			"state":      "rebuild",
+			"interface":  "SATA",
+			"media":      "SSD",
+			"model":      "INTEL SSDSC2BB240G4",
+		})
+
+	require.Len(t, acc.Metrics, 4)
+}
+
+func TestGatherPerccliSudo(t *testing.T) {
+	r := newRaid("perccli")
+	r.UseSudo = true
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+	assert.True(t, acc.HasMeasurement("raid_virtual_drive"))
+	assert.True(t, acc.HasMeasurement("raid_physical_disk"))
+}
+
+func TestGatherStorcliCommandFailed(t *testing.T) {
+	r := newRaid("storcli")
+	r.Path = "/failing/storcli64"
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	var acc testutil.Accumulator
+	err := r.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed on controller 0: Un-supported command")
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestGatherArcconf(t *testing.T) {
+	r := newRaid("arcconf")
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	acc.AssertContainsTaggedFields(t, "raid_virtual_drive",
+		map[string]interface{}{
+			"state_code":       stateDegraded,
+			"rebuild_progress": float64(17),
+		},
+		map[string]string{
+			"controller":    "1",
+			"virtual_drive": "0",
+			"raid_level":    "5",
+			"state":         "rebuilding",
+			"name":          "data",
+		})
+
+	acc.AssertContainsTaggedFields(t, "raid_physical_disk",
+		map[string]interface{}{
+			"state_code":     stateOK,
+			"smart_warnings": int64(0),
+		},
+		map[string]string{
+			"controller": "1",
+			"slot":       "0,0",
+			"state":      "online",
+			"model":      "ST4000NM0035-1V4",
+		})
+	acc.AssertContainsTaggedFields(t, "raid_physical_disk",
+		map[string]interface{}{
+			"state_code":     stateDegraded,
+			"smart_warnings": int64(3),
+		},
+		map[string]string{
+			"controller": "1",
+			"slot":       "0,1",
+			"state":      "rebuilding",
+			"model":      "ST4000NM0035-1V4",
+		})
+
+	acc.AssertContainsTaggedFields(t, "raid_battery",
+		map[string]interface{}{
+			"state_code":       stateOK,
+			"capacity_percent": int64(99),
+		},
+		map[string]string{
+			"controller": "1",
+			"type":       "bbu",
+			"state":      "optimal",
+		})
+
+	// The enclosure is not a physical disk, the second controller has no
+	// logical devices.
+	acc.AssertContainsTaggedFields(t, "raid_physical_disk",
+		map[string]interface{}{
+			"state_code": stateFailed,
+		},
+		map[string]string{
+			"controller": "2",
+			"slot":       "0,0",
+			"state":      "failed",
+			"model":      "HUS726040ALS210",
+		})
+
+	require.Len(t, acc.Metrics, 5)
+}
+
+func TestGatherUnknownTool(t *testing.T) {
+	r := newRaid("megacli")
+
+	var acc testutil.Accumulator
+	err := r.Gather(&acc)
+	require.Error(t, err)
+	assert.Equal(t, `unknown tool "megacli"`, err.Error())
+}
+
+func TestParseTemperature(t *testing.T) {
+	tests := []struct {
+		in    string
+		value int64
+		ok    bool
+	}{
+		{"28C", 28, true},
+		{" 30C (86.00 F)", 30, true},
+		{"N/A", 0, false},
+		{"", 0, false},
+		{"86F", 0, false},
+	}
+	for _, tt := range tests {
+		v, ok := parseTemperature(tt.in)
+		assert.Equal(t, tt.ok, ok, tt.in)
+		assert.Equal(t, tt.value, v, tt.in)
+	}
+}
+
+func TestNormalizeState(t *testing.T) {
+	assert.Equal(t, "suboptimal_fault_tolerant", normalizeState("Suboptimal, Fault Tolerant"))
+	assert.Equal(t, "hot_spare", normalizeState(" Hot-Spare "))
+	assert.Equal(t, "optimal", normalizeState("Optimal"))
+}
+
+func newRaid(tool string) *Raid {
+	return &Raid{
+		Tool:    tool,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command
+// For example, if you run:
+// GO_WANT_HELPER_PROCESS=1 go test -test.run=TestHelperProcess -- /usr/sbin/arcconf GETSTATUS 1
+// it returns below mockArcconfStatus.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args[3:]
+	if args[0] == "sudo" {
+		if args[1] != "-n" {
+			fmt.Fprint(os.Stdout, "sudo: a password is required")
+			os.Exit(1)
+		}
+		args = args[2:]
+	}
+
+	cmd, argv := args[0], strings.Join(args[1:], " ")
+	switch {
+	case cmd == "/failing/storcli64":
+		fmt.Fprint(os.Stdout, mockStorcliFailed)
+	case strings.HasSuffix(cmd, "cli64") && argv == "/call show all J":
+		fmt.Fprint(os.Stdout, mockStorcliController)
+	case strings.HasSuffix(cmd, "cli64") && argv == "/call/eall/sall show rebuild J":
+		fmt.Fprint(os.Stdout, mockStorcliRebuild)
+	case strings.HasSuffix(cmd, "cli64") && argv == "/call/eall/sall show all J":
+		fmt.Fprint(os.Stdout, mockStorcliDrives)
+	case cmd == "/usr/sbin/arcconf" && argv == "GETCONFIG 1 AL":
+		fmt.Fprint(os.Stdout, mockArcconfConfig1)
+	case cmd == "/usr/sbin/arcconf" && argv == "GETSTATUS 1":
+		fmt.Fprint(os.Stdout, mockArcconfStatus)
+	case cmd == "/usr/sbin/arcconf" && argv == "GETCONFIG 2 AL":
+		fmt.Fprint(os.Stdout, mockArcconfConfig2)
+	case cmd == "/usr/sbin/arcconf" && argv == "GETSTATUS 2":
+		fmt.Fprint(os.Stdout, "Controllers found: 2\nLogical device Task:\n   No tasks\n")
+	default:
+		fmt.Fprintf(os.Stdout, "unexpected command %s %s", cmd, argv)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+const mockStorcliFailed = `{
+"Controllers":[
+{
+	"Command Status" : {
+		"CLI Version" : "007.0606.0000.0000 Mar 20, 2018",
+		"Controller" : 0,
+		"Status" : "Failure",
+		"Description" : "Un-supported command"
+	}
+}
+]
+}`
+
+const mockStorcliController = `{
+"Controllers":[
+{
+	"Command Status" : {
+		"CLI Version" : "007.0606.0000.0000 Mar 20, 2018",
+		"Operating system" : "Linux 4.15.0-23-generic",
+		"Controller" : 0,
+		"Status" : "Success",
+		"Description" : "None"
+	},
+	"Response Data" : {
+		"Basics" : {
+			"Controller" : 0,
+			"Model" : "AVAGO MegaRAID SAS 9361-8i"
+		},
+		"Virtual Drives" : 1,
+		"VD LIST" : [
+			{
+				"DG/VD" : "0/0",
+				"TYPE" : "RAID1",
+				"State" : "Dgrd",
+				"Access" : "RW",
+				"Consist" : "No",
+				"Cache" : "RWBD",
+				"Cac" : "-",
+				"sCC" : "ON",
+				"Size" : "222.585 GB",
+				"Name" : "system"
+			}
+		],
+		"Physical Drives" : 2,
+		"Cachevault_Info" : [
+			{
+				"Model" : "CVPM02",
+				"State" : "Optimal",
+				"Temp" : "28C",
+				"Mode" : "-",
+				"MfgDate" : "2017/03/28"
+			}
+		]
+	}
+}
+]
+}`
+
+const mockStorcliRebuild = `{
+"Controllers":[
+{
+	"Command Status" : {
+		"CLI Version" : "007.0606.0000.0000 Mar 20, 2018",
+		"Controller" : 0,
+		"Status" : "Success",
+		"Description" : "Show Drive Rebuild Status Succeeded."
+	},
+	"Response Data" : [
+		{
+			"Drive-ID" : "/c0/e252/s0",
+			"Progress%" : "-",
+			"Status" : "Not in progress",
+			"Estimated Time Left" : "-"
+		},
+		{
+			"Drive-ID" : "/c0/e252/s1",
+			"Progress%" : 42,
+			"Status" : "In progress",
+			"Estimated Time Left" : "12 Minutes"
+		}
+	]
+}
+]
+}`
+
+const mockStorcliDrives = `{
+"Controllers":[
+{
+	"Command Status" : {
+		"CLI Version" : "007.0606.0000.0000 Mar 20, 2018",
+		"Controller" : 0,
+		"Status" : "Success",
+		"Description" : "Show Drive Information Succeeded."
+	},
+	"Response Data" : {
+		"Drive /c0/e252/s0" : [
+			{
+				"EID:Slt" : "252:0",
+				"DID" : 4,
+				"State" : "Onln",
+				"DG" : 0,
+				"Size" : "222.585 GB",
+				"Intf" : "SATA",
+				"Med" : "SSD",
+				"SED" : "N",
+				"PI" : "N",
+				"SeSz" : "512B",
+				"Model" : "INTEL SSDSC2BB240G4 ",
+				"Sp" : "U",
+				"Type" : "-"
+			}
+		],
+		"Drive /c0/e252/s0 - Detailed Information" : {
+			"Drive /c0/e252/s0 State" : {
+				"Shield Counter" : 0,
+				"Media Error Count" : 0,
+				"Other Error Count" : 2,
+				"Drive Temperature" : " 30C (86.00 F)",
+				"Predictive Failure Count" : 0,
+				"S.M.A.R.T alert flagged by drive" : "No"
+			},
+			"Drive /c0/e252/s0 Device attributes" : {
+				"SN" : "BTWL4253019H240NGN",
+				"Firmware Revision" : "D2010370"
+			}
+		},
+		"Drive /c0/e252/s1" : [
+			{
+				"EID:Slt" : "252:1",
+				"DID" : 5,
+				"State" : "Rbld",
+				"DG" : 0,
+				"Size" : "222.585 GB",
+				"Intf" : "SATA",
+				"Med" : "SSD",
+				"SED" : "N",
+				"PI" : "N",
+				"SeSz" : "512B",
+				"Model" : "INTEL SSDSC2BB240G4 ",
+				"Sp" : "U",
+				"Type" : "-"
+			}
+		],
+		"Drive /c0/e252/s1 - Detailed Information" : {
+			"Drive /c0/e252/s1 State" : {
+				"Shield Counter" : 0,
+				"Media Error Count" : 12,
+				"Other Error Count" : 0,
+				"Drive Temperature" : " 33C (91.40 F)",
+				"Predictive Failure Count" : 1,
+				"S.M.A.R.T alert flagged by drive" : "Yes"
+			}
+		}
+	}
+}
+]
+}`
+
+const mockArcconfConfig1 = `Controllers found: 2
+----------------------------------------------------------------------
+Controller information
+----------------------------------------------------------------------
+   Controller Status                        : Optimal
+   Controller Model                         : Adaptec ASR8405
+   --------------------------------------------------------
+   Controller Battery Information
+   --------------------------------------------------------
+   Status                                   : Optimal
+   Over temperature                         : No
+   Capacity remaining                       : 99 percent
+   Time remaining (at current draw)         : 3 days, 1 hours, 11 minutes
+
+----------------------------------------------------------------------
+Logical device information
+----------------------------------------------------------------------
+Logical Device number 0
+   Logical Device name                      : data
+   Block Size of member drives              : 512 Bytes
+   RAID level                               : 5
+   Status of Logical Device                 : Rebuilding
+   Size                                     : 7629312 MB
+   --------------------------------------------------------
+   Logical Device segment information
+   --------------------------------------------------------
+   Group 0, Segment 0                       : Present (Controller:1,Enclosure:0,Slot:0)
+   Group 0, Segment 1                       : Rebuilding (Controller:1,Enclosure:0,Slot:1)
+
+----------------------------------------------------------------------
+Physical Device information
+----------------------------------------------------------------------
+      Device #0
+         Device is a Hard drive
+         State                              : Online
+         Block Size                         : 512 Bytes
+         Supported                          : Yes
+         Transfer Speed                     : SATA 6.0 Gb/s
+         Reported Channel,Device(T:L)       : 0,0(0:0)
+         Vendor                             : 
+         Model                              : ST4000NM0035-1V4
+         S.M.A.R.T. warnings                : 0
+      Device #1
+         Device is a Hard drive
+         State                              : Rebuilding
+         Reported Channel,Device(T:L)       : 0,1(1:0)
+         Model                              : ST4000NM0035-1V4
+         S.M.A.R.T. warnings                : 3
+      Device #2
+         Device is an Enclosure services device
+         Reported Channel,Device(T:L)       : 2,0(0:0)
+         Model                              : SGPIO
+`
+
+const mockArcconfStatus = `Controllers found: 2
+Logical device Task:
+   Logical Device                 : 0
+   Task ID                        : 101
+   Current operation              : Rebuild
+   Status                         : In Progress
+   Priority                       : High
+   Percentage complete            : 17
+
+Command completed successfully.
+`
+
+const mockArcconfConfig2 = `Controllers found: 2
+----------------------------------------------------------------------
+Controller information
+----------------------------------------------------------------------
+   Controller Status                        : Optimal
+   Controller Model                         : Adaptec ASR71605
+
+----------------------------------------------------------------------
+Logical device information
+----------------------------------------------------------------------
+   No logical devices configured
+
+----------------------------------------------------------------------
+Physical Device information
+----------------------------------------------------------------------
+      Device #0
+         Device is a Hard drive
+         State                              : Failed
+         Reported Channel,Device(T:L)       : 0,0(0:0)
+         Model                              : HUS726040ALS210
+`
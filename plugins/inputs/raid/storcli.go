@@ -0,0 +1,304 @@
+package raid
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// storcliVirtualDriveStates maps the abbreviated states of virtual drives
+// to their names and state codes.
+var storcliVirtualDriveStates = map[string]struct {
+	name string
+	code int
+}{
+	"Optl": {"optimal", stateOK},
+	"Pdgd": {"partially_degraded", stateDegraded},
+	"Dgrd": {"degraded", stateDegraded},
+	"Rec":  {"recovery", stateDegraded},
+	"OfLn": {"offline", stateFailed},
+}
+
+// storcliDiskStates maps the abbreviated states of physical disks to their
+// names and state codes.
+var storcliDiskStates = map[string]struct {
+	name string
+	code int
+}{
+	"Onln":   {"online", stateOK},
+	"UGood":  {"unconfigured_good", stateOK},
+	"GHS":    {"global_hot_spare", stateOK},
+	"DHS":    {"dedicated_hot_spare", stateOK},
+	"JBOD":   {"jbod", stateOK},
+	"Rbld":   {"rebuild", stateDegraded},
+	"Cpybck": {"copyback", stateDegraded},
+	"Offln":  {"offline", stateFailed},
+	"UBad":   {"unconfigured_bad", stateFailed},
+	"Msng":   {"missing", stateFailed},
+	"Failed": {"failed", stateFailed},
+}
+
+type storcliResponse struct {
+	Controllers []struct {
+		CommandStatus struct {
+			Controller  json.Number `json:"Controller"`
+			Status      string      `json:"Status"`
+			Description string      `json:"Description"`
+		} `json:"Command Status"`
+		ResponseData json.RawMessage `json:"Response Data"`
+	} `json:"Controllers"`
+}
+
+type storcliControllerData struct {
+	VirtualDrives []struct {
+		DGVD  string `json:"DG/VD"`
+		Type  string `json:"TYPE"`
+		State string `json:"State"`
+		Name  string `json:"Name"`
+	} `json:"VD LIST"`
+	BBU []struct {
+		Model string `json:"Model"`
+		State string `json:"State"`
+		Temp  string `json:"Temp"`
+	} `json:"BBU_Info"`
+	Cachevault []struct {
+		Model string `json:"Model"`
+		State string `json:"State"`
+		Temp  string `json:"Temp"`
+	} `json:"Cachevault_Info"`
+}
+
+type storcliDrive struct {
+	EIDSlt string `json:"EID:Slt"`
+	State  string `json:"State"`
+	Intf   string `json:"Intf"`
+	Med    string `json:"Med"`
+	Model  string `json:"Model"`
+}
+
+type storcliDriveState struct {
+	MediaErrors        *int64 `json:"Media Error Count"`
+	OtherErrors        *int64 `json:"Other Error Count"`
+	PredictiveFailures *int64 `json:"Predictive Failure Count"`
+	Temperature        string `json:"Drive Temperature"`
+	SMARTAlert         string `json:"S.M.A.R.T alert flagged by drive"`
+}
+
+type storcliRebuild struct {
+	DriveID  string      `json:"Drive-ID"`
+	Progress interface{} `json:"Progress%"`
+	Status   string      `json:"Status"`
+}
+
+func (r *Raid) gatherStorcli(acc telegraf.Accumulator, path string) error {
+	controllers, err := r.storcli(path, "/call", "show", "all", "J")
+	if err != nil {
+		return err
+	}
+	for controller, data := range controllers {
+		if err := gatherStorcliController(acc, controller, data); err != nil {
+			acc.AddError(fmt.Errorf("controller %s: %s", controller, err))
+		}
+	}
+
+	rebuilds := make(map[string]float64)
+	controllers, err = r.storcli(path, "/call/eall/sall", "show", "rebuild", "J")
+	if err != nil {
+		acc.AddError(err)
+	}
+	for _, data := range controllers {
+		var progress []storcliRebuild
+		if err := json.Unmarshal(data, &progress); err != nil {
+			acc.AddError(err)
+			continue
+		}
+		for _, p := range progress {
+			if !strings.EqualFold(p.Status, "In progress") {
+				continue
+			}
+			if v, ok := parseProgress(p.Progress); ok {
+				rebuilds[p.DriveID] = v
+			}
+		}
+	}
+
+	controllers, err = r.storcli(path, "/call/eall/sall", "show", "all", "J")
+	if err != nil {
+		return err
+	}
+	for controller, data := range controllers {
+		if err := gatherStorcliDrives(acc, controller, data, rebuilds); err != nil {
+			acc.AddError(fmt.Errorf("controller %s: %s", controller, err))
+		}
+	}
+	return nil
+}
+
+// storcli runs a storcli command and returns the response data of each
+// controller.
+func (r *Raid) storcli(path string, args ...string) (map[string]json.RawMessage, error) {
+	out, err := r.run(path, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &storcliResponse{}
+	if err := json.Unmarshal(out, resp); err != nil {
+		return nil, fmt.Errorf("parsing output of %s: %s", strings.Join(args, " "), err)
+	}
+
+	controllers := make(map[string]json.RawMessage)
+	for _, c := range resp.Controllers {
+		if c.CommandStatus.Status != "Success" {
+			return nil, fmt.Errorf("%s failed on controller %s: %s", strings.Join(args, " "),
+				c.CommandStatus.Controller, c.CommandStatus.Description)
+		}
+		controllers[c.CommandStatus.Controller.String()] = c.ResponseData
+	}
+	return controllers, nil
+}
+
+func gatherStorcliController(acc telegraf.Accumulator, controller string, raw json.RawMessage) error {
+	data := &storcliControllerData{}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return err
+	}
+
+	for _, vd := range data.VirtualDrives {
+		state, ok := storcliVirtualDriveStates[vd.State]
+		if !ok {
+			state.name = normalizeState(vd.State)
+			state.code = stateUnknown
+		}
+		tags := map[string]string{
+			"controller":    controller,
+			"virtual_drive": vd.DGVD,
+			"raid_level":    vd.Type,
+			"state":         state.name,
+		}
+		if vd.Name != "" {
+			tags["name"] = vd.Name
+		}
+		fields := map[string]interface{}{
+			"state_code": state.code,
+		}
+		acc.AddFields("raid_virtual_drive", fields, tags)
+	}
+
+	addBattery := func(typ, model, state, temp string) {
+		code := stateFailed
+		if state == "Optimal" {
+			code = stateOK
+		}
+		tags := map[string]string{
+			"controller": controller,
+			"type":       typ,
+			"model":      model,
+			"state":      normalizeState(state),
+		}
+		fields := map[string]interface{}{
+			"state_code": code,
+		}
+		if t, ok := parseTemperature(temp); ok {
+			fields["temperature_c"] = t
+		}
+		acc.AddFields("raid_battery", fields, tags)
+	}
+	for _, bbu := range data.BBU {
+		addBattery("bbu", bbu.Model, bbu.State, bbu.Temp)
+	}
+	for _, cv := range data.Cachevault {
+		addBattery("cachevault", cv.Model, cv.State, cv.Temp)
+	}
+	return nil
+}
+
+// gatherStorcliDrives adds the physical disks of the controller.  The
+// response data has a list with the summary of each drive, named such as
+// "Drive /c0/e252/s0", and the details of the drive in
+// "Drive /c0/e252/s0 - Detailed Information".
+func gatherStorcliDrives(
+	acc telegraf.Accumulator,
+	controller string,
+	raw json.RawMessage,
+	rebuilds map[string]float64,
+) error {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+
+	for key, value := range data {
+		if !strings.HasPrefix(key, "Drive /") || strings.Contains(key, " - ") {
+			continue
+		}
+		id := strings.TrimPrefix(key, "Drive ")
+
+		var drives []storcliDrive
+		if err := json.Unmarshal(value, &drives); err != nil || len(drives) == 0 {
+			continue
+		}
+		drive := drives[0]
+
+		state, ok := storcliDiskStates[drive.State]
+		if !ok {
+			state.name = normalizeState(drive.State)
+			state.code = stateUnknown
+		}
+		tags := map[string]string{
+			"controller": controller,
+			"slot":       drive.EIDSlt,
+			"state":      state.name,
+			"interface":  drive.Intf,
+			"media":      drive.Med,
+			"model":      strings.TrimSpace(drive.Model),
+		}
+		fields := map[string]interface{}{
+			"state_code": state.code,
+		}
+
+		var details map[string]json.RawMessage
+		if json.Unmarshal(data[key+" - Detailed Information"], &details) == nil {
+			driveState := &storcliDriveState{}
+			if json.Unmarshal(details[key+" State"], driveState) == nil {
+				if driveState.MediaErrors != nil {
+					fields["media_errors"] = *driveState.MediaErrors
+				}
+				if driveState.OtherErrors != nil {
+					fields["other_errors"] = *driveState.OtherErrors
+				}
+				if driveState.PredictiveFailures != nil {
+					fields["predictive_failures"] = *driveState.PredictiveFailures
+				}
+				if t, ok := parseTemperature(driveState.Temperature); ok {
+					fields["temperature_c"] = t
+				}
+				if driveState.SMARTAlert != "" {
+					fields["smart_alert"] = driveState.SMARTAlert == "Yes"
+				}
+			}
+		}
+
+		if progress, ok := rebuilds[id]; ok {
+			fields["rebuild_progress"] = progress
+		}
+		acc.AddFields("raid_physical_disk", fields, tags)
+	}
+	return nil
+}
+
+// parseProgress parses the rebuild progress, which is a number while the
+// rebuild is in progress and "-" otherwise.
+func parseProgress(v interface{}) (float64, bool) {
+	switch p := v.(type) {
+	case float64:
+		return p, true
+	case string:
+		f, err := strconv.ParseFloat(p, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
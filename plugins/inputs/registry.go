@@ -9,3 +9,12 @@ var Inputs = map[string]Creator{}
 func Add(name string, creator Creator) {
 	Inputs[name] = creator
 }
+
+// Excluded are the inputs excluded from the build, mapped to the build tag
+// excluding them.
+var Excluded = map[string]string{}
+
+// AddExcluded records an input excluded from the build by the build tag.
+func AddExcluded(name, tag string) {
+	Excluded[name] = tag
+}
@@ -0,0 +1,294 @@
+package relay_listener
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/listen"
+	"github.com/influxdata/telegraf/internal/relay"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// defaultMaxMessageSize is the maximum size of the batches by default, in
+// bytes.
+const defaultMaxMessageSize = 32 * 1024 * 1024
+
+// helloTimeout is the time for the clients to send the hello frame.
+const helloTimeout = 10 * time.Second
+
+const sampleConfig = `
+  ## Address and port to accept the relays on.
+  service_address = ":8095"
+
+  ## Additional addresses to listen on.  The addresses may be prefixed by
+  ## tcp4:// or tcp6:// to listen on IPv4 or IPv6 only, eg. "tcp6://[::1]:8095".
+  # service_addresses = []
+
+  ## Set to listen on IPv6 only, or on both IPv4 and IPv6, on the empty and
+  ## IPv6 hosts, instead of the defaults of the system.  The IPv4 hosts,
+  ## including 0.0.0.0, then listen on IPv4 only.  Not supported on Windows.
+  # ipv6_v6only = false
+
+  ## Tokens of the relays allowed to connect, as set with the token option
+  ## of the relay output.  All relays are allowed when empty.  The tokens
+  ## are received in clear text unless TLS is configured.
+  # tokens = []
+
+  ## Maximum number of concurrent connections.
+  ## 0 (default) is unlimited.
+  # max_connections = 1024
+
+  ## Maximum size of the batches in bytes, once decompressed.  The larger
+  ## batches are rejected.  0 means to use the default of 33,554,432 bytes
+  ## (32 mebibytes).
+  # max_message_size = 0
+
+  ## Connections without any batch for read_timeout are closed.
+  ## 0 (default) is unlimited.
+  # read_timeout = "0s"
+
+  ## Optional TLS configuration.
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key  = "/etc/telegraf/key.pem"
+  ## Enables client authentication if set.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+`
+
+type RelayListener struct {
+	ServiceAddress   string            `toml:"service_address"`
+	ServiceAddresses []string          `toml:"service_addresses"`
+	Tokens           []string          `toml:"tokens"`
+	MaxConnections   int               `toml:"max_connections"`
+	MaxMessageSize   int               `toml:"max_message_size"`
+	ReadTimeout      internal.Duration `toml:"read_timeout"`
+	tlsint.ServerConfig
+	listen.Config
+
+	acc      telegraf.Accumulator
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func (l *RelayListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *RelayListener) Description() string {
+	return "Accept metrics from the relay output of other Telegraf instances"
+}
+
+func (l *RelayListener) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (l *RelayListener) Start(acc telegraf.Accumulator) error {
+	l.acc = acc
+	l.conns = make(map[net.Conn]struct{})
+	if l.MaxMessageSize == 0 {
+		l.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	tlsConf, err := l.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	listener, err := l.ListenAll(append([]string{l.ServiceAddress}, l.ServiceAddresses...))
+	if err != nil {
+		return err
+	}
+	if tlsConf != nil {
+		listener = tls.NewListener(listener, tlsConf)
+	} else if len(l.Tokens) != 0 {
+		log.Printf("W! [inputs.relay_listener] The tokens are received in clear text, configure TLS")
+	}
+	l.listener = listener
+
+	l.wg.Add(1)
+	go l.accept()
+
+	log.Printf("I! Started relay listener service on %s", listener.Addr())
+	return nil
+}
+
+func (l *RelayListener) accept() {
+	defer l.wg.Done()
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				l.acc.AddError(err)
+			}
+			return
+		}
+		if !l.add(conn) {
+			conn.Close()
+			continue
+		}
+		go l.handle(conn)
+	}
+}
+
+// add tracks a connection, unless the listener is stopped or at
+// max_connections.
+func (l *RelayListener) add(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns == nil {
+		return false
+	}
+	if l.MaxConnections > 0 && len(l.conns) >= l.MaxConnections {
+		l.acc.AddError(fmt.Errorf("unable to accept connection from %s: too many connections", conn.RemoteAddr()))
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	l.wg.Add(1)
+	return true
+}
+
+func (l *RelayListener) remove(conn net.Conn) {
+	conn.Close()
+	l.mu.Lock()
+	delete(l.conns, conn)
+	l.mu.Unlock()
+	l.wg.Done()
+}
+
+// handle checks the hello frame of a connection, then adds the metrics of
+// its batches until it is closed.
+func (l *RelayListener) handle(conn net.Conn) {
+	defer l.remove(conn)
+	r := bufio.NewReader(conn)
+
+	conn.SetDeadline(time.Now().Add(helloTimeout))
+	hello, err := l.hello(r)
+	if err != nil {
+		l.acc.AddError(fmt.Errorf("rejected relay from %s: %s", conn.RemoteAddr(), err))
+		relay.WriteFrame(conn, relay.Frame{Type: relay.FrameError, Payload: []byte(err.Error())})
+		return
+	}
+	if err := relay.WriteFrame(conn, relay.Frame{Type: relay.FrameWelcome}); err != nil {
+		return
+	}
+
+	parser, err := parsers.NewInfluxParser()
+	if err != nil {
+		l.acc.AddError(err)
+		return
+	}
+	for {
+		if l.ReadTimeout.Duration > 0 {
+			conn.SetDeadline(time.Now().Add(l.ReadTimeout.Duration))
+		} else {
+			conn.SetDeadline(time.Time{})
+		}
+		f, err := relay.ReadFrame(r, l.MaxMessageSize)
+		if err != nil && err != relay.ErrFrameTooLarge {
+			return
+		}
+		if f.Type != relay.FrameBatch {
+			relay.WriteFrame(conn, relay.Frame{Type: relay.FrameError, Payload: []byte(fmt.Sprintf("unexpected frame type %d", f.Type))})
+			return
+		}
+
+		reply := relay.Frame{Type: relay.FrameAck, Seq: f.Seq}
+		if err := l.addBatch(parser, hello.Compression, f, err); err != nil {
+			l.acc.AddError(fmt.Errorf("rejected batch from %s: %s", conn.RemoteAddr(), err))
+			reply = relay.Frame{Type: relay.FrameError, Seq: f.Seq, Payload: []byte(err.Error())}
+		}
+		if err := relay.WriteFrame(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// hello reads the hello frame, and checks the version, compression and
+// token of the relay.
+func (l *RelayListener) hello(r *bufio.Reader) (*relay.Hello, error) {
+	f, err := relay.ReadFrame(r, 64*1024)
+	if err != nil {
+		return nil, err
+	}
+	if f.Type != relay.FrameHello {
+		return nil, fmt.Errorf("expecting a hello frame, got type %d", f.Type)
+	}
+	hello, err := relay.ParseHello(f.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if hello.Version != relay.Version {
+		return nil, fmt.Errorf("unsupported protocol version %d", hello.Version)
+	}
+	if !l.allowed(hello.Token) {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return hello, nil
+}
+
+func (l *RelayListener) allowed(token string) bool {
+	if len(l.Tokens) == 0 {
+		return true
+	}
+	for _, t := range l.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// addBatch adds the metrics of a batch frame, read with err, or none if the
+// batch is rejected.
+func (l *RelayListener) addBatch(parser parsers.Parser, compression string, f relay.Frame, err error) error {
+	if err == relay.ErrFrameTooLarge {
+		return fmt.Errorf("batch exceeds max_message_size")
+	}
+	data, err := relay.Decompress(compression, f.Payload, l.MaxMessageSize)
+	if err == relay.ErrTooLarge {
+		return fmt.Errorf("batch exceeds max_message_size")
+	}
+	if err != nil {
+		return fmt.Errorf("unable to decompress batch: %s", err)
+	}
+	metrics, err := parser.Parse(data)
+	if err != nil {
+		return fmt.Errorf("unable to parse batch: %s", err)
+	}
+	for _, m := range metrics {
+		l.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	return nil
+}
+
+func (l *RelayListener) Stop() {
+	l.listener.Close()
+	l.mu.Lock()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+	l.mu.Unlock()
+	l.wg.Wait()
+	log.Println("I! Stopped relay listener service on ", l.ServiceAddress)
+}
+
+func init() {
+	inputs.Add("relay_listener", func() telegraf.Input {
+		return &RelayListener{
+			ServiceAddress: ":8095",
+		}
+	})
+}
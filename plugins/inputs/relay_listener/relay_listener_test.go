@@ -0,0 +1,181 @@
+package relay_listener
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/relay"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var pki = testutil.NewPKI("../../../testutil/pki")
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, l *RelayListener, tlsConfig *tls.Config) *client {
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.Dial("tcp", l.listener.Addr().String(), tlsConfig)
+	} else {
+		conn, err = net.Dial("tcp", l.listener.Addr().String())
+	}
+	require.NoError(t, err)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *client) send(f relay.Frame) relay.Frame {
+	require.NoError(c.t, relay.WriteFrame(c.conn, f))
+	reply, err := relay.ReadFrame(c.r, 1024)
+	require.NoError(c.t, err)
+	return reply
+}
+
+func (c *client) hello(compression, token string) relay.Frame {
+	h := &relay.Hello{Version: relay.Version, Compression: compression, Token: token}
+	return c.send(relay.Frame{Type: relay.FrameHello, Payload: h.Marshal()})
+}
+
+func (c *client) batch(compression string, seq uint64, data string) relay.Frame {
+	payload, err := relay.Compress(compression, []byte(data))
+	require.NoError(c.t, err)
+	return c.send(relay.Frame{Type: relay.FrameBatch, Seq: seq, Payload: payload})
+}
+
+func newListener() *RelayListener {
+	return &RelayListener{
+		ServiceAddress: "127.0.0.1:0",
+		Tokens:         []string{"secret", "other"},
+	}
+}
+
+func start(t *testing.T, l *RelayListener) *testutil.Accumulator {
+	acc := &testutil.Accumulator{}
+	require.NoError(t, l.Start(acc))
+	return acc
+}
+
+func TestListener(t *testing.T) {
+	l := newListener()
+	acc := start(t, l)
+	defer l.Stop()
+
+	for _, compression := range []string{relay.CompressionNone, relay.CompressionGzip, relay.CompressionSnappy} {
+		c := dial(t, l, nil)
+		require.Equal(t, relay.FrameWelcome, c.hello(compression, "other").Type)
+		reply := c.batch(compression, 1, "cpu,host=a usage=1 1500000000000000000\ncpu,host=b usage=2 1500000000000000000\n")
+		require.Equal(t, relay.Frame{Type: relay.FrameAck, Seq: 1, Payload: []byte{}}, reply)
+		require.Equal(t, relay.FrameAck, c.batch(compression, 2, "mem used=3i 1500000000000000000\n").Type)
+		c.conn.Close()
+	}
+
+	acc.Wait(9)
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"usage": float64(1)}, map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"usage": float64(2)}, map[string]string{"host": "b"})
+	acc.AssertContainsFields(t, "mem", map[string]interface{}{"used": int64(3)})
+	require.Empty(t, acc.Errors)
+}
+
+func TestInvalidToken(t *testing.T) {
+	l := newListener()
+	acc := start(t, l)
+	defer l.Stop()
+
+	c := dial(t, l, nil)
+	reply := c.hello(relay.CompressionNone, "wrong")
+	require.Equal(t, relay.Frame{Type: relay.FrameError, Payload: []byte("invalid token")}, reply)
+	_, err := relay.ReadFrame(c.r, 1024)
+	require.Error(t, err)
+	acc.WaitError(1)
+	require.Contains(t, acc.FirstError().Error(), "invalid token")
+
+	// A batch before the hello is not accepted either.
+	c = dial(t, l, nil)
+	reply = c.batch(relay.CompressionNone, 1, "mem used=3i 1500000000000000000\n")
+	require.Equal(t, relay.FrameError, reply.Type)
+	require.Equal(t, "expecting a hello frame, got type 3", string(reply.Payload))
+}
+
+func TestRejectedBatch(t *testing.T) {
+	l := newListener()
+	l.MaxMessageSize = 64
+	acc := start(t, l)
+	defer l.Stop()
+
+	c := dial(t, l, nil)
+	require.Equal(t, relay.FrameWelcome, c.hello(relay.CompressionGzip, "secret").Type)
+
+	// The batches rejected are replied with their sequence, and the next
+	// ones accepted on the same connection.
+	reply := c.batch(relay.CompressionGzip, 1, "mem used=3i 1500000000000000000\n"+strings.Repeat("mem used=4i 1500000000000000000\n", 10))
+	require.Equal(t, relay.Frame{Type: relay.FrameError, Seq: 1, Payload: []byte("batch exceeds max_message_size")}, reply)
+	reply = c.batch(relay.CompressionNone, 2, strings.Repeat("a", 100))
+	require.Equal(t, relay.Frame{Type: relay.FrameError, Seq: 2, Payload: []byte("batch exceeds max_message_size")}, reply)
+	reply = c.batch(relay.CompressionGzip, 3, "not line protocol\n")
+	require.Equal(t, relay.FrameError, reply.Type)
+	require.Equal(t, uint64(3), reply.Seq)
+	require.Contains(t, string(reply.Payload), "unable to parse batch")
+
+	require.Equal(t, relay.FrameAck, c.batch(relay.CompressionGzip, 4, "mem used=5i 1500000000000000000\n").Type)
+	acc.Wait(1)
+	acc.AssertContainsFields(t, "mem", map[string]interface{}{"used": int64(5)})
+	acc.Lock()
+	require.Len(t, acc.Metrics, 1)
+	require.Len(t, acc.Errors, 3)
+	acc.Unlock()
+}
+
+func TestMaxConnections(t *testing.T) {
+	l := newListener()
+	l.MaxConnections = 1
+	acc := start(t, l)
+	defer l.Stop()
+
+	c := dial(t, l, nil)
+	require.Equal(t, relay.FrameWelcome, c.hello(relay.CompressionNone, "secret").Type)
+
+	other := dial(t, l, nil)
+	relay.WriteFrame(other.conn, relay.Frame{Type: relay.FrameHello})
+	_, err := relay.ReadFrame(other.r, 1024)
+	require.Error(t, err)
+	acc.WaitError(1)
+	require.Contains(t, acc.FirstError().Error(), "too many connections")
+}
+
+func TestTLS(t *testing.T) {
+	l := newListener()
+	l.Tokens = nil
+	l.ServerConfig = *pki.TLSServerConfig()
+	acc := start(t, l)
+	defer l.Stop()
+
+	tlsConfig, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	tlsConfig.ServerName = "localhost"
+	c := dial(t, l, tlsConfig)
+	require.Equal(t, relay.FrameWelcome, c.hello(relay.CompressionSnappy, "").Type)
+	require.Equal(t, relay.FrameAck, c.batch(relay.CompressionSnappy, 1, "mem used=3i 1500000000000000000\n").Type)
+	acc.Wait(1)
+}
+
+func TestStop(t *testing.T) {
+	l := newListener()
+	start(t, l)
+	c := dial(t, l, nil)
+	require.Equal(t, relay.FrameWelcome, c.hello(relay.CompressionNone, "secret").Type)
+
+	// Stop closes the open connections.
+	l.Stop()
+	_, err := relay.ReadFrame(c.r, 1024)
+	require.Error(t, err)
+}
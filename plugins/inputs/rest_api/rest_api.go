@@ -0,0 +1,547 @@
+package rest_api
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/token"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultMeasurement = "rest_api"
+
+type RestAPI struct {
+	BaseURL string            `toml:"base_url"`
+	Timeout internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	// HTTP Basic Auth Credentials
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	// OAuth2 Credentials
+	TokenURL     string   `toml:"token_url"`
+	GrantType    string   `toml:"grant_type"`
+	ClientID     string   `toml:"client_id"`
+	ClientSecret string   `toml:"client_secret"`
+	Scopes       []string `toml:"scopes"`
+
+	Endpoints []*Endpoint `toml:"endpoint"`
+
+	client      *http.Client
+	tokenSource *tokenSource
+	now         func() time.Time
+}
+
+// Endpoint is a single API endpoint to poll.
+type Endpoint struct {
+	Name     string            `toml:"name"`
+	URL      string            `toml:"url"`
+	Method   string            `toml:"method"`
+	Body     string            `toml:"body"`
+	Headers  map[string]string `toml:"headers"`
+	Interval internal.Duration `toml:"interval"`
+
+	ItemsPath  string            `toml:"items_path"`
+	Tags       map[string]string `toml:"tags"`
+	Fields     map[string]string `toml:"fields"`
+	TimePath   string            `toml:"time_path"`
+	TimeFormat string            `toml:"time_format"`
+
+	Pagination  string `toml:"pagination"`
+	CursorPath  string `toml:"cursor_path"`
+	CursorParam string `toml:"cursor_param"`
+	NextURLPath string `toml:"next_url_path"`
+	OffsetParam string `toml:"offset_param"`
+	LimitParam  string `toml:"limit_param"`
+	PageSize    int    `toml:"page_size"`
+	MaxPages    int    `toml:"max_pages"`
+
+	lastPoll time.Time
+}
+
+var sampleConfig = `
+  ## Base URL for endpoints with a relative URL.  When the token response
+  ## contains an "instance_url", such as with Salesforce, it is used instead.
+  # base_url = "https://api.example.com"
+
+  ## Amount of time allowed to complete each HTTP request.
+  # timeout = "5s"
+
+  ## Optional HTTP Basic Auth Credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Optional OAuth2 Credentials.  The grant type may be "client_credentials"
+  ## or "password", in which case username and password are sent to the token
+  ## URL instead of being used for basic authentication.
+  # token_url = "https://login.example.com/oauth2/token"
+  # grant_type = "client_credentials"
+  # client_id = ""
+  # client_secret = ""
+  # scopes = []
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  [[inputs.rest_api.endpoint]]
+    ## Measurement name.
+    name = "rest_api"
+
+    ## URL of the endpoint, HTTP method and optional request body.
+    url = "https://api.example.com/v1/tickets"
+    # method = "GET"
+    # body = ""
+    # headers = {"Accept" = "application/json"}
+
+    ## Poll this endpoint at most once per interval, instead of on every
+    ## gather of the plugin.
+    # interval = "5m"
+
+    ## Path of the list of items in the response.  Each item is converted
+    ## into a metric, when empty the response is used.  Paths use the GJSON
+    ## syntax: https://github.com/tidwall/gjson#path-syntax
+    # items_path = "data"
+
+    ## Tags and fields to extract, relative to the item, as names mapped to
+    ## paths.  When no fields are set all top level numbers and booleans of
+    ## the item are used.
+    # tags = {"status" = "status"}
+    # fields = {"priority" = "priority", "age" = "stats.age_seconds"}
+
+    ## Path of the timestamp of the item and its format, one of "unix",
+    ## "unix_ms", "unix_us", "unix_ns" or a Go time layout.  The current time
+    ## is used if not set.
+    # time_path = "created_at"
+    # time_format = "2006-01-02T15:04:05Z07:00"
+
+    ## Pagination, one of:
+    ##   "none"     - a single request
+    ##   "cursor"   - the value at cursor_path is passed as cursor_param
+    ##   "next_url" - the URL at next_url_path is requested
+    ##   "offset"   - offset_param is increased by the number of items
+    # pagination = "none"
+    # cursor_path = "meta.next_cursor"
+    # cursor_param = "cursor"
+    # next_url_path = "nextRecordsUrl"
+    # offset_param = "offset"
+
+    ## Page size sent as limit_param, and the maximum number of pages
+    ## requested per poll.
+    # limit_param = "limit"
+    # page_size = 100
+    # max_pages = 10
+`
+
+func (r *RestAPI) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RestAPI) Description() string {
+	return "Poll JSON REST APIs and extract metrics from the responses"
+}
+
+func (r *RestAPI) Gather(acc telegraf.Accumulator) error {
+	if r.client == nil {
+		if err := r.init(); err != nil {
+			return err
+		}
+	}
+
+	now := r.now()
+
+	var wg sync.WaitGroup
+	for _, e := range r.Endpoints {
+		// Each endpoint is polled once per its own interval; the gather
+		// interval of the plugin should be no longer than the shortest one.
+		if !e.lastPoll.IsZero() && now.Sub(e.lastPoll) < e.Interval.Duration {
+			continue
+		}
+		e.lastPoll = now
+
+		wg.Add(1)
+		go func(e *Endpoint) {
+			defer wg.Done()
+			if err := r.gatherEndpoint(acc, e); err != nil {
+				acc.AddError(fmt.Errorf("[%s]: %s", e.Name, err))
+			}
+		}(e)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (r *RestAPI) init() error {
+	for _, e := range r.Endpoints {
+		if e.Name == "" {
+			e.Name = defaultMeasurement
+		}
+		if e.Method == "" {
+			e.Method = "GET"
+		}
+		if e.TimeFormat == "" {
+			e.TimeFormat = time.RFC3339
+		}
+		if e.Pagination == "" {
+			e.Pagination = "none"
+		}
+		if e.CursorParam == "" {
+			e.CursorParam = "cursor"
+		}
+		if e.OffsetParam == "" {
+			e.OffsetParam = "offset"
+		}
+		if e.PageSize == 0 {
+			e.PageSize = 100
+		}
+		if e.MaxPages == 0 {
+			e.MaxPages = 10
+		}
+
+		switch e.Pagination {
+		case "none", "offset":
+		case "cursor":
+			if e.CursorPath == "" {
+				return fmt.Errorf("endpoint %s: cursor_path is required for cursor pagination", e.Name)
+			}
+		case "next_url":
+			if e.NextURLPath == "" {
+				return fmt.Errorf("endpoint %s: next_url_path is required for next_url pagination", e.Name)
+			}
+		default:
+			return fmt.Errorf("endpoint %s: invalid pagination %q", e.Name, e.Pagination)
+		}
+	}
+
+	tlsCfg, err := r.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	r.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: r.Timeout.Duration,
+	}
+
+	if r.TokenURL != "" {
+		form := url.Values{
+			"client_id":     {r.ClientID},
+			"client_secret": {r.ClientSecret},
+		}
+		switch r.GrantType {
+		case "", "client_credentials":
+			form.Set("grant_type", "client_credentials")
+		case "password":
+			form.Set("grant_type", "password")
+			form.Set("username", r.Username)
+			form.Set("password", r.Password)
+		default:
+			return fmt.Errorf("invalid grant_type %q", r.GrantType)
+		}
+		if len(r.Scopes) > 0 {
+			form.Set("scope", strings.Join(r.Scopes, " "))
+		}
+		r.tokenSource = &tokenSource{
+			tokenURL: r.TokenURL,
+			form:     form,
+			client:   r.client,
+			now:      r.now,
+			cache:    token.Cache{Now: r.now},
+		}
+	}
+	return nil
+}
+
+func (r *RestAPI) gatherEndpoint(acc telegraf.Accumulator, e *Endpoint) error {
+	loc, err := r.resolve(e.URL)
+	if err != nil {
+		return err
+	}
+
+	var offset int
+	for page := 0; page < e.MaxPages; page++ {
+		pageURL := loc
+		if e.LimitParam != "" || e.Pagination == "offset" {
+			params := url.Values{}
+			if e.LimitParam != "" {
+				params.Set(e.LimitParam, strconv.Itoa(e.PageSize))
+			}
+			if e.Pagination == "offset" {
+				params.Set(e.OffsetParam, strconv.Itoa(offset))
+			}
+			pageURL, err = setParams(pageURL, params)
+			if err != nil {
+				return err
+			}
+		}
+
+		body, err := r.request(e, pageURL)
+		if err != nil {
+			return err
+		}
+		if !gjson.Valid(string(body)) {
+			return fmt.Errorf("invalid JSON response")
+		}
+
+		items, err := e.addItems(acc, body, r.now())
+		if err != nil {
+			return err
+		}
+
+		switch e.Pagination {
+		case "cursor":
+			cursor := gjson.GetBytes(body, e.CursorPath).String()
+			if cursor == "" {
+				return nil
+			}
+			loc, err = setParams(loc, url.Values{e.CursorParam: {cursor}})
+		case "next_url":
+			next := gjson.GetBytes(body, e.NextURLPath).String()
+			if next == "" {
+				return nil
+			}
+			loc, err = resolveReference(pageURL, next)
+		case "offset":
+			if items == 0 || (e.LimitParam != "" && items < e.PageSize) {
+				return nil
+			}
+			offset += items
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addItems adds a metric for each item of the response and returns the
+// number of items.
+func (e *Endpoint) addItems(acc telegraf.Accumulator, body []byte, now time.Time) (int, error) {
+	result := gjson.ParseBytes(body)
+	if e.ItemsPath != "" {
+		result = result.Get(e.ItemsPath)
+		if !result.Exists() {
+			return 0, fmt.Errorf("items path %q not found in response", e.ItemsPath)
+		}
+	}
+
+	items := []gjson.Result{result}
+	if result.Type == gjson.JSON && strings.HasPrefix(result.Raw, "[") {
+		items = result.Array()
+	}
+
+	for _, item := range items {
+		tags := make(map[string]string, len(e.Tags))
+		for name, path := range e.Tags {
+			if v := item.Get(path); v.Exists() && v.Type != gjson.Null {
+				tags[name] = v.String()
+			}
+		}
+
+		fields := make(map[string]interface{})
+		if len(e.Fields) == 0 {
+			item.ForEach(func(key, value gjson.Result) bool {
+				if v, ok := fieldValue(value, false); ok {
+					fields[key.String()] = v
+				}
+				return true
+			})
+		} else {
+			for name, path := range e.Fields {
+				if v, ok := fieldValue(item.Get(path), true); ok {
+					fields[name] = v
+				}
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		t := now
+		if e.TimePath != "" {
+			var err error
+			t, err = parseTime(item.Get(e.TimePath), e.TimeFormat)
+			if err != nil {
+				acc.AddError(fmt.Errorf("[%s]: %s", e.Name, err))
+				continue
+			}
+		}
+
+		acc.AddFields(e.Name, fields, tags, t)
+	}
+	return len(items), nil
+}
+
+// fieldValue converts a JSON value into a field value.  Strings are only
+// converted if requested explicitly; objects, arrays and nulls are skipped.
+func fieldValue(value gjson.Result, allowStrings bool) (interface{}, bool) {
+	switch value.Type {
+	case gjson.Number:
+		return value.Float(), true
+	case gjson.True, gjson.False:
+		return value.Bool(), true
+	case gjson.String:
+		if allowStrings {
+			return value.String(), true
+		}
+	}
+	return nil, false
+}
+
+// parseTime parses a timestamp as a unix time or using a Go time layout.
+func parseTime(value gjson.Result, format string) (time.Time, error) {
+	if !value.Exists() || value.Type == gjson.Null {
+		return time.Time{}, fmt.Errorf("timestamp not found")
+	}
+
+	var unit time.Duration
+	switch format {
+	case "unix":
+		unit = time.Second
+	case "unix_ms":
+		unit = time.Millisecond
+	case "unix_us":
+		unit = time.Microsecond
+	case "unix_ns":
+		unit = time.Nanosecond
+	default:
+		return time.Parse(format, value.String())
+	}
+
+	f, err := strconv.ParseFloat(value.String(), 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", value.String())
+	}
+	return time.Unix(0, int64(f*float64(unit))), nil
+}
+
+// request sends a request to the endpoint, refreshing the OAuth2 token once
+// if it is rejected.
+func (r *RestAPI) request(e *Endpoint, loc string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if e.Body != "" {
+			body = strings.NewReader(e.Body)
+		}
+		req, err := http.NewRequest(e.Method, loc, body)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", "application/json")
+		if e.Body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k, v := range e.Headers {
+			if strings.ToLower(k) == "host" {
+				req.Host = v
+			} else {
+				req.Header.Set(k, v)
+			}
+		}
+
+		if r.tokenSource != nil {
+			token, err := r.tokenSource.Token()
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if r.Username != "" || r.Password != "" {
+			req.SetBasicAuth(r.Username, r.Password)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && r.tokenSource != nil && attempt == 0 {
+			r.tokenSource.Invalidate()
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("received status code %d (%s)",
+				resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		return b, nil
+	}
+}
+
+// resolve returns the absolute URL of an endpoint.
+func (r *RestAPI) resolve(loc string) (string, error) {
+	base := r.BaseURL
+	if r.tokenSource != nil && strings.HasPrefix(loc, "/") {
+		// The instance URL is only known after the token has been requested.
+		if _, err := r.tokenSource.Token(); err != nil {
+			return "", err
+		}
+		if instanceURL := r.tokenSource.InstanceURL(); instanceURL != "" {
+			base = instanceURL
+		}
+	}
+	if base == "" {
+		return loc, nil
+	}
+	return resolveReference(base, loc)
+}
+
+func resolveReference(base, ref string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return u.ResolveReference(r).String(), nil
+}
+
+// setParams sets the query parameters of the URL, replacing existing ones.
+func setParams(loc string, params url.Values) (string, error) {
+	u, err := url.Parse(loc)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func init() {
+	inputs.Add("rest_api", func() telegraf.Input {
+		return &RestAPI{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			now:     time.Now,
+		}
+	})
+}
@@ -0,0 +1,340 @@
+package rest_api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+var now = time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newRestAPI(endpoints ...*Endpoint) *RestAPI {
+	return &RestAPI{
+		Timeout:   internal.Duration{Duration: time.Second},
+		Endpoints: endpoints,
+		now:       func() time.Time { return now },
+	}
+}
+
+func TestGatherItems(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/tickets", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "user", user)
+		require.Equal(t, "pass", pass)
+		w.Write([]byte(`{
+		  "data": [
+		    {"id": "1", "status": "open", "priority": 2, "escalated": true, "stats": {"age": 60}, "created_at": "2018-06-01T10:00:00Z"},
+		    {"id": "2", "status": "closed", "priority": 1, "escalated": false, "stats": {"age": 30}, "created_at": "2018-06-01T11:00:00Z"}
+		  ]
+		}`))
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(&Endpoint{
+		Name:      "tickets",
+		URL:       "/v1/tickets",
+		ItemsPath: "data",
+		Tags:      map[string]string{"status": "status"},
+		Fields: map[string]string{
+			"priority": "priority",
+			"age":      "stats.age",
+			"id":       "id",
+		},
+		TimePath: "created_at",
+	})
+	r.BaseURL = ts.URL
+	r.Username = "user"
+	r.Password = "pass"
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	acc.AssertContainsTaggedFields(t, "tickets",
+		map[string]interface{}{
+			"priority": 2.0,
+			"age":      60.0,
+			"id":       "1",
+		},
+		map[string]string{"status": "open"})
+	acc.AssertContainsTaggedFields(t, "tickets",
+		map[string]interface{}{
+			"priority": 1.0,
+			"age":      30.0,
+			"id":       "2",
+		},
+		map[string]string{"status": "closed"})
+	require.True(t, acc.HasTimestamp("tickets", time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestGatherDefaultFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "queue", "size": 12, "paused": false, "tags": ["a"], "updated": 1527854400000}`))
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(&Endpoint{
+		URL:        ts.URL,
+		TimePath:   "updated",
+		TimeFormat: "unix_ms",
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	acc.AssertContainsFields(t, "rest_api",
+		map[string]interface{}{
+			"size":    12.0,
+			"paused":  false,
+			"updated": 1527854400000.0,
+		})
+	require.True(t, acc.HasTimestamp("rest_api", time.Unix(1527854400, 0)))
+}
+
+func TestGatherCursorPagination(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "2", r.URL.Query().Get("limit"))
+		switch r.URL.Query().Get("after") {
+		case "":
+			w.Write([]byte(`{"items": [{"value": 1}, {"value": 2}], "next": "abc"}`))
+		case "abc":
+			w.Write([]byte(`{"items": [{"value": 3}], "next": null}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(&Endpoint{
+		URL:         ts.URL + "/items?limit=50",
+		ItemsPath:   "items",
+		Pagination:  "cursor",
+		CursorPath:  "next",
+		CursorParam: "after",
+		LimitParam:  "limit",
+		PageSize:    2,
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.Equal(t, 2, requests)
+	require.Len(t, acc.Metrics, 3)
+}
+
+func TestGatherOffsetPagination(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Query().Get("start") {
+		case "0":
+			w.Write([]byte(`[{"value": 1}, {"value": 2}]`))
+		case "2":
+			w.Write([]byte(`[{"value": 3}, {"value": 4}]`))
+		case "4":
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(&Endpoint{
+		URL:         ts.URL,
+		Pagination:  "offset",
+		OffsetParam: "start",
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.Equal(t, 3, requests)
+	require.Len(t, acc.Metrics, 4)
+}
+
+func TestGatherMaxPages(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"items": [{"value": 1}], "next": "more"}`))
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(&Endpoint{
+		URL:        ts.URL,
+		ItemsPath:  "items",
+		Pagination: "cursor",
+		CursorPath: "next",
+		MaxPages:   3,
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.Equal(t, 3, requests)
+}
+
+func TestGatherSalesforce(t *testing.T) {
+	var ts *httptest.Server
+	var logins int
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "password", r.PostForm.Get("grant_type"))
+			require.Equal(t, "client", r.PostForm.Get("client_id"))
+			require.Equal(t, "secret", r.PostForm.Get("client_secret"))
+			require.Equal(t, "user@example.com", r.PostForm.Get("username"))
+			require.Equal(t, "pass", r.PostForm.Get("password"))
+			logins++
+			fmt.Fprintf(w, `{"access_token": "token%d", "instance_url": "%s", "token_type": "Bearer"}`,
+				logins, ts.URL)
+		case "/services/data/v42.0/query":
+			// The first token is rejected as expired.
+			if r.Header.Get("Authorization") != "Bearer token2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			require.Equal(t, "SELECT Status, COUNT(Id) total FROM Case GROUP BY Status",
+				r.URL.Query().Get("q"))
+			w.Write([]byte(`{"done": false, "records": [{"Status": "New", "total": 3}],
+			  "nextRecordsUrl": "/services/data/v42.0/query/01gD0000002HU6KIAW-2000"}`))
+		case "/services/data/v42.0/query/01gD0000002HU6KIAW-2000":
+			require.Equal(t, "Bearer token2", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"done": true, "records": [{"Status": "Closed", "total": 5}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(&Endpoint{
+		Name:        "salesforce_cases",
+		URL:         "/services/data/v42.0/query?q=SELECT+Status%2C+COUNT%28Id%29+total+FROM+Case+GROUP+BY+Status",
+		ItemsPath:   "records",
+		Tags:        map[string]string{"status": "Status"},
+		Fields:      map[string]string{"total": "total"},
+		Pagination:  "next_url",
+		NextURLPath: "nextRecordsUrl",
+	})
+	r.TokenURL = ts.URL + "/services/oauth2/token"
+	r.GrantType = "password"
+	r.ClientID = "client"
+	r.ClientSecret = "secret"
+	r.Username = "user@example.com"
+	r.Password = "pass"
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.Equal(t, 2, logins)
+
+	acc.AssertContainsTaggedFields(t, "salesforce_cases",
+		map[string]interface{}{"total": 3.0},
+		map[string]string{"status": "New"})
+	acc.AssertContainsTaggedFields(t, "salesforce_cases",
+		map[string]interface{}{"total": 5.0},
+		map[string]string{"status": "Closed"})
+}
+
+func TestGatherClientCredentials(t *testing.T) {
+	var logins int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			require.Equal(t, "read write", r.PostForm.Get("scope"))
+			logins++
+			w.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+		case "/status":
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"value": 1}`))
+		}
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(&Endpoint{URL: ts.URL + "/status"})
+	r.TokenURL = ts.URL + "/token"
+	r.ClientID = "client"
+	r.ClientSecret = "secret"
+	r.Scopes = []string{"read", "write"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.Equal(t, 1, logins)
+	require.Len(t, acc.Metrics, 2)
+
+	// The token is renewed shortly before it expires.
+	now = now.Add(59 * time.Minute)
+	defer func() { now = time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC) }()
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.Equal(t, 2, logins)
+}
+
+func TestGatherEndpointInterval(t *testing.T) {
+	var fast, slow int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			fast++
+		} else {
+			slow++
+		}
+		w.Write([]byte(`{"value": 1}`))
+	}))
+	defer ts.Close()
+
+	r := newRestAPI(
+		&Endpoint{URL: ts.URL + "/fast"},
+		&Endpoint{URL: ts.URL + "/slow", Interval: internal.Duration{Duration: 5 * time.Minute}},
+	)
+	defer func() { now = time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC) }()
+
+	var acc testutil.Accumulator
+	for i := 0; i < 6; i++ {
+		require.NoError(t, acc.GatherError(r.Gather))
+		now = now.Add(time.Minute)
+	}
+	require.Equal(t, 6, fast)
+	require.Equal(t, 2, slow)
+}
+
+func TestInvalidPagination(t *testing.T) {
+	r := newRestAPI(&Endpoint{URL: "http://localhost", Pagination: "cursor"})
+
+	var acc testutil.Accumulator
+	require.Error(t, r.Gather(&acc))
+
+	r = newRestAPI(&Endpoint{URL: "http://localhost", Pagination: "pages"})
+	require.Error(t, r.Gather(&acc))
+}
+
+func TestParseTime(t *testing.T) {
+	tm, err := parseTime(gjsonValue(`1527854400`), "unix")
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1527854400, 0), tm)
+
+	tm, err = parseTime(gjsonValue(`"1527854400.5"`), "unix")
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1527854400, 500000000), tm)
+
+	tm, err = parseTime(gjsonValue(`"01/06/2018"`), "02/01/2006")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), tm)
+
+	_, err = parseTime(gjsonValue(`null`), "unix")
+	require.Error(t, err)
+	_, err = parseTime(gjsonValue(`"soon"`), "unix_ms")
+	require.Error(t, err)
+}
+
+func gjsonValue(json string) gjson.Result {
+	return gjson.Parse(json)
+}
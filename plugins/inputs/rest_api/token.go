@@ -0,0 +1,102 @@
+package rest_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/token"
+)
+
+// tokenSource requests OAuth2 access tokens and caches them until shortly
+// before they expire.
+type tokenSource struct {
+	tokenURL string
+	form     url.Values
+	client   *http.Client
+	now      func() time.Time
+
+	cache token.Cache
+
+	mu          sync.Mutex
+	instanceURL string
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	// InstanceURL is returned by Salesforce, it is the base URL for the API
+	// of the organization.
+	InstanceURL string `json:"instance_url"`
+}
+
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token returns a valid access token, requesting a new one if needed.
+func (t *tokenSource) Token() (string, error) {
+	return t.cache.Token(t.fetch)
+}
+
+func (t *tokenSource) fetch() (string, time.Time, error) {
+	req, err := http.NewRequest("POST", t.tokenURL, strings.NewReader(t.form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &tokenErrorResponse{}
+		json.NewDecoder(resp.Body).Decode(errResp)
+		if errResp.Error != "" {
+			return "", time.Time{}, fmt.Errorf("requesting token failed: %s: %s %s", resp.Status,
+				errResp.Error, errResp.ErrorDescription)
+		}
+		return "", time.Time{}, fmt.Errorf("requesting token failed: %s", resp.Status)
+	}
+
+	tok := &tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(tok); err != nil {
+		return "", time.Time{}, err
+	}
+	if tok.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("requesting token failed: no access token in response")
+	}
+
+	t.mu.Lock()
+	t.instanceURL = tok.InstanceURL
+	t.mu.Unlock()
+
+	// Tokens without an expiry are kept until they are rejected.
+	var expiry time.Time
+	if tok.ExpiresIn > 0 {
+		expiry = t.now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+	return tok.AccessToken, expiry, nil
+}
+
+// InstanceURL returns the instance URL of the last token response, if any.
+func (t *tokenSource) InstanceURL() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.instanceURL
+}
+
+// Invalidate discards the cached token, for example after a request was
+// rejected as unauthorized.
+func (t *tokenSource) Invalidate() {
+	t.cache.Invalidate()
+}
@@ -32,6 +32,10 @@ func (*Sensors) Description() string {
 	return "Monitor sensors, requires lm-sensors package"
 }
 
+func (*Sensors) Dependencies() []string {
+	return []string{"sensors"}
+}
+
 func (*Sensors) SampleConfig() string {
 	return `
   ## Remove numbers from field names.
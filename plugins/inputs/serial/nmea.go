@@ -0,0 +1,187 @@
+package serial
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const kilometersPerNauticalMile = 1.852
+
+type sentence struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// parseNMEA parses a NMEA 0183 sentence, such as:
+//
+//   $GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47
+//
+// Unsupported sentences are ignored, and nil is returned.
+func parseNMEA(s string) (*sentence, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("missing start delimiter")
+	}
+	body := s[1:]
+	if i := strings.LastIndex(body, "*"); i >= 0 {
+		checksum, err := strconv.ParseUint(body[i+1:], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum %q", body[i+1:])
+		}
+		body = body[:i]
+		var sum byte
+		for j := 0; j < len(body); j++ {
+			sum ^= body[j]
+		}
+		if byte(checksum) != sum {
+			return nil, fmt.Errorf("checksum mismatch")
+		}
+	}
+
+	fields := strings.Split(body, ",")
+	address := fields[0]
+	if len(address) != 5 || address[0] == 'P' {
+		// Proprietary sentences.
+		return nil, nil
+	}
+
+	var parse func([]string, map[string]interface{}) error
+	switch address[2:] {
+	case "GGA":
+		parse = parseGGA
+	case "RMC":
+		parse = parseRMC
+	case "VTG":
+		parse = parseVTG
+	default:
+		return nil, nil
+	}
+
+	values := make(map[string]interface{})
+	if err := parse(fields, values); err != nil {
+		return nil, err
+	}
+	return &sentence{
+		tags: map[string]string{
+			"talker":   address[:2],
+			"sentence": address[2:],
+		},
+		fields: values,
+	}, nil
+}
+
+// parseGGA parses the fix data, the position is empty without fix:
+//
+//   $GPGGA,time,lat,N/S,lon,E/W,quality,satellites,hdop,altitude,M,...
+func parseGGA(f []string, values map[string]interface{}) error {
+	if len(f) < 10 {
+		return fmt.Errorf("expected at least 10 fields, got %d", len(f))
+	}
+	if err := parsePosition(f[2], f[3], f[4], f[5], values); err != nil {
+		return err
+	}
+	return parseNumbers(values, map[string]string{
+		"fix_quality": f[6],
+		"satellites":  f[7],
+	}, map[string]string{
+		"hdop":     f[8],
+		"altitude": f[9],
+	})
+}
+
+// parseRMC parses the recommended minimum data:
+//
+//   $GPRMC,time,A/V,lat,N/S,lon,E/W,speed_knots,course,date,...
+func parseRMC(f []string, values map[string]interface{}) error {
+	if len(f) < 10 {
+		return fmt.Errorf("expected at least 10 fields, got %d", len(f))
+	}
+	values["valid"] = f[2] == "A"
+	if err := parsePosition(f[3], f[4], f[5], f[6], values); err != nil {
+		return err
+	}
+	if err := parseNumbers(values, nil, map[string]string{
+		"speed_knots": f[7],
+		"course":      f[8],
+	}); err != nil {
+		return err
+	}
+	if knots, ok := values["speed_knots"].(float64); ok {
+		values["speed_kmh"] = knots * kilometersPerNauticalMile
+	}
+	return nil
+}
+
+// parseVTG parses the course and speed over ground:
+//
+//   $GPVTG,course,T,course_magnetic,M,speed_knots,N,speed_kmh,K,...
+func parseVTG(f []string, values map[string]interface{}) error {
+	if len(f) < 9 {
+		return fmt.Errorf("expected at least 9 fields, got %d", len(f))
+	}
+	return parseNumbers(values, nil, map[string]string{
+		"course":      f[1],
+		"speed_knots": f[5],
+		"speed_kmh":   f[7],
+	})
+}
+
+// parsePosition parses coordinates such as "4807.038" and "01131.000",
+// in degrees and minutes, to decimal degrees.
+func parsePosition(lat, ns, lon, ew string, values map[string]interface{}) error {
+	if lat == "" || lon == "" {
+		return nil
+	}
+	latitude, err := parseDegrees(lat)
+	if err != nil {
+		return err
+	}
+	longitude, err := parseDegrees(lon)
+	if err != nil {
+		return err
+	}
+	if ns == "S" {
+		latitude = -latitude
+	}
+	if ew == "W" {
+		longitude = -longitude
+	}
+	values["latitude"] = latitude
+	values["longitude"] = longitude
+	return nil
+}
+
+func parseDegrees(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+	degrees := math.Floor(v / 100)
+	return degrees + (v-degrees*100)/60, nil
+}
+
+// parseNumbers adds the integers and floats which are not empty.
+func parseNumbers(values map[string]interface{}, integers, floats map[string]string) error {
+	for name, s := range integers {
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", name, s)
+		}
+		values[name] = v
+	}
+	for name, s := range floats {
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", name, s)
+		}
+		values[name] = v
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package serial
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// maxMessageSize bounds the buffered data of a message, when the device
+// never sends the delimiter.
+const maxMessageSize = 64 * 1024
+
+type Serial struct {
+	Port              string            `toml:"port"`
+	BaudRate          int               `toml:"baud_rate"`
+	DataBits          int               `toml:"data_bits"`
+	Parity            string            `toml:"parity"`
+	StopBits          int               `toml:"stop_bits"`
+	Delimiter         string            `toml:"delimiter"`
+	NMEA              bool              `toml:"nmea"`
+	ReconnectInterval internal.Duration `toml:"reconnect_interval"`
+
+	parser parsers.Parser
+	acc    telegraf.Accumulator
+
+	mu   sync.Mutex
+	port io.ReadCloser
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+var sampleConfig = `
+  ## Serial device to read from.
+  port = "/dev/ttyUSB0"
+
+  ## Settings of the serial line, the parity is one of "none", "odd" or
+  ## "even".
+  baud_rate = 9600
+  # data_bits = 8
+  # parity = "none"
+  # stop_bits = 1
+
+  ## Delimiter of the messages sent by the device, carriage returns before
+  ## the delimiter are removed.
+  # delimiter = "\n"
+
+  ## Parse the messages as NMEA 0183 sentences of GPS receivers, instead of
+  ## the data format.  The GGA, RMC and VTG sentences are reported.
+  # nmea = false
+
+  ## Interval between attempts to open the device, when it can't be opened
+  ## or is disconnected.
+  # reconnect_interval = "5s"
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+func (s *Serial) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Serial) Description() string {
+	return "Read metrics from serial devices, such as NMEA 0183 GPS receivers"
+}
+
+func (s *Serial) SetParser(parser parsers.Parser) {
+	s.parser = parser
+}
+
+func (s *Serial) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (s *Serial) Start(acc telegraf.Accumulator) error {
+	switch s.Parity {
+	case "", "none", "odd", "even":
+	default:
+		return fmt.Errorf("invalid parity %q", s.Parity)
+	}
+	if s.Delimiter == "" {
+		s.Delimiter = "\n"
+	}
+
+	s.acc = acc
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+	return nil
+}
+
+func (s *Serial) Stop() {
+	close(s.done)
+	s.mu.Lock()
+	if s.port != nil {
+		s.port.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// run reads the port until the plugin is stopped, reopening the port after
+// errors.
+func (s *Serial) run() {
+	defer s.wg.Done()
+
+	for {
+		port, err := openPort(s)
+		if err == nil {
+			s.mu.Lock()
+			select {
+			case <-s.done:
+				s.mu.Unlock()
+				port.Close()
+				return
+			default:
+			}
+			s.port = port
+			s.mu.Unlock()
+
+			err = s.read(port)
+
+			s.mu.Lock()
+			s.port = nil
+			s.mu.Unlock()
+			port.Close()
+		}
+
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		s.acc.AddError(fmt.Errorf("E! Error reading %s: %s", s.Port, err))
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.ReconnectInterval.Duration):
+		}
+	}
+}
+
+// read reads messages from the port until an error occurs.  Reads time out
+// without data, which is reported as io.EOF by the port, so that the
+// plugin can be stopped.
+func (s *Serial) read(port io.Reader) error {
+	delimiter := []byte(s.Delimiter)
+	buf := make([]byte, 4096)
+	var pending []byte
+	for {
+		n, err := port.Read(buf)
+		if n > 0 {
+			pending = append(pending, buf[:n]...)
+			for {
+				i := bytes.Index(pending, delimiter)
+				if i < 0 {
+					break
+				}
+				s.handle(bytes.TrimRight(pending[:i], "\r"))
+				pending = pending[i+len(delimiter):]
+			}
+			if len(pending) > maxMessageSize {
+				log.Printf("W! Discarding %d bytes from %s without delimiter", len(pending), s.Port)
+				pending = pending[:0]
+			}
+		}
+
+		select {
+		case <-s.done:
+			return nil
+		default:
+		}
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Serial) handle(message []byte) {
+	if len(bytes.TrimSpace(message)) == 0 {
+		return
+	}
+
+	if s.NMEA {
+		sentence, err := parseNMEA(string(message))
+		if err != nil {
+			s.acc.AddError(fmt.Errorf("E! Invalid NMEA sentence from %s: [%s], Error: %s",
+				s.Port, message, err))
+			return
+		}
+		if sentence == nil {
+			return
+		}
+		sentence.tags["port"] = s.Port
+		s.acc.AddFields("nmea", sentence.fields, sentence.tags)
+		return
+	}
+
+	metrics, err := s.parser.Parse(message)
+	if err != nil {
+		s.acc.AddError(fmt.Errorf("E! Malformed message from %s: [%s], Error: %s",
+			s.Port, message, err))
+		return
+	}
+	for _, m := range metrics {
+		s.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+}
+
+func init() {
+	inputs.Add("serial", func() telegraf.Input {
+		return &Serial{
+			BaudRate:          9600,
+			DataBits:          8,
+			Parity:            "none",
+			StopBits:          1,
+			Delimiter:         "\n",
+			ReconnectInterval: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
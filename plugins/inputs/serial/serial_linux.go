@@ -0,0 +1,75 @@
+// +build linux
+
+package serial
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var baudRates = map[int]uint32{
+	1200:   syscall.B1200,
+	2400:   syscall.B2400,
+	4800:   syscall.B4800,
+	9600:   syscall.B9600,
+	19200:  syscall.B19200,
+	38400:  syscall.B38400,
+	57600:  syscall.B57600,
+	115200: syscall.B115200,
+	230400: syscall.B230400,
+}
+
+var dataBits = map[int]uint32{
+	5: syscall.CS5,
+	6: syscall.CS6,
+	7: syscall.CS7,
+	8: syscall.CS8,
+}
+
+// openPort opens the serial device in raw mode.  Reads return after a
+// second without data so that the plugin can be stopped.  It is used to
+// mock the device in tests.
+var openPort = func(s *Serial) (io.ReadCloser, error) {
+	baud, ok := baudRates[s.BaudRate]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", s.BaudRate)
+	}
+	size, ok := dataBits[s.DataBits]
+	if !ok {
+		return nil, fmt.Errorf("unsupported data bits %d", s.DataBits)
+	}
+
+	f, err := os.OpenFile(s.Port, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// The speed is set by the baud rate bits of the control flags.
+	t := syscall.Termios{
+		Cflag: baud | size | syscall.CREAD | syscall.CLOCAL,
+	}
+	switch s.Parity {
+	case "odd":
+		t.Cflag |= syscall.PARENB | syscall.PARODD
+		t.Iflag |= syscall.INPCK
+	case "even":
+		t.Cflag |= syscall.PARENB
+		t.Iflag |= syscall.INPCK
+	}
+	if s.StopBits == 2 {
+		t.Cflag |= syscall.CSTOPB
+	}
+	t.Cc[syscall.VMIN] = 0
+	t.Cc[syscall.VTIME] = 10
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("configuring %s: %s", s.Port, errno)
+	}
+	return f, nil
+}
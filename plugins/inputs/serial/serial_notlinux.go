@@ -0,0 +1,13 @@
+// +build !linux
+
+package serial
+
+import (
+	"fmt"
+	"io"
+)
+
+// openPort is used to mock the device in tests.
+var openPort = func(s *Serial) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("serial devices are only supported on linux")
+}
@@ -0,0 +1,199 @@
+package serial
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNMEA(t *testing.T) {
+	s, err := parseNMEA("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"talker": "GP", "sentence": "GGA"}, s.tags)
+	require.InDelta(t, 48.1173, s.fields["latitude"], 1e-6)
+	require.InDelta(t, 11.516667, s.fields["longitude"], 1e-6)
+	require.Equal(t, int64(1), s.fields["fix_quality"])
+	require.Equal(t, int64(8), s.fields["satellites"])
+	require.Equal(t, 0.9, s.fields["hdop"])
+	require.Equal(t, 545.4, s.fields["altitude"])
+
+	s, err = parseNMEA("$GPRMC,235316.000,A,3348.4220,S,15113.2145,W,0.00,0.00,010618,,,A*62")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"talker": "GP", "sentence": "RMC"}, s.tags)
+	require.Equal(t, true, s.fields["valid"])
+	require.InDelta(t, -33.807033, s.fields["latitude"], 1e-6)
+	require.InDelta(t, -151.220242, s.fields["longitude"], 1e-6)
+	require.Equal(t, 0.0, s.fields["speed_knots"])
+	require.Equal(t, 0.0, s.fields["speed_kmh"])
+	require.Equal(t, 0.0, s.fields["course"])
+
+	s, err = parseNMEA("$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"course":      54.7,
+		"speed_knots": 5.5,
+		"speed_kmh":   10.2,
+	}, s.fields)
+
+	// Without fix, the position is empty.
+	s, err = parseNMEA("$GNGGA,001043.00,,,,,0,00,99.99,,,,,,*7E")
+	require.NoError(t, err)
+	require.Equal(t, "GN", s.tags["talker"])
+	require.Equal(t, map[string]interface{}{
+		"fix_quality": int64(0),
+		"satellites":  int64(0),
+		"hdop":        99.99,
+	}, s.fields)
+
+	s, err = parseNMEA("$GPGSV,1,1,00*79")
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	_, err = parseNMEA("$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*49")
+	require.EqualError(t, err, "checksum mismatch")
+
+	_, err = parseNMEA("GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48")
+	require.EqualError(t, err, "missing start delimiter")
+
+	_, err = parseNMEA("$GPVTG,054.7,T")
+	require.EqualError(t, err, "expected at least 9 fields, got 3")
+}
+
+// fakePort is a serial device fed by the tests.
+type fakePort struct {
+	*io.PipeReader
+	w *io.PipeWriter
+}
+
+func newFakePort() *fakePort {
+	r, w := io.Pipe()
+	return &fakePort{PipeReader: r, w: w}
+}
+
+func mockPort(ports ...*fakePort) func() {
+	original := openPort
+	opens := 0
+	openPort = func(s *Serial) (io.ReadCloser, error) {
+		if opens >= len(ports) || ports[opens] == nil {
+			opens++
+			return nil, fmt.Errorf("open %s: no such file or directory", s.Port)
+		}
+		opens++
+		return ports[opens-1], nil
+	}
+	return func() { openPort = original }
+}
+
+func newSerial() *Serial {
+	return &Serial{
+		Port:              "/dev/ttyUSB0",
+		BaudRate:          9600,
+		DataBits:          8,
+		Parity:            "none",
+		StopBits:          1,
+		Delimiter:         "\n",
+		ReconnectInterval: internal.Duration{Duration: 10 * time.Millisecond},
+	}
+}
+
+func TestStartNMEA(t *testing.T) {
+	port := newFakePort()
+	defer mockPort(port)()
+
+	s := newSerial()
+	s.NMEA = true
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Start(&acc))
+	defer s.Stop()
+
+	// Sentences are split across reads, and end with CR LF.
+	fmt.Fprint(port.w, "$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48\r\n$GPGSV,1,1,00*79\r\n$GPVTG,05")
+	fmt.Fprint(port.w, "4.7,T,034.4,M,005.5,N,010.2,K*49\r\n\r\n")
+	fmt.Fprint(port.w, "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+
+	acc.Wait(2)
+	acc.WaitError(1)
+
+	acc.AssertContainsTaggedFields(t, "nmea",
+		map[string]interface{}{
+			"course":      54.7,
+			"speed_knots": 5.5,
+			"speed_kmh":   10.2,
+		},
+		map[string]string{
+			"port":     "/dev/ttyUSB0",
+			"talker":   "GP",
+			"sentence": "VTG",
+		})
+	require.True(t, acc.HasPoint("nmea",
+		map[string]string{"port": "/dev/ttyUSB0", "talker": "GP", "sentence": "GGA"},
+		"satellites", int64(8)))
+	require.Contains(t, acc.Errors[0].Error(), "checksum mismatch")
+}
+
+func TestStartDataFormat(t *testing.T) {
+	port := newFakePort()
+	defer mockPort(nil, port)()
+
+	s := newSerial()
+	s.Delimiter = ";"
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	s.SetParser(parser)
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Start(&acc))
+	defer s.Stop()
+
+	// The first open fails, the port is opened again.
+	acc.WaitError(1)
+	require.Contains(t, acc.Errors[0].Error(), "open /dev/ttyUSB0: no such file or directory")
+
+	fmt.Fprint(port.w, "weather,station=a temperature=21.5 1528300000000000000;weather,")
+	fmt.Fprint(port.w, "station=a humidity=45i 1528300001000000000;invalid;")
+
+	acc.Wait(2)
+	acc.WaitError(2)
+
+	tags := map[string]string{"station": "a"}
+	require.True(t, acc.HasPoint("weather", tags, "temperature", 21.5))
+	require.True(t, acc.HasPoint("weather", tags, "humidity", int64(45)))
+	require.Contains(t, acc.Errors[1].Error(), "Malformed message from /dev/ttyUSB0: [invalid]")
+}
+
+func TestReconnect(t *testing.T) {
+	first := newFakePort()
+	second := newFakePort()
+	defer mockPort(first, second)()
+
+	s := newSerial()
+	s.NMEA = true
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Start(&acc))
+	defer s.Stop()
+
+	// The device is disconnected, the port is reopened.
+	first.w.CloseWithError(fmt.Errorf("input/output error"))
+	acc.WaitError(1)
+	require.Contains(t, acc.Errors[0].Error(), "Error reading /dev/ttyUSB0: input/output error")
+
+	fmt.Fprint(second.w, "$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48\n")
+	acc.Wait(1)
+	require.True(t, acc.HasMeasurement("nmea"))
+}
+
+func TestStartInvalidParity(t *testing.T) {
+	s := newSerial()
+	s.Parity = "mark"
+
+	var acc testutil.Accumulator
+	require.EqualError(t, s.Start(&acc), `invalid parity "mark"`)
+}
@@ -98,6 +98,10 @@ func (m *Smart) Description() string {
 	return "Read metrics from storage devices supporting S.M.A.R.T."
 }
 
+func (m *Smart) Dependencies() []string {
+	return []string{"smartctl"}
+}
+
 func (m *Smart) Gather(acc telegraf.Accumulator) error {
 	if len(m.Path) == 0 {
 		return fmt.Errorf("smartctl not found: verify that smartctl is installed and that smartctl is in your PATH")
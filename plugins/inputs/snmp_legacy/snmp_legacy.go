@@ -229,6 +229,10 @@ func (s *Snmp) Description() string {
 	return `DEPRECATED! PLEASE USE inputs.snmp INSTEAD.`
 }
 
+func (s *Snmp) Deprecated() (string, string) {
+	return "1.0", "use the snmp input"
+}
+
 func fillnode(parentNode Node, oid_name string, ids []string) {
 	// ids = ["1", "3", "6", ...]
 	id, ids := ids[0], ids[1:]
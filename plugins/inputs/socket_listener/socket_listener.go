@@ -16,6 +16,8 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/internal/listen"
 	tlsint "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
@@ -65,7 +67,7 @@ func (ssl *streamSocketListener) listen() {
 		ssl.connectionsMtx.Unlock()
 
 		if err := ssl.setKeepAlive(c); err != nil {
-			ssl.AddError(fmt.Errorf("unable to configure keep alive (%s): %s", ssl.ServiceAddress, err))
+			ssl.AddError(fmt.Errorf("unable to configure keep alive (%s): %s", ssl.Addr(), err))
 		}
 
 		go ssl.read(c)
@@ -84,7 +86,7 @@ func (ssl *streamSocketListener) setKeepAlive(c net.Conn) error {
 	}
 	tcpc, ok := c.(*net.TCPConn)
 	if !ok {
-		return fmt.Errorf("cannot set keep alive on a %s socket", strings.SplitN(ssl.ServiceAddress, "://", 2)[0])
+		return fmt.Errorf("cannot set keep alive on a %s socket", ssl.sockType)
 	}
 	if ssl.KeepAlivePeriod.Duration == 0 {
 		return tcpc.SetKeepAlive(false)
@@ -105,7 +107,7 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	defer ssl.removeConnection(c)
 	defer c.Close()
 
-	scnr := bufio.NewScanner(c)
+	scnr := bufio.NewScanner(ssl.decoder.Reader(c))
 	for {
 		if ssl.ReadTimeout != nil && ssl.ReadTimeout.Duration > 0 {
 			c.SetReadDeadline(time.Now().Add(ssl.ReadTimeout.Duration))
@@ -149,7 +151,12 @@ func (psl *packetSocketListener) listen() {
 			break
 		}
 
-		metrics, err := psl.Parse(buf[:n])
+		data, err := psl.decoder.Bytes(buf[:n])
+		if err != nil {
+			psl.AddError(fmt.Errorf("unable to decode incoming packet: %s", err))
+			continue
+		}
+		metrics, err := psl.Parse(data)
 		if err != nil {
 			psl.AddError(fmt.Errorf("unable to parse incoming packet: %s", err))
 			//TODO rate limit
@@ -162,12 +169,17 @@ func (psl *packetSocketListener) listen() {
 }
 
 type SocketListener struct {
-	ServiceAddress  string             `toml:"service_address"`
-	MaxConnections  int                `toml:"max_connections"`
-	ReadBufferSize  int                `toml:"read_buffer_size"`
-	ReadTimeout     *internal.Duration `toml:"read_timeout"`
-	KeepAlivePeriod *internal.Duration `toml:"keep_alive_period"`
+	ServiceAddress    string             `toml:"service_address"`
+	ServiceAddresses  []string           `toml:"service_addresses"`
+	MaxConnections    int                `toml:"max_connections"`
+	ReadBufferSize    int                `toml:"read_buffer_size"`
+	ReadTimeout       *internal.Duration `toml:"read_timeout"`
+	KeepAlivePeriod   *internal.Duration `toml:"keep_alive_period"`
+	CharacterEncoding string             `toml:"character_encoding"`
 	tlsint.ServerConfig
+	listen.Config
+
+	decoder *encoding.Decoder
 
 	parsers.Parser
 	telegraf.Accumulator
@@ -192,18 +204,28 @@ func (sl *SocketListener) SampleConfig() string {
   # service_address = "unix:///tmp/telegraf.sock"
   # service_address = "unixgram:///tmp/telegraf.sock"
 
+  ## Additional URLs to listen on, with the other options shared by their
+  ## listeners.
+  # service_addresses = ["udp4://0.0.0.0:8094", "udp6://[::]:8094"]
+
+  ## Set to listen on IPv6 only, or on both IPv4 and IPv6, on the empty and
+  ## IPv6 hosts of the tcp, tcp6, udp and udp6 protocols, instead of the
+  ## defaults of the system.  The IPv4 hosts, including 0.0.0.0, then listen
+  ## on IPv4 only.  Not supported on Windows.
+  # ipv6_v6only = false
+
   ## Maximum number of concurrent connections.
-  ## Only applies to stream sockets (e.g. TCP).
+  ## Only applies to stream sockets (e.g. TCP), to each address.
   ## 0 (default) is unlimited.
   # max_connections = 1024
 
   ## Read timeout.
-  ## Only applies to stream sockets (e.g. TCP).
+  ## Only applies to stream sockets (e.g. TCP), to each address.
   ## 0 (default) is unlimited.
   # read_timeout = "30s"
 
   ## Optional TLS configuration.
-  ## Only applies to stream sockets (e.g. TCP).
+  ## Only applies to stream sockets (e.g. TCP), to each address.
   # tls_cert = "/etc/telegraf/cert.pem"
   # tls_key  = "/etc/telegraf/key.pem"
   ## Enables client authentication if set.
@@ -221,6 +243,12 @@ func (sl *SocketListener) SampleConfig() string {
   ## Defaults to the OS configuration.
   # keep_alive_period = "5m"
 
+  ## Character encoding of the data received, converted to UTF-8 before
+  ## parsing: "utf-8", "utf-16le", "utf-16be", "utf-16" (with a byte order
+  ## mark), "iso-8859-1" (or "latin1"), "iso-8859-15", "windows-1252" or
+  ## "shift_jis".  Defaults to reading the data as is.
+  # character_encoding = ""
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -239,9 +267,40 @@ func (sl *SocketListener) SetParser(parser parsers.Parser) {
 
 func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 	sl.Accumulator = acc
-	spl := strings.SplitN(sl.ServiceAddress, "://", 2)
+	decoder, err := encoding.NewDecoder(sl.CharacterEncoding)
+	if err != nil {
+		return err
+	}
+	sl.decoder = decoder
+
+	addresses := sl.ServiceAddresses
+	if sl.ServiceAddress != "" || len(addresses) == 0 {
+		addresses = append([]string{sl.ServiceAddress}, addresses...)
+	}
+
+	var closers multiCloser
+	for _, address := range addresses {
+		c, err := sl.listen(address)
+		if err != nil {
+			closers.Close()
+			return err
+		}
+		closers = append(closers, c)
+	}
+
+	if len(closers) == 1 {
+		sl.Closer = closers[0]
+	} else {
+		sl.Closer = closers
+	}
+	return nil
+}
+
+// listen starts the listener of an address.
+func (sl *SocketListener) listen(address string) (io.Closer, error) {
+	spl := strings.SplitN(address, "://", 2)
 	if len(spl) != 2 {
-		return fmt.Errorf("invalid service address: %s", sl.ServiceAddress)
+		return nil, fmt.Errorf("invalid service address: %s", address)
 	}
 
 	if spl[0] == "unix" || spl[0] == "unixpacket" || spl[0] == "unixgram" {
@@ -251,25 +310,20 @@ func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 		os.Remove(spl[1])
 	}
 
+	var closer io.Closer
 	switch spl[0] {
 	case "tcp", "tcp4", "tcp6", "unix", "unixpacket":
-		var (
-			err error
-			l   net.Listener
-		)
-
 		tlsCfg, err := sl.ServerConfig.TLSConfig()
 		if err != nil {
-			return nil
+			return nil, err
 		}
 
-		if tlsCfg == nil {
-			l, err = net.Listen(spl[0], spl[1])
-		} else {
-			l, err = tls.Listen(spl[0], spl[1], tlsCfg)
-		}
+		l, err := sl.Listen(spl[0], spl[1])
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if tlsCfg != nil {
+			l = tls.NewListener(l, tlsCfg)
 		}
 
 		ssl := &streamSocketListener{
@@ -278,12 +332,12 @@ func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 			sockType:       spl[0],
 		}
 
-		sl.Closer = ssl
+		closer = ssl
 		go ssl.listen()
 	case "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unixgram":
-		pc, err := net.ListenPacket(spl[0], spl[1])
+		pc, err := sl.ListenPacket(spl[0], spl[1])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if sl.ReadBufferSize > 0 {
@@ -299,17 +353,17 @@ func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 			SocketListener: sl,
 		}
 
-		sl.Closer = psl
+		closer = psl
 		go psl.listen()
 	default:
-		return fmt.Errorf("unknown protocol '%s' in '%s'", spl[0], sl.ServiceAddress)
+		return nil, fmt.Errorf("unknown protocol '%s' in '%s'", spl[0], address)
 	}
 
 	if spl[0] == "unix" || spl[0] == "unixpacket" || spl[0] == "unixgram" {
-		sl.Closer = unixCloser{path: spl[1], closer: sl.Closer}
+		closer = unixCloser{path: spl[1], closer: closer}
 	}
 
-	return nil
+	return closer, nil
 }
 
 func (sl *SocketListener) Stop() {
@@ -327,6 +381,19 @@ func newSocketListener() *SocketListener {
 	}
 }
 
+// multiCloser closes the listeners of several addresses.
+type multiCloser []io.Closer
+
+func (mc multiCloser) Close() error {
+	var err error
+	for _, c := range mc {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
 type unixCloser struct {
 	path   string
 	closer io.Closer
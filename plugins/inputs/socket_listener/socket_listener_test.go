@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"os"
+	"runtime"
 	"testing"
 	"time"
 
@@ -145,6 +146,37 @@ func TestSocketListener_unixgram(t *testing.T) {
 	testSocketListener(t, sl, client)
 }
 
+func TestSocketListener_characterEncoding(t *testing.T) {
+	sl := newSocketListener()
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.CharacterEncoding = "utf-16le"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, sl.Start(acc))
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	var data []byte
+	for _, r := range "\ufefftest,foo=café v=1i 123456789\n" {
+		data = append(data, byte(r), byte(r>>8))
+	}
+	_, err = client.Write(data)
+	require.NoError(t, err)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "test",
+		map[string]interface{}{"v": int64(1)},
+		map[string]string{"foo": "café"})
+
+	sl = newSocketListener()
+	sl.ServiceAddress = "udp://127.0.0.1:0"
+	sl.CharacterEncoding = "ebcdic"
+	require.EqualError(t, sl.Start(acc), `unsupported character_encoding "ebcdic"`)
+}
+
 func testSocketListener(t *testing.T, sl *SocketListener, client net.Conn) {
 	mstr12 := "test,foo=bar v=1i 123456789\ntest,foo=baz v=2i 123456790\n"
 	mstr3 := "test,foo=zab v=3i 123456791"
@@ -179,3 +211,80 @@ func testSocketListener(t *testing.T, sl *SocketListener, client net.Conn) {
 	assert.Equal(t, map[string]interface{}{"v": int64(3)}, m3.Fields)
 	assert.True(t, time.Unix(0, 123456791).Equal(m3.Time))
 }
+
+func TestSocketListener_serviceAddresses(t *testing.T) {
+	defer testEmptyLog(t)()
+
+	sl := newSocketListener()
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.ServiceAddresses = []string{"udp://127.0.0.1:0"}
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	closers := sl.Closer.(multiCloser)
+	require.Len(t, closers, 2)
+	client, err := net.Dial("tcp", closers[0].(net.Listener).Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("test,proto=tcp v=1i 123456789\n"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	client, err = net.Dial("udp", closers[1].(net.PacketConn).LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("test,proto=udp v=2i 123456790\n"))
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	acc.AssertContainsTaggedFields(t, "test",
+		map[string]interface{}{"v": int64(1)}, map[string]string{"proto": "tcp"})
+	acc.AssertContainsTaggedFields(t, "test",
+		map[string]interface{}{"v": int64(2)}, map[string]string{"proto": "udp"})
+}
+
+func TestSocketListener_serviceAddressesError(t *testing.T) {
+	sl := newSocketListener()
+	sl.ServiceAddresses = []string{"tcp://127.0.0.1:0", "sctp://127.0.0.1:0"}
+
+	err := sl.Start(&testutil.Accumulator{})
+	require.EqualError(t, err, "unknown protocol 'sctp' in 'sctp://127.0.0.1:0'")
+	require.Nil(t, sl.Closer)
+}
+
+func TestSocketListener_ipv6Only(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ipv6_v6only is not supported on windows")
+	}
+	if l, err := net.Listen("tcp6", "[::1]:0"); err != nil {
+		t.Skip("IPv6 is not available")
+	} else {
+		l.Close()
+	}
+	defer testEmptyLog(t)()
+
+	v6only := true
+	sl := newSocketListener()
+	sl.ServiceAddress = "udp://:0"
+	sl.IPv6Only = &v6only
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	_, port, err := net.SplitHostPort(sl.Closer.(net.PacketConn).LocalAddr().String())
+	require.NoError(t, err)
+	client, err := net.Dial("udp", net.JoinHostPort("::1", port))
+	require.NoError(t, err)
+
+	testSocketListener(t, sl, client)
+
+	// the IPv4 port is left to other listeners
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:"+port)
+	require.NoError(t, err)
+	pc.Close()
+}
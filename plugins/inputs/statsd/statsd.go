@@ -17,6 +17,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/listen"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/selfstat"
 )
@@ -49,6 +50,11 @@ type Statsd struct {
 	// Address & Port to serve from
 	ServiceAddress string
 
+	// Additional addresses to serve from
+	ServiceAddresses []string `toml:"service_addresses"`
+
+	listen.Config
+
 	// Number of messages allowed to queue up in between calls to Gather. If this
 	// fills up, packets will get dropped until the next Gather interval is ran.
 	AllowedPendingMessages int
@@ -104,10 +110,13 @@ type Statsd struct {
 	// bucket -> influx templates
 	Templates []string
 
-	// Protocol listeners
+	// Protocol listeners, of the first address
 	UDPlistener *net.UDPConn
 	TCPlistener *net.TCPListener
 
+	udpListeners []*net.UDPConn
+	tcpListeners []*net.TCPListener
+
 	// track current connections so we can close them in Stop()
 	conns map[string]*net.TCPConn
 
@@ -174,7 +183,8 @@ func (_ *Statsd) Description() string {
 }
 
 const sampleConfig = `
-  ## Protocol, must be "tcp", "udp", "udp4" or "udp6" (default=udp)
+  ## Protocol, must be "tcp", "tcp4", "tcp6", "udp", "udp4" or "udp6"
+  ## (default=udp)
   protocol = "udp"
 
   ## MaxTCPConnection - applicable when protocol is set to tcp (default=250)
@@ -191,6 +201,16 @@ const sampleConfig = `
   ## Address and port to host UDP listener on
   service_address = ":8125"
 
+  ## Additional addresses to listen on, eg. to listen on both IPv4 and IPv6
+  ## with ipv6_v6only.
+  # service_addresses = ["[::]:8125"]
+
+  ## Set to listen on IPv6 only, or on both IPv4 and IPv6, on the empty and
+  ## IPv6 hosts of the tcp, tcp6, udp and udp6 protocols, instead of the
+  ## defaults of the system.  The IPv4 hosts, including 0.0.0.0, then listen
+  ## on IPv4 only.  Not supported on Windows.
+  # ipv6_v6only = false
+
   ## The following configuration options control when telegraf clears it's cache
   ## of previous values. If set to false, then telegraf will only clear it's
   ## cache when the daemon is restarted.
@@ -336,38 +356,87 @@ func (s *Statsd) Start(_ telegraf.Accumulator) error {
 		s.MetricSeparator = defaultSeparator
 	}
 
-	s.wg.Add(2)
-	// Start the UDP listener
+	if err := s.listen(); err != nil {
+		return err
+	}
+
+	// Start the listeners
 	if s.isUDP() {
-		go s.udpListen()
+		for _, conn := range s.udpListeners {
+			s.wg.Add(1)
+			go s.udpListen(conn)
+		}
 	} else {
-		go s.tcpListen()
+		for _, listener := range s.tcpListeners {
+			s.wg.Add(1)
+			go s.tcpListen(listener)
+		}
 	}
 	// Start the line parser
+	s.wg.Add(1)
 	go s.parser()
 	log.Printf("I! Started the statsd service on %s\n", s.ServiceAddress)
 	return nil
 }
 
-// tcpListen() starts listening for udp packets on the configured port.
-func (s *Statsd) tcpListen() error {
-	defer s.wg.Done()
-	// Start listener
-	var err error
-	address, _ := net.ResolveTCPAddr("tcp", s.ServiceAddress)
-	s.TCPlistener, err = net.ListenTCP("tcp", address)
-	if err != nil {
-		log.Fatalf("ERROR: ListenTCP - %s", err)
-		return err
+// listen opens the listeners of the service addresses, or none if any of
+// them fails.
+func (s *Statsd) listen() error {
+	switch s.Protocol {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return fmt.Errorf("unknown protocol %q", s.Protocol)
+	}
+
+	addresses := append([]string{s.ServiceAddress}, s.ServiceAddresses...)
+	for _, address := range addresses {
+		if s.isUDP() {
+			conn, err := s.ListenPacket(s.Protocol, address)
+			if err != nil {
+				s.closeListeners()
+				return err
+			}
+			s.udpListeners = append(s.udpListeners, conn.(*net.UDPConn))
+		} else {
+			listener, err := s.Listen(s.Protocol, address)
+			if err != nil {
+				s.closeListeners()
+				return err
+			}
+			s.tcpListeners = append(s.tcpListeners, listener.(*net.TCPListener))
+		}
+	}
+
+	if s.isUDP() {
+		s.UDPlistener = s.udpListeners[0]
+	} else {
+		s.TCPlistener = s.tcpListeners[0]
+	}
+	return nil
+}
+
+func (s *Statsd) closeListeners() {
+	for _, conn := range s.udpListeners {
+		conn.Close()
+	}
+	for _, listener := range s.tcpListeners {
+		listener.Close()
 	}
-	log.Println("I! TCP Statsd listening on: ", s.TCPlistener.Addr().String())
+	s.udpListeners = nil
+	s.tcpListeners = nil
+}
+
+// tcpListen() starts listening for tcp connections on the listener.
+func (s *Statsd) tcpListen(listener *net.TCPListener) error {
+	defer s.wg.Done()
+	log.Println("I! TCP Statsd listening on: ", listener.Addr().String())
 	for {
 		select {
 		case <-s.done:
 			return nil
 		default:
 			// Accept connection:
-			conn, err := s.TCPlistener.AcceptTCP()
+			conn, err := listener.AcceptTCP()
 			if err != nil {
 				return err
 			}
@@ -400,16 +469,10 @@ func (s *Statsd) tcpListen() error {
 	}
 }
 
-// udpListen starts listening for udp packets on the configured port.
-func (s *Statsd) udpListen() error {
+// udpListen starts listening for udp packets on the connection.
+func (s *Statsd) udpListen(conn *net.UDPConn) error {
 	defer s.wg.Done()
-	var err error
-	address, _ := net.ResolveUDPAddr(s.Protocol, s.ServiceAddress)
-	s.UDPlistener, err = net.ListenUDP(s.Protocol, address)
-	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
-	}
-	log.Println("I! Statsd UDP listener listening on: ", s.UDPlistener.LocalAddr().String())
+	log.Println("I! Statsd UDP listener listening on: ", conn.LocalAddr().String())
 
 	buf := make([]byte, UDP_MAX_PACKET_SIZE)
 	for {
@@ -417,7 +480,7 @@ func (s *Statsd) udpListen() error {
 		case <-s.done:
 			return nil
 		default:
-			n, _, err := s.UDPlistener.ReadFromUDP(buf)
+			n, _, err := conn.ReadFromUDP(buf)
 			if err != nil && !strings.Contains(err.Error(), "closed network") {
 				log.Printf("E! Error READ: %s\n", err.Error())
 				continue
@@ -847,10 +910,8 @@ func (s *Statsd) Stop() {
 	s.Lock()
 	log.Println("I! Stopping the statsd service")
 	close(s.done)
-	if s.isUDP() {
-		s.UDPlistener.Close()
-	} else {
-		s.TCPlistener.Close()
+	s.closeListeners()
+	if !s.isUDP() {
 		// Close all open TCP connections
 		//  - get all conns from the s.conns map and put into slice
 		//  - this is so the forget() function doesnt conflict with looping
@@ -125,6 +125,56 @@ func TestCloseConcurrentConns(t *testing.T) {
 	listener.Stop()
 }
 
+func TestServiceAddresses(t *testing.T) {
+	listener := Statsd{
+		Protocol:               "udp4",
+		ServiceAddress:         "127.0.0.1:0",
+		ServiceAddresses:       []string{"127.0.0.1:0"},
+		AllowedPendingMessages: 10000,
+		MetricSeparator:        "_",
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+	require.Len(t, listener.udpListeners, 2)
+	require.Equal(t, listener.udpListeners[0], listener.UDPlistener)
+
+	for _, l := range listener.udpListeners {
+		conn, err := net.Dial("udp", l.LocalAddr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("test.udp.msg:1|c"))
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, listener.Gather(acc))
+		if v, ok := acc.Get("test_udp_msg"); ok && v.Fields["value"] == int64(2) {
+			return
+		}
+		acc.ClearMetrics()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the counter was not sent to both addresses")
+}
+
+func TestListenErrors(t *testing.T) {
+	listener := Statsd{
+		Protocol:       "sctp",
+		ServiceAddress: "127.0.0.1:0",
+	}
+	require.EqualError(t, listener.Start(&testutil.Accumulator{}), `unknown protocol "sctp"`)
+
+	listener = Statsd{
+		Protocol:         "tcp4",
+		ServiceAddress:   "127.0.0.1:0",
+		ServiceAddresses: []string{"[::1]:0"},
+	}
+	require.Error(t, listener.Start(&testutil.Accumulator{}))
+	require.Nil(t, listener.tcpListeners)
+}
+
 // benchmark how long it takes to accept & process 100,000 metrics:
 func BenchmarkUDP(b *testing.B) {
 	listener := Statsd{
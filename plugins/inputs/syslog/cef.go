@@ -0,0 +1,221 @@
+package syslog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cefPrefix is the prefix of the fields and tags of the CEF events.
+const cefPrefix = "cef_"
+
+// cefIntegerKeys are the extension keys of the CEF specification with
+// integer values, added as integer fields when their value is one.
+var cefIntegerKeys = map[string]bool{
+	"cn1": true, "cn2": true, "cn3": true, "cnt": true,
+	"in": true, "out": true, "fsize": true, "oldFileSize": true,
+	"spt": true, "dpt": true, "spid": true, "dpid": true, "dvcpid": true,
+	"sourceTranslatedPort": true, "destinationTranslatedPort": true,
+}
+
+// cefEvent is an event in the Common Event Format of ArcSight.
+type cefEvent struct {
+	version       int
+	deviceVendor  string
+	deviceProduct string
+	deviceVersion string
+	signatureID   string
+	name          string
+	severity      string
+	extensions    map[string]string
+}
+
+// parseCEF parses the CEF event of a message, if any:
+//
+//	CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+//
+// The event may be preceded by other text, such as the hostname added by
+// some relays, as long as it starts a word.  It returns nil if the message
+// has no valid CEF header.
+func parseCEF(message string) *cefEvent {
+	i := indexWord(message, "CEF:")
+	if i < 0 {
+		return nil
+	}
+
+	header := splitCEFHeader(message[i+len("CEF:"):])
+	if header == nil {
+		return nil
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(header[0]))
+	if err != nil {
+		return nil
+	}
+	return &cefEvent{
+		version:       version,
+		deviceVendor:  header[1],
+		deviceProduct: header[2],
+		deviceVersion: header[3],
+		signatureID:   header[4],
+		name:          header[5],
+		severity:      header[6],
+		extensions:    parseCEFExtension(header[7]),
+	}
+}
+
+// indexWord returns the index of the first occurrence of prefix starting a
+// word of the message, or -1.
+func indexWord(message, prefix string) int {
+	for i := 0; i < len(message); {
+		j := strings.Index(message[i:], prefix)
+		if j < 0 {
+			return -1
+		}
+		if i+j == 0 || message[i+j-1] == ' ' {
+			return i + j
+		}
+		i += j + 1
+	}
+	return -1
+}
+
+// splitCEFHeader splits the seven fields of the header, unescaped, and the
+// extension, or returns nil if there are fewer fields.
+func splitCEFHeader(s string) []string {
+	parts := make([]string, 0, 8)
+	var field []byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case len(parts) == 7:
+			return append(parts, s[i:])
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '|'):
+			i++
+			field = append(field, s[i])
+		case s[i] == '|':
+			parts = append(parts, string(field))
+			field = field[:0]
+		default:
+			field = append(field, s[i])
+		}
+	}
+	if len(parts) == 7 {
+		return append(parts, "")
+	}
+	return nil
+}
+
+// parseCEFExtension parses the key=value pairs of the extension.  The values
+// may contain spaces, a value ending at the last space before the next key.
+// An unescaped equal sign in a value, as sent by some devices in URLs, is
+// kept in the value when it is not preceded by a space and a key.
+func parseCEFExtension(s string) map[string]string {
+	extensions := map[string]string{}
+	key := ""
+	valueStart := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] != '=' {
+			continue
+		}
+		keyStart := strings.LastIndexByte(s[:i], ' ') + 1
+		if keyStart < valueStart || !isCEFKey(s[keyStart:i]) {
+			continue
+		}
+		if key != "" {
+			extensions[key] = unescapeCEFValue(strings.TrimRight(s[valueStart:keyStart], " "))
+		}
+		key = s[keyStart:i]
+		valueStart = i + 1
+	}
+	if key != "" {
+		extensions[key] = unescapeCEFValue(strings.TrimRight(s[valueStart:], " \r\n"))
+	}
+	return extensions
+}
+
+func isCEFKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '.', r == '-', r == '[', r == ']':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func unescapeCEFValue(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b = append(b, s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case '\\', '=':
+			b = append(b, s[i])
+		default:
+			b = append(b, '\\', s[i])
+		}
+	}
+	return string(b)
+}
+
+// addCEF adds the header and the extensions of the CEF event of the message
+// as fields, and as tags for the header fields identifying the kind of the
+// event and the extensions of cef_extensions_as_tags.
+func (s *Syslog) addCEF(flds map[string]interface{}, ts map[string]string) {
+	message, ok := flds["message"].(string)
+	if !ok {
+		return
+	}
+	event := parseCEF(message)
+	if event == nil {
+		return
+	}
+
+	ts[cefPrefix+"device_vendor"] = event.deviceVendor
+	ts[cefPrefix+"device_product"] = event.deviceProduct
+	ts[cefPrefix+"device_version"] = event.deviceVersion
+	ts[cefPrefix+"signature_id"] = event.signatureID
+	flds[cefPrefix+"version"] = event.version
+	flds[cefPrefix+"name"] = event.name
+	flds[cefPrefix+"severity"] = event.severity
+
+	for key, value := range event.extensions {
+		if s.cefExtensionAsTag(key) {
+			ts[cefPrefix+key] = value
+			continue
+		}
+		if cefIntegerKeys[key] {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				flds[cefPrefix+key] = n
+				continue
+			}
+		}
+		flds[cefPrefix+key] = value
+	}
+}
+
+func (s *Syslog) cefExtensionAsTag(key string) bool {
+	for _, k := range s.CEFAsTags {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCEF(t *testing.T) {
+	tests := []struct {
+		message string
+		event   *cefEvent
+	}{
+		{
+			`CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232`,
+			&cefEvent{
+				deviceVendor:  "Security",
+				deviceProduct: "threatmanager",
+				deviceVersion: "1.0",
+				signatureID:   "100",
+				name:          "worm successfully stopped",
+				severity:      "10",
+				extensions:    map[string]string{"src": "10.0.0.1", "dst": "2.1.2.2", "spt": "1232"},
+			},
+		},
+		{
+			// The values may contain spaces and escaped characters, and the
+			// event may follow the hostname.
+			`host1 CEF:1|Vendor\|Inc|Prod\\uct|2|sig|Login failed|High|msg=bad password\nfor root\=admin suser=root request=https://example.com/?a=b`,
+			&cefEvent{
+				version:       1,
+				deviceVendor:  "Vendor|Inc",
+				deviceProduct: `Prod\uct`,
+				deviceVersion: "2",
+				signatureID:   "sig",
+				name:          "Login failed",
+				severity:      "High",
+				extensions: map[string]string{
+					"msg":     "bad password\nfor root=admin",
+					"suser":   "root",
+					"request": "https://example.com/?a=b",
+				},
+			},
+		},
+		{
+			`CEF:0|Vendor|Product|1|sig|name|5|`,
+			&cefEvent{
+				deviceVendor:  "Vendor",
+				deviceProduct: "Product",
+				deviceVersion: "1",
+				signatureID:   "sig",
+				name:          "name",
+				severity:      "5",
+				extensions:    map[string]string{},
+			},
+		},
+		{`CEF:0|Vendor|Product|1|sig|name`, nil},
+		{`CEF:x|Vendor|Product|1|sig|name|5|`, nil},
+		{`NOTCEF:0|Vendor|Product|1|sig|name|5|`, nil},
+		{`hello`, nil},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.event, parseCEF(tt.message), tt.message)
+	}
+}
+
+func TestCEF(t *testing.T) {
+	s := &Syslog{
+		Options: Options{
+			Separator:      "_",
+			SyslogStandard: standardAuto,
+			CEF:            true,
+			CEFAsTags:      []string{"act"},
+		},
+		now: time.Now,
+	}
+	require.NoError(t, s.configure("udp"))
+	s.registerStats()
+
+	acc := &testutil.Accumulator{}
+	s.storeRFC3164([]byte(`<134>Oct 11 22:14:15 CEF:0|Security|threatmanager|1.0|100|worm stopped|10|src=10.0.0.1 spt=1232 cnt=x act=blocked`), "", false, acc)
+	require.Len(t, acc.Metrics, 1)
+	m := acc.Metrics[0]
+	require.Equal(t, map[string]string{
+		"severity":           "info",
+		"facility":           "local0",
+		"standard":           "RFC3164",
+		"cef_device_vendor":  "Security",
+		"cef_device_product": "threatmanager",
+		"cef_device_version": "1.0",
+		"cef_signature_id":   "100",
+		"cef_act":            "blocked",
+	}, m.Tags)
+	require.Equal(t, 0, m.Fields["cef_version"])
+	require.Equal(t, "worm stopped", m.Fields["cef_name"])
+	require.Equal(t, "10", m.Fields["cef_severity"])
+	require.Equal(t, "10.0.0.1", m.Fields["cef_src"])
+	require.Equal(t, int64(1232), m.Fields["cef_spt"])
+	require.Equal(t, "x", m.Fields["cef_cnt"])
+	require.Contains(t, m.Fields, "message")
+
+	// The messages without an event are added as they are.
+	acc.ClearMetrics()
+	s.storeRFC3164([]byte(`<134>Oct 11 22:14:15 host app: hello`), "", false, acc)
+	require.Len(t, acc.Metrics, 1)
+	require.NotContains(t, acc.Metrics[0].Tags, "cef_device_vendor")
+}
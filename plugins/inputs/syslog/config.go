@@ -0,0 +1,398 @@
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Validate checks the configuration without starting the listener.  Besides
+// the errors of Start, it reports the options which have no effect with the
+// other options.
+func (s *Syslog) Validate() error {
+	if len(s.Servers) > 0 {
+		return s.validateServers()
+	}
+	if s.Address == "" {
+		return fmt.Errorf("server or servers is required")
+	}
+
+	scheme, _, err := getAddressParts(s.Address)
+	if err != nil {
+		return err
+	}
+	if err := s.configure(scheme); err != nil {
+		return err
+	}
+
+	if !s.isStream {
+		switch {
+		case s.Framing != "" || s.Trailer != "":
+			return fmt.Errorf("framing and trailer only apply to stream sockets")
+		case s.TLSCert != "" || s.TLSKey != "" || len(s.TLSAllowedCACerts) > 0 ||
+			len(s.AllowedClientDNs) > 0 || len(s.AllowedClientCNs) > 0 ||
+			s.TLSMinVersion != "" || s.TLSMaxVersion != "" || len(s.TLSCipherSuites) > 0:
+			return fmt.Errorf("TLS only applies to stream sockets")
+		case s.MaxConnections > 0 || s.KeepAlivePeriod != nil || s.PauseOnFailure:
+			return fmt.Errorf("max_connections, keep_alive_period and pause_on_output_failure only apply to stream sockets")
+		case s.ProxyProtocol:
+			return fmt.Errorf("proxy_protocol only applies to stream sockets")
+		case s.ShutdownTimeout != nil:
+			return fmt.Errorf("shutdown_timeout only applies to stream sockets")
+		}
+	}
+	if s.isStream && s.MaxDatagramSize > 0 {
+		return fmt.Errorf("max_datagram_size only applies to datagram sockets")
+	}
+	if s.isDTLS {
+		switch {
+		case s.TLSMinVersion != "" || s.TLSMaxVersion != "":
+			return fmt.Errorf("tls_min_version and tls_max_version do not apply to DTLS, always version 1.2")
+		case s.KeepAlivePeriod != nil || s.ProxyProtocol:
+			return fmt.Errorf("keep_alive_period and proxy_protocol do not apply to DTLS")
+		}
+	}
+	if s.isRELP && (s.Framing != "" || s.Trailer != "") {
+		return fmt.Errorf("framing and trailer do not apply to RELP")
+	}
+	if s.Framing == framingOctetCounting && s.Trailer != "" {
+		return fmt.Errorf("trailer only applies to the non-transparent framing")
+	}
+	if s.QueueSize > 0 && s.ParseWorkers == 0 {
+		return fmt.Errorf("queue_size requires parse_workers")
+	}
+	if s.OversizedMessages != "" && s.MaxMessageSize == 0 && s.MaxDatagramSize == 0 {
+		return fmt.Errorf("oversized_messages requires max_message_size or max_datagram_size")
+	}
+	if s.DefaultTimezone != "" && s.standard == standardRFC5424 {
+		return fmt.Errorf("default_timezone only applies to RFC3164 messages")
+	}
+	if s.sdJSON && s.SDParamPrefix != "" {
+		return fmt.Errorf("sdparam_prefix does not apply to structured_data_format = \"json\"")
+	}
+	if len(s.CEFAsTags) > 0 && !s.CEF {
+		return fmt.Errorf("cef_extensions_as_tags requires cef")
+	}
+	if len(s.LEEFAsTags) > 0 && !s.LEEF {
+		return fmt.Errorf("leef_attributes_as_tags requires leef")
+	}
+	if s.SocketMode != "" && !s.isUnix {
+		return fmt.Errorf("socket_mode only applies to unix domain sockets")
+	}
+	if s.sources != nil && s.isUnix && !s.ProxyProtocol {
+		return fmt.Errorf("allowed_sources and denied_sources do not apply to unix domain sockets")
+	}
+	return nil
+}
+
+// validateServers validates the listeners of servers.  The options which
+// have no effect are only reported when they apply to none of them, since
+// the options are shared by servers of different protocols.
+func (s *Syslog) validateServers() error {
+	if s.Address != "" {
+		return fmt.Errorf("server and servers cannot be both set")
+	}
+	var errs []error
+	for _, server := range s.Servers {
+		l := s.listener(server)
+		scheme, _, err := getAddressParts(server)
+		if err != nil {
+			return err
+		}
+		if err := l.configure(scheme); err != nil {
+			return fmt.Errorf("%s: %s", server, err)
+		}
+		errs = append(errs, l.Validate())
+	}
+	for _, err := range errs {
+		if err == nil || err.Error() != errs[0].Error() {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+// registerStats registers the internal_syslog stats of the listener.
+func (s *Syslog) registerStats() {
+	tags := map[string]string{
+		"address": s.Address,
+	}
+	s.connectionsAccepted = selfstat.Register("syslog", "connections_accepted", tags)
+	s.connectionsRejected = selfstat.Register("syslog", "connections_rejected", tags)
+	s.messagesParsed = selfstat.Register("syslog", "messages_parsed", tags)
+	s.messagesFiltered = selfstat.Register("syslog", "messages_filtered", tags)
+	s.messagesRateLimited = selfstat.Register("syslog", "messages_rate_limited", tags)
+	s.messagesOversized = selfstat.Register("syslog", "messages_oversized", tags)
+	s.tlsClientsRejected = selfstat.Register("syslog", "tls_clients_rejected", tags)
+	s.sourcesDenied = selfstat.Register("syslog", "sources_denied", tags)
+	s.parseErrors = selfstat.Register("syslog", "parse_errors", tags)
+	s.framesDropped = selfstat.Register("syslog", "frames_dropped", tags)
+	s.messagesDropped = selfstat.Register("syslog", "messages_dropped", tags)
+	s.queueBlocked = selfstat.Register("syslog", "queue_blocked", tags)
+}
+
+// configure checks the options and sets the values derived from them.
+func (s *Syslog) configure(scheme string) error {
+	switch strings.ToLower(s.SyslogStandard) {
+	case "", standardRFC5424:
+		s.standard = standardRFC5424
+	case standardRFC3164, standardAuto:
+		s.standard = strings.ToLower(s.SyslogStandard)
+	default:
+		return fmt.Errorf("unknown syslog_standard %q", s.SyslogStandard)
+	}
+
+	s.location = nil
+	if s.DefaultTimezone != "" {
+		location, err := time.LoadLocation(s.DefaultTimezone)
+		if err != nil {
+			return fmt.Errorf("invalid default_timezone %q: %s", s.DefaultTimezone, err)
+		}
+		s.location = location
+	}
+
+	switch strings.ToLower(s.TimeSource) {
+	case "", timeSourceReceived:
+		s.messageTime = false
+	case timeSourceMessage:
+		s.messageTime = true
+	default:
+		return fmt.Errorf("unknown time_source %q", s.TimeSource)
+	}
+
+	switch strings.ToLower(s.SDFormat) {
+	case "", sdFormatFields:
+		s.sdJSON = false
+	case sdFormatJSON:
+		s.sdJSON = true
+	default:
+		return fmt.Errorf("unknown structured_data_format %q", s.SDFormat)
+	}
+
+	switch s.Framing {
+	case "", framingOctetCounting, framingNonTransparent:
+	default:
+		return fmt.Errorf("unknown framing %q", s.Framing)
+	}
+	switch strings.ToUpper(s.Trailer) {
+	case "", "LF":
+		s.trailer = '\n'
+	case "NUL":
+		s.trailer = 0
+	default:
+		return fmt.Errorf("unknown trailer %q", s.Trailer)
+	}
+
+	switch scheme {
+	case "tcp", "tcp4", "tcp6", "unix", "unixpacket", "relp", "dtls":
+		s.isStream = true
+	case "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unixgram":
+		s.isStream = false
+	default:
+		return fmt.Errorf("unknown protocol '%s' in '%s'", scheme, s.Address)
+	}
+
+	priorities, err := priorityFilter(s.SeverityFilter, s.FacilityFilter)
+	if err != nil {
+		return err
+	}
+	s.priorities = priorities
+
+	if s.severityNames, err = tagNames("severity_names", s.SeverityNames, severityCode, severityName, 8); err != nil {
+		return err
+	}
+	if s.facilityNames, err = tagNames("facility_names", s.FacilityNames, facilityCode, facilityName, 24); err != nil {
+		return err
+	}
+
+	if s.MaxRate < 0 || s.MaxRatePerPeer < 0 {
+		return fmt.Errorf("max_messages_per_second and max_messages_per_second_per_peer cannot be negative")
+	}
+	s.limiter = newRateLimiter(s.MaxRate, s.MaxRatePerPeer)
+
+	if s.sources, err = newSourceFilter(s.AllowedSources, s.DeniedSources); err != nil {
+		return err
+	}
+
+	if s.MaxMessageSize < 0 {
+		return fmt.Errorf("max_message_size cannot be negative")
+	}
+	if s.MaxDatagramSize < 0 {
+		return fmt.Errorf("max_datagram_size cannot be negative")
+	}
+	switch strings.ToLower(s.OversizedMessages) {
+	case "", oversizedDrop:
+		s.oversized = oversizedDrop
+	case oversizedTruncate:
+		s.oversized = oversizedTruncate
+	default:
+		return fmt.Errorf("unknown oversized_messages %q", s.OversizedMessages)
+	}
+
+	decoder, err := encoding.NewDecoder(s.CharacterEncoding)
+	if err != nil {
+		return err
+	}
+	s.decoder = decoder
+
+	if s.ParseWorkers < 0 || s.QueueSize < 0 {
+		return fmt.Errorf("parse_workers and queue_size cannot be negative")
+	}
+
+	if len(s.AllowedClientDNs) > 0 || len(s.AllowedClientCNs) > 0 {
+		if len(s.TLSAllowedCACerts) == 0 || !s.TLSRequireClientCert {
+			return fmt.Errorf("allowed_client_dns and allowed_client_cns require tls_allowed_cacerts and tls_require_client_cert")
+		}
+	}
+	if _, _, _, err := s.tlsVersionsAndCiphers(); err != nil {
+		return err
+	}
+
+	s.isUnix = scheme == "unix" || scheme == "unixpacket" || scheme == "unixgram"
+	s.isRELP = scheme == "relp"
+	s.isDTLS = scheme == "dtls"
+	if s.isDTLS && (s.TLSCert == "" || s.TLSKey == "") {
+		return fmt.Errorf("dtls requires tls_cert and tls_key")
+	}
+	if s.isDTLS && !dtlsSupported {
+		return fmt.Errorf("dtls requires Telegraf built with Go 1.18 or later")
+	}
+	if s.isUnix && s.SocketMode != "" {
+		mode, err := strconv.ParseUint(s.SocketMode, 8, 32)
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("invalid socket_mode '%s'", s.SocketMode)
+		}
+		s.socketMode = os.FileMode(mode)
+	}
+	return nil
+}
+
+// priorityFilter returns whether the messages of each priority are kept by
+// the severity and facility filters, or nil if all of them are.
+func priorityFilter(severityFilter string, facilityFilter []string) ([]bool, error) {
+	if severityFilter == "" && len(facilityFilter) == 0 {
+		return nil, nil
+	}
+
+	maxSeverity := uint8(7)
+	if severityFilter != "" {
+		severity, ok := severityCode(severityFilter)
+		if !ok {
+			return nil, fmt.Errorf("unknown severity_filter %q", severityFilter)
+		}
+		maxSeverity = severity
+	}
+
+	facilities := make([]bool, 24)
+	include := false
+	for _, name := range facilityFilter {
+		if !strings.HasPrefix(name, "!") {
+			include = true
+		}
+	}
+	for i := range facilities {
+		facilities[i] = !include
+	}
+	for _, name := range facilityFilter {
+		exclude := strings.HasPrefix(name, "!")
+		facility, ok := facilityCode(strings.TrimPrefix(name, "!"))
+		if !ok {
+			return nil, fmt.Errorf("unknown facility %q in facility_filter", name)
+		}
+		facilities[facility] = !exclude
+	}
+
+	priorities := make([]bool, len(facilities)*8)
+	for i := range priorities {
+		priorities[i] = facilities[i/8] && uint8(i%8) <= maxSeverity
+	}
+	return priorities, nil
+}
+
+// severityCode returns the code of a severity named as in the severity tag.
+func severityCode(name string) (uint8, bool) {
+	for code := uint8(0); code < 8; code++ {
+		if strings.EqualFold(severityName(code), name) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// severityName returns the default name of a severity in the severity tag.
+func severityName(code uint8) string {
+	return *(&rfc5424.SyslogMessage{}).SetPriority(code).SeverityShortLevel()
+}
+
+// facilityCode returns the code of a facility named as in the facility tag.
+func facilityCode(name string) (uint8, bool) {
+	for code := uint8(0); code < 24; code++ {
+		if strings.EqualFold(facilityName(code), name) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// facilityName returns the default name of a facility in the facility tag.
+func facilityName(code uint8) string {
+	return *(&rfc5424.SyslogMessage{}).SetPriority(code * 8).FacilityLevel()
+}
+
+// tagNames returns the names of the severity_names or facility_names option
+// by default name of the tag, the keys of the option being either the
+// codes, below count, or the default names.
+func tagNames(option string, names map[string]string, code func(string) (uint8, bool), name func(uint8) string, count uint8) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	byName := make(map[string]string, len(names))
+	for key, value := range names {
+		c, ok := code(key)
+		if !ok {
+			n, err := strconv.ParseUint(key, 10, 8)
+			if err != nil || uint8(n) >= count {
+				return nil, fmt.Errorf("unknown key %q in %s", key, option)
+			}
+			c = uint8(n)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("empty name for %q in %s", key, option)
+		}
+		byName[name(c)] = value
+	}
+	return byName, nil
+}
+
+// getAddressParts returns the address scheme and host
+// it also sets defaults for them when missing
+// when the input address does not specify the protocol it returns an error
+func getAddressParts(a string) (string, string, error) {
+	parts := strings.SplitN(a, "://", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("missing protocol within address '%s'", a)
+	}
+
+	scheme, host := strings.ToLower(parts[0]), parts[1]
+	switch scheme {
+	case "unix", "unixpacket", "unixgram":
+		return parts[0], parts[1], nil
+	}
+
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host = host[:i]
+	}
+	// The IPv6 literals are bracketed, as with the zones which url.Parse
+	// would reject unless escaped.
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "6514")
+	}
+	return scheme, host, nil
+}
@@ -0,0 +1,202 @@
+//go:build go1.18
+// +build go1.18
+
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"github.com/pion/logging"
+	"github.com/pion/transport/v2/udp"
+)
+
+// The listener of the dtls protocol accepts DTLS 1.2 (RFC6347) associations,
+// for syslog over DTLS as per RFC6012, with the DTLS implementation of
+// github.com/pion/dtls.
+
+const dtlsSupported = true
+
+var errDTLSClosed = errors.New("use of closed network connection")
+
+// dtlsCurves are the elliptic curves supported by DTLS.
+var dtlsCurves = map[tls.CurveID]elliptic.Curve{
+	tls.X25519:    elliptic.X25519,
+	tls.CurveP256: elliptic.P256,
+	tls.CurveP384: elliptic.P384,
+}
+
+// dtlsListener accepts an association for each address sending a handshake
+// record, whose handshake is done by handshake.
+type dtlsListener struct {
+	net.Listener
+	config *dtls.Config
+}
+
+// newDTLSListener listens on the UDP address with the DTLS configuration of
+// a TLS configuration.  IPv6Only sets the IPV6_V6ONLY option of the socket as
+// listen.Config does.
+func newDTLSListener(address string, ipv6Only *bool, config *tls.Config) (*dtlsListener, error) {
+	dtlsConfig, err := newDTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	network := "udp"
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	// Go sets IPV6_V6ONLY on the IPv6 wildcard sockets of the udp6 network,
+	// and clears it on the ones of the udp network.
+	if ipv6Only != nil && *ipv6Only && addr.IP.To4() == nil {
+		network = "udp6"
+	}
+	lc := udp.ListenConfig{
+		// Only the handshake records start an association, the others of
+		// unknown addresses are discarded.
+		AcceptFilter: func(b []byte) bool {
+			records, err := recordlayer.UnpackDatagram(b)
+			if err != nil || len(records) == 0 {
+				return false
+			}
+			var h recordlayer.Header
+			return h.Unmarshal(records[0]) == nil && h.ContentType == protocol.ContentTypeHandshake
+		},
+	}
+	l, err := lc.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &dtlsListener{Listener: l, config: dtlsConfig}, nil
+}
+
+// newDTLSConfig returns the DTLS configuration of a TLS configuration, with
+// its certificates, client authentication and, as restricted by the TLS
+// policy, cipher suites and curves.
+func newDTLSConfig(config *tls.Config) (*dtls.Config, error) {
+	if config == nil || len(config.Certificates) == 0 {
+		return nil, fmt.Errorf("dtls requires tls_cert and tls_key")
+	}
+	dtlsConfig := &dtls.Config{
+		Certificates:          config.Certificates,
+		ClientAuth:            dtls.ClientAuthType(config.ClientAuth),
+		ClientCAs:             config.ClientCAs,
+		VerifyPeerCertificate: config.VerifyPeerCertificate,
+		LoggerFactory:         &logging.DefaultLoggerFactory{DefaultLogLevel: logging.LogLevelDisabled},
+	}
+
+	if config.CipherSuites != nil {
+		supported := map[uint16]bool{}
+		for _, suite := range dtls.CipherSuites() {
+			supported[suite.ID] = true
+		}
+		for _, suite := range config.CipherSuites {
+			if supported[suite] {
+				dtlsConfig.CipherSuites = append(dtlsConfig.CipherSuites, dtls.CipherSuiteID(suite))
+			}
+		}
+		if len(dtlsConfig.CipherSuites) == 0 {
+			return nil, fmt.Errorf("none of the cipher suites is supported by DTLS")
+		}
+	}
+	if config.CurvePreferences != nil {
+		for _, id := range config.CurvePreferences {
+			if curve, ok := dtlsCurves[id]; ok {
+				dtlsConfig.EllipticCurves = append(dtlsConfig.EllipticCurves, curve)
+			}
+		}
+		if len(dtlsConfig.EllipticCurves) == 0 {
+			return nil, fmt.Errorf("none of the curves is supported by DTLS")
+		}
+	}
+	return dtlsConfig, nil
+}
+
+// Accept returns the next association, not handshaken yet.
+func (l *dtlsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == udp.ErrClosedListener {
+		// As the errors of the closed listeners of the net package.
+		err = &net.OpError{Op: "accept", Net: "dtls", Addr: l.Addr(), Err: errDTLSClosed}
+	}
+	return conn, err
+}
+
+// handshake does the handshake of an association, and returns its DTLS
+// connection and the distinguished name of the client certificate if any.
+func (l *dtlsListener) handshake(conn net.Conn) (net.Conn, string, error) {
+	filter := &plaintextFilter{Conn: conn}
+	c, err := dtls.Server(filter, l.config)
+	if err != nil {
+		return nil, "", err
+	}
+	atomic.StoreInt32(&filter.handshaken, 1)
+
+	var identity string
+	if certs := c.ConnectionState().PeerCertificates; len(certs) > 0 {
+		cert, err := x509.ParseCertificate(certs[0])
+		if err != nil {
+			c.Close()
+			return nil, "", err
+		}
+		if identity, err = distinguishedName(cert.RawSubject); err != nil {
+			c.Close()
+			return nil, "", err
+		}
+	}
+	return c, identity, nil
+}
+
+// plaintextFilter discards the records of epoch 0 of an association which
+// are not authenticated and would end it if spoofed, as per
+// RFC6347#section-4.1.2.7: the alerts and application data, and once
+// handshaken the handshake records.  A client failing its handshake with an
+// alert times out instead.
+type plaintextFilter struct {
+	net.Conn
+	handshaken int32
+}
+
+func (c *plaintextFilter) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(b)
+		if err != nil {
+			return n, err
+		}
+		if n = c.filter(b[:n]); n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// filter removes the discarded records of a datagram, and returns the size
+// of the remaining ones.
+func (c *plaintextFilter) filter(b []byte) int {
+	records, err := recordlayer.UnpackDatagram(b)
+	if err != nil {
+		return 0
+	}
+	handshaken := atomic.LoadInt32(&c.handshaken) == 1
+	n := 0
+	for _, r := range records {
+		var h recordlayer.Header
+		if err := h.Unmarshal(r); err != nil {
+			continue
+		}
+		if h.Epoch == 0 && (handshaken ||
+			(h.ContentType != protocol.ContentTypeHandshake && h.ContentType != protocol.ContentTypeChangeCipherSpec)) {
+			continue
+		}
+		n += copy(b[n:], r)
+	}
+	return n
+}
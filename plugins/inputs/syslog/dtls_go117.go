@@ -0,0 +1,25 @@
+//go:build !go1.18
+// +build !go1.18
+
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+)
+
+// dtlsSupported is false as the DTLS implementation requires Go 1.18.
+const dtlsSupported = false
+
+type dtlsListener struct {
+	net.Listener
+}
+
+func newDTLSListener(address string, ipv6Only *bool, config *tls.Config) (*dtlsListener, error) {
+	return nil, fmt.Errorf("dtls requires Telegraf built with Go 1.18 or later")
+}
+
+func (l *dtlsListener) handshake(conn net.Conn) (net.Conn, string, error) {
+	return nil, "", fmt.Errorf("dtls requires Telegraf built with Go 1.18 or later")
+}
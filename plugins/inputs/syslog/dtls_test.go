@@ -0,0 +1,393 @@
+//go:build go1.18
+// +build go1.18
+
+package syslog
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func newDTLSReceiver(t *testing.T, requireClientCert bool) (*Syslog, *testutil.Accumulator) {
+	receiver := &Syslog{
+		Address: "dtls://127.0.0.1:0",
+		Options: Options{
+			ServerConfig:         *pki.TLSServerConfig(),
+			TLSRequireClientCert: requireClientCert,
+			Separator:            "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	return receiver, acc
+}
+
+func TestDTLSValidate(t *testing.T) {
+	tests := []struct {
+		syslog *Syslog
+		err    string
+	}{
+		{&Syslog{Address: "dtls://127.0.0.1:6514", Options: Options{ServerConfig: *pki.TLSServerConfig(), MaxConnections: 10}}, ""},
+		{&Syslog{Address: "dtls://127.0.0.1:6514", Options: Options{ServerConfig: *pki.TLSServerConfig(), TLSMinVersion: "TLS12"}}, "tls_min_version and tls_max_version do not apply to DTLS, always version 1.2"},
+		{&Syslog{Address: "dtls://127.0.0.1:6514", Options: Options{ServerConfig: *pki.TLSServerConfig(), ProxyProtocol: true}}, "keep_alive_period and proxy_protocol do not apply to DTLS"},
+	}
+	for _, tt := range tests {
+		err := tt.syslog.Validate()
+		if tt.err == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, tt.err)
+		}
+	}
+}
+
+// dialDTLS does the handshake of a DTLS client, with the client certificate
+// if cert, and returns its association and UDP socket.
+func dialDTLS(t *testing.T, receiver *Syslog, cert bool, suites ...dtls.CipherSuiteID) (*dtls.Conn, *net.UDPConn, error) {
+	tlsConfig, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	config := &dtls.Config{
+		RootCAs:      tlsConfig.RootCAs,
+		ServerName:   "localhost",
+		CipherSuites: suites,
+		ConnectContextMaker: func() (context.Context, func()) {
+			return context.WithTimeout(context.Background(), 5*time.Second)
+		},
+	}
+	if cert {
+		config.Certificates = tlsConfig.Certificates
+	}
+
+	addr := receiver.tcpListener.Addr().(*net.UDPAddr)
+	conn, err := net.DialUDP("udp", nil, addr)
+	require.NoError(t, err)
+	c, err := dtls.Client(conn, config)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return c, conn, nil
+}
+
+// plaintextRecord returns a DTLS 1.2 record of epoch 0.
+func plaintextRecord(typ protocol.ContentType, seq uint64, data []byte) []byte {
+	b := []byte{byte(typ), 0xfe, 0xfd, 0, 0,
+		byte(seq >> 40), byte(seq >> 32), byte(seq >> 24), byte(seq >> 16), byte(seq >> 8), byte(seq),
+		byte(len(data) >> 8), byte(len(data))}
+	return append(b, data...)
+}
+
+func TestDTLS(t *testing.T) {
+	receiver, acc := newDTLSReceiver(t, true)
+	defer receiver.Stop()
+
+	c, _, err := dialDTLS(t, receiver, true)
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Write([]byte("22 <1>1 - - - - - - hello"))
+	require.NoError(t, err)
+	acc.Wait(1)
+	require.Equal(t, "hello", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "CN=client.localdomain", acc.Metrics[0].Tags["peer_identity"])
+	require.Equal(t, "127.0.0.1", acc.Metrics[0].Tags["source"])
+
+	// close_notify ends the association.
+	require.NoError(t, c.Close())
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		receiver.connectionsMu.Lock()
+		n := len(receiver.connections)
+		receiver.connectionsMu.Unlock()
+		if n == 0 {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "the association is not closed")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDTLSPlaintextRecordsDiscarded(t *testing.T) {
+	receiver, acc := newDTLSReceiver(t, true)
+	defer receiver.Stop()
+
+	c, conn, err := dialDTLS(t, receiver, true)
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Write([]byte("22 <1>1 - - - - - - hello"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	// Records of epoch 0 spoofed from the address of the client, a fatal
+	// handshake_failure alert, a truncated ClientHello and application data,
+	// do not end the association.
+	for i, record := range [][]byte{
+		plaintextRecord(protocol.ContentTypeAlert, 100, []byte{2, 40}),
+		plaintextRecord(protocol.ContentTypeHandshake, 101, []byte{1, 0, 0, 0x20, 0, 0}),
+		plaintextRecord(protocol.ContentTypeApplicationData, 102, []byte("22 <1>1 - - - - - - spoofed")),
+	} {
+		_, err = conn.Write(record)
+		require.NoError(t, err, "record %d", i)
+	}
+	_, err = c.Write([]byte("22 <1>1 - - - - - - again"))
+	require.NoError(t, err)
+	acc.Wait(2)
+	require.Equal(t, "again", acc.Metrics[1].Fields["message"])
+	require.Equal(t, 0, len(acc.Errors))
+}
+
+func TestPlaintextFilter(t *testing.T) {
+	handshake := plaintextRecord(protocol.ContentTypeHandshake, 1, []byte{1, 0, 0, 0})
+	alert := plaintextRecord(protocol.ContentTypeAlert, 2, []byte{2, 40})
+	encrypted := plaintextRecord(protocol.ContentTypeApplicationData, 3, []byte("data"))
+	encrypted[4] = 1
+
+	var f plaintextFilter
+	b := append(append(append([]byte(nil), handshake...), alert...), encrypted...)
+	n := f.filter(b)
+	require.Equal(t, append(append([]byte(nil), handshake...), encrypted...), b[:n])
+
+	f.handshaken = 1
+	b = append(append(append([]byte(nil), handshake...), alert...), encrypted...)
+	n = f.filter(b)
+	require.Equal(t, encrypted, b[:n])
+
+	// The truncated datagrams are discarded.
+	require.Equal(t, 0, f.filter(encrypted[:len(encrypted)-1]))
+}
+
+func TestDTLSClientCertRequired(t *testing.T) {
+	receiver, acc := newDTLSReceiver(t, true)
+	defer receiver.Stop()
+
+	_, _, err := dialDTLS(t, receiver, false)
+	require.Error(t, err)
+	acc.WaitError(1)
+	require.Contains(t, acc.FirstError().Error(), "server required client verification, but got none")
+	require.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestDTLSOptionalClientCert(t *testing.T) {
+	receiver, acc := newDTLSReceiver(t, false)
+	defer receiver.Stop()
+
+	c, _, err := dialDTLS(t, receiver, false)
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Write([]byte("22 <1>1 - - - - - - hello"))
+	require.NoError(t, err)
+	acc.Wait(1)
+	require.Equal(t, "hello", acc.Metrics[0].Fields["message"])
+	_, ok := acc.Metrics[0].Tags["peer_identity"]
+	require.False(t, ok)
+}
+
+func TestDTLSCipherSuites(t *testing.T) {
+	receiver := &Syslog{
+		Address: "dtls://127.0.0.1:0",
+		Options: Options{
+			ServerConfig:    *pki.TLSServerConfig(),
+			TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+			Separator:       "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	_, _, err := dialDTLS(t, receiver, true, dtls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+	require.Error(t, err)
+
+	c, _, err := dialDTLS(t, receiver, true, dtls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384)
+	require.NoError(t, err)
+	c.Close()
+
+	// The cipher suites without DTLS support are refused.
+	receiver = &Syslog{
+		Address: "dtls://127.0.0.1:0",
+		Options: Options{
+			ServerConfig:    *pki.TLSServerConfig(),
+			TLSCipherSuites: []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"},
+		},
+		now: time.Now,
+	}
+	require.EqualError(t, receiver.Start(acc), "none of the cipher suites is supported by DTLS")
+}
+
+func TestDTLSPolicy(t *testing.T) {
+	require.NoError(t, tlsint.SetPolicy("", true))
+	defer tlsint.SetPolicy("", false)
+
+	receiver, _ := newDTLSReceiver(t, true)
+	defer receiver.Stop()
+
+	// AES-CBC is not approved by FIPS 140-2.
+	_, _, err := dialDTLS(t, receiver, true, dtls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA)
+	require.Error(t, err)
+
+	c, _, err := dialDTLS(t, receiver, true, dtls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+	require.NoError(t, err)
+	c.Close()
+}
+
+// opensslClient is the DTLS 1.2 client of OpenSSL, for the
+// interoperability of the listener with another implementation.
+type opensslClient struct {
+	cmd  *exec.Cmd
+	out  bytes.Buffer
+	done chan error
+}
+
+// startOpenSSLClient sends the message with an OpenSSL client.  The test is
+// skipped if the openssl command is not found.
+func startOpenSSLClient(t *testing.T, addr, caFile, message string, args ...string) *opensslClient {
+	path, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl not found")
+	}
+	args = append([]string{"s_client", "-dtls1_2", "-connect", addr, "-CAfile", caFile, "-quiet"}, args...)
+	c := &opensslClient{
+		cmd:  exec.Command(path, args...),
+		done: make(chan error, 1),
+	}
+	c.cmd.Stdin = strings.NewReader(message)
+	c.cmd.Stdout = &c.out
+	c.cmd.Stderr = &c.out
+	require.NoError(t, c.cmd.Start())
+	go func() {
+		c.done <- c.cmd.Wait()
+	}()
+	return c
+}
+
+// waitMetric waits for the message of the client, which does not exit at the
+// end of its input, and stops it.
+func (c *opensslClient) waitMetric(t *testing.T, acc *testutil.Accumulator) {
+	for i := 0; i < 1000 && acc.NMetrics() == 0; i++ {
+		select {
+		case err := <-c.done:
+			t.Fatalf("openssl exited: %v: %s", err, c.out.String())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	c.cmd.Process.Kill()
+	<-c.done
+	require.Equal(t, uint64(1), acc.NMetrics(), c.out.String())
+	acc.Wait(1)
+}
+
+// waitExit waits for the client to exit, such as on an alert of the
+// listener.
+func (c *opensslClient) waitExit(t *testing.T) error {
+	select {
+	case err := <-c.done:
+		return err
+	case <-time.After(10 * time.Second):
+		c.cmd.Process.Kill()
+		<-c.done
+		t.Fatalf("openssl not exited: %s", c.out.String())
+		return nil
+	}
+}
+
+func TestDTLSOpenSSL(t *testing.T) {
+	for _, args := range [][]string{
+		{"-cipher", "ECDHE-RSA-AES128-GCM-SHA256"},
+		{"-cipher", "ECDHE-RSA-AES256-GCM-SHA384"},
+		{"-curves", "P-384"},
+	} {
+		receiver, acc := newDTLSReceiver(t, true)
+		args = append(args, "-cert", pki.ClientCertPath(), "-key", pki.ClientKeyPath())
+		c := startOpenSSLClient(t, receiver.tcpListener.Addr().String(), pki.CACertPath(),
+			"23 <1>1 - - - - - - hello\n", args...)
+		c.waitMetric(t, acc)
+		receiver.Stop()
+
+		require.Equal(t, "hello", strings.TrimSpace(acc.Metrics[0].Fields["message"].(string)), "%v", args)
+		require.Equal(t, "CN=client.localdomain", acc.Metrics[0].Tags["peer_identity"], "%v", args)
+	}
+}
+
+func TestDTLSOpenSSLECDSA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dtls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A self-signed ECDSA certificate of the server.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	receiver := &Syslog{
+		Address: "dtls://127.0.0.1:0",
+		Options: Options{
+			Separator: "_",
+		},
+		now: time.Now,
+	}
+	receiver.TLSCert = certFile
+	receiver.TLSKey = keyFile
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	c := startOpenSSLClient(t, receiver.tcpListener.Addr().String(), certFile,
+		"23 <1>1 - - - - - - hello\n", "-cipher", "ECDHE-ECDSA-AES128-GCM-SHA256")
+	c.waitMetric(t, acc)
+	require.Equal(t, "hello", strings.TrimSpace(acc.Metrics[0].Fields["message"].(string)))
+}
+
+func TestDTLSOpenSSLClientCertRequired(t *testing.T) {
+	receiver, acc := newDTLSReceiver(t, true)
+	defer receiver.Stop()
+
+	c := startOpenSSLClient(t, receiver.tcpListener.Addr().String(), pki.CACertPath(),
+		"23 <1>1 - - - - - - hello\n")
+	require.Error(t, c.waitExit(t))
+	acc.WaitError(1)
+	require.Contains(t, acc.FirstError().Error(), "server required client verification, but got none")
+	require.Equal(t, 0, len(acc.Metrics))
+}
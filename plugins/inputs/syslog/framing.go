@@ -0,0 +1,208 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/telegraf"
+)
+
+// Framings of the messages on stream sockets, as per RFC6587#section-3.4.
+const (
+	framingOctetCounting  = "octet-counting"
+	framingNonTransparent = "non-transparent"
+)
+
+// Handlings of the messages over max_message_size.
+const (
+	oversizedDrop     = "drop"
+	oversizedTruncate = "truncate"
+)
+
+// framer splits the frames of a connection as per its framing.  The frames
+// over maxSize are dropped or truncated as per oversized, or end the
+// connection when it is not set.
+type framer struct {
+	trailer   byte
+	maxSize   int
+	oversized string
+
+	// onOversized is called for each frame dropped or truncated.
+	onOversized func()
+
+	// skip is the number of bytes of an oversized frame left to skip, and
+	// skipToTrailer is set while skipping an oversized frame up to its
+	// trailer.
+	skip          int
+	skipToTrailer bool
+
+	// truncated is set when the last frame returned was truncated.
+	truncated bool
+
+	// partial tells whether an incomplete octet counted frame at the end of
+	// the connection is returned, like with the RFC5425 parser in best effort
+	// mode.  err is then the framing error.
+	partial bool
+	err     error
+}
+
+// splitNonTransparent is a bufio.SplitFunc returning the frames terminated
+// by the trailer, without the trailer.  With the LF trailer, a CR before it
+// is removed too.
+func (f *framer) splitNonTransparent(data []byte, atEOF bool) (int, []byte, error) {
+	f.truncated = false
+	if f.skipToTrailer {
+		if i := bytes.IndexByte(data, f.trailer); i >= 0 {
+			f.skipToTrailer = false
+			return i + 1, nil, nil
+		}
+		return len(data), nil, nil
+	}
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	i := bytes.IndexByte(data, f.trailer)
+	if (i < 0 && len(data) > f.maxSize) || i > f.maxSize {
+		if f.oversized == "" {
+			return 0, nil, bufio.ErrTooLong
+		}
+		advance := i + 1
+		if i < 0 {
+			advance = len(data)
+			f.skipToTrailer = !atEOF
+		}
+		return advance, f.truncate(data), nil
+	}
+
+	if i >= 0 {
+		frame := data[:i]
+		if f.trailer == '\n' {
+			frame = bytes.TrimSuffix(frame, []byte{'\r'})
+		}
+		return i + 1, frame, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// splitOctetCounting is a bufio.SplitFunc returning the frames preceded by
+// their length, as per RFC6587#section-3.4.1.
+func (f *framer) splitOctetCounting(data []byte, atEOF bool) (int, []byte, error) {
+	f.truncated = false
+	if f.skip > 0 {
+		n := f.skip
+		if n > len(data) {
+			n = len(data)
+		}
+		f.skip -= n
+		return n, nil, nil
+	}
+
+	i := bytes.IndexByte(data, ' ')
+	if i < 0 {
+		if len(data) > 10 {
+			return 0, nil, fmt.Errorf("expecting a message length")
+		}
+		if atEOF && len(data) > 0 {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	length, err := strconv.Atoi(string(data[:i]))
+	if err != nil || length < 1 || (length > f.maxSize && f.oversized == "") {
+		return 0, nil, fmt.Errorf("invalid message length %q", data[:i])
+	}
+
+	if length > f.maxSize {
+		if f.oversized == oversizedDrop {
+			f.skip = length
+			return i + 1, f.truncate(nil), nil
+		}
+		// The beginning of the frame is kept, the rest is skipped.
+		if len(data) < i+1+f.maxSize {
+			if atEOF {
+				return 0, nil, io.ErrUnexpectedEOF
+			}
+			return 0, nil, nil
+		}
+		f.skip = length - f.maxSize
+		return i + 1 + f.maxSize, f.truncate(data[i+1:]), nil
+	}
+
+	if len(data) < i+1+length {
+		if atEOF && f.partial {
+			f.err = io.ErrUnexpectedEOF
+			return len(data), data[i+1:], bufio.ErrFinalToken
+		}
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	return i + 1 + length, data[i+1 : i+1+length], nil
+}
+
+// truncate returns the first maxSize bytes of an oversized frame when they
+// are kept, or nil when the frame is dropped.
+func (f *framer) truncate(data []byte) []byte {
+	if f.onOversized != nil {
+		f.onOversized()
+	}
+	if f.oversized != oversizedTruncate {
+		return nil
+	}
+	f.truncated = true
+	return data[:f.maxSize]
+}
+
+// newFramer returns the framer of a connection.  Without max_message_size,
+// the frames over the maximum size of an IP packet end the connection.
+func (s *Syslog) newFramer() *framer {
+	f := &framer{
+		trailer: s.trailer,
+		maxSize: ipMaxPacketSize,
+		partial: s.BestEffort,
+	}
+	if s.MaxMessageSize > 0 {
+		f.maxSize = s.MaxMessageSize
+		f.oversized = s.oversized
+		f.onOversized = func() { s.messagesOversized.Incr(1) }
+	}
+	return f
+}
+
+// handleFrames parses the messages of a connection, delimited by the split
+// function of the framer.
+func (s *Syslog) handleFrames(r io.Reader, f *framer, split bufio.SplitFunc, source string, acc telegraf.Accumulator) {
+	p := rfc5424.NewParser()
+	scanner := bufio.NewScanner(r)
+	// Room for the length of the octet counted frames, and the trailer of
+	// the others.
+	scanner.Buffer(make([]byte, 0, 4096), f.maxSize+16)
+	scanner.Split(split)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			s.dispatch(p, scanner.Bytes(), source, f.truncated, acc, queueBlock)
+		}
+	}
+	err := scanner.Err()
+	if f.err != nil {
+		err = f.err
+	}
+	if err != nil {
+		// Network errors, such as the read timeout, end the connection
+		// like with octet counting.
+		if _, ok := err.(net.Error); !ok {
+			s.framesDropped.Incr(1)
+			acc.AddError(err)
+		}
+	}
+}
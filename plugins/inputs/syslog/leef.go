@@ -0,0 +1,155 @@
+package syslog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// leefPrefix is the prefix of the fields and tags of the LEEF events.
+const leefPrefix = "leef_"
+
+// leefIntegerKeys are the predefined attributes of the LEEF specification
+// with integer values, added as integer fields when their value is one.
+var leefIntegerKeys = map[string]bool{
+	"sev": true, "srcPort": true, "dstPort": true,
+	"srcPreNATPort": true, "srcPostNATPort": true,
+	"dstPreNATPort": true, "dstPostNATPort": true,
+	"srcBytes": true, "dstBytes": true, "totalBytes": true,
+	"srcPackets": true, "dstPackets": true, "totalPackets": true,
+	"vSrc": true,
+}
+
+// leefEvent is an event in the Log Event Extended Format of IBM QRadar.
+type leefEvent struct {
+	version        string
+	vendor         string
+	product        string
+	productVersion string
+	eventID        string
+	attributes     map[string]string
+}
+
+// parseLEEF parses the LEEF event of a message, if any:
+//
+//	LEEF:1.0|Vendor|Product|Version|EventID|Attributes
+//	LEEF:2.0|Vendor|Product|Version|EventID|Delimiter|Attributes
+//
+// The attributes are separated by tabs in LEEF 1.0, and by the delimiter
+// in LEEF 2.0, a character or its code in hexadecimal such as "0x09", tab
+// by default.  As for CEF, the event may be preceded by other text.  It
+// returns nil if the message has no valid LEEF header.
+func parseLEEF(message string) *leefEvent {
+	i := indexWord(message, "LEEF:")
+	if i < 0 {
+		return nil
+	}
+
+	parts := strings.SplitN(message[i+len("LEEF:"):], "|", 6)
+	if len(parts) < 6 {
+		return nil
+	}
+	event := &leefEvent{
+		version:        strings.TrimSpace(parts[0]),
+		vendor:         parts[1],
+		product:        parts[2],
+		productVersion: parts[3],
+		eventID:        parts[4],
+	}
+	attributes := parts[5]
+	delimiter := "\t"
+
+	switch event.version {
+	case "1.0":
+	case "2.0":
+		// The delimiter is optional, the attributes following the event ID
+		// without it.
+		if j := strings.IndexByte(attributes, '|'); j >= 0 {
+			if d, ok := parseLEEFDelimiter(attributes[:j]); ok {
+				delimiter = d
+				attributes = attributes[j+1:]
+			}
+		}
+	default:
+		return nil
+	}
+
+	event.attributes = parseLEEFAttributes(strings.TrimRight(attributes, "\r\n"), delimiter)
+	return event
+}
+
+// parseLEEFDelimiter parses the delimiter of the LEEF 2.0 header, a single
+// character, or its code in hexadecimal prefixed by "0x" or "x".  An empty
+// delimiter is the default one.
+func parseLEEFDelimiter(s string) (string, bool) {
+	switch {
+	case s == "":
+		return "\t", true
+	case len(s) == 1:
+		return s, true
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "x"):
+		code, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(s, "0"), "x"), 16, 8)
+		if err != nil || code == 0 {
+			return "", false
+		}
+		return string([]byte{byte(code)}), true
+	}
+	return "", false
+}
+
+// parseLEEFAttributes parses the key=value attributes separated by the
+// delimiter.  The values may contain equal signs, the key ending at the
+// first one.
+func parseLEEFAttributes(s, delimiter string) map[string]string {
+	attributes := map[string]string{}
+	for _, attribute := range strings.Split(s, delimiter) {
+		i := strings.IndexByte(attribute, '=')
+		if i < 1 {
+			continue
+		}
+		attributes[strings.TrimSpace(attribute[:i])] = attribute[i+1:]
+	}
+	return attributes
+}
+
+// addLEEF adds the header and the attributes of the LEEF event of the
+// message as fields, and as tags for the header fields identifying the
+// kind of the event and the attributes of leef_attributes_as_tags.
+func (s *Syslog) addLEEF(flds map[string]interface{}, ts map[string]string) {
+	message, ok := flds["message"].(string)
+	if !ok {
+		return
+	}
+	event := parseLEEF(message)
+	if event == nil {
+		return
+	}
+
+	ts[leefPrefix+"vendor"] = event.vendor
+	ts[leefPrefix+"product"] = event.product
+	ts[leefPrefix+"product_version"] = event.productVersion
+	ts[leefPrefix+"event_id"] = event.eventID
+	flds[leefPrefix+"version"] = event.version
+
+	for key, value := range event.attributes {
+		if s.leefAttributeAsTag(key) {
+			ts[leefPrefix+key] = value
+			continue
+		}
+		if leefIntegerKeys[key] {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				flds[leefPrefix+key] = n
+				continue
+			}
+		}
+		flds[leefPrefix+key] = value
+	}
+}
+
+func (s *Syslog) leefAttributeAsTag(key string) bool {
+	for _, k := range s.LEEFAsTags {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
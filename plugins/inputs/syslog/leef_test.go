@@ -0,0 +1,101 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLEEF(t *testing.T) {
+	tests := []struct {
+		message string
+		event   *leefEvent
+	}{
+		{
+			"LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.1\tdst=172.50.123.1\tsev=5\tmsg=a=b",
+			&leefEvent{
+				version:        "1.0",
+				vendor:         "Microsoft",
+				product:        "MSExchange",
+				productVersion: "4.0 SP1",
+				eventID:        "15345",
+				attributes:     map[string]string{"src": "192.0.2.1", "dst": "172.50.123.1", "sev": "5", "msg": "a=b"},
+			},
+		},
+		{
+			// The delimiter of LEEF 2.0, after the hostname.
+			"host1 LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=192.0.2.1^dst=172.50.123.1",
+			&leefEvent{
+				version:        "2.0",
+				vendor:         "Lancope",
+				product:        "StealthWatch",
+				productVersion: "1.0",
+				eventID:        "41",
+				attributes:     map[string]string{"src": "192.0.2.1", "dst": "172.50.123.1"},
+			},
+		},
+		{
+			"LEEF:2.0|Lancope|StealthWatch|1.0|41|0x7c|src=192.0.2.1|dst=172.50.123.1",
+			&leefEvent{
+				version:        "2.0",
+				vendor:         "Lancope",
+				product:        "StealthWatch",
+				productVersion: "1.0",
+				eventID:        "41",
+				attributes:     map[string]string{"src": "192.0.2.1", "dst": "172.50.123.1"},
+			},
+		},
+		{
+			// The delimiter is optional, tab by default.
+			"LEEF:2.0|Lancope|StealthWatch|1.0|41|src=192.0.2.1\tdst=172.50.123.1",
+			&leefEvent{
+				version:        "2.0",
+				vendor:         "Lancope",
+				product:        "StealthWatch",
+				productVersion: "1.0",
+				eventID:        "41",
+				attributes:     map[string]string{"src": "192.0.2.1", "dst": "172.50.123.1"},
+			},
+		},
+		{"LEEF:1.0|Microsoft|MSExchange|4.0|15345", nil},
+		{"LEEF:3.0|Microsoft|MSExchange|4.0|15345|src=192.0.2.1", nil},
+		{"hello LEEF", nil},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.event, parseLEEF(tt.message), tt.message)
+	}
+}
+
+func TestLEEF(t *testing.T) {
+	s := &Syslog{
+		Options: Options{
+			Separator:      "_",
+			SyslogStandard: standardAuto,
+			LEEF:           true,
+			LEEFAsTags:     []string{"cat"},
+		},
+		now: time.Now,
+	}
+	require.NoError(t, s.configure("udp"))
+	s.registerStats()
+
+	acc := &testutil.Accumulator{}
+	s.storeRFC3164([]byte("<134>Oct 11 22:14:15 LEEF:1.0|Microsoft|MSExchange|4.0|login|cat=auth\tsrcPort=4444\tusrName=root"), "", false, acc)
+	require.Len(t, acc.Metrics, 1)
+	m := acc.Metrics[0]
+	require.Equal(t, map[string]string{
+		"severity":             "info",
+		"facility":             "local0",
+		"standard":             "RFC3164",
+		"leef_vendor":          "Microsoft",
+		"leef_product":         "MSExchange",
+		"leef_product_version": "4.0",
+		"leef_event_id":        "login",
+		"leef_cat":             "auth",
+	}, m.Tags)
+	require.Equal(t, "1.0", m.Fields["leef_version"])
+	require.Equal(t, int64(4444), m.Fields["leef_srcPort"])
+	require.Equal(t, "root", m.Fields["leef_usrName"])
+}
@@ -0,0 +1,358 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/go-syslog/rfc5425"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/events"
+)
+
+// listen returns the listener of the stream sockets, and sets the TLS
+// configuration of their connections.  The DTLS associations are accepted
+// as connections.
+func (s *Syslog) listen(scheme string) (net.Listener, error) {
+	config, err := s.serverTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if s.isDTLS {
+		l, err := newDTLSListener(s.Address, s.IPv6Only, config)
+		if err != nil {
+			return nil, err
+		}
+		s.dtlsListener = l
+		return l, nil
+	}
+
+	network := scheme
+	if s.isRELP {
+		network = "tcp"
+	}
+	l, err := s.Listen(network, s.Address)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setSocketMode(); err != nil {
+		l.Close()
+		return nil, err
+	}
+	s.tlsConfig = config
+	return l, nil
+}
+
+// removeStaleSocket removes the socket file left at path, such as after a
+// crash.  Other files are not removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("'%s' exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
+
+func (s *Syslog) setSocketMode() error {
+	if !s.isUnix || s.SocketMode == "" {
+		return nil
+	}
+	return os.Chmod(s.Address, s.socketMode)
+}
+
+// watchOutputs pauses and resumes accepting connections as the outputs
+// become unavailable and available again.
+func (s *Syslog) watchOutputs() {
+	defer s.wg.Done()
+	for e := range s.outputEvents.C {
+		switch e.Type {
+		case events.OutputsUnavailable:
+			atomic.StoreInt32(&s.paused, 1)
+		case events.OutputsAvailable:
+			atomic.StoreInt32(&s.paused, 0)
+		}
+	}
+}
+
+// listenPacket parses the datagrams, one message each.  The datagrams are
+// read with a byte more than max_datagram_size, since the larger ones are
+// truncated by the system without an error.
+func (s *Syslog) listenPacket(conn net.PacketConn, acc telegraf.Accumulator) {
+	defer s.readers.Done()
+	size := ipMaxPacketSize
+	if s.MaxDatagramSize > 0 {
+		size = s.MaxDatagramSize
+	}
+	b := make([]byte, size+1)
+	p := rfc5424.NewParser()
+	for {
+		n, addr, err := conn.ReadFrom(b)
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			// The datagram sockets are idle rather than failed, the
+			// deadline is set again on the next datagram.
+			conn.SetReadDeadline(time.Time{})
+			continue
+		}
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				acc.AddError(err)
+				s.fail(err)
+			}
+			break
+		}
+
+		if s.ReadTimeout != nil && s.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+		}
+
+		if !s.allowSource(addr) {
+			continue
+		}
+
+		truncated := false
+		if n > size {
+			s.messagesOversized.Incr(1)
+			if s.oversized != oversizedTruncate {
+				continue
+			}
+			n, truncated = size, true
+		}
+		data, err := s.decoder.Bytes(b[:n])
+		if err != nil {
+			s.parseErrors.Incr(1)
+			acc.AddError(err)
+			continue
+		}
+		if s.MaxMessageSize > 0 && len(data) > s.MaxMessageSize {
+			if !truncated {
+				s.messagesOversized.Incr(1)
+			}
+			if s.oversized != oversizedTruncate {
+				continue
+			}
+			data, truncated = data[:s.MaxMessageSize], true
+		}
+		s.dispatch(p, data, s.source(addr), truncated, acc, queueDrop)
+	}
+}
+
+func (s *Syslog) listenStream(acc telegraf.Accumulator) {
+	defer s.readers.Done()
+
+	s.connections = map[net.Conn]struct{}{}
+
+	for {
+		conn, err := s.tcpListener.Accept()
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				acc.AddError(err)
+				s.fail(err)
+			}
+			break
+		}
+		if atomic.LoadInt32(&s.paused) == 1 {
+			s.connectionsRejected.Incr(1)
+			conn.Close()
+			continue
+		}
+		// The connections from a proxy are checked once the address of
+		// their client is known.
+		if !s.ProxyProtocol && !s.allowSource(conn.RemoteAddr()) {
+			conn.Close()
+			continue
+		}
+
+		var tcpConn, _ = conn.(*net.TCPConn)
+		if s.tlsConfig != nil && !s.ProxyProtocol {
+			conn = tls.Server(conn, s.tlsConfig)
+		}
+
+		s.connectionsMu.Lock()
+		if s.MaxConnections > 0 && len(s.connections) >= s.MaxConnections {
+			s.connectionsMu.Unlock()
+			s.connectionsRejected.Incr(1)
+			conn.Close()
+			continue
+		}
+		s.connections[conn] = struct{}{}
+		s.connectionsMu.Unlock()
+		s.connectionsAccepted.Incr(1)
+
+		if err := s.setKeepAlive(tcpConn); err != nil {
+			acc.AddError(fmt.Errorf("unable to configure keep alive (%s): %s", s.Address, err))
+		}
+
+		s.handlers.Add(1)
+		go s.handle(conn, acc)
+	}
+
+	if s.ShutdownTimeout != nil && s.ShutdownTimeout.Duration > 0 {
+		s.drain(s.ShutdownTimeout.Duration)
+	}
+	s.connectionsMu.Lock()
+	for c := range s.connections {
+		if s.isRELP {
+			// Tell the senders to send the messages not acknowledged yet
+			// to another server, or later.
+			c.SetWriteDeadline(time.Now().Add(time.Second))
+			writeRELP(c, 0, relpServerClose, "")
+		}
+		c.Close()
+	}
+	s.connectionsMu.Unlock()
+	s.handlers.Wait()
+}
+
+// drain waits for the connections to end, and their messages to be parsed,
+// for up to the timeout.
+func (s *Syslog) drain(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (s *Syslog) removeConnection(c net.Conn) {
+	s.connectionsMu.Lock()
+	delete(s.connections, c)
+	s.connectionsMu.Unlock()
+}
+
+// replaceConnection replaces a connection with the one reading its messages,
+// such as the connection wrapped to read the header of the proxy.
+func (s *Syslog) replaceConnection(old, c net.Conn) {
+	s.connectionsMu.Lock()
+	delete(s.connections, old)
+	s.connections[c] = struct{}{}
+	s.connectionsMu.Unlock()
+}
+
+func (s *Syslog) handle(conn net.Conn, acc telegraf.Accumulator) {
+	defer func() {
+		s.removeConnection(conn)
+		conn.Close()
+		s.handlers.Done()
+	}()
+
+	if s.dtlsListener != nil {
+		// The handshake precedes the read timeout of the messages.
+		c, identity, err := s.dtlsListener.handshake(conn)
+		if err != nil {
+			acc.AddError(fmt.Errorf("DTLS handshake with %s: %s", conn.RemoteAddr(), err))
+			return
+		}
+		s.replaceConnection(conn, c)
+		conn = c
+		if identity != "" {
+			acc = peerAccumulator{Accumulator: acc, identity: identity}
+		}
+	}
+	if s.ReadTimeout != nil && s.ReadTimeout.Duration > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+	}
+
+	if s.ProxyProtocol {
+		// The header precedes the TLS handshake.
+		pc, err := newProxyConn(conn)
+		if err != nil {
+			if _, ok := err.(net.Error); !ok {
+				acc.AddError(fmt.Errorf("PROXY protocol: %s", err))
+			}
+			return
+		}
+		if !s.allowSource(pc.RemoteAddr()) {
+			return
+		}
+		var c net.Conn = pc
+		if s.tlsConfig != nil {
+			c = tls.Server(c, s.tlsConfig)
+		}
+		s.replaceConnection(conn, c)
+		conn = c
+	}
+
+	source := s.source(conn.RemoteAddr())
+	if s.isRELP {
+		s.handleRELP(conn, source, acc)
+		return
+	}
+	// The messages are decoded before being split as per their framing.
+	r := s.decoder.Reader(conn)
+	nonTransparent := s.Framing == framingNonTransparent
+	if s.Framing == "" {
+		switch s.standard {
+		case standardRFC3164:
+			nonTransparent = true
+		case standardAuto:
+			// Octet counted frames start with the length of the message,
+			// non-transparent frames with the priority of the message.
+			br := bufio.NewReader(r)
+			first, err := br.Peek(1)
+			if err != nil {
+				return
+			}
+			r = br
+			nonTransparent = first[0] == '<'
+		}
+	}
+	f := s.newFramer()
+	if nonTransparent {
+		s.handleFrames(r, f, f.splitNonTransparent, source, acc)
+		return
+	}
+	// The RFC5425 parser only accepts RFC5424 messages, does not limit their
+	// size, does not return the raw messages it fails to parse in best
+	// effort mode, and parses the messages as it reads them.
+	if s.standard != standardRFC5424 || s.MaxMessageSize > 0 || s.BestEffort || s.queue != nil {
+		s.handleFrames(r, f, f.splitOctetCounting, source, acc)
+		return
+	}
+
+	p := rfc5425.NewParser(r)
+	p.ParseExecuting(func(r *rfc5425.Result) {
+		s.store(*r, source, acc)
+	})
+}
+
+func (s *Syslog) setKeepAlive(c *net.TCPConn) error {
+	if s.KeepAlivePeriod == nil {
+		return nil
+	}
+
+	if s.KeepAlivePeriod.Duration == 0 {
+		return c.SetKeepAlive(false)
+	}
+	if err := c.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return c.SetKeepAlivePeriod(s.KeepAlivePeriod.Duration)
+}
+
+type unixCloser struct {
+	path   string
+	closer io.Closer
+}
+
+func (uc unixCloser) Close() error {
+	err := uc.closer.Close()
+	os.Remove(uc.path) // ignore error
+	return err
+}
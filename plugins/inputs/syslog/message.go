@@ -0,0 +1,302 @@
+package syslog
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/go-syslog/rfc5425"
+	"github.com/influxdata/telegraf"
+)
+
+// keep tells whether a message of the priority passes the severity and
+// facility filters.  Messages without a priority are kept.
+func (s *Syslog) keep(priority *uint8) bool {
+	if s.priorities == nil || priority == nil || int(*priority) >= len(s.priorities) {
+		return true
+	}
+	if !s.priorities[*priority] {
+		s.messagesFiltered.Incr(1)
+		return false
+	}
+	return true
+}
+
+// parseMessage parses a message according to the syslog standard, and adds
+// it to the accumulator.  The truncated messages are parsed in best effort
+// mode, since they may end anywhere.  In best effort mode, the messages
+// which cannot be parsed at all are added with their raw content.  It
+// returns false if the message is dropped by the rate limits.
+func (s *Syslog) parseMessage(p *rfc5424.Parser, data []byte, source string, truncated bool, acc telegraf.Accumulator) bool {
+	if !s.allow(source) {
+		return false
+	}
+	if s.standard == standardRFC3164 || (s.standard == standardAuto && !isRFC5424(data)) {
+		s.storeRFC3164(data, source, truncated, acc)
+		return true
+	}
+
+	bestEffort := s.BestEffort || truncated
+	message, err := p.Parse(data, &bestEffort)
+	if message != nil {
+		s.messagesParsed.Incr(1)
+		if s.keep(message.Priority()) {
+			flds := fields(*message, s)
+			if truncated {
+				flds["truncated"] = true
+			}
+			ts := tags(*message, s)
+			s.addStandard(ts, standardRFC5424)
+			s.addFields(acc, flds, ts, source)
+		}
+	}
+	if err != nil && message == nil && s.BestEffort && len(data) > 0 {
+		s.addFields(acc, map[string]interface{}{"raw": string(data)},
+			map[string]string{"parse_error": err.Error()}, source)
+	}
+	if err != nil && !(truncated && message != nil) {
+		s.parseErrors.Incr(1)
+		acc.AddError(err)
+	}
+	return true
+}
+
+func (s *Syslog) store(res rfc5425.Result, source string, acc telegraf.Accumulator) {
+	if res.Message != nil && !s.allow(source) {
+		return
+	}
+	if res.Error != nil {
+		s.framesDropped.Incr(1)
+		acc.AddError(res.Error)
+	}
+	if res.MessageError != nil {
+		s.parseErrors.Incr(1)
+		acc.AddError(res.MessageError)
+	}
+	if res.Message != nil {
+		s.messagesParsed.Incr(1)
+		if s.keep(res.Message.Priority()) {
+			msg := *res.Message
+			s.addFields(acc, fields(msg, s), tags(msg, s), source)
+		}
+	}
+}
+
+func (s *Syslog) storeRFC3164(data []byte, source string, truncated bool, acc telegraf.Accumulator) {
+	now := s.now()
+	if s.location != nil {
+		now = now.In(s.location)
+	}
+	msg, err := parseRFC3164(data, now, s.BestEffort || truncated)
+	if err != nil {
+		s.parseErrors.Incr(1)
+		acc.AddError(err)
+		return
+	}
+	s.messagesParsed.Incr(1)
+	if !s.keep(&msg.priority) {
+		return
+	}
+	flds := fields3164(msg)
+	if truncated {
+		flds["truncated"] = true
+	}
+	ts := tags3164(msg)
+	s.addStandard(ts, standardRFC3164)
+	s.addFields(acc, flds, ts, source)
+}
+
+// addStandard adds the standard tag with the standard detected, as spelled
+// in syslog_standard, such as "RFC3164", when detecting the standard of
+// each message.
+func (s *Syslog) addStandard(ts map[string]string, standard string) {
+	if s.standard == standardAuto {
+		ts["standard"] = strings.ToUpper(standard)
+	}
+}
+
+// source returns the source tag of the messages received from the address:
+// the IP address of the sender, with its port if source_port is set.  The
+// senders on unix domain sockets have no source.
+func (s *Syslog) source(addr net.Addr) string {
+	ip, port := addrIP(addr)
+	if ip == nil {
+		return ""
+	}
+	if s.SourcePort && port != 0 {
+		return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	}
+	return ip.String()
+}
+
+// addFields adds a message to the accumulator, with the extra tags which
+// are not tags of the message and the source tag.
+func (s *Syslog) addFields(acc telegraf.Accumulator, flds map[string]interface{}, ts map[string]string, source string) {
+	if name, ok := s.severityNames[ts["severity"]]; ok {
+		ts["severity"] = name
+	}
+	if name, ok := s.facilityNames[ts["facility"]]; ok {
+		ts["facility"] = name
+	}
+	if s.CEF {
+		s.addCEF(flds, ts)
+	}
+	if s.LEEF {
+		s.addLEEF(flds, ts)
+	}
+	for k, v := range s.ExtraTags {
+		if _, ok := ts[k]; !ok {
+			ts[k] = v
+		}
+	}
+	if source != "" {
+		ts["source"] = source
+	}
+	if s.server != "" {
+		ts["server"] = s.server
+	}
+
+	measurement := s.Measurement
+	if measurement == "" {
+		measurement = defaultMeasurement
+	}
+	t := s.time()
+	if s.messageTime {
+		flds["received_at"] = t.UnixNano()
+		if timestamp, ok := flds["timestamp"].(int64); ok {
+			t = time.Unix(0, timestamp)
+		}
+	}
+	acc.AddFields(measurement, flds, ts, t)
+}
+
+func tags(msg rfc5424.SyslogMessage, s *Syslog) map[string]string {
+	ts := map[string]string{}
+
+	// Not checking assuming a minimally valid message
+	ts["severity"] = *msg.SeverityShortLevel()
+	ts["facility"] = *msg.FacilityLevel()
+
+	if msg.Hostname() != nil {
+		ts["hostname"] = *msg.Hostname()
+	}
+
+	if msg.Appname() != nil {
+		ts["appname"] = *msg.Appname()
+	}
+
+	if msg.StructuredData() != nil {
+		for sdid, sdparams := range *msg.StructuredData() {
+			if !s.sdidAsTag(sdid) {
+				continue
+			}
+			if len(sdparams) == 0 {
+				ts[s.SDParamPrefix+sdid] = "true"
+				continue
+			}
+			for name, value := range sdparams {
+				ts[s.sdParamName(sdid, name)] = value
+			}
+		}
+	}
+
+	return ts
+}
+
+func fields(msg rfc5424.SyslogMessage, s *Syslog) map[string]interface{} {
+	// Not checking assuming a minimally valid message
+	flds := map[string]interface{}{
+		"version": msg.Version(),
+	}
+	flds["severity_code"] = int(*msg.Severity())
+	flds["facility_code"] = int(*msg.Facility())
+
+	if msg.Timestamp() != nil {
+		flds["timestamp"] = (*msg.Timestamp()).UnixNano()
+	}
+
+	if msg.ProcID() != nil {
+		flds["procid"] = *msg.ProcID()
+	}
+
+	if msg.MsgID() != nil {
+		flds["msgid"] = *msg.MsgID()
+	}
+
+	if msg.Message() != nil {
+		flds["message"] = *msg.Message()
+	}
+
+	if msg.StructuredData() != nil {
+		if s.sdJSON {
+			s.addStructuredDataJSON(*msg.StructuredData(), flds)
+			return flds
+		}
+		for sdid, sdparams := range *msg.StructuredData() {
+			if s.sdidAsTag(sdid) {
+				continue
+			}
+			if len(sdparams) == 0 {
+				// When SD-ID does not have params we indicate its presence with a bool
+				flds[s.SDParamPrefix+sdid] = true
+				continue
+			}
+			for name, value := range sdparams {
+				flds[s.sdParamName(sdid, name)] = value
+			}
+		}
+	}
+
+	return flds
+}
+
+func (s *Syslog) sdParamName(sdid, name string) string {
+	return s.SDParamPrefix + sdid + s.Separator + name
+}
+
+// addStructuredDataJSON adds the structured data field with the SD-ELEMENTs
+// but those of sdids_as_tags, if any.
+func (s *Syslog) addStructuredDataJSON(sd map[string]map[string]string, flds map[string]interface{}) {
+	elements := make(map[string]map[string]string, len(sd))
+	for sdid, sdparams := range sd {
+		if s.sdidAsTag(sdid) {
+			continue
+		}
+		if sdparams == nil {
+			sdparams = map[string]string{}
+		}
+		elements[sdid] = sdparams
+	}
+	if len(elements) == 0 {
+		return
+	}
+	// The keys are sorted, so the same structured data is the same string.
+	b, err := json.Marshal(elements)
+	if err != nil {
+		return
+	}
+	flds[sdJSONField] = string(b)
+}
+
+func (s *Syslog) sdidAsTag(sdid string) bool {
+	for _, id := range s.SDIDsAsTags {
+		if id == sdid {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Syslog) time() time.Time {
+	s.timeMu.Lock()
+	defer s.timeMu.Unlock()
+	t := s.now()
+	if t == s.lastTime {
+		t = t.Add(time.Nanosecond)
+	}
+	s.lastTime = t
+	return t
+}
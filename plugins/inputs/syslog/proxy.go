@@ -0,0 +1,159 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// proxyV2Signature starts the binary header of the version 2 of the PROXY
+// protocol.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyV1MaxLen is the maximum length of the text header of the version 1 of
+// the PROXY protocol, with its CRLF.
+const proxyV1MaxLen = 107
+
+// proxyConn is a connection from a proxy, reading the messages after the
+// PROXY protocol header, and whose remote address is the one of the client
+// of the proxy.
+type proxyConn struct {
+	net.Conn
+	r          *bufio.Reader
+	remoteAddr net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// newProxyConn reads the PROXY protocol header of a connection, of version 1
+// or 2, as per https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt.
+// The remote address remains the one of the proxy for the local connections
+// of the proxy, such as its health checks, and the unknown protocols.
+func newProxyConn(conn net.Conn) (*proxyConn, error) {
+	c := &proxyConn{
+		Conn:       conn,
+		r:          bufio.NewReader(conn),
+		remoteAddr: conn.RemoteAddr(),
+	}
+
+	first, err := c.r.Peek(1)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	var addr net.Addr
+	switch first[0] {
+	case 'P':
+		addr, err = readProxyV1(c.r)
+	case proxyV2Signature[0]:
+		addr, err = readProxyV2(c.r)
+	default:
+		return nil, fmt.Errorf("missing the PROXY protocol header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if addr != nil {
+		c.remoteAddr = addr
+	}
+	return c, nil
+}
+
+// readProxyV1 reads a text header, such as
+// "PROXY TCP4 192.0.2.1 192.0.2.2 56324 514\r\n".
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == proxyV1MaxLen {
+			return nil, fmt.Errorf("PROXY protocol header over %d bytes", proxyV1MaxLen)
+		}
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		line = append(line, c)
+	}
+
+	parts := strings.Split(string(line[:len(line)-2]), " ")
+	if parts[0] != "PROXY" || len(parts) < 2 {
+		return nil, fmt.Errorf("invalid PROXY protocol header %q", line)
+	}
+	switch parts[1] {
+	case "UNKNOWN":
+		return nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("unknown PROXY protocol %q", parts[1])
+	}
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid PROXY protocol header %q", line)
+	}
+	ip := net.ParseIP(parts[2])
+	port, err := strconv.ParseUint(parts[4], 10, 16)
+	if ip == nil || err != nil || (ip.To4() != nil) != (parts[1] == "TCP4") {
+		return nil, fmt.Errorf("invalid PROXY protocol source %q", parts[2]+" "+parts[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2 reads a binary header, made of the signature, the version and
+// command, the address family and protocol, the length of the addresses and
+// the addresses.  The TLVs following the addresses are skipped.
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(proxyV2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if !bytes.Equal(header[:len(proxyV2Signature)], proxyV2Signature) {
+		return nil, fmt.Errorf("invalid PROXY protocol signature")
+	}
+	versionCommand, family := header[12], header[13]
+	if versionCommand>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", versionCommand>>4)
+	}
+	data := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	switch versionCommand & 0xf {
+	case 0x0:
+		// LOCAL, the connection of the proxy itself.
+		return nil, nil
+	case 0x1:
+		// PROXY
+	default:
+		return nil, fmt.Errorf("unknown PROXY protocol command %d", versionCommand&0xf)
+	}
+
+	var ipLen int
+	switch family >> 4 {
+	case 0x1:
+		ipLen = net.IPv4len
+	case 0x2:
+		ipLen = net.IPv6len
+	default:
+		// AF_UNSPEC and AF_UNIX have no IP address.
+		return nil, nil
+	}
+	if len(data) < 2*ipLen+4 {
+		return nil, fmt.Errorf("PROXY protocol addresses of %d bytes, expecting %d", len(data), 2*ipLen+4)
+	}
+	ip := make(net.IP, ipLen)
+	copy(ip, data[:ipLen])
+	port := binary.BigEndian.Uint16(data[2*ipLen:])
+	if family&0xf == 0x2 {
+		return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
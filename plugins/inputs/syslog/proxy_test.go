@@ -0,0 +1,171 @@
+package syslog
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProxyConn(t *testing.T) {
+	v2 := func(command, family byte, addresses ...byte) string {
+		header := append([]byte{}, proxyV2Signature...)
+		header = append(header, 0x20|command, family, 0, byte(len(addresses)))
+		return string(append(header, addresses...))
+	}
+	ipv4 := []byte{192, 0, 2, 1, 192, 0, 2, 2, 0xdc, 0x04, 0x02, 0x02}
+	ipv6 := append(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")...)
+	ipv6 = append(ipv6, 0xdc, 0x04, 0x02, 0x02)
+
+	tests := []struct {
+		name string
+		data string
+		addr string
+		err  string
+	}{
+		{
+			name: "v1 tcp4",
+			data: "PROXY TCP4 192.0.2.1 192.0.2.2 56324 514\r\n",
+			addr: "192.0.2.1:56324",
+		},
+		{
+			name: "v1 tcp6",
+			data: "PROXY TCP6 2001:db8::1 2001:db8::2 56324 514\r\n",
+			addr: "[2001:db8::1]:56324",
+		},
+		{
+			name: "v1 unknown",
+			data: "PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n",
+			addr: "pipe",
+		},
+		{
+			name: "v1 mismatched family",
+			data: "PROXY TCP6 192.0.2.1 192.0.2.2 56324 514\r\n",
+			err:  `invalid PROXY protocol source "192.0.2.1 56324"`,
+		},
+		{
+			name: "v1 protocol",
+			data: "PROXY UDP4 192.0.2.1 192.0.2.2 56324 514\r\n",
+			err:  `unknown PROXY protocol "UDP4"`,
+		},
+		{
+			name: "v1 fields",
+			data: "PROXY TCP4 192.0.2.1\r\n",
+			err:  `invalid PROXY protocol header "PROXY TCP4 192.0.2.1\r\n"`,
+		},
+		{
+			name: "v1 too long",
+			data: "PROXY TCP4 " + string(make([]byte, 100)) + "\r\n",
+			err:  "PROXY protocol header over 107 bytes",
+		},
+		{
+			name: "v2 ipv4",
+			data: v2(0x1, 0x11, ipv4...),
+			addr: "192.0.2.1:56324",
+		},
+		{
+			name: "v2 ipv6 with TLVs",
+			data: v2(0x1, 0x21, append(ipv6, 0x04, 0x00, 0x01, 0x00)...),
+			addr: "[2001:db8::1]:56324",
+		},
+		{
+			name: "v2 local",
+			data: v2(0x0, 0x00),
+			addr: "pipe",
+		},
+		{
+			name: "v2 short addresses",
+			data: v2(0x1, 0x11, ipv4[:8]...),
+			err:  "PROXY protocol addresses of 8 bytes, expecting 12",
+		},
+		{
+			name: "v2 command",
+			data: v2(0x2, 0x11, ipv4...),
+			err:  "unknown PROXY protocol command 2",
+		},
+		{
+			name: "missing header",
+			data: "<1>1 - - - - - -",
+			err:  "missing the PROXY protocol header",
+		},
+		{
+			name: "unexpected EOF",
+			data: "PROXY TCP4 192.0.2.1",
+			err:  "unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				client.Write([]byte(tt.data + "message"))
+				client.Close()
+			}()
+
+			c, err := newProxyConn(server)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.addr, c.RemoteAddr().String())
+			data, err := ioutil.ReadAll(c)
+			require.NoError(t, err)
+			require.Equal(t, "message", string(data))
+		})
+	}
+}
+
+func TestProxyProtocol(t *testing.T) {
+	receiver := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			ProxyProtocol: true,
+			SourcePort:    true,
+			ReadTimeout:   &internal.Duration{Duration: time.Second},
+			Separator:     "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("PROXY TCP4 192.0.2.1 192.0.2.2 56324 514\r\n16 <1>1 - - - - - -"))
+	require.NoError(t, err)
+	acc.Wait(1)
+	require.Equal(t, "192.0.2.1:56324", acc.Metrics[0].Tags["source"])
+
+	// The connection is replaced by the one with the address of the client
+	// of the proxy.
+	receiver.connectionsMu.Lock()
+	var addr string
+	for c := range receiver.connections {
+		addr = c.RemoteAddr().String()
+	}
+	require.Len(t, receiver.connections, 1)
+	receiver.connectionsMu.Unlock()
+	require.Equal(t, "192.0.2.1:56324", addr)
+
+	// The connections without header are closed.
+	conn2, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+	_, err = conn2.Write([]byte("16 <1>1 - - - - - -"))
+	require.NoError(t, err)
+	acc.WaitError(1)
+	require.EqualError(t, acc.FirstError(), "PROXY protocol: missing the PROXY protocol header")
+	conn2.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn2.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+}
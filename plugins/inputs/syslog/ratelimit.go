@@ -0,0 +1,112 @@
+package syslog
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// peerIdleTime is the time after which the bucket of an idle peer is
+// forgotten.  The bucket is full again by then, so forgetting it changes
+// nothing but the memory used by the many peers of a UDP listener.
+const peerIdleTime = time.Minute
+
+// tokenBucket allows rate events per second on average, with bursts of up
+// to one second of events.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate)}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.rate, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimiter limits the messages of all the peers together and of each
+// peer, identified by its IP address.
+type rateLimiter struct {
+	mu        sync.Mutex
+	global    *tokenBucket
+	perPeer   int
+	peers     map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+// newRateLimiter returns a rate limiter for the max_messages_per_second and
+// max_messages_per_second_per_peer options, or nil if both are unlimited.
+func newRateLimiter(global, perPeer int) *rateLimiter {
+	if global <= 0 && perPeer <= 0 {
+		return nil
+	}
+	r := &rateLimiter{
+		perPeer: perPeer,
+		peers:   make(map[string]*tokenBucket),
+	}
+	if global > 0 {
+		r.global = newTokenBucket(global)
+	}
+	return r
+}
+
+// allow tells whether a message of the peer is within the limits.  The
+// messages over the limit of their peer are not counted against the global
+// limit.
+func (r *rateLimiter) allow(peer string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.perPeer > 0 {
+		if now.Sub(r.lastPrune) > peerIdleTime {
+			r.prune(now)
+		}
+		b, ok := r.peers[peer]
+		if !ok {
+			b = newTokenBucket(r.perPeer)
+			r.peers[peer] = b
+		}
+		if !b.allow(now) {
+			return false
+		}
+	}
+	return r.global == nil || r.global.allow(now)
+}
+
+// prune forgets the peers idle for longer than peerIdleTime.
+func (r *rateLimiter) prune(now time.Time) {
+	for peer, b := range r.peers {
+		if now.Sub(b.last) > peerIdleTime {
+			delete(r.peers, peer)
+		}
+	}
+	r.lastPrune = now
+}
+
+// allow tells whether a message from the source is within the rate limits.
+func (s *Syslog) allow(source string) bool {
+	if s.limiter == nil {
+		return true
+	}
+	// The limit applies to the IP address whatever the port.
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+	if !s.limiter.allow(source, time.Now()) {
+		s.messagesRateLimited.Incr(1)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	require.Nil(t, newRateLimiter(0, 0))
+
+	now := time.Unix(1528300000, 0)
+	r := newRateLimiter(5, 2)
+
+	// The burst of each peer is its limit.
+	require.True(t, r.allow("192.0.2.1", now))
+	require.True(t, r.allow("192.0.2.1", now))
+	require.False(t, r.allow("192.0.2.1", now))
+
+	// The global limit is shared by the peers, the messages dropped by the
+	// limit of their peer not counting.
+	require.True(t, r.allow("192.0.2.2", now))
+	require.True(t, r.allow("192.0.2.2", now))
+	require.True(t, r.allow("192.0.2.3", now))
+	require.False(t, r.allow("192.0.2.3", now))
+	require.False(t, r.allow("192.0.2.4", now))
+
+	// The buckets are refilled over time.
+	now = now.Add(500 * time.Millisecond)
+	require.True(t, r.allow("192.0.2.1", now))
+	require.False(t, r.allow("192.0.2.1", now))
+
+	// The idle peers are forgotten.
+	now = now.Add(2 * peerIdleTime)
+	require.True(t, r.allow("192.0.2.1", now))
+	require.Len(t, r.peers, 1)
+}
+
+func TestMaxMessagesPerSecondPerPeer(t *testing.T) {
+	s := &Syslog{
+		Options: Options{
+			Separator:      "_",
+			SourcePort:     true,
+			MaxRatePerPeer: 1,
+		},
+		now: time.Now,
+	}
+	require.NoError(t, s.configure("udp"))
+	s.registerStats()
+	// The stats are shared by the listeners of the same address.
+	limited := s.messagesRateLimited.Get()
+
+	acc := &testutil.Accumulator{}
+	p := rfc5424.NewParser()
+	// Different ports of the same host are the same peer.
+	s.parseMessage(p, []byte("<1>1 - - - - - - A"), "192.0.2.1:514", false, acc)
+	s.parseMessage(p, []byte("<1>1 - - - - - - B"), "192.0.2.1:515", false, acc)
+	s.parseMessage(p, []byte("<1>1 - - - - - - C"), "192.0.2.2:514", false, acc)
+
+	require.Len(t, acc.Metrics, 2)
+	require.Equal(t, "A", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "C", acc.Metrics[1].Fields["message"])
+	require.Equal(t, limited+1, s.messagesRateLimited.Get())
+}
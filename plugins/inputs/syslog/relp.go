@@ -0,0 +1,202 @@
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/telegraf"
+)
+
+// Commands of RELP, the Reliable Event Logging Protocol of rsyslog.
+const (
+	relpOpen        = "open"
+	relpSyslog      = "syslog"
+	relpClose       = "close"
+	relpResponse    = "rsp"
+	relpServerClose = "serverclose"
+)
+
+// relpOffers are the offers of the listener in response to the open command.
+const relpOffers = "relp_version=0\nrelp_software=telegraf\ncommands=" + relpSyslog
+
+// relpNotAccepted is the response to the messages dropped, which the senders
+// send again.
+const relpNotAccepted = "500 message not accepted, try again later"
+
+// relpFrame is a RELP frame, "TXNR SP COMMAND SP DATALEN [SP DATA] LF".
+type relpFrame struct {
+	txnr    int
+	command string
+	data    []byte
+}
+
+// readRELP reads a RELP frame.  The data over maxSize is dropped or
+// truncated as per oversized, or is an error when it is not set.  io.EOF is
+// only returned when the connection ends between frames.
+func (f *framer) readRELP(r *bufio.Reader) (relpFrame, error) {
+	f.truncated = false
+	var frame relpFrame
+
+	txnr, _, err := readRELPToken(r, 9)
+	if err != nil {
+		if err == io.EOF && txnr == "" {
+			return frame, io.EOF
+		}
+		return frame, unexpectedEOF(err)
+	}
+	frame.txnr, err = strconv.Atoi(txnr)
+	if err != nil || frame.txnr < 0 {
+		return frame, fmt.Errorf("invalid RELP transaction number %q", txnr)
+	}
+	frame.command, _, err = readRELPToken(r, 32)
+	if err != nil {
+		return frame, unexpectedEOF(err)
+	}
+	datalen, delim, err := readRELPToken(r, 9)
+	if err != nil {
+		return frame, unexpectedEOF(err)
+	}
+	length, err := strconv.Atoi(datalen)
+	if err != nil || length < 0 || (length > 0 && delim != ' ') {
+		return frame, fmt.Errorf("invalid RELP data length %q", datalen)
+	}
+	if length > f.maxSize && f.oversized == "" {
+		return frame, fmt.Errorf("invalid message length %q", datalen)
+	}
+
+	if length > 0 {
+		n := length
+		if n > f.maxSize {
+			n = f.maxSize
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return frame, unexpectedEOF(err)
+		}
+		frame.data = data
+		if length > f.maxSize {
+			frame.data = f.truncate(data)
+			if _, err := io.CopyN(ioutil.Discard, r, int64(length-n)); err != nil {
+				return frame, unexpectedEOF(err)
+			}
+		}
+	}
+
+	// The trailer follows the data, or the data length without data.
+	if length > 0 || delim == ' ' {
+		c, err := r.ReadByte()
+		if err != nil {
+			return frame, unexpectedEOF(err)
+		}
+		if c != '\n' {
+			return frame, fmt.Errorf("expecting the RELP trailer after %d bytes of data", length)
+		}
+	}
+	return frame, nil
+}
+
+// readRELPToken reads a token of the header of a RELP frame, terminated by a
+// space, or by the trailer for the data length without data.
+func readRELPToken(r *bufio.Reader, maxLen int) (string, byte, error) {
+	var token []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return string(token), 0, err
+		}
+		if c == ' ' || c == '\n' {
+			return string(token), c, nil
+		}
+		if len(token) == maxLen {
+			return "", 0, fmt.Errorf("RELP header over %d bytes: %q", maxLen, token)
+		}
+		token = append(token, c)
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// writeRELP writes a RELP frame.
+func writeRELP(w io.Writer, txnr int, command string, data string) error {
+	frame := strconv.Itoa(txnr) + " " + command + " " + strconv.Itoa(len(data))
+	if data != "" {
+		frame += " " + data
+	}
+	_, err := io.WriteString(w, frame+"\n")
+	return err
+}
+
+// handleRELP handles a RELP session.  Each message is acknowledged once it
+// is parsed and added to the accumulator, so that the senders send the
+// messages which were not acknowledged again when the session ends.  The
+// read timeout applies to each frame rather than to the whole session.
+func (s *Syslog) handleRELP(conn net.Conn, source string, acc telegraf.Accumulator) {
+	p := rfc5424.NewParser()
+	f := s.newFramer()
+	r := bufio.NewReader(conn)
+	open := false
+	for {
+		if s.ReadTimeout != nil && s.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+		}
+		frame, err := f.readRELP(r)
+		if err != nil {
+			if _, ok := err.(net.Error); !ok && err != io.EOF {
+				s.framesDropped.Incr(1)
+				acc.AddError(err)
+			}
+			return
+		}
+
+		rsp := "200 OK"
+		switch {
+		case frame.command == relpOpen:
+			open = true
+			rsp += "\n" + relpOffers
+		case !open:
+			writeRELP(conn, frame.txnr, relpResponse, "500 expecting the open command")
+			return
+		case frame.command == relpClose:
+			writeRELP(conn, frame.txnr, relpResponse, "")
+			return
+		case frame.command == relpSyslog:
+			if !s.parseRELPMessage(p, frame.data, source, f.truncated, acc) {
+				// The sender sends the message again later.
+				rsp = relpNotAccepted
+			}
+		default:
+			rsp = "500 unsupported command " + frame.command
+		}
+		if err := writeRELP(conn, frame.txnr, relpResponse, rsp); err != nil {
+			return
+		}
+	}
+}
+
+// parseRELPMessage parses the message of a syslog command, and returns
+// false if it is dropped, by the rate limits or because the listener stops.
+// The messages which cannot be decoded or parsed are acknowledged, since they
+// would fail again.
+func (s *Syslog) parseRELPMessage(p *rfc5424.Parser, data []byte, source string, truncated bool, acc telegraf.Accumulator) bool {
+	if len(data) == 0 {
+		return true
+	}
+	data, err := s.decoder.Bytes(data)
+	if err != nil {
+		s.parseErrors.Incr(1)
+		acc.AddError(err)
+		return true
+	}
+	return s.dispatch(p, data, source, truncated, acc, queueWait)
+}
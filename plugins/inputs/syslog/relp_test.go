@@ -0,0 +1,216 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRELP(t *testing.T) {
+	tests := []struct {
+		name      string
+		oversized string
+		data      string
+		frames    []relpFrame
+		truncated []bool
+		err       string
+	}{
+		{
+			name: "frames",
+			data: "1 open 5 a=b\nc\n2 syslog 6 <1>1 -\n3 close 0\n",
+			frames: []relpFrame{
+				{1, "open", []byte("a=b\nc")},
+				{2, "syslog", []byte("<1>1 -")},
+				{3, "close", nil},
+			},
+			truncated: []bool{false, false, false},
+		},
+		{
+			name:      "empty data with space",
+			data:      "4 close 0 \n",
+			frames:    []relpFrame{{4, "close", nil}},
+			truncated: []bool{false},
+		},
+		{
+			name:      "truncate",
+			oversized: oversizedTruncate,
+			data:      "1 syslog 10 0123456789\n2 syslog 3 abc\n",
+			frames:    []relpFrame{{1, "syslog", []byte("01234567")}, {2, "syslog", []byte("abc")}},
+			truncated: []bool{true, false},
+		},
+		{
+			name:      "drop",
+			oversized: oversizedDrop,
+			data:      "1 syslog 10 0123456789\n2 syslog 3 abc\n",
+			frames:    []relpFrame{{1, "syslog", nil}, {2, "syslog", []byte("abc")}},
+			truncated: []bool{false, false},
+		},
+		{
+			name: "oversized",
+			data: "1 syslog 10 0123456789\n",
+			err:  `invalid message length "10"`,
+		},
+		{
+			name: "transaction number",
+			data: "x syslog 1 a\n",
+			err:  `invalid RELP transaction number "x"`,
+		},
+		{
+			name: "data length",
+			data: "1 syslog 2\n",
+			err:  `invalid RELP data length "2"`,
+		},
+		{
+			name: "trailer",
+			data: "1 syslog 1 ab\n",
+			err:  "expecting the RELP trailer after 1 bytes of data",
+		},
+		{
+			name: "header",
+			data: "1 " + strings.Repeat("x", 40) + " 0\n",
+			err:  `RELP header over 32 bytes: "` + strings.Repeat("x", 32) + `"`,
+		},
+		{
+			name:   "unexpected EOF",
+			data:   "1 close 0\n2 syslog 6 <1>1",
+			frames: []relpFrame{{1, "close", nil}},
+			err:    io.ErrUnexpectedEOF.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &framer{maxSize: 8, oversized: tt.oversized}
+			r := bufio.NewReader(strings.NewReader(tt.data))
+			var frames []relpFrame
+			var truncated []bool
+			var err error
+			for {
+				var frame relpFrame
+				frame, err = f.readRELP(r)
+				if err != nil {
+					break
+				}
+				frames = append(frames, frame)
+				truncated = append(truncated, f.truncated)
+			}
+			if tt.err == "" {
+				require.Equal(t, io.EOF, err)
+				require.Equal(t, tt.truncated, truncated)
+			} else {
+				require.EqualError(t, err, tt.err)
+			}
+			require.Equal(t, tt.frames, frames)
+		})
+	}
+}
+
+// relpSession connects to the RELP listener of the receiver, and returns the
+// reader of the responses.
+func relpSession(t *testing.T, receiver *Syslog) (net.Conn, func() relpFrame) {
+	conn, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+	require.NoError(t, err)
+
+	f := &framer{maxSize: ipMaxPacketSize}
+	r := bufio.NewReader(conn)
+	return conn, func() relpFrame {
+		frame, err := f.readRELP(r)
+		require.NoError(t, err)
+		return frame
+	}
+}
+
+func TestRELP(t *testing.T) {
+	receiver := newRFC3164Receiver("relp://127.0.0.1:0", "auto")
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, response := relpSession(t, receiver)
+	defer conn.Close()
+
+	require.NoError(t, writeRELP(conn, 1, relpOpen, "relp_version=0\nrelp_software=librelp\ncommands=syslog"))
+	require.Equal(t, relpFrame{1, relpResponse, []byte("200 OK\n" + relpOffers)}, response())
+
+	require.NoError(t, writeRELP(conn, 2, relpSyslog, "<1>1 - host01 - - - - first"))
+	require.Equal(t, relpFrame{2, relpResponse, []byte("200 OK")}, response())
+	// The messages are added when they are acknowledged.
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "first", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "host01", acc.Metrics[0].Tags["hostname"])
+	require.Equal(t, "127.0.0.1", acc.Metrics[0].Tags["source"])
+
+	require.NoError(t, writeRELP(conn, 3, relpSyslog, "<13>Jun 15 11:59:00 host02 app: second"))
+	require.Equal(t, relpFrame{3, relpResponse, []byte("200 OK")}, response())
+	require.Len(t, acc.Metrics, 2)
+	require.Equal(t, "second", acc.Metrics[1].Fields["message"])
+
+	require.NoError(t, writeRELP(conn, 4, "starttls", ""))
+	require.Equal(t, relpFrame{4, relpResponse, []byte("500 unsupported command starttls")}, response())
+
+	require.NoError(t, writeRELP(conn, 5, relpClose, ""))
+	require.Equal(t, relpFrame{5, relpResponse, nil}, response())
+	_, err := conn.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+	require.Empty(t, acc.Errors)
+}
+
+func TestRELPRateLimited(t *testing.T) {
+	for _, workers := range []int{0, 2} {
+		receiver := newRFC3164Receiver("relp://127.0.0.1:0", "RFC5424")
+		receiver.MaxRatePerPeer = 1
+		receiver.ParseWorkers = workers
+		acc := &testutil.Accumulator{}
+		require.NoError(t, receiver.Start(acc))
+
+		conn, response := relpSession(t, receiver)
+		require.NoError(t, writeRELP(conn, 1, relpOpen, "commands=syslog"))
+		require.Equal(t, 1, response().txnr)
+
+		// The messages dropped are not acknowledged.
+		require.NoError(t, writeRELP(conn, 2, relpSyslog, "<1>1 - host01 - - - - first"))
+		require.Equal(t, relpFrame{2, relpResponse, []byte("200 OK")}, response())
+		require.NoError(t, writeRELP(conn, 3, relpSyslog, "<1>1 - host01 - - - - second"))
+		require.Equal(t, relpFrame{3, relpResponse, []byte(relpNotAccepted)}, response())
+		require.Len(t, acc.Metrics, 1)
+
+		conn.Close()
+		receiver.Stop()
+	}
+}
+
+func TestRELPWithoutOpen(t *testing.T) {
+	receiver := newRFC3164Receiver("relp://127.0.0.1:0", "RFC5424")
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, response := relpSession(t, receiver)
+	defer conn.Close()
+
+	require.NoError(t, writeRELP(conn, 1, relpSyslog, "<1>1 - host01 - - - - first"))
+	require.Equal(t, relpFrame{1, relpResponse, []byte("500 expecting the open command")}, response())
+	_, err := conn.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestRELPServerClose(t *testing.T) {
+	receiver := newRFC3164Receiver("relp://127.0.0.1:0", "RFC5424")
+	receiver.ReadTimeout = nil
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+
+	conn, response := relpSession(t, receiver)
+	defer conn.Close()
+
+	require.NoError(t, writeRELP(conn, 1, relpOpen, "commands=syslog"))
+	require.Equal(t, 1, response().txnr)
+
+	receiver.Stop()
+	require.Equal(t, relpFrame{0, relpServerClose, nil}, response())
+}
@@ -0,0 +1,222 @@
+package syslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+)
+
+// Syslog standards of the syslog_standard option.
+const (
+	standardRFC5424 = "rfc5424"
+	standardRFC3164 = "rfc3164"
+	standardAuto    = "auto"
+)
+
+// defaultPriority is the priority of messages without one, user.notice as
+// per RFC3164#section-4.3.3.
+const defaultPriority = 13
+
+// rfc3164Message is a BSD syslog message.
+type rfc3164Message struct {
+	priority  uint8
+	timestamp *time.Time
+	hostname  string
+	appname   string
+	procID    string
+	message   string
+}
+
+// parseRFC3164 parses a BSD syslog message:
+//
+//	<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+//
+// The timestamp has no year nor time zone; it is placed in the year and the
+// location of now that makes it closest to now.  RFC3339 timestamps, as sent
+// by some daemons, are accepted too.  The hostname may be missing, and the
+// tag too in which case the whole content is the message.
+//
+// In best effort mode messages without a priority get the default one, and
+// messages without a valid timestamp are kept with the rest of the message
+// as the content.
+func parseRFC3164(data []byte, now time.Time, bestEffort bool) (*rfc3164Message, error) {
+	line := strings.TrimRight(string(data), "\r\n\x00")
+	if line == "" {
+		return nil, fmt.Errorf("empty message")
+	}
+
+	msg := &rfc3164Message{priority: defaultPriority}
+	pri, rest, err := parsePriority(line)
+	if err != nil {
+		if !bestEffort {
+			return nil, err
+		}
+	} else {
+		msg.priority = pri
+		line = rest
+	}
+
+	ts, rest, err := parseTimestamp(line, now)
+	if err != nil {
+		if !bestEffort {
+			return nil, err
+		}
+		msg.message = line
+		return msg, nil
+	}
+	msg.timestamp = &ts
+	line = strings.TrimLeft(rest, " ")
+
+	// The hostname is omitted by some senders, the first word is then the
+	// tag followed by a colon, or the start of a CEF or LEEF event.
+	if word, rest := nextWord(line); word != "" && !isTag(word) && !isEventStart(word) {
+		msg.hostname = word
+		line = rest
+	}
+
+	word, rest := nextWord(line)
+	if isTag(word) {
+		tag := strings.TrimSuffix(word, ":")
+		if i := strings.IndexByte(tag, '['); i > 0 && strings.HasSuffix(tag, "]") {
+			msg.procID = tag[i+1 : len(tag)-1]
+			tag = tag[:i]
+		}
+		msg.appname = tag
+		line = rest
+	}
+	msg.message = line
+	return msg, nil
+}
+
+// parsePriority parses the <PRI> part of a message.
+func parsePriority(line string) (uint8, string, error) {
+	end := strings.IndexByte(line, '>')
+	if !strings.HasPrefix(line, "<") || end < 2 || end > 4 {
+		return 0, line, fmt.Errorf("expecting a priority value within angle brackets")
+	}
+	pri, err := strconv.ParseUint(line[1:end], 10, 8)
+	if err != nil || pri > 191 {
+		return 0, line, fmt.Errorf("expecting a priority value in the range 1-191 or equal to 0")
+	}
+	return uint8(pri), line[end+1:], nil
+}
+
+// rfc3164Timestamp is the layout of BSD syslog timestamps, with the day of
+// the month padded by a space.
+const rfc3164Timestamp = "Jan _2 15:04:05"
+
+func parseTimestamp(line string, now time.Time) (time.Time, string, error) {
+	if len(line) >= len(rfc3164Timestamp) {
+		t, err := time.ParseInLocation(rfc3164Timestamp, line[:len(rfc3164Timestamp)], now.Location())
+		if err == nil {
+			return withYear(t, now), line[len(rfc3164Timestamp):], nil
+		}
+	}
+
+	word, rest := nextWord(line)
+	if t, err := time.Parse(time.RFC3339Nano, word); err == nil {
+		return t, rest, nil
+	}
+	return time.Time{}, line, fmt.Errorf("expecting a timestamp as \"Mmm dd hh:mm:ss\" or RFC3339")
+}
+
+// withYear sets the year of t to the one of now, or to the previous or the
+// next year if that is closer, such as for messages sent on December 31st
+// and received on January 1st.
+func withYear(t, now time.Time) time.Time {
+	best := t.AddDate(now.Year(), 0, 0)
+	for _, year := range []int{now.Year() - 1, now.Year() + 1} {
+		candidate := t.AddDate(year, 0, 0)
+		if abs(candidate.Sub(now)) < abs(best.Sub(now)) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func nextWord(line string) (string, string) {
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return line, ""
+	}
+	return line[:i], line[i+1:]
+}
+
+// isTag returns true if the word is a tag, such as "sshd:" or
+// "sshd[1234]:".
+func isTag(word string) bool {
+	return len(word) > 1 && strings.HasSuffix(word, ":") && !strings.Contains(word, "=")
+}
+
+// isEventStart returns true if the word starts a CEF or LEEF event.
+func isEventStart(word string) bool {
+	return strings.HasPrefix(word, "CEF:") || strings.HasPrefix(word, "LEEF:")
+}
+
+// isRFC5424 returns true if the message has a version after the priority,
+// such as "<13>1 2018-06-01T...".
+func isRFC5424(data []byte) bool {
+	if len(data) > 10 {
+		data = data[:10]
+	}
+	_, rest, err := parsePriority(string(data))
+	if err != nil {
+		return false
+	}
+	i := strings.IndexByte(rest, ' ')
+	if i < 1 || i > 3 || rest[0] == '0' {
+		return false
+	}
+	_, err = strconv.ParseUint(rest[:i], 10, 16)
+	return err == nil
+}
+
+func tags3164(msg *rfc3164Message) map[string]string {
+	// The names of the facility and severity are the ones of RFC5424.
+	sm := (&rfc5424.SyslogMessage{}).SetPriority(msg.priority)
+	ts := map[string]string{
+		"severity": *sm.SeverityShortLevel(),
+		"facility": *sm.FacilityLevel(),
+	}
+
+	if msg.hostname != "" {
+		ts["hostname"] = msg.hostname
+	}
+
+	if msg.appname != "" {
+		ts["appname"] = msg.appname
+	}
+
+	return ts
+}
+
+func fields3164(msg *rfc3164Message) map[string]interface{} {
+	flds := map[string]interface{}{
+		"severity_code": int(msg.priority % 8),
+		"facility_code": int(msg.priority / 8),
+	}
+
+	if msg.timestamp != nil {
+		flds["timestamp"] = msg.timestamp.UnixNano()
+	}
+
+	if msg.procID != "" {
+		flds["procid"] = msg.procID
+	}
+
+	if msg.message != "" {
+		flds["message"] = msg.message
+	}
+
+	return flds
+}
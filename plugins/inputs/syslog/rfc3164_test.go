@@ -0,0 +1,357 @@
+package syslog
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var now3164 = time.Date(2018, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+func ts3164(month time.Month, day, hour, min, sec int) *time.Time {
+	t := time.Date(2018, month, day, hour, min, sec, 0, time.UTC)
+	return &t
+}
+
+func TestParseRFC3164(t *testing.T) {
+	rfc3339 := time.Date(2018, time.June, 15, 11, 58, 0, 500000000, time.UTC)
+	tests := []struct {
+		name       string
+		data       string
+		bestEffort bool
+		want       *rfc3164Message
+		werr       bool
+	}{
+		{
+			name: "complete",
+			data: "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8",
+			want: &rfc3164Message{
+				priority:  34,
+				timestamp: ts3164(time.October, 11, 22, 14, 15),
+				hostname:  "mymachine",
+				appname:   "su",
+				message:   "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			name: "pid",
+			data: "<86>Jun  5 08:01:02 web01 sshd[1234]: Accepted publickey for root\n",
+			want: &rfc3164Message{
+				priority:  86,
+				timestamp: ts3164(time.June, 5, 8, 1, 2),
+				hostname:  "web01",
+				appname:   "sshd",
+				procID:    "1234",
+				message:   "Accepted publickey for root",
+			},
+		},
+		{
+			name: "no hostname",
+			data: "<13>Jun 15 11:59:00 kernel: eth0: link up",
+			want: &rfc3164Message{
+				priority:  13,
+				timestamp: ts3164(time.June, 15, 11, 59, 0),
+				appname:   "kernel",
+				message:   "eth0: link up",
+			},
+		},
+		{
+			name: "no tag",
+			data: "<165>Jun 15 11:59:00 fw01 %ASA-6-302013: Built outbound TCP connection",
+			want: &rfc3164Message{
+				priority:  165,
+				timestamp: ts3164(time.June, 15, 11, 59, 0),
+				hostname:  "fw01",
+				appname:   "%ASA-6-302013",
+				message:   "Built outbound TCP connection",
+			},
+		},
+		{
+			name: "rfc3339 timestamp",
+			data: "<30>2018-06-15T11:58:00.5Z host01 systemd[1]: Started Session 42.",
+			want: &rfc3164Message{
+				priority:  30,
+				timestamp: &rfc3339,
+				hostname:  "host01",
+				appname:   "systemd",
+				procID:    "1",
+				message:   "Started Session 42.",
+			},
+		},
+		{
+			name: "missing priority",
+			data: "Jun 15 11:59:00 host01 app: message",
+			werr: true,
+		},
+		{
+			name:       "missing priority best effort",
+			data:       "Jun 15 11:59:00 host01 app: message",
+			bestEffort: true,
+			want: &rfc3164Message{
+				priority:  defaultPriority,
+				timestamp: ts3164(time.June, 15, 11, 59, 0),
+				hostname:  "host01",
+				appname:   "app",
+				message:   "message",
+			},
+		},
+		{
+			name: "invalid timestamp",
+			data: "<13>yesterday host01 app: message",
+			werr: true,
+		},
+		{
+			name:       "invalid timestamp best effort",
+			data:       "<13>yesterday host01 app: message",
+			bestEffort: true,
+			want: &rfc3164Message{
+				priority: 13,
+				message:  "yesterday host01 app: message",
+			},
+		},
+		{
+			name: "invalid priority",
+			data: "<192>Jun 15 11:59:00 host01 app: message",
+			werr: true,
+		},
+		{
+			name: "empty",
+			data: "\n",
+			werr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseRFC3164([]byte(tt.data), now3164, tt.bestEffort)
+			if tt.werr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, msg)
+		})
+	}
+}
+
+func TestRFC3164Year(t *testing.T) {
+	newYear := time.Date(2019, time.January, 1, 0, 0, 5, 0, time.UTC)
+	msg, err := parseRFC3164([]byte("<13>Dec 31 23:59:58 host01 app: message"), newYear, false)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2018, time.December, 31, 23, 59, 58, 0, time.UTC), *msg.timestamp)
+
+	newYearsEve := time.Date(2018, time.December, 31, 23, 59, 58, 0, time.UTC)
+	msg, err = parseRFC3164([]byte("<13>Jan  1 00:00:01 host01 app: message"), newYearsEve, false)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2019, time.January, 1, 0, 0, 1, 0, time.UTC), *msg.timestamp)
+}
+
+func TestRFC3164Location(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Still December 31st in New York when already January 1st in UTC.
+	now := time.Date(2019, time.January, 1, 3, 0, 0, 0, time.UTC).In(newYork)
+	msg, err := parseRFC3164([]byte("<13>Dec 31 21:59:00 host01 app: message"), now, false)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2019, time.January, 1, 2, 59, 0, 0, time.UTC), msg.timestamp.UTC())
+}
+
+func TestIsRFC5424(t *testing.T) {
+	require.True(t, isRFC5424([]byte("<1>1 - - - - - -")))
+	require.True(t, isRFC5424([]byte("<191>999 2018-06-15T11:58:00Z host app - - - msg")))
+	require.False(t, isRFC5424([]byte("<13>Jun 15 11:59:00 host01 app: message")))
+	require.False(t, isRFC5424([]byte("<30>2018-06-15T11:58:00Z host01 app: message")))
+	require.False(t, isRFC5424([]byte("<13>0 - - - - - -")))
+	require.False(t, isRFC5424([]byte("1 - - - - - -")))
+}
+
+func TestRFC3164Metric(t *testing.T) {
+	msg, err := parseRFC3164([]byte("<86>Jun  5 08:01:02 web01 sshd[1234]: Accepted publickey"), now3164, false)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"severity": "info",
+		"facility": "authpriv",
+		"hostname": "web01",
+		"appname":  "sshd",
+	}, tags3164(msg))
+	require.Equal(t, map[string]interface{}{
+		"severity_code": 6,
+		"facility_code": 10,
+		"timestamp":     ts3164(time.June, 5, 8, 1, 2).UnixNano(),
+		"procid":        "1234",
+		"message":       "Accepted publickey",
+	}, fields3164(msg))
+}
+
+func TestSyslogStandard(t *testing.T) {
+	rec := &Syslog{
+		Address: "udp://127.0.0.1:0",
+		Options: Options{
+			SyslogStandard: "RFC3339",
+		},
+	}
+	err := rec.Start(&testutil.Accumulator{})
+	require.EqualError(t, err, `unknown syslog_standard "RFC3339"`)
+}
+
+func newRFC3164Receiver(address, standard string) *Syslog {
+	return &Syslog{
+		Address: address,
+		Options: Options{
+			ReadTimeout:    &internal.Duration{Duration: defaultReadTimeout},
+			Separator:      "_",
+			SyslogStandard: standard,
+		},
+		now: func() time.Time {
+			return now3164
+		},
+	}
+}
+
+func TestDefaultTimezone(t *testing.T) {
+	receiver := newRFC3164Receiver("udp://127.0.0.1:0", "RFC3164")
+	receiver.DefaultTimezone = "America/New_York"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("udp", receiver.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<13>Jun 15 08:00:00 host01 app: message"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	require.Equal(t, ts3164(time.June, 15, 12, 0, 0).UnixNano(), acc.Metrics[0].Fields["timestamp"])
+}
+
+func TestDefaultTimezoneErrors(t *testing.T) {
+	rec := &Syslog{
+		Address: "udp://127.0.0.1:0",
+		Options: Options{
+			SyslogStandard:  "RFC3164",
+			DefaultTimezone: "Mars/Olympus_Mons",
+		},
+	}
+	err := rec.Start(&testutil.Accumulator{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid default_timezone "Mars/Olympus_Mons"`)
+
+	rec = &Syslog{
+		Address: "udp://127.0.0.1:0",
+		Options: Options{
+			DefaultTimezone: "UTC",
+		},
+	}
+	require.EqualError(t, rec.Validate(), "default_timezone only applies to RFC3164 messages")
+}
+
+func TestRFC3164_udp(t *testing.T) {
+	receiver := newRFC3164Receiver("udp://127.0.0.1:0", "RFC3164")
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("udp", receiver.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<34>Oct 11 22:14:15 mymachine su: 'su root' failed"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{
+			"severity_code": 2,
+			"facility_code": 4,
+			"timestamp":     ts3164(time.October, 11, 22, 14, 15).UnixNano(),
+			"message":       "'su root' failed",
+		},
+		map[string]string{
+			"severity": "crit",
+			"facility": "auth",
+			"hostname": "mymachine",
+			"appname":  "su",
+			"source":   "127.0.0.1",
+		})
+}
+
+func TestRFC3164_tcp(t *testing.T) {
+	receiver := newRFC3164Receiver("tcp://127.0.0.1:0", "RFC3164")
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<13>Jun 15 11:59:00 host01 app: first\n" +
+		"<14>Jun 15 11:59:01 host01 app: second\r\n"))
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	require.Equal(t, "first", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "second", acc.Metrics[1].Fields["message"])
+	require.Equal(t, "info", acc.Metrics[1].Tags["severity"])
+}
+
+func TestAuto_udp(t *testing.T) {
+	receiver := newRFC3164Receiver("udp://127.0.0.1:0", "auto")
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("udp", receiver.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<13>Jun 15 11:59:00 host01 app: legacy"))
+	require.NoError(t, err)
+	acc.Wait(1)
+	_, err = conn.Write([]byte("<1>1 - host02 - - - - modern"))
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	require.Equal(t, "legacy", acc.Metrics[0].Fields["message"])
+	require.NotContains(t, acc.Metrics[0].Fields, "version")
+	require.Equal(t, "modern", acc.Metrics[1].Fields["message"])
+	require.Equal(t, uint16(1), acc.Metrics[1].Fields["version"])
+	require.Equal(t, "RFC3164", acc.Metrics[0].Tags["standard"])
+	require.Equal(t, "RFC5424", acc.Metrics[1].Tags["standard"])
+}
+
+func TestAuto_tcp(t *testing.T) {
+	receiver := newRFC3164Receiver("tcp://127.0.0.1:0", "auto")
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+	addr := receiver.tcpListener.Addr().String()
+
+	// Non-transparent framing, with messages of both standards
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<13>Jun 15 11:59:00 host01 app: legacy\n<1>1 - host02 - - - - modern\n"))
+	require.NoError(t, err)
+	acc.Wait(2)
+	require.Equal(t, "legacy", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "modern", acc.Metrics[1].Fields["message"])
+
+	// Octet counting
+	conn2, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn2.Close()
+	_, err = conn2.Write([]byte("29 <1>1 - host03 - - - - counted"))
+	require.NoError(t, err)
+	acc.Wait(3)
+	require.Equal(t, "counted", acc.Metrics[2].Fields["message"])
+	require.Equal(t, "host03", acc.Metrics[2].Tags["hostname"])
+	require.Equal(t, "RFC5424", acc.Metrics[2].Tags["standard"])
+}
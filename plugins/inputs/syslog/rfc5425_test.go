@@ -349,12 +349,14 @@ func newTCPSyslogReceiver(address string, keepAlive *internal.Duration, maxConn
 	}
 	s := &Syslog{
 		Address: address,
+		Options: Options{
+			ReadTimeout: d,
+			BestEffort:  bestEffort,
+			Separator:   "_",
+		},
 		now: func() time.Time {
 			return defaultTime
 		},
-		ReadTimeout: d,
-		BestEffort:  bestEffort,
-		Separator:   "_",
 	}
 	if keepAlive != nil {
 		s.KeepAlivePeriod = keepAlive
@@ -418,6 +420,9 @@ func testStrictRFC5425(t *testing.T, protocol string, address string, wantTLS bo
 			for _, metric := range acc.Metrics {
 				got = append(got, *metric)
 			}
+			for _, want := range tc.wantStrict {
+				addSourceTag(protocol, want.Tags)
+			}
 			if !cmp.Equal(tc.wantStrict, got) {
 				t.Fatalf("Got (+) / Want (-)\n %s", cmp.Diff(tc.wantStrict, got))
 			}
@@ -472,6 +477,9 @@ func testBestEffortRFC5425(t *testing.T, protocol string, address string, wantTL
 			for _, metric := range acc.Metrics {
 				got = append(got, *metric)
 			}
+			for _, want := range tc.wantBestEffort {
+				addSourceTag(protocol, want.Tags)
+			}
 			if !cmp.Equal(tc.wantBestEffort, got) {
 				t.Fatalf("Got (+) / Want (-)\n %s", cmp.Diff(tc.wantBestEffort, got))
 			}
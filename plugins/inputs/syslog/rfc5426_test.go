@@ -3,7 +3,6 @@ package syslog
 import (
 	"fmt"
 	"net"
-	"os"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -208,11 +207,13 @@ func getTestCasesForRFC5426() []testCase5426 {
 func newUDPSyslogReceiver(address string, bestEffort bool) *Syslog {
 	return &Syslog{
 		Address: address,
+		Options: Options{
+			BestEffort: bestEffort,
+			Separator:  "_",
+		},
 		now: func() time.Time {
 			return defaultTime
 		},
-		BestEffort: bestEffort,
-		Separator:  "_",
 	}
 }
 
@@ -258,6 +259,9 @@ func testRFC5426(t *testing.T, protocol string, address string, bestEffort bool)
 			} else {
 				want = tc.wantStrict
 			}
+			if want != nil {
+				addSourceTag(protocol, want.Tags)
+			}
 			if !cmp.Equal(want, got) {
 				t.Fatalf("Got (+) / Want (-)\n %s", cmp.Diff(want, got))
 			}
@@ -275,13 +279,13 @@ func TestStrict_udp(t *testing.T) {
 
 func TestBestEffort_unixgram(t *testing.T) {
 	sockname := "/tmp/telegraf_test.sock"
-	os.Create(sockname)
+	createStaleSocket(t, sockname)
 	testRFC5426(t, "unixgram", sockname, true)
 }
 
 func TestStrict_unixgram(t *testing.T) {
 	sockname := "/tmp/telegraf_test.sock"
-	os.Create(sockname)
+	createStaleSocket(t, sockname)
 	testRFC5426(t, "unixgram", sockname, false)
 }
 
@@ -297,10 +301,12 @@ func TestTimeIncrement_udp(t *testing.T) {
 
 	// Create receiver
 	receiver := &Syslog{
-		Address:    "udp://" + address,
-		now:        getNow,
-		BestEffort: false,
-		Separator:  "_",
+		Address: "udp://" + address,
+		Options: Options{
+			BestEffort: false,
+			Separator:  "_",
+		},
+		now: getNow,
 	}
 	acc := &testutil.Accumulator{}
 	require.NoError(t, receiver.Start(acc))
@@ -329,6 +335,7 @@ func TestTimeIncrement_udp(t *testing.T) {
 		Tags: map[string]string{
 			"severity": "alert",
 			"facility": "kern",
+			"source":   "127.0.0.1",
 		},
 		Time: getNow(),
 	}
@@ -360,6 +367,7 @@ func TestTimeIncrement_udp(t *testing.T) {
 		Tags: map[string]string{
 			"severity": "alert",
 			"facility": "kern",
+			"source":   "127.0.0.1",
 		},
 		Time: getNow(),
 	}
@@ -390,6 +398,7 @@ func TestTimeIncrement_udp(t *testing.T) {
 		Tags: map[string]string{
 			"severity": "alert",
 			"facility": "kern",
+			"source":   "127.0.0.1",
 		},
 		Time: getNow().Add(time.Nanosecond),
 	}
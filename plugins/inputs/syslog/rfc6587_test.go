@@ -0,0 +1,264 @@
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testNonTransparent(t *testing.T, standard, trailer, data string) *testutil.Accumulator {
+	receiver := newRFC3164Receiver("tcp://127.0.0.1:0", standard)
+	receiver.Framing = framingNonTransparent
+	receiver.Trailer = trailer
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(data))
+	require.NoError(t, err)
+	acc.Wait(2)
+	return acc
+}
+
+func TestNonTransparent_LF(t *testing.T) {
+	acc := testNonTransparent(t, "RFC5424", "",
+		"<1>1 - host01 - - - - first\n\n<14>1 - host02 - - - - second\r\n")
+
+	require.Equal(t, "first", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "host01", acc.Metrics[0].Tags["hostname"])
+	require.Equal(t, "second", acc.Metrics[1].Fields["message"])
+	require.Equal(t, "info", acc.Metrics[1].Tags["severity"])
+	require.Equal(t, uint16(1), acc.Metrics[1].Fields["version"])
+}
+
+func TestNonTransparent_NUL(t *testing.T) {
+	acc := testNonTransparent(t, "RFC5424", "NUL",
+		"<1>1 - host01 - - - - multi\nline\x00<14>1 - host02 - - - - second\x00")
+
+	require.Equal(t, "multi\nline", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "second", acc.Metrics[1].Fields["message"])
+}
+
+func TestNonTransparent_RFC3164(t *testing.T) {
+	acc := testNonTransparent(t, "RFC3164", "nul",
+		"<13>Jun 15 11:59:00 host01 app: first\x00<14>Jun 15 11:59:01 host01 app: second\x00")
+
+	require.Equal(t, "first", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "second", acc.Metrics[1].Fields["message"])
+}
+
+func TestOctetCounting_RFC3164(t *testing.T) {
+	receiver := newRFC3164Receiver("tcp://127.0.0.1:0", "RFC3164")
+	receiver.Framing = framingOctetCounting
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("37 <13>Jun 15 11:59:00 host01 app: first"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	require.Equal(t, "first", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "host01", acc.Metrics[0].Tags["hostname"])
+}
+
+func TestFramingErrors(t *testing.T) {
+	rec := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			Framing: "newline",
+		},
+	}
+	require.EqualError(t, rec.Start(&testutil.Accumulator{}), `unknown framing "newline"`)
+
+	rec = &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			Trailer: "CR",
+		},
+	}
+	require.EqualError(t, rec.Start(&testutil.Accumulator{}), `unknown trailer "CR"`)
+}
+
+func TestFramer(t *testing.T) {
+	tests := []struct {
+		name      string
+		octet     bool
+		oversized string
+		data      string
+		frames    []string
+		truncated []bool
+		err       error
+	}{
+		{
+			name:      "non-transparent drop",
+			oversized: oversizedDrop,
+			data:      "0123\n0123456789\n01234567\n",
+			frames:    []string{"0123", "01234567"},
+			truncated: []bool{false, false},
+		},
+		{
+			name:      "non-transparent truncate",
+			oversized: oversizedTruncate,
+			data:      "0123456789abcdef0123\n0123",
+			frames:    []string{"01234567", "0123"},
+			truncated: []bool{true, false},
+		},
+		{
+			name: "non-transparent too long",
+			data: "0123456789\n",
+			err:  bufio.ErrTooLong,
+		},
+		{
+			name:      "octet counting drop",
+			octet:     true,
+			oversized: oversizedDrop,
+			data:      "4 01239 0123456788 01234567",
+			frames:    []string{"0123", "01234567"},
+			truncated: []bool{false, false},
+		},
+		{
+			name:      "octet counting truncate",
+			octet:     true,
+			oversized: oversizedTruncate,
+			data:      "20 0123456789abcdef01234 0123",
+			frames:    []string{"01234567", "0123"},
+			truncated: []bool{true, false},
+		},
+		{
+			name:  "octet counting too long",
+			octet: true,
+			data:  "9 012345678",
+			err:   fmt.Errorf(`invalid message length "9"`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oversized := 0
+			f := &framer{
+				trailer:     '\n',
+				maxSize:     8,
+				oversized:   tt.oversized,
+				onOversized: func() { oversized++ },
+			}
+			split := f.splitNonTransparent
+			if tt.octet {
+				split = f.splitOctetCounting
+			}
+			// One byte at a time, the worst case of the frames split over
+			// several reads.
+			scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(tt.data)))
+			scanner.Buffer(make([]byte, 0, 4), f.maxSize+16)
+			scanner.Split(split)
+
+			var frames []string
+			var truncated []bool
+			for scanner.Scan() {
+				frames = append(frames, scanner.Text())
+				truncated = append(truncated, f.truncated)
+			}
+			require.Equal(t, tt.err, scanner.Err())
+			require.Equal(t, tt.frames, frames)
+			require.Equal(t, tt.truncated, truncated)
+			if tt.oversized != "" {
+				require.Equal(t, 1, oversized)
+			}
+		})
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	receiver := newRFC3164Receiver("tcp://127.0.0.1:0", "RFC5424")
+	receiver.Framing = framingNonTransparent
+	receiver.MaxMessageSize = 32
+	receiver.OversizedMessages = oversizedTruncate
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+	// The stats are shared with the other listeners of the same address.
+	oversized := receiver.messagesOversized.Get()
+
+	conn, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<1>1 - host01 - - - - " + strings.Repeat("long ", 1000) + "\n" +
+		"<14>1 - host02 - - - - short\n"))
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	require.Equal(t, "long long ", acc.Metrics[0].Fields["message"])
+	require.Equal(t, true, acc.Metrics[0].Fields["truncated"])
+	require.Equal(t, "short", acc.Metrics[1].Fields["message"])
+	require.NotContains(t, acc.Metrics[1].Fields, "truncated")
+	require.Equal(t, oversized+1, receiver.messagesOversized.Get())
+	require.Empty(t, acc.Errors)
+}
+
+func TestMaxMessageSize_udp(t *testing.T) {
+	receiver := newRFC3164Receiver("udp://127.0.0.1:0", "RFC5424")
+	receiver.MaxMessageSize = 32
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+	oversized := receiver.messagesOversized.Get()
+
+	conn, err := net.Dial("udp", receiver.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<1>1 - host01 - - - - " + strings.Repeat("long ", 10)))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<14>1 - host02 - - - - short"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "short", acc.Metrics[0].Fields["message"])
+	require.Equal(t, oversized+1, receiver.messagesOversized.Get())
+}
+
+func TestMaxDatagramSize_unixgram(t *testing.T) {
+	sockname := "/tmp/telegraf_test.sock"
+	receiver := newRFC3164Receiver("unixgram://"+sockname, "RFC3164")
+	receiver.MaxDatagramSize = 100000
+	receiver.OversizedMessages = oversizedTruncate
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+	oversized := receiver.messagesOversized.Get()
+
+	conn, err := net.Dial("unixgram", sockname)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The datagrams over 64KiB are received whole, up to max_datagram_size,
+	// as sent by syslog(3) to /dev/log.
+	header := "<13>Jun 15 12:00:00 app[42]: "
+	_, err = conn.Write([]byte(header + strings.Repeat("a", 90000)))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(header + strings.Repeat("b", 110000)))
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	require.Equal(t, strings.Repeat("a", 90000), acc.Metrics[0].Fields["message"])
+	require.Equal(t, "app", acc.Metrics[0].Tags["appname"])
+	require.NotContains(t, acc.Metrics[0].Fields, "truncated")
+	require.Equal(t, strings.Repeat("b", 100000-len(header)), acc.Metrics[1].Fields["message"])
+	require.Equal(t, true, acc.Metrics[1].Fields["truncated"])
+	require.Equal(t, oversized+1, receiver.messagesOversized.Get())
+}
@@ -0,0 +1,106 @@
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// sourceFilter allows or denies the senders by their IP address.
+type sourceFilter struct {
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+}
+
+// newSourceFilter returns the filter of the allowed and denied CIDRs, or nil
+// when there are none.
+func newSourceFilter(allowed, denied []string) (*sourceFilter, error) {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil, nil
+	}
+	f := &sourceFilter{}
+	var err error
+	if f.allowed, err = parseCIDRs(allowed); err != nil {
+		return nil, fmt.Errorf("allowed_sources: %s", err)
+	}
+	if f.denied, err = parseCIDRs(denied); err != nil {
+		return nil, fmt.Errorf("denied_sources: %s", err)
+	}
+	return f, nil
+}
+
+// parseCIDRs parses CIDRs, such as "192.0.2.0/24", or single IP addresses.
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// allow tells whether the sender of the IP address is allowed: it is in
+// none of the denied networks, and in one of the allowed networks if any.
+// The senders without IP address are only allowed without allowed networks.
+func (f *sourceFilter) allow(ip net.IP) bool {
+	if ip == nil {
+		return len(f.allowed) == 0
+	}
+	for _, n := range f.denied {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(f.allowed) == 0 {
+		return true
+	}
+	for _, n := range f.allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// addrIP returns the IP address and port of a network address, no address
+// for the unix domain sockets.
+func addrIP(addr net.Addr) (net.IP, int) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP, a.Port
+	case *net.UDPAddr:
+		return a.IP, a.Port
+	case *net.IPAddr:
+		return a.IP, 0
+	}
+	return nil, 0
+}
+
+// allowSource tells whether the sender of the address is allowed by
+// allowed_sources and denied_sources.
+func (s *Syslog) allowSource(addr net.Addr) bool {
+	if s.sources == nil {
+		return true
+	}
+	ip, _ := addrIP(addr)
+	if !s.sources.allow(ip) {
+		s.sourcesDenied.Incr(1)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package syslog
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceFilter(t *testing.T) {
+	f, err := newSourceFilter([]string{"10.1.0.0/16", "2001:db8:1::/48", "192.0.2.1"}, []string{"10.1.255.0/24"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		ip    string
+		allow bool
+	}{
+		{"10.1.0.1", true},
+		{"::ffff:10.1.0.1", true},
+		{"10.1.255.1", false},
+		{"10.2.0.1", false},
+		{"192.0.2.1", true},
+		{"192.0.2.2", false},
+		{"2001:db8:1::1", true},
+		{"2001:db8:2::1", false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.allow, f.allow(net.ParseIP(tt.ip)), tt.ip)
+	}
+	require.False(t, f.allow(nil))
+
+	f, err = newSourceFilter(nil, []string{"10.1.255.0/24"})
+	require.NoError(t, err)
+	require.True(t, f.allow(net.ParseIP("10.2.0.1")))
+	require.False(t, f.allow(net.ParseIP("10.1.255.1")))
+	require.True(t, f.allow(nil))
+
+	f, err = newSourceFilter(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, f)
+
+	_, err = newSourceFilter([]string{"10.1.0.0/33"}, nil)
+	require.EqualError(t, err, `allowed_sources: invalid CIDR "10.1.0.0/33"`)
+	_, err = newSourceFilter(nil, []string{"host"})
+	require.EqualError(t, err, `denied_sources: invalid IP address "host"`)
+}
+
+func TestDeniedSources(t *testing.T) {
+	for _, protocol := range []string{"udp", "tcp"} {
+		t.Run(protocol, func(t *testing.T) {
+			receiver := &Syslog{
+				Address: protocol + "://127.0.0.1:0",
+				Options: Options{
+					AllowedSources: []string{"127.0.0.0/8"},
+					DeniedSources:  []string{"127.0.0.1"},
+					Separator:      "_",
+				},
+				now: time.Now,
+			}
+			acc := &testutil.Accumulator{}
+			require.NoError(t, receiver.Start(acc))
+			defer receiver.Stop()
+			denied := receiver.sourcesDenied.Get()
+
+			var addr string
+			if protocol == "udp" {
+				addr = receiver.udpListener.LocalAddr().String()
+			} else {
+				addr = receiver.tcpListener.Addr().String()
+			}
+			conn, err := net.Dial(protocol, addr)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("16 <1>1 - - - - - -"))
+			require.NoError(t, err)
+			waitStat(t, receiver.sourcesDenied, denied+1)
+			if protocol == "tcp" {
+				conn.SetReadDeadline(time.Now().Add(time.Second))
+				_, err = conn.Read(make([]byte, 1))
+				require.Equal(t, io.EOF, err)
+			}
+			require.Empty(t, acc.Metrics)
+		})
+	}
+}
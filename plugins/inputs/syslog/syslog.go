@@ -5,47 +5,177 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"net/url"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/influxdata/go-syslog/rfc5424"
-	"github.com/influxdata/go-syslog/rfc5425"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/internal/events"
+	"github.com/influxdata/telegraf/internal/listen"
 	tlsConfig "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 const defaultReadTimeout = time.Millisecond * 500
+
 const ipMaxPacketSize = 64 * 1024
 
-// Syslog is a syslog plugin
-type Syslog struct {
+const defaultMeasurement = "syslog"
+
+// Formats of the structured data of the structured_data_format option.
+const (
+	sdFormatFields = "fields"
+	sdFormatJSON   = "json"
+)
+
+// sdJSONField is the field of the structured data in the json format.
+const sdJSONField = "structured_data"
+
+// Sources of the time of the metrics of the time_source option.
+const (
+	timeSourceReceived = "received"
+	timeSourceMessage  = "message"
+)
+
+// Options are the options of a listener, shared by the listeners of
+// servers which each have a copy of them.
+type Options struct {
 	tlsConfig.ServerConfig
-	Address         string `toml:"server"`
+	listen.Config
+	TLSRequireClientCert bool     `toml:"tls_require_client_cert"`
+	AllowedClientDNs     []string `toml:"allowed_client_dns"`
+	AllowedClientCNs     []string `toml:"allowed_client_cns"`
+	TLSMinVersion        string   `toml:"tls_min_version"`
+	TLSMaxVersion        string   `toml:"tls_max_version"`
+	TLSCipherSuites      []string `toml:"tls_cipher_suites"`
+
 	KeepAlivePeriod *internal.Duration
 	ReadTimeout     *internal.Duration
+	ShutdownTimeout *internal.Duration `toml:"shutdown_timeout"`
 	MaxConnections  int
 	BestEffort      bool
-	Separator       string `toml:"sdparam_separator"`
+	Separator       string            `toml:"sdparam_separator"`
+	SDParamPrefix   string            `toml:"sdparam_prefix"`
+	SDFormat        string            `toml:"structured_data_format"`
+	SDIDsAsTags     []string          `toml:"sdids_as_tags"`
+	CEF             bool              `toml:"cef"`
+	CEFAsTags       []string          `toml:"cef_extensions_as_tags"`
+	LEEF            bool              `toml:"leef"`
+	LEEFAsTags      []string          `toml:"leef_attributes_as_tags"`
+	PauseOnFailure  bool              `toml:"pause_on_output_failure"`
+	SyslogStandard  string            `toml:"syslog_standard"`
+	DefaultTimezone string            `toml:"default_timezone"`
+	TimeSource      string            `toml:"time_source"`
+	SocketMode      string            `toml:"socket_mode"`
+	Framing         string            `toml:"framing"`
+	Trailer         string            `toml:"trailer"`
+	Measurement     string            `toml:"measurement"`
+	ExtraTags       map[string]string `toml:"extra_tags"`
+	SourcePort      bool              `toml:"source_port"`
+	ProxyProtocol   bool              `toml:"proxy_protocol"`
+	AllowedSources  []string          `toml:"allowed_sources"`
+	DeniedSources   []string          `toml:"denied_sources"`
+	SeverityFilter  string            `toml:"severity_filter"`
+	FacilityFilter  []string          `toml:"facility_filter"`
+	SeverityNames   map[string]string `toml:"severity_names"`
+	FacilityNames   map[string]string `toml:"facility_names"`
+	MaxRate         int               `toml:"max_messages_per_second"`
+	MaxRatePerPeer  int               `toml:"max_messages_per_second_per_peer"`
+
+	MaxMessageSize    int    `toml:"max_message_size"`
+	MaxDatagramSize   int    `toml:"max_datagram_size"`
+	OversizedMessages string `toml:"oversized_messages"`
+	CharacterEncoding string `toml:"character_encoding"`
+
+	ParseWorkers int `toml:"parse_workers"`
+	QueueSize    int `toml:"queue_size"`
+}
+
+// Syslog is a syslog plugin
+type Syslog struct {
+	Options
+	Address string   `toml:"server"`
+	Servers []string `toml:"servers"`
+
+	// listeners are the listeners of servers, each a copy of the options
+	// with its address.
+	listeners []*Syslog
+	// server is the address of the listener as in servers, added as the
+	// server tag.
+	server string
+	// address is the address as configured, with its protocol, Address
+	// being its host once started, so that the listener can be restarted.
+	address string
+	// failed receives the error which ended the listener, shared with the
+	// listeners of servers.
+	failed chan error
 
 	now      func() time.Time
 	lastTime time.Time
+	timeMu   sync.Mutex
 
 	mu sync.Mutex
 	wg sync.WaitGroup
+	// readers are the goroutines reading the listener, which stop before
+	// the parse workers so that the messages read are parsed.
+	readers sync.WaitGroup
 	io.Closer
 
+	standard      string
+	severityNames map[string]string
+	facilityNames map[string]string
+	location      *time.Location
+	messageTime   bool
+	sdJSON        bool
 	isStream      bool
+	isUnix        bool
+	isRELP        bool
+	isDTLS        bool
+	socketMode    os.FileMode
+	trailer       byte
+	oversized     string
+	decoder       *encoding.Decoder
 	tcpListener   net.Listener
 	tlsConfig     *tls.Config
-	connections   map[string]net.Conn
+	dtlsListener  *dtlsListener
+	connections   map[net.Conn]struct{}
 	connectionsMu sync.Mutex
+	handlers      sync.WaitGroup
 
 	udpListener net.PacketConn
+
+	// priorities tells whether the messages of each priority are kept, nil
+	// when all of them are.
+	priorities []bool
+	limiter    *rateLimiter
+	sources    *sourceFilter
+
+	// queue is the queue of the messages to parse by the parse workers, nil
+	// without parse_workers.
+	queue           chan parseJob
+	stopWorkers     chan struct{}
+	stopWorkersOnce sync.Once
+
+	// paused is set while new connections are refused because all outputs
+	// are failing.
+	paused       int32
+	outputEvents *events.Subscription
+
+	connectionsAccepted selfstat.Stat
+	connectionsRejected selfstat.Stat
+	messagesParsed      selfstat.Stat
+	messagesFiltered    selfstat.Stat
+	messagesRateLimited selfstat.Stat
+	messagesOversized   selfstat.Stat
+	tlsClientsRejected  selfstat.Stat
+	sourcesDenied       selfstat.Stat
+	parseErrors         selfstat.Stat
+	framesDropped       selfstat.Stat
+	messagesDropped     selfstat.Stat
+	queueBlocked        selfstat.Stat
 }
 
 var sampleConfig = `
@@ -53,13 +183,70 @@ var sampleConfig = `
   ## Protocol, address and port to host the syslog receiver.
   ## If no host is specified, then localhost is used.
   ## If no port is specified, 6514 is used (RFC5425#section-4.1).
+  ## Unix domain sockets are supported with the unix and unixgram protocols,
+  ##   eg., unix:///var/run/telegraf-syslog.sock
+  ## RELP, the reliable event logging protocol of rsyslog, is supported with
+  ## the relp protocol, eg., relp://:2514.  The messages are acknowledged to
+  ## the senders once they are parsed.
+  ## Syslog over DTLS 1.2, as per RFC6012, is supported with the dtls
+  ## protocol, eg., dtls://:6514, with the TLS options below.
   server = "tcp://:6514"
 
+  ## Addresses to listen on instead of server, with the other options shared
+  ## by their listeners.  The options which do not apply to the protocol of
+  ## an address are ignored by its listener.  The metrics have the server
+  ## tag with the address they were received on.
+  # servers = ["tcp://:6514", "udp://:514", "unix:///run/telegraf.sock"]
+
+  ## The tcp4, tcp6, udp4 and udp6 protocols listen on IPv4 or IPv6 only,
+  ## eg., tcp6://[::1]:6514.  Set ipv6_v6only to listen on IPv6 only, or on
+  ## both IPv4 and IPv6, on the empty and IPv6 hosts of the other protocols,
+  ## instead of the defaults of the system.  The IPv4 hosts, including
+  ## 0.0.0.0, then listen on IPv4 only.  Not supported on Windows.
+  # ipv6_v6only = false
+
+  ## Permissions of the unix domain socket file, in octal (eg., "0660").
+  ## Defaults to the umask of the Telegraf process.
+  # socket_mode = ""
+
+  ## Maximum size of the datagrams received on the udp and unixgram
+  ## protocols, in bytes.  The larger datagrams are handled as per
+  ## oversized_messages.  Local applications may send datagrams over 64KiB
+  ## on unixgram sockets (default = 65536).
+  # max_datagram_size = 65536
+
+  ## Name of the measurement of the messages (default = "syslog").
+  # measurement = "syslog"
+
   ## TLS Config
   # tls_allowed_cacerts = ["/etc/telegraf/ca.pem"]
   # tls_cert = "/etc/telegraf/cert.pem"
   # tls_key = "/etc/telegraf/key.pem"
 
+  ## Whether the clients must present a certificate signed by one of the
+  ## tls_allowed_cacerts (default = true).  If false, clients without a
+  ## certificate are accepted too, the certificates presented are verified.
+  # tls_require_client_cert = true
+
+  ## Distinguished names, as per RFC4514 (eg., "CN=relay1,O=Example,C=US"),
+  ## and common names of the client certificates allowed to connect.  A
+  ## client is accepted if its certificate matches any of them.  Defaults to
+  ## accepting all the certificates signed by the tls_allowed_cacerts.
+  # allowed_client_dns = []
+  # allowed_client_cns = []
+
+  ## Minimum and maximum TLS versions accepted, "TLS10", "TLS11" or "TLS12".
+  ## Defaults to the versions of Go, with the tls_min_version of the agent.
+  # tls_min_version = "TLS12"
+  # tls_max_version = "TLS12"
+
+  ## Cipher suites accepted, by IANA name.  Defaults to the ones of Go.  With
+  ## the fips option of the agent, only the FIPS approved ones are kept.
+  # tls_cipher_suites = [
+  #   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+  #   "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+  # ]
+
   ## Period between keep alive probes.
   ## 0 disables keep alive probes.
   ## Defaults to the OS configuration.
@@ -71,20 +258,180 @@ var sampleConfig = `
   ## Only applies to stream sockets (e.g. TCP).
   # max_connections = 1024
 
+  ## Number of goroutines parsing the messages, and size of their queue of
+  ## messages (default = 0 and 1000).  0 parses the messages in the goroutine
+  ## reading each connection or the datagrams.  With workers, the reads of a
+  ## connection pause while the queue is full, and the datagrams received
+  ## are dropped.
+  # parse_workers = 0
+  # queue_size = 1000
+
   ## Read timeout (default = 500ms).
   ## 0 means unlimited.
   # read_timeout = 500ms
 
+  ## Time given to the open connections to end when stopping, such as on
+  ## reloads, after new connections are refused.  The messages received
+  ## meanwhile are parsed, then the connections left are closed.  0 closes
+  ## the connections right away (default = 0s).
+  ## Only applies to stream sockets (e.g. TCP).
+  # shutdown_timeout = "5s"
+
+  ## The syslog standard of the messages (default = "RFC5424"):
+  ##   "RFC5424" - messages per RFC5424, framed by octet counting as per
+  ##               RFC5425 on stream sockets
+  ##   "RFC3164" - BSD syslog messages, one per line on stream sockets
+  ##   "auto"    - detect the standard of each message and the framing of
+  ##               each connection, with the standard tag set to the
+  ##               standard of each message
+  # syslog_standard = "RFC5424"
+
+  ## Time zone of the BSD syslog timestamps, which have none, as "Local",
+  ## "UTC" or a name of the IANA Time Zone database such as
+  ## "America/New_York" (default = "Local").  Their year, which they have
+  ## neither, is the current year in that time zone, or the previous or the
+  ## next year around the new year if that is closer.
+  # default_timezone = "Local"
+
+  ## The time of the metrics (default = "received"):
+  ##   "received" - the time the message was received, its own timestamp
+  ##                being the timestamp field
+  ##   "message"  - the timestamp of the message, the time it was received
+  ##                being the received_at field.  Messages without a
+  ##                timestamp get the time they were received.
+  # time_source = "received"
+
+  ## Framing of the messages on stream sockets, as per RFC6587:
+  ##   "octet-counting"  - each message is preceded by its length
+  ##   "non-transparent" - each message is terminated by the trailer, the
+  ##                       default of rsyslog and syslog-ng TCP forwarding
+  ## Defaults to octet counting for RFC5424, non-transparent for RFC3164 and
+  ## to detecting the framing of each connection for auto.
+  # framing = "octet-counting"
+
+  ## Trailer of the non-transparent framing, "LF" or "NUL" (default = "LF").
+  # trailer = "LF"
+
+  ## The IP address of the sender of the messages is added as the source tag,
+  ## except for unix domain sockets.  Whether to add its port too, such as
+  ## "192.0.2.1:514" (default = false).
+  # source_port = false
+
+  ## Read the PROXY protocol header, version 1 or 2, at the start of the
+  ## connections, such as from HAProxy or an AWS network load balancer, and
+  ## use the address of the client of the proxy as the source of the
+  ## messages (default = false).  The connections without the header are
+  ## closed.  Only applies to stream sockets (e.g. TCP).
+  # proxy_protocol = false
+
+  ## CIDRs of the senders allowed, and denied, such as ["10.0.0.0/8"].  The
+  ## connections and datagrams of the senders denied, or not allowed when
+  ## allowed_sources is set, are dropped before the TLS handshake and the
+  ## parsing.  Defaults to allowing all the senders.
+  # allowed_sources = []
+  # denied_sources = []
+
+  ## Drop the messages less severe than this severity, eg., "err" keeps the
+  ## emerg, alert, crit and err messages.  The severities are the default
+  ## names of the severity tag.  Defaults to keeping all the messages.
+  # severity_filter = ""
+
+  ## Facilities of the messages to keep, as in the facility tag.  Facilities
+  ## starting with "!" are dropped instead, eg., ["!local7"] keeps all the
+  ## messages but the ones of local7.  Defaults to keeping all the messages.
+  # facility_filter = []
+
+  ## Names of the severity and facility tags replacing the default ones,
+  ## the keys being the codes or the default names, eg., "3" or "err".  The
+  ## severity_filter and facility_filter use the default names.
+  # [inputs.syslog.severity_names]
+  #   "3" = "ERROR"
+  #   warning = "WARN"
+  # [inputs.syslog.facility_names]
+  #   authpriv = "security"
+
+  ## Maximum number of messages per second accepted from all the senders,
+  ## and from each sender identified by its IP address.  The messages over
+  ## the limits are dropped, with bursts of up to one second of messages
+  ## allowed.  0 means unlimited (default = 0).
+  # max_messages_per_second = 0
+  # max_messages_per_second_per_peer = 0
+
+  ## Maximum size of the messages in bytes, without their framing.  0 means
+  ## the maximum size of an IP packet, 64KiB, over which the messages end
+  ## the connection on stream sockets (default = 0).
+  # max_message_size = 0
+
+  ## Handling of the messages over max_message_size, and of the datagrams
+  ## over max_datagram_size (default = "drop"):
+  ##   "drop"     - the messages are dropped
+  ##   "truncate" - the first max_message_size (or max_datagram_size) bytes
+  ##                of the messages are parsed in best effort mode, with the
+  ##                truncated field
+  # oversized_messages = "drop"
+
+  ## Character encoding of the messages, converted to UTF-8 before parsing:
+  ## "utf-8", "utf-16le", "utf-16be", "utf-16" (with a byte order mark),
+  ## "iso-8859-1" (or "latin1"), "iso-8859-15", "windows-1252" or "shift_jis".
+  ## Defaults to reading the messages as is.
+  # character_encoding = ""
+
   ## Whether to parse in best effort mode or not (default = false).
-  ## By default best effort parsing is off.
+  ## By default best effort parsing is off.  In best effort mode, the messages
+  ## which cannot be parsed are added with their content in the raw field.
   # best_effort = false
 
+  ## Format of the structured data (default = "fields"):
+  ##   "fields" - a field for each SD-PARAM, named as per sdparam_prefix and
+  ##              sdparam_separator
+  ##   "json"   - a single structured_data field with all the SD-ELEMENTs
+  ##              as a JSON object, eg. {"id1":{"name1":"val1"}}
+  # structured_data_format = "fields"
+
   ## Character to prepend to SD-PARAMs (default = "_").
   ## A syslog message can contain multiple parameters and multiple identifiers within structured data section.
   ## Eg., [id1 name1="val1" name2="val2"][id2 name1="val1" nameA="valA"]
   ## For each combination a field is created.
   ## Its name is created concatenating identifier, sdparam_separator, and parameter name.
   # sdparam_separator = "_"
+
+  ## Prefix of the names of the structured data fields and tags, to set
+  ## them apart from the other fields, eg. "sd_" for the sd_id1_name1 field.
+  # sdparam_prefix = ""
+
+  ## SD-IDs whose parameters are added as tags instead of fields, named as
+  ## the fields would be, eg., ["origin"] adds the origin_ip tag.  An SD-ID
+  ## without parameters is added as a tag with the "true" value.
+  # sdids_as_tags = []
+
+  ## Decode the events in the Common Event Format of ArcSight in the
+  ## messages, such as "CEF:0|Vendor|Product|1.0|100|Name|5|src=10.0.0.1",
+  ## into cef_ fields and tags (default = false).
+  # cef = false
+
+  ## CEF extension keys added as tags instead of fields, eg., ["act"] adds
+  ## the cef_act tag.
+  # cef_extensions_as_tags = []
+
+  ## Decode the events in the Log Event Extended Format of IBM QRadar, 1.0
+  ## and 2.0, in the messages, such as
+  ## "LEEF:1.0|Vendor|Product|1.0|login|src=10.0.0.1<tab>usrName=root", into
+  ## leef_ fields and tags (default = false).
+  # leef = false
+
+  ## LEEF attributes added as tags instead of fields, eg., ["cat"] adds the
+  ## leef_cat tag.
+  # leef_attributes_as_tags = []
+
+  ## Refuse new connections while the writes to all outputs are failing
+  ## (default = false).  Connections are accepted again as soon as an output
+  ## recovers.  Only applies to stream sockets (e.g. TCP).
+  # pause_on_output_failure = false
+
+  ## Tags added to the messages received by this listener, to distinguish
+  ## syslog instances.  The tags of the messages take precedence.
+  # [inputs.syslog.extra_tags]
+  #   listener = "dmz"
 `
 
 // SampleConfig returns sample configuration message
@@ -94,7 +441,7 @@ func (s *Syslog) SampleConfig() string {
 
 // Description returns the plugin description
 func (s *Syslog) Description() string {
-	return "Accepts syslog messages per RFC5425"
+	return "Accepts syslog messages per RFC5424 or RFC3164"
 }
 
 // Gather ...
@@ -107,298 +454,145 @@ func (s *Syslog) Start(acc telegraf.Accumulator) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	scheme, host, err := getAddressParts(s.Address)
+	if s.server == "" {
+		s.failed = make(chan error, 1)
+	}
+	if len(s.Servers) > 0 {
+		return s.startServers(acc)
+	}
+	if s.address == "" {
+		s.address = s.Address
+	}
+	if s.address == "" {
+		return fmt.Errorf("server or servers is required")
+	}
+
+	scheme, host, err := getAddressParts(s.address)
 	if err != nil {
 		return err
 	}
 	s.Address = host
 
-	switch scheme {
-	case "tcp", "tcp4", "tcp6", "unix", "unixpacket":
-		s.isStream = true
-	case "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unixgram":
-		s.isStream = false
-	default:
-		return fmt.Errorf("unknown protocol '%s' in '%s'", scheme, s.Address)
+	if err := s.configure(scheme); err != nil {
+		return err
 	}
-
-	if scheme == "unix" || scheme == "unixpacket" || scheme == "unixgram" {
-		os.Remove(s.Address)
+	s.registerStats()
+	if s.isUnix {
+		if err := removeStaleSocket(s.Address); err != nil {
+			return err
+		}
 	}
 
 	if s.isStream {
-		l, err := net.Listen(scheme, s.Address)
+		l, err := s.listen(scheme)
 		if err != nil {
 			return err
 		}
 		s.Closer = l
 		s.tcpListener = l
-		s.tlsConfig, err = s.TLSConfig()
-		if err != nil {
-			return err
+
+		if s.PauseOnFailure {
+			s.outputEvents = events.Subscribe(events.OutputsUnavailable, events.OutputsAvailable)
+			s.wg.Add(1)
+			go s.watchOutputs()
 		}
 
-		s.wg.Add(1)
+		s.startWorkers()
+		s.readers.Add(1)
 		go s.listenStream(acc)
 	} else {
-		l, err := net.ListenPacket(scheme, s.Address)
+		l, err := s.ListenPacket(scheme, s.Address)
 		if err != nil {
 			return err
 		}
+		if err := s.setSocketMode(); err != nil {
+			l.Close()
+			return err
+		}
 		s.Closer = l
 		s.udpListener = l
 
-		s.wg.Add(1)
-		go s.listenPacket(acc)
+		s.startWorkers()
+		s.readers.Add(1)
+		go s.listenPacket(l, acc)
 	}
 
-	if scheme == "unix" || scheme == "unixpacket" || scheme == "unixgram" {
+	if s.isUnix {
 		s.Closer = unixCloser{path: s.Address, closer: s.Closer}
 	}
 
 	return nil
 }
 
-// Stop cleans up all resources
-func (s *Syslog) Stop() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.Closer != nil {
-		s.Close()
-	}
-	s.wg.Wait()
-}
-
-// getAddressParts returns the address scheme and host
-// it also sets defaults for them when missing
-// when the input address does not specify the protocol it returns an error
-func getAddressParts(a string) (string, string, error) {
-	parts := strings.SplitN(a, "://", 2)
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("missing protocol within address '%s'", a)
-	}
-
-	u, _ := url.Parse(a)
-	switch u.Scheme {
-	case "unix", "unixpacket", "unixgram":
-		return parts[0], parts[1], nil
-	}
-
-	var host string
-	if u.Hostname() != "" {
-		host = u.Hostname()
-	}
-	host += ":"
-	if u.Port() == "" {
-		host += "6514"
-	} else {
-		host += u.Port()
-	}
-
-	return u.Scheme, host, nil
-}
-
-func (s *Syslog) listenPacket(acc telegraf.Accumulator) {
-	defer s.wg.Done()
-	b := make([]byte, ipMaxPacketSize)
-	p := rfc5424.NewParser()
-	for {
-		n, _, err := s.udpListener.ReadFrom(b)
-		if err != nil {
-			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
-				acc.AddError(err)
-			}
-			break
-		}
-
-		if s.ReadTimeout != nil && s.ReadTimeout.Duration > 0 {
-			s.udpListener.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
-		}
-
-		message, err := p.Parse(b[:n], &s.BestEffort)
-		if message != nil {
-			acc.AddFields("syslog", fields(*message, s), tags(*message), s.time())
-		}
-		if err != nil {
-			acc.AddError(err)
-		}
+// startServers starts a listener for each of the servers, or none if any of
+// them fails.
+func (s *Syslog) startServers(acc telegraf.Accumulator) error {
+	if s.Address != "" {
+		return fmt.Errorf("server and servers cannot be both set")
 	}
-}
-
-func (s *Syslog) listenStream(acc telegraf.Accumulator) {
-	defer s.wg.Done()
-
-	s.connections = map[string]net.Conn{}
-
-	for {
-		conn, err := s.tcpListener.Accept()
-		if err != nil {
-			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
-				acc.AddError(err)
-			}
-			break
-		}
-		var tcpConn, _ = conn.(*net.TCPConn)
-		if s.tlsConfig != nil {
-			conn = tls.Server(conn, s.tlsConfig)
+	for _, server := range s.Servers {
+		l := s.listener(server)
+		if err := l.Start(acc); err != nil {
+			s.stopServers()
+			return fmt.Errorf("%s: %s", server, err)
 		}
-
-		s.connectionsMu.Lock()
-		if s.MaxConnections > 0 && len(s.connections) >= s.MaxConnections {
-			s.connectionsMu.Unlock()
-			conn.Close()
-			continue
-		}
-		s.connections[conn.RemoteAddr().String()] = conn
-		s.connectionsMu.Unlock()
-
-		if err := s.setKeepAlive(tcpConn); err != nil {
-			acc.AddError(fmt.Errorf("unable to configure keep alive (%s): %s", s.Address, err))
-		}
-
-		go s.handle(conn, acc)
-	}
-
-	s.connectionsMu.Lock()
-	for _, c := range s.connections {
-		c.Close()
+		s.listeners = append(s.listeners, l)
 	}
-	s.connectionsMu.Unlock()
-}
-
-func (s *Syslog) removeConnection(c net.Conn) {
-	s.connectionsMu.Lock()
-	delete(s.connections, c.RemoteAddr().String())
-	s.connectionsMu.Unlock()
+	return nil
 }
 
-func (s *Syslog) handle(conn net.Conn, acc telegraf.Accumulator) {
-	defer func() {
-		s.removeConnection(conn)
-		conn.Close()
-	}()
-
-	if s.ReadTimeout != nil && s.ReadTimeout.Duration > 0 {
-		conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+func (s *Syslog) stopServers() {
+	for _, l := range s.listeners {
+		l.Stop()
 	}
-
-	var p *rfc5425.Parser
-	if s.BestEffort {
-		p = rfc5425.NewParser(conn, rfc5425.WithBestEffort())
-	} else {
-		p = rfc5425.NewParser(conn)
-	}
-
-	p.ParseExecuting(func(r *rfc5425.Result) {
-		s.store(*r, acc)
-	})
+	s.listeners = nil
 }
 
-func (s *Syslog) setKeepAlive(c *net.TCPConn) error {
-	if s.KeepAlivePeriod == nil {
-		return nil
+// listener returns the listener of a server, with a copy of the options.
+func (s *Syslog) listener(server string) *Syslog {
+	return &Syslog{
+		Options: s.Options,
+		Address: server,
+		server:  server,
+		now:     s.now,
+		failed:  s.failed,
 	}
-
-	if s.KeepAlivePeriod.Duration == 0 {
-		return c.SetKeepAlive(false)
-	}
-	if err := c.SetKeepAlive(true); err != nil {
-		return err
-	}
-	return c.SetKeepAlivePeriod(s.KeepAlivePeriod.Duration)
 }
 
-func (s *Syslog) store(res rfc5425.Result, acc telegraf.Accumulator) {
-	if res.Error != nil {
-		acc.AddError(res.Error)
-	}
-	if res.MessageError != nil {
-		acc.AddError(res.MessageError)
-	}
-	if res.Message != nil {
-		msg := *res.Message
-		acc.AddFields("syslog", fields(msg, s), tags(msg), s.time())
-	}
+// Failed returns the channel receiving the error which ended the listener,
+// or one of the listeners of servers, for the agent to restart the input.
+func (s *Syslog) Failed() <-chan error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
 }
 
-func tags(msg rfc5424.SyslogMessage) map[string]string {
-	ts := map[string]string{}
-
-	// Not checking assuming a minimally valid message
-	ts["severity"] = *msg.SeverityShortLevel()
-	ts["facility"] = *msg.FacilityLevel()
-
-	if msg.Hostname() != nil {
-		ts["hostname"] = *msg.Hostname()
-	}
-
-	if msg.Appname() != nil {
-		ts["appname"] = *msg.Appname()
+// fail reports the error which ended the listener.
+func (s *Syslog) fail(err error) {
+	select {
+	case s.failed <- err:
+	default:
 	}
-
-	return ts
 }
 
-func fields(msg rfc5424.SyslogMessage, s *Syslog) map[string]interface{} {
-	// Not checking assuming a minimally valid message
-	flds := map[string]interface{}{
-		"version": msg.Version(),
-	}
-	flds["severity_code"] = int(*msg.Severity())
-	flds["facility_code"] = int(*msg.Facility())
-
-	if msg.Timestamp() != nil {
-		flds["timestamp"] = (*msg.Timestamp()).UnixNano()
-	}
-
-	if msg.ProcID() != nil {
-		flds["procid"] = *msg.ProcID()
-	}
-
-	if msg.MsgID() != nil {
-		flds["msgid"] = *msg.MsgID()
-	}
+// Stop cleans up all resources
+func (s *Syslog) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if msg.Message() != nil {
-		flds["message"] = *msg.Message()
+	s.stopServers()
+	if s.Closer != nil {
+		s.Close()
 	}
-
-	if msg.StructuredData() != nil {
-		for sdid, sdparams := range *msg.StructuredData() {
-			if len(sdparams) == 0 {
-				// When SD-ID does not have params we indicate its presence with a bool
-				flds[sdid] = true
-				continue
-			}
-			for name, value := range sdparams {
-				// Using whitespace as separator since it is not allowed by the grammar within SDID
-				flds[sdid+s.Separator+name] = value
-			}
-		}
+	s.readers.Wait()
+	if s.outputEvents != nil {
+		events.Unsubscribe(s.outputEvents)
+		s.outputEvents = nil
 	}
-
-	return flds
-}
-
-type unixCloser struct {
-	path   string
-	closer io.Closer
-}
-
-func (uc unixCloser) Close() error {
-	err := uc.closer.Close()
-	os.Remove(uc.path) // ignore error
-	return err
-}
-
-func (s *Syslog) time() time.Time {
-	t := s.now()
-	if t == s.lastTime {
-		t = t.Add(time.Nanosecond)
+	if s.stopWorkers != nil {
+		s.stopWorkersOnce.Do(func() { close(s.stopWorkers) })
 	}
-	s.lastTime = t
-	return t
+	s.wg.Wait()
 }
 
 func getNanoNow() time.Time {
@@ -406,14 +600,17 @@ func getNanoNow() time.Time {
 }
 
 func init() {
-	receiver := &Syslog{
-		Address: ":6514",
-		now:     getNanoNow,
-		ReadTimeout: &internal.Duration{
-			Duration: defaultReadTimeout,
-		},
-		Separator: "_",
-	}
-
-	inputs.Add("syslog", func() telegraf.Input { return receiver })
+	inputs.Add("syslog", func() telegraf.Input {
+		return &Syslog{
+			Options: Options{
+				ReadTimeout: &internal.Duration{
+					Duration: defaultReadTimeout,
+				},
+				Separator:            "_",
+				Measurement:          defaultMeasurement,
+				TLSRequireClientCert: true,
+			},
+			now: getNanoNow,
+		}
+	})
 }
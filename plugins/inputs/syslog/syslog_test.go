@@ -1,10 +1,23 @@
 package syslog
 
 import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/events"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -58,3 +71,869 @@ func TestAddress(t *testing.T) {
 	require.Equal(t, "localhost:6514", rec.Address)
 	rec.Stop()
 }
+
+func TestAddressParts(t *testing.T) {
+	tests := []struct {
+		address string
+		scheme  string
+		host    string
+	}{
+		{"tcp://:6514", "tcp", ":6514"},
+		{"udp4://0.0.0.0", "udp4", "0.0.0.0:6514"},
+		{"tcp6://[::1]:514", "tcp6", "[::1]:514"},
+		{"udp://[fe80::1%eth0]:514", "udp", "[fe80::1%eth0]:514"},
+		{"unix:///tmp/telegraf.sock", "unix", "/tmp/telegraf.sock"},
+	}
+	for _, tt := range tests {
+		scheme, host, err := getAddressParts(tt.address)
+		require.NoError(t, err)
+		require.Equal(t, tt.scheme, scheme, tt.address)
+		require.Equal(t, tt.host, host, tt.address)
+	}
+}
+
+func TestIPv6Only(t *testing.T) {
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available")
+	}
+	l.Close()
+	if runtime.GOOS == "windows" {
+		t.Skip("ipv6_v6only is not supported on windows")
+	}
+
+	v6only := true
+	receiver := newRFC3164Receiver("", "RFC3164")
+	receiver.Servers = []string{"tcp://[::1]:0", "udp://:0"}
+	receiver.Framing = framingNonTransparent
+	receiver.IPv6Only = &v6only
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	_, port, err := net.SplitHostPort(receiver.listeners[1].udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	addresses := []string{
+		receiver.listeners[0].tcpListener.Addr().String(),
+		net.JoinHostPort("::1", port),
+	}
+	for i, network := range []string{"tcp", "udp"} {
+		conn, err := net.Dial(network, addresses[i])
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("<13>Jun 15 11:59:00 host01 app: " + network + "\n"))
+		require.NoError(t, err)
+		conn.Close()
+		acc.Wait(i + 1)
+	}
+	require.Equal(t, "::1", acc.Metrics[1].Tags["source"])
+
+	// the wildcard udp listener leaves the IPv4 port to other listeners
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:"+port)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestPauseOnOutputFailure(t *testing.T) {
+	rec := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			PauseOnFailure: true,
+		},
+		now: getNanoNow,
+	}
+	err := rec.Start(&testutil.Accumulator{})
+	require.NoError(t, err)
+	defer rec.Stop()
+	addr := rec.tcpListener.Addr().String()
+
+	// connections are closed immediately while paused
+	events.Publish(events.OutputsUnavailable, "")
+	waitPaused(t, rec, 1)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+	conn.Close()
+
+	events.Publish(events.OutputsAvailable, "")
+	waitPaused(t, rec, 0)
+
+	conn, err = net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1))
+	netErr, ok := err.(net.Error)
+	require.True(t, ok)
+	require.True(t, netErr.Timeout())
+}
+
+func waitPaused(t *testing.T, rec *Syslog, paused int32) {
+	for i := 0; i < 100; i++ {
+		if atomic.LoadInt32(&rec.paused) == paused {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("paused was not set to %d", paused)
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "syslog.sock")
+
+	// A socket file left by a previous run is replaced.
+	createStaleSocket(t, sock)
+
+	rec := &Syslog{
+		Address: "unix://" + sock,
+		Options: Options{
+			SocketMode: "0660",
+			Separator:  "_",
+		},
+		now: func() time.Time {
+			return defaultTime
+		},
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+
+	info, err := os.Stat(sock)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", sock)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("18 <1>1 - - - - - - A"))
+	require.NoError(t, err)
+	acc.Wait(1)
+	require.Equal(t, "A", acc.Metrics[0].Fields["message"])
+	conn.Close()
+
+	// The socket file is removed on stop.
+	rec.Stop()
+	_, err = os.Stat(sock)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "syslog.sock")
+
+	rec := &Syslog{
+		Address: "unix://" + sock,
+		Options: Options{
+			Framing:        framingNonTransparent,
+			MaxConnections: 1,
+			SeverityFilter: "err",
+			Separator:      "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+
+	conn, err := net.Dial("unix", sock)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<191>1 - - - - - - B\nnot syslog\n<1>1 - - - - - - A\n"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	// The connection above is the maximum.
+	rejected, err := net.Dial("unix", sock)
+	require.NoError(t, err)
+	defer rejected.Close()
+	waitStat(t, rec.connectionsRejected, 1)
+
+	_, err = conn.Write([]byte(strings.Repeat("l", ipMaxPacketSize+1) + "\n"))
+	require.NoError(t, err)
+	waitStat(t, rec.framesDropped, 1)
+
+	require.Equal(t, int64(1), rec.connectionsAccepted.Get())
+	require.Equal(t, int64(2), rec.messagesParsed.Get())
+	require.Equal(t, int64(1), rec.messagesFiltered.Get())
+	require.Equal(t, int64(1), rec.parseErrors.Get())
+}
+
+func TestMaxConnectionsUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "syslog.sock")
+
+	rec := &Syslog{
+		Address: "unix://" + sock,
+		Options: Options{
+			MaxConnections: 2,
+			Separator:      "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+
+	// The unix peers all have the same empty address.
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("unix", sock)
+		require.NoError(t, err)
+		defer conn.Close()
+		waitStat(t, rec.connectionsAccepted, int64(i+1))
+	}
+	rejected, err := net.Dial("unix", sock)
+	require.NoError(t, err)
+	defer rejected.Close()
+	waitStat(t, rec.connectionsRejected, 1)
+
+	rec.connectionsMu.Lock()
+	require.Len(t, rec.connections, 2)
+	rec.connectionsMu.Unlock()
+}
+
+func waitStat(t *testing.T, stat selfstat.Stat, value int64) {
+	for i := 0; i < 100 && stat.Get() != value; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, value, stat.Get())
+}
+
+func TestMultipleInstances(t *testing.T) {
+	f, err := ioutil.TempFile("", "syslog")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+[[inputs.syslog]]
+  server = "tcp://127.0.0.1:6514"
+  measurement = "syslog_tcp"
+  [inputs.syslog.extra_tags]
+    listener = "tcp"
+
+[[inputs.syslog]]
+  server = "udp://127.0.0.1:6514"
+`)
+	require.NoError(t, err)
+	f.Close()
+
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig(f.Name()))
+	require.Len(t, c.Inputs, 2)
+	tcp := c.Inputs[0].Input.(*Syslog)
+	udp := c.Inputs[1].Input.(*Syslog)
+	require.True(t, tcp != udp, "instances share the same plugin")
+	require.Equal(t, "tcp://127.0.0.1:6514", tcp.Address)
+	require.Equal(t, "syslog_tcp", tcp.Measurement)
+	require.Equal(t, map[string]string{"listener": "tcp"}, tcp.ExtraTags)
+	require.Equal(t, "udp://127.0.0.1:6514", udp.Address)
+	require.Equal(t, defaultMeasurement, udp.Measurement)
+	require.Empty(t, udp.ExtraTags)
+}
+
+func TestUnixSocketErrors(t *testing.T) {
+	f, err := ioutil.TempFile("", "syslog")
+	require.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	// Regular files are not removed.
+	rec := &Syslog{Address: "unixgram://" + f.Name()}
+	err = rec.Start(&testutil.Accumulator{})
+	require.EqualError(t, err, "'"+f.Name()+"' exists and is not a socket")
+	_, err = os.Stat(f.Name())
+	require.NoError(t, err)
+
+	rec = &Syslog{Address: "unixgram:///tmp/telegraf_mode.sock", Options: Options{SocketMode: "rw"}}
+	err = rec.Start(&testutil.Accumulator{})
+	require.EqualError(t, err, "invalid socket_mode 'rw'")
+}
+
+func TestSourcePort(t *testing.T) {
+	receiver := &Syslog{
+		Address: "udp://127.0.0.1:0",
+		Options: Options{
+			SourcePort: true,
+			Separator:  "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, err := net.Dial("udp", receiver.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<1>1 - - - - - -"))
+	require.NoError(t, err)
+	acc.Wait(1)
+	require.Equal(t, conn.LocalAddr().String(), acc.Metrics[0].Tags["source"])
+}
+
+func TestSDIDsAsTags(t *testing.T) {
+	s := &Syslog{Options: Options{Separator: "_", SDIDsAsTags: []string{"origin", "flag"}}}
+	bestEffort := false
+	msg, err := rfc5424.NewParser().Parse([]byte(`<29>1 - web1 app - - [origin ip="192.0.2.1"][flag][meta sequence="1"] hello`), &bestEffort)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"severity":  "notice",
+		"facility":  "daemon",
+		"hostname":  "web1",
+		"appname":   "app",
+		"origin_ip": "192.0.2.1",
+		"flag":      "true",
+	}, tags(*msg, s))
+
+	flds := fields(*msg, s)
+	require.Equal(t, "1", flds["meta_sequence"])
+	require.NotContains(t, flds, "origin_ip")
+	require.NotContains(t, flds, "flag")
+}
+
+func TestStructuredDataFormat(t *testing.T) {
+	bestEffort := false
+	msg, err := rfc5424.NewParser().Parse([]byte(`<29>1 - web1 app - - [origin ip="192.0.2.1"][flag][meta sequence="1" sysUpTime="37"] hello`), &bestEffort)
+	require.NoError(t, err)
+
+	s := &Syslog{Options: Options{Separator: ".", SDParamPrefix: "sd.", SDIDsAsTags: []string{"origin"}}}
+	require.NoError(t, s.configure("udp"))
+	require.Equal(t, "192.0.2.1", tags(*msg, s)["sd.origin.ip"])
+	flds := fields(*msg, s)
+	require.Equal(t, "1", flds["sd.meta.sequence"])
+	require.Equal(t, "37", flds["sd.meta.sysUpTime"])
+	require.Equal(t, true, flds["sd.flag"])
+
+	// The SD-IDs of sdids_as_tags are left out of the json field.
+	s = &Syslog{Options: Options{Separator: "_", SDFormat: "json", SDIDsAsTags: []string{"origin"}}}
+	require.NoError(t, s.configure("udp"))
+	require.Equal(t, "192.0.2.1", tags(*msg, s)["origin_ip"])
+	flds = fields(*msg, s)
+	require.Equal(t, `{"flag":{},"meta":{"sequence":"1","sysUpTime":"37"}}`, flds["structured_data"])
+	require.NotContains(t, flds, "meta_sequence")
+	require.NotContains(t, flds, "flag")
+
+	s.SDIDsAsTags = []string{"origin", "flag", "meta"}
+	require.NotContains(t, fields(*msg, s), "structured_data")
+}
+
+func TestMeasurementAndExtraTags(t *testing.T) {
+	s := &Syslog{
+		Options: Options{
+			Measurement: "syslog_dmz",
+			ExtraTags:   map[string]string{"listener": "dmz", "hostname": "unknown"},
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	s.addFields(acc, map[string]interface{}{"message": "hello"}, map[string]string{"hostname": "web1"}, "192.0.2.1")
+
+	acc.AssertContainsTaggedFields(t, "syslog_dmz",
+		map[string]interface{}{"message": "hello"},
+		map[string]string{"hostname": "web1", "listener": "dmz", "source": "192.0.2.1"})
+}
+
+func TestSeverityAndFacilityFilters(t *testing.T) {
+	s := &Syslog{
+		Options: Options{
+			Separator:      "_",
+			SyslogStandard: standardAuto,
+			SeverityFilter: "err",
+			FacilityFilter: []string{"!local7"},
+		},
+		now: time.Now,
+	}
+	require.NoError(t, s.configure("udp"))
+	s.registerStats()
+
+	acc := &testutil.Accumulator{}
+	p := rfc5424.NewParser()
+	for _, msg := range []string{
+		"<11>1 - web1 app - - - kept",
+		"<14>1 - web1 app - - - info",
+		"<187>1 - web1 app - - - local7",
+		"<3>Dec  3 14:23:01 web1 kernel: kept",
+		"<6>Dec  3 14:23:01 web1 kernel: info",
+		"<186>Dec  3 14:23:01 web1 app: local7",
+	} {
+		s.parseMessage(p, []byte(msg), "", false, acc)
+	}
+
+	require.Len(t, acc.Metrics, 2)
+	for _, m := range acc.Metrics {
+		require.Equal(t, "kept", m.Fields["message"])
+		require.Equal(t, "err", m.Tags["severity"])
+	}
+
+	priorities, err := priorityFilter("", []string{"auth", "authpriv"})
+	require.NoError(t, err)
+	require.True(t, priorities[4*8+7])
+	require.True(t, priorities[10*8])
+	require.False(t, priorities[1*8])
+}
+
+func TestTimeSource(t *testing.T) {
+	received := time.Date(2018, time.June, 15, 12, 0, 0, 0, time.UTC)
+	timestamp := time.Date(2018, time.June, 15, 11, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		timeSource string
+		time       time.Time
+		receivedAt interface{}
+	}{
+		{"", received, nil},
+		{"received", received, nil},
+		{"message", timestamp, received.UnixNano()},
+	} {
+		s := &Syslog{
+			Options: Options{
+				Separator:  "_",
+				TimeSource: tt.timeSource,
+			},
+			now: func() time.Time { return received },
+		}
+		require.NoError(t, s.configure("udp"))
+		s.registerStats()
+
+		acc := &testutil.Accumulator{}
+		s.parseMessage(rfc5424.NewParser(), []byte("<13>1 2018-06-15T11:00:00Z web1 app - - - hello"), "", false, acc)
+		require.Len(t, acc.Metrics, 1)
+		require.Equal(t, tt.time.UnixNano(), acc.Metrics[0].Time.UnixNano(), tt.timeSource)
+		require.Equal(t, timestamp.UnixNano(), acc.Metrics[0].Fields["timestamp"])
+		require.Equal(t, tt.receivedAt, acc.Metrics[0].Fields["received_at"], tt.timeSource)
+	}
+
+	// Messages without a timestamp get the time they were received.
+	s := &Syslog{
+		Options: Options{
+			Separator:  "_",
+			TimeSource: "message",
+		},
+		now: func() time.Time { return received },
+	}
+	require.NoError(t, s.configure("udp"))
+	s.registerStats()
+	acc := &testutil.Accumulator{}
+	s.parseMessage(rfc5424.NewParser(), []byte("<13>1 - web1 app - - - hello"), "", false, acc)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, received.UnixNano(), acc.Metrics[0].Time.UnixNano())
+	require.Equal(t, received.UnixNano(), acc.Metrics[0].Fields["received_at"])
+
+	s = &Syslog{Options: Options{Separator: "_", TimeSource: "device"}}
+	require.EqualError(t, s.configure("udp"), `unknown time_source "device"`)
+}
+
+func TestSeverityAndFacilityNames(t *testing.T) {
+	s := &Syslog{
+		Options: Options{
+			Separator:      "_",
+			SyslogStandard: standardAuto,
+			SeverityFilter: "warning",
+			SeverityNames:  map[string]string{"3": "ERROR", "warning": "WARN"},
+			FacilityNames:  map[string]string{"AUTHPRIV": "security"},
+		},
+		now: time.Now,
+	}
+	require.NoError(t, s.configure("udp"))
+	s.registerStats()
+
+	acc := &testutil.Accumulator{}
+	p := rfc5424.NewParser()
+	for _, msg := range []string{
+		"<83>1 - web1 sshd - - - denied",
+		"<12>Dec  3 14:23:01 web1 kernel: low memory",
+		"<10>Dec  3 14:23:01 web1 kernel: failure",
+	} {
+		s.parseMessage(p, []byte(msg), "", false, acc)
+	}
+
+	require.Len(t, acc.Metrics, 3)
+	require.Equal(t, "ERROR", acc.Metrics[0].Tags["severity"])
+	require.Equal(t, "security", acc.Metrics[0].Tags["facility"])
+	require.Equal(t, 3, acc.Metrics[0].Fields["severity_code"])
+	require.Equal(t, "WARN", acc.Metrics[1].Tags["severity"])
+	require.Equal(t, "user", acc.Metrics[1].Tags["facility"])
+	require.Equal(t, "crit", acc.Metrics[2].Tags["severity"])
+
+	for _, tt := range []struct {
+		syslog *Syslog
+		err    string
+	}{
+		{&Syslog{Options: Options{SeverityNames: map[string]string{"8": "TRACE"}}}, `unknown key "8" in severity_names`},
+		{&Syslog{Options: Options{SeverityNames: map[string]string{"error": "ERROR"}}}, `unknown key "error" in severity_names`},
+		{&Syslog{Options: Options{FacilityNames: map[string]string{"23": "local"}}}, ""},
+		{&Syslog{Options: Options{FacilityNames: map[string]string{"24": "local"}}}, `unknown key "24" in facility_names`},
+		{&Syslog{Options: Options{FacilityNames: map[string]string{"kern": ""}}}, `empty name for "kern" in facility_names`},
+	} {
+		tt.syslog.Separator = "_"
+		err := tt.syslog.configure("udp")
+		if tt.err == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, tt.err)
+		}
+	}
+}
+
+// errorPacketConn fails to read.
+type errorPacketConn struct {
+	net.PacketConn
+}
+
+func (c errorPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	return 0, nil, errors.New("read: connection reset by peer")
+}
+
+func TestFailedAndRestart(t *testing.T) {
+	rec := newUDPSyslogReceiver("udp://127.0.0.1:0", false)
+	rec.ParseWorkers = 1
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+
+	// The listener fails on the read error.
+	rec.readers.Add(1)
+	go rec.listenPacket(errorPacketConn{rec.udpListener}, acc)
+	select {
+	case err := <-rec.Failed():
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("failure not reported")
+	}
+	rec.Stop()
+
+	// The listener is restarted as configured.
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+	conn, err := net.Dial("udp", rec.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<1>1 - - - - - -"))
+	require.NoError(t, err)
+	acc.Wait(1)
+	require.Equal(t, "alert", acc.Metrics[0].Tags["severity"])
+	select {
+	case err := <-rec.Failed():
+		t.Fatalf("unexpected failure: %s", err)
+	default:
+	}
+}
+
+func TestIdleDatagrams(t *testing.T) {
+	rec := newUDPSyslogReceiver("udp://127.0.0.1:0", false)
+	rec.ReadTimeout = &internal.Duration{Duration: 20 * time.Millisecond}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+
+	conn, err := net.Dial("udp", rec.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<1>1 - - - - - -"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	// The listener stays idle past the read timeout.
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-rec.Failed():
+		t.Fatalf("unexpected failure: %s", err)
+	default:
+	}
+	_, err = conn.Write([]byte("<2>1 - - - - - -"))
+	require.NoError(t, err)
+	acc.Wait(2)
+	require.Equal(t, "crit", acc.Metrics[1].Tags["severity"])
+	require.Empty(t, acc.Errors)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	rec := newTCPSyslogReceiver("tcp://127.0.0.1:0", nil, 0, false)
+	rec.ReadTimeout = nil
+	rec.ShutdownTimeout = &internal.Duration{Duration: 5 * time.Second}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	addr := rec.tcpListener.Addr().String()
+	accepted := rec.connectionsAccepted.Get()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("16 <1>1 - - "))
+	require.NoError(t, err)
+	waitStat(t, rec.connectionsAccepted, accepted+1)
+
+	stopped := make(chan struct{})
+	go func() {
+		rec.Stop()
+		close(stopped)
+	}()
+
+	// New connections are refused while the open one is drained.
+	for i := 0; i < 100; i++ {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		c.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err = net.Dial("tcp", addr)
+	require.Error(t, err)
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the connection ended")
+	default:
+	}
+
+	_, err = conn.Write([]byte("- - - -"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("not stopped once the connection ended")
+	}
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "alert", acc.Metrics[0].Tags["severity"])
+}
+
+func TestShutdownTimeoutExpired(t *testing.T) {
+	rec := newTCPSyslogReceiver("tcp://127.0.0.1:0", nil, 0, false)
+	rec.ReadTimeout = nil
+	rec.ShutdownTimeout = &internal.Duration{Duration: 100 * time.Millisecond}
+	require.NoError(t, rec.Start(&testutil.Accumulator{}))
+	accepted := rec.connectionsAccepted.Get()
+
+	conn, err := net.Dial("tcp", rec.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	waitStat(t, rec.connectionsAccepted, accepted+1)
+
+	start := time.Now()
+	rec.Stop()
+	require.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	// The connection left open is closed.
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+}
+
+func TestBestEffortRaw(t *testing.T) {
+	rec := newTCPSyslogReceiver("tcp://127.0.0.1:0", nil, 0, true)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+
+	conn, err := net.Dial("tcp", rec.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("11 hello world16 <1>1 - - - - - -"))
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	require.Equal(t, map[string]interface{}{"raw": "hello world"}, acc.Metrics[0].Fields)
+	require.Equal(t, map[string]string{
+		"parse_error": "expecting a priority value within angle brackets [col 0]",
+		"source":      "127.0.0.1",
+	}, acc.Metrics[0].Tags)
+	require.NotContains(t, acc.Metrics[1].Fields, "raw")
+	require.Len(t, acc.Errors, 1)
+
+	// The messages are kept whole only in best effort mode.
+	rec = newTCPSyslogReceiver("udp://127.0.0.1:0", nil, 0, false)
+	acc = &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+
+	conn, err = net.Dial("udp", rec.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello world"))
+	require.NoError(t, err)
+	acc.WaitError(1)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestCharacterEncoding(t *testing.T) {
+	rec := newRFC3164Receiver("tcp://127.0.0.1:0", "RFC5424")
+	rec.Framing = framingNonTransparent
+	rec.CharacterEncoding = "utf-16le"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+
+	conn, err := net.Dial("tcp", rec.tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var data []byte
+	for _, r := range "\ufeff<14>1 - host01 - - - - caf\u00e9\r\n<14>1 - host02 - - - - second\n" {
+		data = append(data, byte(r), byte(r>>8))
+	}
+	_, err = conn.Write(data)
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	require.Equal(t, "café", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "host02", acc.Metrics[1].Tags["hostname"])
+
+	rec = newRFC3164Receiver("udp://127.0.0.1:0", "RFC3164")
+	rec.CharacterEncoding = "latin1"
+	acc = &testutil.Accumulator{}
+	require.NoError(t, rec.Start(acc))
+	defer rec.Stop()
+
+	conn, err = net.Dial("udp", rec.udpListener.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<13>Jun 15 11:59:00 host01 app: caf\xe9"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	require.Equal(t, "café", acc.Metrics[0].Fields["message"])
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		syslog *Syslog
+		err    string
+	}{
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{Framing: framingNonTransparent, Trailer: "NUL"}}, ""},
+		{&Syslog{Address: "unixgram:///tmp/telegraf.sock", Options: Options{SocketMode: "0660"}}, ""},
+		{&Syslog{Address: "localhost:6514"}, "missing protocol within address 'localhost:6514'"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{Framing: "newline"}}, `unknown framing "newline"`},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{Trailer: "LF"}}, "framing and trailer only apply to stream sockets"},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{MaxConnections: 10}}, "max_connections, keep_alive_period and pause_on_output_failure only apply to stream sockets"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{Framing: framingOctetCounting, Trailer: "LF"}}, "trailer only applies to the non-transparent framing"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{SocketMode: "0660"}}, "socket_mode only applies to unix domain sockets"},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{SeverityFilter: "error"}}, `unknown severity_filter "error"`},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{FacilityFilter: []string{"!local8"}}}, `unknown facility "!local8" in facility_filter`},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{MaxRatePerPeer: -1}}, "max_messages_per_second and max_messages_per_second_per_peer cannot be negative"},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{MaxMessageSize: 1024, OversizedMessages: "truncate"}}, ""},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{MaxMessageSize: -1}}, "max_message_size cannot be negative"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{MaxMessageSize: 1024, OversizedMessages: "split"}}, `unknown oversized_messages "split"`},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{OversizedMessages: "drop"}}, "oversized_messages requires max_message_size or max_datagram_size"},
+		{&Syslog{Address: "unixgram:///tmp/telegraf.sock", Options: Options{MaxDatagramSize: 256 * 1024, OversizedMessages: "truncate"}}, ""},
+		{&Syslog{Address: "unixgram:///tmp/telegraf.sock", Options: Options{MaxDatagramSize: -1}}, "max_datagram_size cannot be negative"},
+		{&Syslog{Address: "unix:///tmp/telegraf.sock", Options: Options{MaxDatagramSize: 1024}}, "max_datagram_size only applies to datagram sockets"},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{CharacterEncoding: "ebcdic"}}, `unsupported character_encoding "ebcdic"`},
+		{&Syslog{Address: "relp://127.0.0.1:2514", Options: Options{MaxMessageSize: 1024}}, ""},
+		{&Syslog{Address: "relp://127.0.0.1:2514", Options: Options{Framing: framingNonTransparent}}, "framing and trailer do not apply to RELP"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{ProxyProtocol: true}}, ""},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{ProxyProtocol: true}}, "proxy_protocol only applies to stream sockets"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Options: Options{ShutdownTimeout: &internal.Duration{Duration: time.Second}}}, ""},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{ShutdownTimeout: &internal.Duration{Duration: time.Second}}}, "shutdown_timeout only applies to stream sockets"},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{AllowedSources: []string{"10.0.0.0/8"}}}, ""},
+		{&Syslog{Address: "unix:///tmp/telegraf.sock", Options: Options{DeniedSources: []string{"10.0.0.0/8"}}}, "allowed_sources and denied_sources do not apply to unix domain sockets"},
+		{&Syslog{Address: "unix:///tmp/telegraf.sock", Options: Options{DeniedSources: []string{"10.0.0.0/8"}, ProxyProtocol: true}}, ""},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{ParseWorkers: 4, QueueSize: 100}}, ""},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{QueueSize: 100}}, "queue_size requires parse_workers"},
+		{&Syslog{Address: "udp://127.0.0.1:6514", Options: Options{ParseWorkers: -1}}, "parse_workers and queue_size cannot be negative"},
+		{&Syslog{Address: "dtls://127.0.0.1:6514"}, "dtls requires tls_cert and tls_key"},
+		{&Syslog{}, "server or servers is required"},
+		{&Syslog{Address: "udp://:514", Options: Options{SDFormat: "JSON"}}, ""},
+		{&Syslog{Address: "udp://:514", Options: Options{SDFormat: "nested"}}, `unknown structured_data_format "nested"`},
+		{&Syslog{Address: "udp://:514", Options: Options{SDFormat: "json", SDParamPrefix: "sd_"}}, `sdparam_prefix does not apply to structured_data_format = "json"`},
+		{&Syslog{Address: "udp://:514", Options: Options{CEF: true, CEFAsTags: []string{"act"}}}, ""},
+		{&Syslog{Address: "udp://:514", Options: Options{CEFAsTags: []string{"act"}}}, "cef_extensions_as_tags requires cef"},
+		{&Syslog{Address: "udp://:514", Options: Options{LEEFAsTags: []string{"cat"}}}, "leef_attributes_as_tags requires leef"},
+		{&Syslog{Address: "udp://:514", Servers: []string{"tcp://:6514"}}, "server and servers cannot be both set"},
+		{&Syslog{Servers: []string{"tcp://:6514", "udp://:514"}, Options: Options{Framing: framingNonTransparent, MaxDatagramSize: 1024}}, ""},
+		{&Syslog{Servers: []string{"tcp://:6514", "udp://:514"}, Options: Options{SocketMode: "0660"}}, "socket_mode only applies to unix domain sockets"},
+		{&Syslog{Servers: []string{"tcp://:6514", "dtls://:6514"}}, "dtls://:6514: dtls requires tls_cert and tls_key"},
+		{&Syslog{Servers: []string{"tcp://:6514", ":514"}}, "missing protocol within address ':514'"},
+	}
+	for _, tt := range tests {
+		err := tt.syslog.Validate()
+		if tt.err == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, tt.err)
+		}
+	}
+}
+
+// addSourceTag adds the source tag of the test clients to the tags expected
+// on IP sockets, the test cases being shared with unix domain sockets.
+func addSourceTag(protocol string, tags map[string]string) {
+	if !strings.HasPrefix(protocol, "unix") {
+		tags["source"] = "127.0.0.1"
+	}
+}
+
+// createStaleSocket leaves a socket file at path, as after a crash.
+func createStaleSocket(t *testing.T, path string) {
+	os.Remove(path)
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	l.SetUnlinkOnClose(false)
+	l.Close()
+}
+
+func TestServers(t *testing.T) {
+	sockname := "/tmp/telegraf_test.sock"
+	createStaleSocket(t, sockname)
+	receiver := newRFC3164Receiver("", "RFC3164")
+	receiver.Servers = []string{"tcp://127.0.0.1:0", "udp://127.0.0.1:0", "unixgram://" + sockname}
+	receiver.Framing = framingNonTransparent
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+	require.Len(t, receiver.listeners, 3)
+	// Each listener has a copy of the options, with its own address.
+	for i, l := range receiver.listeners {
+		require.Equal(t, receiver.Options, l.Options)
+		require.Equal(t, receiver.Servers[i], l.server)
+		require.Nil(t, l.Servers)
+	}
+
+	addresses := []string{
+		receiver.listeners[0].tcpListener.Addr().String(),
+		receiver.listeners[1].udpListener.LocalAddr().String(),
+		sockname,
+	}
+	for i, network := range []string{"tcp", "udp", "unixgram"} {
+		conn, err := net.Dial(network, addresses[i])
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("<13>Jun 15 11:59:00 host01 app: " + network + "\n"))
+		require.NoError(t, err)
+		conn.Close()
+		acc.Wait(i + 1)
+	}
+
+	// The options are shared by the listeners, such as the framing which
+	// only applies to stream sockets.
+	for i, server := range receiver.Servers {
+		require.Equal(t, server, acc.Metrics[i].Tags["server"])
+		require.Equal(t, "app", acc.Metrics[i].Tags["appname"])
+	}
+	require.Equal(t, "tcp", acc.Metrics[0].Fields["message"])
+	require.Equal(t, "udp", acc.Metrics[1].Fields["message"])
+	require.Equal(t, "unixgram", acc.Metrics[2].Fields["message"])
+	require.Equal(t, "127.0.0.1", acc.Metrics[1].Tags["source"])
+	require.NotContains(t, acc.Metrics[2].Tags, "source")
+
+	receiver.Stop()
+	require.Nil(t, receiver.listeners)
+	_, err := os.Stat(sockname)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestServersError(t *testing.T) {
+	receiver := newRFC3164Receiver("", "RFC3164")
+	receiver.Servers = []string{"udp://127.0.0.1:0", "unsupported://example.com:6514"}
+	err := receiver.Start(&testutil.Accumulator{})
+	require.EqualError(t, err, "unsupported://example.com:6514: unknown protocol 'unsupported' in 'example.com:6514'")
+	require.Nil(t, receiver.listeners)
+
+	receiver = newRFC3164Receiver("udp://127.0.0.1:0", "RFC3164")
+	receiver.Servers = []string{"tcp://127.0.0.1:0"}
+	require.EqualError(t, receiver.Start(&testutil.Accumulator{}), "server and servers cannot be both set")
+}
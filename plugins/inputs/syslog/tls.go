@@ -0,0 +1,172 @@
+package syslog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+)
+
+// attributeNames are the names of the attributes of distinguished names, as
+// per RFC4514#section-3.
+var attributeNames = map[string]string{
+	"2.5.4.3":                    "CN",
+	"2.5.4.5":                    "SERIALNUMBER",
+	"2.5.4.6":                    "C",
+	"2.5.4.7":                    "L",
+	"2.5.4.8":                    "ST",
+	"2.5.4.9":                    "STREET",
+	"2.5.4.10":                   "O",
+	"2.5.4.11":                   "OU",
+	"2.5.4.17":                   "postalCode",
+	"0.9.2342.19200300.100.1.1":  "UID",
+	"0.9.2342.19200300.100.1.25": "DC",
+	"1.2.840.113549.1.9.1":       "emailAddress",
+}
+
+// serverTLSConfig returns the TLS configuration of the listener, with the
+// verification of the client certificates, or nil if TLS is not configured.
+func (s *Syslog) serverTLSConfig() (*tls.Config, error) {
+	config, err := s.TLSConfig()
+	if err != nil || config == nil {
+		return config, err
+	}
+	if len(s.TLSAllowedCACerts) > 0 && !s.TLSRequireClientCert {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if len(s.AllowedClientDNs) > 0 || len(s.AllowedClientCNs) > 0 {
+		config.VerifyPeerCertificate = s.verifyClient
+	}
+
+	minVersion, maxVersion, suites, err := s.tlsVersionsAndCiphers()
+	if err != nil {
+		return nil, err
+	}
+	if minVersion > config.MinVersion {
+		config.MinVersion = minVersion
+	}
+	config.MaxVersion = maxVersion
+	if suites != nil {
+		config.CipherSuites = suites
+		config.PreferServerCipherSuites = true
+	}
+	// The policy of the agent takes precedence.
+	tlsint.ApplyPolicy(config)
+	return config, nil
+}
+
+// tlsVersionsAndCiphers returns the minimum and maximum TLS versions and the
+// cipher suites of the options, zero or nil when not set.
+func (s *Syslog) tlsVersionsAndCiphers() (uint16, uint16, []uint16, error) {
+	var minVersion, maxVersion uint16
+	var err error
+	if s.TLSMinVersion != "" {
+		if minVersion, err = tlsint.ParseVersion(s.TLSMinVersion); err != nil {
+			return 0, 0, nil, fmt.Errorf("tls_min_version: %s", err)
+		}
+	}
+	if s.TLSMaxVersion != "" {
+		if maxVersion, err = tlsint.ParseVersion(s.TLSMaxVersion); err != nil {
+			return 0, 0, nil, fmt.Errorf("tls_max_version: %s", err)
+		}
+		if maxVersion < minVersion {
+			return 0, 0, nil, fmt.Errorf("tls_max_version is lower than tls_min_version")
+		}
+	}
+
+	var suites []uint16
+	if len(s.TLSCipherSuites) > 0 {
+		if suites, err = tlsint.ParseCipherSuites(s.TLSCipherSuites); err != nil {
+			return 0, 0, nil, fmt.Errorf("tls_cipher_suites: %s", err)
+		}
+	}
+	return minVersion, maxVersion, suites, nil
+}
+
+// verifyClient checks that the subject of the client certificate is one of
+// the allowed ones.  The certificate chain is already verified.
+func (s *Syslog) verifyClient(_ [][]byte, chains [][]*x509.Certificate) error {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		s.tlsClientsRejected.Incr(1)
+		return fmt.Errorf("no client certificate")
+	}
+	cert := chains[0][0]
+
+	for _, cn := range s.AllowedClientCNs {
+		if cert.Subject.CommonName == cn {
+			return nil
+		}
+	}
+	dn, err := distinguishedName(cert.RawSubject)
+	if err != nil {
+		s.tlsClientsRejected.Incr(1)
+		return err
+	}
+	for _, allowed := range s.AllowedClientDNs {
+		if dn == allowed {
+			return nil
+		}
+	}
+	s.tlsClientsRejected.Incr(1)
+	return fmt.Errorf("client certificate %q not allowed", dn)
+}
+
+// peerAccumulator adds the peer_identity tag to the messages of a DTLS
+// association authenticated by a client certificate.
+type peerAccumulator struct {
+	telegraf.Accumulator
+	identity string
+}
+
+func (a peerAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	tags["peer_identity"] = a.identity
+	a.Accumulator.AddFields(measurement, fields, tags, t...)
+}
+
+// distinguishedName returns the string representation of an ASN.1 encoded
+// distinguished name as per RFC4514, such as "CN=client,O=Example,C=US".
+func distinguishedName(raw []byte) (string, error) {
+	var rdns pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(raw, &rdns); err != nil {
+		return "", err
+	} else if len(rest) > 0 {
+		return "", fmt.Errorf("trailing data after the distinguished name")
+	}
+
+	parts := make([]string, 0, len(rdns))
+	for i := len(rdns) - 1; i >= 0; i-- {
+		attrs := make([]string, 0, len(rdns[i]))
+		for _, attr := range rdns[i] {
+			name, ok := attributeNames[attr.Type.String()]
+			if !ok {
+				name = attr.Type.String()
+			}
+			attrs = append(attrs, name+"="+escapeAttribute(fmt.Sprint(attr.Value)))
+		}
+		parts = append(parts, strings.Join(attrs, "+"))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// escapeAttribute escapes the special characters of an attribute value as
+// per RFC4514#section-2.4.
+func escapeAttribute(value string) string {
+	var b bytes.Buffer
+	for i, r := range value {
+		switch {
+		case strings.ContainsRune(`,+"\<>;`, r),
+			i == 0 && (r == ' ' || r == '#'),
+			i == len(value)-1 && r == ' ':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,187 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistinguishedName(t *testing.T) {
+	rdns := pkix.RDNSequence{
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 6}, Value: "US"}},
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 10}, Value: "Example, Inc."}},
+		{
+			{Type: asn1.ObjectIdentifier{2, 5, 4, 11}, Value: "Ops"},
+			{Type: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: " x"},
+		},
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: "relay1"}},
+	}
+	raw, err := asn1.Marshal(rdns)
+	require.NoError(t, err)
+
+	dn, err := distinguishedName(raw)
+	require.NoError(t, err)
+	require.Equal(t, `CN=relay1,1.2.3.4=\ x+OU=Ops,O=Example\, Inc.,C=US`, dn)
+
+	_, err = distinguishedName([]byte("not a name"))
+	require.Error(t, err)
+}
+
+func TestAllowedClients(t *testing.T) {
+	tests := []struct {
+		name    string
+		dns     []string
+		cns     []string
+		allowed bool
+	}{
+		{name: "any certificate", allowed: true},
+		{name: "allowed DN", dns: []string{"CN=relay1", "CN=client.localdomain"}, allowed: true},
+		{name: "allowed CN", cns: []string{"client.localdomain"}, allowed: true},
+		{name: "not allowed", dns: []string{"CN=relay1"}, cns: []string{"relay2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &Syslog{
+				Address: "tcp://127.0.0.1:0",
+				Options: Options{
+					ServerConfig:         *pki.TLSServerConfig(),
+					TLSRequireClientCert: true,
+					AllowedClientDNs:     tt.dns,
+					AllowedClientCNs:     tt.cns,
+					Separator:            "_",
+				},
+				now: time.Now,
+			}
+			acc := &testutil.Accumulator{}
+			require.NoError(t, receiver.Start(acc))
+			defer receiver.Stop()
+			rejected := receiver.tlsClientsRejected.Get()
+
+			config, err := pki.TLSClientConfig().TLSConfig()
+			require.NoError(t, err)
+			config.ServerName = "localhost"
+			conn, err := tls.Dial("tcp", receiver.tcpListener.Addr().String(), config)
+			if err == nil {
+				defer conn.Close()
+				_, err = conn.Write([]byte("22 <1>1 - - - - - - hello"))
+			}
+
+			if tt.allowed {
+				require.NoError(t, err)
+				acc.Wait(1)
+				require.Equal(t, "hello", acc.Metrics[0].Fields["message"])
+			} else {
+				// With TLS 1.3, the handshake of the client may complete
+				// before the server rejects its certificate.
+				waitStat(t, receiver.tlsClientsRejected, rejected+1)
+				require.Equal(t, 0, len(acc.Metrics))
+			}
+		})
+	}
+}
+
+func TestOptionalClientCert(t *testing.T) {
+	receiver := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			ServerConfig: *pki.TLSServerConfig(),
+			Separator:    "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	config, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	config.ServerName = "localhost"
+	config.Certificates = nil
+	conn, err := tls.Dial("tcp", receiver.tcpListener.Addr().String(), config)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("22 <1>1 - - - - - - hello"))
+	require.NoError(t, err)
+	acc.Wait(1)
+}
+
+func TestAllowedClientsConfig(t *testing.T) {
+	receiver := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			AllowedClientCNs: []string{"relay1"},
+		},
+	}
+	require.EqualError(t, receiver.Validate(), "allowed_client_dns and allowed_client_cns require tls_allowed_cacerts and tls_require_client_cert")
+
+	receiver.ServerConfig = *pki.TLSServerConfig()
+	require.Error(t, receiver.Validate())
+	receiver.TLSRequireClientCert = true
+	require.NoError(t, receiver.Validate())
+
+	receiver.Address = "udp://127.0.0.1:0"
+	require.EqualError(t, receiver.Validate(), "TLS only applies to stream sockets")
+}
+
+func TestTLSVersionsAndCiphers(t *testing.T) {
+	receiver := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			ServerConfig:    *pki.TLSServerConfig(),
+			TLSMinVersion:   "TLS12",
+			TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+			Separator:       "_",
+		},
+		now: time.Now,
+	}
+	config, err := receiver.serverTLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	require.Equal(t, uint16(0), config.MaxVersion)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, config.CipherSuites)
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	client, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	client.ServerName = "localhost"
+	client.MaxVersion = tls.VersionTLS11
+	_, err = tls.Dial("tcp", receiver.tcpListener.Addr().String(), client)
+	require.Error(t, err)
+
+	client.MaxVersion = tls.VersionTLS12
+	client.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	_, err = tls.Dial("tcp", receiver.tcpListener.Addr().String(), client)
+	require.Error(t, err)
+
+	client.CipherSuites = nil
+	conn, err := tls.Dial("tcp", receiver.tcpListener.Addr().String(), client)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, conn.ConnectionState().CipherSuite)
+}
+
+func TestTLSVersionsConfig(t *testing.T) {
+	receiver := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			TLSMinVersion: "TLS12",
+			TLSMaxVersion: "TLS11",
+		},
+	}
+	require.EqualError(t, receiver.Validate(), "tls_max_version is lower than tls_min_version")
+
+	receiver.TLSMaxVersion = "SSL3"
+	require.EqualError(t, receiver.Validate(), `tls_max_version: unsupported TLS version "SSL3"`)
+
+	receiver.TLSMaxVersion = ""
+	receiver.TLSCipherSuites = []string{"TLS_RSA_WITH_NULL_SHA"}
+	require.EqualError(t, receiver.Validate(), `tls_cipher_suites: unsupported cipher suite "TLS_RSA_WITH_NULL_SHA"`)
+}
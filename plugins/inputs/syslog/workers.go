@@ -0,0 +1,129 @@
+package syslog
+
+import (
+	"sync"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/telegraf"
+)
+
+const defaultQueueSize = 1000
+
+// queueMode is how a reader queues its messages when the parse queue is
+// full.
+type queueMode int
+
+const (
+	// queueDrop drops the message, for the datagrams.
+	queueDrop queueMode = iota
+	// queueBlock waits for room in the queue, so that the reads of the
+	// connection pause.
+	queueBlock
+	// queueWait waits for room in the queue and for the message to be
+	// parsed, for the acknowledgements of RELP.
+	queueWait
+)
+
+// parseJob is a message queued for the parse workers.
+type parseJob struct {
+	data      []byte
+	source    string
+	truncated bool
+	acc       telegraf.Accumulator
+	// done receives whether the message is kept once parsed, with
+	// queueWait.
+	done chan bool
+}
+
+// startWorkers starts the parse workers, if any.
+func (s *Syslog) startWorkers() {
+	if s.ParseWorkers == 0 {
+		return
+	}
+	size := s.QueueSize
+	if size == 0 {
+		size = defaultQueueSize
+	}
+	s.queue = make(chan parseJob, size)
+	s.stopWorkers = make(chan struct{})
+	s.stopWorkersOnce = sync.Once{}
+	for i := 0; i < s.ParseWorkers; i++ {
+		s.wg.Add(1)
+		go s.parseWorker()
+	}
+}
+
+// parseWorker parses the queued messages until the listener stops, and then
+// the messages left in the queue.
+func (s *Syslog) parseWorker() {
+	defer s.wg.Done()
+
+	p := rfc5424.NewParser()
+	for {
+		select {
+		case job := <-s.queue:
+			s.parseJob(p, job)
+		case <-s.stopWorkers:
+			for {
+				select {
+				case job := <-s.queue:
+					s.parseJob(p, job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Syslog) parseJob(p *rfc5424.Parser, job parseJob) {
+	kept := s.parseMessage(p, job.data, job.source, job.truncated, job.acc)
+	if job.done != nil {
+		job.done <- kept
+	}
+}
+
+// dispatch parses a message with the parser of the reader, or queues it for
+// the parse workers when parse_workers is set.  The data is copied, the
+// readers reusing their buffers.  It returns false if the message is
+// dropped, by the rate limits, because the queue is full or because the
+// listener stops; with queueBlock and queueDrop, it returns once queued.
+func (s *Syslog) dispatch(p *rfc5424.Parser, data []byte, source string, truncated bool, acc telegraf.Accumulator, mode queueMode) bool {
+	if s.queue == nil {
+		return s.parseMessage(p, data, source, truncated, acc)
+	}
+
+	job := parseJob{
+		data:      append([]byte(nil), data...),
+		source:    source,
+		truncated: truncated,
+		acc:       acc,
+	}
+	if mode == queueWait {
+		job.done = make(chan bool, 1)
+	}
+	select {
+	case s.queue <- job:
+	default:
+		if mode == queueDrop {
+			s.messagesDropped.Incr(1)
+			return false
+		}
+		s.queueBlocked.Incr(1)
+		select {
+		case s.queue <- job:
+		case <-s.stopWorkers:
+			s.messagesDropped.Incr(1)
+			return false
+		}
+	}
+	if job.done != nil {
+		select {
+		case kept := <-job.done:
+			return kept
+		case <-s.stopWorkers:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package syslog
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkers(t *testing.T) {
+	receiver := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Options: Options{
+			ParseWorkers: 2,
+			QueueSize:    1,
+			Separator:    "_",
+		},
+		now: time.Now,
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+	require.Equal(t, 1, cap(receiver.queue))
+
+	var conns []net.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", receiver.tcpListener.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	for i := 0; i < 10; i++ {
+		for j, conn := range conns {
+			msg := "<1>1 - - - - - - " + strconv.Itoa(j) + "-" + strconv.Itoa(i)
+			_, err := conn.Write([]byte(strconv.Itoa(len(msg)) + " " + msg))
+			require.NoError(t, err)
+		}
+	}
+	acc.Wait(30)
+
+	messages := make(map[string]bool)
+	for _, m := range acc.Metrics {
+		messages[m.Fields["message"].(string)] = true
+	}
+	require.Len(t, messages, 30)
+	require.Empty(t, acc.Errors)
+}
+
+func TestRELPParseWorkers(t *testing.T) {
+	receiver := newRFC3164Receiver("relp://127.0.0.1:0", "RFC5424")
+	receiver.ParseWorkers = 1
+	acc := &testutil.Accumulator{}
+	require.NoError(t, receiver.Start(acc))
+	defer receiver.Stop()
+
+	conn, response := relpSession(t, receiver)
+	defer conn.Close()
+
+	require.NoError(t, writeRELP(conn, 1, relpOpen, "commands=syslog"))
+	require.Equal(t, 1, response().txnr)
+	require.NoError(t, writeRELP(conn, 2, relpSyslog, "<1>1 - host01 - - - - first"))
+	require.Equal(t, relpFrame{2, relpResponse, []byte("200 OK")}, response())
+	// The messages are parsed by the workers when they are acknowledged.
+	acc.Lock()
+	require.Len(t, acc.Metrics, 1)
+	acc.Unlock()
+}
+
+func TestQueueFull(t *testing.T) {
+	receiver := &Syslog{Address: "queue-full"}
+	receiver.registerStats()
+	receiver.queue = make(chan parseJob, 1)
+	receiver.stopWorkers = make(chan struct{})
+	dropped := receiver.messagesDropped.Get()
+	blocked := receiver.queueBlocked.Get()
+	acc := &testutil.Accumulator{}
+
+	data := []byte("<1>1 - - - - - - first")
+	receiver.dispatch(nil, data, "", false, acc, queueDrop)
+	// The data is copied, the readers reusing their buffers.
+	data[len(data)-1] = 'x'
+	require.Equal(t, "<1>1 - - - - - - first", string((<-receiver.queue).data))
+
+	require.True(t, receiver.dispatch(nil, data, "", false, acc, queueDrop))
+	require.False(t, receiver.dispatch(nil, data, "", false, acc, queueDrop))
+	require.Equal(t, dropped+1, receiver.messagesDropped.Get())
+
+	done := make(chan struct{})
+	go func() {
+		receiver.dispatch(nil, data, "", false, acc, queueBlock)
+		close(done)
+	}()
+	waitStat(t, receiver.queueBlocked, blocked+1)
+	select {
+	case <-done:
+		t.Fatal("the message was queued in a full queue")
+	default:
+	}
+	<-receiver.queue
+	<-done
+	require.Len(t, receiver.queue, 1)
+
+	// The readers waiting for room in the queue stop with the listener.
+	done = make(chan struct{})
+	go func() {
+		receiver.dispatch(nil, data, "", false, acc, queueBlock)
+		close(done)
+	}()
+	waitStat(t, receiver.queueBlocked, blocked+2)
+	close(receiver.stopWorkers)
+	<-done
+	require.Equal(t, dropped+2, receiver.messagesDropped.Get())
+
+	// The RELP messages waiting to be parsed are not acknowledged once the
+	// listener stops.
+	<-receiver.queue
+	require.False(t, receiver.dispatch(nil, data, "", false, acc, queueWait))
+}
@@ -73,6 +73,10 @@ func (*Sysstat) Description() string {
 	return "Sysstat metrics collector"
 }
 
+func (*Sysstat) Dependencies() []string {
+	return []string{"sadc", "sadf"}
+}
+
 var sampleConfig = `
   ## Path to the sadc command.
   #
@@ -0,0 +1,96 @@
+// +build linux
+
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// hugepagesFields are the fields of the huge page counters of
+// /proc/meminfo.
+var hugepagesFields = map[string]string{
+	"HugePages_Total": "total",
+	"HugePages_Free":  "free",
+	"HugePages_Rsvd":  "reserved",
+	"HugePages_Surp":  "surplus",
+	"Hugepagesize":    "size_bytes",
+	"Hugetlb":         "hugetlb_bytes",
+	"AnonHugePages":   "anon_bytes",
+	"ShmemHugePages":  "shmem_bytes",
+	"FileHugePages":   "file_bytes",
+}
+
+type Hugepages struct {
+	meminfoFile string
+}
+
+func (h *Hugepages) Description() string {
+	return "Get huge page statistics from /proc/meminfo"
+}
+
+func (h *Hugepages) SampleConfig() string {
+	return ""
+}
+
+func (h *Hugepages) Gather(acc telegraf.Accumulator) error {
+	data, err := ioutil.ReadFile(h.meminfoFile)
+	if err != nil {
+		return err
+	}
+	meminfo, err := parseMeminfo(data)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	for key, name := range hugepagesFields {
+		if value, ok := meminfo[key]; ok {
+			fields[name] = value
+		}
+	}
+	acc.AddFields("hugepages", fields, nil)
+	return nil
+}
+
+// parseMeminfo parses the lines of /proc/meminfo, or of the meminfo files
+// of the NUMA nodes which start with the node, such as:
+//
+//	Node 0 MemTotal:       16318600 kB
+//
+// The values in kB are converted to bytes.
+func parseMeminfo(data []byte) (map[string]int64, error) {
+	meminfo := make(map[string]int64)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		f := strings.Fields(string(line))
+		if len(f) > 2 && f[0] == "Node" {
+			f = f[2:]
+		}
+		if len(f) < 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if len(f) > 2 && f[2] == "kB" {
+			value *= 1024
+		}
+		meminfo[strings.TrimSuffix(f[0], ":")] = value
+	}
+	return meminfo, nil
+}
+
+func init() {
+	inputs.Add("hugepages", func() telegraf.Input {
+		return &Hugepages{
+			meminfoFile: path.Join(GetHostProc(), "meminfo"),
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// +build linux
+
+package system
+
+import (
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const meminfoFile = `MemTotal:       32657348 kB
+MemFree:         1213620 kB
+MemAvailable:   20134452 kB
+Buffers:          798824 kB
+Cached:         17392312 kB
+Slab:            1732708 kB
+SReclaimable:    1477328 kB
+SUnreclaim:       255380 kB
+AnonHugePages:   2048000 kB
+ShmemHugePages:        0 kB
+ShmemPmdMapped:        0 kB
+HugePages_Total:     512
+HugePages_Free:      384
+HugePages_Rsvd:       16
+HugePages_Surp:        0
+Hugepagesize:       2048 kB
+Hugetlb:         1048576 kB
+DirectMap4k:      687532 kB
+`
+
+func TestHugepages(t *testing.T) {
+	tmpfile := makeFakeStatFile([]byte(meminfoFile))
+	defer os.Remove(tmpfile)
+
+	h := Hugepages{meminfoFile: tmpfile}
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, h.Gather(&acc))
+
+	acc.AssertContainsFields(t, "hugepages", map[string]interface{}{
+		"total":         int64(512),
+		"free":          int64(384),
+		"reserved":      int64(16),
+		"surplus":       int64(0),
+		"size_bytes":    int64(2097152),
+		"hugetlb_bytes": int64(1073741824),
+		"anon_bytes":    int64(2097152000),
+		"shmem_bytes":   int64(0),
+	})
+}
+
+func TestHugepagesMissingFile(t *testing.T) {
+	h := Hugepages{meminfoFile: "/nonexistent/meminfo"}
+
+	acc := testutil.Accumulator{}
+	require.Error(t, h.Gather(&acc))
+}
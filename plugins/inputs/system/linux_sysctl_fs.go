@@ -90,6 +90,14 @@ func GetHostProc() string {
 	return procPath
 }
 
+func GetHostSys() string {
+	sysPath := "/sys"
+	if os.Getenv("HOST_SYS") != "" {
+		sysPath = os.Getenv("HOST_SYS")
+	}
+	return sysPath
+}
+
 func init() {
 
 	inputs.Add("linux_sysctl_fs", func() telegraf.Input {
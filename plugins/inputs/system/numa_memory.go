@@ -0,0 +1,101 @@
+// +build linux
+
+package system
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// numaMeminfoFields are the fields of the meminfo file of the NUMA nodes.
+var numaMeminfoFields = map[string]string{
+	"MemTotal":        "total",
+	"MemFree":         "free",
+	"MemUsed":         "used",
+	"Active":          "active",
+	"Inactive":        "inactive",
+	"FilePages":       "file_pages",
+	"AnonPages":       "anon_pages",
+	"Shmem":           "shmem",
+	"Dirty":           "dirty",
+	"Writeback":       "writeback",
+	"KernelStack":     "kernel_stack",
+	"PageTables":      "page_tables",
+	"Slab":            "slab",
+	"SReclaimable":    "slab_reclaimable",
+	"SUnreclaim":      "slab_unreclaimable",
+	"HugePages_Total": "hugepages_total",
+	"HugePages_Free":  "hugepages_free",
+	"HugePages_Surp":  "hugepages_surplus",
+}
+
+type NumaMemory struct {
+	nodePath string
+}
+
+func (n *NumaMemory) Description() string {
+	return "Get memory statistics of each NUMA node from /sys/devices/system/node"
+}
+
+func (n *NumaMemory) SampleConfig() string {
+	return ""
+}
+
+func (n *NumaMemory) Gather(acc telegraf.Accumulator) error {
+	nodes, err := filepath.Glob(filepath.Join(n.nodePath, "node[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range nodes {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "meminfo"))
+		if err != nil {
+			return err
+		}
+		meminfo, err := parseMeminfo(data)
+		if err != nil {
+			return err
+		}
+
+		fields := make(map[string]interface{})
+		for key, name := range numaMeminfoFields {
+			if value, ok := meminfo[key]; ok {
+				fields[name] = value
+			}
+		}
+
+		// The allocation counters of the node, such as numa_hit and
+		// numa_miss, one per line.
+		if data, err := ioutil.ReadFile(filepath.Join(dir, "numastat")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				f := strings.Fields(line)
+				if len(f) != 2 {
+					continue
+				}
+				if value, err := strconv.ParseInt(f[1], 10, 64); err == nil {
+					fields[f[0]] = value
+				}
+			}
+		}
+
+		tags := map[string]string{
+			"node": strings.TrimPrefix(filepath.Base(dir), "node"),
+		}
+		acc.AddFields("numa_memory", fields, tags)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("numa_memory", func() telegraf.Input {
+		return &NumaMemory{
+			nodePath: path.Join(GetHostSys(), "devices/system/node"),
+		}
+	})
+}
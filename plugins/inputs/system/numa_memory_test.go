@@ -0,0 +1,88 @@
+// +build linux
+
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const nodeMeminfoFile = `Node 1 MemTotal:       16450692 kB
+Node 1 MemFree:          611420 kB
+Node 1 MemUsed:        15839272 kB
+Node 1 Active:          9265664 kB
+Node 1 Inactive:        5224988 kB
+Node 1 Dirty:               112 kB
+Node 1 Writeback:             0 kB
+Node 1 FilePages:      12103724 kB
+Node 1 Mapped:           403308 kB
+Node 1 AnonPages:       2372120 kB
+Node 1 Shmem:             81324 kB
+Node 1 KernelStack:        8128 kB
+Node 1 PageTables:        21200 kB
+Node 1 Slab:             859476 kB
+Node 1 SReclaimable:     734024 kB
+Node 1 SUnreclaim:       125452 kB
+Node 1 HugePages_Total:   256
+Node 1 HugePages_Free:    192
+Node 1 HugePages_Surp:      0
+`
+
+const nodeNumastatFile = `numa_hit 2130943856
+numa_miss 1402
+numa_foreign 31
+interleave_hit 27813
+local_node 2130886543
+other_node 58715
+`
+
+func TestNumaMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "numatest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	node := filepath.Join(dir, "node1")
+	require.NoError(t, os.MkdirAll(node, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(node, "meminfo"), []byte(nodeMeminfoFile), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(node, "numastat"), []byte(nodeNumastatFile), 0644))
+	// Not a node.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "power"), 0755))
+
+	n := NumaMemory{nodePath: dir}
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "numa_memory", map[string]interface{}{
+		"total":              int64(16845508608),
+		"free":               int64(626094080),
+		"used":               int64(16219414528),
+		"active":             int64(9488039936),
+		"inactive":           int64(5350387712),
+		"dirty":              int64(114688),
+		"writeback":          int64(0),
+		"file_pages":         int64(12394213376),
+		"anon_pages":         int64(2429050880),
+		"shmem":              int64(83275776),
+		"kernel_stack":       int64(8323072),
+		"page_tables":        int64(21708800),
+		"slab":               int64(880103424),
+		"slab_reclaimable":   int64(751640576),
+		"slab_unreclaimable": int64(128462848),
+		"hugepages_total":    int64(256),
+		"hugepages_free":     int64(192),
+		"hugepages_surplus":  int64(0),
+		"numa_hit":           int64(2130943856),
+		"numa_miss":          int64(1402),
+		"numa_foreign":       int64(31),
+		"interleave_hit":     int64(27813),
+		"local_node":         int64(2130886543),
+		"other_node":         int64(58715),
+	}, map[string]string{"node": "1"})
+}
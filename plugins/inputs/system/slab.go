@@ -0,0 +1,138 @@
+// +build linux
+
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// slabFields are the fields of the columns of /proc/slabinfo, after the
+// name of the cache.
+var slabFields = []string{"active_objects", "objects", "object_size_bytes", "objects_per_slab", "pages_per_slab"}
+
+type Slab struct {
+	Top     int  `toml:"top"`
+	UseSudo bool `toml:"use_sudo"`
+
+	slabinfoFile string
+	readSlabinfo func(file string, useSudo bool) ([]byte, error)
+}
+
+var slabSampleConfig = `
+  ## Number of slab caches reported, the largest ones by memory usage.
+  ## 0 reports all the caches.
+  # top = 10
+
+  ## /proc/slabinfo is only readable by root.  Setting 'use_sudo' to true
+  ## will make use of sudo to read it.  Sudo must be configured to allow the
+  ## telegraf user to run "cat /proc/slabinfo" without password.
+  # use_sudo = false
+`
+
+func (s *Slab) Description() string {
+	return "Get the largest kernel slab caches from /proc/slabinfo"
+}
+
+func (s *Slab) SampleConfig() string {
+	return slabSampleConfig
+}
+
+type slabCache struct {
+	name   string
+	size   int64
+	fields map[string]interface{}
+}
+
+func (s *Slab) Gather(acc telegraf.Accumulator) error {
+	data, err := s.readSlabinfo(s.slabinfoFile, s.UseSudo)
+	if err != nil {
+		return err
+	}
+	caches, err := parseSlabinfo(data)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(caches, func(i, j int) bool {
+		if caches[i].size != caches[j].size {
+			return caches[i].size > caches[j].size
+		}
+		return caches[i].name < caches[j].name
+	})
+	if s.Top > 0 && len(caches) > s.Top {
+		caches = caches[:s.Top]
+	}
+	for _, c := range caches {
+		acc.AddFields("slab", c.fields, map[string]string{"name": c.name})
+	}
+	return nil
+}
+
+// parseSlabinfo parses the caches of /proc/slabinfo version 2.1:
+//
+//	# name <active_objs> <num_objs> <objsize> <objperslab> <pagesperslab> : tunables <limit> <batchcount> <sharedfactor> : slabdata <active_slabs> <num_slabs> <sharedavail>
+func parseSlabinfo(data []byte) ([]slabCache, error) {
+	var caches []slabCache
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "slabinfo") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) < 16 || f[6] != ":" || f[11] != ":" {
+			return nil, fmt.Errorf("unexpected slabinfo line: %q", line)
+		}
+
+		c := slabCache{name: f[0], fields: make(map[string]interface{})}
+		for i, name := range slabFields {
+			value, err := strconv.ParseInt(f[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing slabinfo line %q: %s", line, err)
+			}
+			c.fields[name] = value
+		}
+		for i, name := range []string{"active_slabs", "slabs"} {
+			value, err := strconv.ParseInt(f[i+13], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing slabinfo line %q: %s", line, err)
+			}
+			c.fields[name] = value
+		}
+		c.size = c.fields["objects"].(int64) * c.fields["object_size_bytes"].(int64)
+		c.fields["size_bytes"] = c.size
+		caches = append(caches, c)
+	}
+	return caches, nil
+}
+
+func readSlabinfo(file string, useSudo bool) ([]byte, error) {
+	if !useSudo {
+		return ioutil.ReadFile(file)
+	}
+	out, err := exec.Command("sudo", "-n", "cat", file).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command sudo -n cat %s: %s", file, err)
+	}
+	return out, nil
+}
+
+func init() {
+	inputs.Add("slab", func() telegraf.Input {
+		return &Slab{
+			Top:          10,
+			slabinfoFile: path.Join(GetHostProc(), "slabinfo"),
+			readSlabinfo: readSlabinfo,
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// +build linux
+
+package system
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const slabinfoFile = `slabinfo - version: 2.1
+# name            <active_objs> <num_objs> <objsize> <objperslab> <pagesperslab> : tunables <limit> <batchcount> <sharedfactor> : slabdata <active_slabs> <num_slabs> <sharedavail>
+ext4_inode_cache  276536 281490   1096   29    8 : tunables    0    0    0 : slabdata   9707   9707      0
+dentry            845397 868014    192   21    1 : tunables    0    0    0 : slabdata  41334  41334      0
+kmalloc-64         93728  95616     64   64    1 : tunables    0    0    0 : slabdata   1494   1494      0
+radix_tree_node   102446 106680    584   28    4 : tunables    0    0    0 : slabdata   3810   3810      0
+`
+
+func TestSlab(t *testing.T) {
+	s := Slab{
+		Top: 2,
+		readSlabinfo: func(file string, useSudo bool) ([]byte, error) {
+			return []byte(slabinfoFile), nil
+		},
+	}
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "slab", map[string]interface{}{
+		"active_objects":    int64(276536),
+		"objects":           int64(281490),
+		"object_size_bytes": int64(1096),
+		"objects_per_slab":  int64(29),
+		"pages_per_slab":    int64(8),
+		"active_slabs":      int64(9707),
+		"slabs":             int64(9707),
+		"size_bytes":        int64(308513040),
+	}, map[string]string{"name": "ext4_inode_cache"})
+	acc.AssertContainsTaggedFields(t, "slab", map[string]interface{}{
+		"active_objects":    int64(845397),
+		"objects":           int64(868014),
+		"object_size_bytes": int64(192),
+		"objects_per_slab":  int64(21),
+		"pages_per_slab":    int64(1),
+		"active_slabs":      int64(41334),
+		"slabs":             int64(41334),
+		"size_bytes":        int64(166658688),
+	}, map[string]string{"name": "dentry"})
+
+	s.Top = 0
+	acc.ClearMetrics()
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Metrics, 4)
+}
+
+func TestSlabErrors(t *testing.T) {
+	s := Slab{
+		readSlabinfo: func(file string, useSudo bool) ([]byte, error) {
+			return nil, fmt.Errorf("open /proc/slabinfo: permission denied")
+		},
+	}
+	acc := testutil.Accumulator{}
+	require.EqualError(t, s.Gather(&acc), "open /proc/slabinfo: permission denied")
+
+	s.readSlabinfo = func(file string, useSudo bool) ([]byte, error) {
+		return []byte("slabinfo - version: 2.1\nkmalloc-64 93728 95616\n"), nil
+	}
+	require.Error(t, s.Gather(&acc))
+}
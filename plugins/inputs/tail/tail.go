@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/tail"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/encoding"
 	"github.com/influxdata/telegraf/internal/globpath"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
@@ -25,7 +26,11 @@ type Tail struct {
 	Pipe          bool
 	WatchMethod   string
 
+	CharacterEncoding string
+
 	tailers []*tail.Tail
+	decoder *encoding.Decoder
+	offsets map[string]int64
 	parser  parsers.Parser
 	wg      sync.WaitGroup
 	acc     telegraf.Accumulator
@@ -36,6 +41,7 @@ type Tail struct {
 func NewTail() *Tail {
 	return &Tail{
 		FromBeginning: false,
+		offsets:       make(map[string]int64),
 	}
 }
 
@@ -58,6 +64,12 @@ const sampleConfig = `
   ## Method used to watch for file updates.  Can be either "inotify" or "poll".
   # watch_method = "inotify"
 
+  ## Character encoding of the files, converted to UTF-8 before parsing:
+  ## "utf-8", "iso-8859-1" (or "latin1"), "iso-8859-15", "windows-1252" or
+  ## "shift_jis".  UTF-16 is not supported.  Defaults to reading the files
+  ## as is.
+  # character_encoding = ""
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -83,6 +95,12 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 
 	t.acc = acc
 
+	decoder, err := encoding.NewLineDecoder(t.CharacterEncoding)
+	if err != nil {
+		return err
+	}
+	t.decoder = decoder
+
 	var seek *tail.SeekInfo
 	if !t.Pipe && !t.FromBeginning {
 		seek = &tail.SeekInfo{
@@ -103,11 +121,20 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 			t.acc.AddError(fmt.Errorf("E! Error Glob %s failed to compile, %s", filepath, err))
 		}
 		for file, _ := range g.Match() {
+			// Resume from the saved offset of the file, if any.
+			location := seek
+			if offset, ok := t.offsets[file]; ok && !t.Pipe {
+				location = &tail.SeekInfo{
+					Whence: 0,
+					Offset: offset,
+				}
+			}
+
 			tailer, err := tail.TailFile(file,
 				tail.Config{
 					ReOpen:    true,
 					Follow:    true,
-					Location:  seek,
+					Location:  location,
 					MustExist: true,
 					Poll:      poll,
 					Pipe:      t.Pipe,
@@ -141,8 +168,13 @@ func (t *Tail) receiver(tailer *tail.Tail) {
 				tailer.Filename, err))
 			continue
 		}
+		decoded, err := t.decoder.Line([]byte(line.Text))
+		if err != nil {
+			t.acc.AddError(fmt.Errorf("E! Error decoding a line of %s: %s", tailer.Filename, err))
+			continue
+		}
 		// Fix up files with Windows line endings.
-		text := strings.TrimRight(line.Text, "\r")
+		text := strings.TrimRight(string(decoded), "\r")
 
 		m, err = t.parser.ParseLine(text)
 		if err == nil {
@@ -163,6 +195,15 @@ func (t *Tail) Stop() {
 	defer t.Unlock()
 
 	for _, tailer := range t.tailers {
+		if !t.Pipe {
+			// Record the offset so that tailing can be resumed after a
+			// restart when the state is persisted.
+			offset, err := tailer.Tell()
+			if err == nil {
+				t.offsets[tailer.Filename] = offset
+			}
+		}
+
 		err := tailer.Stop()
 		if err != nil {
 			t.acc.AddError(fmt.Errorf("E! Error stopping tail on file %s\n", tailer.Filename))
@@ -172,6 +213,33 @@ func (t *Tail) Stop() {
 	t.wg.Wait()
 }
 
+// GetState returns the offsets of the tailed files, keyed by filename.
+func (t *Tail) GetState() interface{} {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.offsets
+}
+
+// SetState restores the file offsets returned by GetState.
+func (t *Tail) SetState(state interface{}) error {
+	t.Lock()
+	defer t.Unlock()
+
+	offsets, ok := state.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid state type %T", state)
+	}
+	for file, value := range offsets {
+		offset, ok := value.(float64)
+		if !ok || offset < 0 {
+			return fmt.Errorf("invalid offset %v for file %s", value, file)
+		}
+		t.offsets[file] = int64(offset)
+	}
+	return nil
+}
+
 func (t *Tail) SetParser(parser parsers.Parser) {
 	t.parser = parser
 }
@@ -141,3 +141,92 @@ func TestTailDosLineendings(t *testing.T) {
 			"usage_idle": float64(200),
 		})
 }
+
+func TestTailCharacterEncoding(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	// "cpu,host=café usage_idle=100\r\ncpu2 usage_idle=200\r\n" in
+	// Windows-1252.
+	_, err = tmpfile.WriteString("cpu,host=caf\xe9 usage_idle=100\r\ncpu2 usage_idle=200\r\n")
+	require.NoError(t, err)
+
+	tt := NewTail()
+	tt.FromBeginning = true
+	tt.Files = []string{tmpfile.Name()}
+	tt.CharacterEncoding = "windows-1252"
+	p, _ := parsers.NewInfluxParser()
+	tt.SetParser(p)
+	defer tt.Stop()
+	defer tmpfile.Close()
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, tt.Start(&acc))
+
+	acc.Wait(2)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{
+			"usage_idle": float64(100),
+		},
+		map[string]string{
+			"host": "café",
+		})
+	acc.AssertContainsFields(t, "cpu2",
+		map[string]interface{}{
+			"usage_idle": float64(200),
+		})
+}
+
+func TestTailCharacterEncodingInvalid(t *testing.T) {
+	tt := NewTail()
+	tt.CharacterEncoding = "ebcdic"
+	require.EqualError(t, tt.Start(&testutil.Accumulator{}), `unsupported character_encoding "ebcdic"`)
+
+	tt.CharacterEncoding = "utf-16le"
+	require.EqualError(t, tt.Start(&testutil.Accumulator{}),
+		`character_encoding "utf-16le" is not supported, the lines are split before being decoded`)
+}
+
+func TestTailResumeFromState(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	defer tmpfile.Close()
+	_, err = tmpfile.WriteString("cpu usage_idle=100\ncpu2 usage_idle=200\n")
+	require.NoError(t, err)
+
+	tt := NewTail()
+	tt.FromBeginning = true
+	tt.Files = []string{tmpfile.Name()}
+	p, _ := parsers.NewInfluxParser()
+	tt.SetParser(p)
+
+	// The state is restored from decoded JSON, so offsets are float64.
+	err = tt.SetState(map[string]interface{}{
+		tmpfile.Name(): float64(len("cpu usage_idle=100\n")),
+	})
+	require.NoError(t, err)
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, tt.Start(&acc))
+
+	acc.Wait(1)
+	tt.Stop()
+
+	acc.AssertContainsFields(t, "cpu2",
+		map[string]interface{}{
+			"usage_idle": float64(200),
+		})
+	assert.Len(t, acc.Metrics, 1)
+
+	state, ok := tt.GetState().(map[string]int64)
+	require.True(t, ok)
+	require.Equal(t, int64(len("cpu usage_idle=100\ncpu2 usage_idle=200\n")),
+		state[tmpfile.Name()])
+}
+
+func TestTailSetStateInvalid(t *testing.T) {
+	tt := NewTail()
+	require.Error(t, tt.SetState("invalid"))
+	require.Error(t, tt.SetState(map[string]interface{}{"/var/log/syslog": "42"}))
+}
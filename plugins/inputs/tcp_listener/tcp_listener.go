@@ -71,6 +71,10 @@ func (t *TcpListener) Description() string {
 	return "Generic TCP listener"
 }
 
+func (t *TcpListener) Deprecated() (string, string) {
+	return "1.3", "use the socket_listener input"
+}
+
 // All the work is done in the Start() function, so this is just a dummy
 // function.
 func (t *TcpListener) Gather(_ telegraf.Accumulator) error {
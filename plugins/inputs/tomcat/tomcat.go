@@ -10,7 +10,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -63,8 +63,7 @@ type Tomcat struct {
 	URL      string
 	Username string
 	Password string
-	Timeout  internal.Duration
-	tls.ClientConfig
+	httpconfig.HTTPClientConfig
 
 	client  *http.Client
 	request *http.Request
@@ -87,6 +86,15 @@ var sampleconfig = `
   # tls_key = "/etc/telegraf/key.pem"
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+
+  ## Optional HTTP headers and cookies
+  # headers = {"X-Special-Header" = "Special-Value"}
+  # cookies = {"session" = "value"}
+
+  ## Idle connections kept open for the next requests, per host, and time
+  ## before closing them (default = 2 and 0, unlimited).
+  # max_idle_conn_per_host = 2
+  # idle_conn_timeout = "0s"
 `
 
 func (s *Tomcat) Description() string {
@@ -99,7 +107,7 @@ func (s *Tomcat) SampleConfig() string {
 
 func (s *Tomcat) Gather(acc telegraf.Accumulator) error {
 	if s.client == nil {
-		client, err := s.createHttpClient()
+		client, err := s.CreateClient("inputs.tomcat")
 		if err != nil {
 			return err
 		}
@@ -116,6 +124,7 @@ func (s *Tomcat) Gather(acc telegraf.Accumulator) error {
 			return err
 		}
 		request.SetBasicAuth(s.Username, s.Password)
+		s.PrepareRequest(request)
 		s.request = request
 	}
 
@@ -187,29 +196,15 @@ func (s *Tomcat) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
-func (s *Tomcat) createHttpClient() (*http.Client, error) {
-	tlsConfig, err := s.ClientConfig.TLSConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
-		},
-		Timeout: s.Timeout.Duration,
-	}
-
-	return client, nil
-}
-
 func init() {
 	inputs.Add("tomcat", func() telegraf.Input {
 		return &Tomcat{
 			URL:      "http://127.0.0.1:8080/manager/status/all?XML=true",
 			Username: "tomcat",
 			Password: "s3cret",
-			Timeout:  internal.Duration{Duration: 5 * time.Second},
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
 		}
 	})
 }
@@ -80,6 +80,10 @@ func (u *UdpListener) Description() string {
 	return "Generic UDP listener"
 }
 
+func (u *UdpListener) Deprecated() (string, string) {
+	return "1.3", "use the socket_listener input"
+}
+
 // All the work is done in the Start() function, so this is just a dummy
 // function.
 func (u *UdpListener) Gather(_ telegraf.Accumulator) error {
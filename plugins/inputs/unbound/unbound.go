@@ -61,6 +61,10 @@ func (s *Unbound) Description() string {
 	return "A plugin to collect stats from the Unbound DNS resolver"
 }
 
+func (s *Unbound) Dependencies() []string {
+	return []string{"unbound-control"}
+}
+
 // SampleConfig displays configuration instructions
 func (s *Unbound) SampleConfig() string {
 	return sampleConfig
@@ -0,0 +1,255 @@
+package upsd
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultAddress = "127.0.0.1:3493"
+
+// Upsd reads the variables of UPSes from Network UPS Tools servers.
+type Upsd struct {
+	Servers []string          `toml:"servers"`
+	Ups     []string          `toml:"ups"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+var sampleConfig = `
+  ## A list of upsd servers to connect to, with host and port.
+  ## If not provided will default to 127.0.0.1:3493
+  servers = ["127.0.0.1:3493"]
+
+  ## Names of the UPSes to gather, by default all UPSes of the servers are
+  ## gathered.
+  # ups = ["myups"]
+
+  ## Timeout for dialing and reading from each server.
+  timeout = "5s"
+`
+
+func (u *Upsd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (u *Upsd) Description() string {
+	return "Monitor UPSes connected to Network UPS Tools"
+}
+
+// fields maps the standard variables of NUT to field names.  Counters,
+// durations in seconds and powers are integers, other values are floats.
+var fields = map[string]struct {
+	name    string
+	integer bool
+}{
+	"battery.charge":        {"battery_charge_percent", false},
+	"battery.runtime":       {"battery_runtime_seconds", true},
+	"battery.voltage":       {"battery_voltage", false},
+	"input.voltage":         {"input_voltage", false},
+	"input.frequency":       {"input_frequency", false},
+	"output.voltage":        {"output_voltage", false},
+	"ups.load":              {"load_percent", false},
+	"ups.temperature":       {"internal_temp", false},
+	"ups.realpower.nominal": {"nominal_power", true},
+}
+
+func (u *Upsd) Gather(acc telegraf.Accumulator) error {
+	servers := u.Servers
+	if len(servers) == 0 {
+		servers = []string{defaultAddress}
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			if err := u.gatherServer(acc, server); err != nil {
+				acc.AddError(fmt.Errorf("[server=%s]: %s", server, err))
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (u *Upsd) gatherServer(acc telegraf.Accumulator, server string) error {
+	conn, err := net.DialTimeout("tcp", server, u.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(u.Timeout.Duration))
+	c := textproto.NewConn(conn)
+
+	names := u.Ups
+	if len(names) == 0 {
+		upses, err := list(c, "UPS")
+		if err != nil {
+			return err
+		}
+		for _, ups := range upses {
+			names = append(names, ups[0])
+		}
+	}
+
+	for _, name := range names {
+		vars, err := list(c, "VAR", name)
+		if err != nil {
+			acc.AddError(fmt.Errorf("[server=%s]: ups %s: %s", server, name, err))
+			continue
+		}
+
+		values := make(map[string]string)
+		for _, v := range vars {
+			values[v[0]] = v[1]
+		}
+		gatherUps(acc, server, name, values)
+	}
+	return nil
+}
+
+func gatherUps(acc telegraf.Accumulator, server, name string, values map[string]string) {
+	tags := map[string]string{
+		"server":   server,
+		"ups_name": name,
+	}
+	if model := first(values, "device.model", "ups.model"); model != "" {
+		tags["model"] = model
+	}
+	if serial := first(values, "device.serial", "ups.serial"); serial != "" {
+		tags["serial"] = serial
+	}
+	if status := values["ups.status"]; status != "" {
+		tags["status"] = status
+	}
+	metrics := make(map[string]interface{})
+	for variable, field := range fields {
+		value, ok := values[variable]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		if field.integer {
+			metrics[field.name] = int64(v)
+		} else {
+			metrics[field.name] = v
+		}
+	}
+	acc.AddFields("upsd", metrics, tags)
+}
+
+// list sends a LIST command and returns the arguments of the items of the
+// list, following the name of the list, with quoted arguments unquoted:
+//
+//	LIST VAR myups
+//	BEGIN LIST VAR myups
+//	VAR myups battery.charge "100"
+//	END LIST VAR myups
+func list(c *textproto.Conn, args ...string) ([][]string, error) {
+	query := strings.Join(args, " ")
+	if err := c.PrintfLine("LIST %s", query); err != nil {
+		return nil, err
+	}
+
+	line, err := c.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(line, "ERR ") {
+		return nil, fmt.Errorf("LIST %s: %s", query, strings.TrimPrefix(line, "ERR "))
+	}
+	if line != "BEGIN LIST "+query {
+		return nil, fmt.Errorf("LIST %s: unexpected response %q", query, line)
+	}
+
+	prefix := args[0] + " "
+	if len(args) > 1 {
+		prefix += strings.Join(args[1:], " ") + " "
+	}
+	var items [][]string
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END LIST "+query {
+			return items, nil
+		}
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		item, err := splitArgs(strings.TrimPrefix(line, prefix))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// splitArgs splits a line on spaces, except in quoted arguments.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	for line != "" {
+		if line[0] != '"' {
+			i := strings.IndexByte(line, ' ')
+			if i < 0 {
+				i = len(line)
+			}
+			args = append(args, line[:i])
+			line = strings.TrimLeft(line[i:], " ")
+			continue
+		}
+
+		// Find the closing quote, skipping escaped characters.
+		end := -1
+		for i := 1; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+			} else if line[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated quote in %q", line)
+		}
+		arg, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		line = strings.TrimLeft(line[end+1:], " ")
+	}
+	return args, nil
+}
+
+// first returns the first non-empty value of the variables.
+func first(values map[string]string, variables ...string) string {
+	for _, variable := range variables {
+		if v := values[variable]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func init() {
+	inputs.Add("upsd", func() telegraf.Input {
+		return &Upsd{
+			Servers: []string{defaultAddress},
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
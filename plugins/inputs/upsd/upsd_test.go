@@ -0,0 +1,154 @@
+package upsd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var responses = map[string]string{
+	"LIST UPS": `BEGIN LIST UPS
+UPS rack-a "APC Smart-UPS 1500"
+UPS rack-b "Eaton 5E"
+END LIST UPS
+`,
+	"LIST VAR rack-a": `BEGIN LIST VAR rack-a
+VAR rack-a battery.charge "100"
+VAR rack-a battery.runtime "3480"
+VAR rack-a battery.voltage "27.10"
+VAR rack-a device.model "Smart-UPS 1500"
+VAR rack-a device.serial "AS1234567890"
+VAR rack-a input.voltage "231.8"
+VAR rack-a input.frequency "50.0"
+VAR rack-a output.voltage "231.8"
+VAR rack-a ups.load "17.0"
+VAR rack-a ups.realpower.nominal "1000"
+VAR rack-a ups.status "OL CHRG"
+VAR rack-a ups.temperature "29.2"
+VAR rack-a ups.mfr "American Power Conversion"
+END LIST VAR rack-a
+`,
+	"LIST VAR rack-b": `BEGIN LIST VAR rack-b
+VAR rack-b battery.charge "42"
+VAR rack-b battery.runtime "600.00"
+VAR rack-b input.voltage "0.0"
+VAR rack-b ups.model "Eaton \"5E\""
+VAR rack-b ups.status "OB DISCHRG"
+END LIST VAR rack-b
+`,
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			resp, ok := responses[scanner.Text()]
+			if !ok {
+				resp = "ERR UNKNOWN-UPS\n"
+			}
+			fmt.Fprint(conn, resp)
+		}
+	}()
+	return l
+}
+
+func TestGather(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+
+	u := &Upsd{
+		Servers: []string{l.Addr().String()},
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(u.Gather))
+
+	acc.AssertContainsTaggedFields(t, "upsd",
+		map[string]interface{}{
+			"battery_charge_percent":  100.0,
+			"battery_runtime_seconds": int64(3480),
+			"battery_voltage":         27.1,
+			"input_voltage":           231.8,
+			"input_frequency":         50.0,
+			"output_voltage":          231.8,
+			"load_percent":            17.0,
+			"internal_temp":           29.2,
+			"nominal_power":           int64(1000),
+		},
+		map[string]string{
+			"server":   l.Addr().String(),
+			"ups_name": "rack-a",
+			"model":    "Smart-UPS 1500",
+			"serial":   "AS1234567890",
+			"status":   "OL CHRG",
+		})
+
+	acc.AssertContainsTaggedFields(t, "upsd",
+		map[string]interface{}{
+			"battery_charge_percent":  42.0,
+			"battery_runtime_seconds": int64(600),
+			"input_voltage":           0.0,
+		},
+		map[string]string{
+			"server":   l.Addr().String(),
+			"ups_name": "rack-b",
+			"model":    `Eaton "5E"`,
+			"status":   "OB DISCHRG",
+		})
+
+	require.Len(t, acc.Metrics, 2)
+}
+
+func TestGatherUnknownUps(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+
+	u := &Upsd{
+		Servers: []string{l.Addr().String()},
+		Ups:     []string{"missing", "rack-b"},
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, u.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "ups missing: LIST VAR missing: UNKNOWN-UPS")
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "rack-b", acc.Metrics[0].Tags["ups_name"])
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		args []string
+	}{
+		{`rack-a "APC Smart-UPS 1500"`, []string{"rack-a", "APC Smart-UPS 1500"}},
+		{`ups.model "Eaton \"5E\""`, []string{"ups.model", `Eaton "5E"`}},
+		{`ups.mfr ""`, []string{"ups.mfr", ""}},
+		{`a  b`, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		args, err := splitArgs(tt.line)
+		require.NoError(t, err, tt.line)
+		require.Equal(t, tt.args, args, tt.line)
+	}
+
+	_, err := splitArgs(`ups.model "Eaton`)
+	require.Error(t, err)
+}
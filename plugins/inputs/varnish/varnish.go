@@ -55,6 +55,10 @@ func (s *Varnish) Description() string {
 	return "A plugin to collect stats from Varnish HTTP Cache"
 }
 
+func (s *Varnish) Dependencies() []string {
+	return []string{"varnishstat"}
+}
+
 // SampleConfig displays configuration instructions
 func (s *Varnish) SampleConfig() string {
 	return sampleConfig
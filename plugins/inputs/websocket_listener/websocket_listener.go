@@ -0,0 +1,223 @@
+package websocket_listener
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/listen"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"golang.org/x/net/websocket"
+)
+
+// defaultMaxMessageSize is the maximum size of the messages by default, in
+// bytes.
+const defaultMaxMessageSize = 32 * 1024 * 1024
+
+const sampleConfig = `
+  ## Address and port to accept the WebSocket connections on.
+  service_address = ":8080"
+
+  ## Additional addresses to listen on.  The addresses may be prefixed by
+  ## tcp4:// or tcp6:// to listen on IPv4 or IPv6 only, eg. "tcp6://[::1]:8080".
+  # service_addresses = []
+
+  ## Set to listen on IPv6 only, or on both IPv4 and IPv6, on the empty and
+  ## IPv6 hosts, instead of the defaults of the system.  The IPv4 hosts,
+  ## including 0.0.0.0, then listen on IPv4 only.  Not supported on Windows.
+  # ipv6_v6only = false
+
+  ## Path of the endpoint.
+  # path = "/telegraf"
+
+  ## Maximum number of concurrent connections.
+  ## 0 (default) is unlimited.
+  # max_connections = 1024
+
+  ## Maximum size of the messages in bytes.  The larger messages are dropped.
+  ## 0 means to use the default of 33,554,432 bytes (32 mebibytes).
+  # max_message_size = 0
+
+  ## Connections without any message for read_timeout are closed.
+  ## 0 (default) is unlimited.
+  # read_timeout = "0s"
+
+  ## Optional TLS configuration, for the wss scheme.
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key  = "/etc/telegraf/key.pem"
+  ## Enables client authentication if set.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  # data_format = "influx"
+`
+
+type WebSocketListener struct {
+	ServiceAddress   string            `toml:"service_address"`
+	ServiceAddresses []string          `toml:"service_addresses"`
+	Path             string            `toml:"path"`
+	MaxConnections   int               `toml:"max_connections"`
+	MaxMessageSize   int               `toml:"max_message_size"`
+	ReadTimeout      internal.Duration `toml:"read_timeout"`
+	tlsint.ServerConfig
+	listen.Config
+
+	parser parsers.Parser
+	acc    telegraf.Accumulator
+
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+
+	// conns are the WebSocket connections, which the server does not close
+	// on Stop once they are hijacked.
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+}
+
+func (l *WebSocketListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *WebSocketListener) Description() string {
+	return "Accept metrics over WebSocket connections"
+}
+
+func (l *WebSocketListener) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (l *WebSocketListener) SetParser(parser parsers.Parser) {
+	l.parser = parser
+}
+
+func (l *WebSocketListener) Start(acc telegraf.Accumulator) error {
+	l.acc = acc
+	l.conns = make(map[*websocket.Conn]struct{})
+	if l.MaxMessageSize == 0 {
+		l.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	tlsConf, err := l.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	listener, err := l.ListenAll(append([]string{l.ServiceAddress}, l.ServiceAddresses...))
+	if err != nil {
+		return err
+	}
+	if tlsConf != nil {
+		listener = tls.NewListener(listener, tlsConf)
+	}
+	l.listener = listener
+
+	mux := http.NewServeMux()
+	mux.Handle(l.Path, websocket.Server{
+		// The origin is not checked, the senders not being browsers.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   l.handle,
+	})
+	l.server = &http.Server{Handler: mux}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		if err := l.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			acc.AddError(err)
+		}
+	}()
+
+	log.Printf("I! Started WebSocket listener service on %s", listener.Addr())
+	return nil
+}
+
+// handle parses the messages of a connection until it is closed.
+func (l *WebSocketListener) handle(conn *websocket.Conn) {
+	if !l.add(conn) {
+		conn.Close()
+		return
+	}
+	defer l.remove(conn)
+
+	conn.MaxPayloadBytes = l.MaxMessageSize
+	var msg []byte
+	for {
+		if l.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(l.ReadTimeout.Duration))
+		}
+		err := websocket.Message.Receive(conn, &msg)
+		if err == websocket.ErrFrameTooLarge {
+			l.acc.AddError(fmt.Errorf("message from %s exceeds max_message_size", conn.Request().RemoteAddr))
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		metrics, err := l.parser.Parse(msg)
+		if err != nil {
+			l.acc.AddError(fmt.Errorf("unable to parse message from %s: %s", conn.Request().RemoteAddr, err))
+			continue
+		}
+		for _, m := range metrics {
+			l.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+		}
+	}
+}
+
+// add tracks a connection, unless the listener is stopped or at
+// max_connections.
+func (l *WebSocketListener) add(conn *websocket.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns == nil {
+		return false
+	}
+	if l.MaxConnections > 0 && len(l.conns) >= l.MaxConnections {
+		l.acc.AddError(fmt.Errorf("unable to accept connection from %s: too many connections", conn.Request().RemoteAddr))
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	l.wg.Add(1)
+	return true
+}
+
+func (l *WebSocketListener) remove(conn *websocket.Conn) {
+	conn.Close()
+	l.mu.Lock()
+	delete(l.conns, conn)
+	l.mu.Unlock()
+	l.wg.Done()
+}
+
+func (l *WebSocketListener) Stop() {
+	l.server.Close()
+	l.mu.Lock()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+	l.mu.Unlock()
+	l.wg.Wait()
+	log.Println("I! Stopped WebSocket listener service on ", l.ServiceAddress)
+}
+
+func init() {
+	inputs.Add("websocket_listener", func() telegraf.Input {
+		return &WebSocketListener{
+			ServiceAddress: ":8080",
+			Path:           "/telegraf",
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package websocket_listener
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+var pki = testutil.NewPKI("../../../testutil/pki")
+
+func newListener(t *testing.T) *WebSocketListener {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	l := &WebSocketListener{
+		ServiceAddress: "127.0.0.1:0",
+		Path:           "/telegraf",
+	}
+	l.SetParser(parser)
+	return l
+}
+
+func dial(t *testing.T, l *WebSocketListener, scheme string) *websocket.Conn {
+	url := scheme + "://" + l.listener.Addr().String() + "/telegraf"
+	config, err := websocket.NewConfig(url, "http://localhost")
+	require.NoError(t, err)
+	if scheme == "wss" {
+		config.TlsConfig, err = pki.TLSClientConfig().TLSConfig()
+		require.NoError(t, err)
+		config.TlsConfig.ServerName = "localhost"
+	}
+	conn, err := websocket.DialConfig(config)
+	require.NoError(t, err)
+	return conn
+}
+
+func TestListener(t *testing.T) {
+	l := newListener(t)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, l.Start(acc))
+	defer l.Stop()
+
+	conn := dial(t, l, "ws")
+	defer conn.Close()
+	require.NoError(t, websocket.Message.Send(conn, []byte("cpu,host=a usage=1 1500000000000000000\ncpu,host=b usage=2 1500000000000000000\n")))
+	require.NoError(t, websocket.Message.Send(conn, "mem used=3i 1500000000000000000\n"))
+	acc.Wait(3)
+
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"usage": float64(1)}, map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"usage": float64(2)}, map[string]string{"host": "b"})
+	acc.AssertContainsFields(t, "mem", map[string]interface{}{"used": int64(3)})
+
+	// The invalid messages are reported and skipped.
+	require.NoError(t, websocket.Message.Send(conn, "not line protocol"))
+	require.NoError(t, websocket.Message.Send(conn, "mem used=4i 1500000000000000000\n"))
+	acc.Wait(4)
+	acc.Lock()
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "unable to parse message")
+	acc.Unlock()
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	l := newListener(t)
+	l.MaxMessageSize = 64
+	acc := &testutil.Accumulator{}
+	require.NoError(t, l.Start(acc))
+	defer l.Stop()
+
+	conn := dial(t, l, "ws")
+	defer conn.Close()
+	require.NoError(t, websocket.Message.Send(conn, "cpu usage=1 1500000000000000000 "+strings.Repeat(" ", 64)))
+	require.NoError(t, websocket.Message.Send(conn, "mem used=3i 1500000000000000000\n"))
+	acc.Wait(1)
+	acc.AssertContainsFields(t, "mem", map[string]interface{}{"used": int64(3)})
+	require.Contains(t, acc.FirstError().Error(), "exceeds max_message_size")
+}
+
+func TestMaxConnections(t *testing.T) {
+	l := newListener(t)
+	l.MaxConnections = 1
+	acc := &testutil.Accumulator{}
+	require.NoError(t, l.Start(acc))
+	defer l.Stop()
+
+	conn := dial(t, l, "ws")
+	defer conn.Close()
+	require.NoError(t, websocket.Message.Send(conn, "mem used=3i 1500000000000000000\n"))
+	acc.Wait(1)
+
+	// The connection over the limit is closed after its opening handshake.
+	other := dial(t, l, "ws")
+	defer other.Close()
+	other.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg []byte
+	require.Error(t, websocket.Message.Receive(other, &msg))
+	acc.WaitError(1)
+	require.Contains(t, acc.FirstError().Error(), "too many connections")
+}
+
+func TestTLS(t *testing.T) {
+	l := newListener(t)
+	l.ServerConfig = *pki.TLSServerConfig()
+	acc := &testutil.Accumulator{}
+	require.NoError(t, l.Start(acc))
+	defer l.Stop()
+
+	conn := dial(t, l, "wss")
+	defer conn.Close()
+	require.NoError(t, websocket.Message.Send(conn, "mem used=3i 1500000000000000000\n"))
+	acc.Wait(1)
+}
+
+func TestStop(t *testing.T) {
+	l := newListener(t)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, l.Start(acc))
+
+	conn := dial(t, l, "ws")
+	defer conn.Close()
+	require.NoError(t, websocket.Message.Send(conn, "mem used=3i 1500000000000000000\n"))
+	acc.Wait(1)
+
+	// Stop closes the open connections.
+	l.Stop()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg []byte
+	require.Error(t, websocket.Message.Receive(conn, &msg))
+}
@@ -0,0 +1,264 @@
+// +build linux freebsd
+
+package zfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+func run(command string, args ...string) ([]string, error) {
+	cmd := exec.Command(command, args...)
+	var outbuf, errbuf bytes.Buffer
+	cmd.Stdout = &outbuf
+	cmd.Stderr = &errbuf
+	err := cmd.Run()
+
+	stdout := strings.TrimSpace(outbuf.String())
+	stderr := strings.TrimSpace(errbuf.String())
+
+	if _, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%s error: %s", command, stderr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stdout == "" {
+		return nil, nil
+	}
+	return strings.Split(stdout, "\n"), nil
+}
+
+// zpool lists the pools, with the columns of the default output of FreeBSD
+// whatever the ZFS version.
+func zpool() ([]string, error) {
+	return run("zpool", "list", "-Hp", "-o",
+		"name,size,allocated,free,expandsize,fragmentation,capacity,dedupratio,health,altroot")
+}
+
+func zfsList() ([]string, error) {
+	return run("zfs", "list", "-Hp", "-t", "filesystem,volume", "-o",
+		"name,used,available,referenced,usedbysnapshots,usedbydataset")
+}
+
+// zpoolStatus returns the status of the pools as JSON, which requires
+// OpenZFS 2.3 or later.
+func zpoolStatus() ([]byte, error) {
+	lines, err := run("zpool", "status", "-j", "--json-int")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// parsePoolList parses a line of the zpool list output.
+func parsePoolList(line string) (map[string]string, map[string]interface{}, error) {
+	col := strings.Split(line, "\t")
+	if len(col) < 9 {
+		return nil, nil, fmt.Errorf("Error parsing pool list: %q", line)
+	}
+	tags := map[string]string{"pool": col[0], "health": col[8]}
+	fields := map[string]interface{}{}
+
+	if tags["health"] == "UNAVAIL" {
+		fields["size"] = int64(0)
+		return tags, fields, nil
+	}
+
+	size, err := strconv.ParseInt(col[1], 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing size: %s", err)
+	}
+	fields["size"] = size
+
+	alloc, err := strconv.ParseInt(col[2], 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing allocation: %s", err)
+	}
+	fields["allocated"] = alloc
+
+	free, err := strconv.ParseInt(col[3], 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing free: %s", err)
+	}
+	fields["free"] = free
+
+	frag, err := strconv.ParseInt(strings.TrimSuffix(col[5], "%"), 10, 0)
+	if err != nil { // This might be - for RO devs
+		frag = 0
+	}
+	fields["fragmentation"] = frag
+
+	capval, err := strconv.ParseInt(col[6], 10, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing capacity: %s", err)
+	}
+	fields["capacity"] = capval
+
+	dedup, err := strconv.ParseFloat(strings.TrimSuffix(col[7], "x"), 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing dedupratio: %s", err)
+	}
+	fields["dedupratio"] = dedup
+
+	return tags, fields, nil
+}
+
+// datasetFields are the fields of the columns of the zfs list output.
+var datasetFields = []string{"used", "available", "referenced", "used_by_snapshots", "used_by_dataset"}
+
+// gatherDatasets adds the space of the datasets listed by zfs, with their
+// I/O statistics if any.
+func (z *Zfs) gatherDatasets(acc telegraf.Accumulator, stats map[string]map[string]interface{}) {
+	if z.zfsList != nil {
+		lines, err := z.zfsList()
+		if err != nil {
+			acc.AddError(err)
+		}
+		for _, line := range lines {
+			col := strings.Split(line, "\t")
+			if len(col) != len(datasetFields)+1 {
+				continue
+			}
+			fields, ok := stats[col[0]]
+			if !ok {
+				fields = make(map[string]interface{})
+				stats[col[0]] = fields
+			}
+			for i, name := range datasetFields {
+				// Some values, such as the available space of volumes,
+				// may be "-".
+				if value, err := strconv.ParseInt(col[i+1], 10, 64); err == nil {
+					fields[name] = value
+				}
+			}
+		}
+	}
+
+	for dataset, fields := range stats {
+		tags := map[string]string{
+			"pool":    strings.SplitN(dataset, "/", 2)[0],
+			"dataset": dataset,
+		}
+		acc.AddFields("zfs_dataset", fields, tags)
+	}
+}
+
+// jsonInt is an integer of the zpool JSON output, which may be a string.
+// Values which are not integers, such as "-", are zero.
+type jsonInt int64
+
+func (i *jsonInt) UnmarshalJSON(b []byte) error {
+	if v, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64); err == nil {
+		*i = jsonInt(v)
+	}
+	return nil
+}
+
+type poolStatus struct {
+	Pools map[string]struct {
+		Name      string `json:"name"`
+		State     string `json:"state"`
+		ScanStats *struct {
+			Function  string  `json:"function"`
+			State     string  `json:"state"`
+			ToExamine jsonInt `json:"to_examine"`
+			Examined  jsonInt `json:"examined"`
+			Issued    jsonInt `json:"issued"`
+			Processed jsonInt `json:"processed"`
+			Errors    jsonInt `json:"errors"`
+		} `json:"scan_stats"`
+		Vdevs   map[string]*vdevStatus `json:"vdevs"`
+		Logs    map[string]*vdevStatus `json:"logs"`
+		L2Cache map[string]*vdevStatus `json:"l2cache"`
+		Spares  map[string]*vdevStatus `json:"spares"`
+	} `json:"pools"`
+}
+
+type vdevStatus struct {
+	Name           string                 `json:"name"`
+	Type           string                 `json:"vdev_type"`
+	State          string                 `json:"state"`
+	AllocSpace     jsonInt                `json:"alloc_space"`
+	TotalSpace     jsonInt                `json:"total_space"`
+	ReadErrors     jsonInt                `json:"read_errors"`
+	WriteErrors    jsonInt                `json:"write_errors"`
+	ChecksumErrors jsonInt                `json:"checksum_errors"`
+	Vdevs          map[string]*vdevStatus `json:"vdevs"`
+}
+
+// gatherStatus adds the progress of the scrub or resilver of each pool and
+// the error counters of its vdevs, from the zpool status JSON output.
+func (z *Zfs) gatherStatus(acc telegraf.Accumulator) error {
+	if z.zpoolStatus == nil {
+		return nil
+	}
+	out, err := z.zpoolStatus()
+	if err != nil {
+		return err
+	}
+	var status poolStatus
+	if err := json.Unmarshal(out, &status); err != nil {
+		return fmt.Errorf("Error parsing zpool status: %s", err)
+	}
+
+	for name, pool := range status.Pools {
+		if scan := pool.ScanStats; scan != nil && scan.Function != "" {
+			tags := map[string]string{
+				"pool":     name,
+				"function": strings.ToLower(scan.Function),
+				"state":    strings.ToLower(scan.State),
+			}
+			fields := map[string]interface{}{
+				"to_examine": int64(scan.ToExamine),
+				"examined":   int64(scan.Examined),
+				"issued":     int64(scan.Issued),
+				"processed":  int64(scan.Processed),
+				"errors":     int64(scan.Errors),
+			}
+			if scan.ToExamine > 0 {
+				fields["percent_done"] = 100 * float64(scan.Issued) / float64(scan.ToExamine)
+			}
+			acc.AddFields("zfs_scan", fields, tags)
+		}
+
+		for _, vdevs := range []map[string]*vdevStatus{pool.Vdevs, pool.Logs, pool.L2Cache, pool.Spares} {
+			for vdevName, vdev := range vdevs {
+				gatherVdev(acc, name, vdevName, vdev)
+			}
+		}
+	}
+	return nil
+}
+
+func gatherVdev(acc telegraf.Accumulator, pool, name string, vdev *vdevStatus) {
+	if vdev == nil {
+		return
+	}
+	tags := map[string]string{
+		"pool":  pool,
+		"vdev":  name,
+		"type":  vdev.Type,
+		"state": vdev.State,
+	}
+	fields := map[string]interface{}{
+		"read_errors":     int64(vdev.ReadErrors),
+		"write_errors":    int64(vdev.WriteErrors),
+		"checksum_errors": int64(vdev.ChecksumErrors),
+	}
+	if vdev.TotalSpace > 0 {
+		fields["allocated"] = int64(vdev.AllocSpace)
+		fields["size"] = int64(vdev.TotalSpace)
+	}
+	acc.AddFields("zfs_vdev", fields, tags)
+
+	for childName, child := range vdev.Vdevs {
+		gatherVdev(acc, pool, childName, child)
+	}
+}
@@ -0,0 +1,179 @@
+// +build linux freebsd
+
+package zfs
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// $ zpool status -j --json-int
+const zpoolStatusOutput = `{
+  "output_version": {"command": "zpool status", "vers_major": 0, "vers_minor": 1},
+  "pools": {
+    "tank": {
+      "name": "tank",
+      "state": "ONLINE",
+      "pool_guid": 6803128547386315218,
+      "txg": 2473901,
+      "scan_stats": {
+        "function": "SCRUB",
+        "state": "SCANNING",
+        "start_time": 1718410241,
+        "end_time": 0,
+        "to_examine": 4000000000,
+        "examined": 3000000000,
+        "skipped": 0,
+        "processed": 0,
+        "errors": 0,
+        "issued": 1000000000
+      },
+      "vdevs": {
+        "tank": {
+          "name": "tank",
+          "vdev_type": "root",
+          "state": "ONLINE",
+          "alloc_space": 4000000000,
+          "total_space": 16000000000,
+          "read_errors": 0,
+          "write_errors": 0,
+          "checksum_errors": 0,
+          "vdevs": {
+            "mirror-0": {
+              "name": "mirror-0",
+              "vdev_type": "mirror",
+              "state": "DEGRADED",
+              "alloc_space": 4000000000,
+              "total_space": 16000000000,
+              "read_errors": 0,
+              "write_errors": 0,
+              "checksum_errors": 0,
+              "vdevs": {
+                "sda": {
+                  "name": "sda",
+                  "vdev_type": "disk",
+                  "state": "ONLINE",
+                  "read_errors": 0,
+                  "write_errors": 0,
+                  "checksum_errors": 0
+                },
+                "sdb": {
+                  "name": "sdb",
+                  "vdev_type": "disk",
+                  "state": "FAULTED",
+                  "read_errors": 3,
+                  "write_errors": 1,
+                  "checksum_errors": "12"
+                }
+              }
+            }
+          }
+        }
+      },
+      "spares": {
+        "sdc": {
+          "name": "sdc",
+          "vdev_type": "disk",
+          "state": "AVAIL"
+        }
+      },
+      "error_count": 0
+    }
+  }
+}`
+
+func TestParsePoolList(t *testing.T) {
+	tags, fields, err := parsePoolList("red1	8933531975680	1126164848640	7807367127040	-	8%	12	1.83x	ONLINE	/mnt")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"pool": "red1", "health": "ONLINE"}, tags)
+	require.Equal(t, int64(8), fields["fragmentation"])
+	require.InDelta(t, 1.83, fields["dedupratio"], 0.001)
+
+	_, _, err = parsePoolList("red1")
+	require.Error(t, err)
+}
+
+func TestGatherStatus(t *testing.T) {
+	var acc testutil.Accumulator
+	z := &Zfs{
+		zpoolStatus: func() ([]byte, error) { return []byte(zpoolStatusOutput), nil },
+	}
+	require.NoError(t, z.gatherStatus(&acc))
+
+	acc.AssertContainsTaggedFields(t, "zfs_scan",
+		map[string]interface{}{
+			"to_examine":   int64(4000000000),
+			"examined":     int64(3000000000),
+			"issued":       int64(1000000000),
+			"processed":    int64(0),
+			"errors":       int64(0),
+			"percent_done": float64(25),
+		},
+		map[string]string{"pool": "tank", "function": "scrub", "state": "scanning"})
+
+	acc.AssertContainsTaggedFields(t, "zfs_vdev",
+		map[string]interface{}{
+			"read_errors":     int64(0),
+			"write_errors":    int64(0),
+			"checksum_errors": int64(0),
+			"allocated":       int64(4000000000),
+			"size":            int64(16000000000),
+		},
+		map[string]string{"pool": "tank", "vdev": "mirror-0", "type": "mirror", "state": "DEGRADED"})
+
+	acc.AssertContainsTaggedFields(t, "zfs_vdev",
+		map[string]interface{}{
+			"read_errors":     int64(3),
+			"write_errors":    int64(1),
+			"checksum_errors": int64(12),
+		},
+		map[string]string{"pool": "tank", "vdev": "sdb", "type": "disk", "state": "FAULTED"})
+
+	require.True(t, acc.HasPoint("zfs_vdev",
+		map[string]string{"pool": "tank", "vdev": "sdc", "type": "disk", "state": "AVAIL"},
+		"read_errors", int64(0)))
+	require.Len(t, acc.Metrics, 6)
+
+	z.zpoolStatus = func() ([]byte, error) { return []byte("no pools available"), nil }
+	require.Error(t, z.gatherStatus(&acc))
+}
+
+func TestGatherDatasets(t *testing.T) {
+	var acc testutil.Accumulator
+	z := &Zfs{
+		zfsList: func() ([]string, error) {
+			return []string{
+				"tank	4000000000	12000000000	98304	0	98304",
+				"tank/home	3000000000	12000000000	2900000000	100000000	2900000000",
+				"tank/vol	1000000000	-	500000000	0	500000000",
+			}, nil
+		},
+	}
+	stats := map[string]map[string]interface{}{
+		"tank/home": {"reads": int64(75003), "nread": int64(1003724800)},
+	}
+	z.gatherDatasets(&acc, stats)
+
+	acc.AssertContainsTaggedFields(t, "zfs_dataset",
+		map[string]interface{}{
+			"used":              int64(3000000000),
+			"available":         int64(12000000000),
+			"referenced":        int64(2900000000),
+			"used_by_snapshots": int64(100000000),
+			"used_by_dataset":   int64(2900000000),
+			"reads":             int64(75003),
+			"nread":             int64(1003724800),
+		},
+		map[string]string{"pool": "tank", "dataset": "tank/home"})
+
+	acc.AssertContainsTaggedFields(t, "zfs_dataset",
+		map[string]interface{}{
+			"used":              int64(1000000000),
+			"referenced":        int64(500000000),
+			"used_by_snapshots": int64(0),
+			"used_by_dataset":   int64(500000000),
+		},
+		map[string]string{"pool": "tank", "dataset": "tank/vol"})
+}
@@ -4,11 +4,15 @@ type Sysctl func(metric string) ([]string, error)
 type Zpool func() ([]string, error)
 
 type Zfs struct {
-	KstatPath    string
-	KstatMetrics []string
-	PoolMetrics  bool
-	sysctl       Sysctl
-	zpool        Zpool
+	KstatPath      string
+	KstatMetrics   []string
+	PoolMetrics    bool
+	DatasetMetrics bool
+	StatusMetrics  bool
+	sysctl         Sysctl
+	zpool          Zpool
+	zfsList        func() ([]string, error)
+	zpoolStatus    func() ([]byte, error)
 }
 
 var sampleConfig = `
@@ -24,6 +28,14 @@ var sampleConfig = `
   #   "dmu_tx", "fm", "vdev_mirror_stats", "zfetchstats", "zil"]
   ## By default, don't gather zpool stats
   # poolMetrics = false
+
+  ## By default, don't gather the space and, on Linux, the I/O stats of the
+  ## datasets
+  # datasetMetrics = false
+
+  ## By default, don't gather the scrub and resilver progress and the vdev
+  ## error counters, which require OpenZFS 2.3 or later
+  # statusMetrics = false
 `
 
 func (z *Zfs) SampleConfig() string {
@@ -31,5 +43,5 @@ func (z *Zfs) SampleConfig() string {
 }
 
 func (z *Zfs) Description() string {
-	return "Read metrics of ZFS from arcstats, zfetchstats, vdev_cache_stats, and pools"
+	return "Read metrics of ZFS from arcstats, zfetchstats, vdev_cache_stats, pools and datasets"
 }
@@ -44,7 +44,7 @@ func getTags(pools []poolInfo) map[string]string {
 	return map[string]string{"pools": poolNames}
 }
 
-func gatherPoolStats(pool poolInfo, acc telegraf.Accumulator) error {
+func gatherPoolStats(pool poolInfo, list map[string]string, acc telegraf.Accumulator) error {
 	lines, err := internal.ReadLines(pool.ioFilename)
 	if err != nil {
 		return err
@@ -72,6 +72,18 @@ func gatherPoolStats(pool poolInfo, acc telegraf.Accumulator) error {
 		}
 		fields[keys[i]] = value
 	}
+
+	// The size and health of the pool, as on FreeBSD.
+	if line, ok := list[pool.name]; ok {
+		listTags, listFields, err := parsePoolList(line)
+		if err != nil {
+			return err
+		}
+		tag["health"] = listTags["health"]
+		for k, v := range listFields {
+			fields[k] = v
+		}
+	}
 	acc.AddFields("zfs_pool", fields, tag)
 
 	return nil
@@ -96,8 +108,18 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 	tags := getTags(pools)
 
 	if z.PoolMetrics {
+		list := make(map[string]string)
+		if z.zpool != nil {
+			lines, err := z.zpool()
+			if err != nil {
+				acc.AddError(err)
+			}
+			for _, line := range lines {
+				list[strings.Split(line, "\t")[0]] = line
+			}
+		}
 		for _, pool := range pools {
-			err := gatherPoolStats(pool, acc)
+			err := gatherPoolStats(pool, list, acc)
 			if err != nil {
 				return err
 			}
@@ -128,11 +150,60 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 	acc.AddFields("zfs", fields, tags)
+
+	if z.DatasetMetrics {
+		z.gatherDatasets(acc, getDatasetStats(kstatPath))
+	}
+	if z.StatusMetrics {
+		if err := z.gatherStatus(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
 	return nil
 }
 
+// getDatasetStats returns the I/O statistics of the datasets, from the
+// objset kstats of ZFS on Linux 0.8 and later, by dataset name.
+func getDatasetStats(kstatPath string) map[string]map[string]interface{} {
+	stats := make(map[string]map[string]interface{})
+	objsets, _ := filepath.Glob(kstatPath + "/*/objset-*")
+	for _, objset := range objsets {
+		lines, err := internal.ReadLines(objset)
+		if err != nil {
+			continue
+		}
+
+		var dataset string
+		fields := make(map[string]interface{})
+		for i, line := range lines {
+			if i == 0 || i == 1 {
+				continue
+			}
+			rawData := strings.Fields(line)
+			if len(rawData) != 3 {
+				continue
+			}
+			if rawData[0] == "dataset_name" {
+				dataset = rawData[2]
+				continue
+			}
+			if value, err := strconv.ParseInt(rawData[2], 10, 64); err == nil {
+				fields[rawData[0]] = value
+			}
+		}
+		if dataset != "" {
+			stats[dataset] = fields
+		}
+	}
+	return stats
+}
+
 func init() {
 	inputs.Add("zfs", func() telegraf.Input {
-		return &Zfs{}
+		return &Zfs{
+			zpool:       zpool,
+			zfsList:     zfsList,
+			zpoolStatus: zpoolStatus,
+		}
 	})
 }
@@ -243,6 +243,17 @@ preferred_found                 4    0
 preferred_not_found             4    43
 `
 
+const objsetContents = `36 1 0x01 7 2160 5214787391 74985931356512
+name                            type data
+dataset_name                    7    HOME/data
+writes                          4    7823
+nwritten                        4    1024006123
+reads                           4    75003
+nread                           4    1003724800
+nunlinks                        4    12
+nunlinked                       4    10
+`
+
 var testKstatPath = os.TempDir() + "/telegraf/proc/spl/kstat/zfs"
 
 func TestZfsPoolMetrics(t *testing.T) {
@@ -279,6 +290,69 @@ func TestZfsPoolMetrics(t *testing.T) {
 	}
 
 	acc.AssertContainsTaggedFields(t, "zfs_pool", poolMetrics, tags)
+	acc.Metrics = nil
+
+	//size and health from zpool list
+	z = &Zfs{KstatPath: testKstatPath, KstatMetrics: []string{"arcstats"}, PoolMetrics: true,
+		zpool: func() ([]string, error) {
+			return []string{"HOME\t8933531975680\t1126164848640\t7807367127040\t-\t8%\t12\t1.00x\tONLINE\t-"}, nil
+		},
+	}
+	err = z.Gather(&acc)
+	require.NoError(t, err)
+
+	poolMetrics["size"] = int64(8933531975680)
+	poolMetrics["allocated"] = int64(1126164848640)
+	poolMetrics["free"] = int64(7807367127040)
+	poolMetrics["fragmentation"] = int64(8)
+	poolMetrics["capacity"] = int64(12)
+	poolMetrics["dedupratio"] = float64(1)
+	tags["health"] = "ONLINE"
+
+	acc.AssertContainsTaggedFields(t, "zfs_pool", poolMetrics, tags)
+
+	err = os.RemoveAll(os.TempDir() + "/telegraf")
+	require.NoError(t, err)
+}
+
+func TestZfsDatasetMetrics(t *testing.T) {
+	err := os.MkdirAll(testKstatPath+"/HOME", 0755)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(testKstatPath+"/HOME/io", []byte(pool_ioContents), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(testKstatPath+"/HOME/objset-0x36", []byte(objsetContents), 0644)
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+
+	z := &Zfs{KstatPath: testKstatPath, KstatMetrics: []string{"arcstats"}, DatasetMetrics: true,
+		zfsList: func() ([]string, error) {
+			return []string{"HOME/data\t3000000000\t12000000000\t2900000000\t100000000\t2900000000"}, nil
+		},
+	}
+	err = z.Gather(&acc)
+	require.NoError(t, err)
+
+	tags := map[string]string{
+		"pool":    "HOME",
+		"dataset": "HOME/data",
+	}
+	fields := map[string]interface{}{
+		"writes":            int64(7823),
+		"nwritten":          int64(1024006123),
+		"reads":             int64(75003),
+		"nread":             int64(1003724800),
+		"nunlinks":          int64(12),
+		"nunlinked":         int64(10),
+		"used":              int64(3000000000),
+		"available":         int64(12000000000),
+		"referenced":        int64(2900000000),
+		"used_by_snapshots": int64(100000000),
+		"used_by_dataset":   int64(2900000000),
+	}
+	acc.AssertContainsTaggedFields(t, "zfs_dataset", fields, tags)
 
 	err = os.RemoveAll(os.TempDir() + "/telegraf")
 	require.NoError(t, err)
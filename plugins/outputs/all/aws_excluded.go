@@ -0,0 +1,10 @@
+// +build !custom,noaws
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/outputs"
+
+func init() {
+	outputs.AddExcluded("cloudwatch", "noaws")
+	outputs.AddExcluded("kinesis", "noaws")
+}
@@ -0,0 +1,8 @@
+// +build !custom,!noazure
+
+package all
+
+// The Microsoft Azure plugins, excluded with the noazure build tag.
+import (
+	_ "github.com/influxdata/telegraf/plugins/outputs/application_insights"
+)
@@ -0,0 +1,9 @@
+// +build !custom,noazure
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/outputs"
+
+func init() {
+	outputs.AddExcluded("application_insights", "noazure")
+}
@@ -0,0 +1,8 @@
+// +build !custom,!nosqldrivers
+
+package all
+
+// The SQL database drivers plugins, excluded with the nosqldrivers build tag.
+import (
+	_ "github.com/influxdata/telegraf/plugins/outputs/cratedb"
+)
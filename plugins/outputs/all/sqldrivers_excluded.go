@@ -0,0 +1,9 @@
+// +build !custom,nosqldrivers
+
+package all
+
+import "github.com/influxdata/telegraf/plugins/outputs"
+
+func init() {
+	outputs.AddExcluded("cratedb", "nosqldrivers")
+}
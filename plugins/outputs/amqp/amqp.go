@@ -10,6 +10,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/signing"
 	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -45,12 +46,14 @@ type AMQP struct {
 	DeliveryMode string
 
 	tls.ClientConfig
+	signing.Config
 
 	sync.Mutex
 	c *client
 
 	deliveryMode uint8
 	serializer   serializers.Serializer
+	signer       *signing.Signer
 }
 
 type externalAuth struct{}
@@ -100,6 +103,17 @@ var sampleConfig = `
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
 
+  ## Optional signing of the messages, sent in the "signature" header.  The
+  ## method is one of "hmac-sha256", with a shared secret key, or "ed25519",
+  ## with a hex or base64 encoded private key or seed.  When signature_chain
+  ## is enabled each signature also covers the previous one, so that
+  ## consumers can detect dropped or reordered messages.
+  # signature_method = "hmac-sha256"
+  # signature_key = "$TELEGRAF_SIGNATURE_KEY"
+  # signature_key_file = "/etc/telegraf/signature.key"
+  # signature_key_id = "telegraf-1"
+  # signature_chain = false
+
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -136,6 +150,14 @@ func (q *AMQP) Connect() error {
 		return err
 	}
 
+	// The signer is kept when reconnecting, to continue the signature chain.
+	if q.signer == nil {
+		q.signer, err = q.Config.Signer()
+		if err != nil {
+			return err
+		}
+	}
+
 	// parse auth method
 	var sasl []amqp.Authentication // nil by default
 
@@ -249,6 +271,17 @@ func (q *AMQP) Write(metrics []telegraf.Metric) error {
 	}
 
 	for key, buf := range outbuf {
+		headers := c.headers
+		var sig *signing.Signature
+		if q.signer != nil {
+			sig = q.signer.Sign(buf)
+			headers = make(amqp.Table, len(c.headers)+1)
+			for k, v := range c.headers {
+				headers[k] = v
+			}
+			headers["signature"] = sig.String()
+		}
+
 		// Note that since the channel is not in confirm mode, the absence of
 		// an error does not indicate successful delivery.
 		err := c.channel.Publish(
@@ -257,7 +290,7 @@ func (q *AMQP) Write(metrics []telegraf.Metric) error {
 			false,      // mandatory
 			false,      // immediate
 			amqp.Publishing{
-				Headers:      c.headers,
+				Headers:      headers,
 				ContentType:  "text/plain",
 				Body:         buf,
 				DeliveryMode: q.deliveryMode,
@@ -265,6 +298,9 @@ func (q *AMQP) Write(metrics []telegraf.Metric) error {
 		if err != nil {
 			return fmt.Errorf("Failed to send AMQP message: %s", err)
 		}
+		if sig != nil {
+			q.signer.Ack(sig)
+		}
 	}
 	return nil
 }
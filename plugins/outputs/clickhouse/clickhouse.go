@@ -0,0 +1,492 @@
+package clickhouse
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// Schemas of the tables.
+const (
+	schemaWide     = "wide"
+	schemaKeyValue = "key_value"
+)
+
+const timestampColumn = "timestamp"
+
+var defaultTableEngines = map[string]string{
+	schemaWide:     "MergeTree() ORDER BY timestamp",
+	schemaKeyValue: "MergeTree() ORDER BY (field, timestamp)",
+}
+
+type ClickHouse struct {
+	URL                string
+	Database           string
+	Username           string
+	Password           string
+	Schema             string
+	TableCreate        bool   `toml:"table_create"`
+	TableEngine        string `toml:"table_engine"`
+	AsyncInsert        bool   `toml:"async_insert"`
+	WaitForAsyncInsert bool   `toml:"wait_for_async_insert"`
+	Timeout            internal.Duration
+	tls.ClientConfig
+
+	conn conn
+	// tables are the columns of the tables written to, by name, and their
+	// types.
+	tables map[string]map[string]string
+}
+
+// conn is a connection to ClickHouse, over the native protocol or HTTP.
+type conn interface {
+	// exec runs a statement without result.
+	exec(query string) error
+	// columns returns the columns of a table and their types, none if the
+	// table does not exist.
+	columns(database, table string) (map[string]string, error)
+	// insert inserts a block of rows, with the settings of the insert.
+	insert(table string, b *block, settings map[string]string) error
+	close() error
+}
+
+// block is the rows inserted into a table.  The values are converted to the
+// types of the columns, nil being the default value of the column.
+type block struct {
+	columns []string
+	types   []string
+	rows    [][]interface{}
+}
+
+var sampleConfig = `
+  ## URL of the ClickHouse server, with the scheme of its protocol:
+  ##   tcp://localhost:9000  - native protocol, tcps:// with TLS
+  ##   http://localhost:8123 - HTTP interface, https:// with TLS
+  url = "tcp://localhost:9000"
+
+  ## Database of the tables, the default database of the user by default.
+  # database = ""
+
+  ## Credentials of the ClickHouse user.
+  # username = "default"
+  # password = ""
+
+  ## Schema of the tables, a table per measurement:
+  ##   "wide"      - a row per metric, with a column per tag and field
+  ##   "key_value" - a row per numeric field, with the tags in a map column,
+  ##                 the field name and the field value
+  # schema = "wide"
+
+  ## Create the tables of the measurements which do not exist, and add the
+  ## columns of the new tags and fields to the wide tables.
+  # table_create = true
+
+  ## Engine of the tables created.  Defaults to "MergeTree() ORDER BY
+  ## timestamp" for the wide schema, and to "MergeTree() ORDER BY (field,
+  ## timestamp)" for the key_value schema.
+  # table_engine = ""
+
+  ## Insert the metrics asynchronously, ClickHouse batching the inserts of the
+  ## clients in its buffers, and whether to wait for the buffers to be
+  ## flushed before the write succeeds.
+  # async_insert = false
+  # wait_for_async_insert = true
+
+  ## Timeout for the queries.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (ch *ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (ch *ClickHouse) Description() string {
+	return "Write metrics to ClickHouse, over its native protocol or HTTP"
+}
+
+func (ch *ClickHouse) Connect() error {
+	switch ch.Schema {
+	case "":
+		ch.Schema = schemaWide
+	case schemaWide, schemaKeyValue:
+	default:
+		return fmt.Errorf("unknown schema %q", ch.Schema)
+	}
+	if ch.TableEngine == "" {
+		ch.TableEngine = defaultTableEngines[ch.Schema]
+	}
+	ch.tables = make(map[string]map[string]string)
+	return ch.dial()
+}
+
+func (ch *ClickHouse) dial() error {
+	u, err := url.Parse(ch.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %s", ch.URL, err)
+	}
+	tlsCfg, err := ch.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcps":
+		ch.conn, err = dialNative(u, ch.Database, ch.Username, ch.Password, ch.Timeout.Duration, tlsCfg)
+	case "http", "https":
+		ch.conn, err = newHTTPConn(u, ch.Database, ch.Username, ch.Password, ch.Timeout.Duration, tlsCfg)
+	default:
+		return fmt.Errorf("unsupported scheme %q in url %q", u.Scheme, ch.URL)
+	}
+	return err
+}
+
+func (ch *ClickHouse) Close() error {
+	if ch.conn == nil {
+		return nil
+	}
+	err := ch.conn.close()
+	ch.conn = nil
+	return err
+}
+
+// Write inserts the metrics into the table of their measurement.  The
+// native connection is dialed again after an error.
+func (ch *ClickHouse) Write(metrics []telegraf.Metric) error {
+	if ch.conn == nil {
+		if err := ch.dial(); err != nil {
+			return err
+		}
+	}
+
+	var tables []string
+	byTable := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		if _, ok := byTable[m.Name()]; !ok {
+			tables = append(tables, m.Name())
+		}
+		byTable[m.Name()] = append(byTable[m.Name()], m)
+	}
+
+	for _, table := range tables {
+		if err := ch.writeTable(table, byTable[table]); err != nil {
+			// The table is checked again, in case it was changed.
+			delete(ch.tables, table)
+			if _, ok := ch.conn.(*nativeConn); ok {
+				ch.Close()
+			}
+			return fmt.Errorf("writing to table %s: %s", table, err)
+		}
+	}
+	return nil
+}
+
+func (ch *ClickHouse) writeTable(table string, metrics []telegraf.Metric) error {
+	var rows []map[string]interface{}
+	columns := make(map[string]string)
+	if ch.Schema == schemaKeyValue {
+		rows = keyValueRows(metrics, columns)
+	} else {
+		rows = wideRows(metrics, columns)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	types, err := ch.tableColumns(table, columns)
+	if err != nil {
+		return err
+	}
+
+	b := &block{}
+	for name := range columns {
+		if _, ok := types[name]; ok {
+			b.columns = append(b.columns, name)
+		} else {
+			log.Printf("D! [outputs.clickhouse] Table %s has no column %s", table, name)
+		}
+	}
+	sort.Strings(b.columns)
+	for _, name := range b.columns {
+		b.types = append(b.types, types[name])
+	}
+	for _, row := range rows {
+		values := make([]interface{}, len(b.columns))
+		for i, name := range b.columns {
+			values[i] = convert(row[name], b.types[i])
+		}
+		b.rows = append(b.rows, values)
+	}
+
+	settings := map[string]string{}
+	if ch.AsyncInsert {
+		settings["async_insert"] = "1"
+		settings["wait_for_async_insert"] = "0"
+		if ch.WaitForAsyncInsert {
+			settings["wait_for_async_insert"] = "1"
+		}
+	}
+	return ch.conn.insert(ch.qualify(table), b, settings)
+}
+
+// tableColumns returns the columns of a table, after creating the table or
+// adding the columns missing when table_create is set.
+func (ch *ClickHouse) tableColumns(table string, columns map[string]string) (map[string]string, error) {
+	types, ok := ch.tables[table]
+	if !ok {
+		var err error
+		types, err = ch.conn.columns(ch.Database, table)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(types) == 0 {
+		if !ch.TableCreate {
+			return nil, fmt.Errorf("the table does not exist")
+		}
+		if err := ch.conn.exec(createTable(ch.qualify(table), columns, ch.TableEngine)); err != nil {
+			return nil, err
+		}
+		types = make(map[string]string)
+		for name, typ := range columns {
+			types[name] = typ
+		}
+	} else if ch.TableCreate {
+		var missing []string
+		for name := range columns {
+			if _, ok := types[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			var adds []string
+			for _, name := range missing {
+				adds = append(adds, "ADD COLUMN IF NOT EXISTS "+quoteIdentifier(name)+" "+columns[name])
+				types[name] = columns[name]
+			}
+			query := "ALTER TABLE " + ch.qualify(table) + " " + strings.Join(adds, ", ")
+			if err := ch.conn.exec(query); err != nil {
+				return nil, err
+			}
+		}
+	}
+	ch.tables[table] = types
+	return types, nil
+}
+
+func (ch *ClickHouse) qualify(table string) string {
+	if ch.Database == "" {
+		return quoteIdentifier(table)
+	}
+	return quoteIdentifier(ch.Database) + "." + quoteIdentifier(table)
+}
+
+func createTable(table string, columns map[string]string, engine string) string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		if name != timestampColumn {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	defs := []string{quoteIdentifier(timestampColumn) + " " + columns[timestampColumn]}
+	for _, name := range names {
+		defs = append(defs, quoteIdentifier(name)+" "+columns[name])
+	}
+	return "CREATE TABLE IF NOT EXISTS " + table + " (" + strings.Join(defs, ", ") + ") ENGINE = " + engine
+}
+
+// wideRows returns a row per metric, with a column per tag and field.  The
+// types of the columns created are added to columns.
+func wideRows(metrics []telegraf.Metric, columns map[string]string) []map[string]interface{} {
+	columns[timestampColumn] = "DateTime64(9, 'UTC')"
+	rows := make([]map[string]interface{}, 0, len(metrics))
+	for _, m := range metrics {
+		row := map[string]interface{}{timestampColumn: m.Time()}
+		for _, tag := range m.TagList() {
+			if tag.Key == timestampColumn {
+				continue
+			}
+			row[tag.Key] = tag.Value
+			columns[tag.Key] = "String"
+		}
+		for _, field := range m.FieldList() {
+			typ := fieldType(field.Value)
+			if typ == "" || field.Key == timestampColumn {
+				continue
+			}
+			if _, ok := row[field.Key]; ok {
+				continue
+			}
+			row[field.Key] = field.Value
+			if _, ok := columns[field.Key]; !ok {
+				columns[field.Key] = "Nullable(" + typ + ")"
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// keyValueRows returns a row per numeric field, the string fields being
+// skipped.
+func keyValueRows(metrics []telegraf.Metric, columns map[string]string) []map[string]interface{} {
+	columns[timestampColumn] = "DateTime64(9, 'UTC')"
+	columns["tags"] = "Map(String, String)"
+	columns["field"] = "String"
+	columns["value"] = "Float64"
+
+	var rows []map[string]interface{}
+	for _, m := range metrics {
+		for _, field := range m.FieldList() {
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+			rows = append(rows, map[string]interface{}{
+				timestampColumn: m.Time(),
+				"tags":          m.Tags(),
+				"field":         field.Key,
+				"value":         value,
+			})
+		}
+	}
+	return rows
+}
+
+func fieldType(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "Float64"
+	case int64:
+		return "Int64"
+	case uint64:
+		return "UInt64"
+	case bool:
+		return "UInt8"
+	case string:
+		return "String"
+	}
+	return ""
+}
+
+// convert converts a value to the type of its column, or returns nil, the
+// default value of the column, when it cannot be converted.
+func convert(v interface{}, typ string) interface{} {
+	if v == nil {
+		return nil
+	}
+	typ, _ = unwrapNullable(typ)
+	switch {
+	case typ == "String":
+		switch v := v.(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		case int64:
+			return strconv.FormatInt(v, 10)
+		case uint64:
+			return strconv.FormatUint(v, 10)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	case strings.HasPrefix(typ, "Float"):
+		if f, ok := toFloat(v); ok {
+			return f
+		}
+	case strings.HasPrefix(typ, "Int"):
+		if f, ok := toFloat(v); ok && f >= math.MinInt64 && f < math.MaxInt64 {
+			if i, ok := v.(int64); ok {
+				return i
+			}
+			return int64(f)
+		}
+	case strings.HasPrefix(typ, "UInt"):
+		if f, ok := toFloat(v); ok && f >= 0 && f < math.MaxUint64 {
+			if u, ok := v.(uint64); ok {
+				return u
+			}
+			if i, ok := v.(int64); ok {
+				return uint64(i)
+			}
+			return uint64(f)
+		}
+	case typ == "Bool":
+		if f, ok := toFloat(v); ok {
+			return f != 0
+		}
+	case strings.HasPrefix(typ, "DateTime"):
+		if t, ok := v.(time.Time); ok {
+			return t
+		}
+	case strings.HasPrefix(typ, "Map("):
+		if m, ok := v.(map[string]string); ok {
+			return m
+		}
+	}
+	return nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// unwrapNullable returns the type of the values of a Nullable type, and
+// whether it was Nullable.
+func unwrapNullable(typ string) (string, bool) {
+	if strings.HasPrefix(typ, "Nullable(") && strings.HasSuffix(typ, ")") {
+		return typ[len("Nullable(") : len(typ)-1], true
+	}
+	return typ, false
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(name) + "`"
+}
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(s) + "'"
+}
+
+func init() {
+	outputs.Add("clickhouse", func() telegraf.Output {
+		return &ClickHouse{
+			TableCreate:        true,
+			WaitForAsyncInsert: true,
+			Timeout:            internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,421 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+var ts = time.Unix(1500000000, 123456789).UTC()
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	m1, err := metric.New("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage_idle": 99.5, "threads": int64(4), "up": true, "state": "ok"},
+		ts)
+	require.NoError(t, err)
+	m2, err := metric.New("cpu",
+		map[string]string{"host": "b", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": int64(98)},
+		ts)
+	require.NoError(t, err)
+	return []telegraf.Metric{m1, m2}
+}
+
+func TestWideRows(t *testing.T) {
+	columns := map[string]string{}
+	rows := wideRows(testMetrics(t), columns)
+
+	require.Equal(t, map[string]string{
+		"timestamp":  "DateTime64(9, 'UTC')",
+		"host":       "String",
+		"cpu":        "String",
+		"usage_idle": "Nullable(Float64)",
+		"threads":    "Nullable(Int64)",
+		"up":         "Nullable(UInt8)",
+		"state":      "Nullable(String)",
+	}, columns)
+	require.Equal(t, []map[string]interface{}{
+		{"timestamp": ts, "host": "a", "usage_idle": 99.5, "threads": int64(4), "up": true, "state": "ok"},
+		{"timestamp": ts, "host": "b", "cpu": "cpu0", "usage_idle": int64(98)},
+	}, rows)
+}
+
+func TestKeyValueRows(t *testing.T) {
+	columns := map[string]string{}
+	rows := keyValueRows(testMetrics(t)[1:], columns)
+
+	require.Equal(t, "Map(String, String)", columns["tags"])
+	require.Equal(t, []map[string]interface{}{
+		{"timestamp": ts, "tags": map[string]string{"host": "b", "cpu": "cpu0"}, "field": "usage_idle", "value": 98.0},
+	}, rows)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		typ   string
+		want  interface{}
+	}{
+		{int64(98), "Nullable(Float64)", 98.0},
+		{99.5, "Int64", int64(99)},
+		{true, "Nullable(UInt8)", uint64(1)},
+		{int64(-1), "UInt64", nil},
+		{"ok", "Float64", nil},
+		{1.5, "String", "1.5"},
+		{uint64(1), "Bool", true},
+		{ts, "DateTime", ts},
+		{nil, "String", nil},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, convert(tt.value, tt.typ), "%v %s", tt.value, tt.typ)
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	columns := map[string]string{
+		"timestamp":  "DateTime64(9, 'UTC')",
+		"host":       "String",
+		"usage_idle": "Nullable(Float64)",
+	}
+	require.Equal(t,
+		"CREATE TABLE IF NOT EXISTS `db`.`cpu` (`timestamp` DateTime64(9, 'UTC'), `host` String, `usage_idle` Nullable(Float64)) ENGINE = MergeTree() ORDER BY timestamp",
+		createTable("`db`.`cpu`", columns, defaultTableEngines[schemaWide]))
+	require.Equal(t, "`a\\`b`", quoteIdentifier("a`b"))
+	require.Equal(t, `'it\'s'`, quoteString("it's"))
+}
+
+func TestHTTP(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	var inserted string
+	var params map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		user, password, _ := r.BasicAuth()
+		require.Equal(t, "telegraf", user)
+		require.Equal(t, "secret", password)
+
+		query := r.URL.Query().Get("query")
+		if query == "" {
+			query = string(body)
+		} else {
+			inserted = string(body)
+			params = r.URL.Query()
+		}
+		queries = append(queries, query)
+		if strings.HasPrefix(query, "SELECT name, type") && strings.Contains(query, "'cpu'") {
+			fmt.Fprintln(w, `{"name":"timestamp","type":"DateTime64(9, 'UTC')"}`)
+			fmt.Fprintln(w, `{"name":"host","type":"String"}`)
+			fmt.Fprintln(w, `{"name":"usage_idle","type":"Nullable(Float64)"}`)
+		}
+		if strings.HasPrefix(query, "INSERT INTO `telegraf`.`missing`") {
+			http.Error(w, "Code: 60. DB::Exception: Table doesn't exist", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ch := &ClickHouse{
+		URL:                srv.URL,
+		Database:           "telegraf",
+		Username:           "telegraf",
+		Password:           "secret",
+		TableCreate:        true,
+		AsyncInsert:        true,
+		WaitForAsyncInsert: true,
+	}
+	require.NoError(t, ch.Connect())
+	require.NoError(t, ch.Write(testMetrics(t)))
+
+	require.Equal(t, []string{
+		"SELECT 1",
+		"SELECT name, type FROM system.columns WHERE database = 'telegraf' AND table = 'cpu' FORMAT JSONEachRow",
+		"ALTER TABLE `telegraf`.`cpu` ADD COLUMN IF NOT EXISTS `cpu` String, ADD COLUMN IF NOT EXISTS `state` Nullable(String), " +
+			"ADD COLUMN IF NOT EXISTS `threads` Nullable(Int64), ADD COLUMN IF NOT EXISTS `up` Nullable(UInt8)",
+		"INSERT INTO `telegraf`.`cpu` (`cpu`, `host`, `state`, `threads`, `timestamp`, `up`, `usage_idle`) FORMAT JSONEachRow",
+	}, queries)
+	require.Equal(t,
+		`{"host":"a","state":"ok","threads":4,"timestamp":"2017-07-14T02:40:00.123456789Z","up":1,"usage_idle":99.5}`+"\n"+
+			`{"cpu":"cpu0","host":"b","timestamp":"2017-07-14T02:40:00.123456789Z","usage_idle":98}`+"\n",
+		inserted)
+	require.Equal(t, "1", params["async_insert"][0])
+	require.Equal(t, "1", params["wait_for_async_insert"][0])
+	require.Equal(t, "best_effort", params["date_time_input_format"][0])
+	require.Equal(t, "telegraf", params["database"][0])
+
+	// The columns of the table are only queried once.
+	queries = nil
+	require.NoError(t, ch.Write(testMetrics(t)))
+	require.Len(t, queries, 1)
+
+	// The tables which do not exist are created.
+	m, err := metric.New("missing", nil, map[string]interface{}{"value": 1.0}, ts)
+	require.NoError(t, err)
+	err = ch.Write([]telegraf.Metric{m})
+	require.EqualError(t, err, "writing to table missing: 404 Not Found: Code: 60. DB::Exception: Table doesn't exist")
+	require.Equal(t,
+		"CREATE TABLE IF NOT EXISTS `telegraf`.`missing` (`timestamp` DateTime64(9, 'UTC'), `value` Nullable(Float64)) ENGINE = MergeTree() ORDER BY timestamp",
+		queries[2])
+}
+
+// fakeServer is a ClickHouse server speaking the native protocol, with a
+// table of the columns given.
+type fakeServer struct {
+	listener net.Listener
+	columns  map[string]string
+
+	mu       sync.Mutex
+	queries  []string
+	settings map[string]string
+	blocks   []*block
+}
+
+func newFakeServer(t *testing.T, columns map[string]string) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: l, columns: columns}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+
+	// Hello of the client.
+	if packet, _ := binary.ReadUvarint(r); packet != clientHello {
+		return
+	}
+	readString(r)
+	skipUvarints(r, 3)
+	for i := 0; i < 3; i++ {
+		readString(r)
+	}
+	writeUvarint(w, serverHello)
+	writeString(w, "ClickHouse")
+	writeUvarint(w, 23)
+	writeUvarint(w, 8)
+	writeUvarint(w, 54465)
+	writeString(w, "UTC")
+	writeString(w, "fake")
+	writeUvarint(w, 1)
+	w.Flush()
+
+	for {
+		query, settings, err := readQuery(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.queries = append(s.queries, query)
+		s.settings = settings
+		s.mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(query, "SELECT name, type"):
+			b := &block{columns: []string{"name", "type"}, types: []string{"String", "String"}}
+			s.mu.Lock()
+			for name, typ := range s.columns {
+				b.rows = append(b.rows, []interface{}{name, typ})
+			}
+			s.mu.Unlock()
+			writeUvarint(w, serverData)
+			writeBlock(w, &block{columns: b.columns, types: b.types})
+			writeUvarint(w, serverData)
+			writeBlock(w, b)
+		case strings.HasPrefix(query, "INSERT INTO `missing`"):
+			writeUvarint(w, serverException)
+			writeFixed(w, 60, 4)
+			writeString(w, "DB::Exception")
+			writeString(w, "Table default.missing doesn't exist")
+			writeString(w, "")
+			w.WriteByte(0)
+			w.Flush()
+			continue
+		case strings.HasPrefix(query, "INSERT"):
+			writeUvarint(w, serverTableColumns)
+			writeString(w, "")
+			writeString(w, "columns format version: 1")
+			sample := &block{}
+			s.mu.Lock()
+			for name, typ := range s.columns {
+				sample.columns = append(sample.columns, name)
+				sample.types = append(sample.types, typ)
+			}
+			s.mu.Unlock()
+			writeUvarint(w, serverData)
+			writeBlock(w, sample)
+			w.Flush()
+			for {
+				if packet, _ := binary.ReadUvarint(r); packet != clientData {
+					return
+				}
+				b, err := readBlock(r)
+				if err != nil {
+					return
+				}
+				if len(b.columns) == 0 {
+					break
+				}
+				s.mu.Lock()
+				s.blocks = append(s.blocks, b)
+				s.mu.Unlock()
+			}
+			writeUvarint(w, serverProgress)
+			writeUvarint(w, 0)
+			writeUvarint(w, 0)
+			writeUvarint(w, 0)
+			writeUvarint(w, 2)
+			writeUvarint(w, 100)
+		}
+		writeUvarint(w, serverEndOfStream)
+		w.Flush()
+	}
+}
+
+// readQuery reads a query packet and the empty block of external tables
+// following it.
+func readQuery(r *bufio.Reader) (string, map[string]string, error) {
+	if packet, err := binary.ReadUvarint(r); err != nil || packet != clientQuery {
+		return "", nil, fmt.Errorf("expecting a query")
+	}
+	readString(r)
+	r.ReadByte()
+	for i := 0; i < 3; i++ {
+		readString(r)
+	}
+	r.ReadByte()
+	for i := 0; i < 3; i++ {
+		readString(r)
+	}
+	skipUvarints(r, 3)
+	readString(r)
+	skipUvarints(r, 1)
+
+	settings := map[string]string{}
+	for {
+		name, err := readString(r)
+		if err != nil {
+			return "", nil, err
+		}
+		if name == "" {
+			break
+		}
+		skipUvarints(r, 1)
+		settings[name], _ = readString(r)
+	}
+	skipUvarints(r, 2)
+	query, err := readString(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if packet, _ := binary.ReadUvarint(r); packet != clientData {
+		return "", nil, fmt.Errorf("expecting the external tables")
+	}
+	_, err = readBlock(r)
+	return query, settings, err
+}
+
+func TestNative(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"timestamp":  "DateTime64(9, 'UTC')",
+		"tags":       "Map(String, String)",
+		"field":      "LowCardinality(String)",
+		"value":      "Float64",
+		"unexpected": "UInt32",
+	})
+	defer s.listener.Close()
+
+	ch := &ClickHouse{
+		URL:         "tcp://" + s.listener.Addr().String(),
+		Schema:      schemaKeyValue,
+		AsyncInsert: true,
+		Timeout:     internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, ch.Connect())
+	defer ch.Close()
+
+	err := ch.Write(testMetrics(t)[1:])
+	// The types of the sample block are used.
+	require.EqualError(t, err, "writing to table cpu: column field: unsupported type LowCardinality(String)")
+
+	s.mu.Lock()
+	s.columns["field"] = "String"
+	s.mu.Unlock()
+	require.NoError(t, ch.Write(testMetrics(t)))
+
+	s.mu.Lock()
+	require.Equal(t, "SELECT name, type FROM system.columns WHERE database = currentDatabase() AND table = 'cpu'", s.queries[0])
+	require.Equal(t, "INSERT INTO `cpu` (`field`, `tags`, `timestamp`, `value`) VALUES", s.queries[len(s.queries)-1])
+	require.Equal(t, map[string]string{"async_insert": "1", "wait_for_async_insert": "0"}, s.settings)
+
+	require.Len(t, s.blocks, 1)
+	b := s.blocks[0]
+	require.Equal(t, []string{"field", "tags", "timestamp", "value"}, b.columns)
+	require.Equal(t, []string{"String", "Map(String, String)", "DateTime64(9, 'UTC')", "Float64"}, b.types)
+	tagsA := map[string]string{"host": "a"}
+	tagsB := map[string]string{"host": "b", "cpu": "cpu0"}
+	require.ElementsMatch(t, [][]interface{}{
+		{"usage_idle", tagsA, ts, 99.5},
+		{"threads", tagsA, ts, 4.0},
+		{"up", tagsA, ts, 1.0},
+		{"usage_idle", tagsB, ts, 98.0},
+	}, b.rows)
+	s.mu.Unlock()
+
+	m, err := metric.New("missing", nil, map[string]interface{}{"value": 1.0}, ts)
+	require.NoError(t, err)
+	err = ch.Write([]telegraf.Metric{m})
+	require.EqualError(t, err, "writing to table missing: code 60: Table default.missing doesn't exist")
+}
+
+func TestColumnEncoding(t *testing.T) {
+	tests := []struct {
+		typ    string
+		values []interface{}
+	}{
+		{"Nullable(Int32)", []interface{}{int64(-5), nil, int64(7)}},
+		{"UInt16", []interface{}{uint64(65535), uint64(0)}},
+		{"Float32", []interface{}{1.5, -2.0}},
+		{"Nullable(String)", []interface{}{"a", nil, ""}},
+		{"Bool", []interface{}{true, false}},
+		{"DateTime('UTC')", []interface{}{time.Unix(1500000000, 0).UTC()}},
+		{"DateTime64(3)", []interface{}{time.Unix(1500000000, 123000000).UTC()}},
+		{"Map(String, String)", []interface{}{map[string]string{"a": "1", "b": "2"}, map[string]string{}, map[string]string{"c": "3"}}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		require.NoError(t, writeColumn(w, tt.typ, tt.values))
+		require.NoError(t, w.Flush())
+
+		values, err := readColumn(bufio.NewReader(&buf), tt.typ, len(tt.values))
+		require.NoError(t, err)
+		require.Equal(t, tt.values, values, tt.typ)
+	}
+}
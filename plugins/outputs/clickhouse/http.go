@@ -0,0 +1,170 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// httpConn queries the HTTP interface of ClickHouse.
+type httpConn struct {
+	client   *http.Client
+	url      *url.URL
+	database string
+	username string
+	password string
+}
+
+func newHTTPConn(u *url.URL, database, username, password string, timeout time.Duration, tlsCfg *tls.Config) (*httpConn, error) {
+	c := &httpConn{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: timeout,
+		},
+		url:      u,
+		database: database,
+		username: username,
+		password: password,
+	}
+	return c, c.exec("SELECT 1")
+}
+
+// query posts a query, in the body or, with the data of an insert, in the
+// URL.
+func (c *httpConn) query(query string, settings map[string]string, data io.Reader) ([]byte, error) {
+	u := *c.url
+	params := u.Query()
+	if c.database != "" {
+		params.Set("database", c.database)
+	}
+	for name, value := range settings {
+		params.Set(name, value)
+	}
+	body := data
+	if data == nil {
+		body = strings.NewReader(query)
+	} else {
+		params.Set("query", query)
+	}
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(result))
+	}
+	return result, nil
+}
+
+func (c *httpConn) exec(query string) error {
+	_, err := c.query(query, nil, nil)
+	return err
+}
+
+func (c *httpConn) columns(database, table string) (map[string]string, error) {
+	result, err := c.query(columnsQuery(database, table)+" FORMAT JSONEachRow", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(result))
+	for scanner.Scan() {
+		var column struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &column); err != nil {
+			return nil, err
+		}
+		columns[column.Name] = column.Type
+	}
+	return columns, scanner.Err()
+}
+
+// insert inserts the rows in the JSONEachRow format.  The timestamps are in
+// the RFC3339 format, parsed whatever the time zone of their column with
+// the best effort parsing of ClickHouse.
+func (c *httpConn) insert(table string, b *block, settings map[string]string) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, values := range b.rows {
+		row := make(map[string]interface{}, len(b.columns))
+		for i, v := range values {
+			switch v := v.(type) {
+			case nil:
+				continue
+			case float64:
+				// The infinite and NaN values cannot be encoded in JSON.
+				if math.IsInf(v, 0) || math.IsNaN(v) {
+					continue
+				}
+			case time.Time:
+				row[b.columns[i]] = v.UTC().Format(time.RFC3339Nano)
+				continue
+			}
+			row[b.columns[i]] = v
+		}
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	params := map[string]string{"date_time_input_format": "best_effort"}
+	for name, value := range settings {
+		params[name] = value
+	}
+	query := "INSERT INTO " + table + " (" + quoteIdentifiers(b.columns) + ") FORMAT JSONEachRow"
+	_, err := c.query(query, params, &data)
+	return err
+}
+
+func (c *httpConn) close() error {
+	return nil
+}
+
+// columnsQuery returns the query of the columns of a table, in the current
+// database by default.
+func columnsQuery(database, table string) string {
+	db := "currentDatabase()"
+	if database != "" {
+		db = quoteString(database)
+	}
+	return "SELECT name, type FROM system.columns WHERE database = " + db +
+		" AND table = " + quoteString(table)
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,730 @@
+package clickhouse
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	nativeClientName   = "telegraf"
+	nativeVersionMajor = 1
+	nativeVersionMinor = 7
+	// nativeRevision is the revision of the native protocol implemented,
+	// the first one with the settings sent as strings.  The servers use the
+	// older of their revision and the one of the client.
+	nativeRevision = 54429
+)
+
+// Packets of the client.
+const (
+	clientHello = 0
+	clientQuery = 1
+	clientData  = 2
+)
+
+// Packets of the server.
+const (
+	serverHello        = 0
+	serverData         = 1
+	serverException    = 2
+	serverProgress     = 3
+	serverEndOfStream  = 5
+	serverProfileInfo  = 6
+	serverTotals       = 7
+	serverExtremes     = 8
+	serverLog          = 10
+	serverTableColumns = 11
+)
+
+const (
+	queryKindInitial   = 1
+	interfaceTCP       = 1
+	stageComplete      = 2
+	compressionDisable = 0
+)
+
+// maxStringSize bounds the strings read, so that a corrupted stream does
+// not allocate all the memory.
+const maxStringSize = 1 << 30
+
+// nativeConn is a connection over the native protocol of ClickHouse, without
+// compression.
+type nativeConn struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	w        *bufio.Writer
+	timeout  time.Duration
+	hostname string
+}
+
+func dialNative(u *url.URL, database, username, password string, timeout time.Duration, tlsCfg *tls.Config) (*nativeConn, error) {
+	address := u.Host
+	if u.Port() == "" {
+		port := "9000"
+		if u.Scheme == "tcps" {
+			port = "9440"
+		}
+		address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var c net.Conn
+	var err error
+	if u.Scheme == "tcps" {
+		if tlsCfg == nil {
+			tlsCfg = &tls.Config{}
+		}
+		c, err = tls.DialWithDialer(dialer, "tcp", address, tlsCfg)
+	} else {
+		c, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nc := &nativeConn{
+		conn:    c,
+		r:       bufio.NewReader(c),
+		w:       bufio.NewWriter(c),
+		timeout: timeout,
+	}
+	nc.hostname, _ = os.Hostname()
+	if username == "" {
+		username = "default"
+	}
+	if err := nc.hello(database, username, password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return nc, nil
+}
+
+func (c *nativeConn) setDeadline() {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+func (c *nativeConn) hello(database, username, password string) error {
+	c.setDeadline()
+	writeUvarint(c.w, clientHello)
+	writeString(c.w, nativeClientName)
+	writeUvarint(c.w, nativeVersionMajor)
+	writeUvarint(c.w, nativeVersionMinor)
+	writeUvarint(c.w, nativeRevision)
+	writeString(c.w, database)
+	writeString(c.w, username)
+	writeString(c.w, password)
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+
+	packet, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return err
+	}
+	switch packet {
+	case serverHello:
+	case serverException:
+		return readException(c.r)
+	default:
+		return fmt.Errorf("unexpected packet %d instead of the hello of the server", packet)
+	}
+
+	// Name, version, revision, time zone, display name and version patch.
+	if _, err := readString(c.r); err != nil {
+		return err
+	}
+	var revision uint64
+	for i := 0; i < 3; i++ {
+		if revision, err = binary.ReadUvarint(c.r); err != nil {
+			return err
+		}
+	}
+	if revision < nativeRevision {
+		return fmt.Errorf("unsupported server revision %d, %d or later is required", revision, nativeRevision)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := readString(c.r); err != nil {
+			return err
+		}
+	}
+	_, err = binary.ReadUvarint(c.r)
+	return err
+}
+
+// sendQuery sends a query, followed by the empty block ending the external
+// tables.
+func (c *nativeConn) sendQuery(query string, settings map[string]string) error {
+	c.setDeadline()
+	writeUvarint(c.w, clientQuery)
+	writeString(c.w, "")
+
+	// Client info.
+	c.w.WriteByte(queryKindInitial)
+	writeString(c.w, "")
+	writeString(c.w, "")
+	writeString(c.w, "0.0.0.0:0")
+	c.w.WriteByte(interfaceTCP)
+	writeString(c.w, "")
+	writeString(c.w, c.hostname)
+	writeString(c.w, nativeClientName)
+	writeUvarint(c.w, nativeVersionMajor)
+	writeUvarint(c.w, nativeVersionMinor)
+	writeUvarint(c.w, nativeRevision)
+	writeString(c.w, "")
+	writeUvarint(c.w, 0)
+
+	// Settings, as names, flags and values, up to an empty name.
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeString(c.w, name)
+		writeUvarint(c.w, 0)
+		writeString(c.w, settings[name])
+	}
+	writeString(c.w, "")
+
+	writeUvarint(c.w, stageComplete)
+	writeUvarint(c.w, compressionDisable)
+	writeString(c.w, query)
+	writeUvarint(c.w, clientData)
+	if err := writeBlock(c.w, &block{}); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// receive reads the packets of the server up to the end of the query,
+// passing the blocks of data to onData until it returns true.
+func (c *nativeConn) receive(onData func(*block) bool) error {
+	for {
+		packet, err := binary.ReadUvarint(c.r)
+		if err != nil {
+			return err
+		}
+		switch packet {
+		case serverData, serverTotals, serverExtremes, serverLog:
+			b, err := readBlock(c.r)
+			if err != nil {
+				return err
+			}
+			if packet == serverData && onData != nil && onData(b) {
+				return nil
+			}
+		case serverException:
+			return readException(c.r)
+		case serverProgress:
+			// Rows, bytes and total rows read, rows and bytes written.
+			if err := skipUvarints(c.r, 5); err != nil {
+				return err
+			}
+		case serverProfileInfo:
+			// Rows, blocks, bytes, applied limit, rows before limit, and
+			// whether they were calculated.
+			if err := skipUvarints(c.r, 3); err != nil {
+				return err
+			}
+			if _, err := c.r.ReadByte(); err != nil {
+				return err
+			}
+			if err := skipUvarints(c.r, 1); err != nil {
+				return err
+			}
+			if _, err := c.r.ReadByte(); err != nil {
+				return err
+			}
+		case serverTableColumns:
+			// External table name and description of the columns.
+			for i := 0; i < 2; i++ {
+				if _, err := readString(c.r); err != nil {
+					return err
+				}
+			}
+		case serverEndOfStream:
+			return nil
+		default:
+			return fmt.Errorf("unexpected packet %d", packet)
+		}
+	}
+}
+
+func (c *nativeConn) exec(query string) error {
+	if err := c.sendQuery(query, nil); err != nil {
+		return err
+	}
+	return c.receive(nil)
+}
+
+func (c *nativeConn) columns(database, table string) (map[string]string, error) {
+	if err := c.sendQuery(columnsQuery(database, table), nil); err != nil {
+		return nil, err
+	}
+	columns := make(map[string]string)
+	err := c.receive(func(b *block) bool {
+		if len(b.columns) == 2 {
+			for _, row := range b.rows {
+				name, _ := row[0].(string)
+				typ, _ := row[1].(string)
+				columns[name] = typ
+			}
+		}
+		return false
+	})
+	return columns, err
+}
+
+// insert sends the block after receiving the block of the structure of the
+// table, with the types of the columns inserted.
+func (c *nativeConn) insert(table string, b *block, settings map[string]string) error {
+	query := "INSERT INTO " + table + " (" + quoteIdentifiers(b.columns) + ") VALUES"
+	if err := c.sendQuery(query, settings); err != nil {
+		return err
+	}
+	var sample *block
+	if err := c.receive(func(s *block) bool { sample = s; return true }); err != nil {
+		return err
+	}
+	if sample == nil {
+		return fmt.Errorf("no structure of the table received")
+	}
+
+	types := make(map[string]string)
+	for i, name := range sample.columns {
+		types[name] = sample.types[i]
+	}
+	data := &block{columns: b.columns, rows: b.rows}
+	for i, name := range b.columns {
+		typ, ok := types[name]
+		if !ok {
+			typ = b.types[i]
+		}
+		data.types = append(data.types, typ)
+	}
+
+	c.setDeadline()
+	writeUvarint(c.w, clientData)
+	if err := writeBlock(c.w, data); err != nil {
+		return err
+	}
+	writeUvarint(c.w, clientData)
+	if err := writeBlock(c.w, &block{}); err != nil {
+		return err
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	return c.receive(nil)
+}
+
+func (c *nativeConn) close() error {
+	return c.conn.Close()
+}
+
+func writeUvarint(w *bufio.Writer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	w.Write(buf[:n])
+}
+
+func writeString(w *bufio.Writer, s string) {
+	writeUvarint(w, uint64(len(s)))
+	w.WriteString(s)
+}
+
+func writeFixed(w *bufio.Writer, v uint64, size int) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	w.Write(buf[:size])
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > maxStringSize {
+		return "", fmt.Errorf("string of %d bytes", n)
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+func readFixed(r *bufio.Reader, size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+func skipUvarints(r *bufio.Reader, n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := binary.ReadUvarint(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readException returns the exception sent by the server, with its nested
+// exceptions.
+func readException(r *bufio.Reader) error {
+	code, err := readFixed(r, 4)
+	if err != nil {
+		return err
+	}
+	var texts [3]string // Name, message and stack trace.
+	for i := range texts {
+		if texts[i], err = readString(r); err != nil {
+			return err
+		}
+	}
+	nested, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("code %d: %s", int32(code), texts[1])
+	if nested != 0 {
+		if err := readException(r); err != nil {
+			msg += ": " + err.Error()
+		}
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// writeBlock writes the block of a data packet, after the packet type.  The
+// block is preceded by the name of the external table, empty, and the block
+// info.
+func writeBlock(w *bufio.Writer, b *block) error {
+	writeString(w, "")
+	writeBlockInfo(w)
+	writeUvarint(w, uint64(len(b.columns)))
+	writeUvarint(w, uint64(len(b.rows)))
+	for i, name := range b.columns {
+		writeString(w, name)
+		writeString(w, b.types[i])
+		values := make([]interface{}, len(b.rows))
+		for j, row := range b.rows {
+			values[j] = row[i]
+		}
+		if err := writeColumn(w, b.types[i], values); err != nil {
+			return fmt.Errorf("column %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// writeBlockInfo writes the info of a block: not an overflow, without
+// bucket.
+func writeBlockInfo(w *bufio.Writer) {
+	writeUvarint(w, 1)
+	w.WriteByte(0)
+	writeUvarint(w, 2)
+	writeFixed(w, uint64(math.MaxUint32), 4)
+	writeUvarint(w, 0)
+}
+
+// readBlock reads a block of a data packet, after the packet type.
+func readBlock(r *bufio.Reader) (*block, error) {
+	if _, err := readString(r); err != nil {
+		return nil, err
+	}
+	for {
+		field, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case 0:
+		case 1:
+			_, err = r.ReadByte()
+		case 2:
+			_, err = readFixed(r, 4)
+		default:
+			return nil, fmt.Errorf("unknown block info field %d", field)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if field == 0 {
+			break
+		}
+	}
+
+	columns, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if rows > maxStringSize {
+		return nil, fmt.Errorf("block of %d rows", rows)
+	}
+
+	b := &block{rows: make([][]interface{}, rows)}
+	for i := range b.rows {
+		b.rows[i] = make([]interface{}, columns)
+	}
+	for i := 0; i < int(columns); i++ {
+		name, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		values, err := readColumn(r, typ, int(rows))
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %s", name, err)
+		}
+		for j, v := range values {
+			b.rows[j][i] = v
+		}
+		b.columns = append(b.columns, name)
+		b.types = append(b.types, typ)
+	}
+	return b, nil
+}
+
+// columnType is the encoding of the values of a column type.
+type columnType struct {
+	kind string // string, int, uint, float, bool, datetime or map
+	size int    // size of the fixed size values
+	// scale is the number of nanoseconds of the ticks of DateTime64.
+	scale int64
+}
+
+func parseColumnType(typ string) (columnType, error) {
+	switch typ {
+	case "String":
+		return columnType{kind: "string"}, nil
+	case "Int8", "Int16", "Int32", "Int64":
+		bits, _ := strconv.Atoi(typ[3:])
+		return columnType{kind: "int", size: bits / 8}, nil
+	case "UInt8", "UInt16", "UInt32", "UInt64":
+		bits, _ := strconv.Atoi(typ[4:])
+		return columnType{kind: "uint", size: bits / 8}, nil
+	case "Float32":
+		return columnType{kind: "float", size: 4}, nil
+	case "Float64":
+		return columnType{kind: "float", size: 8}, nil
+	case "Bool":
+		return columnType{kind: "bool", size: 1}, nil
+	case "Map(String, String)":
+		return columnType{kind: "map"}, nil
+	}
+	if typ == "DateTime" || strings.HasPrefix(typ, "DateTime(") {
+		return columnType{kind: "datetime", size: 4, scale: int64(time.Second)}, nil
+	}
+	if strings.HasPrefix(typ, "DateTime64(") {
+		params := strings.SplitN(strings.TrimSuffix(typ[len("DateTime64("):], ")"), ",", 2)
+		precision, err := strconv.Atoi(strings.TrimSpace(params[0]))
+		if err == nil && precision >= 0 && precision <= 9 {
+			scale := int64(1)
+			for i := precision; i < 9; i++ {
+				scale *= 10
+			}
+			return columnType{kind: "datetime", size: 8, scale: scale}, nil
+		}
+	}
+	return columnType{}, fmt.Errorf("unsupported type %s", typ)
+}
+
+// writeColumn writes the values of a column, nil being the default value.
+func writeColumn(w *bufio.Writer, typ string, values []interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if inner, ok := unwrapNullable(typ); ok {
+		for _, v := range values {
+			if v == nil {
+				w.WriteByte(1)
+			} else {
+				w.WriteByte(0)
+			}
+		}
+		typ = inner
+	}
+	ct, err := parseColumnType(typ)
+	if err != nil {
+		return err
+	}
+
+	switch ct.kind {
+	case "string":
+		for _, v := range values {
+			s, _ := v.(string)
+			writeString(w, s)
+		}
+	case "map":
+		var offset uint64
+		keys := make([][]string, len(values))
+		for i, v := range values {
+			m, _ := v.(map[string]string)
+			for k := range m {
+				keys[i] = append(keys[i], k)
+			}
+			sort.Strings(keys[i])
+			offset += uint64(len(m))
+			writeFixed(w, offset, 8)
+		}
+		for i := range values {
+			for _, k := range keys[i] {
+				writeString(w, k)
+			}
+		}
+		for i, v := range values {
+			m, _ := v.(map[string]string)
+			for _, k := range keys[i] {
+				writeString(w, m[k])
+			}
+		}
+	default:
+		for _, v := range values {
+			writeFixed(w, fixedValue(ct, v), ct.size)
+		}
+	}
+	return nil
+}
+
+// fixedValue returns the bits of a fixed size value.
+func fixedValue(ct columnType, v interface{}) uint64 {
+	switch v := v.(type) {
+	case int64:
+		return uint64(v)
+	case uint64:
+		return v
+	case float64:
+		if ct.size == 4 {
+			return uint64(math.Float32bits(float32(v)))
+		}
+		return math.Float64bits(v)
+	case bool:
+		if v {
+			return 1
+		}
+	case time.Time:
+		if ct.scale == int64(time.Second) {
+			return uint64(v.Unix())
+		}
+		return uint64(v.UnixNano() / ct.scale)
+	}
+	return 0
+}
+
+// readColumn reads the values of a column of the rows of a block.
+func readColumn(r *bufio.Reader, typ string, rows int) ([]interface{}, error) {
+	values := make([]interface{}, rows)
+	if rows == 0 {
+		return values, nil
+	}
+	var nulls []byte
+	if inner, ok := unwrapNullable(typ); ok {
+		nulls = make([]byte, rows)
+		if _, err := io.ReadFull(r, nulls); err != nil {
+			return nil, err
+		}
+		typ = inner
+	}
+	ct, err := parseColumnType(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ct.kind {
+	case "string":
+		for i := range values {
+			if values[i], err = readString(r); err != nil {
+				return nil, err
+			}
+		}
+	case "map":
+		offsets := make([]uint64, rows)
+		for i := range offsets {
+			if offsets[i], err = readFixed(r, 8); err != nil {
+				return nil, err
+			}
+		}
+		var total uint64
+		if rows > 0 {
+			total = offsets[rows-1]
+		}
+		if total > maxStringSize {
+			return nil, fmt.Errorf("map of %d entries", total)
+		}
+		keys := make([]string, total)
+		for i := range keys {
+			if keys[i], err = readString(r); err != nil {
+				return nil, err
+			}
+		}
+		var start uint64
+		for i := range values {
+			m := make(map[string]string)
+			for j := start; j < offsets[i] && j < total; j++ {
+				if m[keys[j]], err = readString(r); err != nil {
+					return nil, err
+				}
+			}
+			start = offsets[i]
+			values[i] = m
+		}
+	default:
+		for i := range values {
+			bits, err := readFixed(r, ct.size)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = fromFixed(ct, bits)
+		}
+	}
+
+	for i, null := range nulls {
+		if null != 0 {
+			values[i] = nil
+		}
+	}
+	return values, nil
+}
+
+func fromFixed(ct columnType, bits uint64) interface{} {
+	switch ct.kind {
+	case "int":
+		shift := uint(64 - 8*ct.size)
+		return int64(bits<<shift) >> shift
+	case "uint":
+		return bits
+	case "float":
+		if ct.size == 4 {
+			return float64(math.Float32frombits(uint32(bits)))
+		}
+		return math.Float64frombits(bits)
+	case "bool":
+		return bits != 0
+	case "datetime":
+		if ct.scale == int64(time.Second) {
+			return time.Unix(int64(bits), 0).UTC()
+		}
+		return time.Unix(0, int64(bits)*ct.scale).UTC()
+	}
+	return nil
+}
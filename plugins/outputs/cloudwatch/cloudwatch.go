@@ -3,11 +3,13 @@ package cloudwatch
 import (
 	"log"
 	"math"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/query/queryutil"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/sts"
 
@@ -137,6 +139,24 @@ func (c *CloudWatch) WriteToCloudWatch(datums []*cloudwatch.MetricDatum) error {
 	return err
 }
 
+// MetricSize returns the size of the MetricDatums of the metric in the form
+// encoded body of the PutMetricData requests, for max_body_size.
+func (c *CloudWatch) MetricSize(metric telegraf.Metric) int {
+	datums := BuildMetricDatum(metric)
+	if len(datums) == 0 {
+		return 0
+	}
+	params := &cloudwatch.PutMetricDataInput{
+		MetricData: datums,
+	}
+	body := url.Values{}
+	if err := queryutil.Parse(body, params, false); err != nil {
+		return 0
+	}
+	// the parameters are joined to the others with a "&"
+	return len(body.Encode()) + 1
+}
+
 // Partition the MetricDatums into smaller slices of a max size so that are under the limit
 // for the AWS API calls.
 func PartitionDatums(size int, datums []*cloudwatch.MetricDatum) [][]*cloudwatch.MetricDatum {
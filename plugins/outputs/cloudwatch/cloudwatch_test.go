@@ -5,11 +5,13 @@ import (
 	"math"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -81,6 +83,26 @@ func TestBuildMetricDatums(t *testing.T) {
 	}
 }
 
+func TestMetricSize(t *testing.T) {
+	c := &CloudWatch{}
+
+	m, _ := metric.New("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0).UTC())
+	body := "&MetricData.member.1.Dimensions.member.1.Name=host" +
+		"&MetricData.member.1.Dimensions.member.1.Value=a" +
+		"&MetricData.member.1.MetricName=cpu_value" +
+		"&MetricData.member.1.Timestamp=1970-01-01T00%3A00%3A00Z" +
+		"&MetricData.member.1.Value=1"
+	assert.Equal(t, len(body), c.MetricSize(m))
+
+	m, _ = metric.New("cpu", nil,
+		map[string]interface{}{"value": "Foo"},
+		time.Unix(0, 0))
+	assert.Equal(t, 0, c.MetricSize(m))
+}
+
 func TestPartitionDatums(t *testing.T) {
 
 	assert := assert.New(t)
@@ -11,16 +11,25 @@ import (
 	"strings"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
 type Datadog struct {
-	Apikey  string
-	Timeout internal.Duration
+	Apikey         string
+	Timeout        internal.Duration
+	Site           string   `toml:"site"`
+	APIVersion     string   `toml:"api_version"`
+	Distributions  []string `toml:"distributions"`
+	HTTPProxy      string   `toml:"http_proxy"`
+	MaxPayloadSize int      `toml:"max_payload_size"`
 
-	apiUrl string
-	client *http.Client
+	// apiUrl is the URL of the v1 series endpoint, the other endpoints
+	// being on the same host.
+	apiUrl        string
+	client        *http.Client
+	distributions filter.Filter
 }
 
 var sampleConfig = `
@@ -29,6 +38,27 @@ var sampleConfig = `
 
   ## Connection timeout.
   # timeout = "5s"
+
+  ## Datadog site of the account, such as "datadoghq.eu" for the EU region
+  ## or "us3.datadoghq.com".  Defaults to the US1 site.
+  # site = "datadoghq.com"
+
+  ## Version of the metrics API: "v1", or "v2" which sends the host as a
+  ## resource and the gauges with their type.
+  # api_version = "v1"
+
+  ## Metrics sent as distributions instead, aggregated by Datadog across the
+  ## hosts, as names of the Datadog metrics with glob patterns, such as
+  ## ["http_request.duration_*"].
+  # distributions = []
+
+  ## HTTP Proxy URL.  Defaults to the HTTPS_PROXY environment variable.
+  # http_proxy = "http://corporate.proxy:3128"
+
+  ## Maximum size of the requests in bytes, the metrics of the larger ones
+  ## being sent in several requests.  Defaults to the limit of the API,
+  ## 3200000 bytes for v1 and 512000 bytes for v2.
+  # max_payload_size = 0
 `
 
 type TimeSeries struct {
@@ -44,8 +74,72 @@ type Metric struct {
 
 type Point [2]float64
 
+// TimeSeriesV2 is the payload of the v2 series endpoint.
+type TimeSeriesV2 struct {
+	Series []*MetricV2 `json:"series"`
+}
+
+type MetricV2 struct {
+	Metric    string      `json:"metric"`
+	Type      int         `json:"type"`
+	Points    [1]PointV2  `json:"points"`
+	Resources []*Resource `json:"resources,omitempty"`
+	Tags      []string    `json:"tags,omitempty"`
+}
+
+type PointV2 struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type Resource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types of the v2 series.
+const (
+	typeUnspecified = 0
+	typeGauge       = 3
+)
+
+// Distributions is the payload of the distribution points endpoint.
+type Distributions struct {
+	Series []*Distribution `json:"series"`
+}
+
+type Distribution struct {
+	Metric string              `json:"metric"`
+	Points []DistributionPoint `json:"points"`
+	Host   string              `json:"host"`
+	Tags   []string            `json:"tags,omitempty"`
+}
+
+// DistributionPoint is the timestamp and the values of a distribution.
+type DistributionPoint [2]interface{}
+
 const datadog_api = "https://app.datadoghq.com/api/v1/series"
 
+const (
+	seriesV1Path           = "/api/v1/series"
+	seriesV2Path           = "/api/v2/series"
+	distributionPointsPath = "/api/v1/distribution_points"
+
+	maxPayloadSizeV1 = 3200000
+	maxPayloadSizeV2 = 512000
+
+	redactedApiKey = "****************"
+)
+
+// series is a value of a metric to send.
+type series struct {
+	name      string
+	host      string
+	tags      []string
+	valueType telegraf.ValueType
+	point     Point
+}
+
 func NewDatadog(apiUrl string) *Datadog {
 	return &Datadog{
 		apiUrl: apiUrl,
@@ -57,9 +151,43 @@ func (d *Datadog) Connect() error {
 		return fmt.Errorf("apikey is a required field for datadog output")
 	}
 
+	switch d.APIVersion {
+	case "", "v1":
+		if d.MaxPayloadSize == 0 {
+			d.MaxPayloadSize = maxPayloadSizeV1
+		}
+	case "v2":
+		if d.MaxPayloadSize == 0 {
+			d.MaxPayloadSize = maxPayloadSizeV2
+		}
+	default:
+		return fmt.Errorf("unknown api_version %q", d.APIVersion)
+	}
+	if d.MaxPayloadSize < 0 {
+		return fmt.Errorf("max_payload_size cannot be negative")
+	}
+
+	if d.Site != "" {
+		d.apiUrl = "https://api." + d.Site + seriesV1Path
+	}
+
+	var err error
+	if d.distributions, err = filter.Compile(d.Distributions); err != nil {
+		return fmt.Errorf("invalid distributions: %s", err)
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if d.HTTPProxy != "" {
+		proxyURL, err := url.Parse(d.HTTPProxy)
+		if err != nil {
+			return fmt.Errorf("error parsing http_proxy [%s]: %v", d.HTTPProxy, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
 	d.client = &http.Client{
 		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
+			Proxy: proxy,
 		},
 		Timeout: d.Timeout.Duration,
 	}
@@ -70,9 +198,9 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
-	ts := TimeSeries{}
-	tempSeries := []*Metric{}
-	metricCounter := 0
+	var values []*series
+	var distributions []*Distribution
+	distributionIndex := make(map[string]*Distribution)
 
 	for _, m := range metrics {
 		if dogMs, err := buildMetrics(m); err == nil {
@@ -87,32 +215,116 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 				}
 				var host string
 				host, _ = m.Tags()["host"]
-				metric := &Metric{
-					Metric: dname,
-					Tags:   buildTags(m.Tags()),
-					Host:   host,
+				tags := buildTags(m.Tags())
+
+				if d.distributions != nil && d.distributions.Match(dname) {
+					// The values of the same distribution are sent together.
+					key := dname + "\n" + host + "\n" + strings.Join(tags, ",")
+					dist, ok := distributionIndex[key]
+					if !ok {
+						dist = &Distribution{Metric: dname, Host: host, Tags: tags}
+						distributionIndex[key] = dist
+						distributions = append(distributions, dist)
+					}
+					dist.addValue(dogM)
+					continue
 				}
-				metric.Points[0] = dogM
-				tempSeries = append(tempSeries, metric)
-				metricCounter++
+
+				values = append(values, &series{
+					name:      dname,
+					host:      host,
+					tags:      tags,
+					valueType: m.Type(),
+					point:     dogM,
+				})
 			}
 		} else {
 			log.Printf("I! unable to build Metric for %s due to error '%v', skipping\n", m.Name(), err)
 		}
 	}
 
-	redactedApiKey := "****************"
-	ts.Series = make([]*Metric, metricCounter)
-	copy(ts.Series, tempSeries[0:])
-	tsBytes, err := json.Marshal(ts)
+	if len(values) > 0 {
+		if err := d.writeSeries(values); err != nil {
+			return err
+		}
+	}
+	if len(distributions) > 0 {
+		endpoint := d.endpoint(distributionPointsPath)
+		return d.postSplit(endpoint, len(distributions), func(i, j int) ([]byte, error) {
+			return json.Marshal(Distributions{Series: distributions[i:j]})
+		})
+	}
+	return nil
+}
+
+// writeSeries sends the series with the version of the API.
+func (d *Datadog) writeSeries(values []*series) error {
+	if d.APIVersion == "v2" {
+		return d.postSplit(d.endpoint(seriesV2Path), len(values), func(i, j int) ([]byte, error) {
+			ts := TimeSeriesV2{Series: make([]*MetricV2, 0, j-i)}
+			for _, v := range values[i:j] {
+				metric := &MetricV2{
+					Metric: v.name,
+					Type:   typeUnspecified,
+					Tags:   v.tags,
+				}
+				if v.valueType == telegraf.Gauge {
+					metric.Type = typeGauge
+				}
+				if v.host != "" {
+					metric.Resources = []*Resource{{Name: v.host, Type: "host"}}
+				}
+				metric.Points[0] = PointV2{Timestamp: int64(v.point[0]), Value: v.point[1]}
+				ts.Series = append(ts.Series, metric)
+			}
+			return json.Marshal(ts)
+		})
+	}
+
+	return d.postSplit(d.authenticatedUrl(), len(values), func(i, j int) ([]byte, error) {
+		ts := TimeSeries{Series: make([]*Metric, 0, j-i)}
+		for _, v := range values[i:j] {
+			metric := &Metric{
+				Metric: v.name,
+				Tags:   v.tags,
+				Host:   v.host,
+			}
+			metric.Points[0] = v.point
+			ts.Series = append(ts.Series, metric)
+		}
+		return json.Marshal(ts)
+	})
+}
+
+// postSplit posts the n items marshalled by marshal, in several requests
+// if they are over max_payload_size.  An item over max_payload_size on its
+// own is sent anyway, for the API to report the error.
+func (d *Datadog) postSplit(endpoint string, n int, marshal func(i, j int) ([]byte, error)) error {
+	return d.postRange(endpoint, 0, n, marshal)
+}
+
+func (d *Datadog) postRange(endpoint string, i, j int, marshal func(i, j int) ([]byte, error)) error {
+	body, err := marshal(i, j)
 	if err != nil {
 		return fmt.Errorf("unable to marshal TimeSeries, %s\n", err.Error())
 	}
-	req, err := http.NewRequest("POST", d.authenticatedUrl(), bytes.NewBuffer(tsBytes))
+	if len(body) > d.MaxPayloadSize && j-i > 1 {
+		middle := (i + j) / 2
+		if err := d.postRange(endpoint, i, middle, marshal); err != nil {
+			return err
+		}
+		return d.postRange(endpoint, middle, j, marshal)
+	}
+	return d.post(endpoint, body)
+}
+
+func (d *Datadog) post(endpoint string, body []byte) error {
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("unable to create http.Request, %s\n", strings.Replace(err.Error(), d.Apikey, redactedApiKey, -1))
 	}
 	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("DD-API-KEY", d.Apikey)
 
 	resp, err := d.client.Do(req)
 	if err != nil {
@@ -127,6 +339,23 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// endpoint returns the URL of the endpoint on the host of the v1 series
+// endpoint.
+func (d *Datadog) endpoint(path string) string {
+	return strings.TrimSuffix(d.apiUrl, seriesV1Path) + path
+}
+
+// addValue adds a value to the point of its timestamp.
+func (dist *Distribution) addValue(p Point) {
+	for i, point := range dist.Points {
+		if point[0] == p[0] {
+			dist.Points[i][1] = append(point[1].([]float64), p[1])
+			return
+		}
+	}
+	dist.Points = append(dist.Points, DistributionPoint{p[0], []float64{p[1]}})
+}
+
 func (d *Datadog) SampleConfig() string {
 	return sampleConfig
 }
@@ -3,12 +3,15 @@ package datadog
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/influxdata/telegraf"
@@ -229,3 +232,122 @@ func TestVerifyValue(t *testing.T) {
 		}
 	}
 }
+
+// fakeAPI records the bodies of the requests by path.
+type fakeAPI struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests map[string][]string
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{requests: make(map[string][]string)}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, fakeApiKey, r.Header.Get("DD-API-KEY"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		api.mu.Lock()
+		api.requests[r.URL.Path] = append(api.requests[r.URL.Path], string(body))
+		api.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return api
+}
+
+func newTestMetric(t *testing.T, name, host string, value float64, tp telegraf.ValueType) telegraf.Metric {
+	tags := map[string]string{}
+	if host != "" {
+		tags["host"] = host
+	}
+	m, err := metric.New(name, tags, map[string]interface{}{"value": value}, time.Unix(1500000000, 0), tp)
+	require.NoError(t, err)
+	return m
+}
+
+func TestWriteV2(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	d := NewDatadog(api.URL + seriesV1Path)
+	d.Apikey = fakeApiKey
+	d.APIVersion = "v2"
+	require.NoError(t, d.Connect())
+	require.NoError(t, d.Write([]telegraf.Metric{
+		newTestMetric(t, "cpu", "a", 1.5, telegraf.Gauge),
+		newTestMetric(t, "requests", "", 2, telegraf.Counter),
+	}))
+
+	require.Len(t, api.requests[seriesV2Path], 1)
+	require.JSONEq(t, `{"series":[
+		{"metric":"cpu","type":3,"points":[{"timestamp":1500000000,"value":1.5}],"resources":[{"name":"a","type":"host"}],"tags":["host:a"]},
+		{"metric":"requests","type":0,"points":[{"timestamp":1500000000,"value":2}]}
+	]}`, api.requests[seriesV2Path][0])
+}
+
+func TestDistributions(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	d := NewDatadog(api.URL + seriesV1Path)
+	d.Apikey = fakeApiKey
+	d.Distributions = []string{"latency*"}
+	require.NoError(t, d.Connect())
+	require.NoError(t, d.Write([]telegraf.Metric{
+		newTestMetric(t, "latency", "a", 1, telegraf.Untyped),
+		newTestMetric(t, "latency", "a", 2, telegraf.Untyped),
+		newTestMetric(t, "latency", "b", 3, telegraf.Untyped),
+		newTestMetric(t, "cpu", "a", 4, telegraf.Untyped),
+	}))
+
+	require.Len(t, api.requests[distributionPointsPath], 1)
+	require.JSONEq(t, `{"series":[
+		{"metric":"latency","points":[[1500000000,[1,2]]],"host":"a","tags":["host:a"]},
+		{"metric":"latency","points":[[1500000000,[3]]],"host":"b","tags":["host:b"]}
+	]}`, api.requests[distributionPointsPath][0])
+	require.Len(t, api.requests[seriesV1Path], 1)
+	require.JSONEq(t, `{"series":[{"metric":"cpu","points":[[1500000000,4]],"host":"a","tags":["host:a"]}]}`, api.requests[seriesV1Path][0])
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	d := NewDatadog(api.URL + seriesV1Path)
+	d.Apikey = fakeApiKey
+	d.MaxPayloadSize = 200
+	require.NoError(t, d.Connect())
+	var metrics []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		metrics = append(metrics, newTestMetric(t, "cpu", fmt.Sprintf("host%d", i), 1, telegraf.Untyped))
+	}
+	require.NoError(t, d.Write(metrics))
+
+	// The series are split in requests of at most 200 bytes.
+	series := 0
+	for _, body := range api.requests[seriesV1Path] {
+		require.True(t, len(body) <= 200, body)
+		var ts TimeSeries
+		require.NoError(t, json.Unmarshal([]byte(body), &ts))
+		series += len(ts.Series)
+	}
+	require.Equal(t, 10, series)
+	require.True(t, len(api.requests[seriesV1Path]) > 1)
+}
+
+func TestSite(t *testing.T) {
+	d := fakeDatadog()
+	d.Site = "datadoghq.eu"
+	require.NoError(t, d.Connect())
+	require.Equal(t, "https://api.datadoghq.eu/api/v1/series?api_key=123456", d.authenticatedUrl())
+	require.Equal(t, "https://api.datadoghq.eu/api/v2/series", d.endpoint(seriesV2Path))
+}
+
+func TestConnectErrors(t *testing.T) {
+	d := fakeDatadog()
+	d.APIVersion = "v3"
+	require.EqualError(t, d.Connect(), `unknown api_version "v3"`)
+
+	d = fakeDatadog()
+	d.HTTPProxy = ":invalid"
+	require.Error(t, d.Connect())
+}
@@ -2,36 +2,69 @@ package graylog
 
 import (
 	"bytes"
+	"compress/gzip"
 	"compress/zlib"
 	"crypto/rand"
-	"encoding/binary"
+	"crypto/tls"
 	ejson "encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"math"
 	"net"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
 const (
 	defaultGraylogEndpoint = "127.0.0.1:12201"
-	defaultConnection      = "wan"
-	defaultMaxChunkSizeWan = 1420
-	defaultMaxChunkSizeLan = 8154
+	defaultMaxChunkSize    = 1420
+	defaultTimeout         = 5 * time.Second
+
+	// maxChunks is the maximum number of chunks of a message, as per the
+	// GELF specification.
+	maxChunks = 128
+	// chunkHeaderSize is the size of the header of the chunks: the magic
+	// bytes, the message ID, and the sequence number and count.
+	chunkHeaderSize = 12
+)
+
+// Compressions of the messages sent over UDP.
+const (
+	compressionZlib = "zlib"
+	compressionGzip = "gzip"
+	compressionNone = "none"
 )
 
 type GelfConfig struct {
 	GraylogEndpoint string
-	Connection      string
-	MaxChunkSizeWan int
-	MaxChunkSizeLan int
+	// Network is udp, tcp or tls.
+	Network string
+	// MaxChunkSize is the maximum size of the UDP datagrams, with their
+	// chunk header for the chunked messages.
+	MaxChunkSize int
+	Compression  string
+	Timeout      time.Duration
+	TLSConfig    *tls.Config
 }
 
+// Gelf writes the messages to a server, over a connection opened again by
+// the next write when it fails.
 type Gelf struct {
 	GelfConfig
+	conn net.Conn
+
+	// The datagrams are sent from an unconnected socket, so that they are
+	// not failing while the server is down, as the datagrams are dropped
+	// anyway.
+	packetConn net.PacketConn
+	addr       net.Addr
 }
 
 func NewGelfWriter(config GelfConfig) *Gelf {
@@ -39,16 +72,20 @@ func NewGelfWriter(config GelfConfig) *Gelf {
 		config.GraylogEndpoint = defaultGraylogEndpoint
 	}
 
-	if config.Connection == "" {
-		config.Connection = defaultConnection
+	if config.Network == "" {
+		config.Network = "udp"
+	}
+
+	if config.MaxChunkSize == 0 {
+		config.MaxChunkSize = defaultMaxChunkSize
 	}
 
-	if config.MaxChunkSizeWan == 0 {
-		config.MaxChunkSizeWan = defaultMaxChunkSizeWan
+	if config.Compression == "" {
+		config.Compression = compressionZlib
 	}
 
-	if config.MaxChunkSizeLan == 0 {
-		config.MaxChunkSizeLan = defaultMaxChunkSizeLan
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
 	}
 
 	g := &Gelf{GelfConfig: config}
@@ -57,117 +94,218 @@ func NewGelfWriter(config GelfConfig) *Gelf {
 }
 
 func (g *Gelf) Write(message []byte) (n int, err error) {
-	compressed := g.compress(message)
-
-	chunksize := g.GelfConfig.MaxChunkSizeWan
-	length := compressed.Len()
-
-	if length > chunksize {
+	if g.conn == nil && g.packetConn == nil {
+		if err := g.connect(); err != nil {
+			return 0, err
+		}
+	}
 
-		chunkCountInt := int(math.Ceil(float64(length) / float64(chunksize)))
+	if g.Network == "udp" {
+		err = g.writeUDP(message)
+	} else {
+		// The messages are uncompressed and delimited by a null byte over
+		// TCP, as per the GELF specification.
+		frame := make([]byte, len(message)+1)
+		copy(frame, message)
+		g.conn.SetWriteDeadline(time.Now().Add(g.Timeout))
+		_, err = g.conn.Write(frame)
+	}
+	if err != nil {
+		g.Close()
+		return 0, err
+	}
 
-		id := make([]byte, 8)
-		rand.Read(id)
+	return len(message), nil
+}
 
-		for i, index := 0, 0; i < length; i, index = i+chunksize, index+1 {
-			packet := g.createChunkedMessage(index, chunkCountInt, id, &compressed)
-			_, err = g.send(packet.Bytes())
-			if err != nil {
-				return 0, err
-			}
+func (g *Gelf) connect() error {
+	if g.Network == "udp" {
+		addr, err := net.ResolveUDPAddr("udp", g.GraylogEndpoint)
+		if err != nil {
+			return err
 		}
-	} else {
-		_, err = g.send(compressed.Bytes())
+		conn, err := net.ListenPacket("udp", "")
 		if err != nil {
-			return 0, err
+			return err
 		}
+		g.packetConn, g.addr = conn, addr
+		return nil
 	}
 
-	n = len(message)
-
-	return
+	dialer := &net.Dialer{Timeout: g.Timeout}
+	var conn net.Conn
+	var err error
+	if g.Network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", g.GraylogEndpoint, g.TLSConfig)
+	} else {
+		conn, err = dialer.Dial(g.Network, g.GraylogEndpoint)
+	}
+	if err != nil {
+		return err
+	}
+	g.conn = conn
+	return nil
 }
 
-func (g *Gelf) createChunkedMessage(index int, chunkCountInt int, id []byte, compressed *bytes.Buffer) bytes.Buffer {
-	var packet bytes.Buffer
-
-	chunksize := g.getChunksize()
+// writeUDP sends a message in a datagram, or in chunks if it is larger than
+// MaxChunkSize once compressed.  The messages over the maximum number of
+// chunks are dropped, since they would be dropped by the server.
+func (g *Gelf) writeUDP(message []byte) error {
+	compressed, err := g.compress(message)
+	if err != nil {
+		return err
+	}
 
-	packet.Write(g.intToBytes(30))
-	packet.Write(g.intToBytes(15))
-	packet.Write(id)
+	length := compressed.Len()
+	if length <= g.MaxChunkSize {
+		_, err := g.packetConn.WriteTo(compressed.Bytes(), g.addr)
+		return err
+	}
 
-	packet.Write(g.intToBytes(index))
-	packet.Write(g.intToBytes(chunkCountInt))
+	chunksize := g.MaxChunkSize - chunkHeaderSize
+	chunkCountInt := int(math.Ceil(float64(length) / float64(chunksize)))
+	if chunkCountInt > maxChunks {
+		log.Printf("E! [outputs.graylog] Dropping message of %d bytes to %s, over %d chunks", length, g.GraylogEndpoint, maxChunks)
+		return nil
+	}
 
-	packet.Write(compressed.Next(chunksize))
+	id := make([]byte, 8)
+	rand.Read(id)
 
-	return packet
+	for index := 0; index < chunkCountInt; index++ {
+		packet := g.createChunkedMessage(index, chunkCountInt, id, chunksize, &compressed)
+		if _, err := g.packetConn.WriteTo(packet.Bytes(), g.addr); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (g *Gelf) getChunksize() int {
-	if g.GelfConfig.Connection == "wan" {
-		return g.GelfConfig.MaxChunkSizeWan
-	}
+func (g *Gelf) createChunkedMessage(index int, chunkCountInt int, id []byte, chunksize int, compressed *bytes.Buffer) bytes.Buffer {
+	var packet bytes.Buffer
 
-	if g.GelfConfig.Connection == "lan" {
-		return g.GelfConfig.MaxChunkSizeLan
-	}
+	packet.Write([]byte{0x1e, 0x0f})
+	packet.Write(id)
 
-	return g.GelfConfig.MaxChunkSizeWan
-}
+	packet.WriteByte(byte(index))
+	packet.WriteByte(byte(chunkCountInt))
 
-func (g *Gelf) intToBytes(i int) []byte {
-	buf := new(bytes.Buffer)
+	packet.Write(compressed.Next(chunksize))
 
-	binary.Write(buf, binary.LittleEndian, int8(i))
-	return buf.Bytes()
+	return packet
 }
 
-func (g *Gelf) compress(b []byte) bytes.Buffer {
+func (g *Gelf) compress(b []byte) (bytes.Buffer, error) {
 	var buf bytes.Buffer
-	comp := zlib.NewWriter(&buf)
-
-	comp.Write(b)
-	comp.Close()
+	var comp io.WriteCloser
+	switch g.Compression {
+	case compressionNone:
+		buf.Write(b)
+		return buf, nil
+	case compressionGzip:
+		comp = gzip.NewWriter(&buf)
+	default:
+		comp = zlib.NewWriter(&buf)
+	}
 
-	return buf
+	if _, err := comp.Write(b); err != nil {
+		return buf, err
+	}
+	err := comp.Close()
+	return buf, err
 }
 
-func (g *Gelf) send(b []byte) (n int, err error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", g.GelfConfig.GraylogEndpoint)
-	if err != nil {
-		return
+func (g *Gelf) Close() error {
+	var err error
+	if g.conn != nil {
+		err = g.conn.Close()
+		g.conn = nil
 	}
-
-	conn, err := net.DialUDP("udp", nil, udpAddr)
-	if err != nil {
-		return
+	if g.packetConn != nil {
+		err = g.packetConn.Close()
+		g.packetConn = nil
 	}
-
-	n, err = conn.Write(b)
-	return
+	return err
 }
 
 type Graylog struct {
-	Servers []string
+	Servers     []string          `toml:"servers"`
+	ChunkSize   int               `toml:"chunk_size"`
+	Compression string            `toml:"compression"`
+	Timeout     internal.Duration `toml:"timeout"`
+	tlsint.ClientConfig
+
+	writers []*Gelf
 	writer  io.Writer
 }
 
 var sampleConfig = `
-  ## UDP endpoint for your graylog instance.
+  ## Endpoints for your graylog instances, with the udp scheme by default,
+  ## or the tcp or tls schemes, eg. tcp://127.0.0.1:12201.
   servers = ["127.0.0.1:12201", "192.168.1.1:12201"]
+
+  ## Maximum size of the UDP datagrams.  The larger messages are sent in
+  ## chunks, up to 128 chunks per message.  Use 8154 on local networks.
+  # chunk_size = 1420
+
+  ## Compression of the messages sent over UDP: "zlib", "gzip" or "none".
+  ## The messages sent over TCP are not compressed.
+  # compression = "zlib"
+
+  ## Timeout for connecting and writing over TCP.
+  # timeout = "5s"
+
+  ## Optional TLS Config, with the tls scheme.
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
 `
 
 func (g *Graylog) Connect() error {
-	writers := []io.Writer{}
-
 	if len(g.Servers) == 0 {
 		g.Servers = append(g.Servers, "localhost:12201")
 	}
 
+	switch g.Compression {
+	case "", compressionZlib, compressionGzip, compressionNone:
+	default:
+		return fmt.Errorf("unknown compression %q", g.Compression)
+	}
+	if g.ChunkSize != 0 && g.ChunkSize <= chunkHeaderSize {
+		return fmt.Errorf("chunk_size must be greater than %d", chunkHeaderSize)
+	}
+
+	writers := []io.Writer{}
+	g.writers = nil
 	for _, server := range g.Servers {
-		w := NewGelfWriter(GelfConfig{GraylogEndpoint: server})
+		config := GelfConfig{
+			GraylogEndpoint: server,
+			MaxChunkSize:    g.ChunkSize,
+			Compression:     g.Compression,
+			Timeout:         g.Timeout.Duration,
+		}
+		if i := strings.Index(server, "://"); i >= 0 {
+			config.Network, config.GraylogEndpoint = server[:i], server[i+3:]
+		}
+		switch config.Network {
+		case "", "udp", "tcp":
+		case "tls":
+			tlsConfig, err := g.ClientConfig.TLSConfig()
+			if err != nil {
+				return err
+			}
+			if tlsConfig == nil {
+				tlsConfig = &tls.Config{}
+			}
+			config.TLSConfig = tlsConfig
+		default:
+			return fmt.Errorf("unknown scheme %q in %q", config.Network, server)
+		}
+
+		w := NewGelfWriter(config)
+		g.writers = append(g.writers, w)
 		writers = append(writers, w)
 	}
 
@@ -176,6 +314,9 @@ func (g *Graylog) Connect() error {
 }
 
 func (g *Graylog) Close() error {
+	for _, w := range g.writers {
+		w.Close()
+	}
 	return nil
 }
 
@@ -229,12 +370,12 @@ func serialize(metric telegraf.Metric) ([]string, error) {
 
 	for key, value := range metric.Tags() {
 		if key != "host" {
-			m["_"+key] = value
+			m[fieldName(key)] = value
 		}
 	}
 
 	for key, value := range metric.Fields() {
-		m["_"+key] = value
+		m[fieldName(key)] = value
 	}
 
 	serialized, err := ejson.Marshal(m)
@@ -246,8 +387,29 @@ func serialize(metric telegraf.Metric) ([]string, error) {
 	return out, nil
 }
 
+// fieldName returns the name of the additional field of a tag or field,
+// with the characters other than letters, digits, underscores, dashes and
+// dots replaced by underscores as per the GELF specification.  The _id
+// field being reserved, the id tag or field is sent as __id.
+func fieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "id" {
+		return "__id"
+	}
+	return "_" + name
+}
+
 func init() {
 	outputs.Add("graylog", func() telegraf.Output {
-		return &Graylog{}
+		return &Graylog{
+			Timeout: internal.Duration{Duration: defaultTimeout},
+		}
 	})
 }
@@ -1,16 +1,24 @@
 package graylog
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"compress/zlib"
+	"crypto/tls"
 	"encoding/json"
 	"io"
 	"net"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWrite(t *testing.T) {
@@ -56,3 +64,170 @@ func UDPServer(t *testing.T, wg *sync.WaitGroup, wg2 *sync.WaitGroup) {
 	json.Unmarshal(bufW.Bytes(), &obj)
 	assert.Equal(t, obj["_value"], float64(1))
 }
+
+func newMetric(t *testing.T, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New("cpu", map[string]string{"host": "a", "cpu id": "cpu0"}, fields, time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	return m
+}
+
+// readGelf reads the null delimited messages sent over TCP.
+func readGelf(t *testing.T, r *bufio.Reader) GelfObject {
+	msg, err := r.ReadBytes(0)
+	require.NoError(t, err)
+	var obj GelfObject
+	require.NoError(t, json.Unmarshal(msg[:len(msg)-1], &obj))
+	return obj
+}
+
+func TestWriteTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	g := &Graylog{Servers: []string{"tcp://" + l.Addr().String()}}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+	require.NoError(t, g.Write([]telegraf.Metric{newMetric(t, map[string]interface{}{"value": 1.0, "id": 42})}))
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	obj := readGelf(t, bufio.NewReader(conn))
+	require.Equal(t, "a", obj["host"])
+	require.Equal(t, "cpu0", obj["_cpu_id"])
+	require.Equal(t, float64(1), obj["_value"])
+	require.Equal(t, float64(42), obj["__id"])
+
+	// The connection lost is opened again by the next write, after the
+	// write failing.
+	conn.Close()
+	for i := 0; i < 100; i++ {
+		if err = g.Write([]telegraf.Metric{newMetric(t, map[string]interface{}{"value": 2.0})}); err != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Error(t, err)
+	require.NoError(t, g.Write([]telegraf.Metric{newMetric(t, map[string]interface{}{"value": 3.0})}))
+	conn, err = l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, float64(3), readGelf(t, bufio.NewReader(conn))["_value"])
+}
+
+func TestWriteTLS(t *testing.T) {
+	pki := testutil.NewPKI("../../../testutil/pki")
+	tlsConfig, err := pki.TLSServerConfig().TLSConfig()
+	require.NoError(t, err)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	g := &Graylog{Servers: []string{"tls://localhost:" + port}, ClientConfig: *pki.TLSClientConfig()}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	done := make(chan GelfObject)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(done)
+			return
+		}
+		defer conn.Close()
+		msg, _ := bufio.NewReader(conn).ReadBytes(0)
+		var obj GelfObject
+		json.Unmarshal(bytes.TrimSuffix(msg, []byte{0}), &obj)
+		done <- obj
+	}()
+	require.NoError(t, g.Write([]telegraf.Metric{newMetric(t, map[string]interface{}{"value": 1.0})}))
+	require.Equal(t, float64(1), (<-done)["_value"])
+}
+
+func TestChunking(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	g := &Graylog{
+		Servers:     []string{"udp://" + conn.LocalAddr().String()},
+		ChunkSize:   100,
+		Compression: "none",
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+	long := strings.Repeat("a", 500)
+	require.NoError(t, g.Write([]telegraf.Metric{newMetric(t, map[string]interface{}{"message": long})}))
+
+	// The chunks are at most chunk_size bytes, with their header.
+	var message []byte
+	b := make([]byte, 1024)
+	for i := 0; ; i++ {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(b)
+		require.NoError(t, err)
+		require.True(t, n <= 100)
+		require.Equal(t, []byte{0x1e, 0x0f}, b[:2])
+		require.Equal(t, byte(i), b[10])
+		message = append(message, b[12:n]...)
+		if int(b[11]) == i+1 {
+			break
+		}
+	}
+	var obj GelfObject
+	require.NoError(t, json.Unmarshal(message, &obj))
+	require.Equal(t, long, obj["_message"])
+
+	// The messages over 128 chunks are dropped.
+	g.Close()
+	g.ChunkSize = 13
+	require.NoError(t, g.Connect())
+	require.NoError(t, g.Write([]telegraf.Metric{newMetric(t, map[string]interface{}{"message": long})}))
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = conn.ReadFrom(b)
+	require.Error(t, err)
+}
+
+func TestCompression(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	g := &Graylog{
+		Servers:     []string{conn.LocalAddr().String()},
+		Compression: "gzip",
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+	require.NoError(t, g.Write([]telegraf.Metric{newMetric(t, map[string]interface{}{"value": 1.0})}))
+
+	b := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(b)
+	require.NoError(t, err)
+	r, err := gzip.NewReader(bytes.NewReader(b[:n]))
+	require.NoError(t, err)
+	var obj GelfObject
+	require.NoError(t, json.NewDecoder(r).Decode(&obj))
+	require.Equal(t, float64(1), obj["_value"])
+}
+
+func TestConnectErrors(t *testing.T) {
+	g := &Graylog{Servers: []string{"http://127.0.0.1:12201"}}
+	require.EqualError(t, g.Connect(), `unknown scheme "http" in "http://127.0.0.1:12201"`)
+
+	g = &Graylog{Compression: "lz4"}
+	require.EqualError(t, g.Connect(), `unknown compression "lz4"`)
+
+	g = &Graylog{ChunkSize: 12}
+	require.EqualError(t, g.Connect(), "chunk_size must be greater than 12")
+}
+
+func TestFieldName(t *testing.T) {
+	require.Equal(t, "_usage_idle", fieldName("usage_idle"))
+	require.Equal(t, "_disk.io-time", fieldName("disk.io-time"))
+	require.Equal(t, "_cpu_id_", fieldName("cpu id?"))
+	require.Equal(t, "__id", fieldName("id"))
+}
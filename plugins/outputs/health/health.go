@@ -0,0 +1,332 @@
+package health
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	defaultServiceAddress = "http://:8080"
+	defaultReadTimeout    = 5 * time.Second
+	defaultWriteTimeout   = 5 * time.Second
+)
+
+var sampleConfig = `
+  ## Address and port to listen on, with the http scheme, or https with
+  ## tls_cert and tls_key.
+  # service_address = "http://:8080"
+
+  ## Timeouts for reading the requests and writing the responses.
+  # read_timeout = "5s"
+  # write_timeout = "5s"
+
+  ## HTTP basic authentication.
+  # basic_username = "user1"
+  # basic_password = "secret"
+
+  ## Optional TLS configuration.
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Enables client authentication if set.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## The endpoint replies 200 while all the checks pass, and 503 otherwise.
+  ## Use namepass to select the metrics checked, such as the internal_write
+  ## metrics of the internal input.
+
+  ## Compares the values of a field, the check failing when a value of the
+  ## last write with the field does not satisfy the comparisons, gt, ge, lt
+  ## and le.  The values which are not numbers fail the check.
+  # [[outputs.health.compares]]
+  #   field = "buffer_size"
+  #   lt = 5000.0
+
+  ## Fails when no metric of the measurement was written for max_age.
+  # [[outputs.health.heartbeats]]
+  #   measurement = "heartbeat"
+  #   max_age = "30s"
+`
+
+// Compares checks the values of a field.
+type Compares struct {
+	Field string   `toml:"field"`
+	GT    *float64 `toml:"gt"`
+	GE    *float64 `toml:"ge"`
+	LT    *float64 `toml:"lt"`
+	LE    *float64 `toml:"le"`
+}
+
+// Heartbeat checks that a measurement was written recently.
+type Heartbeat struct {
+	Measurement string            `toml:"measurement"`
+	MaxAge      internal.Duration `toml:"max_age"`
+}
+
+type Health struct {
+	ServiceAddress string            `toml:"service_address"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+	BasicUsername  string            `toml:"basic_username"`
+	BasicPassword  string            `toml:"basic_password"`
+	tlsint.ServerConfig
+
+	Compares   []*Compares  `toml:"compares"`
+	Heartbeats []*Heartbeat `toml:"heartbeats"`
+
+	server *http.Server
+	origin string
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// comparesFailed tells whether each compares check failed on the last
+	// write with its field.
+	comparesFailed []bool
+	// lastSeen is when a metric of each heartbeat was last written, or
+	// when the output started.
+	lastSeen []time.Time
+	now      func() time.Time
+}
+
+func (h *Health) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *Health) Description() string {
+	return "Configurable HTTP health check resource based on metrics"
+}
+
+// Start starts the HTTP server of the health endpoint.
+func (h *Health) Start() error {
+	for _, c := range h.Compares {
+		if c.Field == "" {
+			return fmt.Errorf("compares requires field")
+		}
+	}
+	for _, hb := range h.Heartbeats {
+		if hb.Measurement == "" || hb.MaxAge.Duration <= 0 {
+			return fmt.Errorf("heartbeats requires measurement and a positive max_age")
+		}
+	}
+
+	u, err := url.Parse(h.ServiceAddress)
+	if err != nil {
+		return fmt.Errorf("invalid service_address %q: %s", h.ServiceAddress, err)
+	}
+	tlsConfig, err := h.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http":
+		if tlsConfig != nil {
+			return fmt.Errorf("TLS requires the https scheme in service_address")
+		}
+	case "https":
+		if tlsConfig == nil {
+			return fmt.Errorf("the https scheme requires tls_cert and tls_key")
+		}
+	default:
+		return fmt.Errorf("invalid service_address %q: the scheme must be http or https", h.ServiceAddress)
+	}
+
+	if h.now == nil {
+		h.now = time.Now
+	}
+	h.comparesFailed = make([]bool, len(h.Compares))
+	h.lastSeen = make([]time.Time, len(h.Heartbeats))
+	for i := range h.lastSeen {
+		h.lastSeen[i] = h.now()
+	}
+
+	listener, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return err
+	}
+	h.origin = u.Scheme + "://" + listener.Addr().String()
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	h.server = &http.Server{
+		Handler:      h,
+		ReadTimeout:  h.ReadTimeout.Duration,
+		WriteTimeout: h.WriteTimeout.Duration,
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		err := h.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("E! [outputs.health] Serving health endpoint on %s: %s", h.ServiceAddress, err)
+		}
+	}()
+
+	log.Printf("I! [outputs.health] Listening on %s", h.origin)
+	return nil
+}
+
+// Stop shuts the HTTP server down.
+func (h *Health) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h.server.Shutdown(ctx)
+	h.wg.Wait()
+}
+
+func (h *Health) Connect() error {
+	// The server is started by Start.
+	return nil
+}
+
+func (h *Health) Close() error {
+	return nil
+}
+
+// ServeHTTP replies 200 if the checks pass, or 503 with the failed checks.
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.BasicUsername != "" || h.BasicPassword != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(h.BasicUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(h.BasicPassword)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+			http.Error(w, "Not authorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	failed := h.failedChecks()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if len(failed) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, strings.Join(failed, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+// failedChecks returns the descriptions of the checks failing.
+func (h *Health) failedChecks() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var failed []string
+	for i, c := range h.Compares {
+		if h.comparesFailed[i] {
+			failed = append(failed, c.String())
+		}
+	}
+	now := h.now()
+	for i, hb := range h.Heartbeats {
+		if now.Sub(h.lastSeen[i]) > hb.MaxAge.Duration {
+			failed = append(failed, hb.String())
+		}
+	}
+	return failed
+}
+
+func (h *Health) Write(metrics []telegraf.Metric) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	for i, c := range h.Compares {
+		seen, failed := false, false
+		for _, m := range metrics {
+			v, ok := m.Fields()[c.Field]
+			if !ok {
+				continue
+			}
+			seen = true
+			if !c.check(v) {
+				failed = true
+				break
+			}
+		}
+		// The result is kept until a write with the field.
+		if seen {
+			h.comparesFailed[i] = failed
+		}
+	}
+	for i, hb := range h.Heartbeats {
+		for _, m := range metrics {
+			if m.Name() == hb.Measurement {
+				h.lastSeen[i] = now
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// check tells whether the value satisfies the comparisons.
+func (c *Compares) check(v interface{}) bool {
+	var value float64
+	switch v := v.(type) {
+	case float64:
+		value = v
+	case int64:
+		value = float64(v)
+	case uint64:
+		value = float64(v)
+	case bool:
+		if v {
+			value = 1
+		}
+	default:
+		return false
+	}
+
+	return (c.GT == nil || value > *c.GT) &&
+		(c.GE == nil || value >= *c.GE) &&
+		(c.LT == nil || value < *c.LT) &&
+		(c.LE == nil || value <= *c.LE)
+}
+
+// String describes the check, such as "buffer_size < 5000".
+func (c *Compares) String() string {
+	var conditions []string
+	for _, cmp := range []struct {
+		op    string
+		value *float64
+	}{{">", c.GT}, {">=", c.GE}, {"<", c.LT}, {"<=", c.LE}} {
+		if cmp.value != nil {
+			conditions = append(conditions, fmt.Sprintf("%s %s %v", c.Field, cmp.op, *cmp.value))
+		}
+	}
+	if len(conditions) == 0 {
+		return c.Field + " is a number"
+	}
+	return strings.Join(conditions, " and ")
+}
+
+// String describes the check, such as "heartbeat written within 30s".
+func (hb *Heartbeat) String() string {
+	return fmt.Sprintf("%s written within %s", hb.Measurement, hb.MaxAge.Duration)
+}
+
+func init() {
+	outputs.Add("health", func() telegraf.Output {
+		return &Health{
+			ServiceAddress: defaultServiceAddress,
+			ReadTimeout:    internal.Duration{Duration: defaultReadTimeout},
+			WriteTimeout:   internal.Duration{Duration: defaultWriteTimeout},
+			now:            time.Now,
+		}
+	})
+}
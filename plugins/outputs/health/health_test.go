@@ -0,0 +1,157 @@
+package health
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var pki = testutil.NewPKI("../../../testutil/pki")
+
+// fakeClock is the clock of the heartbeats.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newHealth(clock *fakeClock) *Health {
+	lt := 5000.0
+	return &Health{
+		ServiceAddress: "http://127.0.0.1:0",
+		Compares:       []*Compares{{Field: "buffer_size", LT: &lt}},
+		Heartbeats:     []*Heartbeat{{Measurement: "heartbeat", MaxAge: internal.Duration{Duration: 30 * time.Second}}},
+		now:            clock.now,
+	}
+}
+
+func newMetric(t *testing.T, name string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New(name, map[string]string{}, fields, time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestChecks(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1500000000, 0)}
+	h := newHealth(clock)
+	require.NoError(t, h.Start())
+	defer h.Stop()
+
+	status, body := get(t, http.DefaultClient, h.origin)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "OK\n", body)
+
+	require.NoError(t, h.Write([]telegraf.Metric{
+		newMetric(t, "internal_write", map[string]interface{}{"buffer_size": int64(10)}),
+		newMetric(t, "internal_write", map[string]interface{}{"buffer_size": int64(6000)}),
+	}))
+	status, body = get(t, http.DefaultClient, h.origin)
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "buffer_size < 5000\n", body)
+
+	// The result is kept until a write with the field.
+	require.NoError(t, h.Write([]telegraf.Metric{newMetric(t, "cpu", map[string]interface{}{"usage": 1.0})}))
+	status, _ = get(t, http.DefaultClient, h.origin)
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.NoError(t, h.Write([]telegraf.Metric{newMetric(t, "internal_write", map[string]interface{}{"buffer_size": int64(10)})}))
+	status, _ = get(t, http.DefaultClient, h.origin)
+	require.Equal(t, http.StatusOK, status)
+
+	// The heartbeat fails max_age after the start or the last heartbeat.
+	clock.add(20 * time.Second)
+	require.NoError(t, h.Write([]telegraf.Metric{newMetric(t, "heartbeat", map[string]interface{}{"value": 1.0})}))
+	clock.add(20 * time.Second)
+	status, _ = get(t, http.DefaultClient, h.origin)
+	require.Equal(t, http.StatusOK, status)
+	clock.add(20 * time.Second)
+	status, body = get(t, http.DefaultClient, h.origin)
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "heartbeat written within 30s\n", body)
+}
+
+func TestCompares(t *testing.T) {
+	ge, le := 1.0, 2.0
+	c := &Compares{Field: "value", GE: &ge, LE: &le}
+	require.True(t, c.check(1.0))
+	require.True(t, c.check(int64(2)))
+	require.True(t, c.check(true))
+	require.False(t, c.check(uint64(3)))
+	require.False(t, c.check(0.5))
+	require.False(t, c.check("1"))
+	require.Equal(t, "value >= 1 and value <= 2", c.String())
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := newHealth(&fakeClock{t: time.Now()})
+	h.BasicUsername, h.BasicPassword = "user", "secret"
+	require.NoError(t, h.Start())
+	defer h.Stop()
+
+	status, _ := get(t, http.DefaultClient, h.origin)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	req, err := http.NewRequest("GET", h.origin, nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("user", "secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTLS(t *testing.T) {
+	h := newHealth(&fakeClock{t: time.Now()})
+	h.ServiceAddress = "https://127.0.0.1:0"
+	h.ServerConfig = *pki.TLSServerConfig()
+	require.NoError(t, h.Start())
+	defer h.Stop()
+
+	tlsConfig, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	tlsConfig.ServerName = "localhost"
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	status, _ := get(t, client, h.origin)
+	require.Equal(t, http.StatusOK, status)
+}
+
+func TestStartErrors(t *testing.T) {
+	h := &Health{ServiceAddress: "tcp://:8080"}
+	require.EqualError(t, h.Start(), `invalid service_address "tcp://:8080": the scheme must be http or https`)
+
+	h = &Health{ServiceAddress: "https://:8080"}
+	require.EqualError(t, h.Start(), "the https scheme requires tls_cert and tls_key")
+
+	h = &Health{ServiceAddress: "http://:8080", Compares: []*Compares{{}}}
+	require.EqualError(t, h.Start(), "compares requires field")
+
+	h = &Health{ServiceAddress: "http://:8080", Heartbeats: []*Heartbeat{{Measurement: "heartbeat"}}}
+	require.EqualError(t, h.Start(), "heartbeats requires measurement and a positive max_age")
+}
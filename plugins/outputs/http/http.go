@@ -10,7 +10,8 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/internal/signing"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
@@ -34,12 +35,38 @@ var sampleConfig = `
   #   # Should be set to "application/json" for json data_format
   #   Content-Type = "text/plain; charset=utf-8"
 
+  ## Additional HTTP cookies
+  # [outputs.http.cookies]
+  #   session = "value"
+
   ## Optional TLS Config
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
   # tls_key = "/etc/telegraf/key.pem"
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+  ## TLS renegotiation requested by the servers, "never", "once" or "freely"
+  # tls_renegotiation_method = "never"
+
+  ## Idle connections kept open for the next requests, in total and per
+  ## host, and time before closing them (default = 0, 2 per host and 0).
+  ## 0 means unlimited.
+  # max_idle_conn = 0
+  # max_idle_conn_per_host = 2
+  # idle_conn_timeout = "0s"
+  ## Close the connections after each request.
+  # disable_keep_alives = false
+
+  ## Optional signing of the request bodies, sent in the X-Telegraf-Signature
+  ## header.  The method is one of "hmac-sha256", with a shared secret key,
+  ## or "ed25519", with a hex or base64 encoded private key or seed.  When
+  ## signature_chain is enabled each signature also covers the previous one,
+  ## so that consumers can detect dropped or reordered batches.
+  # signature_method = "hmac-sha256"
+  # signature_key = "$TELEGRAF_SIGNATURE_KEY"
+  # signature_key_file = "/etc/telegraf/signature.key"
+  # signature_key_id = "telegraf-1"
+  # signature_chain = false
 
   ## Data format to output.
   ## Each data format has it's own unique set of configuration options, read
@@ -52,19 +79,20 @@ const (
 	defaultClientTimeout = 5 * time.Second
 	defaultContentType   = "text/plain; charset=utf-8"
 	defaultMethod        = http.MethodPost
+	signatureHeader      = "X-Telegraf-Signature"
 )
 
 type HTTP struct {
-	URL      string            `toml:"url"`
-	Timeout  internal.Duration `toml:"timeout"`
-	Method   string            `toml:"method"`
-	Username string            `toml:"username"`
-	Password string            `toml:"password"`
-	Headers  map[string]string `toml:"headers"`
-	tls.ClientConfig
+	URL      string `toml:"url"`
+	Method   string `toml:"method"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	httpconfig.HTTPClientConfig
+	signing.Config
 
 	client     *http.Client
 	serializer serializers.Serializer
+	signer     *signing.Signer
 }
 
 func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
@@ -84,17 +112,15 @@ func (h *HTTP) Connect() error {
 		h.Timeout.Duration = defaultClientTimeout
 	}
 
-	tlsCfg, err := h.ClientConfig.TLSConfig()
+	client, err := h.CreateClient("outputs.http")
 	if err != nil {
 		return err
 	}
+	h.client = client
 
-	h.client = &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: tlsCfg,
-			Proxy:           http.ProxyFromEnvironment,
-		},
-		Timeout: h.Timeout.Duration,
+	h.signer, err = h.Config.Signer()
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -129,8 +155,12 @@ func (h *HTTP) write(reqBody []byte) error {
 	req, err := http.NewRequest(h.Method, h.URL, bytes.NewBuffer(reqBody))
 
 	req.Header.Set("Content-Type", defaultContentType)
-	for k, v := range h.Headers {
-		req.Header.Set(k, v)
+	h.PrepareRequest(req)
+
+	var sig *signing.Signature
+	if h.signer != nil {
+		sig = h.signer.Sign(reqBody)
+		req.Header.Set(signatureHeader, sig.String())
 	}
 
 	resp, err := h.client.Do(req)
@@ -144,14 +174,19 @@ func (h *HTTP) write(reqBody []byte) error {
 		return fmt.Errorf("when writing to [%s] received status code: %d", h.URL, resp.StatusCode)
 	}
 
+	if sig != nil {
+		h.signer.Ack(sig)
+	}
 	return nil
 }
 
 func init() {
 	outputs.Add("http", func() telegraf.Output {
 		return &HTTP{
-			Timeout: internal.Duration{Duration: defaultClientTimeout},
-			Method:  defaultMethod,
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: defaultClientTimeout},
+			},
+			Method: defaultMethod,
 		}
 	})
 }
@@ -2,6 +2,7 @@ package http
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/internal/signing"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/stretchr/testify/require"
@@ -202,8 +205,10 @@ func TestContentType(t *testing.T) {
 		{
 			name: "overwrite content_type",
 			plugin: &HTTP{
-				URL:     u.String(),
-				Headers: map[string]string{"Content-Type": "application/json"},
+				URL: u.String(),
+				HTTPClientConfig: httpconfig.HTTPClientConfig{
+					Headers: map[string]string{"Content-Type": "application/json"},
+				},
 			},
 			expected: "application/json",
 		},
@@ -289,3 +294,42 @@ func TestBasicAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestSignature(t *testing.T) {
+	verifier, err := signing.NewVerifier("hmac-sha256", "secret")
+	require.NoError(t, err)
+
+	status := http.StatusOK
+	var verifyErr error
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		if status == http.StatusOK {
+			verifyErr = verifier.Verify(body, r.Header.Get("X-Telegraf-Signature"))
+		}
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL: ts.URL,
+		Config: signing.Config{
+			SignatureMethod: "hmac-sha256",
+			SignatureKey:    "secret",
+			SignatureChain:  true,
+		},
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	require.NoError(t, plugin.Connect())
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{getMetric()}))
+	require.NoError(t, verifyErr)
+
+	// A failed write does not break the chain.
+	status = http.StatusInternalServerError
+	require.Error(t, plugin.Write([]telegraf.Metric{getMetric()}))
+
+	status = http.StatusOK
+	require.NoError(t, plugin.Write([]telegraf.Metric{getMetric()}))
+	require.NoError(t, verifyErr)
+}
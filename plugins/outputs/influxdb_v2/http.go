@@ -0,0 +1,320 @@
+package influxdb_v2
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+type APIError struct {
+	StatusCode  int
+	Title       string
+	Description string
+}
+
+func (e APIError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Title, e.Description)
+	}
+	return e.Title
+}
+
+const (
+	defaultRequestTimeout = time.Second * 5
+	defaultMaxRetryWait   = time.Minute * 5
+	defaultUserAgent      = "telegraf"
+)
+
+type HTTPConfig struct {
+	URL             *url.URL
+	Token           string
+	Organization    string
+	Bucket          string
+	BucketTag       string
+	Timeout         time.Duration
+	Headers         map[string]string
+	Proxy           *url.URL
+	UserAgent       string
+	ContentEncoding string
+	TLSConfig       *tls.Config
+
+	Serializer *influx.Serializer
+}
+
+type httpClient struct {
+	ContentEncoding string
+	Timeout         time.Duration
+	Headers         map[string]string
+	Organization    string
+	Bucket          string
+	BucketTag       string
+
+	client     *http.Client
+	serializer *influx.Serializer
+	url        *url.URL
+
+	// The client does not send any writes before retryTime has passed. It
+	// is set after the server rejects a write with a retryable status, using
+	// the Retry-After header when present or an exponential backoff
+	// otherwise.
+	retryTime  time.Time
+	retryCount int
+	now        func() time.Time
+}
+
+func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
+	if config.URL == nil {
+		return nil, ErrMissingURL
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+
+	var headers = make(map[string]string, len(config.Headers)+2)
+	headers["User-Agent"] = userAgent
+	headers["Authorization"] = "Token " + config.Token
+	for k, v := range config.Headers {
+		headers[k] = v
+	}
+
+	var proxy func(*http.Request) (*url.URL, error)
+	if config.Proxy != nil {
+		proxy = http.ProxyURL(config.Proxy)
+	} else {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	serializer := config.Serializer
+	if serializer == nil {
+		serializer = influx.NewSerializer()
+	}
+
+	var transport *http.Transport
+	switch config.URL.Scheme {
+	case "http", "https":
+		transport = &http.Transport{
+			Proxy:           proxy,
+			TLSClientConfig: config.TLSConfig,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", config.URL.Scheme)
+	}
+
+	client := &httpClient{
+		serializer: serializer,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		url:             config.URL,
+		ContentEncoding: config.ContentEncoding,
+		Timeout:         timeout,
+		Headers:         headers,
+		Organization:    config.Organization,
+		Bucket:          config.Bucket,
+		BucketTag:       config.BucketTag,
+		now:             time.Now,
+	}
+	return client, nil
+}
+
+// URL returns the origin URL that this client connects too.
+func (c *httpClient) URL() string {
+	return c.url.String()
+}
+
+// Write sends the metrics to InfluxDB, routing each metric to the bucket
+// named by its BucketTag if set.  The buckets are written in turn until one
+// fails, the metrics of the buckets not written are then returned in an
+// outputs.PartialWriteError if others were written.
+func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error {
+	if c.retryTime.After(c.now()) {
+		return errors.New("retry time has not elapsed")
+	}
+
+	if c.BucketTag == "" {
+		return c.writeBatch(ctx, c.Bucket, metrics)
+	}
+
+	var buckets []string
+	batches := make(map[string][]telegraf.Metric)
+	for _, metric := range metrics {
+		bucket, ok := metric.GetTag(c.BucketTag)
+		if !ok {
+			bucket = c.Bucket
+		}
+		if _, ok := batches[bucket]; !ok {
+			buckets = append(buckets, bucket)
+		}
+		batches[bucket] = append(batches[bucket], metric)
+	}
+
+	for i, bucket := range buckets {
+		err := c.writeBatch(ctx, bucket, batches[bucket])
+		if err == nil {
+			continue
+		}
+		if i == 0 {
+			return err
+		}
+		var failed []telegraf.Metric
+		for _, bucket := range buckets[i:] {
+			failed = append(failed, batches[bucket]...)
+		}
+		return &outputs.PartialWriteError{Err: err, Failed: failed}
+	}
+	return nil
+}
+
+func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []telegraf.Metric) error {
+	loc, err := makeWriteURL(*c.url, c.Organization, bucket)
+	if err != nil {
+		return err
+	}
+
+	reader := influx.NewReader(metrics, c.serializer)
+	req, err := c.makeWriteRequest(loc, reader)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		c.retryCount = 0
+		return nil
+	}
+
+	writeResp := &genericResponse{}
+	var desc string
+	if json.NewDecoder(resp.Body).Decode(writeResp) == nil {
+		desc = writeResp.Message
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		// The request will never succeed, such as with a parse error or a
+		// batch exceeding the maximum request size, so the points are dropped
+		// instead of retrying.
+		log.Printf("E! [outputs.influxdb_v2] when writing to [%s]: received error %v; discarding points",
+			c.URL(), desc)
+		return nil
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		c.retryTime = c.now().Add(c.retryDuration(resp.Header.Get("Retry-After")))
+		c.retryCount++
+	}
+
+	return &APIError{
+		StatusCode:  resp.StatusCode,
+		Title:       resp.Status,
+		Description: desc,
+	}
+}
+
+// retryDuration returns the time to wait before the next write, honoring
+// the Retry-After header if present, otherwise backing off exponentially.
+func (c *httpClient) retryDuration(retryAfter string) time.Duration {
+	if retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	backoff := time.Duration(math.Pow(2, float64(c.retryCount))) * time.Second
+	if backoff > defaultMaxRetryWait {
+		backoff = defaultMaxRetryWait
+	}
+	return backoff
+}
+
+func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request, error) {
+	var err error
+	if c.ContentEncoding == "gzip" {
+		body, err = compressWithGzip(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	c.addHeaders(req)
+
+	if c.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	return req, nil
+}
+
+func (c *httpClient) addHeaders(req *http.Request) {
+	for header, value := range c.Headers {
+		req.Header.Set(header, value)
+	}
+}
+
+// genericResponse is the error response body of the InfluxDB 2.x API.
+type genericResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func compressWithGzip(data io.Reader) (io.Reader, error) {
+	pr, pw := io.Pipe()
+	gw := gzip.NewWriter(pw)
+	var err error
+
+	go func() {
+		_, err = io.Copy(gw, data)
+		gw.Close()
+		pw.Close()
+	}()
+
+	return pr, err
+}
+
+func makeWriteURL(loc url.URL, org, bucket string) (string, error) {
+	params := url.Values{}
+	params.Set("bucket", bucket)
+	params.Set("org", org)
+
+	switch loc.Scheme {
+	case "http", "https":
+		loc.Path = path.Join(loc.Path, "/api/v2/write")
+	default:
+		return "", fmt.Errorf("unsupported scheme: %q", loc.Scheme)
+	}
+	loc.RawQuery = params.Encode()
+	return loc.String(), nil
+}
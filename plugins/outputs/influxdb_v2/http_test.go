@@ -0,0 +1,241 @@
+package influxdb_v2
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/stretchr/testify/require"
+)
+
+func genURL(u string) *url.URL {
+	URL, _ := url.Parse(u)
+	return URL
+}
+
+func newMetric(name string, tags map[string]string) telegraf.Metric {
+	m, _ := metric.New(name, tags,
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0))
+	return m
+}
+
+func TestHTTP_EmptyConfig(t *testing.T) {
+	_, err := NewHTTPClient(&HTTPConfig{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrMissingURL.Error())
+}
+
+func TestHTTP_UnsupportedScheme(t *testing.T) {
+	_, err := NewHTTPClient(&HTTPConfig{URL: genURL("udp://localhost:9999")})
+	require.Error(t, err)
+}
+
+func TestMakeWriteURL(t *testing.T) {
+	tests := []struct {
+		url *url.URL
+		act string
+		err bool
+	}{
+		{
+			url: genURL("http://localhost:9999"),
+			act: "http://localhost:9999/api/v2/write?bucket=telegraf&org=influx",
+		},
+		{
+			url: genURL("https://localhost:9999/prefix"),
+			act: "https://localhost:9999/prefix/api/v2/write?bucket=telegraf&org=influx",
+		},
+		{
+			url: genURL("unix://var/run/influxd.sock"),
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		rURL, err := makeWriteURL(*tt.url, "influx", "telegraf")
+		if tt.err {
+			require.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tt.act, rURL)
+	}
+}
+
+func TestHTTP_Write(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/write", r.URL.Path)
+		require.Equal(t, "influx", r.URL.Query().Get("org"))
+		require.Equal(t, "telegraf", r.URL.Query().Get("bucket"))
+		require.Equal(t, "Token my-token", r.Header.Get("Authorization"))
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		gr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(gr)
+		require.NoError(t, err)
+		require.Equal(t, "cpu value=42 0\n", string(body))
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client, err := NewHTTPClient(&HTTPConfig{
+		URL:             genURL(ts.URL),
+		Token:           "my-token",
+		Organization:    "influx",
+		Bucket:          "telegraf",
+		ContentEncoding: "gzip",
+	})
+	require.NoError(t, err)
+
+	err = client.Write(context.Background(),
+		[]telegraf.Metric{newMetric("cpu", map[string]string{})})
+	require.NoError(t, err)
+}
+
+func TestHTTP_BucketTag(t *testing.T) {
+	var mu sync.Mutex
+	buckets := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		mu.Lock()
+		buckets[r.URL.Query().Get("bucket")] += string(body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client, err := NewHTTPClient(&HTTPConfig{
+		URL:          genURL(ts.URL),
+		Organization: "influx",
+		Bucket:       "telegraf",
+		BucketTag:    "bucket",
+	})
+	require.NoError(t, err)
+
+	err = client.Write(context.Background(), []telegraf.Metric{
+		newMetric("cpu", map[string]string{"bucket": "foo"}),
+		newMetric("mem", map[string]string{}),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"foo":      "cpu,bucket=foo value=42 0\n",
+		"telegraf": "mem value=42 0\n",
+	}, buckets)
+}
+
+// Verify that only the metrics of the buckets not written are returned for
+// retry when a bucket fails.
+func TestHTTP_BucketTagPartialWrite(t *testing.T) {
+	var mu sync.Mutex
+	var written []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket := r.URL.Query().Get("bucket")
+		if bucket == "foo" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		mu.Lock()
+		written = append(written, bucket)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client, err := NewHTTPClient(&HTTPConfig{
+		URL:          genURL(ts.URL),
+		Organization: "influx",
+		Bucket:       "telegraf",
+		BucketTag:    "bucket",
+	})
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		newMetric("mem", map[string]string{}),
+		newMetric("cpu", map[string]string{"bucket": "foo"}),
+		newMetric("disk", map[string]string{"bucket": "bar"}),
+		newMetric("net", map[string]string{"bucket": "foo"}),
+	}
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	perr, ok := err.(*outputs.PartialWriteError)
+	require.True(t, ok)
+	require.Equal(t, []telegraf.Metric{metrics[1], metrics[3], metrics[2]}, perr.Failed)
+	require.Equal(t, []string{"telegraf"}, written)
+
+	// nothing was written when the first bucket fails
+	err = client.Write(context.Background(), metrics[1:])
+	require.Error(t, err)
+	_, ok = err.(*outputs.PartialWriteError)
+	require.False(t, ok)
+}
+
+func TestHTTP_RetryAfter(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	client, err := NewHTTPClient(&HTTPConfig{URL: genURL(ts.URL)})
+	require.NoError(t, err)
+
+	now := time.Unix(0, 0)
+	client.now = func() time.Time { return now }
+
+	metrics := []telegraf.Metric{newMetric("cpu", map[string]string{})}
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	require.Equal(t, now.Add(30*time.Second), client.retryTime)
+
+	// No request is sent until the retry time has elapsed.
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	require.Equal(t, 1, requests)
+
+	now = now.Add(31 * time.Second)
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	require.Equal(t, 2, requests)
+}
+
+func TestHTTP_RetryBackoff(t *testing.T) {
+	client, err := NewHTTPClient(&HTTPConfig{URL: genURL("http://localhost:9999")})
+	require.NoError(t, err)
+
+	require.Equal(t, time.Second, client.retryDuration(""))
+	client.retryCount = 3
+	require.Equal(t, 8*time.Second, client.retryDuration(""))
+	require.Equal(t, 2*time.Second, client.retryDuration("2"))
+	client.retryCount = 20
+	require.Equal(t, defaultMaxRetryWait, client.retryDuration("invalid"))
+}
+
+func TestHTTP_BadRequestDiscards(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"invalid","message":"unable to parse"}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewHTTPClient(&HTTPConfig{URL: genURL(ts.URL)})
+	require.NoError(t, err)
+
+	err = client.Write(context.Background(),
+		[]telegraf.Metric{newMetric("cpu", map[string]string{})})
+	require.NoError(t, err)
+}
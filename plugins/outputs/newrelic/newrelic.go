@@ -0,0 +1,310 @@
+package newrelic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## License key of the New Relic account, or Insights insert key.
+  license_key = "$NEW_RELIC_LICENSE_KEY"
+  # insert_key = ""
+
+  ## Region of the account, "US" or "EU".
+  # region = "US"
+
+  ## URL of the Metric API, instead of the one of the region, such as for
+  ## the FedRAMP endpoint.
+  # url = ""
+
+  ## Timeout for HTTP requests.
+  # timeout = "15s"
+
+  ## HTTP Proxy URL.  Defaults to the HTTPS_PROXY environment variable.
+  # http_proxy = "http://corporate.proxy:3128"
+
+  ## Maximum size of the requests in bytes, before compression, the metrics
+  ## of the larger ones being sent in several requests.
+  # max_payload_size = 1000000
+`
+
+const (
+	defaultTimeout        = 15 * time.Second
+	defaultMaxPayloadSize = 1000000
+
+	// maxAttributeLength is the maximum length of the attribute values of
+	// the Metric API.
+	maxAttributeLength = 4096
+)
+
+// URLs of the Metric API by region.
+var regionURLs = map[string]string{
+	"US": "https://metric-api.newrelic.com/metric/v1",
+	"EU": "https://metric-api.eu.newrelic.com/metric/v1",
+}
+
+// nrqlReservedWords are the reserved words of NRQL, which must be quoted in
+// queries when used as attribute names.
+var nrqlReservedWords = map[string]bool{
+	"ago": true, "and": true, "as": true, "auto": true, "begin": true,
+	"begintime": true, "compare": true, "day": true, "days": true, "end": true,
+	"endtime": true, "explain": true, "facet": true, "from": true, "hour": true,
+	"hours": true, "in": true, "is": true, "like": true, "limit": true,
+	"minute": true, "minutes": true, "month": true, "months": true, "not": true,
+	"null": true, "offset": true, "or": true, "raw": true, "second": true,
+	"seconds": true, "select": true, "since": true, "timeseries": true,
+	"until": true, "week": true, "weeks": true, "where": true, "with": true,
+}
+
+type NewRelic struct {
+	LicenseKey     string            `toml:"license_key"`
+	InsertKey      string            `toml:"insert_key"`
+	Region         string            `toml:"region"`
+	URL            string            `toml:"url"`
+	Timeout        internal.Duration `toml:"timeout"`
+	HTTPProxy      string            `toml:"http_proxy"`
+	MaxPayloadSize int               `toml:"max_payload_size"`
+
+	client *http.Client
+}
+
+// payload is the body of the requests to the Metric API.
+type payload []*metricsData
+
+type metricsData struct {
+	Common  common      `json:"common"`
+	Metrics []*nrMetric `json:"metrics"`
+}
+
+type common struct {
+	Attributes map[string]string `json:"attributes"`
+}
+
+type nrMetric struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Value      float64           `json:"value"`
+	Timestamp  int64             `json:"timestamp"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (nr *NewRelic) SampleConfig() string {
+	return sampleConfig
+}
+
+func (nr *NewRelic) Description() string {
+	return "Send metrics to the Metric API of New Relic"
+}
+
+func (nr *NewRelic) Connect() error {
+	if nr.LicenseKey == "" && nr.InsertKey == "" {
+		return fmt.Errorf("license_key or insert_key is required")
+	}
+	if nr.LicenseKey != "" && nr.InsertKey != "" {
+		return fmt.Errorf("license_key and insert_key cannot be both set")
+	}
+
+	if nr.URL == "" {
+		region := strings.ToUpper(nr.Region)
+		if region == "" {
+			region = "US"
+		}
+		u, ok := regionURLs[region]
+		if !ok {
+			return fmt.Errorf("unknown region %q", nr.Region)
+		}
+		nr.URL = u
+	}
+
+	if nr.MaxPayloadSize == 0 {
+		nr.MaxPayloadSize = defaultMaxPayloadSize
+	}
+	if nr.MaxPayloadSize < 0 {
+		return fmt.Errorf("max_payload_size cannot be negative")
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if nr.HTTPProxy != "" {
+		proxyURL, err := url.Parse(nr.HTTPProxy)
+		if err != nil {
+			return fmt.Errorf("error parsing http_proxy [%s]: %v", nr.HTTPProxy, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	nr.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy: proxy,
+		},
+		Timeout: nr.Timeout.Duration,
+	}
+	return nil
+}
+
+func (nr *NewRelic) Close() error {
+	return nil
+}
+
+func (nr *NewRelic) Write(metrics []telegraf.Metric) error {
+	var nrMetrics []*nrMetric
+	for _, m := range metrics {
+		nrMetrics = append(nrMetrics, buildMetrics(m)...)
+	}
+	if len(nrMetrics) == 0 {
+		return nil
+	}
+	return nr.writeRange(nrMetrics)
+}
+
+// writeRange sends the metrics, in several requests if they are over
+// max_payload_size.  A metric over max_payload_size on its own is sent
+// anyway, for the API to report the error.
+func (nr *NewRelic) writeRange(metrics []*nrMetric) error {
+	body, err := json.Marshal(payload{{
+		Common:  common{Attributes: map[string]string{"instrumentation.provider": "telegraf"}},
+		Metrics: metrics,
+	}})
+	if err != nil {
+		return fmt.Errorf("unable to marshal metrics: %s", err)
+	}
+	if len(body) > nr.MaxPayloadSize && len(metrics) > 1 {
+		middle := len(metrics) / 2
+		if err := nr.writeRange(metrics[:middle]); err != nil {
+			return err
+		}
+		return nr.writeRange(metrics[middle:])
+	}
+	return nr.post(body)
+}
+
+func (nr *NewRelic) post(body []byte) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", nr.URL, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	if nr.LicenseKey != "" {
+		req.Header.Set("Api-Key", nr.LicenseKey)
+	} else {
+		req.Header.Set("X-Insert-Key", nr.InsertKey)
+	}
+
+	resp, err := nr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("when writing to [%s] received status code: %d", nr.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// buildMetrics returns the gauges of the numeric and boolean fields of a
+// metric, named after the measurement and the field, with its tags as
+// attributes.
+func buildMetrics(m telegraf.Metric) []*nrMetric {
+	attributes := make(map[string]string, len(m.Tags()))
+	for k, v := range m.Tags() {
+		if len(v) > maxAttributeLength {
+			v = v[:maxAttributeLength]
+		}
+		attributes[attributeName(k)] = v
+	}
+
+	var metrics []*nrMetric
+	for k, v := range m.Fields() {
+		value, ok := floatValue(v)
+		if !ok {
+			continue
+		}
+		name := m.Name()
+		if k != "value" {
+			name += "." + k
+		}
+		metrics = append(metrics, &nrMetric{
+			Name:       sanitize(name),
+			Type:       "gauge",
+			Value:      value,
+			Timestamp:  m.Time().UnixNano() / int64(time.Millisecond),
+			Attributes: attributes,
+		})
+	}
+	return metrics
+}
+
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sanitize replaces the characters of a name other than letters, digits,
+// underscores, dots and colons, which must be quoted in NRQL queries, by
+// underscores.
+func sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '.', r == ':':
+			return r
+		}
+		return '_'
+	}, name)
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// attributeName returns the name of the attribute of a tag, which can be
+// queried in NRQL without quoting.
+func attributeName(key string) string {
+	name := sanitize(key)
+	if nrqlReservedWords[strings.ToLower(name)] {
+		name += "_"
+	}
+	return name
+}
+
+func init() {
+	outputs.Add("newrelic", func() telegraf.Output {
+		return &NewRelic{
+			Timeout: internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package newrelic
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI records the payloads of the requests.
+type fakeAPI struct {
+	*httptest.Server
+	mu       sync.Mutex
+	headers  []http.Header
+	payloads []string
+	status   int
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{status: http.StatusAccepted}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(gz)
+		require.NoError(t, err)
+		api.mu.Lock()
+		api.headers = append(api.headers, r.Header)
+		api.payloads = append(api.payloads, string(body))
+		api.mu.Unlock()
+		w.WriteHeader(api.status)
+	}))
+	return api
+}
+
+func newMetric(t *testing.T, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New("cpu", tags, fields, time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func TestWrite(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	nr := &NewRelic{LicenseKey: "secret", URL: api.URL}
+	require.NoError(t, nr.Connect())
+	require.NoError(t, nr.Write([]telegraf.Metric{
+		newMetric(t,
+			map[string]string{"host": "a", "cpu id": "0", "from": "x"},
+			map[string]interface{}{"usage idle": 90.5, "ok": true, "state": "up"}),
+	}))
+
+	require.Len(t, api.payloads, 1)
+	require.Equal(t, "secret", api.headers[0].Get("Api-Key"))
+	var p []struct {
+		Common  common      `json:"common"`
+		Metrics []*nrMetric `json:"metrics"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(api.payloads[0]), &p))
+	require.Len(t, p, 1)
+	require.Equal(t, "telegraf", p[0].Common.Attributes["instrumentation.provider"])
+	require.Len(t, p[0].Metrics, 2)
+
+	attributes := map[string]string{"host": "a", "cpu_id": "0", "from_": "x"}
+	metrics := map[string]*nrMetric{}
+	for _, m := range p[0].Metrics {
+		metrics[m.Name] = m
+	}
+	require.Equal(t, &nrMetric{Name: "cpu.usage_idle", Type: "gauge", Value: 90.5, Timestamp: 1500000000000, Attributes: attributes}, metrics["cpu.usage_idle"])
+	require.Equal(t, &nrMetric{Name: "cpu.ok", Type: "gauge", Value: 1, Timestamp: 1500000000000, Attributes: attributes}, metrics["cpu.ok"])
+}
+
+func TestInsertKey(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	nr := &NewRelic{InsertKey: "insert", URL: api.URL}
+	require.NoError(t, nr.Connect())
+	require.NoError(t, nr.Write([]telegraf.Metric{newMetric(t, nil, map[string]interface{}{"value": 1.0})}))
+	require.Equal(t, "insert", api.headers[0].Get("X-Insert-Key"))
+	require.Empty(t, api.headers[0].Get("Api-Key"))
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	nr := &NewRelic{LicenseKey: "secret", URL: api.URL, MaxPayloadSize: 400}
+	require.NoError(t, nr.Connect())
+	var metrics []telegraf.Metric
+	for i := 0; i < 20; i++ {
+		metrics = append(metrics, newMetric(t, map[string]string{"host": fmt.Sprintf("host%d", i)}, map[string]interface{}{"value": 1.0}))
+	}
+	require.NoError(t, nr.Write(metrics))
+
+	count := 0
+	for _, body := range api.payloads {
+		require.True(t, len(body) <= 400, body)
+		var p []metricsData
+		require.NoError(t, json.Unmarshal([]byte(body), &p))
+		count += len(p[0].Metrics)
+	}
+	require.Equal(t, 20, count)
+	require.True(t, len(api.payloads) > 1)
+}
+
+func TestBadStatusCode(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+	api.status = http.StatusForbidden
+
+	nr := &NewRelic{LicenseKey: "secret", URL: api.URL}
+	require.NoError(t, nr.Connect())
+	err := nr.Write([]telegraf.Metric{newMetric(t, nil, map[string]interface{}{"value": 1.0})})
+	require.EqualError(t, err, fmt.Sprintf("when writing to [%s] received status code: 403", api.URL))
+}
+
+func TestConnect(t *testing.T) {
+	nr := &NewRelic{LicenseKey: "secret", Region: "eu"}
+	require.NoError(t, nr.Connect())
+	require.Equal(t, "https://metric-api.eu.newrelic.com/metric/v1", nr.URL)
+
+	nr = &NewRelic{LicenseKey: "secret"}
+	require.NoError(t, nr.Connect())
+	require.Equal(t, "https://metric-api.newrelic.com/metric/v1", nr.URL)
+
+	nr = &NewRelic{}
+	require.EqualError(t, nr.Connect(), "license_key or insert_key is required")
+
+	nr = &NewRelic{LicenseKey: "secret", InsertKey: "insert"}
+	require.EqualError(t, nr.Connect(), "license_key and insert_key cannot be both set")
+
+	nr = &NewRelic{LicenseKey: "secret", Region: "APAC"}
+	require.EqualError(t, nr.Connect(), `unknown region "APAC"`)
+}
+
+func TestAttributeName(t *testing.T) {
+	require.Equal(t, "host", attributeName("host"))
+	require.Equal(t, "aws.region:zone", attributeName("aws.region:zone"))
+	require.Equal(t, "cpu_id", attributeName("cpu id"))
+	require.Equal(t, "_1st", attributeName("1st"))
+	require.Equal(t, "Limit_", attributeName("Limit"))
+}
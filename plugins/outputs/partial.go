@@ -0,0 +1,19 @@
+package outputs
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// PartialWriteError is returned by the outputs that wrote only some of the
+// metrics passed to Write, so that only the metrics that were not written are
+// kept for retry.
+type PartialWriteError struct {
+	Err error
+
+	// Failed are the metrics that were not written.
+	Failed []telegraf.Metric
+}
+
+func (e *PartialWriteError) Error() string {
+	return e.Err.Error()
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/subtle"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"regexp"
@@ -15,7 +16,9 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -54,16 +57,19 @@ type MetricFamily struct {
 
 type PrometheusClient struct {
 	Listen             string
-	TLSCert            string            `toml:"tls_cert"`
-	TLSKey             string            `toml:"tls_key"`
 	BasicUsername      string            `toml:"basic_username"`
 	BasicPassword      string            `toml:"basic_password"`
 	ExpirationInterval internal.Duration `toml:"expiration_interval"`
+	MetricExpiration   map[string]string `toml:"metric_expiration"`
 	Path               string            `toml:"path"`
 	CollectorsExclude  []string          `toml:"collectors_exclude"`
 	StringAsLabel      bool              `toml:"string_as_label"`
+	ExportFamilies     bool              `toml:"export_families"`
+	tls.ServerConfig
 
 	server *http.Server
+	// expirations are the compiled MetricExpiration, longest pattern first.
+	expirations []expiration
 
 	sync.Mutex
 	// fam is the non-expired MetricFamily by Prometheus metric name.
@@ -79,6 +85,8 @@ var sampleConfig = `
   ## Use TLS
   #tls_cert = "/etc/ssl/telegraf.crt"
   #tls_key = "/etc/ssl/telegraf.key"
+  ## Require clients to present a certificate signed by one of these CAs
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
 
   ## Use http basic authentication
   #basic_username = "Foo"
@@ -87,6 +95,13 @@ var sampleConfig = `
   ## Interval to expire metrics and not deliver to prometheus, 0 == no expiration
   # expiration_interval = "60s"
 
+  ## Expiration intervals of individual measurements, overriding
+  ## expiration_interval.  Glob patterns are supported, if several patterns
+  ## match a measurement the longest one is used.
+  # [outputs.prometheus_client.metric_expiration]
+  #   cpu = "10s"
+  #   "disk*" = "0s"
+
   ## Collectors to enable, valid entries are "gocollector" and "process".
   ## If unset, both are enabled.
   collectors_exclude = ["gocollector", "process"]
@@ -94,8 +109,21 @@ var sampleConfig = `
   # Send string metrics as Prometheus labels.
   # Unless set to false all string metrics will be sent as labels.
   string_as_label = true
+
+  ## Export the buckets of the histogram aggregator, "<field>_bucket" fields
+  ## with an "le" tag, as Prometheus histograms and fields with a "quantile"
+  ## tag as Prometheus summaries.  The sum and count are taken from the
+  ## "<field>_sum" and "<field>_count" fields with the same tags, such as
+  ## those of the basicstats aggregator.
+  # export_families = false
 `
 
+type expiration struct {
+	pattern  string
+	filter   filter.Filter
+	interval time.Duration
+}
+
 func (p *PrometheusClient) basicAuth(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if p.BasicUsername != "" && p.BasicPassword != "" {
@@ -115,6 +143,15 @@ func (p *PrometheusClient) basicAuth(h http.Handler) http.Handler {
 }
 
 func (p *PrometheusClient) Start() error {
+	if err := p.compileExpirations(); err != nil {
+		return err
+	}
+
+	tlsConfig, err := p.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
 	defaultCollectors := map[string]bool{
 		"gocollector": true,
 		"process":     true,
@@ -150,14 +187,15 @@ func (p *PrometheusClient) Start() error {
 		registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})))
 
 	p.server = &http.Server{
-		Addr:    p.Listen,
-		Handler: mux,
+		Addr:      p.Listen,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
 	}
 
 	go func() {
 		var err error
-		if p.TLSCert != "" && p.TLSKey != "" {
-			err = p.server.ListenAndServeTLS(p.TLSCert, p.TLSKey)
+		if tlsConfig != nil {
+			err = p.server.ListenAndServeTLS("", "")
 		} else {
 			err = p.server.ListenAndServe()
 		}
@@ -170,6 +208,46 @@ func (p *PrometheusClient) Start() error {
 	return nil
 }
 
+// compileExpirations compiles the patterns of MetricExpiration.
+func (p *PrometheusClient) compileExpirations() error {
+	p.expirations = p.expirations[:0]
+	for pattern, interval := range p.MetricExpiration {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return fmt.Errorf("invalid expiration of %q: %s", pattern, err)
+		}
+		f, err := filter.Compile([]string{pattern})
+		if err != nil {
+			return fmt.Errorf("invalid expiration pattern %q: %s", pattern, err)
+		}
+		p.expirations = append(p.expirations, expiration{pattern, f, d})
+	}
+	sort.Slice(p.expirations, func(i, j int) bool {
+		a, b := p.expirations[i].pattern, p.expirations[j].pattern
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return nil
+}
+
+// expiration returns the deadline of a sample of the measurement written
+// at now, or the zero time if it does not expire.
+func (p *PrometheusClient) expiration(measurement string, now time.Time) time.Time {
+	interval := p.ExpirationInterval.Duration
+	for _, e := range p.expirations {
+		if e.filter.Match(measurement) {
+			interval = e.interval
+			break
+		}
+	}
+	if interval == 0 {
+		return time.Time{}
+	}
+	return now.Add(interval)
+}
+
 func (p *PrometheusClient) Stop() {
 	// plugin gets cleaned up in Close() already.
 }
@@ -205,7 +283,7 @@ func (p *PrometheusClient) Expire() {
 	now := p.now()
 	for name, family := range p.fam {
 		for key, sample := range family.Samples {
-			if p.ExpirationInterval.Duration != 0 && now.After(sample.Expiration) {
+			if !sample.Expiration.IsZero() && now.After(sample.Expiration) {
 				for k, _ := range sample.Labels {
 					family.LabelSet[k]--
 				}
@@ -226,7 +304,12 @@ func (p *PrometheusClient) Collect(ch chan<- prometheus.Metric) {
 
 	p.Expire()
 
-	for name, family := range p.fam {
+	families := p.fam
+	if p.ExportFamilies {
+		families = p.families()
+	}
+
+	for name, family := range families {
 		// Get list of all labels on MetricFamily
 		var labelNames []string
 		for k, v := range family.LabelSet {
@@ -266,8 +349,194 @@ func (p *PrometheusClient) Collect(ch chan<- prometheus.Metric) {
 	}
 }
 
+// families returns the metric families with the histogram buckets and the
+// quantiles produced by aggregators merged into Prometheus histograms and
+// summaries.  The samples of the matching "_sum" and "_count" families are
+// moved into the merged families.
+func (p *PrometheusClient) families() map[string]*MetricFamily {
+	families := make(map[string]*MetricFamily, len(p.fam))
+	for name, family := range p.fam {
+		families[name] = family
+	}
+
+	for name, family := range p.fam {
+		if family.TelegrafValueType == telegraf.Histogram ||
+			family.TelegrafValueType == telegraf.Summary {
+			continue
+		}
+
+		var base, tag string
+		var valueType telegraf.ValueType
+		switch {
+		case family.LabelSet["le"] > 0 && strings.HasSuffix(name, "_bucket"):
+			base, tag, valueType = strings.TrimSuffix(name, "_bucket"), "le", telegraf.Histogram
+			if _, ok := families[base]; ok {
+				// The name of the histogram is used by another metric.
+				continue
+			}
+		case family.LabelSet["quantile"] > 0:
+			base, tag, valueType = name, "quantile", telegraf.Summary
+		default:
+			continue
+		}
+
+		merged := &MetricFamily{
+			Samples:           make(map[SampleID]*Sample),
+			TelegrafValueType: valueType,
+			LabelSet:          make(map[string]int),
+		}
+		for _, sample := range family.Samples {
+			bound, err := strconv.ParseFloat(sample.Labels[tag], 64)
+			if err != nil {
+				continue
+			}
+
+			labels := make(map[string]string, len(sample.Labels)-1)
+			for k, v := range sample.Labels {
+				if k != tag {
+					labels[k] = v
+				}
+			}
+			id := CreateSampleID(labels)
+			s, ok := merged.Samples[id]
+			if !ok {
+				s = &Sample{
+					Labels:         labels,
+					HistogramValue: make(map[float64]uint64),
+					SummaryValue:   make(map[float64]float64),
+					Expiration:     sample.Expiration,
+				}
+				addSample(merged, s, id)
+			}
+
+			switch {
+			case valueType == telegraf.Summary:
+				s.SummaryValue[bound] = sample.Value
+			case math.IsInf(bound, 1):
+				s.Count = uint64(sample.Value)
+			default:
+				s.HistogramValue[bound] = uint64(sample.Value)
+			}
+		}
+		if len(merged.Samples) == 0 {
+			continue
+		}
+
+		for id, s := range merged.Samples {
+			if v, ok := takeSample(families, base+"_sum", id); ok {
+				s.Sum = v
+			}
+			if v, ok := takeSample(families, base+"_count", id); ok {
+				s.Count = uint64(v)
+			}
+		}
+		delete(families, name)
+		families[base] = merged
+	}
+	return families
+}
+
+// takeSample removes the sample with the labels of id from the named
+// family and returns its value.  The family is copied, leaving the stored
+// families unchanged.
+func takeSample(families map[string]*MetricFamily, name string, id SampleID) (float64, bool) {
+	family, ok := families[name]
+	if !ok {
+		return 0, false
+	}
+	for key, sample := range family.Samples {
+		if CreateSampleID(sample.Labels) != id {
+			continue
+		}
+
+		cp := &MetricFamily{
+			Samples:           make(map[SampleID]*Sample, len(family.Samples)),
+			TelegrafValueType: family.TelegrafValueType,
+			LabelSet:          make(map[string]int, len(family.LabelSet)),
+		}
+		for k, v := range family.Samples {
+			if k != key {
+				cp.Samples[k] = v
+			}
+		}
+		for k, v := range family.LabelSet {
+			cp.LabelSet[k] = v
+		}
+		for k := range sample.Labels {
+			cp.LabelSet[k]--
+		}
+
+		if len(cp.Samples) == 0 {
+			delete(families, name)
+		} else {
+			families[name] = cp
+		}
+		return sample.Value, true
+	}
+	return 0, false
+}
+
+// sanitize converts a name to a valid Prometheus metric or label name.
 func sanitize(value string) string {
-	return invalidNameCharRE.ReplaceAllString(value, "_")
+	value = invalidNameCharRE.ReplaceAllString(value, "_")
+	if value == "" || (value[0] >= '0' && value[0] <= '9') {
+		value = "_" + value
+	}
+	return value
+}
+
+// sanitizeLabel converts a name to a valid Prometheus label name, which
+// may not start with "__" as those are reserved by Prometheus.
+func sanitizeLabel(value string) string {
+	value = sanitize(value)
+	for strings.HasPrefix(value, "__") {
+		value = value[1:]
+	}
+	return value
+}
+
+// labels returns the Prometheus labels of the metric and the SampleID of its
+// tags.  Tags and string fields may have the same label name once
+// sanitized; only one value is kept for each label, tags take precedence
+// over fields and otherwise the first name in sorted order wins.  The
+// SampleID is created from the sanitized tags, so that metrics with tags
+// that collide replace each other instead of producing duplicate series.
+func (p *PrometheusClient) labels(point telegraf.Metric) (map[string]string, SampleID) {
+	labels := make(map[string]string)
+	for _, tag := range point.TagList() {
+		name := sanitizeLabel(tag.Key)
+		if _, ok := labels[name]; ok {
+			log.Printf("D! [outputs.prometheus_client] Tag %q of %q collides with label %q, skipping",
+				tag.Key, point.Name(), name)
+			continue
+		}
+		labels[name] = tag.Value
+	}
+	sampleID := CreateSampleID(labels)
+
+	// Prometheus doesn't have a string value type, so convert string
+	// fields to labels if enabled.
+	if p.StringAsLabel {
+		var keys []string
+		for fn, fv := range point.Fields() {
+			if _, ok := fv.(string); ok {
+				keys = append(keys, fn)
+			}
+		}
+		sort.Strings(keys)
+
+		fields := point.Fields()
+		for _, fn := range keys {
+			name := sanitizeLabel(fn)
+			if _, ok := labels[name]; ok {
+				log.Printf("D! [outputs.prometheus_client] Field %q of %q collides with label %q, skipping",
+					fn, point.Name(), name)
+				continue
+			}
+			labels[name] = fields[fn].(string)
+		}
+	}
+	return labels, sampleID
 }
 
 func getPromValueType(tt telegraf.ValueType) prometheus.ValueType {
@@ -292,6 +561,12 @@ func CreateSampleID(tags map[string]string) SampleID {
 }
 
 func addSample(fam *MetricFamily, sample *Sample, sampleID SampleID) {
+	// Replace the labels of the previous sample with the same ID.
+	if old, ok := fam.Samples[sampleID]; ok {
+		for k := range old.Labels {
+			fam.LabelSet[k]--
+		}
+	}
 
 	for k, _ := range sample.Labels {
 		fam.LabelSet[k]++
@@ -322,24 +597,8 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 	now := p.now()
 
 	for _, point := range metrics {
-		tags := point.Tags()
-		sampleID := CreateSampleID(tags)
-
-		labels := make(map[string]string)
-		for k, v := range tags {
-			labels[sanitize(k)] = v
-		}
-
-		// Prometheus doesn't have a string value type, so convert string
-		// fields to labels if enabled.
-		if p.StringAsLabel {
-			for fn, fv := range point.Fields() {
-				switch fv := fv.(type) {
-				case string:
-					labels[sanitize(fn)] = fv
-				}
-			}
-		}
+		labels, sampleID := p.labels(point)
+		expiration := p.expiration(point.Name(), now)
 
 		switch point.Type() {
 		case telegraf.Summary:
@@ -377,7 +636,7 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 				SummaryValue: summaryvalue,
 				Count:        count,
 				Sum:          sum,
-				Expiration:   now.Add(p.ExpirationInterval.Duration),
+				Expiration:   expiration,
 			}
 			mname = sanitize(point.Name())
 
@@ -418,7 +677,7 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 				HistogramValue: histogramvalue,
 				Count:          count,
 				Sum:            sum,
-				Expiration:     now.Add(p.ExpirationInterval.Duration),
+				Expiration:     expiration,
 			}
 			mname = sanitize(point.Name())
 
@@ -442,7 +701,7 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 				sample := &Sample{
 					Labels:     labels,
 					Value:      value,
-					Expiration: now.Add(p.ExpirationInterval.Duration),
+					Expiration: expiration,
 				}
 
 				// Special handling of value field; supports passthrough from
@@ -1,6 +1,9 @@
 package prometheus_client
 
 import (
+	"net"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +12,8 @@ import (
 	"github.com/influxdata/telegraf/metric"
 	prometheus_input "github.com/influxdata/telegraf/plugins/inputs/prometheus"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -198,7 +203,8 @@ func TestWrite_Sanitize(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, map[string]int{"tag_with_dash": 1}, fam.LabelSet)
 
-	sample1, ok := fam.Samples[CreateSampleID(p1.Tags())]
+	sample1, ok := fam.Samples[CreateSampleID(map[string]string{
+		"tag_with_dash": "localhost.local"})]
 	require.True(t, ok)
 
 	require.Equal(t, map[string]string{
@@ -691,3 +697,191 @@ func setupPrometheus() (*PrometheusClient, *prometheus_input.Prometheus, error)
 
 	return pTesting, p, nil
 }
+
+func TestWrite_SanitizeCollisions(t *testing.T) {
+	client := NewClient()
+
+	p1, err := metric.New(
+		"1foo",
+		map[string]string{"a.b": "1", "a_b": "2", "__name": "x"},
+		map[string]interface{}{"value": 1.0, "a-b": "field"},
+		time.Now())
+	require.NoError(t, err)
+	p2, err := metric.New(
+		"1foo",
+		map[string]string{"a_b": "1", "__name": "x"},
+		map[string]interface{}{"value": 2.0},
+		time.Now())
+	require.NoError(t, err)
+	p3, err := metric.New(
+		"1foo",
+		map[string]string{"a.b": "1", "_name": "x"},
+		map[string]interface{}{"value": 3.0},
+		time.Now())
+	require.NoError(t, err)
+
+	err = client.Write([]telegraf.Metric{p1})
+	require.NoError(t, err)
+
+	fam, ok := client.fam["_1foo"]
+	require.True(t, ok)
+	require.Len(t, fam.Samples, 1)
+	for _, sample := range fam.Samples {
+		require.Equal(t, map[string]string{"a_b": "1", "_name": "x"}, sample.Labels)
+	}
+
+	// Metrics with tags that collide once sanitized are the same series.
+	err = client.Write([]telegraf.Metric{p2, p3})
+	require.NoError(t, err)
+	require.Len(t, fam.Samples, 1)
+	require.Equal(t, map[string]int{"a_b": 1, "_name": 1}, fam.LabelSet)
+	for _, sample := range fam.Samples {
+		require.Equal(t, 3.0, sample.Value)
+	}
+}
+
+func TestMetricExpiration(t *testing.T) {
+	client := NewClient()
+	client.MetricExpiration = map[string]string{
+		"cpu": "10s",
+		"c*":  "0s",
+		"*":   "5s",
+	}
+	require.NoError(t, client.compileExpirations())
+
+	var metrics []telegraf.Metric
+	for _, name := range []string{"cpu", "cache", "mem"} {
+		m, err := metric.New(name,
+			map[string]string{},
+			map[string]interface{}{"value": 1.0},
+			time.Now())
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	setUnixTime(client, 0)
+	require.NoError(t, client.Write(metrics))
+
+	setUnixTime(client, 6)
+	client.Expire()
+	require.Contains(t, client.fam, "cpu")
+	require.Contains(t, client.fam, "cache")
+	require.NotContains(t, client.fam, "mem")
+
+	setUnixTime(client, 3600)
+	client.Expire()
+	require.NotContains(t, client.fam, "cpu")
+	require.Contains(t, client.fam, "cache")
+
+	client.MetricExpiration = map[string]string{"cpu": "soon"}
+	require.Error(t, client.compileExpirations())
+}
+
+func TestExportFamilies(t *testing.T) {
+	client := NewClient()
+	client.ExportFamilies = true
+
+	var metrics []telegraf.Metric
+	for le, count := range map[string]int64{"10": 1, "50": 3, "+Inf": 4} {
+		m, err := metric.New("latency",
+			map[string]string{"host": "a", "le": le},
+			map[string]interface{}{"request_bucket": count},
+			time.Now())
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	for q, value := range map[string]float64{"0.5": 12.5, "0.99": 80} {
+		m, err := metric.New("latency",
+			map[string]string{"host": "a", "quantile": q},
+			map[string]interface{}{"response": value},
+			time.Now())
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	stats, err := metric.New("latency",
+		map[string]string{"host": "a"},
+		map[string]interface{}{
+			"request_sum":    int64(95),
+			"response_sum":   200.0,
+			"response_count": int64(7),
+			"other_count":    int64(2),
+		},
+		time.Now())
+	require.NoError(t, err)
+	metrics = append(metrics, stats)
+	require.NoError(t, client.Write(metrics))
+
+	ch := make(chan prometheus.Metric, 10)
+	client.Collect(ch)
+	close(ch)
+
+	collected := make(map[string]*dto.Metric)
+	for m := range ch {
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+		name := m.Desc().String()
+		name = name[strings.Index(name, `"`)+1:]
+		name = name[:strings.Index(name, `"`)]
+		collected[name] = pb
+	}
+	require.Len(t, collected, 3)
+
+	histogram := collected["latency_request"].GetHistogram()
+	require.NotNil(t, histogram)
+	require.Equal(t, uint64(4), histogram.GetSampleCount())
+	require.Equal(t, 95.0, histogram.GetSampleSum())
+	require.Len(t, histogram.GetBucket(), 2)
+	require.Equal(t, "host", collected["latency_request"].GetLabel()[0].GetName())
+
+	summary := collected["latency_response"].GetSummary()
+	require.NotNil(t, summary)
+	require.Equal(t, uint64(7), summary.GetSampleCount())
+	require.Equal(t, 200.0, summary.GetSampleSum())
+	require.Len(t, summary.GetQuantile(), 2)
+
+	require.Equal(t, 2.0, collected["latency_other_count"].GetUntyped().GetValue())
+
+	// The stored families are unchanged.
+	require.Contains(t, client.fam, "latency_request_bucket")
+	require.Contains(t, client.fam, "latency_response_count")
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	pki := testutil.NewPKI("../../../testutil/pki")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := NewClient()
+	client.Listen = addr
+	client.CollectorsExclude = []string{"gocollector", "process"}
+	client.ServerConfig = *pki.TLSServerConfig()
+	require.NoError(t, client.Start())
+	defer client.Close()
+
+	tlsConfig, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+
+	url := "https://" + addr + "/metrics"
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		resp, err = httpClient.Get(url)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Clients without a certificate are refused.
+	tlsConfig.Certificates = nil
+	httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	_, err = httpClient.Get(url)
+	require.Error(t, err)
+}
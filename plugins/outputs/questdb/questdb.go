@@ -0,0 +1,227 @@
+package questdb
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+const defaultBatchSize = 1000
+
+// invalidTableChars and invalidColumnChars are the characters QuestDB does
+// not accept in the names of the tables and of the columns.
+const (
+	invalidTableChars  = ".?,'\"\\/:()+*%~\r\n\t\x00\ufeff"
+	invalidColumnChars = invalidTableChars + "-"
+)
+
+var sampleConfig = `
+  ## Address of the InfluxDB line protocol listener of QuestDB, usually on the
+  ## port 9009.
+  address = "tcp://localhost:9009"
+
+  ## Number of lines sent at once.
+  # batch_size = 1000
+
+  ## Timeout for the connection and the writes.
+  # timeout = "5s"
+
+  ## Period between keep alive probes.
+  ## 0 disables keep alive probes.
+  ## Defaults to the OS configuration.
+  # keep_alive_period = "5m"
+
+  ## QuestDB has no unsigned integer type.  How to write the unsigned
+  ## integer fields:
+  ##   "clamp" - as long integers, the values over the maximum clamped
+  ##   "float" - as doubles
+  ##   "drop"  - the fields are dropped
+  # uint_mode = "clamp"
+
+  ## Optional TLS Config, for QuestDB Enterprise or a TLS terminating proxy
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type QuestDB struct {
+	Address         string
+	BatchSize       int `toml:"batch_size"`
+	Timeout         internal.Duration
+	KeepAlivePeriod *internal.Duration
+	UintMode        string `toml:"uint_mode"`
+	tlsint.ClientConfig
+
+	conn       net.Conn
+	serializer *influx.Serializer
+}
+
+func (q *QuestDB) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *QuestDB) Description() string {
+	return "Write metrics to QuestDB over the InfluxDB line protocol"
+}
+
+func (q *QuestDB) Connect() error {
+	mode, err := influx.ParseUintMode(q.UintMode)
+	if err != nil {
+		return err
+	}
+	if q.BatchSize <= 0 {
+		q.BatchSize = defaultBatchSize
+	}
+	q.serializer = influx.NewSerializer()
+	q.serializer.SetUintMode(mode)
+	return q.dial()
+}
+
+func (q *QuestDB) dial() error {
+	spl := strings.SplitN(q.Address, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid address: %s", q.Address)
+	}
+	switch spl[0] {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return fmt.Errorf("unsupported protocol %q in address %q", spl[0], q.Address)
+	}
+
+	tlsCfg, err := q.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	dialer := &net.Dialer{Timeout: q.Timeout.Duration}
+	if q.KeepAlivePeriod != nil {
+		dialer.KeepAlive = q.KeepAlivePeriod.Duration
+		if dialer.KeepAlive == 0 {
+			dialer.KeepAlive = -1
+		}
+	}
+
+	var c net.Conn
+	if tlsCfg == nil {
+		c, err = dialer.Dial(spl[0], spl[1])
+	} else {
+		c, err = tls.DialWithDialer(dialer, spl[0], spl[1], tlsCfg)
+	}
+	if err != nil {
+		return err
+	}
+	q.conn = c
+	return nil
+}
+
+func (q *QuestDB) Close() error {
+	if q.conn == nil {
+		return nil
+	}
+	err := q.conn.Close()
+	q.conn = nil
+	return err
+}
+
+// Write sends the lines of the metrics in batches.  QuestDB does not
+// acknowledge the lines, and closes the connection on the lines it cannot
+// write, which fails a later write.
+func (q *QuestDB) Write(metrics []telegraf.Metric) error {
+	if q.conn == nil {
+		if err := q.dial(); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	lines := 0
+	for _, m := range metrics {
+		if _, err := q.serializer.Write(&buf, sanitize(m)); err != nil {
+			log.Printf("D! [outputs.questdb] Could not serialize metric %q: %s; discarding metric", m.Name(), err)
+			continue
+		}
+		lines++
+		if lines == q.BatchSize {
+			if err := q.send(&buf); err != nil {
+				return err
+			}
+			lines = 0
+		}
+	}
+	if lines > 0 {
+		return q.send(&buf)
+	}
+	return nil
+}
+
+func (q *QuestDB) send(buf *bytes.Buffer) error {
+	if q.Timeout.Duration > 0 {
+		q.conn.SetWriteDeadline(time.Now().Add(q.Timeout.Duration))
+	}
+	if _, err := q.conn.Write(buf.Bytes()); err != nil {
+		q.Close()
+		return fmt.Errorf("closing connection: %v", err)
+	}
+	buf.Reset()
+	return nil
+}
+
+// sanitize returns the metric with the characters QuestDB does not accept in
+// the names of the tables and columns replaced by underscores.  The tables
+// are named after the measurements, and the columns after the tags, of the
+// symbol type, and the fields.
+func sanitize(m telegraf.Metric) telegraf.Metric {
+	dirty := strings.ContainsAny(m.Name(), invalidTableChars)
+	for _, tag := range m.TagList() {
+		dirty = dirty || strings.ContainsAny(tag.Key, invalidColumnChars)
+	}
+	for _, field := range m.FieldList() {
+		dirty = dirty || strings.ContainsAny(field.Key, invalidColumnChars)
+	}
+	if !dirty {
+		return m
+	}
+
+	tags := make(map[string]string, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		tags[replaceChars(tag.Key, invalidColumnChars)] = tag.Value
+	}
+	fields := make(map[string]interface{}, len(m.FieldList()))
+	for _, field := range m.FieldList() {
+		fields[replaceChars(field.Key, invalidColumnChars)] = field.Value
+	}
+	sanitized, err := metric.New(replaceChars(m.Name(), invalidTableChars), tags, fields, m.Time(), m.Type())
+	if err != nil {
+		return m
+	}
+	return sanitized
+}
+
+func replaceChars(s, chars string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func init() {
+	outputs.Add("questdb", func() telegraf.Output {
+		return &QuestDB{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
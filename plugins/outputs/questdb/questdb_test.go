@@ -0,0 +1,95 @@
+package questdb
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitize(t *testing.T) {
+	m, err := metric.New("disk.io",
+		map[string]string{"dev-name": "sda", "host": "a"},
+		map[string]interface{}{"read/s": 1.0, "writes": int64(2)},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+
+	s := sanitize(m)
+	require.Equal(t, "disk_io", s.Name())
+	require.Equal(t, map[string]string{"dev_name": "sda", "host": "a"}, s.Tags())
+	require.Equal(t, map[string]interface{}{"read_s": 1.0, "writes": int64(2)}, s.Fields())
+	require.Equal(t, m.Time(), s.Time())
+
+	m, err = metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1.0}, time.Unix(0, 0))
+	require.NoError(t, err)
+	require.True(t, m == sanitize(m))
+}
+
+func TestWrite(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	q := &QuestDB{
+		Address:   "tcp://" + listener.Addr().String(),
+		BatchSize: 2,
+	}
+	require.NoError(t, q.Connect())
+	defer q.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ts := time.Unix(1500000000, 123)
+	var metrics []telegraf.Metric
+	for _, fields := range []map[string]interface{}{
+		{"value": 1.5},
+		{"value": uint64(1) << 63},
+		{"value": "text"},
+	} {
+		m, err := metric.New("cpu.load", map[string]string{"host": "a"}, fields, ts)
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	require.NoError(t, q.Write(metrics))
+
+	r := bufio.NewReader(conn)
+	for _, expected := range []string{
+		"cpu_load,host=a value=1.5 1500000000000000123\n",
+		"cpu_load,host=a value=9223372036854775807i 1500000000000000123\n",
+		"cpu_load,host=a value=\"text\" 1500000000000000123\n",
+	} {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, expected, line)
+	}
+
+	// The connection is dialed again after it is closed.
+	conn.Close()
+	for i := 0; i < 10 && err == nil; i++ {
+		err = q.Write(metrics)
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Error(t, err)
+	require.Nil(t, q.conn)
+	require.NoError(t, q.Write(metrics[:1]))
+	conn, err = listener.Accept()
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "cpu_load,host=a value=1.5 1500000000000000123\n", line)
+}
+
+func TestConnect(t *testing.T) {
+	q := &QuestDB{Address: "udp://127.0.0.1:9009"}
+	require.EqualError(t, q.Connect(), `unsupported protocol "udp" in address "udp://127.0.0.1:9009"`)
+
+	q = &QuestDB{Address: "tcp://127.0.0.1:9009", UintMode: "wrap"}
+	require.EqualError(t, q.Connect(), "invalid uint mode: wrap")
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T) telegraf.Metric {
+	m, err := metric.New(
+		"cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 99.5, "threads": int64(4), "state": "ok"},
+		time.Unix(0, 1500000000123456789),
+	)
+	require.NoError(t, err)
+	return m
+}
+
+func TestKey(t *testing.T) {
+	m := newMetric(t)
+	tests := []struct {
+		key   string
+		field string
+		want  string
+	}{
+		{"{{series}}:{{field}}", "usage_idle", "cpu,cpu=cpu0,host=a:usage_idle"},
+		{"telegraf:{{ measurement }}", "", "telegraf:cpu"},
+		{"{{host}}/{{measurement}}/{{region}}", "", "a/cpu/"},
+		{"metrics", "", "metrics"},
+	}
+	for _, tt := range tests {
+		key, err := compileKey(tt.key)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, key.expand(m, tt.field), tt.key)
+	}
+
+	_, err := compileKey("{{}}")
+	require.EqualError(t, err, `empty placeholder in key "{{}}"`)
+}
+
+func TestInit(t *testing.T) {
+	r := &Redis{}
+	require.NoError(t, r.init())
+	require.Equal(t, modeTimeSeries, r.Mode)
+	require.Equal(t, defaultTimeSeriesKey, r.Key)
+	require.Equal(t, 1000, r.PipelineSize)
+
+	r = &Redis{Mode: modeStream}
+	require.NoError(t, r.init())
+	require.Equal(t, defaultStreamKey, r.Key)
+
+	r = &Redis{Mode: modeStream, Key: "{{measurement}}.{{field}}"}
+	require.EqualError(t, r.init(), "the {{field}} placeholder of the key requires the timeseries mode")
+
+	r = &Redis{Mode: "list"}
+	require.EqualError(t, r.init(), `unknown mode "list"`)
+
+	r = &Redis{DuplicatePolicy: "newest"}
+	require.EqualError(t, r.init(), `unknown duplicate_policy "newest"`)
+}
+
+func TestTSAddArgs(t *testing.T) {
+	r := &Redis{
+		Retention:       internal.Duration{Duration: time.Hour},
+		DuplicatePolicy: "last",
+	}
+	require.NoError(t, r.init())
+
+	// The string field is not added.
+	commands := r.tsaddArgs(newMetric(t))
+	require.Len(t, commands, 2)
+	require.Contains(t, commands, []interface{}{
+		"TS.ADD", "cpu,cpu=cpu0,host=a:usage_idle", int64(1500000000123), 99.5,
+		"RETENTION", int64(3600000), "ON_DUPLICATE", "LAST",
+		"LABELS", "measurement", "cpu", "field", "usage_idle", "cpu", "cpu0", "host", "a",
+	})
+	require.Contains(t, commands, []interface{}{
+		"TS.ADD", "cpu,cpu=cpu0,host=a:threads", int64(1500000000123), 4.0,
+		"RETENTION", int64(3600000), "ON_DUPLICATE", "LAST",
+		"LABELS", "measurement", "cpu", "field", "threads", "cpu", "cpu0", "host", "a",
+	})
+}
+
+func TestXAddArgs(t *testing.T) {
+	r := &Redis{Mode: modeStream, StreamMaxLen: 10000}
+	require.NoError(t, r.init())
+
+	args := r.xaddArgs(newMetric(t))
+	require.Equal(t, []interface{}{
+		"XADD", "telegraf:cpu", "MAXLEN", "~", int64(10000), "*",
+		"measurement", "cpu", "cpu", "cpu0", "host", "a",
+	}, args[:12])
+	require.Equal(t, []interface{}{"time", int64(1500000000123456789)}, args[len(args)-2:])
+
+	fields := map[interface{}]interface{}{}
+	for i := 12; i < len(args)-2; i += 2 {
+		fields[args[i]] = args[i+1]
+	}
+	require.Equal(t, map[interface{}]interface{}{
+		"usage_idle": "99.5",
+		"threads":    "4",
+		"state":      "ok",
+	}, fields)
+}
+
+func TestConnectAndWrite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	r := &Redis{
+		Server:  "tcp://" + testutil.GetLocalHost() + ":6379",
+		Mode:    modeStream,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, r.Connect())
+	defer r.Close()
+
+	require.NoError(t, r.Write(testutil.MockMetrics()))
+}
@@ -11,3 +11,12 @@ var Outputs = map[string]Creator{}
 func Add(name string, creator Creator) {
 	Outputs[name] = creator
 }
+
+// Excluded are the outputs excluded from the build, mapped to the build tag
+// excluding them.
+var Excluded = map[string]string{}
+
+// AddExcluded records an output excluded from the build by the build tag.
+func AddExcluded(name, tag string) {
+	Excluded[name] = tag
+}
@@ -0,0 +1,225 @@
+package relay
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/relay"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// maxReplySize is the maximum size of the frames sent by the listener, its
+// error messages.
+const maxReplySize = 64 * 1024
+
+var sampleConfig = `
+  ## Address of the relay_listener input, with the tcp scheme, or tls to
+  ## connect with TLS.
+  address = "tcp://127.0.0.1:8095"
+
+  ## Token of the relay, among the tokens of the relay_listener input.
+  ## Sent in clear text unless the tls scheme is used.
+  # token = ""
+
+  ## Compression of the batches: "none", "gzip" or "snappy".
+  # compression = "snappy"
+
+  ## Timeout for connecting, and for sending a batch until it is
+  ## acknowledged.
+  # timeout = "10s"
+
+  ## Optional TLS Config, with the tls scheme.
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type Relay struct {
+	Address     string            `toml:"address"`
+	Token       string            `toml:"token"`
+	Compression string            `toml:"compression"`
+	Timeout     internal.Duration `toml:"timeout"`
+	tlsint.ClientConfig
+
+	serializer serializers.Serializer
+	network    string
+	host       string
+	tlsConfig  *tls.Config
+
+	conn   net.Conn
+	reader *bufio.Reader
+	seq    uint64
+}
+
+func (r *Relay) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Relay) Description() string {
+	return "Forward metrics to the relay_listener input of another Telegraf"
+}
+
+func (r *Relay) Connect() error {
+	u, err := url.Parse(r.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %s", r.Address, err)
+	}
+	if u.Scheme != "tcp" && u.Scheme != "tls" {
+		return fmt.Errorf("invalid address %q: the scheme must be tcp or tls", r.Address)
+	}
+	r.network = u.Scheme
+	r.host = u.Host
+	if u.Port() == "" {
+		r.host = net.JoinHostPort(u.Hostname(), relay.DefaultPort)
+	}
+	if err := relay.CheckCompression(r.Compression); err != nil {
+		return err
+	}
+
+	if r.network == "tls" {
+		tlsConfig, err := r.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		r.tlsConfig = tlsConfig
+	} else if r.Token != "" {
+		log.Printf("W! [outputs.relay] The token is sent in clear text to %s, use the tls scheme", r.Address)
+	}
+	r.serializer = influx.NewSerializer()
+
+	// The connection is retried by the writes, so that Telegraf starts
+	// while the listener is unreachable.
+	if err := r.connect(); err != nil {
+		log.Printf("W! [outputs.relay] %s", err)
+	}
+	return nil
+}
+
+// connect opens the connection and sends the hello frame, until the
+// listener welcomes it.
+func (r *Relay) connect() error {
+	dialer := &net.Dialer{Timeout: r.Timeout.Duration}
+	var conn net.Conn
+	var err error
+	if r.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.host, r.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", r.host)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %s", r.Address, err)
+	}
+
+	hello := &relay.Hello{
+		Version:     relay.Version,
+		Compression: r.Compression,
+		Token:       r.Token,
+	}
+	reader := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(r.Timeout.Duration))
+	err = relay.WriteFrame(conn, relay.Frame{Type: relay.FrameHello, Payload: hello.Marshal()})
+	if err == nil {
+		var f relay.Frame
+		f, err = relay.ReadFrame(reader, maxReplySize)
+		switch {
+		case err != nil:
+		case f.Type == relay.FrameError:
+			err = fmt.Errorf("rejected: %s", f.Payload)
+		case f.Type != relay.FrameWelcome:
+			err = fmt.Errorf("unexpected frame type %d", f.Type)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("connecting to %s: %s", r.Address, err)
+	}
+
+	r.conn = conn
+	r.reader = reader
+	return nil
+}
+
+// Write sends the metrics as a batch, and waits until it is acknowledged.
+// The batches rejected by the listener are dropped, since they would be
+// rejected again.
+func (r *Relay) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	if r.conn == nil {
+		if err := r.connect(); err != nil {
+			return err
+		}
+	}
+
+	data, err := r.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+	payload, err := relay.Compress(r.Compression, data)
+	if err != nil {
+		return err
+	}
+
+	r.seq++
+	r.conn.SetDeadline(time.Now().Add(r.Timeout.Duration))
+	if err := relay.WriteFrame(r.conn, relay.Frame{Type: relay.FrameBatch, Seq: r.seq, Payload: payload}); err != nil {
+		r.close()
+		return fmt.Errorf("sending to %s: %s", r.Address, err)
+	}
+	f, err := relay.ReadFrame(r.reader, maxReplySize)
+	switch {
+	case err != nil:
+	case f.Type == relay.FrameAck && f.Seq == r.seq:
+		return nil
+	case f.Type == relay.FrameError && f.Seq == r.seq:
+		log.Printf("E! [outputs.relay] Batch of %d metrics rejected by %s, dropping it: %s", len(metrics), r.Address, f.Payload)
+		return nil
+	case f.Type == relay.FrameError:
+		err = fmt.Errorf("%s", f.Payload)
+	default:
+		err = fmt.Errorf("unexpected frame type %d", f.Type)
+	}
+	r.close()
+	return fmt.Errorf("sending to %s: %s", r.Address, err)
+}
+
+func (r *Relay) close() {
+	r.conn.Close()
+	r.conn = nil
+	r.reader = nil
+}
+
+func (r *Relay) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	r.reader = nil
+	return err
+}
+
+func init() {
+	outputs.Add("relay", func() telegraf.Output {
+		return &Relay{
+			Compression: relay.CompressionSnappy,
+			Timeout:     internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package relay
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/relay"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var pki = testutil.NewPKI("../../../testutil/pki")
+
+// fakeListener accepts the relays, and replies to their frames with the
+// replies function.
+type fakeListener struct {
+	net.Listener
+	hellos  chan *relay.Hello
+	batches chan string
+	replies func(f relay.Frame) *relay.Frame
+}
+
+func newFakeListener(t *testing.T, l net.Listener) *fakeListener {
+	s := &fakeListener{
+		Listener: l,
+		hellos:   make(chan *relay.Hello, 10),
+		batches:  make(chan string, 10),
+		replies: func(f relay.Frame) *relay.Frame {
+			return &relay.Frame{Type: relay.FrameAck, Seq: f.Seq}
+		},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(t, conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeListener) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	f, err := relay.ReadFrame(r, 1024)
+	if err != nil {
+		return
+	}
+	hello, err := relay.ParseHello(f.Payload)
+	require.NoError(t, err)
+	s.hellos <- hello
+	if hello.Token != "secret" {
+		relay.WriteFrame(conn, relay.Frame{Type: relay.FrameError, Payload: []byte("invalid token")})
+		return
+	}
+	relay.WriteFrame(conn, relay.Frame{Type: relay.FrameWelcome})
+
+	for {
+		f, err := relay.ReadFrame(r, 1024*1024)
+		if err != nil {
+			return
+		}
+		data, err := relay.Decompress(hello.Compression, f.Payload, 1024*1024)
+		require.NoError(t, err)
+		s.batches <- string(data)
+		reply := s.replies(f)
+		if reply == nil {
+			return
+		}
+		relay.WriteFrame(conn, *reply)
+	}
+}
+
+func (s *fakeListener) next(t *testing.T) string {
+	select {
+	case batch := <-s.batches:
+		return batch
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no batch received")
+		return ""
+	}
+}
+
+func listen(t *testing.T) *fakeListener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return newFakeListener(t, l)
+}
+
+func newRelay(address string) *Relay {
+	return &Relay{
+		Address:     address,
+		Token:       "secret",
+		Compression: relay.CompressionSnappy,
+		Timeout:     internal.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func newMetrics(t *testing.T) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	for _, host := range []string{"a", "b"} {
+		m, err := metric.New("cpu", map[string]string{"host": host}, map[string]interface{}{"usage": 1.0}, time.Unix(1500000000, 0))
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+const batch = "cpu,host=a usage=1 1500000000000000000\ncpu,host=b usage=1 1500000000000000000\n"
+
+func TestWrite(t *testing.T) {
+	s := listen(t)
+	defer s.Close()
+
+	for _, compression := range []string{relay.CompressionNone, relay.CompressionGzip, relay.CompressionSnappy} {
+		r := newRelay("tcp://" + s.Addr().String())
+		r.Compression = compression
+		require.NoError(t, r.Connect())
+		require.Equal(t, &relay.Hello{Version: relay.Version, Compression: compression, Token: "secret"}, <-s.hellos)
+
+		require.NoError(t, r.Write(newMetrics(t)))
+		require.Equal(t, batch, s.next(t))
+		require.NoError(t, r.Write(newMetrics(t)))
+		s.next(t)
+		require.Equal(t, uint64(2), r.seq)
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestRejected(t *testing.T) {
+	s := listen(t)
+	defer s.Close()
+	s.replies = func(f relay.Frame) *relay.Frame {
+		return &relay.Frame{Type: relay.FrameError, Seq: f.Seq, Payload: []byte("unable to parse batch")}
+	}
+
+	// The batches rejected are dropped, on the same connection.
+	r := newRelay("tcp://" + s.Addr().String())
+	require.NoError(t, r.Connect())
+	defer r.Close()
+	require.NoError(t, r.Write(newMetrics(t)))
+	require.NoError(t, r.Write(newMetrics(t)))
+	require.NotNil(t, r.conn)
+
+	r = newRelay("tcp://" + s.Addr().String())
+	r.Token = "wrong"
+	require.NoError(t, r.Connect())
+	err := r.Write(newMetrics(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "rejected: invalid token")
+}
+
+func TestReconnect(t *testing.T) {
+	s := listen(t)
+	defer s.Close()
+	// The listener closes the connection without acknowledging the first
+	// batch, which is then sent again.
+	var replied int32
+	s.replies = func(f relay.Frame) *relay.Frame {
+		if atomic.CompareAndSwapInt32(&replied, 0, 1) {
+			return nil
+		}
+		return &relay.Frame{Type: relay.FrameAck, Seq: f.Seq}
+	}
+
+	r := newRelay("tcp://" + s.Addr().String())
+	require.NoError(t, r.Connect())
+	defer r.Close()
+	require.Error(t, r.Write(newMetrics(t)))
+	require.Nil(t, r.conn)
+	require.NoError(t, r.Write(newMetrics(t)))
+	require.Equal(t, batch, s.next(t))
+	require.Equal(t, batch, s.next(t))
+}
+
+func TestUnreachable(t *testing.T) {
+	s := listen(t)
+	address := "tcp://" + s.Addr().String()
+	s.Close()
+
+	// Connect succeeds for the writes to retry.
+	r := newRelay(address)
+	require.NoError(t, r.Connect())
+	err := r.Write(newMetrics(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "connecting to "+address)
+}
+
+func TestTLS(t *testing.T) {
+	tlsConfig, err := pki.TLSServerConfig().TLSConfig()
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := newFakeListener(t, tls.NewListener(l, tlsConfig))
+	defer s.Close()
+
+	_, port, err := net.SplitHostPort(s.Addr().String())
+	require.NoError(t, err)
+	r := newRelay("tls://localhost:" + port)
+	r.ClientConfig = *pki.TLSClientConfig()
+	require.NoError(t, r.Connect())
+	defer r.Close()
+	require.NoError(t, r.Write(newMetrics(t)))
+	require.Equal(t, batch, s.next(t))
+}
+
+func TestConfig(t *testing.T) {
+	r := newRelay("http://127.0.0.1:8095")
+	require.EqualError(t, r.Connect(), `invalid address "http://127.0.0.1:8095": the scheme must be tcp or tls`)
+
+	r = newRelay("tcp://127.0.0.1:8095")
+	r.Compression = "zstd"
+	require.EqualError(t, r.Connect(), `unknown compression "zstd"`)
+
+	// The port is the one of the relay_listener input by default.
+	r = newRelay("tcp://127.0.0.1")
+	r.Timeout.Duration = time.Millisecond
+	require.NoError(t, r.Connect())
+	require.Equal(t, "127.0.0.1:8095", r.host)
+}
@@ -0,0 +1,404 @@
+package snmp_trap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	// sysUpTimeOID and snmpTrapOID are the first two variables of the
+	// traps, as per RFC3416#section-4.2.6.
+	sysUpTimeOID   = ".1.3.6.1.2.1.1.3.0"
+	snmpTrapOIDOID = ".1.3.6.1.6.3.1.1.4.1.0"
+)
+
+var sampleConfig = `
+  ## Address of the trap receiver, with the port 162 by default.
+  address = "127.0.0.1:162"
+
+  ## Timeout for sending a trap.
+  # timeout = "5s"
+
+  ## SNMP version, values can be 2 or 3
+  # version = 2
+
+  ## SNMP community string.
+  # community = "public"
+
+  ## SNMPv3 auth parameters
+  #sec_name = "myuser"
+  #auth_protocol = "md5"      # Values: "MD5", "SHA", ""
+  #auth_password = "pass"
+  #sec_level = "authNoPriv"   # Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+  #context_name = ""
+  #priv_protocol = ""         # Values: "DES", "AES", ""
+  #priv_password = ""
+  ## Engine ID of Telegraf in hex, as the authoritative engine of the SNMPv3
+  ## traps, to configure for the user on the receiver.
+  #engine_id = "8000000001020304"
+
+  ## Traps sent for the metrics, the first one matching the measurement.  The
+  ## metrics without a matching trap, or without any of its fields, are not
+  ## sent.
+  [[outputs.snmp_trap.trap]]
+    ## Measurements of the metrics, with glob matching.  All metrics match
+    ## when empty.
+    measurements = ["disk"]
+    ## snmpTrapOID of the trap.
+    oid = ".1.3.6.1.4.1.99999.0.1"
+
+    ## Variables of the trap: the fields and tags of the metrics, by OID.
+    [outputs.snmp_trap.trap.fields]
+      used_percent = ".1.3.6.1.4.1.99999.1.1"
+    [outputs.snmp_trap.trap.tags]
+      host = ".1.3.6.1.4.1.99999.1.2"
+      path = ".1.3.6.1.4.1.99999.1.3"
+`
+
+// SnmpTrap sends metrics as SNMP traps.
+type SnmpTrap struct {
+	// The trap receiver. Format is ADDR[:PORT] (e.g. 1.2.3.4:162).
+	Address string
+	// Timeout to send a trap.
+	Timeout internal.Duration
+	// Values: 2, 3
+	Version uint8
+
+	// Parameters for Version 2
+	Community string
+
+	// Parameters for Version 3
+	ContextName string
+	// Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+	SecLevel string
+	SecName  string
+	// Values: "MD5", "SHA", "". Default: ""
+	AuthProtocol string
+	AuthPassword string
+	// Values: "DES", "AES", "". Default: ""
+	PrivProtocol string
+	PrivPassword string
+	// Hex encoded.
+	EngineID string `toml:"engine_id"`
+
+	Traps []Trap `toml:"trap"`
+
+	client   *gosnmp.GoSNMP
+	engineID string
+	start    time.Time
+	// filters are the compiled measurements of the traps.
+	filters []filter.Filter
+}
+
+// Trap maps metrics to the variables of a trap.
+type Trap struct {
+	Measurements []string
+	// snmpTrapOID of the trap.
+	Oid string
+	// Fields and tags of the metrics, by OID of the variables.
+	Fields map[string]string
+	Tags   map[string]string
+}
+
+func (s *SnmpTrap) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SnmpTrap) Description() string {
+	return "Send metrics as SNMP v2c or v3 traps"
+}
+
+func (s *SnmpTrap) Connect() error {
+	s.filters = make([]filter.Filter, len(s.Traps))
+	for i, t := range s.Traps {
+		if t.Oid == "" {
+			return fmt.Errorf("trap %d: oid is required", i+1)
+		}
+		f, err := filter.Compile(t.Measurements)
+		if err != nil {
+			return fmt.Errorf("trap %d: %s", i+1, err)
+		}
+		s.filters[i] = f
+	}
+
+	client, err := s.newClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("setting up connection: %s", err)
+	}
+	if client.Version == gosnmp.Version3 {
+		if err := localizeKeys(client, s.engineID); err != nil {
+			return err
+		}
+	}
+	// Connect marks the messages as reportable, which the traps are not
+	// as per RFC3412#section-7.1.
+	client.MsgFlags &^= gosnmp.Reportable
+	s.client = client
+	s.start = time.Now()
+	return nil
+}
+
+func (s *SnmpTrap) newClient() (*gosnmp.GoSNMP, error) {
+	client := &gosnmp.GoSNMP{Timeout: s.Timeout.Duration}
+
+	host, portStr, err := net.SplitHostPort(s.Address)
+	if err != nil {
+		if err, ok := err.(*net.AddrError); !ok || err.Err != "missing port in address" {
+			return nil, fmt.Errorf("parsing address: %s", err)
+		}
+		host = s.Address
+		portStr = "162"
+	}
+	client.Target = host
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("parsing port: %s", err)
+	}
+	client.Port = uint16(port)
+
+	switch s.Version {
+	case 2, 0:
+		client.Version = gosnmp.Version2c
+		client.Community = s.Community
+		if client.Community == "" {
+			client.Community = "public"
+		}
+		return client, nil
+	case 3:
+		client.Version = gosnmp.Version3
+	default:
+		return nil, fmt.Errorf("invalid version")
+	}
+
+	client.ContextName = s.ContextName
+	sp := &gosnmp.UsmSecurityParameters{
+		UserName:                 s.SecName,
+		AuthenticationPassphrase: s.AuthPassword,
+		PrivacyPassphrase:        s.PrivPassword,
+		AuthoritativeEngineBoots: 1,
+	}
+	client.SecurityParameters = sp
+	client.SecurityModel = gosnmp.UserSecurityModel
+
+	switch strings.ToLower(s.SecLevel) {
+	case "noauthnopriv", "":
+		client.MsgFlags = gosnmp.NoAuthNoPriv
+	case "authnopriv":
+		client.MsgFlags = gosnmp.AuthNoPriv
+	case "authpriv":
+		client.MsgFlags = gosnmp.AuthPriv
+	default:
+		return nil, fmt.Errorf("invalid secLevel")
+	}
+
+	switch strings.ToLower(s.AuthProtocol) {
+	case "md5":
+		sp.AuthenticationProtocol = gosnmp.MD5
+	case "sha":
+		sp.AuthenticationProtocol = gosnmp.SHA
+	case "":
+		sp.AuthenticationProtocol = gosnmp.NoAuth
+	default:
+		return nil, fmt.Errorf("invalid authProtocol")
+	}
+
+	switch strings.ToLower(s.PrivProtocol) {
+	case "des":
+		sp.PrivacyProtocol = gosnmp.DES
+	case "aes":
+		sp.PrivacyProtocol = gosnmp.AES
+	case "":
+		sp.PrivacyProtocol = gosnmp.NoPriv
+	default:
+		return nil, fmt.Errorf("invalid privProtocol")
+	}
+
+	// Telegraf is the authoritative engine of the traps it sends, so that
+	// there is no engine discovery.
+	if s.EngineID == "" {
+		return nil, fmt.Errorf("engine_id is required with version 3")
+	}
+	engineID, err := hex.DecodeString(s.EngineID)
+	if err != nil || len(engineID) < 5 || len(engineID) > 32 {
+		return nil, fmt.Errorf("invalid engine_id %q, expected 5 to 32 bytes in hex", s.EngineID)
+	}
+	s.engineID = string(engineID)
+	return client, nil
+}
+
+// localizeKeys sets the engine ID of the traps, with the keys of the user
+// localized to it.  gosnmp only localizes them to the engine IDs it parses,
+// such as on the discovery of the agents before the requests, so the engine
+// ID is parsed from a trap without authentication nor privacy, with the
+// parameters of the user.
+func localizeKeys(client *gosnmp.GoSNMP, engineID string) error {
+	logger := log.New(ioutil.Discard, "", 0)
+	conn := &recordingConn{}
+	sender := &gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.NoAuthNoPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			AuthoritativeEngineID:    engineID,
+			AuthoritativeEngineBoots: 1,
+			UserName:                 client.SecurityParameters.(*gosnmp.UsmSecurityParameters).UserName,
+			Logger:                   logger,
+		},
+		Conn:   conn,
+		Logger: logger,
+	}
+	trap := gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{{Name: sysUpTimeOID, Type: gosnmp.TimeTicks, Value: uint32(0)}},
+	}
+	if _, err := sender.SendTrap(trap); err != nil {
+		return fmt.Errorf("localizing the keys: %s", err)
+	}
+
+	parser := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           gosnmp.NoAuthNoPriv,
+		SecurityParameters: client.SecurityParameters,
+		Logger:             logger,
+	}
+	packet := parser.UnmarshalTrap(conn.packet)
+	if packet == nil {
+		return fmt.Errorf("localizing the keys: invalid trap")
+	}
+	client.SecurityParameters = packet.SecurityParameters
+	return nil
+}
+
+// recordingConn records the last packet written to it.
+type recordingConn struct {
+	net.Conn
+	packet []byte
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.packet = append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func (c *recordingConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (s *SnmpTrap) Close() error {
+	if s.client == nil || s.client.Conn == nil {
+		return nil
+	}
+	return s.client.Conn.Close()
+}
+
+// Write sends a trap for each metric matching one.
+func (s *SnmpTrap) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		variables := s.variables(m)
+		if variables == nil {
+			continue
+		}
+
+		uptime := time.Since(s.start)
+		if sp, ok := s.client.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok {
+			sp.AuthoritativeEngineTime = uint32(uptime / time.Second)
+		}
+		trap := gosnmp.SnmpTrap{
+			Variables: append([]gosnmp.SnmpPDU{
+				{Name: sysUpTimeOID, Type: gosnmp.TimeTicks, Value: uint32(uptime / (10 * time.Millisecond))},
+			}, variables...),
+		}
+		if _, err := s.client.SendTrap(trap); err != nil {
+			return fmt.Errorf("sending trap: %s", err)
+		}
+	}
+	return nil
+}
+
+// variables returns the snmpTrapOID and the variables of the trap of a
+// metric, or nil when none matches or has fields in the metric.
+func (s *SnmpTrap) variables(m telegraf.Metric) []gosnmp.SnmpPDU {
+	for i, t := range s.Traps {
+		if s.filters[i] != nil && !s.filters[i].Match(m.Name()) {
+			continue
+		}
+
+		var fields []gosnmp.SnmpPDU
+		for _, field := range sortedKeys(t.Fields) {
+			if value, ok := m.GetField(field); ok {
+				fields = append(fields, octetString(t.Fields[field], formatValue(value)))
+			}
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+
+		variables := []gosnmp.SnmpPDU{{Name: snmpTrapOIDOID, Type: gosnmp.ObjectIdentifier, Value: t.Oid}}
+		variables = append(variables, fields...)
+		for _, tag := range sortedKeys(t.Tags) {
+			if value, ok := m.GetTag(tag); ok {
+				variables = append(variables, octetString(t.Tags[tag], value))
+			}
+		}
+		return variables
+	}
+	return nil
+}
+
+func octetString(oid, value string) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: value}
+}
+
+// formatValue formats the values of the fields as text, the integer
+// encodings of gosnmp being limited to 16 bits.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	outputs.Add("snmp_trap", func() telegraf.Output {
+		return &SnmpTrap{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			Version: 2,
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package snmp_trap
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T, name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New(name, tags, fields, time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	return m
+}
+
+// listen returns a UDP socket receiving the traps, and a function returning
+// the next one.
+func listen(t *testing.T) (*net.UDPConn, func() []byte) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	return conn, func() []byte {
+		b := make([]byte, 4096)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(b)
+		require.NoError(t, err)
+		return b[:n]
+	}
+}
+
+// newReceiver returns the parameters decoding the traps.  The SNMPv3 traps
+// are authenticated with the keys of the user localized to the engine ID.
+func newReceiver(t *testing.T, params *gosnmp.GoSNMP, engineID string) *gosnmp.GoSNMP {
+	params.Target = "127.0.0.1"
+	params.Port = 1
+	require.NoError(t, params.Connect())
+	if params.Version == gosnmp.Version3 {
+		require.NoError(t, localizeKeys(params, engineID))
+	}
+	return params
+}
+
+func newSnmpTrap(address string) *SnmpTrap {
+	return &SnmpTrap{
+		Address: address,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		Version: 2,
+		Traps: []Trap{
+			{
+				Measurements: []string{"disk"},
+				Oid:          ".1.3.6.1.4.1.99999.0.1",
+				Fields:       map[string]string{"used_percent": ".1.3.6.1.4.1.99999.1.1", "inodes_used": ".1.3.6.1.4.1.99999.1.2"},
+				Tags:         map[string]string{"path": ".1.3.6.1.4.1.99999.1.3"},
+			},
+			{
+				Oid:    ".1.3.6.1.4.1.99999.0.2",
+				Fields: map[string]string{"up": ".1.3.6.1.4.1.99999.2.1"},
+			},
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	conn, next := listen(t)
+	defer conn.Close()
+	receiver := newReceiver(t, &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "private"}, "")
+
+	s := newSnmpTrap(conn.LocalAddr().String())
+	s.Community = "private"
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	metrics := []telegraf.Metric{
+		newMetric(t, "disk", map[string]string{"path": "/", "host": "a"}, map[string]interface{}{"used_percent": 91.5, "free": int64(3)}),
+		// No field of the disk trap, nor of the other one.
+		newMetric(t, "disk", nil, map[string]interface{}{"free": int64(3)}),
+		newMetric(t, "service", nil, map[string]interface{}{"up": true}),
+	}
+	require.NoError(t, s.Write(metrics))
+
+	trap := receiver.UnmarshalTrap(next())
+	require.NotNil(t, trap)
+	require.Equal(t, gosnmp.Version2c, trap.Version)
+	require.Equal(t, "private", trap.Community)
+	require.Equal(t, gosnmp.SNMPv2Trap, trap.PDUType)
+	require.Len(t, trap.Variables, 4)
+	require.Equal(t, sysUpTimeOID, trap.Variables[0].Name)
+	require.Equal(t, gosnmp.Asn1BER(gosnmp.TimeTicks), trap.Variables[0].Type)
+	require.Equal(t, snmpTrapOIDOID, trap.Variables[1].Name)
+	require.Equal(t, ".1.3.6.1.4.1.99999.0.1", trap.Variables[1].Value)
+	require.Equal(t, ".1.3.6.1.4.1.99999.1.1", trap.Variables[2].Name)
+	require.Equal(t, []byte("91.5"), trap.Variables[2].Value)
+	require.Equal(t, ".1.3.6.1.4.1.99999.1.3", trap.Variables[3].Name)
+	require.Equal(t, []byte("/"), trap.Variables[3].Value)
+
+	trap = receiver.UnmarshalTrap(next())
+	require.NotNil(t, trap)
+	require.Len(t, trap.Variables, 3)
+	require.Equal(t, ".1.3.6.1.4.1.99999.0.2", trap.Variables[1].Value)
+	require.Equal(t, []byte("true"), trap.Variables[2].Value)
+}
+
+func TestWriteV3(t *testing.T) {
+	engineID := "\x80\x00\x00\x00\x01\x02\x03\x04"
+	conn, next := listen(t)
+	defer conn.Close()
+	user := func(authPassword string) *gosnmp.GoSNMP {
+		return &gosnmp.GoSNMP{
+			Version:       gosnmp.Version3,
+			SecurityModel: gosnmp.UserSecurityModel,
+			MsgFlags:      gosnmp.AuthPriv,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{
+				UserName:                 "telegraf",
+				AuthenticationProtocol:   gosnmp.SHA,
+				AuthenticationPassphrase: authPassword,
+				PrivacyProtocol:          gosnmp.AES,
+				PrivacyPassphrase:        "privpassword",
+			},
+		}
+	}
+	receiver := newReceiver(t, user("authpassword"), engineID)
+
+	s := newSnmpTrap(conn.LocalAddr().String())
+	s.Version = 3
+	s.SecLevel = "authPriv"
+	s.SecName = "telegraf"
+	s.AuthProtocol = "SHA"
+	s.AuthPassword = "authpassword"
+	s.PrivProtocol = "AES"
+	s.PrivPassword = "privpassword"
+	s.EngineID = "8000000001020304"
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Write([]telegraf.Metric{newMetric(t, "service", nil, map[string]interface{}{"up": false})}))
+	packet := next()
+	trap := receiver.UnmarshalTrap(packet)
+	require.NotNil(t, trap)
+	require.Equal(t, gosnmp.Version3, trap.Version)
+	require.Equal(t, gosnmp.AuthPriv, trap.MsgFlags)
+	sp := trap.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	require.Equal(t, engineID, sp.AuthoritativeEngineID)
+	require.Equal(t, "telegraf", sp.UserName)
+	require.Equal(t, uint32(1), sp.AuthoritativeEngineBoots)
+	require.Len(t, trap.Variables, 3)
+	require.Equal(t, []byte("false"), trap.Variables[2].Value)
+
+	// The traps are discarded with another password.
+	require.Nil(t, newReceiver(t, user("otherpassword"), engineID).UnmarshalTrap(packet))
+}
+
+func TestConfig(t *testing.T) {
+	s := newSnmpTrap("127.0.0.1:162")
+	s.Version = 1
+	require.EqualError(t, s.Connect(), "invalid version")
+
+	s.Version = 3
+	require.EqualError(t, s.Connect(), "engine_id is required with version 3")
+	s.EngineID = "80"
+	require.EqualError(t, s.Connect(), `invalid engine_id "80", expected 5 to 32 bytes in hex`)
+
+	s = newSnmpTrap("127.0.0.1:162")
+	s.Traps[0].Oid = ""
+	require.EqualError(t, s.Connect(), "trap 1: oid is required")
+
+	client, err := newSnmpTrap("localhost").newClient()
+	require.NoError(t, err)
+	require.Equal(t, uint16(162), client.Port)
+	require.Equal(t, "public", client.Community)
+}
@@ -3,12 +3,15 @@ package socket_writer
 import (
 	"bufio"
 	"bytes"
+	"io"
 	"net"
+	"net/http"
 	"os"
 	"sync"
 	"testing"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/proxy"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,6 +33,54 @@ func TestSocketWriter_tcp(t *testing.T) {
 	testSocketWriter_stream(t, sw, lconn)
 }
 
+func TestSocketWriter_proxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	// An HTTP proxy tunneling a connection with the CONNECT method.
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer proxyListener.Close()
+	go func() {
+		conn, err := proxyListener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || req.Method != "CONNECT" {
+			return
+		}
+		target, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			return
+		}
+		defer target.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(conn, target)
+		io.Copy(target, conn)
+	}()
+
+	sw := newSocketWriter()
+	sw.Address = "tcp://" + listener.Addr().String()
+	sw.ProxyConfig = proxy.ProxyConfig{ProxyURL: "http://" + proxyListener.Addr().String()}
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+
+	testSocketWriter_stream(t, sw, lconn)
+}
+
+func TestSocketWriter_proxyUDP(t *testing.T) {
+	sw := newSocketWriter()
+	sw.Address = "udp://127.0.0.1:8094"
+	sw.ProxyConfig = proxy.ProxyConfig{ProxyURL: "socks5://127.0.0.1:1080"}
+	require.EqualError(t, sw.Connect(), "proxy_url only applies to tcp, not udp")
+}
+
 func TestSocketWriter_udp(t *testing.T) {
 	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
 	require.NoError(t, err)
@@ -0,0 +1,187 @@
+package syslog
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/go-syslog/rfc5424"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/proxy"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2018, time.June, 15, 12, 0, 0, 500000000, time.UTC)
+
+func newMetric(name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New(name, tags, fields, testTime)
+	return m
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric telegraf.Metric
+		sdids  []string
+		want   string
+	}{
+		{
+			name: "metric",
+			metric: newMetric("cpu",
+				map[string]string{"host": "server01", "cpu": "cpu0"},
+				map[string]interface{}{"usage_idle": 98.5, "cores": int64(4)}),
+			want: `<13>1 2018-06-15T12:00:00.500000Z server01 Telegraf - cpu [default cores="4" cpu="cpu0" usage_idle="98.5"]`,
+		},
+		{
+			name: "syslog metric",
+			metric: newMetric("syslog",
+				map[string]string{
+					"severity": "crit",
+					"facility": "auth",
+					"hostname": "mymachine",
+					"appname":  "su",
+				},
+				map[string]interface{}{
+					"version":       uint16(1),
+					"severity_code": 2,
+					"facility_code": 4,
+					"timestamp":     time.Date(2018, time.June, 15, 11, 0, 0, 0, time.UTC).UnixNano(),
+					"procid":        "1234",
+					"msgid":         "ID47",
+					"message":       "'su root' failed",
+					"origin":        true,
+					"origin_ip":     "192.0.2.1",
+					"meta":          true,
+				}),
+			sdids: []string{"origin", "meta"},
+			want:  `<34>1 2018-06-15T11:00:00.000000Z mymachine su 1234 ID47 [origin ip="192.0.2.1"][meta] 'su root' failed`,
+		},
+		{
+			name: "defaults",
+			metric: newMetric("event",
+				map[string]string{},
+				map[string]interface{}{"severity_code": int64(9), "message": "hello"}),
+			want: `<13>1 2018-06-15T12:00:00.500000Z - Telegraf - event - hello`,
+		},
+		{
+			name: "escaping",
+			metric: newMetric("my event",
+				map[string]string{"hostname": "my host", "path": `C:\"a"]`},
+				map[string]interface{}{"a=b": "c"}),
+			want: `<13>1 2018-06-15T12:00:00.500000Z my_host Telegraf - my_event [default a_b="c" path="C:\\\"a\"\]"]`,
+		},
+		{
+			name: "default element",
+			metric: newMetric("event",
+				map[string]string{"dc": "east"},
+				map[string]interface{}{"origin_ip": "192.0.2.1", "meta_id": int64(1)}),
+			sdids: []string{"origin", "default"},
+			want:  `<13>1 2018-06-15T12:00:00.500000Z - Telegraf - event [origin ip="192.0.2.1"][default dc="east" meta_id="1"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSyslog()
+			s.SDIDs = tt.sdids
+			msg := s.message(tt.metric)
+			require.Equal(t, tt.want, string(msg))
+
+			_, err := rfc5424.NewParser().Parse(msg, nil)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestMessageCodes(t *testing.T) {
+	s := NewSyslog()
+	s.DefaultSeverityCode = 6
+	s.DefaultFacilityCode = 16
+	m := newMetric("event", map[string]string{}, map[string]interface{}{"facility_code": uint64(3)})
+	require.Equal(t, "<30>", string(s.message(m)[:4]))
+}
+
+func TestFrame(t *testing.T) {
+	s := NewSyslog()
+	s.trailer = []byte{'\n'}
+	require.Equal(t, "msg", string(s.frame([]byte("msg"))))
+
+	s.stream = true
+	require.Equal(t, "3 msg", string(s.frame([]byte("msg"))))
+
+	s.Framing = framingNonTransparent
+	require.Equal(t, "msg\n", string(s.frame([]byte("msg"))))
+}
+
+func TestSyslog_tcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := NewSyslog()
+	s.Address = "tcp://" + listener.Addr().String()
+	s.Framing = framingNonTransparent
+	s.Trailer = "NUL"
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	m1 := newMetric("event", map[string]string{}, map[string]interface{}{"message": "first"})
+	m2 := newMetric("event", map[string]string{}, map[string]interface{}{"message": "second"})
+	require.NoError(t, s.Write([]telegraf.Metric{m1, m2}))
+
+	r := bufio.NewReader(conn)
+	msg, err := r.ReadString(0)
+	require.NoError(t, err)
+	require.Equal(t, "<13>1 2018-06-15T12:00:00.500000Z - Telegraf - event - first\x00", msg)
+	msg, err = r.ReadString(0)
+	require.NoError(t, err)
+	require.Equal(t, "<13>1 2018-06-15T12:00:00.500000Z - Telegraf - event - second\x00", msg)
+}
+
+func TestSyslog_udp(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := NewSyslog()
+	s.Address = "udp://" + listener.LocalAddr().String()
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	m := newMetric("event", map[string]string{}, map[string]interface{}{"message": "hello"})
+	require.NoError(t, s.Write([]telegraf.Metric{m}))
+
+	buf := make([]byte, 256)
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, "<13>1 2018-06-15T12:00:00.500000Z - Telegraf - event - hello", string(buf[:n]))
+}
+
+func TestConnectErrors(t *testing.T) {
+	tests := []struct {
+		syslog *Syslog
+		err    string
+	}{
+		{&Syslog{Address: "127.0.0.1:6514"}, "invalid address: 127.0.0.1:6514"},
+		{&Syslog{Address: "http://127.0.0.1:6514"}, "unknown protocol 'http' in 'http://127.0.0.1:6514'"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Framing: "newline"}, `unknown framing "newline"`},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Framing: framingOctetCounting, Trailer: "CR"}, `unknown trailer "CR"`},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Framing: framingOctetCounting, DefaultSeverityCode: 8},
+			"invalid default priority, the severity code must be in 0-7 and the facility code in 0-23"},
+		{&Syslog{Address: "udp://127.0.0.1:514", Framing: framingOctetCounting, ProxyConfig: proxy.ProxyConfig{ProxyURL: "socks5://127.0.0.1:1080"}},
+			"proxy_url only applies to tcp, not udp"},
+		{&Syslog{Address: "tcp://127.0.0.1:6514", Framing: framingOctetCounting, ProxyConfig: proxy.ProxyConfig{ProxyURL: "https://127.0.0.1:3128"}},
+			"unknown protocol 'https' in proxy_url, socks5 or http"},
+	}
+	for _, tt := range tests {
+		require.EqualError(t, tt.syslog.Connect(), tt.err)
+	}
+
+	s := &Syslog{Address: "unix:///tmp/syslog.sock", Framing: framingOctetCounting, ProxyConfig: proxy.ProxyConfig{ProxyURL: "socks5://127.0.0.1:1080"}}
+	require.EqualError(t, s.Validate(), "proxy_url only applies to tcp")
+}
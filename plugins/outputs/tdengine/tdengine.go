@@ -0,0 +1,631 @@
+package tdengine
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	defaultBatchSize    = 1000
+	defaultStringLength = 256
+
+	// timestampColumn is the first column of the super tables.
+	timestampColumn = "ts"
+	// nullTag is the tag of the super tables of the measurements without
+	// tags, a super table having at least one tag.  It is the one of the
+	// schemaless writes of TDengine.
+	nullTag = "_tag_null"
+)
+
+// precisions are the units of the timestamps of the precisions of the
+// databases.
+var precisions = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+var sampleConfig = `
+  ## URL of the REST interface of TDengine, of taosAdapter since TDengine 2.4
+  ## or of taosd before.
+  url = "http://localhost:6041"
+
+  ## Database of the tables, which must exist.
+  database = "telegraf"
+
+  ## Credentials of the TDengine user.
+  # username = "root"
+  # password = "taosdata"
+
+  ## Precision of the timestamps of the database, "ms", "us" or "ns".
+  # precision = "ms"
+
+  ## Create the super tables of the measurements which do not exist, and add
+  ## the columns and tags of the new fields and tags.
+  # table_create = true
+
+  ## Length of the NCHAR columns and tags created for the string fields and
+  ## the tags.  The longer values are truncated.
+  # string_length = 256
+
+  ## Number of rows inserted by an INSERT statement.
+  # batch_size = 1000
+
+  ## Timeout for the HTTP requests.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type TDengine struct {
+	URL          string
+	Database     string
+	Username     string
+	Password     string
+	Precision    string
+	TableCreate  bool `toml:"table_create"`
+	StringLength int  `toml:"string_length"`
+	BatchSize    int  `toml:"batch_size"`
+	Timeout      internal.Duration
+	tls.ClientConfig
+
+	client *http.Client
+	unit   time.Duration
+	// stables are the columns and tags of the super tables written to.
+	stables map[string]*stable
+}
+
+// stable is the schema of a super table, the types of its columns and tags
+// by name.
+type stable struct {
+	columns map[string]columnType
+	tags    map[string]columnType
+}
+
+// columnType is the type of a column or tag, with its length for the NCHAR
+// and BINARY types.
+type columnType struct {
+	name   string
+	length int
+}
+
+func (t columnType) String() string {
+	if t.length > 0 {
+		return t.name + "(" + strconv.Itoa(t.length) + ")"
+	}
+	return t.name
+}
+
+// row is a row of a sub table, named after the measurement and the tags.
+type row struct {
+	table string
+	tags  map[string]string
+	time  time.Time
+	// values are the fields of the metric, without those named like the
+	// timestamp column or a tag.
+	values map[string]interface{}
+}
+
+func (td *TDengine) SampleConfig() string {
+	return sampleConfig
+}
+
+func (td *TDengine) Description() string {
+	return "Write metrics to TDengine over its REST interface"
+}
+
+func (td *TDengine) Connect() error {
+	if td.Database == "" {
+		return fmt.Errorf("database is required")
+	}
+	if td.Precision == "" {
+		td.Precision = "ms"
+	}
+	unit, ok := precisions[td.Precision]
+	if !ok {
+		return fmt.Errorf("unknown precision %q", td.Precision)
+	}
+	td.unit = unit
+	if td.StringLength <= 0 {
+		td.StringLength = defaultStringLength
+	}
+	if td.BatchSize <= 0 {
+		td.BatchSize = defaultBatchSize
+	}
+
+	tlsCfg, err := td.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	td.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: td.Timeout.Duration,
+	}
+	td.stables = make(map[string]*stable)
+	return nil
+}
+
+func (td *TDengine) Close() error {
+	return nil
+}
+
+// Write inserts the metrics into the sub tables of the super table of their
+// measurement.
+func (td *TDengine) Write(metrics []telegraf.Metric) error {
+	var names []string
+	byStable := make(map[string][]row)
+	for _, m := range metrics {
+		if _, ok := byStable[m.Name()]; !ok {
+			names = append(names, m.Name())
+		}
+		byStable[m.Name()] = append(byStable[m.Name()], newRow(m))
+	}
+
+	for _, name := range names {
+		if err := td.writeStable(name, byStable[name]); err != nil {
+			// The super table is described again, in case it was changed.
+			delete(td.stables, name)
+			return fmt.Errorf("writing to super table %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func newRow(m telegraf.Metric) row {
+	r := row{
+		tags:   m.Tags(),
+		time:   m.Time(),
+		values: make(map[string]interface{}),
+	}
+	for _, field := range m.FieldList() {
+		if _, ok := r.tags[field.Key]; ok || field.Key == timestampColumn {
+			continue
+		}
+		r.values[field.Key] = field.Value
+	}
+	r.table = subTable(m.Name(), r.tags)
+	return r
+}
+
+// subTable returns the name of the sub table of a measurement and tags,
+// with the hash of the tags.
+func subTable(measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := md5.New()
+	h.Write([]byte(measurement))
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(tags[k]))
+	}
+	// The names of the tables are limited to 192 characters.
+	if len(measurement) > 150 {
+		measurement = measurement[:150]
+	}
+	return measurement + "_" + hex.EncodeToString(h.Sum(nil))
+}
+
+func (td *TDengine) writeStable(name string, rows []row) error {
+	columns := make(map[string]columnType)
+	tags := make(map[string]columnType)
+	for _, r := range rows {
+		for k, v := range r.values {
+			if typ, ok := td.fieldType(v); ok {
+				if _, ok := columns[k]; !ok {
+					columns[k] = typ
+				}
+			}
+		}
+		for k := range r.tags {
+			tags[k] = columnType{"NCHAR", td.StringLength}
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	if len(tags) == 0 {
+		tags[nullTag] = columnType{name: "BOOL"}
+	}
+
+	st, err := td.stable(name, columns, tags)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(rows); start += td.BatchSize {
+		end := start + td.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if _, err := td.query(td.insert(name, st, rows[start:end])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (td *TDengine) fieldType(v interface{}) (columnType, bool) {
+	switch v.(type) {
+	case float64:
+		return columnType{name: "DOUBLE"}, true
+	case int64:
+		return columnType{name: "BIGINT"}, true
+	case uint64:
+		return columnType{name: "BIGINT UNSIGNED"}, true
+	case bool:
+		return columnType{name: "BOOL"}, true
+	case string:
+		return columnType{"NCHAR", td.StringLength}, true
+	}
+	return columnType{}, false
+}
+
+// stable returns the schema of a super table, after creating the super table
+// or adding the columns and tags missing when table_create is set.
+func (td *TDengine) stable(name string, columns, tags map[string]columnType) (*stable, error) {
+	st, ok := td.stables[name]
+	if !ok {
+		var err error
+		st, err = td.describe(name)
+		if err != nil {
+			if !td.TableCreate {
+				return nil, err
+			}
+			if _, err := td.query(createStable(td.qualify(name), columns, tags)); err != nil {
+				return nil, err
+			}
+			st = &stable{columns: columns, tags: tags}
+		}
+	}
+
+	if td.TableCreate {
+		for _, add := range []struct {
+			kind    string
+			wanted  map[string]columnType
+			current map[string]columnType
+		}{
+			{"COLUMN", columns, st.columns},
+			{"TAG", tags, st.tags},
+		} {
+			for _, k := range sortedKeys(add.wanted) {
+				if _, ok := st.columns[k]; ok {
+					continue
+				}
+				if _, ok := st.tags[k]; ok || k == nullTag {
+					continue
+				}
+				query := "ALTER STABLE " + td.qualify(name) + " ADD " + add.kind + " " + quoteIdentifier(k) + " " + add.wanted[k].String()
+				if _, err := td.query(query); err != nil {
+					return nil, err
+				}
+				add.current[k] = add.wanted[k]
+			}
+		}
+	}
+	td.stables[name] = st
+	return st, nil
+}
+
+// describe returns the schema of a super table, or an error if it does not
+// exist.
+func (td *TDengine) describe(name string) (*stable, error) {
+	data, err := td.query("DESCRIBE " + td.qualify(name))
+	if err != nil {
+		return nil, err
+	}
+	st := &stable{
+		columns: make(map[string]columnType),
+		tags:    make(map[string]columnType),
+	}
+	for _, values := range data {
+		// The rows are the name, type, length and note of the columns
+		// and tags.
+		if len(values) < 4 {
+			return nil, fmt.Errorf("unexpected description %v", values)
+		}
+		name, _ := values[0].(string)
+		typ := columnType{name: strings.ToUpper(fmt.Sprint(values[1]))}
+		if typ.name == "NCHAR" || typ.name == "BINARY" || typ.name == "VARCHAR" {
+			length, _ := values[2].(float64)
+			typ.length = int(length)
+		}
+		if note, _ := values[3].(string); note == "TAG" {
+			st.tags[name] = typ
+		} else if name != timestampColumn {
+			st.columns[name] = typ
+		}
+	}
+	return st, nil
+}
+
+// insert returns the INSERT statement of rows, creating their sub tables
+// as needed.
+func (td *TDengine) insert(name string, st *stable, rows []row) string {
+	var tables []string
+	byTable := make(map[string][]row)
+	for _, r := range rows {
+		if _, ok := byTable[r.table]; !ok {
+			tables = append(tables, r.table)
+		}
+		byTable[r.table] = append(byTable[r.table], r)
+	}
+
+	tagNames := sortedKeys(st.tags)
+	var b bytes.Buffer
+	b.WriteString("INSERT INTO")
+	for _, table := range tables {
+		rows := byTable[table]
+
+		var tagValues []string
+		for _, k := range tagNames {
+			if k == nullTag {
+				tagValues = append(tagValues, "true")
+				continue
+			}
+			v, ok := rows[0].tags[k]
+			if !ok {
+				tagValues = append(tagValues, "NULL")
+				continue
+			}
+			tagValues = append(tagValues, formatValue(v, st.tags[k]))
+		}
+
+		columnSet := make(map[string]bool)
+		for _, r := range rows {
+			for k := range r.values {
+				if _, ok := st.columns[k]; ok {
+					columnSet[k] = true
+				}
+			}
+		}
+		var columnNames []string
+		for k := range columnSet {
+			columnNames = append(columnNames, k)
+		}
+		sort.Strings(columnNames)
+
+		b.WriteString(" " + td.qualify(table) + " USING " + td.qualify(name))
+		b.WriteString(" (" + quoteIdentifiers(tagNames) + ") TAGS (" + strings.Join(tagValues, ", ") + ")")
+		b.WriteString(" (" + quoteIdentifiers(append([]string{timestampColumn}, columnNames...)) + ") VALUES")
+		for _, r := range rows {
+			values := []string{strconv.FormatInt(r.time.UnixNano()/int64(td.unit), 10)}
+			for _, k := range columnNames {
+				v, ok := r.values[k]
+				if !ok {
+					values = append(values, "NULL")
+					continue
+				}
+				values = append(values, formatValue(v, st.columns[k]))
+			}
+			b.WriteString(" (" + strings.Join(values, ", ") + ")")
+		}
+	}
+	return b.String()
+}
+
+// query runs a statement and returns the rows of its result.  The responses
+// of TDengine 2.x have a status, those of TDengine 3.x a code.
+func (td *TDengine) query(query string) ([][]interface{}, error) {
+	req, err := http.NewRequest("POST", strings.TrimSuffix(td.URL, "/")+"/rest/sql", strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	if td.Username != "" || td.Password != "" {
+		req.SetBasicAuth(td.Username, td.Password)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := td.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Status string          `json:"status"`
+		Code   int             `json:"code"`
+		Desc   string          `json:"desc"`
+		Data   [][]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+		}
+		return nil, fmt.Errorf("invalid response: %s", err)
+	}
+	if result.Status == "error" || result.Code != 0 {
+		return nil, fmt.Errorf("code %d: %s", result.Code, result.Desc)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return result.Data, nil
+}
+
+func (td *TDengine) qualify(table string) string {
+	return quoteIdentifier(td.Database) + "." + quoteIdentifier(table)
+}
+
+func createStable(name string, columns, tags map[string]columnType) string {
+	defs := []string{quoteIdentifier(timestampColumn) + " TIMESTAMP"}
+	for _, k := range sortedKeys(columns) {
+		defs = append(defs, quoteIdentifier(k)+" "+columns[k].String())
+	}
+	var tagDefs []string
+	for _, k := range sortedKeys(tags) {
+		tagDefs = append(tagDefs, quoteIdentifier(k)+" "+tags[k].String())
+	}
+	return "CREATE STABLE IF NOT EXISTS " + name + " (" + strings.Join(defs, ", ") + ") TAGS (" + strings.Join(tagDefs, ", ") + ")"
+}
+
+// formatValue returns the SQL literal of a value for a column, NULL when it
+// cannot be converted to the type of the column.
+func formatValue(v interface{}, typ columnType) string {
+	switch typ.name {
+	case "NCHAR", "BINARY", "VARCHAR":
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case float64:
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case uint64:
+			s = strconv.FormatUint(v, 10)
+		case bool:
+			s = strconv.FormatBool(v)
+		default:
+			return "NULL"
+		}
+		return quoteString(truncate(s, typ))
+	case "BOOL":
+		switch v := v.(type) {
+		case bool:
+			return strconv.FormatBool(v)
+		case string:
+			return "NULL"
+		}
+		if f, ok := toFloat(v); ok {
+			return strconv.FormatBool(f != 0)
+		}
+	case "FLOAT", "DOUBLE":
+		if f, ok := toFloat(v); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	default:
+		switch v := v.(type) {
+		case int64:
+			if strings.HasSuffix(typ.name, "UNSIGNED") && v < 0 {
+				return "NULL"
+			}
+			return strconv.FormatInt(v, 10)
+		case uint64:
+			if !strings.HasSuffix(typ.name, "UNSIGNED") && v > math.MaxInt64 {
+				return "NULL"
+			}
+			return strconv.FormatUint(v, 10)
+		case bool:
+			if v {
+				return "1"
+			}
+			return "0"
+		case float64:
+			if v >= math.MinInt64 && v < math.MaxInt64 && !(strings.HasSuffix(typ.name, "UNSIGNED") && v < 0) {
+				return strconv.FormatInt(int64(v), 10)
+			}
+		}
+	}
+	return "NULL"
+}
+
+// truncate truncates a string to the length of its column, in characters for
+// NCHAR and in bytes for BINARY.
+func truncate(s string, typ columnType) string {
+	if typ.length <= 0 {
+		return s
+	}
+	if typ.name != "NCHAR" {
+		if len(s) > typ.length {
+			// Not to cut a character.
+			n := typ.length
+			for n > 0 && !utf8.RuneStart(s[n]) {
+				n--
+			}
+			s = s[:n]
+		}
+		return s
+	}
+	if runes := []rune(s); len(runes) > typ.length {
+		return string(runes[:typ.length])
+	}
+	return s
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// quoteIdentifier quotes a name with backquotes, as of TDengine 2.4, the
+// backquotes of the name being replaced by underscores.
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "_", -1) + "`"
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
+
+func sortedKeys(m map[string]columnType) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	outputs.Add("tdengine", func() telegraf.Output {
+		return &TDengine{
+			Username:    "root",
+			Password:    "taosdata",
+			TableCreate: true,
+			Timeout:     internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
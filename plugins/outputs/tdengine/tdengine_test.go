@@ -0,0 +1,188 @@
+package tdengine
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+var ts = time.Unix(1500000000, 123456789)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		typ      columnType
+		expected string
+	}{
+		{1.5, columnType{name: "DOUBLE"}, "1.5"},
+		{int64(-2), columnType{name: "DOUBLE"}, "-2"},
+		{"text", columnType{name: "DOUBLE"}, "NULL"},
+		{int64(-2), columnType{name: "BIGINT"}, "-2"},
+		{2.7, columnType{name: "BIGINT"}, "2"},
+		{int64(-2), columnType{name: "BIGINT UNSIGNED"}, "NULL"},
+		{uint64(1) << 63, columnType{name: "BIGINT"}, "NULL"},
+		{uint64(1) << 63, columnType{name: "BIGINT UNSIGNED"}, "9223372036854775808"},
+		{true, columnType{name: "INT"}, "1"},
+		{true, columnType{name: "BOOL"}, "true"},
+		{0.0, columnType{name: "BOOL"}, "false"},
+		{"it's", columnType{"NCHAR", 10}, `'it\'s'`},
+		{`a\b`, columnType{"NCHAR", 10}, `'a\\b'`},
+		{"日本語テキスト", columnType{"NCHAR", 3}, "'日本語'"},
+		{"日本語", columnType{"BINARY", 4}, "'日'"},
+		{int64(42), columnType{"NCHAR", 10}, "'42'"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, formatValue(tt.value, tt.typ), "%v %s", tt.value, tt.typ)
+	}
+}
+
+func TestSubTable(t *testing.T) {
+	a := subTable("cpu", map[string]string{"host": "a", "cpu": "cpu0"})
+	require.True(t, strings.HasPrefix(a, "cpu_"))
+	require.Len(t, a, len("cpu_")+32)
+	require.Equal(t, a, subTable("cpu", map[string]string{"cpu": "cpu0", "host": "a"}))
+	require.NotEqual(t, a, subTable("cpu", map[string]string{"host": "a", "cpu": "cpu1"}))
+	require.NotEqual(t, a, subTable("cpu", map[string]string{"host": "acpu", "": "cpu0"}))
+	require.Len(t, subTable(strings.Repeat("m", 200), nil), 150+1+32)
+}
+
+func TestCreateStable(t *testing.T) {
+	query := createStable("`telegraf`.`cpu`",
+		map[string]columnType{"usage": {name: "DOUBLE"}, "state": {"NCHAR", 64}},
+		map[string]columnType{"host": {"NCHAR", 64}},
+	)
+	require.Equal(t, "CREATE STABLE IF NOT EXISTS `telegraf`.`cpu` (`ts` TIMESTAMP, `state` NCHAR(64), `usage` DOUBLE) TAGS (`host` NCHAR(64))", query)
+}
+
+// fakeServer is a REST interface of TDengine where the cpu super table
+// exists, with the responses of TDengine 3.x.
+type fakeServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	queries []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if r.URL.Path != "/rest/sql" || user != "root" || password != "taosdata" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		query := string(body)
+		s.mu.Lock()
+		s.queries = append(s.queries, query)
+		s.mu.Unlock()
+
+		switch {
+		case query == "DESCRIBE `telegraf`.`cpu`":
+			w.Write([]byte(`{"code":0,"column_meta":[["field","VARCHAR",64],["type","VARCHAR",32],["length","INT",4],["note","VARCHAR",8]],"data":[` +
+				`["ts","TIMESTAMP",8,""],["usage","DOUBLE",8,""],["state","NCHAR",4,""],["host","NCHAR",16,"TAG"]],"rows":4}`))
+		case strings.HasPrefix(query, "DESCRIBE"):
+			w.Write([]byte(`{"code":9826,"desc":"Table does not exist"}`))
+		case strings.Contains(query, "`invalid`"):
+			w.Write([]byte(`{"code":9730,"desc":"Invalid value"}`))
+		default:
+			w.Write([]byte(`{"code":0,"column_meta":[["affected_rows","INT",4]],"data":[[1]],"rows":1}`))
+		}
+	}))
+	return s
+}
+
+func newMetric(t *testing.T, name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New(name, tags, fields, ts)
+	require.NoError(t, err)
+	return m
+}
+
+func TestWrite(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	td := &TDengine{
+		URL:         s.URL,
+		Database:    "telegraf",
+		Username:    "root",
+		Password:    "taosdata",
+		TableCreate: true,
+		BatchSize:   2,
+	}
+	require.NoError(t, td.Connect())
+
+	a := subTable("cpu", map[string]string{"host": "a"})
+	b := subTable("cpu", map[string]string{"host": "b", "cpu": "cpu0"})
+	require.NoError(t, td.Write([]telegraf.Metric{
+		newMetric(t, "cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1.5, "state": "running"}),
+		newMetric(t, "cpu", map[string]string{"host": "b", "cpu": "cpu0"}, map[string]interface{}{"usage": int64(2), "host": "x"}),
+		newMetric(t, "cpu", map[string]string{"host": "a"}, map[string]interface{}{"threads": int64(4)}),
+	}))
+
+	require.Equal(t, []string{
+		"DESCRIBE `telegraf`.`cpu`",
+		"ALTER STABLE `telegraf`.`cpu` ADD COLUMN `threads` BIGINT",
+		"ALTER STABLE `telegraf`.`cpu` ADD TAG `cpu` NCHAR(256)",
+		"INSERT INTO `telegraf`.`" + a + "` USING `telegraf`.`cpu` (`cpu`, `host`) TAGS (NULL, 'a') (`ts`, `state`, `usage`) VALUES (1500000000123, 'runn', 1.5)" +
+			" `telegraf`.`" + b + "` USING `telegraf`.`cpu` (`cpu`, `host`) TAGS ('cpu0', 'b') (`ts`, `usage`) VALUES (1500000000123, 2)",
+		"INSERT INTO `telegraf`.`" + a + "` USING `telegraf`.`cpu` (`cpu`, `host`) TAGS (NULL, 'a') (`ts`, `threads`) VALUES (1500000000123, 4)",
+	}, s.queries)
+
+	// The super tables which do not exist are created.
+	s.queries = nil
+	td.Precision = "us"
+	require.NoError(t, td.Connect())
+	require.NoError(t, td.Write([]telegraf.Metric{
+		newMetric(t, "mem", nil, map[string]interface{}{"free": uint64(1024), "ts": 1.0}),
+	}))
+	mem := subTable("mem", nil)
+	require.Equal(t, []string{
+		"DESCRIBE `telegraf`.`mem`",
+		"CREATE STABLE IF NOT EXISTS `telegraf`.`mem` (`ts` TIMESTAMP, `free` BIGINT UNSIGNED) TAGS (`_tag_null` BOOL)",
+		"INSERT INTO `telegraf`.`" + mem + "` USING `telegraf`.`mem` (`_tag_null`) TAGS (true) (`ts`, `free`) VALUES (1500000000123456, 1024)",
+	}, s.queries)
+
+	// Without table_create, the super tables must exist.
+	td.TableCreate = false
+	err := td.Write([]telegraf.Metric{newMetric(t, "disk", nil, map[string]interface{}{"free": 1.0})})
+	require.EqualError(t, err, "writing to super table disk: code 9826: Table does not exist")
+
+	td.TableCreate = true
+	err = td.Write([]telegraf.Metric{newMetric(t, "invalid", nil, map[string]interface{}{"free": 1.0})})
+	require.EqualError(t, err, "writing to super table invalid: code 9730: Invalid value")
+}
+
+func TestQuery(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("unauthorized"))
+		default:
+			// The response of TDengine 2.x.
+			w.Write([]byte(`{"status":"error","code":866,"desc":"Table does not exist"}`))
+		}
+	}))
+	defer s.Close()
+
+	td := &TDengine{URL: s.URL + "/", Database: "telegraf"}
+	require.NoError(t, td.Connect())
+	_, err := td.query("SELECT 1")
+	require.EqualError(t, err, "401 Unauthorized: unauthorized")
+
+	td.Username = "root"
+	_, err = td.query("SELECT 1")
+	require.EqualError(t, err, "code 866: Table does not exist")
+
+	td = &TDengine{URL: s.URL, Database: "telegraf", Precision: "s"}
+	require.EqualError(t, td.Connect(), `unknown precision "s"`)
+}
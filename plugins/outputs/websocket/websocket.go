@@ -0,0 +1,250 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	defaultConnectTimeout       = 30 * time.Second
+	defaultWriteTimeout         = 30 * time.Second
+	defaultReconnectMaxInterval = 5 * time.Minute
+)
+
+var sampleConfig = `
+  ## URL of the endpoint, with the ws or wss scheme.
+  url = "ws://127.0.0.1:8080/telegraf"
+
+  ## Timeout for connecting and the opening handshake.
+  # connect_timeout = "30s"
+
+  ## Timeout for sending the metrics of a write.
+  # write_timeout = "30s"
+
+  ## The connection is opened again at the next write after it is lost,
+  ## and then after increasing intervals, from 1s to reconnect_max_interval,
+  ## while it fails.
+  # reconnect_max_interval = "5m"
+
+  ## Additional HTTP headers of the opening handshake.
+  # [outputs.websocket.headers]
+  #   Authorization = "Bearer my-token"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  # data_format = "influx"
+`
+
+type WebSocket struct {
+	URL                  string            `toml:"url"`
+	ConnectTimeout       internal.Duration `toml:"connect_timeout"`
+	WriteTimeout         internal.Duration `toml:"write_timeout"`
+	ReconnectMaxInterval internal.Duration `toml:"reconnect_max_interval"`
+	Headers              map[string]string `toml:"headers"`
+	tlsint.ClientConfig
+
+	serializer serializers.Serializer
+	config     *websocket.Config
+
+	conn *websocket.Conn
+	// closed is closed once the connection is lost, as found out by the
+	// reader of its messages.
+	closed chan struct{}
+
+	// No connection is attempted before retryTime, set after the
+	// connection fails with an exponential backoff.
+	retryTime  time.Time
+	retryCount int
+	now        func() time.Time
+}
+
+func (w *WebSocket) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *WebSocket) Description() string {
+	return "Stream metrics to a WebSocket endpoint"
+}
+
+func (w *WebSocket) SetSerializer(serializer serializers.Serializer) {
+	w.serializer = serializer
+}
+
+func (w *WebSocket) Connect() error {
+	location, err := url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %s", w.URL, err)
+	}
+	if location.Scheme != "ws" && location.Scheme != "wss" {
+		return fmt.Errorf("invalid url %q: the scheme must be ws or wss", w.URL)
+	}
+
+	// The origin is only meaningful to browsers; it is the endpoint itself
+	// as the WebSocket listener input expects.
+	origin := &url.URL{Scheme: "http", Host: location.Host}
+	if location.Scheme == "wss" {
+		origin.Scheme = "https"
+	}
+	config, err := websocket.NewConfig(location.String(), origin.String())
+	if err != nil {
+		return err
+	}
+	config.TlsConfig, err = w.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	config.Dialer = &net.Dialer{Timeout: w.ConnectTimeout.Duration}
+	for k, v := range w.Headers {
+		config.Header.Set(k, v)
+	}
+	w.config = config
+
+	if w.now == nil {
+		w.now = time.Now
+	}
+	// The connection is retried by the writes, so that Telegraf starts
+	// while the endpoint is unreachable.
+	if err := w.connect(); err != nil {
+		log.Printf("W! [outputs.websocket] %s", err)
+	}
+	return nil
+}
+
+// connect opens the connection, and starts reading its messages so that the
+// control frames are handled and its loss noticed.
+func (w *WebSocket) connect() error {
+	conn, err := w.dial()
+	if err != nil {
+		w.retryTime = w.now().Add(w.retryDuration())
+		w.retryCount++
+		return fmt.Errorf("connecting to %s: %s", w.URL, err)
+	}
+	w.retryCount = 0
+	w.conn = conn
+	w.closed = make(chan struct{})
+	go read(conn, w.closed)
+	return nil
+}
+
+// dial dials and performs the opening handshake within the connect timeout,
+// which the dialer alone does not bound.
+func (w *WebSocket) dial() (*websocket.Conn, error) {
+	type result struct {
+		conn *websocket.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := websocket.DialConfig(w.config)
+		done <- result{conn, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-time.After(w.ConnectTimeout.Duration):
+		go func() {
+			if r := <-done; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, fmt.Errorf("timeout")
+	}
+}
+
+// read reads the messages of the connection until it fails, discarding them.
+func read(conn *websocket.Conn, closed chan struct{}) {
+	defer close(closed)
+	var msg []byte
+	for {
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			return
+		}
+	}
+}
+
+// retryDuration returns the time to wait before the next connection,
+// backing off exponentially.
+func (w *WebSocket) retryDuration() time.Duration {
+	backoff := time.Second << uint(w.retryCount)
+	if backoff > w.ReconnectMaxInterval.Duration || backoff <= 0 {
+		backoff = w.ReconnectMaxInterval.Duration
+	}
+	return backoff
+}
+
+// Write sends the metrics as a single message, after opening the connection
+// again if it was lost.
+func (w *WebSocket) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	if w.conn != nil {
+		select {
+		case <-w.closed:
+			w.conn.Close()
+			w.conn = nil
+			log.Printf("W! [outputs.websocket] Connection to %s lost, reconnecting", w.URL)
+		default:
+		}
+	}
+	if w.conn == nil {
+		if w.now().Before(w.retryTime) {
+			return fmt.Errorf("not connected to %s, reconnecting in %s", w.URL, w.retryTime.Sub(w.now())/time.Second*time.Second)
+		}
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	msg, err := w.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(w.WriteTimeout.Duration))
+	if err := websocket.Message.Send(w.conn, msg); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return fmt.Errorf("sending to %s: %s", w.URL, err)
+	}
+	return nil
+}
+
+func (w *WebSocket) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func init() {
+	outputs.Add("websocket", func() telegraf.Output {
+		return &WebSocket{
+			ConnectTimeout:       internal.Duration{Duration: defaultConnectTimeout},
+			WriteTimeout:         internal.Duration{Duration: defaultWriteTimeout},
+			ReconnectMaxInterval: internal.Duration{Duration: defaultReconnectMaxInterval},
+		}
+	})
+}
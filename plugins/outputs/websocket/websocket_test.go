@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// fakeServer records the messages sent to it, and the headers of the
+// connections.
+type fakeServer struct {
+	*httptest.Server
+	messages chan string
+	mu       sync.Mutex
+	headers  []http.Header
+	conns    []*websocket.Conn
+}
+
+func newFakeServer() *fakeServer {
+	s := &fakeServer{messages: make(chan string, 10)}
+	s.Server = httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		s.mu.Lock()
+		s.headers = append(s.headers, conn.Request().Header)
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		for {
+			var msg string
+			if err := websocket.Message.Receive(conn, &msg); err != nil {
+				return
+			}
+			s.messages <- msg
+		}
+	}))
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// drop closes the connections of the server.
+func (s *fakeServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeServer) next(t *testing.T) string {
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no message received")
+		return ""
+	}
+}
+
+func newWebSocket(url string) *WebSocket {
+	w := &WebSocket{
+		URL:                  url,
+		ConnectTimeout:       internal.Duration{Duration: 5 * time.Second},
+		WriteTimeout:         internal.Duration{Duration: 5 * time.Second},
+		ReconnectMaxInterval: internal.Duration{Duration: 5 * time.Minute},
+		Headers:              map[string]string{"Authorization": "Bearer token"},
+	}
+	w.SetSerializer(influx.NewSerializer())
+	return w
+}
+
+func newMetrics(t *testing.T) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	for _, host := range []string{"a", "b"} {
+		m, err := metric.New("cpu", map[string]string{"host": host}, map[string]interface{}{"usage": 1.0}, time.Unix(1500000000, 0))
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func TestWrite(t *testing.T) {
+	s := newFakeServer()
+	defer s.Close()
+
+	w := newWebSocket(s.url())
+	require.NoError(t, w.Connect())
+	defer w.Close()
+
+	require.NoError(t, w.Write(newMetrics(t)))
+	require.Equal(t, "cpu,host=a usage=1 1500000000000000000\ncpu,host=b usage=1 1500000000000000000\n", s.next(t))
+	s.mu.Lock()
+	require.Equal(t, "Bearer token", s.headers[0].Get("Authorization"))
+	s.mu.Unlock()
+}
+
+func TestReconnect(t *testing.T) {
+	s := newFakeServer()
+	defer s.Close()
+
+	w := newWebSocket(s.url())
+	require.NoError(t, w.Connect())
+	defer w.Close()
+	require.NoError(t, w.Write(newMetrics(t)))
+	s.next(t)
+
+	// The connection lost is opened again by the next write.
+	closed := w.closed
+	s.drop()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "connection loss not noticed")
+	}
+	require.NoError(t, w.Write(newMetrics(t)))
+	s.next(t)
+	s.mu.Lock()
+	require.Len(t, s.headers, 2)
+	s.mu.Unlock()
+}
+
+func TestBackoff(t *testing.T) {
+	s := newFakeServer()
+	url := s.url()
+	s.Close()
+
+	now := time.Unix(1500000000, 0)
+	w := newWebSocket(url)
+	w.ReconnectMaxInterval.Duration = 3 * time.Second
+	w.now = func() time.Time { return now }
+
+	// Connect succeeds while the endpoint is down, for the writes to retry.
+	require.NoError(t, w.Connect())
+	err := w.Write(newMetrics(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not connected to "+url+", reconnecting in 1s")
+
+	now = now.Add(time.Second)
+	err = w.Write(newMetrics(t))
+	require.Contains(t, err.Error(), "connecting to "+url)
+	err = w.Write(newMetrics(t))
+	require.Contains(t, err.Error(), "reconnecting in 2s")
+
+	// The backoff is capped by reconnect_max_interval.
+	now = now.Add(2 * time.Second)
+	require.Error(t, w.Write(newMetrics(t)))
+	require.Contains(t, w.Write(newMetrics(t)).Error(), "reconnecting in 3s")
+	now = now.Add(3 * time.Second)
+	require.Error(t, w.Write(newMetrics(t)))
+	require.Contains(t, w.Write(newMetrics(t)).Error(), "reconnecting in 3s")
+}
+
+func TestConnectInvalidURL(t *testing.T) {
+	w := newWebSocket("http://localhost:8080/telegraf")
+	require.EqualError(t, w.Connect(), `invalid url "http://localhost:8080/telegraf": the scheme must be ws or wss`)
+}
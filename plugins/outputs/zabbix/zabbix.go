@@ -0,0 +1,426 @@
+package zabbix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// header starts the packets of the Zabbix protocol, followed by the length of
+// the data in little endian.
+var header = []byte("ZBXD\x01")
+
+// maxResponseSize is the maximum size of the responses of the server.
+const maxResponseSize = 1 << 20
+
+// maxItemsPerRequest is the number of values sent at once, as by
+// zabbix_sender.
+const maxItemsPerRequest = 250
+
+// processedRegexp matches the counts of the responses of the server.
+var processedRegexp = regexp.MustCompile(`processed: (\d+); failed: (\d+); total: (\d+)`)
+
+var sampleConfig = `
+  ## Address of the Zabbix server or proxy, with the port of its trapper.
+  address = "localhost:10051"
+
+  ## Prefix of the keys of the items, such as "telegraf.cpu.usage_idle".
+  # key_prefix = "telegraf."
+
+  ## Tag holding the name of the Zabbix host of the metrics.  The metrics
+  ## without the tag are sent for the host of Telegraf.
+  # host_tag = "host"
+
+  ## Whether to leave the measurement out of the keys, such as
+  ## "telegraf.usage_idle".
+  # skip_measurement_prefix = false
+
+  ## Low-level discovery: the tag sets of each measurement are sent to the
+  ## "<key_prefix>lld.<measurement>.<tag keys>" trapper discovery rule as
+  ## soon as they appear, and all of them every lld_send_interval.  The tag
+  ## sets not seen for lld_clear_interval are left out.  0 disables the
+  ## low-level discovery.
+  # lld_send_interval = "10m"
+  # lld_clear_interval = "1h"
+
+  ## Timeout for the connection and the exchange with the server.
+  # timeout = "5s"
+`
+
+type Zabbix struct {
+	Address               string
+	KeyPrefix             string            `toml:"key_prefix"`
+	HostTag               string            `toml:"host_tag"`
+	SkipMeasurementPrefix bool              `toml:"skip_measurement_prefix"`
+	LLDSendInterval       internal.Duration `toml:"lld_send_interval"`
+	LLDClearInterval      internal.Duration `toml:"lld_clear_interval"`
+	Timeout               internal.Duration
+
+	hostname string
+	// lld are the tag sets of the discovery rules, by host and key, with the
+	// time they were last seen.
+	lld      map[lldRule]map[string]*lldEntry
+	lastSend time.Time
+	now      func() time.Time
+}
+
+// item is a value of an item of a host.
+type item struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock"`
+	NS    int    `json:"ns"`
+}
+
+// lldRule is a discovery rule of a host.
+type lldRule struct {
+	host string
+	key  string
+}
+
+// lldEntry is a tag set of a discovery rule, as its LLD macros.
+type lldEntry struct {
+	macros   map[string]string
+	lastSeen time.Time
+}
+
+func (z *Zabbix) SampleConfig() string {
+	return sampleConfig
+}
+
+func (z *Zabbix) Description() string {
+	return "Send metrics to Zabbix trapper items, with low-level discovery"
+}
+
+func (z *Zabbix) Connect() error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	z.hostname = hostname
+	z.lld = make(map[lldRule]map[string]*lldEntry)
+	if z.now == nil {
+		z.now = time.Now
+	}
+	return nil
+}
+
+func (z *Zabbix) Close() error {
+	return nil
+}
+
+// Write sends the values of the fields of the metrics, after the discovery
+// data of the new tag sets.
+func (z *Zabbix) Write(metrics []telegraf.Metric) error {
+	var items []item
+	changed := make(map[lldRule]bool)
+	for _, m := range metrics {
+		host := z.hostname
+		if h, ok := m.GetTag(z.HostTag); ok {
+			host = h
+		}
+		tags := z.tags(m)
+		for _, field := range m.FieldList() {
+			value, ok := formatValue(field.Value)
+			if !ok {
+				continue
+			}
+			items = append(items, item{
+				Host:  host,
+				Key:   z.key(m.Name(), field.Key, tags),
+				Value: value,
+				Clock: m.Time().Unix(),
+				NS:    m.Time().Nanosecond(),
+			})
+		}
+		if len(tags) > 0 && z.LLDSendInterval.Duration > 0 {
+			if rule, ok := z.discover(host, m.Name(), tags); ok {
+				changed[rule] = true
+			}
+		}
+	}
+
+	// The discovery data is sent first, for the item prototypes.
+	items = append(z.lldItems(changed), items...)
+	for len(items) > 0 {
+		batch := items
+		if len(batch) > maxItemsPerRequest {
+			batch = batch[:maxItemsPerRequest]
+		}
+		if err := z.send(batch); err != nil {
+			return err
+		}
+		items = items[len(batch):]
+	}
+	return nil
+}
+
+// tags returns the tags of a metric but its host, sorted by key.
+func (z *Zabbix) tags(m telegraf.Metric) []*telegraf.Tag {
+	var tags []*telegraf.Tag
+	for _, tag := range m.TagList() {
+		if tag.Key != z.HostTag {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// key returns the key of the item of a field, with the values of the tags as
+// parameters, such as "telegraf.cpu.usage_idle[cpu0]".
+func (z *Zabbix) key(measurement, field string, tags []*telegraf.Tag) string {
+	key := z.KeyPrefix
+	if !z.SkipMeasurementPrefix {
+		key += measurement + "."
+	}
+	key += field
+	if len(tags) == 0 {
+		return key
+	}
+	params := make([]string, len(tags))
+	for i, tag := range tags {
+		params[i] = quoteParameter(tag.Value)
+	}
+	return key + "[" + strings.Join(params, ",") + "]"
+}
+
+// lldKey returns the key of the discovery rule of the tag keys of a
+// measurement, such as "telegraf.lld.cpu.cpu".
+func (z *Zabbix) lldKey(measurement string, tags []*telegraf.Tag) string {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tag.Key
+	}
+	return z.KeyPrefix + "lld." + measurement + "." + strings.Join(keys, ".")
+}
+
+// discover records the tag set of a metric, and tells whether it is new to
+// its discovery rule.
+func (z *Zabbix) discover(host, measurement string, tags []*telegraf.Tag) (lldRule, bool) {
+	rule := lldRule{host: host, key: z.lldKey(measurement, tags)}
+	entries, ok := z.lld[rule]
+	if !ok {
+		entries = make(map[string]*lldEntry)
+		z.lld[rule] = entries
+	}
+
+	var id bytes.Buffer
+	macros := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		macros[macro(tag.Key)] = tag.Value
+		id.WriteString(tag.Key + "\x00" + tag.Value + "\x00")
+	}
+	entry, ok := entries[id.String()]
+	if !ok {
+		entry = &lldEntry{macros: macros}
+		entries[id.String()] = entry
+	}
+	entry.lastSeen = z.now()
+	return rule, !ok
+}
+
+// lldItems returns the discovery data of the changed rules, or of all the
+// rules every lld_send_interval, when the tag sets not seen for
+// lld_clear_interval are forgotten.
+func (z *Zabbix) lldItems(changed map[lldRule]bool) []item {
+	if z.LLDSendInterval.Duration <= 0 {
+		return nil
+	}
+	now := z.now()
+	if now.Sub(z.lastSend) >= z.LLDSendInterval.Duration {
+		z.lastSend = now
+		changed = make(map[lldRule]bool)
+		for rule, entries := range z.lld {
+			for id, entry := range entries {
+				if z.LLDClearInterval.Duration > 0 && now.Sub(entry.lastSeen) > z.LLDClearInterval.Duration {
+					delete(entries, id)
+				}
+			}
+			if len(entries) == 0 {
+				delete(z.lld, rule)
+				continue
+			}
+			changed[rule] = true
+		}
+	}
+
+	rules := make([]lldRule, 0, len(changed))
+	for rule := range changed {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].host != rules[j].host {
+			return rules[i].host < rules[j].host
+		}
+		return rules[i].key < rules[j].key
+	})
+
+	items := make([]item, 0, len(rules))
+	for _, rule := range rules {
+		ids := make([]string, 0, len(z.lld[rule]))
+		for id := range z.lld[rule] {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		data := make([]map[string]string, len(ids))
+		for i, id := range ids {
+			data[i] = z.lld[rule][id].macros
+		}
+		value, err := json.Marshal(map[string]interface{}{"data": data})
+		if err != nil {
+			continue
+		}
+		items = append(items, item{
+			Host:  rule.host,
+			Key:   rule.key,
+			Value: string(value),
+			Clock: now.Unix(),
+			NS:    now.Nanosecond(),
+		})
+	}
+	return items
+}
+
+// send sends values to the server.  The values which the server fails to
+// process, such as those of items which do not exist, are logged: sending
+// them again would fail again.
+func (z *Zabbix) send(items []item) error {
+	now := z.now()
+	data, err := json.Marshal(map[string]interface{}{
+		"request": "sender data",
+		"data":    items,
+		"clock":   now.Unix(),
+		"ns":      now.Nanosecond(),
+	})
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", z.Address, z.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if z.Timeout.Duration > 0 {
+		conn.SetDeadline(time.Now().Add(z.Timeout.Duration))
+	}
+
+	if _, err := conn.Write(packet(data)); err != nil {
+		return err
+	}
+	response, err := readPacket(conn)
+	if err != nil {
+		return fmt.Errorf("reading the response: %s", err)
+	}
+
+	var result struct {
+		Response string `json:"response"`
+		Info     string `json:"info"`
+	}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return fmt.Errorf("invalid response %q: %s", response, err)
+	}
+	if result.Response != "success" {
+		return fmt.Errorf("%s: %s", result.Response, result.Info)
+	}
+	if match := processedRegexp.FindStringSubmatch(result.Info); match != nil && match[2] != "0" {
+		log.Printf("W! [outputs.zabbix] Failed to process %s of %s values, whose items may not exist: %s", match[2], match[3], result.Info)
+	}
+	return nil
+}
+
+// packet returns the packet of data, with its header.
+func packet(data []byte) []byte {
+	p := make([]byte, len(header)+8, len(header)+8+len(data))
+	copy(p, header)
+	binary.LittleEndian.PutUint64(p[len(header):], uint64(len(data)))
+	return append(p, data...)
+}
+
+// readPacket reads a packet, and returns its data.
+func readPacket(r io.Reader) ([]byte, error) {
+	h := make([]byte, len(header)+8)
+	if _, err := io.ReadFull(r, h); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h[:len(header)], header) {
+		return nil, fmt.Errorf("invalid header %q", h[:len(header)])
+	}
+	length := binary.LittleEndian.Uint64(h[len(header):])
+	if length > maxResponseSize {
+		return nil, fmt.Errorf("packet of %d bytes over %d bytes", length, maxResponseSize)
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func formatValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+// quoteParameter quotes a parameter of a key when needed, as per the syntax
+// of the keys of Zabbix.
+func quoteParameter(p string) string {
+	if !strings.ContainsAny(p, `,[]"`) && !strings.HasPrefix(p, " ") {
+		return p
+	}
+	return `"` + strings.Replace(p, `"`, `\"`, -1) + `"`
+}
+
+// macro returns the LLD macro of a tag key, such as "{#CPU}", made of the
+// characters allowed in the macros.
+func macro(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, key)
+	return "{#" + name + "}"
+}
+
+func init() {
+	outputs.Add("zabbix", func() telegraf.Output {
+		return &Zabbix{
+			KeyPrefix:        "telegraf.",
+			HostTag:          "host",
+			LLDSendInterval:  internal.Duration{Duration: 10 * time.Minute},
+			LLDClearInterval: internal.Duration{Duration: time.Hour},
+			Timeout:          internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package zabbix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+var ts = time.Unix(1500000000, 123)
+
+func TestKey(t *testing.T) {
+	z := &Zabbix{KeyPrefix: "telegraf.", HostTag: "host"}
+	m := newMetric(t, "disk", map[string]string{"host": "a", "path": "/", "fstype": "ext4,rw"}, map[string]interface{}{"free": 1.0})
+	tags := z.tags(m)
+	require.Equal(t, `telegraf.disk.free["ext4,rw",/]`, z.key(m.Name(), "free", tags))
+	require.Equal(t, "telegraf.lld.disk.fstype.path", z.lldKey(m.Name(), tags))
+	require.Equal(t, "telegraf.disk.free", z.key(m.Name(), "free", nil))
+
+	z.SkipMeasurementPrefix = true
+	require.Equal(t, "telegraf.free", z.key(m.Name(), "free", nil))
+
+	require.Equal(t, `"a\"b"`, quoteParameter(`a"b`))
+	require.Equal(t, `" a"`, quoteParameter(" a"))
+	require.Equal(t, "{#DEV_NAME.1}", macro("dev-name.1"))
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+		ok       bool
+	}{
+		{1.5, "1.5", true},
+		{int64(-2), "-2", true},
+		{uint64(1) << 63, "9223372036854775808", true},
+		{true, "1", true},
+		{false, "0", true},
+		{"text", "text", true},
+		{[]byte("bytes"), "", false},
+	}
+	for _, tt := range tests {
+		value, ok := formatValue(tt.value)
+		require.Equal(t, tt.expected, value)
+		require.Equal(t, tt.ok, ok)
+	}
+}
+
+// fakeServer is a Zabbix trapper which records the values sent to it, and
+// fails to process the values of the "missing" items.
+type fakeServer struct {
+	net.Listener
+	mu       sync.Mutex
+	requests [][]item
+	response string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{Listener: listener, response: "success"}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.handle(t, conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	data, err := readPacket(conn)
+	if err != nil {
+		return
+	}
+	var request struct {
+		Request string `json:"request"`
+		Data    []item `json:"data"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil || request.Request != "sender data" {
+		conn.Write(packet([]byte(`{"response":"failed","info":"invalid request"}`)))
+		return
+	}
+
+	failed := 0
+	for _, i := range request.Data {
+		if i.Key == "telegraf.missing" {
+			failed++
+		}
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, request.Data)
+	response := s.response
+	s.mu.Unlock()
+	info := fmt.Sprintf("processed: %d; failed: %d; total: %d; seconds spent: 0.000100", len(request.Data)-failed, failed, len(request.Data))
+	conn.Write(packet([]byte(fmt.Sprintf(`{"response":%q,"info":%q}`, response, info))))
+}
+
+func (s *fakeServer) takeRequests() [][]item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func newMetric(t *testing.T, name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New(name, tags, fields, ts)
+	require.NoError(t, err)
+	return m
+}
+
+func TestWrite(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	now := time.Unix(1600000000, 0)
+	z := &Zabbix{
+		Address:          s.Addr().String(),
+		KeyPrefix:        "telegraf.",
+		HostTag:          "host",
+		LLDSendInterval:  internal.Duration{Duration: 10 * time.Minute},
+		LLDClearInterval: internal.Duration{Duration: time.Hour},
+		now:              func() time.Time { return now },
+	}
+	require.NoError(t, z.Connect())
+
+	metrics := []telegraf.Metric{
+		newMetric(t, "cpu", map[string]string{"host": "a", "cpu": "cpu0"}, map[string]interface{}{"usage_idle": 99.5}),
+		newMetric(t, "cpu", map[string]string{"host": "a", "cpu": "cpu1"}, map[string]interface{}{"usage_idle": 98.0}),
+		newMetric(t, "system", nil, map[string]interface{}{"uptime": uint64(42)}),
+	}
+	require.NoError(t, z.Write(metrics))
+
+	lld := item{
+		Host:  "a",
+		Key:   "telegraf.lld.cpu.cpu",
+		Value: `{"data":[{"{#CPU}":"cpu0"},{"{#CPU}":"cpu1"}]}`,
+		Clock: now.Unix(),
+	}
+	require.Equal(t, [][]item{{
+		lld,
+		{Host: "a", Key: "telegraf.cpu.usage_idle[cpu0]", Value: "99.5", Clock: ts.Unix(), NS: 123},
+		{Host: "a", Key: "telegraf.cpu.usage_idle[cpu1]", Value: "98", Clock: ts.Unix(), NS: 123},
+		{Host: z.hostname, Key: "telegraf.system.uptime", Value: "42", Clock: ts.Unix(), NS: 123},
+	}}, s.takeRequests())
+
+	// The discovery data is only sent again for new tag sets, and then every
+	// lld_send_interval without the tag sets not seen for lld_clear_interval.
+	now = now.Add(time.Minute)
+	require.NoError(t, z.Write(metrics[:1]))
+	require.Len(t, s.takeRequests()[0], 1)
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, z.Write(metrics[1:2]))
+	lld.Value = `{"data":[{"{#CPU}":"cpu1"}]}`
+	lld.Clock = now.Unix()
+	require.Equal(t, lld, s.takeRequests()[0][0])
+
+	// The values which fail to be processed are not sent again.
+	z.SkipMeasurementPrefix = true
+	require.NoError(t, z.Write([]telegraf.Metric{newMetric(t, "system", nil, map[string]interface{}{"missing": 1.0})}))
+	s.takeRequests()
+
+	s.mu.Lock()
+	s.response = "failed"
+	s.mu.Unlock()
+	err := z.Write([]telegraf.Metric{newMetric(t, "system", nil, map[string]interface{}{"uptime": 1.0})})
+	require.EqualError(t, err, "failed: processed: 1; failed: 0; total: 1; seconds spent: 0.000100")
+}
+
+func TestWriteBatches(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	z := &Zabbix{Address: s.Addr().String(), HostTag: "host"}
+	require.NoError(t, z.Connect())
+
+	var metrics []telegraf.Metric
+	for i := 0; i < maxItemsPerRequest+1; i++ {
+		metrics = append(metrics, newMetric(t, "cpu", map[string]string{"cpu": fmt.Sprint(i)}, map[string]interface{}{"usage": 1.0}))
+	}
+	require.NoError(t, z.Write(metrics))
+	requests := s.takeRequests()
+	require.Len(t, requests, 2)
+	require.Len(t, requests[0], maxItemsPerRequest)
+	require.Len(t, requests[1], 1)
+}
+
+func TestReadPacket(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		server.Write([]byte("HTTP/1.1 400 Bad Request\r\n"))
+		server.Close()
+	}()
+	_, err := readPacket(client)
+	require.EqualError(t, err, `invalid header "HTTP/"`)
+}
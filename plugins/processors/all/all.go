@@ -1,9 +1,21 @@
+// +build !custom
+
 package all
 
 import (
+	_ "github.com/influxdata/telegraf/plugins/processors/anomaly"
 	_ "github.com/influxdata/telegraf/plugins/processors/converter"
+	_ "github.com/influxdata/telegraf/plugins/processors/join"
+	_ "github.com/influxdata/telegraf/plugins/processors/limit"
 	_ "github.com/influxdata/telegraf/plugins/processors/override"
 	_ "github.com/influxdata/telegraf/plugins/processors/printer"
+	_ "github.com/influxdata/telegraf/plugins/processors/ratelimit"
+	_ "github.com/influxdata/telegraf/plugins/processors/redact"
 	_ "github.com/influxdata/telegraf/plugins/processors/regex"
+	_ "github.com/influxdata/telegraf/plugins/processors/rename"
+	_ "github.com/influxdata/telegraf/plugins/processors/sample"
+	_ "github.com/influxdata/telegraf/plugins/processors/scale"
+	_ "github.com/influxdata/telegraf/plugins/processors/schema"
+	_ "github.com/influxdata/telegraf/plugins/processors/strings"
 	_ "github.com/influxdata/telegraf/plugins/processors/topk"
 )
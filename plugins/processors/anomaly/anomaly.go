@@ -0,0 +1,301 @@
+package anomaly
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Fields to watch, glob patterns are supported.  Only numeric fields are
+  ## checked.
+  fields = ["*"]
+
+  ## Statistics of each series and field used to detect anomalies:
+  ##   "zscore" - mean and standard deviation of the last window values
+  ##   "ewma"   - exponentially weighted mean and standard deviation, with
+  ##              the weight alpha given to each new value
+  # method = "ewma"
+  # window = 30
+  # alpha = 0.3
+
+  ## A value is an anomaly if it is more than threshold standard deviations
+  ## away from the mean.  No value is checked before min_samples values of
+  ## the series were seen.
+  # threshold = 3.0
+  # min_samples = 10
+
+  ## How to report anomalies:
+  ##   "tag"   - add the tag anomaly=true to the metric
+  ##   "event" - emit a "<measurement>_anomaly" metric for each anomalous
+  ##             field, with the field name in the "field" tag
+  # mode = "tag"
+
+  ## Statistics of series that are not updated for this long are removed.
+  # series_timeout = "1h"
+`
+
+type Anomaly struct {
+	Fields        []string          `toml:"fields"`
+	Method        string            `toml:"method"`
+	Window        int               `toml:"window"`
+	Alpha         float64           `toml:"alpha"`
+	Threshold     float64           `toml:"threshold"`
+	MinSamples    int               `toml:"min_samples"`
+	Mode          string            `toml:"mode"`
+	SeriesTimeout internal.Duration `toml:"series_timeout"`
+
+	initialized bool
+	fieldFilter filter.Filter
+	series      map[seriesKey]*stats
+	lastExpire  time.Time
+	now         func() time.Time
+}
+
+type seriesKey struct {
+	id    uint64
+	field string
+}
+
+// stats are the statistics of a field of a series.
+type stats struct {
+	count    int
+	lastSeen time.Time
+
+	// zscore
+	values []float64
+	next   int
+
+	// ewma
+	mean     float64
+	variance float64
+}
+
+func NewAnomaly() *Anomaly {
+	return &Anomaly{
+		Fields:        []string{"*"},
+		Method:        "ewma",
+		Window:        30,
+		Alpha:         0.3,
+		Threshold:     3.0,
+		MinSamples:    10,
+		Mode:          "tag",
+		SeriesTimeout: internal.Duration{Duration: time.Hour},
+		now:           time.Now,
+	}
+}
+
+func (a *Anomaly) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Anomaly) Description() string {
+	return "Tag or report values that deviate from the rolling statistics of their series"
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (a *Anomaly) Validate() error {
+	return a.init()
+}
+
+func (a *Anomaly) init() error {
+	var err error
+	a.fieldFilter, err = filter.Compile(a.Fields)
+	if err != nil {
+		return fmt.Errorf("fields: %s", err)
+	}
+
+	switch a.Method {
+	case "zscore":
+		if a.Window < 2 {
+			return fmt.Errorf("window must be at least 2")
+		}
+	case "ewma":
+		if a.Alpha <= 0 || a.Alpha > 1 {
+			return fmt.Errorf("alpha must be in (0, 1]")
+		}
+	default:
+		return fmt.Errorf("unknown method %q", a.Method)
+	}
+	switch a.Mode {
+	case "tag", "event":
+	default:
+		return fmt.Errorf("unknown mode %q", a.Mode)
+	}
+	if a.Threshold <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+
+	a.series = make(map[seriesKey]*stats)
+	a.initialized = true
+	return nil
+}
+
+func (a *Anomaly) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !a.initialized {
+		if err := a.init(); err != nil {
+			log.Printf("E! [processors.anomaly] Invalid configuration, not checking metrics: %s", err)
+			return in
+		}
+	}
+
+	now := a.now()
+	a.expire(now)
+
+	out := in
+	for _, m := range in {
+		if a.fieldFilter == nil {
+			break
+		}
+
+		anomalous := false
+		id := m.HashID()
+		for _, field := range m.FieldList() {
+			if !a.fieldFilter.Match(field.Key) {
+				continue
+			}
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+
+			key := seriesKey{id, field.Key}
+			s, ok := a.series[key]
+			if !ok {
+				s = &stats{}
+				a.series[key] = s
+			}
+			s.lastSeen = now
+
+			mean, stddev := a.stats(s)
+			checked := s.count >= a.MinSamples && stddev > 0
+			a.update(s, value)
+			if !checked {
+				continue
+			}
+
+			score := math.Abs(value-mean) / stddev
+			if score <= a.Threshold {
+				continue
+			}
+			anomalous = true
+
+			if a.Mode == "event" {
+				event, err := a.event(m, field.Key, value, mean, stddev, score)
+				if err != nil {
+					log.Printf("E! [processors.anomaly] Unable to create event: %s", err)
+					continue
+				}
+				out = append(out, event)
+			}
+		}
+
+		if anomalous && a.Mode == "tag" {
+			m.AddTag("anomaly", "true")
+		}
+	}
+	return out
+}
+
+// stats returns the mean and standard deviation of the values seen so far.
+func (a *Anomaly) stats(s *stats) (float64, float64) {
+	if a.Method == "ewma" {
+		return s.mean, math.Sqrt(s.variance)
+	}
+
+	n := len(s.values)
+	if n < 2 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range s.values {
+		sum += v
+	}
+	mean := sum / float64(n)
+	var sq float64
+	for _, v := range s.values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(n-1))
+}
+
+func (a *Anomaly) update(s *stats, value float64) {
+	s.count++
+	if a.Method == "ewma" {
+		if s.count == 1 {
+			s.mean = value
+			return
+		}
+		diff := value - s.mean
+		incr := a.Alpha * diff
+		s.mean += incr
+		s.variance = (1 - a.Alpha) * (s.variance + diff*incr)
+		return
+	}
+
+	if len(s.values) < a.Window {
+		s.values = append(s.values, value)
+		return
+	}
+	s.values[s.next] = value
+	s.next = (s.next + 1) % a.Window
+}
+
+func (a *Anomaly) event(
+	m telegraf.Metric,
+	field string,
+	value, mean, stddev, score float64,
+) (telegraf.Metric, error) {
+	tags := m.Tags()
+	tags["field"] = field
+	return metric.New(m.Name()+"_anomaly", tags,
+		map[string]interface{}{
+			"value":  value,
+			"mean":   mean,
+			"stddev": stddev,
+			"score":  score,
+		},
+		m.Time(),
+	)
+}
+
+// expire removes the statistics of series that were not updated within
+// the series timeout.  It runs at most once per timeout.
+func (a *Anomaly) expire(now time.Time) {
+	timeout := a.SeriesTimeout.Duration
+	if timeout <= 0 || now.Sub(a.lastExpire) < timeout {
+		return
+	}
+	for key, s := range a.series {
+		if now.Sub(s.lastSeen) > timeout {
+			delete(a.series, key)
+		}
+	}
+	a.lastExpire = now
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func init() {
+	processors.Add("anomaly", func() telegraf.Processor {
+		return NewAnomaly()
+	})
+}
@@ -0,0 +1,174 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(host string, value float64) telegraf.Metric {
+	m, _ := metric.New("latency",
+		map[string]string{"host": host},
+		map[string]interface{}{"value": value, "status": "ok"},
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+// baseline writes values alternating around 100 to the processor.
+func baseline(a *Anomaly, host string, n int) {
+	for i := 0; i < n; i++ {
+		v := 99.0
+		if i%2 == 0 {
+			v = 101.0
+		}
+		a.Apply(newMetric(host, v))
+	}
+}
+
+func TestTag(t *testing.T) {
+	for _, method := range []string{"ewma", "zscore"} {
+		t.Run(method, func(t *testing.T) {
+			a := NewAnomaly()
+			a.Method = method
+			baseline(a, "a", 20)
+
+			out := a.Apply(newMetric("a", 100.5))
+			require.Len(t, out, 1)
+			require.False(t, out[0].HasTag("anomaly"))
+
+			out = a.Apply(newMetric("a", 150))
+			require.Len(t, out, 1)
+			require.Equal(t, map[string]string{"host": "a", "anomaly": "true"}, out[0].Tags())
+		})
+	}
+}
+
+func TestMinSamples(t *testing.T) {
+	a := NewAnomaly()
+	baseline(a, "a", 5)
+
+	out := a.Apply(newMetric("a", 150))
+	require.False(t, out[0].HasTag("anomaly"))
+}
+
+func TestSeriesAreSeparate(t *testing.T) {
+	a := NewAnomaly()
+	baseline(a, "a", 20)
+	for i := 0; i < 20; i++ {
+		a.Apply(newMetric("b", 150.0+float64(i%2)))
+	}
+
+	out := a.Apply(newMetric("b", 150))
+	require.False(t, out[0].HasTag("anomaly"))
+	out = a.Apply(newMetric("a", 150))
+	require.True(t, out[0].HasTag("anomaly"))
+}
+
+func TestEvent(t *testing.T) {
+	a := NewAnomaly()
+	a.Mode = "event"
+	a.Method = "zscore"
+	a.Window = 10
+	baseline(a, "a", 20)
+
+	out := a.Apply(newMetric("a", 110))
+	require.Len(t, out, 2)
+	require.False(t, out[0].HasTag("anomaly"))
+
+	event := out[1]
+	require.Equal(t, "latency_anomaly", event.Name())
+	require.Equal(t, map[string]string{"host": "a", "field": "value"}, event.Tags())
+	fields := event.Fields()
+	require.Equal(t, 110.0, fields["value"])
+	require.Equal(t, 100.0, fields["mean"])
+	require.InDelta(t, 1.054, fields["stddev"], 0.001)
+	require.InDelta(t, 9.487, fields["score"], 0.001)
+	require.Equal(t, time.Unix(0, 0), event.Time())
+}
+
+func TestFields(t *testing.T) {
+	a := NewAnomaly()
+	a.Fields = []string{"other"}
+	baseline(a, "a", 20)
+
+	out := a.Apply(newMetric("a", 150))
+	require.False(t, out[0].HasTag("anomaly"))
+	require.Empty(t, a.series)
+}
+
+func TestExpire(t *testing.T) {
+	now := time.Unix(0, 0)
+	a := NewAnomaly()
+	a.now = func() time.Time { return now }
+
+	baseline(a, "a", 20)
+	require.Len(t, a.series, 1)
+
+	now = now.Add(30 * time.Minute)
+	baseline(a, "b", 1)
+	require.Len(t, a.series, 2)
+
+	now = now.Add(45 * time.Minute)
+	a.Apply()
+	require.Len(t, a.series, 1)
+
+	// The statistics of the series start over.
+	out := a.Apply(newMetric("a", 150))
+	require.False(t, out[0].HasTag("anomaly"))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(a *Anomaly)
+		err       string
+	}{
+		{
+			name:      "invalid fields",
+			configure: func(a *Anomaly) { a.Fields = []string{"a[b"} },
+			err:       "fields: unexpected end of input",
+		},
+		{
+			name:      "unknown method",
+			configure: func(a *Anomaly) { a.Method = "median" },
+			err:       `unknown method "median"`,
+		},
+		{
+			name:      "unknown mode",
+			configure: func(a *Anomaly) { a.Mode = "drop" },
+			err:       `unknown mode "drop"`,
+		},
+		{
+			name:      "window too small",
+			configure: func(a *Anomaly) { a.Method = "zscore"; a.Window = 1 },
+			err:       "window must be at least 2",
+		},
+		{
+			name:      "alpha out of range",
+			configure: func(a *Anomaly) { a.Alpha = 0 },
+			err:       "alpha must be in (0, 1]",
+		},
+		{
+			name:      "threshold not positive",
+			configure: func(a *Anomaly) { a.Threshold = 0 },
+			err:       "threshold must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnomaly()
+			tt.configure(a)
+			require.EqualError(t, a.Validate(), tt.err)
+
+			// Metrics are passed through unchanged.
+			baseline(a, "a", 20)
+			out := a.Apply(newMetric("a", 150))
+			require.Len(t, out, 1)
+			require.False(t, out[0].HasTag("anomaly"))
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"math"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/filter"
@@ -23,6 +25,8 @@ var sampleConfig = `
     unsigned = []
     boolean = []
     float = []
+    duration = []
+    bytes = []
 
   ## Fields to convert
   ##
@@ -36,6 +40,8 @@ var sampleConfig = `
     unsigned = []
     boolean = []
     float = []
+    duration = []
+    bytes = []
 `
 
 type Conversion struct {
@@ -45,6 +51,8 @@ type Conversion struct {
 	Unsigned []string `toml:"unsigned"`
 	Boolean  []string `toml:"boolean"`
 	Float    []string `toml:"float"`
+	Duration []string `toml:"duration"`
+	Bytes    []string `toml:"bytes"`
 }
 
 type Converter struct {
@@ -63,6 +71,8 @@ type ConversionFilter struct {
 	Unsigned filter.Filter
 	Boolean  filter.Filter
 	Float    filter.Filter
+	Duration filter.Filter
+	Bytes    filter.Filter
 }
 
 func (p *Converter) SampleConfig() string {
@@ -147,6 +157,16 @@ func compileFilter(conv *Conversion) (*ConversionFilter, error) {
 		return nil, err
 	}
 
+	cf.Duration, err = filter.Compile(conv.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.Bytes, err = filter.Compile(conv.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
 	return cf, nil
 }
 
@@ -173,6 +193,7 @@ func (p *Converter) convertTags(metric telegraf.Metric) {
 
 			metric.RemoveTag(key)
 			metric.AddField(key, v)
+			continue
 		}
 
 		if p.tagConversions.Unsigned != nil && p.tagConversions.Unsigned.Match(key) {
@@ -213,6 +234,32 @@ func (p *Converter) convertTags(metric telegraf.Metric) {
 			metric.AddField(key, v)
 			continue
 		}
+
+		if p.tagConversions.Duration != nil && p.tagConversions.Duration.Match(key) {
+			v, ok := toDuration(value)
+			if !ok {
+				metric.RemoveTag(key)
+				logPrintf("error converting to duration [%T]: %v\n", value, value)
+				continue
+			}
+
+			metric.RemoveTag(key)
+			metric.AddField(key, v)
+			continue
+		}
+
+		if p.tagConversions.Bytes != nil && p.tagConversions.Bytes.Match(key) {
+			v, ok := toBytes(value)
+			if !ok {
+				metric.RemoveTag(key)
+				logPrintf("error converting to bytes [%T]: %v\n", value, value)
+				continue
+			}
+
+			metric.RemoveTag(key)
+			metric.AddField(key, v)
+			continue
+		}
 	}
 }
 
@@ -300,6 +347,32 @@ func (p *Converter) convertFields(metric telegraf.Metric) {
 			metric.AddField(key, v)
 			continue
 		}
+
+		if p.fieldConversions.Duration != nil && p.fieldConversions.Duration.Match(key) {
+			v, ok := toDuration(value)
+			if !ok {
+				metric.RemoveField(key)
+				logPrintf("error converting to duration [%T]: %v\n", value, value)
+				continue
+			}
+
+			metric.RemoveField(key)
+			metric.AddField(key, v)
+			continue
+		}
+
+		if p.fieldConversions.Bytes != nil && p.fieldConversions.Bytes.Match(key) {
+			v, ok := toBytes(value)
+			if !ok {
+				metric.RemoveField(key)
+				logPrintf("error converting to bytes [%T]: %v\n", value, value)
+				continue
+			}
+
+			metric.RemoveField(key)
+			metric.AddField(key, v)
+			continue
+		}
 	}
 }
 
@@ -419,6 +492,75 @@ func toString(v interface{}) (string, bool) {
 	return "", false
 }
 
+// toDuration converts a duration string such as "10ms" to a float number of
+// seconds.  Numeric values are assumed to already be in seconds.
+func toDuration(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case string:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0.0, false
+		}
+		return d.Seconds(), true
+	}
+	return toFloat(v)
+}
+
+// byteUnits are the multipliers of the size suffixes accepted by toBytes.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+}
+
+// toBytes converts a size string such as "2Gi" or "1.5 MB" to an integer
+// number of bytes.  Decimal suffixes are powers of 1000 and binary suffixes
+// are powers of 1024.  Numeric values are assumed to already be in bytes.
+func toBytes(v interface{}) (int64, bool) {
+	switch value := v.(type) {
+	case string:
+		value = strings.TrimSpace(value)
+		i := strings.IndexFunc(value, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+		})
+		if i == -1 {
+			i = len(value)
+		}
+
+		n, err := strconv.ParseFloat(value[:i], 64)
+		if err != nil {
+			return 0, false
+		}
+
+		unit := strings.ToLower(strings.TrimSpace(value[i:]))
+		multiplier, ok := byteUnits[unit]
+		if !ok {
+			return 0, false
+		}
+		return toInteger(n * multiplier)
+	}
+	return toInteger(v)
+}
+
 func logPrintf(format string, v ...interface{}) {
 	log.Printf("D! [processors.converter] "+format, v...)
 }
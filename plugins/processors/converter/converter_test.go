@@ -464,6 +464,83 @@ func TestConverter(t *testing.T) {
 				),
 			),
 		},
+		{
+			name: "from tag matching several types",
+			converter: &Converter{
+				Tags: &Conversion{
+					Integer:  []string{"*"},
+					Unsigned: []string{"*"},
+					Float:    []string{"*"},
+					Duration: []string{"*"},
+				},
+			},
+			input: Metric(
+				metric.New(
+					"cpu",
+					map[string]string{
+						"a": "42",
+					},
+					map[string]interface{}{},
+					time.Unix(0, 0),
+				),
+			),
+			expected: Metric(
+				metric.New(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"a": int64(42),
+					},
+					time.Unix(0, 0),
+				),
+			),
+		},
+		{
+			name: "units",
+			converter: &Converter{
+				Tags: &Conversion{
+					Bytes: []string{"limit"},
+				},
+				Fields: &Conversion{
+					Duration: []string{"latency", "elapsed"},
+					Bytes:    []string{"size_*"},
+				},
+			},
+			input: Metric(
+				metric.New(
+					"cpu",
+					map[string]string{
+						"limit": "2Gi",
+					},
+					map[string]interface{}{
+						"latency":   "10ms",
+						"elapsed":   int64(3),
+						"size_a":    "1.5 KB",
+						"size_b":    "512",
+						"size_c":    "3MiB",
+						"size_d":    "10 parsecs",
+						"unchanged": "10ms",
+					},
+					time.Unix(0, 0),
+				),
+			),
+			expected: Metric(
+				metric.New(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"limit":     int64(2147483648),
+						"latency":   0.01,
+						"elapsed":   3.0,
+						"size_a":    int64(1500),
+						"size_b":    int64(512),
+						"size_c":    int64(3145728),
+						"unchanged": "10ms",
+					},
+					time.Unix(0, 0),
+				),
+			),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
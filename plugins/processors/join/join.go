@@ -0,0 +1,248 @@
+package join
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Measurements to join.  Each metric of the left measurement is joined
+  ## with the metric of the right measurement closest in time.
+  left = "procstat"
+  right = "cpu"
+
+  ## Tags that must be equal in the joined metrics.
+  tags = ["host"]
+
+  ## Maximum difference between the timestamps of the joined metrics.
+  # tolerance = "5s"
+
+  ## How long metrics are kept waiting for a match.
+  # buffer = "30s"
+
+  ## Name of the joined measurement, by default "<left>_<right>".
+  # name = ""
+
+  ## Prefix of the fields of the right measurement in the joined metric, by
+  ## default "<right>_".
+  # right_field_prefix = ""
+`
+
+type Join struct {
+	Left             string            `toml:"left"`
+	Right            string            `toml:"right"`
+	Tags             []string          `toml:"tags"`
+	Tolerance        internal.Duration `toml:"tolerance"`
+	Buffer           internal.Duration `toml:"buffer"`
+	Name             string            `toml:"name"`
+	RightFieldPrefix string            `toml:"right_field_prefix"`
+
+	initialized bool
+	lefts       map[string][]entry
+	rights      map[string][]entry
+	now         func() time.Time
+}
+
+// entry is a buffered metric and the time it was received.
+type entry struct {
+	metric   telegraf.Metric
+	received time.Time
+}
+
+func NewJoin() *Join {
+	return &Join{
+		Tolerance: internal.Duration{Duration: 5 * time.Second},
+		Buffer:    internal.Duration{Duration: 30 * time.Second},
+		now:       time.Now,
+	}
+}
+
+func (j *Join) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Join) Description() string {
+	return "Join the fields of two measurements with matching tags and close timestamps"
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (j *Join) Validate() error {
+	return j.init()
+}
+
+func (j *Join) init() error {
+	if j.Left == "" || j.Right == "" {
+		return fmt.Errorf("left and right measurements are required")
+	}
+	if j.Left == j.Right {
+		return fmt.Errorf("left and right measurements must be different")
+	}
+	if j.Name == "" {
+		j.Name = j.Left + "_" + j.Right
+	}
+	if j.RightFieldPrefix == "" {
+		j.RightFieldPrefix = j.Right + "_"
+	}
+
+	j.lefts = make(map[string][]entry)
+	j.rights = make(map[string][]entry)
+	j.initialized = true
+	return nil
+}
+
+func (j *Join) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !j.initialized {
+		if err := j.init(); err != nil {
+			log.Printf("E! [processors.join] Invalid configuration, not joining metrics: %s", err)
+			return in
+		}
+	}
+
+	now := j.now()
+	j.expire(now)
+
+	out := in
+	for _, m := range in {
+		var isLeft bool
+		switch m.Name() {
+		case j.Left:
+			isLeft = true
+		case j.Right:
+		default:
+			continue
+		}
+
+		key, ok := j.key(m)
+		if !ok {
+			continue
+		}
+
+		if isLeft {
+			right, ok := j.closest(j.rights[key], m.Time())
+			if !ok {
+				j.lefts[key] = append(j.lefts[key], entry{m.Copy(), now})
+				continue
+			}
+			out = j.appendJoined(out, m, right)
+			continue
+		}
+
+		// A right metric can be joined with many left metrics, such as the
+		// cpu usage of a host with each of its processes.
+		right := m.Copy()
+		j.rights[key] = append(j.rights[key], entry{right, now})
+
+		pending := j.lefts[key][:0]
+		for _, e := range j.lefts[key] {
+			if abs(e.metric.Time().Sub(right.Time())) > j.Tolerance.Duration {
+				pending = append(pending, e)
+				continue
+			}
+			out = j.appendJoined(out, e.metric, right)
+		}
+		j.setEntries(j.lefts, key, pending)
+	}
+	return out
+}
+
+// key returns the values of the join tags of the metric, and false if the
+// metric does not have all the join tags.
+func (j *Join) key(m telegraf.Metric) (string, bool) {
+	values := make([]string, 0, len(j.Tags))
+	for _, tag := range j.Tags {
+		value, ok := m.GetTag(tag)
+		if !ok {
+			return "", false
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+// closest returns the metric of the entries closest to t within the
+// tolerance.
+func (j *Join) closest(entries []entry, t time.Time) (telegraf.Metric, bool) {
+	var best telegraf.Metric
+	var bestDiff time.Duration
+	for _, e := range entries {
+		diff := abs(e.metric.Time().Sub(t))
+		if diff > j.Tolerance.Duration {
+			continue
+		}
+		if best == nil || diff < bestDiff {
+			best = e.metric
+			bestDiff = diff
+		}
+	}
+	return best, best != nil
+}
+
+// appendJoined appends the metric with the tags and fields of the left
+// metric, completed by the tags and the prefixed fields of the right one.
+func (j *Join) appendJoined(out []telegraf.Metric, left, right telegraf.Metric) []telegraf.Metric {
+	tags := left.Tags()
+	for k, v := range right.Tags() {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	fields := left.Fields()
+	for k, v := range right.Fields() {
+		k = j.RightFieldPrefix + k
+		if _, ok := fields[k]; !ok {
+			fields[k] = v
+		}
+	}
+
+	m, err := metric.New(j.Name, tags, fields, left.Time())
+	if err != nil {
+		log.Printf("E! [processors.join] Unable to create joined metric: %s", err)
+		return out
+	}
+	return append(out, m)
+}
+
+// expire removes the metrics received before the buffer duration.  Left
+// metrics that expire were not joined.
+func (j *Join) expire(now time.Time) {
+	for _, entries := range []map[string][]entry{j.lefts, j.rights} {
+		for key, es := range entries {
+			kept := es[:0]
+			for _, e := range es {
+				if now.Sub(e.received) < j.Buffer.Duration {
+					kept = append(kept, e)
+				}
+			}
+			j.setEntries(entries, key, kept)
+		}
+	}
+}
+
+func (j *Join) setEntries(entries map[string][]entry, key string, es []entry) {
+	if len(es) == 0 {
+		delete(entries, key)
+		return
+	}
+	entries[key] = es
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func init() {
+	processors.Add("join", func() telegraf.Processor {
+		return NewJoin()
+	})
+}
@@ -0,0 +1,146 @@
+package join
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newProcstat(host, process string, sec int64) telegraf.Metric {
+	m, _ := metric.New("procstat",
+		map[string]string{"host": host, "process_name": process},
+		map[string]interface{}{"cpu_usage": 12.5},
+		time.Unix(sec, 0),
+	)
+	return m
+}
+
+func newCPU(host string, sec int64) telegraf.Metric {
+	m, _ := metric.New("cpu",
+		map[string]string{"host": host, "cpu": "cpu-total"},
+		map[string]interface{}{"usage_user": 40.0, "usage_idle": 55.0},
+		time.Unix(sec, 0),
+	)
+	return m
+}
+
+func newJoin(now *time.Time) *Join {
+	j := NewJoin()
+	j.Left = "procstat"
+	j.Right = "cpu"
+	j.Tags = []string{"host"}
+	j.now = func() time.Time { return *now }
+	return j
+}
+
+func TestJoinRightFirst(t *testing.T) {
+	now := time.Unix(100, 0)
+	j := newJoin(&now)
+
+	out := j.Apply(newCPU("a", 100), newProcstat("a", "nginx", 101), newProcstat("a", "mysqld", 101))
+	require.Len(t, out, 5)
+
+	joined := out[3]
+	require.Equal(t, "procstat_cpu", joined.Name())
+	require.Equal(t, map[string]string{
+		"host":         "a",
+		"process_name": "nginx",
+		"cpu":          "cpu-total",
+	}, joined.Tags())
+	require.Equal(t, map[string]interface{}{
+		"cpu_usage":      12.5,
+		"cpu_usage_user": 40.0,
+		"cpu_usage_idle": 55.0,
+	}, joined.Fields())
+	require.Equal(t, time.Unix(101, 0), joined.Time())
+	require.Equal(t, "mysqld", out[4].Tags()["process_name"])
+}
+
+func TestJoinLeftFirst(t *testing.T) {
+	now := time.Unix(100, 0)
+	j := newJoin(&now)
+
+	require.Len(t, j.Apply(newProcstat("a", "nginx", 100)), 1)
+
+	now = now.Add(time.Second)
+	out := j.Apply(newCPU("a", 101))
+	require.Len(t, out, 2)
+	require.Equal(t, "procstat_cpu", out[1].Name())
+	require.Equal(t, "nginx", out[1].Tags()["process_name"])
+	require.Equal(t, time.Unix(100, 0), out[1].Time())
+
+	// Left metrics are joined only once.
+	require.Len(t, j.Apply(newCPU("a", 101)), 1)
+}
+
+func TestJoinClosest(t *testing.T) {
+	now := time.Unix(100, 0)
+	j := newJoin(&now)
+
+	early := newCPU("a", 96)
+	early.AddField("usage_user", 10.0)
+	j.Apply(early, newCPU("a", 104))
+
+	out := j.Apply(newProcstat("a", "nginx", 103))
+	require.Len(t, out, 2)
+	require.Equal(t, 40.0, out[1].Fields()["cpu_usage_user"])
+}
+
+func TestJoinTags(t *testing.T) {
+	now := time.Unix(100, 0)
+	j := newJoin(&now)
+
+	out := j.Apply(newCPU("a", 100), newProcstat("b", "nginx", 100))
+	require.Len(t, out, 2)
+
+	// Metrics without the join tags are not joined.
+	j.Tags = []string{"host", "dc"}
+	require.Len(t, j.Apply(newCPU("a", 100), newProcstat("a", "nginx", 100)), 2)
+}
+
+func TestJoinTolerance(t *testing.T) {
+	now := time.Unix(100, 0)
+	j := newJoin(&now)
+
+	require.Len(t, j.Apply(newCPU("a", 100), newProcstat("a", "nginx", 106)), 2)
+	require.Len(t, j.Apply(newProcstat("a", "nginx", 105)), 2)
+}
+
+func TestJoinBuffer(t *testing.T) {
+	now := time.Unix(100, 0)
+	j := newJoin(&now)
+
+	j.Apply(newProcstat("a", "nginx", 100))
+	now = now.Add(30 * time.Second)
+	require.Len(t, j.Apply(newCPU("a", 100)), 1)
+
+	now = now.Add(30 * time.Second)
+	require.Len(t, j.Apply(newProcstat("a", "nginx", 100)), 1)
+	require.Empty(t, j.rights)
+	require.Len(t, j.lefts, 1)
+}
+
+func TestJoinOptions(t *testing.T) {
+	now := time.Unix(100, 0)
+	j := newJoin(&now)
+	j.Name = "process"
+	j.RightFieldPrefix = "host_"
+
+	out := j.Apply(newCPU("a", 100), newProcstat("a", "nginx", 100))
+	require.Len(t, out, 3)
+	require.Equal(t, "process", out[2].Name())
+	require.Equal(t, map[string]interface{}{
+		"cpu_usage":       12.5,
+		"host_usage_user": 40.0,
+		"host_usage_idle": 55.0,
+	}, out[2].Fields())
+}
+
+func TestInvalidConfig(t *testing.T) {
+	j := &Join{Left: "cpu", Right: "cpu"}
+	in := []telegraf.Metric{newCPU("a", 100)}
+	require.Equal(t, in, j.Apply(in...))
+}
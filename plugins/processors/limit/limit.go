@@ -0,0 +1,154 @@
+package limit
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Maximum number of tags and fields to preserve on each metric, 0 means
+  ## no limit.
+  tag_limit = 10
+  # field_limit = 0
+
+  ## Tags and fields that are always preserved.  They count towards the
+  ## limit, the remaining slots are given to the other keys in sorted order.
+  # keep_tags = ["host", "region"]
+  # keep_fields = []
+
+  ## What to do with the tags and fields over the limit:
+  ##   "drop"  - remove them from the metric
+  ##   "merge" - remove them and store them as key=value pairs in a single
+  ##             string field named by overflow_field
+  # overflow = "drop"
+  # overflow_field = "overflow"
+`
+
+type Limit struct {
+	TagLimit      int      `toml:"tag_limit"`
+	FieldLimit    int      `toml:"field_limit"`
+	KeepTags      []string `toml:"keep_tags"`
+	KeepFields    []string `toml:"keep_fields"`
+	Overflow      string   `toml:"overflow"`
+	OverflowField string   `toml:"overflow_field"`
+
+	initialized bool
+	keepTags    map[string]bool
+	keepFields  map[string]bool
+	merge       bool
+}
+
+func (l *Limit) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Limit) Description() string {
+	return "Restricts the number of tags and fields that can pass through this filter."
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (l *Limit) Validate() error {
+	return l.init()
+}
+
+func (l *Limit) init() error {
+	switch l.Overflow {
+	case "", "drop":
+	case "merge":
+		l.merge = true
+	default:
+		return fmt.Errorf("unknown overflow %q, must be \"drop\" or \"merge\"", l.Overflow)
+	}
+
+	l.keepTags = make(map[string]bool, len(l.KeepTags))
+	for _, key := range l.KeepTags {
+		l.keepTags[key] = true
+	}
+	l.keepFields = make(map[string]bool, len(l.KeepFields))
+	for _, key := range l.KeepFields {
+		l.keepFields[key] = true
+	}
+
+	if l.OverflowField == "" {
+		l.OverflowField = "overflow"
+	}
+	l.initialized = true
+	return nil
+}
+
+func (l *Limit) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !l.initialized {
+		if err := l.init(); err != nil {
+			log.Printf("E! [processors.limit] Invalid configuration, not limiting metrics: %s", err)
+			return in
+		}
+	}
+
+	for _, metric := range in {
+		var overflow []string
+
+		if l.TagLimit > 0 && len(metric.TagList()) > l.TagLimit {
+			keys := make([]string, 0, len(metric.TagList()))
+			for _, tag := range metric.TagList() {
+				keys = append(keys, tag.Key)
+			}
+			for _, key := range overLimit(keys, l.keepTags, l.TagLimit) {
+				value, _ := metric.GetTag(key)
+				overflow = append(overflow, key+"="+value)
+				metric.RemoveTag(key)
+			}
+		}
+
+		if l.FieldLimit > 0 && len(metric.FieldList()) > l.FieldLimit {
+			keys := make([]string, 0, len(metric.FieldList()))
+			for _, field := range metric.FieldList() {
+				keys = append(keys, field.Key)
+			}
+			for _, key := range overLimit(keys, l.keepFields, l.FieldLimit) {
+				value, _ := metric.GetField(key)
+				overflow = append(overflow, fmt.Sprintf("%s=%v", key, value))
+				metric.RemoveField(key)
+			}
+		}
+
+		if l.merge && len(overflow) > 0 {
+			metric.AddField(l.OverflowField, strings.Join(overflow, ","))
+		}
+	}
+	return in
+}
+
+// overLimit returns the sorted keys that do not fit within limit, after
+// reserving space for the keys in keep.
+func overLimit(items []string, keep map[string]bool, limit int) []string {
+	var keys []string
+	remaining := limit
+	for _, key := range items {
+		if keep[key] {
+			remaining--
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining >= len(keys) {
+		return nil
+	}
+	return keys[remaining:]
+}
+
+func init() {
+	processors.Add("limit", func() telegraf.Processor {
+		return &Limit{}
+	})
+}
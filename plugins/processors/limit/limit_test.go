@@ -0,0 +1,93 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric() telegraf.Metric {
+	m, _ := metric.New("foo",
+		map[string]string{
+			"a": "1",
+			"b": "2",
+			"c": "3",
+			"d": "4",
+		},
+		map[string]interface{}{
+			"x": int64(1),
+			"y": 2.5,
+			"z": "three",
+		},
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+func TestUnderLimit(t *testing.T) {
+	l := &Limit{TagLimit: 4, FieldLimit: 3}
+	m := l.Apply(newMetric())[0]
+
+	require.Len(t, m.TagList(), 4)
+	require.Len(t, m.FieldList(), 3)
+}
+
+func TestTagLimitDrop(t *testing.T) {
+	l := &Limit{TagLimit: 2}
+	m := l.Apply(newMetric())[0]
+
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, m.Tags())
+	require.Len(t, m.FieldList(), 3)
+}
+
+func TestTagLimitKeep(t *testing.T) {
+	l := &Limit{TagLimit: 2, KeepTags: []string{"d"}}
+	m := l.Apply(newMetric())[0]
+
+	require.Equal(t, map[string]string{"a": "1", "d": "4"}, m.Tags())
+}
+
+func TestKeepExceedsLimit(t *testing.T) {
+	l := &Limit{TagLimit: 1, KeepTags: []string{"c", "d"}}
+	m := l.Apply(newMetric())[0]
+
+	require.Equal(t, map[string]string{"c": "3", "d": "4"}, m.Tags())
+}
+
+func TestFieldLimitDrop(t *testing.T) {
+	l := &Limit{FieldLimit: 1, KeepFields: []string{"z"}}
+	m := l.Apply(newMetric())[0]
+
+	require.Equal(t, map[string]interface{}{"z": "three"}, m.Fields())
+}
+
+func TestMerge(t *testing.T) {
+	l := &Limit{
+		TagLimit:   2,
+		FieldLimit: 1,
+		Overflow:   "merge",
+	}
+	m := l.Apply(newMetric())[0]
+
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, m.Tags())
+	require.Equal(t, map[string]interface{}{
+		"x":        int64(1),
+		"overflow": "c=3,d=4,y=2.5,z=three",
+	}, m.Fields())
+}
+
+func TestUnknownOverflow(t *testing.T) {
+	plugin := &Limit{
+		TagLimit: 1,
+		Overflow: "truncate",
+	}
+	require.EqualError(t, plugin.Validate(),
+		`unknown overflow "truncate", must be "drop" or "merge"`)
+
+	// the metrics are not modified
+	m := plugin.Apply(newMetric())[0]
+	require.Len(t, m.TagList(), 4)
+}
@@ -0,0 +1,231 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Maximum number of metrics of each series within any window.
+  limit = 100
+  window = "1m"
+
+  ## Tags identifying a series, in addition to the measurement name.  By
+  ## default all tags are used.
+  # series_tags = ["appname", "hostname"]
+
+  ## What to do with the metrics over the limit:
+  ##   "drop"      - drop them
+  ##   "summarize" - drop them and emit a "<measurement>_ratelimit" metric
+  ##                 with the number of dropped metrics of the series once
+  ##                 per window
+  # action = "drop"
+`
+
+type RateLimit struct {
+	Limit      int               `toml:"limit"`
+	Window     internal.Duration `toml:"window"`
+	SeriesTags []string          `toml:"series_tags"`
+	Action     string            `toml:"action"`
+
+	initialized bool
+	summarize   bool
+	series      map[string]*series
+	now         func() time.Time
+}
+
+// series is the state of a series: the times of the accepted metrics
+// within the window, in a ring buffer, and the dropped metrics.
+type series struct {
+	name string
+	tags map[string]string
+
+	times []time.Time
+	next  int
+
+	dropped      int64
+	droppedSince time.Time
+}
+
+func NewRateLimit() *RateLimit {
+	return &RateLimit{
+		Limit:  100,
+		Window: internal.Duration{Duration: time.Minute},
+		Action: "drop",
+		now:    time.Now,
+	}
+}
+
+func (r *RateLimit) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RateLimit) Description() string {
+	return "Limit the number of metrics of each series within a sliding window"
+}
+
+// Validate checks the configuration, which Apply otherwise handles by not
+// limiting the metrics or by dropping them.
+func (r *RateLimit) Validate() error {
+	switch r.Action {
+	case "", "drop", "summarize":
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	if r.Limit <= 0 || r.Window.Duration <= 0 {
+		return fmt.Errorf("limit and window must be positive, metrics are not limited otherwise")
+	}
+	return nil
+}
+
+func (r *RateLimit) init() {
+	switch r.Action {
+	case "", "drop":
+	case "summarize":
+		r.summarize = true
+	default:
+		log.Printf("E! [processors.ratelimit] Unknown action %q, using \"drop\"", r.Action)
+	}
+	r.series = make(map[string]*series)
+	r.initialized = true
+}
+
+func (r *RateLimit) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !r.initialized {
+		r.init()
+	}
+	if r.Limit <= 0 || r.Window.Duration <= 0 {
+		return in
+	}
+
+	now := r.now()
+	out := in[:0:0]
+	for _, m := range in {
+		id, tags := r.seriesID(m)
+		s, ok := r.series[id]
+		if !ok {
+			s = &series{
+				name:  m.Name(),
+				tags:  tags,
+				times: make([]time.Time, 0, r.Limit),
+			}
+			r.series[id] = s
+		}
+
+		if !s.accept(now, r.Limit, r.Window.Duration) {
+			if s.dropped == 0 {
+				s.droppedSince = now
+			}
+			s.dropped++
+			continue
+		}
+
+		// Report the dropped metrics before the series resumes.
+		if s.dropped > 0 {
+			out = r.appendSummary(out, s, now)
+		}
+		out = append(out, m)
+	}
+
+	for id, s := range r.series {
+		if s.dropped > 0 && now.Sub(s.droppedSince) >= r.Window.Duration {
+			out = r.appendSummary(out, s, now)
+		}
+		if s.dropped == 0 && s.idle(now, r.Window.Duration) {
+			delete(r.series, id)
+		}
+	}
+	return out
+}
+
+// seriesID returns the key and the tags of the series of the metric.
+func (r *RateLimit) seriesID(m telegraf.Metric) (string, map[string]string) {
+	tags := m.Tags()
+	if len(r.SeriesTags) > 0 {
+		tags = make(map[string]string, len(r.SeriesTags))
+		for _, key := range r.SeriesTags {
+			if value, ok := m.GetTag(key); ok {
+				tags[key] = value
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, 2*len(keys)+1)
+	parts = append(parts, m.Name())
+	for _, k := range keys {
+		parts = append(parts, k, tags[k])
+	}
+	return strings.Join(parts, "\x00"), tags
+}
+
+// appendSummary appends the summary of the dropped metrics of the series
+// if summarizing, and resets the count of dropped metrics.
+func (r *RateLimit) appendSummary(out []telegraf.Metric, s *series, now time.Time) []telegraf.Metric {
+	dropped := s.dropped
+	s.dropped = 0
+	if !r.summarize {
+		return out
+	}
+
+	tags := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	m, err := metric.New(s.name+"_ratelimit", tags,
+		map[string]interface{}{"dropped": dropped}, now)
+	if err != nil {
+		log.Printf("E! [processors.ratelimit] Unable to create summary: %s", err)
+		return out
+	}
+	return append(out, m)
+}
+
+// accept records a metric at now and returns true if the series has less
+// than limit metrics within the window ending at now.
+func (s *series) accept(now time.Time, limit int, window time.Duration) bool {
+	if len(s.times) < limit {
+		s.times = append(s.times, now)
+		return true
+	}
+
+	// The oldest accepted metric is the next one to be replaced.
+	if now.Sub(s.times[s.next]) < window {
+		return false
+	}
+	s.times[s.next] = now
+	s.next = (s.next + 1) % limit
+	return true
+}
+
+// idle returns true if no metric of the series was accepted within the
+// window ending at now.
+func (s *series) idle(now time.Time, window time.Duration) bool {
+	if len(s.times) == 0 {
+		return true
+	}
+	last := s.next - 1
+	if last < 0 {
+		last = len(s.times) - 1
+	}
+	return now.Sub(s.times[last]) >= window
+}
+
+func init() {
+	processors.Add("ratelimit", func() telegraf.Processor {
+		return NewRateLimit()
+	})
+}
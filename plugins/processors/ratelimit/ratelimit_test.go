@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(host string) telegraf.Metric {
+	m, _ := metric.New("syslog",
+		map[string]string{"hostname": host, "appname": "cron"},
+		map[string]interface{}{"message": "loop"},
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+func newRateLimit(now *time.Time) *RateLimit {
+	r := NewRateLimit()
+	r.Limit = 2
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestDrop(t *testing.T) {
+	now := time.Unix(100, 0)
+	r := newRateLimit(&now)
+
+	out := r.Apply(newMetric("a"), newMetric("a"), newMetric("a"), newMetric("b"))
+	require.Len(t, out, 3)
+	require.Equal(t, "b", out[2].Tags()["hostname"])
+
+	now = now.Add(30 * time.Second)
+	require.Empty(t, r.Apply(newMetric("a")))
+
+	// The window slides past the first metrics.
+	now = now.Add(30 * time.Second)
+	require.Len(t, r.Apply(newMetric("a"), newMetric("a"), newMetric("a")), 2)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	now := time.Unix(100, 0)
+	r := newRateLimit(&now)
+
+	require.Len(t, r.Apply(newMetric("a")), 1)
+	now = now.Add(40 * time.Second)
+	require.Len(t, r.Apply(newMetric("a")), 1)
+	now = now.Add(40 * time.Second)
+	// Only the first metric left the window.
+	require.Len(t, r.Apply(newMetric("a"), newMetric("a")), 1)
+}
+
+func TestSeriesTags(t *testing.T) {
+	now := time.Unix(100, 0)
+	r := newRateLimit(&now)
+	r.SeriesTags = []string{"appname"}
+
+	out := r.Apply(newMetric("a"), newMetric("b"), newMetric("c"))
+	require.Len(t, out, 2)
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Unix(100, 0)
+	r := newRateLimit(&now)
+	r.Action = "summarize"
+
+	out := r.Apply(newMetric("a"), newMetric("a"), newMetric("a"), newMetric("a"))
+	require.Len(t, out, 2)
+
+	// The summary is emitted once the series accepts metrics again.
+	now = now.Add(time.Minute)
+	out = r.Apply(newMetric("a"))
+	require.Len(t, out, 2)
+	require.Equal(t, "syslog_ratelimit", out[0].Name())
+	require.Equal(t, map[string]string{"hostname": "a", "appname": "cron"}, out[0].Tags())
+	require.Equal(t, map[string]interface{}{"dropped": int64(2)}, out[0].Fields())
+	require.Equal(t, now, out[0].Time())
+	require.Equal(t, "syslog", out[1].Name())
+}
+
+func TestSummarizeContinuousOverflow(t *testing.T) {
+	now := time.Unix(100, 0)
+	r := newRateLimit(&now)
+	r.Action = "summarize"
+	r.SeriesTags = []string{"hostname"}
+
+	require.Len(t, r.Apply(newMetric("a"), newMetric("a"), newMetric("a")), 2)
+
+	// A series over the limit is summarized once per window.
+	now = now.Add(30 * time.Second)
+	require.Len(t, r.Apply(newMetric("b"), newMetric("b"), newMetric("b")), 2)
+	now = now.Add(29 * time.Second)
+	require.Empty(t, r.Apply(newMetric("b")))
+
+	now = now.Add(time.Second)
+	out := r.Apply(newMetric("c"))
+	require.Len(t, out, 2)
+	require.Equal(t, "c", out[0].Tags()["hostname"])
+	require.Equal(t, "syslog_ratelimit", out[1].Name())
+	require.Equal(t, map[string]string{"hostname": "a"}, out[1].Tags())
+	require.Equal(t, map[string]interface{}{"dropped": int64(1)}, out[1].Fields())
+}
+
+func TestExpireIdleSeries(t *testing.T) {
+	now := time.Unix(100, 0)
+	r := newRateLimit(&now)
+
+	r.Apply(newMetric("a"), newMetric("b"))
+	require.Len(t, r.series, 2)
+
+	now = now.Add(time.Minute)
+	r.Apply(newMetric("b"))
+	require.Len(t, r.series, 1)
+}
+
+func TestUnlimited(t *testing.T) {
+	now := time.Unix(100, 0)
+	r := newRateLimit(&now)
+	r.Limit = 0
+
+	require.Len(t, r.Apply(newMetric("a"), newMetric("a"), newMetric("a")), 3)
+}
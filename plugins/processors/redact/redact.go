@@ -0,0 +1,271 @@
+package redact
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Tags and fields to inspect, glob patterns are supported.  Only string
+  ## values are processed.
+  tags = ["*"]
+  fields = ["*"]
+
+  ## Sensitive data to look for in the values, one or more of
+  ## "credit_card", "email" and "token".  Matches are processed and the rest
+  ## of the value is preserved.  If no patterns are set the whole value of
+  ## the selected tags and fields is processed.
+  patterns = ["credit_card", "email", "token"]
+
+  ## Additional regular expressions of sensitive data.
+  # custom_patterns = []
+
+  ## What to do with the sensitive data:
+  ##   "redact"  - replace it with the replacement string
+  ##   "encrypt" - replace it with "enc:" followed by the base64 encoded
+  ##               AES-GCM encryption of the data
+  # action = "redact"
+  # replacement = "[REDACTED]"
+
+  ## Hex encoded AES key of 16, 24 or 32 bytes, used by the "encrypt"
+  ## action.  Use an environment variable, such as "$TELEGRAF_REDACT_KEY",
+  ## or a key_file instead of writing the key in the configuration.
+  # key = ""
+  # key_file = "/etc/telegraf/redact.key"
+`
+
+// builtinPatterns are the regular expressions of the well known sensitive
+// data.
+var builtinPatterns = map[string]string{
+	// Sequences of 13 to 19 digits, optionally separated by spaces or
+	// dashes; candidates are confirmed with the Luhn checksum.
+	"credit_card": `\b(?:\d[ -]?){12,18}\d\b`,
+	"email":       `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	// JSON web tokens, bearer tokens of authorization headers and the
+	// values of parameters named like credentials.
+	"token": `eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*` +
+		`|(?i:bearer)\s+[A-Za-z0-9\-._~+/]+=*` +
+		`|(?i:(?:api[_\-]?key|access[_\-]?token|token|secret|passw(?:or)?d))[=:]\s*[^\s&,;"']+`,
+}
+
+type Redact struct {
+	Tags           []string `toml:"tags"`
+	Fields         []string `toml:"fields"`
+	Patterns       []string `toml:"patterns"`
+	CustomPatterns []string `toml:"custom_patterns"`
+	Action         string   `toml:"action"`
+	Replacement    string   `toml:"replacement"`
+	Key            string   `toml:"key"`
+	KeyFile        string   `toml:"key_file"`
+
+	initialized bool
+	tagFilter   filter.Filter
+	fieldFilter filter.Filter
+	patterns    []*regexp.Regexp
+	luhn        map[*regexp.Regexp]bool
+	aead        cipher.AEAD
+}
+
+func (r *Redact) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Redact) Description() string {
+	return "Redact or encrypt sensitive data in tags and fields"
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (r *Redact) Validate() error {
+	return r.init()
+}
+
+func (r *Redact) init() error {
+	tagFilter, err := filter.Compile(r.Tags)
+	if err != nil {
+		return fmt.Errorf("tags: %s", err)
+	}
+	fieldFilter, err := filter.Compile(r.Fields)
+	if err != nil {
+		return fmt.Errorf("fields: %s", err)
+	}
+
+	var patterns []*regexp.Regexp
+	luhn := make(map[*regexp.Regexp]bool)
+	for _, name := range r.Patterns {
+		pattern, ok := builtinPatterns[name]
+		if !ok {
+			return fmt.Errorf("unknown pattern %q", name)
+		}
+		re := regexp.MustCompile(pattern)
+		patterns = append(patterns, re)
+		luhn[re] = name == "credit_card"
+	}
+	for _, pattern := range r.CustomPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("custom pattern %q: %s", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	var aead cipher.AEAD
+	switch r.Action {
+	case "", "redact":
+	case "encrypt":
+		aead, err = r.newAEAD()
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+
+	r.tagFilter = tagFilter
+	r.fieldFilter = fieldFilter
+	r.patterns = patterns
+	r.luhn = luhn
+	r.aead = aead
+	r.initialized = true
+	return nil
+}
+
+// newAEAD creates the AES-GCM cipher of the configured key.
+func (r *Redact) newAEAD() (cipher.AEAD, error) {
+	key := r.Key
+	if r.KeyFile != "" {
+		b, err := ioutil.ReadFile(r.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading key file: %s", err)
+		}
+		key = string(b)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("the encrypt action requires a key or key_file")
+	}
+
+	b, err := hex.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("key is not hex encoded: %s", err)
+	}
+	block, err := aes.NewCipher(b)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (r *Redact) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !r.initialized {
+		if err := r.init(); err != nil {
+			// Metrics are dropped rather than sent with the sensitive data.
+			log.Printf("E! [processors.redact] Dropping metrics, invalid configuration: %s", err)
+			return nil
+		}
+	}
+
+	for _, metric := range in {
+		if r.tagFilter != nil {
+			for _, tag := range metric.TagList() {
+				if !r.tagFilter.Match(tag.Key) {
+					continue
+				}
+				if value, ok := r.process(tag.Value); ok {
+					metric.AddTag(tag.Key, value)
+				}
+			}
+		}
+
+		if r.fieldFilter != nil {
+			for _, field := range metric.FieldList() {
+				s, ok := field.Value.(string)
+				if !ok || !r.fieldFilter.Match(field.Key) {
+					continue
+				}
+				if value, ok := r.process(s); ok {
+					metric.AddField(field.Key, value)
+				}
+			}
+		}
+	}
+	return in
+}
+
+// process returns the value with the sensitive data redacted or encrypted,
+// and whether the value was changed.
+func (r *Redact) process(value string) (string, bool) {
+	if len(r.patterns) == 0 {
+		return r.replace(value), true
+	}
+
+	changed := false
+	for _, re := range r.patterns {
+		luhn := r.luhn[re]
+		value = re.ReplaceAllStringFunc(value, func(match string) string {
+			if luhn && !luhnValid(match) {
+				return match
+			}
+			changed = true
+			return r.replace(match)
+		})
+	}
+	return value, changed
+}
+
+func (r *Redact) replace(data string) string {
+	if r.aead == nil {
+		if r.Replacement == "" {
+			return "[REDACTED]"
+		}
+		return r.Replacement
+	}
+
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		log.Printf("E! [processors.redact] Unable to generate nonce, redacting value: %s", err)
+		return "[REDACTED]"
+	}
+	sealed := r.aead.Seal(nonce, nonce, []byte(data), nil)
+	return "enc:" + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// luhnValid checks the Luhn checksum of the digits in s.
+func luhnValid(s string) bool {
+	sum := 0
+	n := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+func init() {
+	processors.Add("redact", func() telegraf.Processor {
+		return &Redact{}
+	})
+}
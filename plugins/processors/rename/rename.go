@@ -0,0 +1,170 @@
+package rename
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Replacements are applied in the order they are defined.  Each
+  ## replacement matches exactly one of measurement, tag or field, using glob
+  ## patterns, and sets it to dest, which is required.  Invalid replacements
+  ## are rejected when loading the configuration.
+
+  ## Rename a measurement:
+  # [[processors.rename.replace]]
+  #   measurement = "network_interface_throughput"
+  #   dest = "throughput"
+
+  ## Rename a tag key:
+  # [[processors.rename.replace]]
+  #   tag = "hostname"
+  #   dest = "host"
+
+  ## Replace the values of a tag, when value is set the tag key is kept:
+  # [[processors.rename.replace]]
+  #   tag = "env"
+  #   value = "prod*"
+  #   dest = "production"
+
+  ## Rename a field key:
+  # [[processors.rename.replace]]
+  #   field = "lower"
+  #   dest = "min"
+`
+
+var (
+	errMissingKey  = errors.New("one of measurement, tag or field is required")
+	errMissingDest = errors.New("dest is required")
+)
+
+type Replace struct {
+	Measurement string `toml:"measurement"`
+	Tag         string `toml:"tag"`
+	Field       string `toml:"field"`
+	Value       string `toml:"value"`
+	Dest        string `toml:"dest"`
+
+	key   filter.Filter
+	value filter.Filter
+}
+
+type Rename struct {
+	Replaces []*Replace `toml:"replace"`
+
+	initialized bool
+}
+
+func (r *Rename) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rename) Description() string {
+	return "Rename measurements, tags, and fields that pass through this filter."
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (r *Rename) Validate() error {
+	return r.init()
+}
+
+func (r *Rename) init() error {
+	for i, replace := range r.Replaces {
+		if err := replace.compile(); err != nil {
+			return fmt.Errorf("invalid replacement %d: %v", i+1, err)
+		}
+	}
+	r.initialized = true
+	return nil
+}
+
+func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !r.initialized {
+		if err := r.init(); err != nil {
+			log.Printf("E! [processors.rename] Invalid configuration, not renaming metrics: %s", err)
+			return in
+		}
+	}
+
+	for _, metric := range in {
+		for _, replace := range r.Replaces {
+			replace.apply(metric)
+		}
+	}
+	return in
+}
+
+func (r *Replace) compile() error {
+	var err error
+	switch {
+	case r.Measurement != "":
+		r.key, err = filter.Compile([]string{r.Measurement})
+	case r.Tag != "":
+		r.key, err = filter.Compile([]string{r.Tag})
+		if err == nil && r.Value != "" {
+			r.value, err = filter.Compile([]string{r.Value})
+		}
+	case r.Field != "":
+		r.key, err = filter.Compile([]string{r.Field})
+	default:
+		return errMissingKey
+	}
+	if err != nil {
+		return err
+	}
+	if r.Dest == "" {
+		return errMissingDest
+	}
+	return nil
+}
+
+func (r *Replace) apply(metric telegraf.Metric) {
+	switch {
+	case r.Measurement != "":
+		if r.key.Match(metric.Name()) {
+			metric.SetName(r.Dest)
+		}
+	case r.Tag != "":
+		// Copy the matching tags before modifying, since the tag list is
+		// changed by RemoveTag and AddTag.
+		var tags []telegraf.Tag
+		for _, tag := range metric.TagList() {
+			if r.key.Match(tag.Key) {
+				tags = append(tags, *tag)
+			}
+		}
+		for _, tag := range tags {
+			if r.value != nil {
+				if r.value.Match(tag.Value) {
+					metric.AddTag(tag.Key, r.Dest)
+				}
+				continue
+			}
+			metric.RemoveTag(tag.Key)
+			metric.AddTag(r.Dest, tag.Value)
+		}
+	case r.Field != "":
+		var fields []telegraf.Field
+		for _, field := range metric.FieldList() {
+			if r.key.Match(field.Key) {
+				fields = append(fields, *field)
+			}
+		}
+		for _, field := range fields {
+			metric.RemoveField(field.Key)
+			metric.AddField(r.Dest, field.Value)
+		}
+	}
+}
+
+func init() {
+	processors.Add("rename", func() telegraf.Processor {
+		return &Rename{}
+	})
+}
@@ -0,0 +1,115 @@
+package rename
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	m, _ := metric.New(name, tags, fields, time.Now())
+	return m
+}
+
+func TestMeasurementRename(t *testing.T) {
+	r := Rename{
+		Replaces: []*Replace{
+			{Measurement: "foo", Dest: "bar"},
+			{Measurement: "net_*", Dest: "network"},
+		},
+	}
+	m1 := newMetric("foo", nil, nil)
+	m2 := newMetric("net_eth0", nil, nil)
+	m3 := newMetric("baz", nil, nil)
+	results := r.Apply(m1, m2, m3)
+	assert.Equal(t, "bar", results[0].Name())
+	assert.Equal(t, "network", results[1].Name())
+	assert.Equal(t, "baz", results[2].Name())
+}
+
+func TestTagRename(t *testing.T) {
+	r := Rename{
+		Replaces: []*Replace{
+			{Tag: "hostname", Dest: "host"},
+		},
+	}
+	m := newMetric("foo", map[string]string{"hostname": "localhost", "region": "east-1"}, nil)
+	results := r.Apply(m)
+
+	assert.Equal(t, map[string]string{"host": "localhost", "region": "east-1"}, results[0].Tags())
+}
+
+func TestTagValueReplace(t *testing.T) {
+	r := Rename{
+		Replaces: []*Replace{
+			{Tag: "env", Value: "prod*", Dest: "production"},
+		},
+	}
+	m1 := newMetric("foo", map[string]string{"env": "prod-us"}, nil)
+	m2 := newMetric("foo", map[string]string{"env": "staging"}, nil)
+	results := r.Apply(m1, m2)
+
+	assert.Equal(t, map[string]string{"env": "production"}, results[0].Tags())
+	assert.Equal(t, map[string]string{"env": "staging"}, results[1].Tags())
+}
+
+func TestFieldRename(t *testing.T) {
+	r := Rename{
+		Replaces: []*Replace{
+			{Field: "time_msec", Dest: "time"},
+		},
+	}
+	m := newMetric("foo", nil, map[string]interface{}{"time_msec": int64(1250), "snakes": true})
+	results := r.Apply(m)
+
+	assert.Equal(t, map[string]interface{}{"time": int64(1250), "snakes": true}, results[0].Fields())
+}
+
+func TestInvalidReplace(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace *Replace
+		err     string
+	}{
+		{
+			name:    "missing key",
+			replace: &Replace{Dest: "bar"},
+			err:     "invalid replacement 2: one of measurement, tag or field is required",
+		},
+		{
+			name:    "missing dest",
+			replace: &Replace{Tag: "foo"},
+			err:     "invalid replacement 2: dest is required",
+		},
+		{
+			name:    "missing dest of value",
+			replace: &Replace{Tag: "foo", Value: "a*"},
+			err:     "invalid replacement 2: dest is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Rename{
+				Replaces: []*Replace{
+					{Measurement: "foo", Dest: "bar"},
+					tt.replace,
+				},
+			}
+			require.EqualError(t, r.Validate(), tt.err)
+
+			// the metrics are not modified
+			results := r.Apply(newMetric("foo", nil, nil))
+			assert.Equal(t, "foo", results[0].Name())
+		})
+	}
+}
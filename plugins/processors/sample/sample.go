@@ -0,0 +1,176 @@
+package sample
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Percentage of metrics to keep, chosen at random.
+  # percent = 100.0
+
+  ## Keep only one in every N metrics of each series.
+  # every = 1
+
+  ## Add random noise to numeric fields, one of "laplace" or "gaussian".
+  ## The scale is the diversity of the laplace distribution, or the standard
+  ## deviation of the gaussian distribution.  Integer fields are rounded to
+  ## remain integers.
+  # noise = ""
+  # noise_scale = 1.0
+  # noise_fields = ["*"]
+`
+
+type Sample struct {
+	Percent     float64  `toml:"percent"`
+	Every       int      `toml:"every"`
+	Noise       string   `toml:"noise"`
+	NoiseScale  float64  `toml:"noise_scale"`
+	NoiseFields []string `toml:"noise_fields"`
+
+	initialized bool
+	rand        *rand.Rand
+	counts      map[uint64]int
+	fieldFilter filter.Filter
+	noise       func() float64
+}
+
+func (s *Sample) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sample) Description() string {
+	return "Randomly sample or downsample metrics and add noise to numeric fields"
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (s *Sample) Validate() error {
+	return s.init()
+}
+
+func (s *Sample) init() error {
+	var noise func() float64
+	switch s.Noise {
+	case "":
+	case "laplace":
+		noise = s.laplace
+	case "gaussian":
+		noise = s.gaussian
+	default:
+		return fmt.Errorf("unknown noise %q, must be \"laplace\" or \"gaussian\"", s.Noise)
+	}
+
+	if noise != nil {
+		if len(s.NoiseFields) == 0 {
+			s.NoiseFields = []string{"*"}
+		}
+		var err error
+		s.fieldFilter, err = filter.Compile(s.NoiseFields)
+		if err != nil {
+			return fmt.Errorf("noise_fields: %s", err)
+		}
+	}
+	s.noise = noise
+
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	s.counts = make(map[uint64]int)
+	s.initialized = true
+	return nil
+}
+
+func (s *Sample) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !s.initialized {
+		if err := s.init(); err != nil {
+			log.Printf("E! [processors.sample] Invalid configuration, not sampling metrics: %s", err)
+			return in
+		}
+	}
+
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, metric := range in {
+		if s.Percent < 100 && s.rand.Float64()*100 >= s.Percent {
+			continue
+		}
+
+		if s.Every > 1 {
+			id := metric.HashID()
+			n := s.counts[id]
+			s.counts[id] = (n + 1) % s.Every
+			if n != 0 {
+				continue
+			}
+		}
+
+		if s.noise != nil {
+			s.addNoise(metric)
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+func (s *Sample) addNoise(metric telegraf.Metric) {
+	for _, field := range metric.FieldList() {
+		if !s.fieldFilter.Match(field.Key) {
+			continue
+		}
+
+		switch v := field.Value.(type) {
+		case float64:
+			metric.AddField(field.Key, v+s.noise())
+		case int64:
+			metric.AddField(field.Key, v+int64(round(s.noise())))
+		case uint64:
+			n := round(s.noise())
+			switch {
+			case n >= 0:
+				metric.AddField(field.Key, v+uint64(n))
+			case uint64(-n) > v:
+				metric.AddField(field.Key, uint64(0))
+			default:
+				metric.AddField(field.Key, v-uint64(-n))
+			}
+		}
+	}
+}
+
+// laplace returns a sample of the laplace distribution centered on zero,
+// using inverse transform sampling.
+func (s *Sample) laplace() float64 {
+	u := s.rand.Float64() - 0.5
+	sign := 1.0
+	if u < 0 {
+		sign = -1.0
+	}
+	return -s.NoiseScale * sign * math.Log(1-2*math.Abs(u))
+}
+
+// gaussian returns a sample of the normal distribution centered on zero.
+func (s *Sample) gaussian() float64 {
+	return s.rand.NormFloat64() * s.NoiseScale
+}
+
+func round(v float64) float64 {
+	return math.Copysign(math.Floor(math.Abs(v)+0.5), v)
+}
+
+func init() {
+	processors.Add("sample", func() telegraf.Processor {
+		return &Sample{
+			Percent:     100.0,
+			Every:       1,
+			NoiseScale:  1.0,
+			NoiseFields: []string{"*"},
+		}
+	})
+}
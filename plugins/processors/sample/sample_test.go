@@ -0,0 +1,147 @@
+package sample
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newSample() *Sample {
+	return &Sample{
+		Percent:     100.0,
+		Every:       1,
+		NoiseScale:  1.0,
+		NoiseFields: []string{"*"},
+		rand:        rand.New(rand.NewSource(42)),
+	}
+}
+
+func newMetric(host string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("cpu",
+		map[string]string{"host": host},
+		fields,
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+func TestPassthrough(t *testing.T) {
+	s := newSample()
+	m := newMetric("a", map[string]interface{}{"value": 42.0})
+	out := s.Apply(m)
+
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"value": 42.0}, out[0].Fields())
+}
+
+func TestPercent(t *testing.T) {
+	s := newSample()
+	s.Percent = 25.0
+
+	var kept int
+	for i := 0; i < 1000; i++ {
+		kept += len(s.Apply(newMetric("a", map[string]interface{}{"value": 42.0})))
+	}
+	require.InDelta(t, 250, kept, 50)
+}
+
+func TestPercentZero(t *testing.T) {
+	s := newSample()
+	s.Percent = 0
+
+	out := s.Apply(newMetric("a", map[string]interface{}{"value": 42.0}))
+	require.Len(t, out, 0)
+}
+
+func TestEveryPerSeries(t *testing.T) {
+	s := newSample()
+	s.Every = 3
+
+	var in []telegraf.Metric
+	for i := 0; i < 6; i++ {
+		in = append(in, newMetric("a", map[string]interface{}{"value": float64(i)}))
+		in = append(in, newMetric("b", map[string]interface{}{"value": float64(i)}))
+	}
+	out := s.Apply(in...)
+
+	require.Len(t, out, 4)
+	for i, m := range out {
+		host, _ := m.GetTag("host")
+		require.Equal(t, []string{"a", "b"}[i%2], host)
+		value, _ := m.GetField("value")
+		require.Equal(t, float64(i/2*3), value)
+	}
+}
+
+func TestNoise(t *testing.T) {
+	for _, noise := range []string{"laplace", "gaussian"} {
+		t.Run(noise, func(t *testing.T) {
+			s := newSample()
+			s.Noise = noise
+			s.NoiseFields = []string{"noisy_*"}
+
+			var sum float64
+			n := 1000
+			for i := 0; i < n; i++ {
+				m := newMetric("a", map[string]interface{}{
+					"noisy_float": 100.0,
+					"noisy_int":   int64(100),
+					"noisy_uint":  uint64(0),
+					"clean":       100.0,
+				})
+				out := s.Apply(m)
+				require.Len(t, out, 1)
+				require.Len(t, out[0].FieldList(), 4)
+
+				fields := out[0].Fields()
+				require.Equal(t, 100.0, fields["clean"])
+				require.IsType(t, int64(0), fields["noisy_int"])
+				require.IsType(t, uint64(0), fields["noisy_uint"])
+				sum += fields["noisy_float"].(float64)
+			}
+			require.InDelta(t, 100.0, sum/float64(n), 0.5)
+		})
+	}
+}
+
+func TestInvalidNoise(t *testing.T) {
+	tests := []struct {
+		name        string
+		noise       string
+		noiseFields []string
+		err         string
+	}{
+		{
+			name:  "unknown noise",
+			noise: "uniform",
+			err:   `unknown noise "uniform", must be "laplace" or "gaussian"`,
+		},
+		{
+			name:        "invalid noise_fields",
+			noise:       "laplace",
+			noiseFields: []string{"a[b"},
+			err:         "noise_fields: unexpected end of input",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSample()
+			s.Every = 2
+			s.Noise = tt.noise
+			s.NoiseFields = tt.noiseFields
+			require.EqualError(t, s.Validate(), tt.err)
+
+			// the metrics are passed through unchanged
+			out := s.Apply(
+				newMetric("a", map[string]interface{}{"value": 1.0}),
+				newMetric("a", map[string]interface{}{"value": 2.0}),
+			)
+			require.Len(t, out, 2)
+			require.Equal(t, 1.0, out[0].Fields()["value"])
+		})
+	}
+}
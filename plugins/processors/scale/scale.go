@@ -0,0 +1,191 @@
+package scale
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Each scaling selects fields by key, which may contain globs.  Numeric
+  ## values are first rescaled, then clamped, then rounded; the result is
+  ## always a float.  All options are optional, but the four range options
+  ## must be set together.  Values must be written as floats, e.g. 4095.0.
+  ## Invalid scalings are rejected when loading the configuration.
+  # [[processors.scale.scaling]]
+  #   fields = ["adc_*"]
+  #
+  #   ## Linearly map values from the input range onto the output range.
+  #   input_minimum = 0.0
+  #   input_maximum = 4095.0
+  #   output_minimum = 0.0
+  #   output_maximum = 100.0
+  #
+  #   ## Clamp values to the range minimum to maximum.
+  #   minimum = 0.0
+  #   maximum = 100.0
+  #
+  #   ## Round values to this number of decimal places.
+  #   round = 2
+`
+
+type Scaling struct {
+	Fields        []string `toml:"fields"`
+	InputMinimum  *float64 `toml:"input_minimum"`
+	InputMaximum  *float64 `toml:"input_maximum"`
+	OutputMinimum *float64 `toml:"output_minimum"`
+	OutputMaximum *float64 `toml:"output_maximum"`
+	Minimum       *float64 `toml:"minimum"`
+	Maximum       *float64 `toml:"maximum"`
+	Round         *int     `toml:"round"`
+
+	filter filter.Filter
+	factor float64
+	offset float64
+	scale  bool
+}
+
+type Scale struct {
+	Scalings []*Scaling `toml:"scaling"`
+
+	initialized bool
+}
+
+func (s *Scale) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Scale) Description() string {
+	return "Rescale, clamp and round numeric fields"
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (s *Scale) Validate() error {
+	return s.init()
+}
+
+func (s *Scale) init() error {
+	for i, scaling := range s.Scalings {
+		if err := scaling.compile(); err != nil {
+			return fmt.Errorf("invalid scaling %d: %v", i+1, err)
+		}
+	}
+	s.initialized = true
+	return nil
+}
+
+func (s *Scale) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !s.initialized {
+		if err := s.init(); err != nil {
+			log.Printf("E! [processors.scale] Invalid configuration, not scaling metrics: %s", err)
+			return in
+		}
+	}
+
+	for _, metric := range in {
+		for _, scaling := range s.Scalings {
+			scaling.apply(metric)
+		}
+	}
+	return in
+}
+
+func (s *Scaling) compile() error {
+	if len(s.Fields) == 0 {
+		return errors.New("no fields selected")
+	}
+
+	var err error
+	s.filter, err = filter.Compile(s.Fields)
+	if err != nil {
+		return err
+	}
+
+	set := 0
+	for _, v := range []*float64{s.InputMinimum, s.InputMaximum, s.OutputMinimum, s.OutputMaximum} {
+		if v != nil {
+			set++
+		}
+	}
+	switch set {
+	case 0:
+	case 4:
+		if *s.InputMinimum == *s.InputMaximum {
+			return errors.New("input_minimum and input_maximum must differ")
+		}
+		s.factor = (*s.OutputMaximum - *s.OutputMinimum) / (*s.InputMaximum - *s.InputMinimum)
+		s.offset = *s.OutputMinimum - s.factor**s.InputMinimum
+		s.scale = true
+	default:
+		return errors.New("input and output ranges must be set together")
+	}
+
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return fmt.Errorf("minimum %v is greater than maximum %v", *s.Minimum, *s.Maximum)
+	}
+	if s.Round != nil && *s.Round < 0 {
+		return fmt.Errorf("round must not be negative: %d", *s.Round)
+	}
+	return nil
+}
+
+func (s *Scaling) apply(metric telegraf.Metric) {
+	var fields []telegraf.Field
+	for _, field := range metric.FieldList() {
+		if s.filter.Match(field.Key) {
+			fields = append(fields, *field)
+		}
+	}
+
+	for _, field := range fields {
+		v, ok := toFloat(field.Value)
+		if !ok {
+			continue
+		}
+
+		if s.scale {
+			v = v*s.factor + s.offset
+		}
+		if s.Minimum != nil && v < *s.Minimum {
+			v = *s.Minimum
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			v = *s.Maximum
+		}
+		if s.Round != nil {
+			v = round(v, *s.Round)
+		}
+
+		metric.AddField(field.Key, v)
+	}
+}
+
+// round rounds v half away from zero to the given number of decimal places.
+func round(v float64, places int) float64 {
+	pow := math.Pow(10, float64(places))
+	return math.Copysign(math.Floor(math.Abs(v)*pow+0.5), v) / pow
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0.0, false
+}
+
+func init() {
+	processors.Add("scale", func() telegraf.Processor {
+		return &Scale{}
+	})
+}
@@ -0,0 +1,180 @@
+package scale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("sensor", map[string]string{}, fields, time.Unix(0, 0))
+	return m
+}
+
+func f(v float64) *float64 {
+	return &v
+}
+
+func i(v int) *int {
+	return &v
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name     string
+		scaling  *Scaling
+		input    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "rescale",
+			scaling: &Scaling{
+				Fields:        []string{"adc_*"},
+				InputMinimum:  f(0),
+				InputMaximum:  f(4095),
+				OutputMinimum: f(0),
+				OutputMaximum: f(100),
+			},
+			input: map[string]interface{}{
+				"adc_a": int64(4095),
+				"adc_b": uint64(0),
+				"adc_c": 2047.5,
+				"other": int64(4095),
+			},
+			expected: map[string]interface{}{
+				"adc_a": 100.0,
+				"adc_b": 0.0,
+				"adc_c": 50.0,
+				"other": int64(4095),
+			},
+		},
+		{
+			name: "rescale with offset",
+			scaling: &Scaling{
+				Fields:        []string{"temp"},
+				InputMinimum:  f(4),
+				InputMaximum:  f(20),
+				OutputMinimum: f(-40),
+				OutputMaximum: f(120),
+			},
+			input:    map[string]interface{}{"temp": 12.0},
+			expected: map[string]interface{}{"temp": 40.0},
+		},
+		{
+			name: "clamp",
+			scaling: &Scaling{
+				Fields:  []string{"*"},
+				Minimum: f(0),
+				Maximum: f(100),
+			},
+			input: map[string]interface{}{
+				"low":    int64(-5),
+				"high":   101.5,
+				"ok":     42.0,
+				"string": "howdy",
+			},
+			expected: map[string]interface{}{
+				"low":    0.0,
+				"high":   100.0,
+				"ok":     42.0,
+				"string": "howdy",
+			},
+		},
+		{
+			name: "round",
+			scaling: &Scaling{
+				Fields: []string{"*"},
+				Round:  i(2),
+			},
+			input: map[string]interface{}{
+				"a": 1.23456,
+				"b": -1.235,
+				"c": int64(7),
+			},
+			expected: map[string]interface{}{
+				"a": 1.23,
+				"b": -1.24,
+				"c": 7.0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Scale{Scalings: []*Scaling{tt.scaling}}
+			metrics := plugin.Apply(newMetric(tt.input))
+
+			require.Len(t, metrics, 1)
+			require.Equal(t, tt.expected, metrics[0].Fields())
+		})
+	}
+}
+
+func TestInvalidScaling(t *testing.T) {
+	tests := []struct {
+		name    string
+		scaling *Scaling
+		err     string
+	}{
+		{
+			name:    "no fields",
+			scaling: &Scaling{Round: i(2)},
+			err:     "invalid scaling 2: no fields selected",
+		},
+		{
+			name: "incomplete range",
+			scaling: &Scaling{
+				Fields:       []string{"*"},
+				InputMinimum: f(0),
+			},
+			err: "invalid scaling 2: input and output ranges must be set together",
+		},
+		{
+			name: "empty input range",
+			scaling: &Scaling{
+				Fields:        []string{"*"},
+				InputMinimum:  f(1),
+				InputMaximum:  f(1),
+				OutputMinimum: f(0),
+				OutputMaximum: f(100),
+			},
+			err: "invalid scaling 2: input_minimum and input_maximum must differ",
+		},
+		{
+			name: "minimum greater than maximum",
+			scaling: &Scaling{
+				Fields:  []string{"*"},
+				Minimum: f(10),
+				Maximum: f(0),
+			},
+			err: "invalid scaling 2: minimum 10 is greater than maximum 0",
+		},
+		{
+			name: "negative round",
+			scaling: &Scaling{
+				Fields: []string{"*"},
+				Round:  i(-1),
+			},
+			err: "invalid scaling 2: round must not be negative: -1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Scale{
+				Scalings: []*Scaling{
+					{Fields: []string{"*"}, Round: i(0)},
+					tt.scaling,
+				},
+			}
+			require.EqualError(t, plugin.Validate(), tt.err)
+
+			// the metrics are not modified
+			metrics := plugin.Apply(newMetric(map[string]interface{}{"a": 1.5}))
+			require.Equal(t, map[string]interface{}{"a": 1.5}, metrics[0].Fields())
+		})
+	}
+}
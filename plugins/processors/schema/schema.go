@@ -0,0 +1,392 @@
+package schema
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## What to do with the metrics that do not conform to their schema:
+  ##   "fix"   - convert the fields to their declared type, add the missing
+  ##             tags and fields from their defaults and, if strict, remove
+  ##             the undeclared ones.  Metrics that cannot be fixed are
+  ##             tagged.
+  ##   "tag"   - add the invalid_tag with the value "true"
+  ##   "route" - rename the measurement to dead_letter, with the original
+  ##             name in the "measurement" tag, so outputs can select the
+  ##             invalid metrics with namepass and namedrop
+  # action = "fix"
+  # invalid_tag = "schema_invalid"
+  # dead_letter = "dead_letter"
+
+  ## When strict, the tags and fields that are not declared in the schema
+  ## make a metric invalid.
+  # strict = false
+
+  ## Schema of each measurement, metrics of other measurements are not
+  ## checked.  The declared tags are required, the declared fields are
+  ## optional unless they have a default, their type is one of "float",
+  ## "integer", "unsigned", "string" or "boolean".
+  [[processors.schema.measurement]]
+    name = "cpu"
+    tags = ["host", "cpu"]
+    [processors.schema.measurement.fields]
+      usage_idle = "float"
+      usage_user = "float"
+    [processors.schema.measurement.tag_defaults]
+      cpu = "cpu-total"
+    [processors.schema.measurement.field_defaults]
+      usage_user = "0"
+`
+
+// Field types.
+const (
+	Float    = "float"
+	Integer  = "integer"
+	Unsigned = "unsigned"
+	String   = "string"
+	Boolean  = "boolean"
+)
+
+// Measurement is the schema of a measurement.
+type Measurement struct {
+	Name          string            `toml:"name"`
+	Tags          []string          `toml:"tags"`
+	Fields        map[string]string `toml:"fields"`
+	TagDefaults   map[string]string `toml:"tag_defaults"`
+	FieldDefaults map[string]string `toml:"field_defaults"`
+
+	tags          map[string]bool
+	fieldDefaults map[string]interface{}
+}
+
+type Schema struct {
+	Action       string         `toml:"action"`
+	InvalidTag   string         `toml:"invalid_tag"`
+	DeadLetter   string         `toml:"dead_letter"`
+	Strict       bool           `toml:"strict"`
+	Measurements []*Measurement `toml:"measurement"`
+
+	initialized  bool
+	measurements map[string]*Measurement
+}
+
+func NewSchema() *Schema {
+	return &Schema{
+		Action:     "fix",
+		InvalidTag: "schema_invalid",
+		DeadLetter: "dead_letter",
+	}
+}
+
+func (s *Schema) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Schema) Description() string {
+	return "Check metrics against the declared schema of their measurement"
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (s *Schema) Validate() error {
+	return s.init()
+}
+
+func (s *Schema) init() error {
+	switch s.Action {
+	case "fix", "tag", "route":
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+
+	s.measurements = make(map[string]*Measurement, len(s.Measurements))
+	for _, m := range s.Measurements {
+		if m.Name == "" {
+			return fmt.Errorf("measurement schema without a name")
+		}
+		if _, ok := s.measurements[m.Name]; ok {
+			return fmt.Errorf("duplicate schema for measurement %q", m.Name)
+		}
+
+		m.tags = make(map[string]bool, len(m.Tags))
+		for _, tag := range m.Tags {
+			m.tags[tag] = true
+		}
+		for tag := range m.TagDefaults {
+			if !m.tags[tag] {
+				return fmt.Errorf("%s: default of undeclared tag %q", m.Name, tag)
+			}
+		}
+
+		for field, typ := range m.Fields {
+			switch typ {
+			case Float, Integer, Unsigned, String, Boolean:
+			default:
+				return fmt.Errorf("%s: unknown type %q of field %q", m.Name, typ, field)
+			}
+		}
+		m.fieldDefaults = make(map[string]interface{}, len(m.FieldDefaults))
+		for field, value := range m.FieldDefaults {
+			typ, ok := m.Fields[field]
+			if !ok {
+				return fmt.Errorf("%s: default of undeclared field %q", m.Name, field)
+			}
+			v, ok := convert(typ, value)
+			if !ok {
+				return fmt.Errorf("%s: default %q of field %q is not a %s", m.Name, value, field, typ)
+			}
+			m.fieldDefaults[field] = v
+		}
+
+		s.measurements[m.Name] = m
+	}
+
+	s.initialized = true
+	return nil
+}
+
+func (s *Schema) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !s.initialized {
+		if err := s.init(); err != nil {
+			log.Printf("E! [processors.schema] Invalid configuration, not checking metrics: %s", err)
+			return in
+		}
+	}
+
+	for _, metric := range in {
+		m, ok := s.measurements[metric.Name()]
+		if !ok {
+			continue
+		}
+
+		if s.Action == "fix" {
+			if s.fix(m, metric) {
+				continue
+			}
+		} else if s.valid(m, metric) {
+			continue
+		}
+
+		switch s.Action {
+		case "route":
+			metric.AddTag("measurement", metric.Name())
+			metric.SetName(s.DeadLetter)
+		default:
+			metric.AddTag(s.InvalidTag, "true")
+		}
+	}
+	return in
+}
+
+// valid returns true if the metric conforms to the schema.
+func (s *Schema) valid(m *Measurement, metric telegraf.Metric) bool {
+	for _, tag := range m.Tags {
+		if !metric.HasTag(tag) {
+			return false
+		}
+	}
+	if s.Strict {
+		for _, tag := range metric.TagList() {
+			if !m.tags[tag.Key] {
+				return false
+			}
+		}
+	}
+
+	for field := range m.fieldDefaults {
+		if !metric.HasField(field) {
+			return false
+		}
+	}
+	for _, field := range metric.FieldList() {
+		typ, ok := m.Fields[field.Key]
+		if !ok {
+			if s.Strict {
+				return false
+			}
+			continue
+		}
+		if !hasType(typ, field.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// fix makes the metric conform to the schema where possible, and returns
+// true if the metric conforms to the schema.
+func (s *Schema) fix(m *Measurement, metric telegraf.Metric) bool {
+	valid := true
+	for _, tag := range m.Tags {
+		if metric.HasTag(tag) {
+			continue
+		}
+		if value, ok := m.TagDefaults[tag]; ok {
+			metric.AddTag(tag, value)
+			continue
+		}
+		valid = false
+	}
+	// The tags and fields are removed while iterating over copies.
+	if s.Strict {
+		for key := range metric.Tags() {
+			if !m.tags[key] {
+				metric.RemoveTag(key)
+			}
+		}
+	}
+
+	for key, v := range metric.Fields() {
+		typ, ok := m.Fields[key]
+		if !ok {
+			if s.Strict {
+				metric.RemoveField(key)
+			}
+			continue
+		}
+		if hasType(typ, v) {
+			continue
+		}
+		if value, ok := convert(typ, v); ok {
+			metric.AddField(key, value)
+			continue
+		}
+		// Fields that cannot be converted are replaced by their default.
+		metric.RemoveField(key)
+	}
+	for field, value := range m.fieldDefaults {
+		if !metric.HasField(field) {
+			metric.AddField(field, value)
+		}
+	}
+
+	if len(metric.FieldList()) == 0 {
+		return false
+	}
+	return valid
+}
+
+func hasType(typ string, v interface{}) bool {
+	switch v.(type) {
+	case float64:
+		return typ == Float
+	case int64:
+		return typ == Integer
+	case uint64:
+		return typ == Unsigned
+	case string:
+		return typ == String
+	case bool:
+		return typ == Boolean
+	}
+	return false
+}
+
+// convert converts the value to the type, and returns false if the value
+// is not representable in the type.
+func convert(typ string, v interface{}) (interface{}, bool) {
+	switch typ {
+	case Float:
+		return toFloat(v)
+	case Integer:
+		return toInteger(v)
+	case Unsigned:
+		return toUnsigned(v)
+	case String:
+		return toString(v)
+	case Boolean:
+		return toBool(v)
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case string:
+		result, err := strconv.ParseFloat(value, 64)
+		return result, err == nil
+	}
+	return nil, false
+}
+
+func toInteger(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value, true
+	case uint64:
+		return int64(value), value <= math.MaxInt64
+	case float64:
+		// Only whole numbers are converted, to not lose precision silently.
+		if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return nil, false
+		}
+		return int64(value), true
+	case string:
+		result, err := strconv.ParseInt(value, 10, 64)
+		return result, err == nil
+	}
+	return nil, false
+}
+
+func toUnsigned(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case uint64:
+		return value, true
+	case int64:
+		return uint64(value), value >= 0
+	case float64:
+		if value != math.Trunc(value) || value < 0 || value >= math.MaxUint64 {
+			return nil, false
+		}
+		return uint64(value), true
+	case string:
+		result, err := strconv.ParseUint(value, 10, 64)
+		return result, err == nil
+	}
+	return nil, false
+}
+
+func toString(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case uint64:
+		return strconv.FormatUint(value, 10), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case string:
+		return value, true
+	}
+	return nil, false
+}
+
+func toBool(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case bool:
+		return value, true
+	case string:
+		result, err := strconv.ParseBool(value)
+		return result, err == nil
+	}
+	return nil, false
+}
+
+func init() {
+	processors.Add("schema", func() telegraf.Processor {
+		return NewSchema()
+	})
+}
@@ -0,0 +1,188 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New(name, tags, fields, time.Unix(0, 0))
+	return m
+}
+
+func newSchema(action string) *Schema {
+	s := NewSchema()
+	s.Action = action
+	s.Measurements = []*Measurement{
+		{
+			Name: "cpu",
+			Tags: []string{"host", "cpu"},
+			Fields: map[string]string{
+				"usage_idle": "float",
+				"usage_user": "float",
+				"cores":      "integer",
+			},
+			TagDefaults: map[string]string{
+				"cpu": "cpu-total",
+			},
+			FieldDefaults: map[string]string{
+				"usage_user": "0",
+			},
+		},
+	}
+	return s
+}
+
+func TestValid(t *testing.T) {
+	for _, action := range []string{"fix", "tag", "route"} {
+		s := newSchema(action)
+		m := newMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0", "dc": "east"},
+			map[string]interface{}{"usage_idle": 90.0, "usage_user": 5.0, "other": "x"},
+		)
+		m = s.Apply(m)[0]
+		require.Equal(t, "cpu", m.Name())
+		require.Equal(t, map[string]string{"host": "a", "cpu": "cpu0", "dc": "east"}, m.Tags())
+		require.Equal(t, map[string]interface{}{"usage_idle": 90.0, "usage_user": 5.0, "other": "x"}, m.Fields())
+	}
+}
+
+func TestOtherMeasurement(t *testing.T) {
+	s := newSchema("tag")
+	m := newMetric("mem", map[string]string{}, map[string]interface{}{"used": "1"})
+	m = s.Apply(m)[0]
+	require.Empty(t, m.Tags())
+}
+
+func TestFix(t *testing.T) {
+	s := newSchema("fix")
+	m := newMetric("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage_idle": int64(90), "cores": "4"},
+	)
+	m = s.Apply(m)[0]
+	require.Equal(t, "cpu", m.Name())
+	require.Equal(t, map[string]string{"host": "a", "cpu": "cpu-total"}, m.Tags())
+	require.Equal(t, map[string]interface{}{
+		"usage_idle": 90.0,
+		"usage_user": 0.0,
+		"cores":      int64(4),
+	}, m.Fields())
+}
+
+func TestFixUnconvertible(t *testing.T) {
+	s := newSchema("fix")
+	m := newMetric("cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 90.0, "usage_user": "high", "cores": 4.5},
+	)
+	m = s.Apply(m)[0]
+	require.Equal(t, map[string]string{"host": "a", "cpu": "cpu0"}, m.Tags())
+	require.Equal(t, map[string]interface{}{
+		"usage_idle": 90.0,
+		"usage_user": 0.0,
+	}, m.Fields())
+}
+
+func TestFixStrict(t *testing.T) {
+	s := newSchema("fix")
+	s.Strict = true
+	m := newMetric("cpu",
+		map[string]string{"host": "a", "cpu": "cpu0", "dc": "east", "rack": "1"},
+		map[string]interface{}{"usage_idle": 90.0, "other": "x", "more": int64(1)},
+	)
+	m = s.Apply(m)[0]
+	require.Equal(t, map[string]string{"host": "a", "cpu": "cpu0"}, m.Tags())
+	require.Equal(t, map[string]interface{}{"usage_idle": 90.0, "usage_user": 0.0}, m.Fields())
+}
+
+func TestFixImpossible(t *testing.T) {
+	s := newSchema("fix")
+	m := newMetric("cpu",
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 90.0},
+	)
+	m = s.Apply(m)[0]
+	require.Equal(t, map[string]string{"cpu": "cpu0", "schema_invalid": "true"}, m.Tags())
+}
+
+func TestTag(t *testing.T) {
+	s := newSchema("tag")
+	s.InvalidTag = "invalid"
+	tests := []telegraf.Metric{
+		newMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 90.0, "usage_user": 1.0}),
+		newMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": "90", "usage_user": 1.0}),
+		newMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 90.0}),
+	}
+	for _, m := range s.Apply(tests...) {
+		require.Equal(t, "true", m.Tags()["invalid"])
+	}
+
+	// Invalid metrics are not changed otherwise.
+	require.Equal(t, "90", tests[1].Fields()["usage_idle"])
+}
+
+func TestTagStrict(t *testing.T) {
+	s := newSchema("tag")
+	s.Strict = true
+	tests := []telegraf.Metric{
+		newMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0", "dc": "east"},
+			map[string]interface{}{"usage_user": 1.0}),
+		newMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_user": 1.0, "other": "x"}),
+	}
+	for _, m := range s.Apply(tests...) {
+		require.Equal(t, "true", m.Tags()["schema_invalid"])
+	}
+}
+
+func TestRoute(t *testing.T) {
+	s := newSchema("route")
+	m := newMetric("cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": "90", "usage_user": 1.0},
+	)
+	m = s.Apply(m)[0]
+	require.Equal(t, "dead_letter", m.Name())
+	require.Equal(t, map[string]string{"host": "a", "cpu": "cpu0", "measurement": "cpu"}, m.Tags())
+	require.Equal(t, "90", m.Fields()["usage_idle"])
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []*Schema{
+		{Action: "drop"},
+		{Action: "tag", Measurements: []*Measurement{{Tags: []string{"host"}}}},
+		{Action: "tag", Measurements: []*Measurement{{Name: "cpu"}, {Name: "cpu"}}},
+		{Action: "tag", Measurements: []*Measurement{{
+			Name:   "cpu",
+			Fields: map[string]string{"usage": "double"},
+		}}},
+		{Action: "tag", Measurements: []*Measurement{{
+			Name:        "cpu",
+			TagDefaults: map[string]string{"host": "a"},
+		}}},
+		{Action: "tag", Measurements: []*Measurement{{
+			Name:          "cpu",
+			Fields:        map[string]string{"usage": "integer"},
+			FieldDefaults: map[string]string{"usage": "0.5"},
+		}}},
+	}
+	for _, s := range tests {
+		m := newMetric("cpu", map[string]string{}, map[string]interface{}{"usage": "x"})
+		m = s.Apply(m)[0]
+		require.Empty(t, m.Tags())
+		require.Equal(t, "cpu", m.Name())
+	}
+}
@@ -0,0 +1,332 @@
+package strings
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+type Strings struct {
+	Lowercase  []converter `toml:"lowercase"`
+	Uppercase  []converter `toml:"uppercase"`
+	Trim       []converter `toml:"trim"`
+	TrimLeft   []converter `toml:"trim_left"`
+	TrimRight  []converter `toml:"trim_right"`
+	TrimPrefix []converter `toml:"trim_prefix"`
+	TrimSuffix []converter `toml:"trim_suffix"`
+	Replace    []converter `toml:"replace"`
+	Left       []converter `toml:"left"`
+	Right      []converter `toml:"right"`
+	SHA256     []converter `toml:"sha256"`
+	HMAC       []converter `toml:"hmac"`
+
+	converters []converter
+	init       bool
+}
+
+type ConvertFunc func(s string) string
+
+type converter struct {
+	Field  string
+	Tag    string
+	Dest   string
+	Cutset string
+	Suffix string
+	Prefix string
+	Old    string
+	New    string
+	Width  int
+	Key    string
+
+	fieldFilter filter.Filter
+	tagFilter   filter.Filter
+	fn          ConvertFunc
+}
+
+const sampleConfig = `
+  ## Each rule selects the tag or field values to modify by key, which may
+  ## contain globs.  Only string field values are modified.  If dest is set
+  ## the result is stored in a new tag or field instead of the original.
+  ## The rules are applied grouped by function, in the order of the functions
+  ## below, and not in the order they are configured in.
+
+  ## Convert a tag value to uppercase
+  # [[processors.strings.uppercase]]
+  #   tag = "method"
+
+  ## Convert a field value to lowercase and store in a new field
+  # [[processors.strings.lowercase]]
+  #   field = "uri_stem"
+  #   dest = "uri_stem_normalised"
+
+  ## Trim leading and trailing whitespace using the default cutset
+  # [[processors.strings.trim]]
+  #   field = "message"
+
+  ## Trim leading characters in cutset
+  # [[processors.strings.trim_left]]
+  #   field = "message"
+  #   cutset = "\t"
+
+  ## Trim trailing characters in cutset
+  # [[processors.strings.trim_right]]
+  #   field = "message"
+  #   cutset = "\r\n"
+
+  ## Trim the given prefix from the field
+  # [[processors.strings.trim_prefix]]
+  #   field = "my_value"
+  #   prefix = "my_"
+
+  ## Trim the given suffix from the field
+  # [[processors.strings.trim_suffix]]
+  #   field = "read_count"
+  #   suffix = "_count"
+
+  ## Replace all non-overlapping instances of old with new
+  # [[processors.strings.replace]]
+  #   tag = "path"
+  #   old = "/"
+  #   new = "_"
+
+  ## Keep only the leftmost or rightmost width characters
+  # [[processors.strings.left]]
+  #   field = "message"
+  #   width = 64
+  # [[processors.strings.right]]
+  #   tag = "serial"
+  #   width = 4
+
+  ## Replace the value with its hex encoded SHA-256 hash
+  # [[processors.strings.sha256]]
+  #   tag = "user"
+
+  ## Replace the value with its hex encoded HMAC-SHA-256 using key, which is
+  ## required
+  # [[processors.strings.hmac]]
+  #   tag = "email"
+  #   key = "secret"
+`
+
+func (s *Strings) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Strings) Description() string {
+	return "Perform string processing on tags and fields"
+}
+
+func (c *converter) compile() error {
+	var err error
+	if c.Field != "" {
+		c.fieldFilter, err = filter.Compile([]string{c.Field})
+		if err != nil {
+			return err
+		}
+	}
+	if c.Tag != "" {
+		c.tagFilter, err = filter.Compile([]string{c.Tag})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *converter) convertTag(metric telegraf.Metric) {
+	if c.tagFilter == nil {
+		return
+	}
+
+	var tags []telegraf.Tag
+	for _, tag := range metric.TagList() {
+		if c.tagFilter.Match(tag.Key) {
+			tags = append(tags, *tag)
+		}
+	}
+
+	for _, tag := range tags {
+		dest := tag.Key
+		if c.Dest != "" {
+			dest = c.Dest
+		}
+		metric.AddTag(dest, c.fn(tag.Value))
+	}
+}
+
+func (c *converter) convertField(metric telegraf.Metric) {
+	if c.fieldFilter == nil {
+		return
+	}
+
+	var fields []telegraf.Field
+	for _, field := range metric.FieldList() {
+		if c.fieldFilter.Match(field.Key) {
+			fields = append(fields, *field)
+		}
+	}
+
+	for _, field := range fields {
+		value, ok := field.Value.(string)
+		if !ok {
+			continue
+		}
+		dest := field.Key
+		if c.Dest != "" {
+			dest = c.Dest
+		}
+		metric.AddField(dest, c.fn(value))
+	}
+}
+
+// Validate checks the configuration, it is valid if the processor can be
+// initialized.
+func (s *Strings) Validate() error {
+	return s.initOnce()
+}
+
+func (s *Strings) initOnce() error {
+	if s.init {
+		return nil
+	}
+
+	for _, c := range s.HMAC {
+		if c.Key == "" {
+			return fmt.Errorf("hmac requires a key")
+		}
+	}
+
+	s.converters = make([]converter, 0)
+	var err error
+	add := func(cs []converter, fn func(c converter) ConvertFunc) {
+		for _, c := range cs {
+			if err != nil {
+				return
+			}
+			if err = c.compile(); err != nil {
+				err = fmt.Errorf("invalid selector: %v", err)
+				return
+			}
+			c.fn = fn(c)
+			s.converters = append(s.converters, c)
+		}
+	}
+
+	add(s.Lowercase, func(c converter) ConvertFunc {
+		return strings.ToLower
+	})
+	add(s.Uppercase, func(c converter) ConvertFunc {
+		return strings.ToUpper
+	})
+	add(s.Trim, func(c converter) ConvertFunc {
+		if c.Cutset == "" {
+			return strings.TrimSpace
+		}
+		return func(s string) string {
+			return strings.Trim(s, c.Cutset)
+		}
+	})
+	add(s.TrimLeft, func(c converter) ConvertFunc {
+		if c.Cutset == "" {
+			return func(s string) string {
+				return strings.TrimLeftFunc(s, unicode.IsSpace)
+			}
+		}
+		return func(s string) string {
+			return strings.TrimLeft(s, c.Cutset)
+		}
+	})
+	add(s.TrimRight, func(c converter) ConvertFunc {
+		if c.Cutset == "" {
+			return func(s string) string {
+				return strings.TrimRightFunc(s, unicode.IsSpace)
+			}
+		}
+		return func(s string) string {
+			return strings.TrimRight(s, c.Cutset)
+		}
+	})
+	add(s.TrimPrefix, func(c converter) ConvertFunc {
+		return func(s string) string {
+			return strings.TrimPrefix(s, c.Prefix)
+		}
+	})
+	add(s.TrimSuffix, func(c converter) ConvertFunc {
+		return func(s string) string {
+			return strings.TrimSuffix(s, c.Suffix)
+		}
+	})
+	add(s.Replace, func(c converter) ConvertFunc {
+		return func(s string) string {
+			return strings.Replace(s, c.Old, c.New, -1)
+		}
+	})
+	add(s.Left, func(c converter) ConvertFunc {
+		return func(s string) string {
+			r := []rune(s)
+			if c.Width < 0 || len(r) <= c.Width {
+				return s
+			}
+			return string(r[:c.Width])
+		}
+	})
+	add(s.Right, func(c converter) ConvertFunc {
+		return func(s string) string {
+			r := []rune(s)
+			if c.Width < 0 || len(r) <= c.Width {
+				return s
+			}
+			return string(r[len(r)-c.Width:])
+		}
+	})
+	add(s.SHA256, func(c converter) ConvertFunc {
+		return func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}
+	})
+	add(s.HMAC, func(c converter) ConvertFunc {
+		key := []byte(c.Key)
+		return func(s string) string {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(s))
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.init = true
+	return nil
+}
+
+func (s *Strings) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if err := s.initOnce(); err != nil {
+		log.Printf("E! [processors.strings] Invalid configuration, not processing metrics: %s", err)
+		return in
+	}
+
+	for _, metric := range in {
+		for _, converter := range s.converters {
+			converter.convertTag(metric)
+			converter.convertField(metric)
+		}
+	}
+
+	return in
+}
+
+func init() {
+	processors.Add("strings", func() telegraf.Processor {
+		return &Strings{}
+	})
+}
@@ -0,0 +1,189 @@
+package strings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newM1() telegraf.Metric {
+	m1, _ := metric.New("IIS_log",
+		map[string]string{
+			"verb":           "GET",
+			"s-computername": "MIXEDCASE_hostname",
+		},
+		map[string]interface{}{
+			"request":    "/mixed/CASE/paTH/?from=-1D&to=now",
+			"whitespace": "  whitespace\t",
+			"bytes":      int64(42),
+		},
+		time.Now(),
+	)
+	return m1
+}
+
+func TestFieldConversions(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Strings
+		check  func(t *testing.T, actual telegraf.Metric)
+	}{
+		{
+			name: "Should change existing field to lowercase",
+			plugin: &Strings{
+				Lowercase: []converter{{Field: "request"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, ok := actual.GetField("request")
+				assert.True(t, ok)
+				assert.Equal(t, "/mixed/case/path/?from=-1d&to=now", fv)
+			},
+		},
+		{
+			name: "Should change existing field to uppercase",
+			plugin: &Strings{
+				Uppercase: []converter{{Field: "request"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, ok := actual.GetField("request")
+				assert.True(t, ok)
+				assert.Equal(t, "/MIXED/CASE/PATH/?FROM=-1D&TO=NOW", fv)
+			},
+		},
+		{
+			name: "Should add new lowercase field",
+			plugin: &Strings{
+				Lowercase: []converter{{Field: "request", Dest: "lowercase_request"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, ok := actual.GetField("request")
+				assert.True(t, ok)
+				assert.Equal(t, "/mixed/CASE/paTH/?from=-1D&to=now", fv)
+
+				fv, ok = actual.GetField("lowercase_request")
+				assert.True(t, ok)
+				assert.Equal(t, "/mixed/case/path/?from=-1d&to=now", fv)
+			},
+		},
+		{
+			name: "Should trim whitespace",
+			plugin: &Strings{
+				Trim: []converter{{Field: "whitespace"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, _ := actual.GetField("whitespace")
+				assert.Equal(t, "whitespace", fv)
+			},
+		},
+		{
+			name: "Should trim left and right with cutset",
+			plugin: &Strings{
+				TrimLeft:  []converter{{Field: "request", Cutset: "/"}},
+				TrimRight: []converter{{Field: "whitespace", Cutset: "\t"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, _ := actual.GetField("request")
+				assert.Equal(t, "mixed/CASE/paTH/?from=-1D&to=now", fv)
+				fv, _ = actual.GetField("whitespace")
+				assert.Equal(t, "  whitespace", fv)
+			},
+		},
+		{
+			name: "Should trim prefix and suffix",
+			plugin: &Strings{
+				TrimPrefix: []converter{{Field: "request", Prefix: "/mixed"}},
+				TrimSuffix: []converter{{Field: "request", Suffix: "&to=now"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, _ := actual.GetField("request")
+				assert.Equal(t, "/CASE/paTH/?from=-1D", fv)
+			},
+		},
+		{
+			name: "Should replace substrings",
+			plugin: &Strings{
+				Replace: []converter{{Field: "request", Old: "/", New: "_"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, _ := actual.GetField("request")
+				assert.Equal(t, "_mixed_CASE_paTH_?from=-1D&to=now", fv)
+			},
+		},
+		{
+			name: "Should truncate left and right",
+			plugin: &Strings{
+				Left:  []converter{{Field: "request", Width: 6}},
+				Right: []converter{{Tag: "s-computername", Width: 8}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, _ := actual.GetField("request")
+				assert.Equal(t, "/mixed", fv)
+				tv, _ := actual.GetTag("s-computername")
+				assert.Equal(t, "hostname", tv)
+			},
+		},
+		{
+			name: "Should ignore non-string fields",
+			plugin: &Strings{
+				Uppercase: []converter{{Field: "*"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv, _ := actual.GetField("bytes")
+				assert.Equal(t, int64(42), fv)
+				fv, _ = actual.GetField("request")
+				assert.Equal(t, "/MIXED/CASE/PATH/?FROM=-1D&TO=NOW", fv)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := tt.plugin.Apply(newM1())
+			assert.Len(t, metrics, 1)
+			tt.check(t, metrics[0])
+		})
+	}
+}
+
+func TestTagConversions(t *testing.T) {
+	plugin := &Strings{
+		Lowercase: []converter{{Tag: "s-computername"}},
+		Uppercase: []converter{{Tag: "verb", Dest: "verb_upper"}},
+	}
+	metrics := plugin.Apply(newM1())
+
+	assert.Equal(t, map[string]string{
+		"verb":           "GET",
+		"verb_upper":     "GET",
+		"s-computername": "mixedcase_hostname",
+	}, metrics[0].Tags())
+}
+
+func TestHashing(t *testing.T) {
+	plugin := &Strings{
+		SHA256: []converter{{Tag: "verb"}},
+		HMAC:   []converter{{Tag: "s-computername", Key: "secret"}},
+	}
+	metrics := plugin.Apply(newM1())
+
+	tv, _ := metrics[0].GetTag("verb")
+	assert.Equal(t, "14e30cd163c732912e048c4c837e15c4e90c062ebb795ab947d57706e2d10dd8", tv)
+	tv, _ = metrics[0].GetTag("s-computername")
+	assert.Equal(t, "5926c02f51921c6ff47f5651d327dc972561ff30f44945701e4d22cefa57d855", tv)
+}
+
+func TestHMACRequiresKey(t *testing.T) {
+	plugin := &Strings{
+		HMAC: []converter{{Tag: "s-computername"}},
+	}
+	require.EqualError(t, plugin.Validate(), "hmac requires a key")
+
+	// the metrics are not modified
+	metrics := plugin.Apply(newM1())
+	tv, _ := metrics[0].GetTag("s-computername")
+	assert.Equal(t, "MIXEDCASE_hostname", tv)
+}
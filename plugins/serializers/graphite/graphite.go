@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
 )
@@ -33,13 +34,20 @@ type GraphiteSerializer struct {
 	Prefix     string
 	Template   string
 	TagSupport bool
+
+	// TimestampUnits is the units of the timestamp, defaults to seconds.
+	TimestampUnits time.Duration
 }
 
 func (s *GraphiteSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	out := []byte{}
 
-	// Convert UnixNano to Unix timestamps
-	timestamp := metric.Time().UnixNano() / 1000000000
+	// Convert UnixNano to the timestamp units
+	units := s.TimestampUnits
+	if units <= 0 {
+		units = time.Second
+	}
+	timestamp := metric.Time().UnixNano() / int64(units)
 
 	switch s.TagSupport {
 	case true:
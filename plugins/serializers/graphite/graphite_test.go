@@ -199,6 +199,21 @@ func TestSerializeValueField(t *testing.T) {
 	assert.Equal(t, expS, mS)
 }
 
+func TestSerializeTimestampUnits(t *testing.T) {
+	now := time.Unix(1525478795, 123456789)
+	fields := map[string]interface{}{
+		"value": float64(91.5),
+	}
+	m, err := metric.New("cpu", defaultTags, fields, now)
+	assert.NoError(t, err)
+
+	s := GraphiteSerializer{TimestampUnits: time.Millisecond}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "localhost.cpu0.us-west-2.cpu 91.5 1525478795123\n", string(buf))
+}
+
 func TestSerializeValueFieldWithTagSupport(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
@@ -8,6 +8,7 @@ import (
 	"math"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/influxdata/telegraf"
 )
@@ -27,6 +28,34 @@ const (
 	UintSupport FieldTypeSupport = 1 << iota
 )
 
+// UintMode controls how unsigned integers are written when UintSupport is
+// not enabled.
+type UintMode int
+
+const (
+	// UintClamp writes unsigned integers as signed integers, values larger
+	// than MaxInt64 are clamped to MaxInt64.
+	UintClamp UintMode = iota
+	// UintFloat writes unsigned integers as floats.
+	UintFloat
+	// UintDrop discards unsigned integer fields.
+	UintDrop
+)
+
+// ParseUintMode returns the UintMode with the given name.
+func ParseUintMode(name string) (UintMode, error) {
+	switch name {
+	case "", "clamp":
+		return UintClamp, nil
+	case "float":
+		return UintFloat, nil
+	case "drop":
+		return UintDrop, nil
+	default:
+		return UintClamp, fmt.Errorf("invalid uint mode: %s", name)
+	}
+}
+
 // MetricError is an error causing a metric to be unserializable.
 type MetricError struct {
 	s string
@@ -57,6 +86,8 @@ type Serializer struct {
 	bytesWritten     int
 	fieldSortOrder   FieldSortOrder
 	fieldTypeSupport FieldTypeSupport
+	uintMode         UintMode
+	timestampUnits   time.Duration
 
 	buf    bytes.Buffer
 	header []byte
@@ -67,6 +98,7 @@ type Serializer struct {
 func NewSerializer() *Serializer {
 	serializer := &Serializer{
 		fieldSortOrder: NoSortFields,
+		timestampUnits: time.Nanosecond,
 
 		header: make([]byte, 0, 50),
 		footer: make([]byte, 0, 21),
@@ -87,6 +119,19 @@ func (s *Serializer) SetFieldTypeSupport(typeSupport FieldTypeSupport) {
 	s.fieldTypeSupport = typeSupport
 }
 
+func (s *Serializer) SetUintMode(mode UintMode) {
+	s.uintMode = mode
+}
+
+// SetTimestampUnits sets the units of the timestamp, the timestamp is
+// truncated to these units.  Defaults to nanoseconds.
+func (s *Serializer) SetTimestampUnits(units time.Duration) {
+	if units <= 0 {
+		units = time.Nanosecond
+	}
+	s.timestampUnits = units
+}
+
 // Serialize writes the telegraf.Metric to a byte slice.  May produce multiple
 // lines of output if longer than maximum line length.  Lines are terminated
 // with a newline (LF) char.
@@ -163,7 +208,7 @@ func (s *Serializer) buildHeader(m telegraf.Metric) error {
 func (s *Serializer) buildFooter(m telegraf.Metric) {
 	s.footer = s.footer[:0]
 	s.footer = append(s.footer, ' ')
-	s.footer = strconv.AppendInt(s.footer, m.Time().UnixNano(), 10)
+	s.footer = strconv.AppendInt(s.footer, m.Time().UnixNano()/int64(s.timestampUnits), 10)
 	s.footer = append(s.footer, '\n')
 }
 
@@ -280,7 +325,14 @@ func (s *Serializer) appendFieldValue(buf []byte, value interface{}) ([]byte, er
 	case uint64:
 		if s.fieldTypeSupport&UintSupport != 0 {
 			return appendUintField(buf, v), nil
-		} else {
+		}
+
+		switch s.uintMode {
+		case UintFloat:
+			return appendFloatField(buf, float64(v)), nil
+		case UintDrop:
+			return nil, &FieldError{"unsigned integers are not supported"}
+		default:
 			if v <= uint64(MaxInt64) {
 				return appendIntField(buf, int64(v)), nil
 			} else {
@@ -433,6 +433,93 @@ func TestSerializer(t *testing.T) {
 	}
 }
 
+func TestSerializer_UintMode(t *testing.T) {
+	m := MustMetric(
+		metric.New(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": uint64(42),
+				"other": 1.0,
+			},
+			time.Unix(0, 0),
+		),
+	)
+
+	tests := []struct {
+		name   string
+		mode   UintMode
+		output string
+	}{
+		{
+			name:   "clamp",
+			mode:   UintClamp,
+			output: "cpu other=1,value=42i 0\n",
+		},
+		{
+			name:   "float",
+			mode:   UintFloat,
+			output: "cpu other=1,value=42 0\n",
+		},
+		{
+			name:   "drop",
+			mode:   UintDrop,
+			output: "cpu other=1 0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serializer := NewSerializer()
+			serializer.SetFieldSortOrder(SortFields)
+			serializer.SetUintMode(tt.mode)
+			output, err := serializer.Serialize(m)
+			require.NoError(t, err)
+			require.Equal(t, tt.output, string(output))
+		})
+	}
+}
+
+func TestParseUintMode(t *testing.T) {
+	mode, err := ParseUintMode("")
+	require.NoError(t, err)
+	require.Equal(t, UintClamp, mode)
+
+	mode, err = ParseUintMode("float")
+	require.NoError(t, err)
+	require.Equal(t, UintFloat, mode)
+
+	_, err = ParseUintMode("round")
+	require.Error(t, err)
+}
+
+func TestSerializer_TimestampUnits(t *testing.T) {
+	m := MustMetric(
+		metric.New(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": 42.0,
+			},
+			time.Unix(1525478795, 123456789),
+		),
+	)
+
+	serializer := NewSerializer()
+	output, err := serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42 1525478795123456789\n", string(output))
+
+	serializer.SetTimestampUnits(time.Millisecond)
+	output, err = serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42 1525478795123\n", string(output))
+
+	serializer.SetTimestampUnits(time.Second)
+	output, err = serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42 1525478795\n", string(output))
+}
+
 func BenchmarkSerializer(b *testing.B) {
 	for _, tt := range tests {
 		b.Run(tt.name, func(b *testing.B) {
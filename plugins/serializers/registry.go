@@ -2,6 +2,8 @@ package serializers
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -18,6 +20,16 @@ type SerializerOutput interface {
 	SetSerializer(serializer Serializer)
 }
 
+// SizedOutput is an interface for output plugins not using a serializer that
+// measure the size of the metrics in the format they send, for the
+// max_body_size option.  The size of the metrics of the other outputs without
+// a serializer is measured as influx line protocol.
+type SizedOutput interface {
+	// MetricSize returns the size in bytes of the metric as sent by the
+	// output.
+	MetricSize(metric telegraf.Metric) int
+}
+
 // Serializer is an interface defining functions that a serializer plugin must
 // satisfy.
 type Serializer interface {
@@ -34,34 +46,57 @@ type Serializer interface {
 
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
+//
+// The fields with a toml tag are read from the table of each output, a new
+// serializer option only needs to be added here.
 type Config struct {
 	// Dataformat can be one of: influx, graphite, or json
-	DataFormat string
+	DataFormat string `toml:"data_format"`
 
 	// Support tags in graphite protocol
-	GraphiteTagSupport bool
+	GraphiteTagSupport bool `toml:"graphite_tag_support"`
 
 	// Maximum line length in bytes; influx format only
-	InfluxMaxLineBytes int
+	InfluxMaxLineBytes int `toml:"influx_max_line_bytes"`
 
 	// Sort field keys, set to true only when debugging as it less performant
 	// than unsorted fields; influx format only
-	InfluxSortFields bool
+	InfluxSortFields bool `toml:"influx_sort_fields"`
 
 	// Support unsigned integer output; influx format only
-	InfluxUintSupport bool
+	InfluxUintSupport bool `toml:"influx_uint_support"`
+
+	// How to write unsigned integers when InfluxUintSupport is not set, one
+	// of "clamp", "float" or "drop"; influx format only
+	InfluxUintMode string `toml:"influx_uint_mode"`
 
 	// Prefix to add to all measurements, only supports Graphite
-	Prefix string
+	Prefix string `toml:"prefix"`
 
 	// Template for converting telegraf metrics into Graphite
 	// only supports Graphite
-	Template string
+	Template string `toml:"template"`
 
-	// Timestamp units to use for JSON formatted output
+	// Timestamp units to use for the output, the timestamp is truncated to
+	// these units.  If zero, the default of the data format is used.  It is
+	// set from "timestamp_units", which needs rounding to a power of ten.
 	TimestampUnits time.Duration
 }
 
+// ConfigKeys returns the configuration keys of the serializer options, the
+// toml tags of Config.
+func ConfigKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+		if tag != "" && tag != "-" {
+			keys = append(keys, tag)
+		}
+	}
+	return keys
+}
+
 // NewSerializer a Serializer interface based on the given config.
 func NewSerializer(config *Config) (Serializer, error) {
 	var err error
@@ -70,7 +105,7 @@ func NewSerializer(config *Config) (Serializer, error) {
 	case "influx":
 		serializer, err = NewInfluxSerializerConfig(config)
 	case "graphite":
-		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template, config.GraphiteTagSupport)
+		serializer, err = NewGraphiteSerializerConfig(config)
 	case "json":
 		serializer, err = NewJsonSerializer(config.TimestampUnits)
 	default:
@@ -94,10 +129,17 @@ func NewInfluxSerializerConfig(config *Config) (Serializer, error) {
 		typeSupport = typeSupport + influx.UintSupport
 	}
 
+	uintMode, err := influx.ParseUintMode(config.InfluxUintMode)
+	if err != nil {
+		return nil, err
+	}
+
 	s := influx.NewSerializer()
 	s.SetMaxLineBytes(config.InfluxMaxLineBytes)
 	s.SetFieldSortOrder(sort)
 	s.SetFieldTypeSupport(typeSupport)
+	s.SetUintMode(uintMode)
+	s.SetTimestampUnits(config.TimestampUnits)
 	return s, nil
 }
 
@@ -105,6 +147,15 @@ func NewInfluxSerializer() (Serializer, error) {
 	return influx.NewSerializer(), nil
 }
 
+func NewGraphiteSerializerConfig(config *Config) (Serializer, error) {
+	return &graphite.GraphiteSerializer{
+		Prefix:         config.Prefix,
+		Template:       config.Template,
+		TagSupport:     config.GraphiteTagSupport,
+		TimestampUnits: config.TimestampUnits,
+	}, nil
+}
+
 func NewGraphiteSerializer(prefix, template string, tag_support bool) (Serializer, error) {
 	return &graphite.GraphiteSerializer{
 		Prefix:     prefix,
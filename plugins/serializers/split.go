@@ -0,0 +1,42 @@
+package serializers
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// SplitBatch splits metrics into batches that each serialize to at most
+// maxSize bytes, using the size of each metric as serialized individually.
+// The order of the metrics is preserved.  A metric larger than maxSize is
+// placed in a batch by itself, and metrics that fail to serialize are
+// assumed to take no space since the serializer will skip them.
+func SplitBatch(serializer Serializer, metrics []telegraf.Metric, maxSize int) [][]telegraf.Metric {
+	return SplitBatchBySize(func(m telegraf.Metric) int {
+		buf, err := serializer.Serialize(m)
+		if err != nil {
+			return 0
+		}
+		return len(buf)
+	}, metrics, maxSize)
+}
+
+// SplitBatchBySize splits metrics into batches of at most maxSize bytes, as
+// SplitBatch does, with the size of each metric returned by size.
+func SplitBatchBySize(size func(telegraf.Metric) int, metrics []telegraf.Metric, maxSize int) [][]telegraf.Metric {
+	var batches [][]telegraf.Metric
+	var batch []telegraf.Metric
+	var total int
+	for _, m := range metrics {
+		n := size(m)
+		if len(batch) > 0 && total+n > maxSize {
+			batches = append(batches, batch)
+			batch = nil
+			total = 0
+		}
+		batch = append(batch, m)
+		total += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
@@ -0,0 +1,91 @@
+package serializers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string) telegraf.Metric {
+	m, _ := metric.New(name,
+		map[string]string{},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+func TestSplitBatch(t *testing.T) {
+	// Each metric serializes to "cpuN value=42 0\n", 16 bytes.
+	metrics := []telegraf.Metric{
+		newMetric("cpu1"),
+		newMetric("cpu2"),
+		newMetric("cpu3"),
+		newMetric("cpu4"),
+		newMetric("cpu5"),
+	}
+
+	tests := []struct {
+		name    string
+		maxSize int
+		sizes   []int
+	}{
+		{
+			name:    "fits",
+			maxSize: 80,
+			sizes:   []int{5},
+		},
+		{
+			name:    "exact",
+			maxSize: 32,
+			sizes:   []int{2, 2, 1},
+		},
+		{
+			name:    "remainder",
+			maxSize: 50,
+			sizes:   []int{3, 2},
+		},
+		{
+			name:    "oversized metric",
+			maxSize: 10,
+			sizes:   []int{1, 1, 1, 1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := SplitBatch(influx.NewSerializer(), metrics, tt.maxSize)
+
+			var sizes []int
+			var all []telegraf.Metric
+			for _, batch := range batches {
+				sizes = append(sizes, len(batch))
+				all = append(all, batch...)
+			}
+			require.Equal(t, tt.sizes, sizes)
+			require.Equal(t, metrics, all)
+		})
+	}
+}
+
+func TestSplitBatchEmpty(t *testing.T) {
+	require.Len(t, SplitBatch(influx.NewSerializer(), nil, 100), 0)
+}
+
+func TestSplitBatchBySize(t *testing.T) {
+	metrics := []telegraf.Metric{
+		newMetric("cpu1"),
+		newMetric("cpu2"),
+		newMetric("cpu3"),
+	}
+	size := func(m telegraf.Metric) int {
+		return len(m.Name()) * 10
+	}
+
+	batches := SplitBatchBySize(size, metrics, 80)
+	require.Equal(t, [][]telegraf.Metric{metrics[:2], metrics[2:]}, batches)
+}
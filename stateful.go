@@ -0,0 +1,16 @@
+package telegraf
+
+// StatefulPlugin is implemented by plugins that need to persist state, such
+// as file offsets or checkpoints, across restarts.  When a statefile is
+// configured the agent restores the state with SetState before the plugin is
+// started and saves the result of GetState after it has been stopped.
+type StatefulPlugin interface {
+	// GetState returns the current state of the plugin.  The state must be
+	// serializable as JSON.
+	GetState() interface{}
+
+	// SetState restores a state previously returned by GetState.  The state
+	// is passed as decoded JSON; slices and maps are []interface{} and
+	// map[string]interface{}, and numbers are float64.
+	SetState(state interface{}) error
+}
@@ -0,0 +1,149 @@
+// custom_builder compiles a Telegraf binary with only the plugins referenced
+// by configuration files.
+//
+// It generates a registration file importing these plugins in the all
+// package of each plugin type, which replaces the registration of all the
+// plugins when building with the custom tag, builds ./cmd/telegraf and then
+// removes the generated files.  It must be run from the root of the
+// repository, or given it with -root:
+//
+//	go run ./tools/custom_builder -config /etc/telegraf/telegraf.conf
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal/config"
+)
+
+// kinds are the plugin types, with an all package each.
+var kinds = []string{"inputs", "outputs", "processors", "aggregators"}
+
+const generatedFile = "custom.go"
+
+type configFiles []string
+
+func (c *configFiles) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *configFiles) Set(path string) error {
+	*c = append(*c, path)
+	return nil
+}
+
+var (
+	fConfigs         configFiles
+	fConfigDirectory = flag.String("config-directory", "",
+		"directory containing additional *.conf files")
+	fRoot   = flag.String("root", ".", "root of the Telegraf repository")
+	fOutput = flag.String("o", "telegraf", "path of the binary to build")
+	fTags   = flag.String("tags", "", "additional build tags, separated by spaces")
+	fDryRun = flag.Bool("dry-run", false,
+		"print the packages of the referenced plugins without building")
+)
+
+func main() {
+	flag.Var(&fConfigs, "config", "configuration file, may be repeated")
+	flag.Parse()
+	if len(fConfigs) == 0 && *fConfigDirectory == "" {
+		log.Fatal("E! At least one of -config and -config-directory is required")
+	}
+
+	imports, err := pluginImports(*fRoot, fConfigs, *fConfigDirectory)
+	if err != nil {
+		log.Fatalf("E! %s", err)
+	}
+
+	if *fDryRun {
+		for _, kind := range kinds {
+			for _, path := range imports[kind] {
+				fmt.Println(path)
+			}
+		}
+		return
+	}
+
+	if err := build(*fRoot, imports); err != nil {
+		log.Fatalf("E! %s", err)
+	}
+}
+
+// pluginImports returns the sorted import paths of the packages registering
+// the plugins of each type referenced by the configuration.
+func pluginImports(root string, paths []string, directory string) (map[string][]string, error) {
+	referenced, err := config.ReferencedPlugins(paths, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make(map[string][]string)
+	for _, kind := range kinds {
+		packages, err := pluginPackages(root, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		unique := make(map[string]bool)
+		for _, name := range referenced[kind] {
+			path, ok := packages[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown plugin %s.%s", kind, name)
+			}
+			if !unique[path] {
+				unique[path] = true
+				imports[kind] = append(imports[kind], path)
+			}
+		}
+		sort.Strings(imports[kind])
+	}
+	return imports, nil
+}
+
+// build generates the registration files and builds Telegraf with them.
+func build(root string, imports map[string][]string) error {
+	for _, kind := range kinds {
+		path := filepath.Join(root, "plugins", kind, "all", generatedFile)
+		if err := ioutil.WriteFile(path, generate(imports[kind]), 0644); err != nil {
+			return err
+		}
+		defer os.Remove(path)
+	}
+
+	output, err := filepath.Abs(*fOutput)
+	if err != nil {
+		return err
+	}
+	tags := strings.TrimSpace("custom " + *fTags)
+	cmd := exec.Command("go", "build", "-tags", tags, "-o", output, "./cmd/telegraf")
+	cmd.Dir = root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	log.Printf("I! Building %s with %s", output, strings.Join(cmd.Args, " "))
+	return cmd.Run()
+}
+
+// generate returns the registration file importing the packages.
+func generate(packages []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by tools/custom_builder; DO NOT EDIT.\n\n")
+	buf.WriteString("// +build custom\n\n")
+	buf.WriteString("package all\n")
+	if len(packages) > 0 {
+		buf.WriteString("\nimport (\n")
+		for _, path := range packages {
+			fmt.Fprintf(&buf, "\t_ %q\n", path)
+		}
+		buf.WriteString(")\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginPackages(t *testing.T) {
+	packages, err := pluginPackages("../..", "inputs")
+	require.NoError(t, err)
+	require.Equal(t, importPrefix+"plugins/inputs/system", packages["cpu"])
+	require.Equal(t, importPrefix+"plugins/inputs/system", packages["mem"])
+	// Registered with a constant name
+	require.Equal(t, importPrefix+"plugins/inputs/ceph", packages["ceph"])
+	require.Equal(t, importPrefix+"plugins/inputs/conntrack", packages["conntrack"])
+	// Imported by a plugin family
+	require.Equal(t, importPrefix+"plugins/inputs/aws_cost", packages["aws_cost"])
+
+	packages, err = pluginPackages("../..", "processors")
+	require.NoError(t, err)
+	require.Equal(t, importPrefix+"plugins/processors/rename", packages["rename"])
+}
+
+func TestPluginImports(t *testing.T) {
+	imports, err := pluginImports("../..", []string{"./testdata/telegraf.conf"}, "")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"inputs": {
+			importPrefix + "plugins/inputs/ceph",
+			importPrefix + "plugins/inputs/system",
+		},
+		"outputs":    {importPrefix + "plugins/outputs/file"},
+		"processors": {importPrefix + "plugins/processors/rename"},
+	}, imports)
+}
+
+func TestGenerate(t *testing.T) {
+	require.Equal(t, `// Code generated by tools/custom_builder; DO NOT EDIT.
+
+// +build custom
+
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/inputs/ceph"
+	_ "github.com/influxdata/telegraf/plugins/inputs/system"
+)
+`, string(generate([]string{
+		importPrefix + "plugins/inputs/ceph",
+		importPrefix + "plugins/inputs/system",
+	})))
+
+	require.Equal(t, "// Code generated by tools/custom_builder; DO NOT EDIT.\n\n// +build custom\n\npackage all\n",
+		string(generate(nil)))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const importPrefix = "github.com/influxdata/telegraf/"
+
+// pluginPackages returns the import path of the package registering each
+// plugin of the type, found in the packages imported by the all package of
+// the type, whatever their build tags, and their calls to the Add function
+// of the registry.
+func pluginPackages(root, kind string) (map[string]string, error) {
+	fset := token.NewFileSet()
+	notGenerated := func(info os.FileInfo) bool {
+		return info.Name() != generatedFile && !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	all, err := parser.ParseDir(fset, filepath.Join(root, "plugins", kind, "all"), notGenerated, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []*ast.ImportSpec
+	for _, pkg := range all {
+		for _, f := range pkg.Files {
+			imports = append(imports, f.Imports...)
+		}
+	}
+
+	packages := make(map[string]string)
+	for _, spec := range imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || spec.Name == nil || spec.Name.Name != "_" || !strings.HasPrefix(path, importPrefix) {
+			continue
+		}
+		dir := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(path, importPrefix)))
+		names, err := registeredNames(fset, dir, kind)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no %s registered by %s", kind, path)
+		}
+		for _, name := range names {
+			packages[name] = path
+		}
+	}
+	return packages, nil
+}
+
+// registeredNames returns the names of the plugins registered by the package
+// in dir, with literal or constant names.
+func registeredNames(fset *token.FileSet, dir, kind string) ([]string, error) {
+	notTest := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(fset, dir, notTest, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pkg := range pkgs {
+		constants := make(map[string]string)
+		var calls []*ast.CallExpr
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.ValueSpec:
+					for i, ident := range n.Names {
+						if i < len(n.Values) {
+							if s, ok := stringLit(n.Values[i]); ok {
+								constants[ident.Name] = s
+							}
+						}
+					}
+				case *ast.CallExpr:
+					if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Add" {
+						if x, ok := sel.X.(*ast.Ident); ok && x.Name == kind && len(n.Args) > 0 {
+							calls = append(calls, n)
+						}
+					}
+				}
+				return true
+			})
+		}
+
+		for _, call := range calls {
+			if s, ok := stringLit(call.Args[0]); ok {
+				names = append(names, s)
+			} else if ident, ok := call.Args[0].(*ast.Ident); ok && constants[ident.Name] != "" {
+				names = append(names, constants[ident.Name])
+			} else {
+				return nil, fmt.Errorf("%s: cannot resolve the name of the plugin", fset.Position(call.Pos()))
+			}
+		}
+	}
+	return names, nil
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
@@ -0,0 +1,11 @@
+package telegraf
+
+// Validator is implemented by plugins that can check their configuration
+// without being started, such as for options that conflict with each other.
+// It is used by "telegraf config check", and when loading the configuration
+// of the processors, which fails if they are invalid.
+type Validator interface {
+	// Validate returns an error describing the first problem found in the
+	// configuration of the plugin, or nil if it is valid.
+	Validate() error
+}